@@ -6,6 +6,7 @@
 package addrmgr
 
 import (
+	"bytes"
 	"container/list"
 	crand "crypto/rand" // for seeding
 	"encoding/base32"
@@ -17,6 +18,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -25,6 +27,7 @@ import (
 
 	"github.com/brsuite/brond/chaincfg/chainhash"
 	"github.com/brsuite/brond/wire"
+	"golang.org/x/crypto/sha3"
 )
 
 // AddrManager provides a concurrency safe address manager for caching potential
@@ -70,10 +73,17 @@ type serializedAddrManager struct {
 }
 
 type localAddress struct {
-	na    *wire.NetAddress
+	na    *wire.NetAddressV2
 	score AddressPriority
 }
 
+// LocalAddress describes a local address known to the address manager along
+// with the priority it is advertised with.
+type LocalAddress struct {
+	NetAddress *wire.NetAddressV2
+	Priority   AddressPriority
+}
+
 // AddressPriority type is used to describe the hierarchy of local address
 // discovery methods.
 type AddressPriority int
@@ -158,12 +168,13 @@ const (
 	getAddrPercent = 23
 
 	// serialisationVersion is the current version of the on-disk format.
-	serialisationVersion = 2
+	// Version 3 may contain Tor v3, I2P and CJDNS addresses.
+	serialisationVersion = 3
 )
 
 // updateAddress is a helper function to either update an address already known
 // to the address manager, or to add the address if not already known.
-func (a *AddrManager) updateAddress(netAddr, srcAddr *wire.NetAddress) {
+func (a *AddrManager) updateAddress(netAddr, srcAddr *wire.NetAddressV2) {
 	// Filter out non-routable addresses. Note that non-routable
 	// also includes invalid and local addresses.
 	if !IsRoutable(netAddr) {
@@ -294,7 +305,7 @@ func (a *AddrManager) pickTried(bucket int) *list.Element {
 	return oldestElem
 }
 
-func (a *AddrManager) getNewBucket(netAddr, srcAddr *wire.NetAddress) int {
+func (a *AddrManager) getNewBucket(netAddr, srcAddr *wire.NetAddressV2) int {
 	// brocoind:
 	// doublesha256(key + sourcegroup + int64(doublesha256(key + group + sourcegroup))%bucket_per_source_group) % num_new_buckets
 
@@ -316,7 +327,7 @@ func (a *AddrManager) getNewBucket(netAddr, srcAddr *wire.NetAddress) int {
 	return int(binary.LittleEndian.Uint64(hash2) % newBucketCount)
 }
 
-func (a *AddrManager) getTriedBucket(netAddr *wire.NetAddress) int {
+func (a *AddrManager) getTriedBucket(netAddr *wire.NetAddressV2) int {
 	// brocoind hashes this as:
 	// doublesha256(key + group + truncate_to_64bits(doublesha256(key)) % buckets_per_group) % num_buckets
 	data1 := []byte{}
@@ -546,9 +557,9 @@ func (a *AddrManager) deserializePeers(filePath string) error {
 	return nil
 }
 
-// DeserializeNetAddress converts a given address string to a *wire.NetAddress.
+// DeserializeNetAddress converts a given address string to a *wire.NetAddressV2.
 func (a *AddrManager) DeserializeNetAddress(addr string,
-	services wire.ServiceFlag) (*wire.NetAddress, error) {
+	services wire.ServiceFlag) (*wire.NetAddressV2, error) {
 
 	host, portStr, err := net.SplitHostPort(addr)
 	if err != nil {
@@ -597,7 +608,7 @@ func (a *AddrManager) Stop() error {
 // AddAddresses adds new addresses to the address manager.  It enforces a max
 // number of addresses and silently ignores duplicate addresses.  It is
 // safe for concurrent access.
-func (a *AddrManager) AddAddresses(addrs []*wire.NetAddress, srcAddr *wire.NetAddress) {
+func (a *AddrManager) AddAddresses(addrs []*wire.NetAddressV2, srcAddr *wire.NetAddressV2) {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 
@@ -609,7 +620,7 @@ func (a *AddrManager) AddAddresses(addrs []*wire.NetAddress, srcAddr *wire.NetAd
 // AddAddress adds a new address to the address manager.  It enforces a max
 // number of addresses and silently ignores duplicate addresses.  It is
 // safe for concurrent access.
-func (a *AddrManager) AddAddress(addr, srcAddr *wire.NetAddress) {
+func (a *AddrManager) AddAddress(addr, srcAddr *wire.NetAddressV2) {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 
@@ -617,7 +628,7 @@ func (a *AddrManager) AddAddress(addr, srcAddr *wire.NetAddress) {
 }
 
 // AddAddressByIP adds an address where we are given an ip:port and not a
-// wire.NetAddress.
+// wire.NetAddressV2.
 func (a *AddrManager) AddAddressByIP(addrIP string) error {
 	// Split IP and port
 	addr, portStr, err := net.SplitHostPort(addrIP)
@@ -633,7 +644,7 @@ func (a *AddrManager) AddAddressByIP(addrIP string) error {
 	if err != nil {
 		return fmt.Errorf("invalid port %s: %v", portStr, err)
 	}
-	na := wire.NewNetAddressIPPort(ip, uint16(port), 0)
+	na := wire.NewNetAddressV2IPPort(ip, uint16(port), 0)
 	a.AddAddress(na, na) // XXX use correct src address
 	return nil
 }
@@ -662,7 +673,7 @@ func (a *AddrManager) NeedMoreAddresses() bool {
 
 // AddressCache returns the current address cache.  It must be treated as
 // read-only (but since it is a copy now, this is not as dangerous).
-func (a *AddrManager) AddressCache() []*wire.NetAddress {
+func (a *AddrManager) AddressCache() []*wire.NetAddressV2 {
 	allAddr := a.getAddresses()
 
 	numAddresses := len(allAddr) * getAddrPercent / 100
@@ -684,7 +695,7 @@ func (a *AddrManager) AddressCache() []*wire.NetAddress {
 
 // getAddresses returns all of the addresses currently found within the
 // manager's address cache.
-func (a *AddrManager) getAddresses() []*wire.NetAddress {
+func (a *AddrManager) getAddresses() []*wire.NetAddressV2 {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 
@@ -693,7 +704,7 @@ func (a *AddrManager) getAddresses() []*wire.NetAddress {
 		return nil
 	}
 
-	addrs := make([]*wire.NetAddress, 0, addrIndexLen)
+	addrs := make([]*wire.NetAddressV2, 0, addrIndexLen)
 	for _, v := range a.addrIndex {
 		addrs = append(addrs, v.na)
 	}
@@ -717,24 +728,65 @@ func (a *AddrManager) reset() {
 	}
 }
 
+// torV3Checksum returns the two byte checksum of a Tor v3 onion address with
+// the passed ed25519 public key as defined by the Tor rendezvous spec.
+func torV3Checksum(pubKey []byte) []byte {
+	h := sha3.New256()
+	h.Write([]byte(".onion checksum"))
+	h.Write(pubKey)
+	h.Write([]byte{torV3Version})
+	return h.Sum(nil)[:2]
+}
+
 // HostToNetAddress returns a netaddress given a host address.  If the address
-// is a Tor .onion address this will be taken care of.  Else if the host is
-// not an IP address it will be resolved (via Tor if required).
-func (a *AddrManager) HostToNetAddress(host string, port uint16, services wire.ServiceFlag) (*wire.NetAddress, error) {
-	// Tor address is 16 char base32 + ".onion"
-	var ip net.IP
-	if len(host) == 22 && host[16:] == ".onion" {
-		// go base32 encoding uses capitals (as does the rfc
-		// but Tor and brocoind tend to user lowercase, so we switch
-		// case here.
+// is a Tor .onion or I2P .b32.i2p address this will be taken care of.  Else if
+// the host is not an IP address it will be resolved (via Tor if required).
+func (a *AddrManager) HostToNetAddress(host string, port uint16, services wire.ServiceFlag) (*wire.NetAddressV2, error) {
+	// go base32 encoding uses capitals (as does the rfc but Tor, I2P and
+	// brocoind tend to use lowercase, so we switch case when decoding.
+	switch {
+	// Tor v2 address is 16 char base32 + ".onion"
+	case len(host) == 22 && host[16:] == ".onion":
 		data, err := base32.StdEncoding.DecodeString(
 			strings.ToUpper(host[:16]))
 		if err != nil {
 			return nil, err
 		}
 		prefix := []byte{0xfd, 0x87, 0xd8, 0x7e, 0xeb, 0x43}
-		ip = net.IP(append(prefix, data...))
-	} else if ip = net.ParseIP(host); ip == nil {
+		ip := net.IP(append(prefix, data...))
+		return wire.NewNetAddressV2IPPort(ip, port, services), nil
+
+	// Tor v3 address is 56 char base32 + ".onion"
+	case len(host) == 62 && host[56:] == ".onion":
+		data, err := base32.StdEncoding.DecodeString(
+			strings.ToUpper(host[:56]))
+		if err != nil {
+			return nil, err
+		}
+		pubKey, checksum, version := data[:32], data[32:34], data[34]
+		if version != torV3Version {
+			return nil, fmt.Errorf("unsupported onion address "+
+				"version %d", version)
+		}
+		if !bytes.Equal(checksum, torV3Checksum(pubKey)) {
+			return nil, fmt.Errorf("invalid onion address "+
+				"checksum for %s", host)
+		}
+		return wire.NewNetAddressV2(wire.NetTorV3, pubKey, port,
+			services), nil
+
+	// I2P address is 52 char unpadded base32 + ".b32.i2p"
+	case len(host) == 60 && host[52:] == ".b32.i2p":
+		data, err := i2pEncoding.DecodeString(strings.ToUpper(host[:52]))
+		if err != nil {
+			return nil, err
+		}
+		return wire.NewNetAddressV2(wire.NetI2P, data, port,
+			services), nil
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
 		ips, err := a.lookupFunc(host)
 		if err != nil {
 			return nil, err
@@ -745,25 +797,47 @@ func (a *AddrManager) HostToNetAddress(host string, port uint16, services wire.S
 		ip = ips[0]
 	}
 
-	return wire.NewNetAddressIPPort(ip, port, services), nil
+	// CJDNS addresses are IPv6 addresses in fc00::/8, which is otherwise
+	// unused since it is the undefined half of the RFC4193 range.
+	if ip.To4() == nil && cjdnsNet.Contains(ip) {
+		return wire.NewNetAddressV2(wire.NetCJDNS, ip.To16(), port,
+			services), nil
+	}
+
+	return wire.NewNetAddressV2IPPort(ip, port, services), nil
 }
 
 // ipString returns a string for the ip from the provided NetAddress. If the
 // ip is in the range used for Tor addresses then it will be transformed into
-// the relevant .onion address.
-func ipString(na *wire.NetAddress) string {
+// the relevant .onion address.  Tor v3 and I2P addresses are transformed into
+// their .onion and .b32.i2p forms respectively.
+func ipString(na *wire.NetAddressV2) string {
+	switch na.NetworkID {
+	case wire.NetTorV3:
+		data := make([]byte, 0, 35)
+		data = append(data, na.Addr...)
+		data = append(data, torV3Checksum(na.Addr)...)
+		data = append(data, torV3Version)
+		return strings.ToLower(base32.StdEncoding.EncodeToString(data)) +
+			".onion"
+
+	case wire.NetI2P:
+		return strings.ToLower(i2pEncoding.EncodeToString(na.Addr)) +
+			".b32.i2p"
+	}
+
 	if IsOnionCatTor(na) {
 		// We know now that na.IP is long enough.
-		base32 := base32.StdEncoding.EncodeToString(na.IP[6:])
+		base32 := base32.StdEncoding.EncodeToString(na.IP()[6:])
 		return strings.ToLower(base32) + ".onion"
 	}
 
-	return na.IP.String()
+	return na.IP().String()
 }
 
 // NetAddressKey returns a string key in the form of ip:port for IPv4 addresses
 // or [ip]:port for IPv6 addresses.
-func NetAddressKey(na *wire.NetAddress) string {
+func NetAddressKey(na *wire.NetAddressV2) string {
 	port := strconv.FormatUint(uint64(na.Port), 10)
 
 	return net.JoinHostPort(ipString(na), port)
@@ -811,42 +885,65 @@ func (a *AddrManager) GetAddress() *KnownAddress {
 		}
 	} else {
 		// new node.
-		// XXX use a closure/function to avoid repeating this.
-		large := 1 << 30
-		factor := 1.0
-		for {
-			// Pick a random bucket.
-			bucket := a.rand.Intn(len(a.addrNew))
-			if len(a.addrNew[bucket]) == 0 {
-				continue
-			}
-			// Then, a random entry in it.
-			var ka *KnownAddress
-			nth := a.rand.Intn(len(a.addrNew[bucket]))
-			for _, value := range a.addrNew[bucket] {
-				if nth == 0 {
-					ka = value
-				}
-				nth--
-			}
-			randval := a.rand.Intn(large)
-			if float64(randval) < (factor * ka.chance() * float64(large)) {
-				log.Tracef("Selected %v from new bucket",
-					NetAddressKey(ka.na))
-				return ka
+		return a.getNewAddress()
+	}
+}
+
+// GetNewTableAddress returns a single address that should be routable picked
+// at random from the addresses which have not been connected to successfully
+// yet.  It is suitable for feeler connections, which test whether such
+// addresses are reachable.  It returns nil if there are no such addresses.
+func (a *AddrManager) GetNewTableAddress() *KnownAddress {
+	// Protect concurrent access.
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if a.nNew == 0 {
+		return nil
+	}
+	return a.getNewAddress()
+}
+
+// getNewAddress picks an address from the new table at random, favoring the
+// addresses with the best chance of being connected to.
+//
+// This function MUST be called with the address manager lock held and at
+// least one address in the new table.
+func (a *AddrManager) getNewAddress() *KnownAddress {
+	large := 1 << 30
+	factor := 1.0
+	for {
+		// Pick a random bucket.
+		bucket := a.rand.Intn(len(a.addrNew))
+		if len(a.addrNew[bucket]) == 0 {
+			continue
+		}
+		// Then, a random entry in it.
+		var ka *KnownAddress
+		nth := a.rand.Intn(len(a.addrNew[bucket]))
+		for _, value := range a.addrNew[bucket] {
+			if nth == 0 {
+				ka = value
 			}
-			factor *= 1.2
+			nth--
 		}
+		randval := a.rand.Intn(large)
+		if float64(randval) < (factor * ka.chance() * float64(large)) {
+			log.Tracef("Selected %v from new bucket",
+				NetAddressKey(ka.na))
+			return ka
+		}
+		factor *= 1.2
 	}
 }
 
-func (a *AddrManager) find(addr *wire.NetAddress) *KnownAddress {
+func (a *AddrManager) find(addr *wire.NetAddressV2) *KnownAddress {
 	return a.addrIndex[NetAddressKey(addr)]
 }
 
 // Attempt increases the given address' attempt counter and updates
 // the last attempt time.
-func (a *AddrManager) Attempt(addr *wire.NetAddress) {
+func (a *AddrManager) Attempt(addr *wire.NetAddressV2) {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 
@@ -864,7 +961,7 @@ func (a *AddrManager) Attempt(addr *wire.NetAddress) {
 // Connected Marks the given address as currently connected and working at the
 // current time.  The address must already be known to AddrManager else it will
 // be ignored.
-func (a *AddrManager) Connected(addr *wire.NetAddress) {
+func (a *AddrManager) Connected(addr *wire.NetAddressV2) {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 
@@ -887,7 +984,7 @@ func (a *AddrManager) Connected(addr *wire.NetAddress) {
 // Good marks the given address as good.  To be called after a successful
 // connection and version exchange.  If the address is unknown to the address
 // manager it will be ignored.
-func (a *AddrManager) Good(addr *wire.NetAddress) {
+func (a *AddrManager) Good(addr *wire.NetAddressV2) {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 
@@ -974,7 +1071,7 @@ func (a *AddrManager) Good(addr *wire.NetAddress) {
 }
 
 // SetServices sets the services for the giiven address to the provided value.
-func (a *AddrManager) SetServices(addr *wire.NetAddress, services wire.ServiceFlag) {
+func (a *AddrManager) SetServices(addr *wire.NetAddressV2, services wire.ServiceFlag) {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 
@@ -994,9 +1091,9 @@ func (a *AddrManager) SetServices(addr *wire.NetAddress, services wire.ServiceFl
 
 // AddLocalAddress adds na to the list of known local addresses to advertise
 // with the given priority.
-func (a *AddrManager) AddLocalAddress(na *wire.NetAddress, priority AddressPriority) error {
+func (a *AddrManager) AddLocalAddress(na *wire.NetAddressV2, priority AddressPriority) error {
 	if !IsRoutable(na) {
-		return fmt.Errorf("address %s is not routable", na.IP)
+		return fmt.Errorf("address %s is not routable", ipString(na))
 	}
 
 	a.lamtx.Lock()
@@ -1019,7 +1116,7 @@ func (a *AddrManager) AddLocalAddress(na *wire.NetAddress, priority AddressPrior
 
 // getReachabilityFrom returns the relative reachability of the provided local
 // address to the provided remote address.
-func getReachabilityFrom(localAddr, remoteAddr *wire.NetAddress) int {
+func getReachabilityFrom(localAddr, remoteAddr *wire.NetAddressV2) int {
 	const (
 		Unreachable = 0
 		Default     = iota
@@ -1034,8 +1131,19 @@ func getReachabilityFrom(localAddr, remoteAddr *wire.NetAddress) int {
 		return Unreachable
 	}
 
-	if IsOnionCatTor(remoteAddr) {
-		if IsOnionCatTor(localAddr) {
+	// Peers on the I2P and CJDNS overlay networks can only be reached by
+	// addresses on the same network.
+	switch remoteAddr.NetworkID {
+	case wire.NetI2P, wire.NetCJDNS:
+		if localAddr.NetworkID == remoteAddr.NetworkID {
+			return Private
+		}
+		return Default
+	}
+
+	if IsOnionCatTor(remoteAddr) || remoteAddr.NetworkID == wire.NetTorV3 {
+		if IsOnionCatTor(localAddr) ||
+			localAddr.NetworkID == wire.NetTorV3 {
 			return Private
 		}
 
@@ -1098,13 +1206,13 @@ func getReachabilityFrom(localAddr, remoteAddr *wire.NetAddress) int {
 
 // GetBestLocalAddress returns the most appropriate local address to use
 // for the given remote address.
-func (a *AddrManager) GetBestLocalAddress(remoteAddr *wire.NetAddress) *wire.NetAddress {
+func (a *AddrManager) GetBestLocalAddress(remoteAddr *wire.NetAddressV2) *wire.NetAddressV2 {
 	a.lamtx.Lock()
 	defer a.lamtx.Unlock()
 
 	bestreach := 0
 	var bestscore AddressPriority
-	var bestAddress *wire.NetAddress
+	var bestAddress *wire.NetAddressV2
 	for _, la := range a.localAddresses {
 		reach := getReachabilityFrom(la.na, remoteAddr)
 		if reach > bestreach ||
@@ -1115,26 +1223,49 @@ func (a *AddrManager) GetBestLocalAddress(remoteAddr *wire.NetAddress) *wire.Net
 		}
 	}
 	if bestAddress != nil {
-		log.Debugf("Suggesting address %s:%d for %s:%d", bestAddress.IP,
-			bestAddress.Port, remoteAddr.IP, remoteAddr.Port)
+		log.Debugf("Suggesting address %s for %s",
+			NetAddressKey(bestAddress), NetAddressKey(remoteAddr))
 	} else {
-		log.Debugf("No worthy address for %s:%d", remoteAddr.IP,
-			remoteAddr.Port)
+		log.Debugf("No worthy address for %s", NetAddressKey(remoteAddr))
 
 		// Send something unroutable if nothing suitable.
 		var ip net.IP
-		if !IsIPv4(remoteAddr) && !IsOnionCatTor(remoteAddr) {
+		if !IsIPv4(remoteAddr) && !IsOnionCatTor(remoteAddr) &&
+			remoteAddr.NetworkID != wire.NetTorV3 {
 			ip = net.IPv6zero
 		} else {
 			ip = net.IPv4zero
 		}
 		services := wire.SFNodeNetwork | wire.SFNodeWitness | wire.SFNodeBloom
-		bestAddress = wire.NewNetAddressIPPort(ip, 0, services)
+		bestAddress = wire.NewNetAddressV2IPPort(ip, 0, services)
 	}
 
 	return bestAddress
 }
 
+// LocalAddresses returns the local addresses that have been added to the
+// address manager, ordered by their address key.
+func (a *AddrManager) LocalAddresses() []LocalAddress {
+	a.lamtx.Lock()
+	defer a.lamtx.Unlock()
+
+	keys := make([]string, 0, len(a.localAddresses))
+	for key := range a.localAddresses {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	addrs := make([]LocalAddress, 0, len(keys))
+	for _, key := range keys {
+		la := a.localAddresses[key]
+		addrs = append(addrs, LocalAddress{
+			NetAddress: la.na,
+			Priority:   la.score,
+		})
+	}
+	return addrs
+}
+
 // New returns a new brocoin address manager.
 // Use Start to begin processing asynchronous address updates.
 func New(dataDir string, lookupFunc func(string) ([]net.IP, error)) *AddrManager {
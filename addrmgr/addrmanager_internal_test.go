@@ -1,51 +1,65 @@
 package addrmgr
 
 import (
+	"bytes"
 	"io/ioutil"
 	"math/rand"
 	"net"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/brsuite/brond/wire"
 )
 
-// randAddr generates a *wire.NetAddress backed by a random IPv4/IPv6 address.
-func randAddr(t *testing.T) *wire.NetAddress {
+// randAddr generates a *wire.NetAddressV2 backed by a random IPv4, IPv6,
+// Tor v3 or I2P address.
+func randAddr(t *testing.T) *wire.NetAddressV2 {
 	t.Helper()
 
-	ipv4 := rand.Intn(2) == 0
-	var ip net.IP
-	if ipv4 {
-		var b [4]byte
-		if _, err := rand.Read(b[:]); err != nil {
-			t.Fatal(err)
-		}
-		ip = b[:]
-	} else {
-		var b [16]byte
-		if _, err := rand.Read(b[:]); err != nil {
-			t.Fatal(err)
-		}
-		ip = b[:]
+	var netID wire.NetworkID
+	var addr []byte
+	switch rand.Intn(4) {
+	case 0:
+		netID, addr = wire.NetIPv4, make([]byte, net.IPv4len)
+	case 1:
+		netID, addr = wire.NetIPv6, make([]byte, net.IPv6len)
+	case 2:
+		netID, addr = wire.NetTorV3, make([]byte, 32)
+	case 3:
+		netID, addr = wire.NetI2P, make([]byte, 32)
+	}
+	if _, err := rand.Read(addr); err != nil {
+		t.Fatal(err)
 	}
 
-	return &wire.NetAddress{
-		Services: wire.ServiceFlag(rand.Uint64()),
-		IP:       ip,
-		Port:     uint16(rand.Uint32()),
+	// Keep IPv6 addresses in the global unicast range so they are not
+	// mistaken for addresses on an overlay network.
+	if netID == wire.NetIPv6 {
+		addr[0] = 0x20
+	}
+
+	return &wire.NetAddressV2{
+		Services:  wire.ServiceFlag(rand.Uint64()),
+		NetworkID: netID,
+		Addr:      addr,
+		Port:      uint16(rand.Uint32()),
 	}
 }
 
 // assertAddr ensures that the two addresses match. The timestamp is not
 // checked as it does not affect uniquely identifying a specific address.
-func assertAddr(t *testing.T, got, expected *wire.NetAddress) {
+func assertAddr(t *testing.T, got, expected *wire.NetAddressV2) {
 	if got.Services != expected.Services {
 		t.Fatalf("expected address services %v, got %v",
 			expected.Services, got.Services)
 	}
-	if !got.IP.Equal(expected.IP) {
-		t.Fatalf("expected address IP %v, got %v", expected.IP, got.IP)
+	if got.NetworkID != expected.NetworkID {
+		t.Fatalf("expected address network %v, got %v",
+			expected.NetworkID, got.NetworkID)
+	}
+	if !bytes.Equal(got.Addr, expected.Addr) {
+		t.Fatalf("expected address %x, got %x", expected.Addr, got.Addr)
 	}
 	if got.Port != expected.Port {
 		t.Fatalf("expected address port %d, got %d", expected.Port,
@@ -56,7 +70,7 @@ func assertAddr(t *testing.T, got, expected *wire.NetAddress) {
 // assertAddrs ensures that the manager's address cache matches the given
 // expected addresses.
 func assertAddrs(t *testing.T, addrMgr *AddrManager,
-	expectedAddrs map[string]*wire.NetAddress) {
+	expectedAddrs map[string]*wire.NetAddressV2) {
 
 	t.Helper()
 
@@ -96,7 +110,7 @@ func TestAddrManagerSerialization(t *testing.T) {
 	// We'll be adding 5 random addresses to the manager.
 	const numAddrs = 5
 
-	expectedAddrs := make(map[string]*wire.NetAddress, numAddrs)
+	expectedAddrs := make(map[string]*wire.NetAddressV2, numAddrs)
 	for i := 0; i < numAddrs; i++ {
 		addr := randAddr(t)
 		expectedAddrs[NetAddressKey(addr)] = addr
@@ -141,7 +155,7 @@ func TestAddrManagerV1ToV2(t *testing.T) {
 	// each addresses' services will not be stored.
 	const numAddrs = 5
 
-	expectedAddrs := make(map[string]*wire.NetAddress, numAddrs)
+	expectedAddrs := make(map[string]*wire.NetAddressV2, numAddrs)
 	for i := 0; i < numAddrs; i++ {
 		addr := randAddr(t)
 		expectedAddrs[NetAddressKey(addr)] = addr
@@ -192,3 +206,37 @@ func TestAddrManagerV1ToV2(t *testing.T) {
 	addrMgr.loadPeers()
 	assertAddrs(t, addrMgr, expectedAddrs)
 }
+
+// TestAddrManagerLoadV2 ensures that a peers file written by a version of the
+// address manager which was not aware of addrv2 networks can still be loaded.
+func TestAddrManagerLoadV2(t *testing.T) {
+	t.Parallel()
+
+	tempDir, err := ioutil.TempDir("", "addrmgr")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	const peersV2 = `{"Version":2,"Key":[],"Addresses":[` +
+		`{"Addr":"173.194.115.66:8688","Src":"[2001:db9::1]:8688",` +
+		`"Attempts":0,"TimeStamp":0,"LastAttempt":0,"LastSuccess":0,` +
+		`"Services":9,"SrcServices":1}],` +
+		`"NewBuckets":[["173.194.115.66:8688"]],"TriedBuckets":[]}`
+	peersFile := filepath.Join(tempDir, "peers.json")
+	err = ioutil.WriteFile(peersFile, []byte(peersV2), 0644)
+	if err != nil {
+		t.Fatalf("unable to write peers file: %v", err)
+	}
+
+	addrMgr := New(tempDir, nil)
+	addrMgr.loadPeers()
+	assertAddrs(t, addrMgr, map[string]*wire.NetAddressV2{
+		"173.194.115.66:8688": {
+			Services:  wire.SFNodeNetwork | wire.SFNodeWitness,
+			NetworkID: wire.NetIPv4,
+			Addr:      net.ParseIP("173.194.115.66").To4(),
+			Port:      8688,
+		},
+	})
+}
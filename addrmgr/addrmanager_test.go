@@ -19,7 +19,7 @@ import (
 // naTest is used to describe a test to be performed against the NetAddressKey
 // method.
 type naTest struct {
-	in   wire.NetAddress
+	in   wire.NetAddressV2
 	want string
 }
 
@@ -93,7 +93,7 @@ func addNaTests() {
 
 func addNaTest(ip string, port uint16, want string) {
 	nip := net.ParseIP(ip)
-	na := *wire.NewNetAddressIPPort(nip, port, wire.SFNodeNetwork)
+	na := *wire.NewNetAddressV2IPPort(nip, port, wire.SFNodeNetwork)
 	test := naTest{na, want}
 	naTests = append(naTests, test)
 }
@@ -157,37 +157,37 @@ func TestAddAddressByIP(t *testing.T) {
 
 func TestAddLocalAddress(t *testing.T) {
 	var tests = []struct {
-		address  wire.NetAddress
+		address  wire.NetAddressV2
 		priority addrmgr.AddressPriority
 		valid    bool
 	}{
 		{
-			wire.NetAddress{IP: net.ParseIP("192.168.0.100")},
+			*wire.NewNetAddressV2IPPort(net.ParseIP("192.168.0.100"), 0, 0),
 			addrmgr.InterfacePrio,
 			false,
 		},
 		{
-			wire.NetAddress{IP: net.ParseIP("204.124.1.1")},
+			*wire.NewNetAddressV2IPPort(net.ParseIP("204.124.1.1"), 0, 0),
 			addrmgr.InterfacePrio,
 			true,
 		},
 		{
-			wire.NetAddress{IP: net.ParseIP("204.124.1.1")},
+			*wire.NewNetAddressV2IPPort(net.ParseIP("204.124.1.1"), 0, 0),
 			addrmgr.BoundPrio,
 			true,
 		},
 		{
-			wire.NetAddress{IP: net.ParseIP("::1")},
+			*wire.NewNetAddressV2IPPort(net.ParseIP("::1"), 0, 0),
 			addrmgr.InterfacePrio,
 			false,
 		},
 		{
-			wire.NetAddress{IP: net.ParseIP("fe80::1")},
+			*wire.NewNetAddressV2IPPort(net.ParseIP("fe80::1"), 0, 0),
 			addrmgr.InterfacePrio,
 			false,
 		},
 		{
-			wire.NetAddress{IP: net.ParseIP("2620:100::1")},
+			*wire.NewNetAddressV2IPPort(net.ParseIP("2620:100::1"), 0, 0),
 			addrmgr.InterfacePrio,
 			true,
 		},
@@ -197,15 +197,46 @@ func TestAddLocalAddress(t *testing.T) {
 		result := amgr.AddLocalAddress(&test.address, test.priority)
 		if result == nil && !test.valid {
 			t.Errorf("TestAddLocalAddress test #%d failed: %s should have "+
-				"been accepted", x, test.address.IP)
+				"been accepted", x, test.address.IP())
 			continue
 		}
 		if result != nil && test.valid {
 			t.Errorf("TestAddLocalAddress test #%d failed: %s should not have "+
-				"been accepted", x, test.address.IP)
+				"been accepted", x, test.address.IP())
 			continue
 		}
 	}
+
+	// Adding an address again with a higher priority bumps its priority
+	// and the local addresses are returned ordered by their key.
+	amgr = addrmgr.New("testlocaladdresses", nil)
+	for _, ip := range []string{"2620:100::1", "204.124.1.1"} {
+		na := wire.NewNetAddressV2IPPort(net.ParseIP(ip), 8333, 0)
+		if err := amgr.AddLocalAddress(na, addrmgr.InterfacePrio); err != nil {
+			t.Fatalf("AddLocalAddress: unexpected error %v", err)
+		}
+	}
+	na := wire.NewNetAddressV2IPPort(net.ParseIP("204.124.1.1"), 8333, 0)
+	if err := amgr.AddLocalAddress(na, addrmgr.BoundPrio); err != nil {
+		t.Fatalf("AddLocalAddress: unexpected error %v", err)
+	}
+	localAddrs := amgr.LocalAddresses()
+	if len(localAddrs) != 2 {
+		t.Fatalf("LocalAddresses: got %d local addresses, want 2",
+			len(localAddrs))
+	}
+	if ip := localAddrs[0].NetAddress.IP(); ip.String() != "204.124.1.1" {
+		t.Errorf("LocalAddresses: got first local address %s, want "+
+			"204.124.1.1", ip)
+	}
+	if localAddrs[0].Priority != addrmgr.BoundPrio+1 {
+		t.Errorf("LocalAddresses: got priority %d, want %d",
+			localAddrs[0].Priority, addrmgr.BoundPrio+1)
+	}
+	if localAddrs[1].Priority != addrmgr.InterfacePrio {
+		t.Errorf("LocalAddresses: got priority %d, want %d",
+			localAddrs[1].Priority, addrmgr.InterfacePrio)
+	}
 }
 
 func TestAttempt(t *testing.T) {
@@ -257,7 +288,7 @@ func TestNeedMoreAddresses(t *testing.T) {
 	if !b {
 		t.Errorf("Expected that we need more addresses")
 	}
-	addrs := make([]*wire.NetAddress, addrsToAdd)
+	addrs := make([]*wire.NetAddressV2, addrsToAdd)
 
 	var err error
 	for i := 0; i < addrsToAdd; i++ {
@@ -268,7 +299,7 @@ func TestNeedMoreAddresses(t *testing.T) {
 		}
 	}
 
-	srcAddr := wire.NewNetAddressIPPort(net.IPv4(173, 144, 173, 111), 8688, 0)
+	srcAddr := wire.NewNetAddressV2IPPort(net.IPv4(173, 144, 173, 111), 8688, 0)
 
 	n.AddAddresses(addrs, srcAddr)
 	numAddrs := n.NumAddresses()
@@ -285,7 +316,7 @@ func TestNeedMoreAddresses(t *testing.T) {
 func TestGood(t *testing.T) {
 	n := addrmgr.New("testgood", lookupFunc)
 	addrsToAdd := 64 * 64
-	addrs := make([]*wire.NetAddress, addrsToAdd)
+	addrs := make([]*wire.NetAddressV2, addrsToAdd)
 
 	var err error
 	for i := 0; i < addrsToAdd; i++ {
@@ -296,7 +327,7 @@ func TestGood(t *testing.T) {
 		}
 	}
 
-	srcAddr := wire.NewNetAddressIPPort(net.IPv4(173, 144, 173, 111), 8688, 0)
+	srcAddr := wire.NewNetAddressV2IPPort(net.IPv4(173, 144, 173, 111), 8688, 0)
 
 	n.AddAddresses(addrs, srcAddr)
 	for _, addr := range addrs {
@@ -331,8 +362,8 @@ func TestGetAddress(t *testing.T) {
 	if ka == nil {
 		t.Fatalf("Did not get an address where there is one in the pool")
 	}
-	if ka.NetAddress().IP.String() != someIP {
-		t.Errorf("Wrong IP: got %v, want %v", ka.NetAddress().IP.String(), someIP)
+	if ka.NetAddress().IP().String() != someIP {
+		t.Errorf("Wrong IP: got %v, want %v", ka.NetAddress().IP().String(), someIP)
 	}
 
 	// Mark this as a good address and get it
@@ -341,8 +372,8 @@ func TestGetAddress(t *testing.T) {
 	if ka == nil {
 		t.Fatalf("Did not get an address where there is one in the pool")
 	}
-	if ka.NetAddress().IP.String() != someIP {
-		t.Errorf("Wrong IP: got %v, want %v", ka.NetAddress().IP.String(), someIP)
+	if ka.NetAddress().IP().String() != someIP {
+		t.Errorf("Wrong IP: got %v, want %v", ka.NetAddress().IP().String(), someIP)
 	}
 
 	numAddrs := n.NumAddresses()
@@ -351,16 +382,44 @@ func TestGetAddress(t *testing.T) {
 	}
 }
 
+func TestGetNewTableAddress(t *testing.T) {
+	n := addrmgr.New("testgetnewtableaddress", lookupFunc)
+
+	// Get an address from an empty set (should error)
+	if rv := n.GetNewTableAddress(); rv != nil {
+		t.Errorf("GetNewTableAddress failed: got: %v want: %v\n", rv, nil)
+	}
+
+	// Add a new address and get it
+	err := n.AddAddressByIP(someIP + ":8688")
+	if err != nil {
+		t.Fatalf("Adding address failed: %v", err)
+	}
+	ka := n.GetNewTableAddress()
+	if ka == nil {
+		t.Fatalf("Did not get an address where there is one in the new table")
+	}
+	if ka.NetAddress().IP().String() != someIP {
+		t.Errorf("Wrong IP: got %v, want %v", ka.NetAddress().IP().String(), someIP)
+	}
+
+	// Mark this as a good address, which moves it out of the new table
+	n.Good(ka.NetAddress())
+	if rv := n.GetNewTableAddress(); rv != nil {
+		t.Errorf("GetNewTableAddress failed: got: %v want: %v\n", rv, nil)
+	}
+}
+
 func TestGetBestLocalAddress(t *testing.T) {
-	localAddrs := []wire.NetAddress{
-		{IP: net.ParseIP("192.168.0.100")},
-		{IP: net.ParseIP("::1")},
-		{IP: net.ParseIP("fe80::1")},
-		{IP: net.ParseIP("2001:470::1")},
+	localAddrs := []*wire.NetAddressV2{
+		wire.NewNetAddressV2IPPort(net.ParseIP("192.168.0.100"), 0, 0),
+		wire.NewNetAddressV2IPPort(net.ParseIP("::1"), 0, 0),
+		wire.NewNetAddressV2IPPort(net.ParseIP("fe80::1"), 0, 0),
+		wire.NewNetAddressV2IPPort(net.ParseIP("2001:470::1"), 0, 0),
 	}
 
 	var tests = []struct {
-		remoteAddr wire.NetAddress
+		remoteAddr wire.NetAddressV2
 		want0      wire.NetAddress
 		want1      wire.NetAddress
 		want2      wire.NetAddress
@@ -368,7 +427,7 @@ func TestGetBestLocalAddress(t *testing.T) {
 	}{
 		{
 			// Remote connection from public IPv4
-			wire.NetAddress{IP: net.ParseIP("204.124.8.1")},
+			*wire.NewNetAddressV2IPPort(net.ParseIP("204.124.8.1"), 0, 0),
 			wire.NetAddress{IP: net.IPv4zero},
 			wire.NetAddress{IP: net.IPv4zero},
 			wire.NetAddress{IP: net.ParseIP("204.124.8.100")},
@@ -376,7 +435,7 @@ func TestGetBestLocalAddress(t *testing.T) {
 		},
 		{
 			// Remote connection from private IPv4
-			wire.NetAddress{IP: net.ParseIP("172.16.0.254")},
+			*wire.NewNetAddressV2IPPort(net.ParseIP("172.16.0.254"), 0, 0),
 			wire.NetAddress{IP: net.IPv4zero},
 			wire.NetAddress{IP: net.IPv4zero},
 			wire.NetAddress{IP: net.IPv4zero},
@@ -384,7 +443,7 @@ func TestGetBestLocalAddress(t *testing.T) {
 		},
 		{
 			// Remote connection from public IPv6
-			wire.NetAddress{IP: net.ParseIP("2602:100:abcd::102")},
+			*wire.NewNetAddressV2IPPort(net.ParseIP("2602:100:abcd::102"), 0, 0),
 			wire.NetAddress{IP: net.IPv6zero},
 			wire.NetAddress{IP: net.ParseIP("2001:470::1")},
 			wire.NetAddress{IP: net.ParseIP("2001:470::1")},
@@ -393,7 +452,7 @@ func TestGetBestLocalAddress(t *testing.T) {
 		/* XXX
 		{
 			// Remote connection from Tor
-			wire.NetAddress{IP: net.ParseIP("fd87:d87e:eb43::100")},
+			*wire.NewNetAddressV2IPPort(net.ParseIP("fd87:d87e:eb43::100"), 0, 0),
 			wire.NetAddress{IP: net.IPv4zero},
 			wire.NetAddress{IP: net.ParseIP("204.124.8.100")},
 			wire.NetAddress{IP: net.ParseIP("fd87:d87e:eb43:25::1")},
@@ -406,51 +465,51 @@ func TestGetBestLocalAddress(t *testing.T) {
 	// Test against default when there's no address
 	for x, test := range tests {
 		got := amgr.GetBestLocalAddress(&test.remoteAddr)
-		if !test.want0.IP.Equal(got.IP) {
+		if !test.want0.IP.Equal(got.IP()) {
 			t.Errorf("TestGetBestLocalAddress test1 #%d failed for remote address %s: want %s got %s",
-				x, test.remoteAddr.IP, test.want1.IP, got.IP)
+				x, test.remoteAddr.IP(), test.want1.IP, got.IP())
 			continue
 		}
 	}
 
 	for _, localAddr := range localAddrs {
-		amgr.AddLocalAddress(&localAddr, addrmgr.InterfacePrio)
+		amgr.AddLocalAddress(localAddr, addrmgr.InterfacePrio)
 	}
 
 	// Test against want1
 	for x, test := range tests {
 		got := amgr.GetBestLocalAddress(&test.remoteAddr)
-		if !test.want1.IP.Equal(got.IP) {
+		if !test.want1.IP.Equal(got.IP()) {
 			t.Errorf("TestGetBestLocalAddress test1 #%d failed for remote address %s: want %s got %s",
-				x, test.remoteAddr.IP, test.want1.IP, got.IP)
+				x, test.remoteAddr.IP(), test.want1.IP, got.IP())
 			continue
 		}
 	}
 
 	// Add a public IP to the list of local addresses.
-	localAddr := wire.NetAddress{IP: net.ParseIP("204.124.8.100")}
-	amgr.AddLocalAddress(&localAddr, addrmgr.InterfacePrio)
+	localAddr := wire.NewNetAddressV2IPPort(net.ParseIP("204.124.8.100"), 0, 0)
+	amgr.AddLocalAddress(localAddr, addrmgr.InterfacePrio)
 
 	// Test against want2
 	for x, test := range tests {
 		got := amgr.GetBestLocalAddress(&test.remoteAddr)
-		if !test.want2.IP.Equal(got.IP) {
+		if !test.want2.IP.Equal(got.IP()) {
 			t.Errorf("TestGetBestLocalAddress test2 #%d failed for remote address %s: want %s got %s",
-				x, test.remoteAddr.IP, test.want2.IP, got.IP)
+				x, test.remoteAddr.IP(), test.want2.IP, got.IP())
 			continue
 		}
 	}
 	/*
 		// Add a Tor generated IP address
-		localAddr = wire.NetAddress{IP: net.ParseIP("fd87:d87e:eb43:25::1")}
-		amgr.AddLocalAddress(&localAddr, addrmgr.ManualPrio)
+		localAddr = wire.NewNetAddressV2IPPort(net.ParseIP("fd87:d87e:eb43:25::1"), 0, 0)
+		amgr.AddLocalAddress(localAddr, addrmgr.ManualPrio)
 
 		// Test against want3
 		for x, test := range tests {
 			got := amgr.GetBestLocalAddress(&test.remoteAddr)
-			if !test.want3.IP.Equal(got.IP) {
+			if !test.want3.IP.Equal(got.IP()) {
 				t.Errorf("TestGetBestLocalAddress test3 #%d failed for remote address %s: want %s got %s",
-					x, test.remoteAddr.IP, test.want3.IP, got.IP)
+					x, test.remoteAddr.IP(), test.want3.IP, got.IP())
 				continue
 			}
 		}
@@ -470,3 +529,72 @@ func TestNetAddressKey(t *testing.T) {
 	}
 
 }
+
+// TestHostToNetAddress ensures hosts on the various supported networks are
+// converted to addresses on the expected network and back to the same host by
+// NetAddressKey.
+func TestHostToNetAddress(t *testing.T) {
+	tests := []struct {
+		name  string
+		host  string
+		netID wire.NetworkID
+		err   bool
+	}{{
+		name:  "ipv4",
+		host:  "173.194.115.66",
+		netID: wire.NetIPv4,
+	}, {
+		name:  "ipv6",
+		host:  "2001:470::1",
+		netID: wire.NetIPv6,
+	}, {
+		name:  "cjdns",
+		host:  "fc32:17ea:e415:c3bf:9808:149d:b5a2:c9aa",
+		netID: wire.NetCJDNS,
+	}, {
+		name:  "torv2 onioncat",
+		host:  "aaaaaaaaaaaaaaaa.onion",
+		netID: wire.NetIPv6,
+	}, {
+		name:  "torv3",
+		host:  "pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion",
+		netID: wire.NetTorV3,
+	}, {
+		name: "torv3 bad checksum",
+		host: "pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscrya.onion",
+		err:  true,
+	}, {
+		name:  "i2p",
+		host:  "ukeu3k5oycgaauneqgtnvselmt4yemvoilkln7jpvamvfx7dnkdq.b32.i2p",
+		netID: wire.NetI2P,
+	}, {
+		name: "unresolvable host",
+		host: "example.com",
+		err:  true,
+	}}
+
+	n := addrmgr.New("testhosttonetaddress", lookupFunc)
+	for _, test := range tests {
+		na, err := n.HostToNetAddress(test.host, 8688, wire.SFNodeNetwork)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if na.NetworkID != test.netID {
+			t.Errorf("%s: unexpected network - got %v, want %v",
+				test.name, na.NetworkID, test.netID)
+			continue
+		}
+		want := net.JoinHostPort(test.host, "8688")
+		if key := addrmgr.NetAddressKey(na); key != want {
+			t.Errorf("%s: unexpected key - got %s, want %s",
+				test.name, key, want)
+		}
+	}
+}
@@ -41,6 +41,7 @@ func (b *BlockChain) maybeAcceptBlock(block *bronutil.Block, flags BehaviorFlags
 	// position of the block within the block chain.
 	err := b.checkBlockContext(block, prevNode, flags)
 	if err != nil {
+		b.markBlockFailed(block.Hash(), err)
 		return false, err
 	}
 
@@ -60,14 +61,20 @@ func (b *BlockChain) maybeAcceptBlock(block *bronutil.Block, flags BehaviorFlags
 		return false, err
 	}
 
-	// Create a new block node for the block and add it to the node index. Even
-	// if the block ultimately gets connected to the main chain, it starts out
+	// Create a new block node for the block and add it to the node index
+	// unless its header was added ahead of the block data, in which case
+	// the node only needs to record that the data is now stored.  Even if
+	// the block ultimately gets connected to the main chain, it starts out
 	// on a side chain.
 	blockHeader := &block.MsgBlock().Header
-	newNode := newBlockNode(blockHeader, prevNode)
-	newNode.status = statusDataStored
-
-	b.index.AddNode(newNode)
+	newNode := b.index.LookupNode(block.Hash())
+	if newNode != nil {
+		b.index.SetStatusFlags(newNode, statusDataStored)
+	} else {
+		newNode = newBlockNode(blockHeader, prevNode)
+		newNode.status = statusDataStored
+		b.index.AddNode(newNode)
+	}
 	err = b.index.flushToDB()
 	if err != nil {
 		return false, err
@@ -78,6 +85,7 @@ func (b *BlockChain) maybeAcceptBlock(block *bronutil.Block, flags BehaviorFlags
 	// also handles validation of the transaction scripts.
 	isMainChain, err := b.connectBestChain(newNode, block, flags)
 	if err != nil {
+		b.markInvalidBestHeader(newNode)
 		return false, err
 	}
 
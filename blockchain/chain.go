@@ -101,6 +101,7 @@ type BlockChain struct {
 	sigCache            *txscript.SigCache
 	indexManager        IndexManager
 	hashCache           *txscript.HashCache
+	pruneTarget         uint64
 
 	// The following fields are calculated based upon the provided chain
 	// parameters.  They are also set when the instance is created and
@@ -126,6 +127,11 @@ type BlockChain struct {
 	index     *blockIndex
 	bestChain *chainView
 
+	// utxoCache houses the recently used and modified entries of the utxo
+	// set in memory and batches the writes of the modifications to the
+	// database.
+	utxoCache *utxoCache
+
 	// These fields are related to handling of orphan blocks.  They are
 	// protected by a combination of the chain lock and the orphan lock.
 	orphanLock   sync.RWMutex
@@ -138,6 +144,21 @@ type BlockChain struct {
 	nextCheckpoint *chaincfg.Checkpoint
 	checkpointNode *blockNode
 
+	// preciousNode is the block most recently passed to PreciousBlock.  It
+	// is preferred over competing blocks with the same amount of work when
+	// selecting the best chain.  It is protected by the chain lock.
+	preciousNode *blockNode
+
+	// prunedHeight is the height of the most recent main chain block whose
+	// data has been removed by pruning, or -1 when no blocks have been
+	// pruned.  It is protected by the chain lock.
+	prunedHeight int32
+
+	// bestHeader is the header with the most cumulative work added to the
+	// block index, whether or not the data of its block is available.  It
+	// is protected by the chain lock.
+	bestHeader *blockNode
+
 	// The state is used as a fairly efficient way to cache information
 	// about the current best chain state that is returned to callers when
 	// requested.  It operates on the principle of MVCC such that any time a
@@ -607,6 +628,13 @@ func (b *BlockChain) connectBlock(node *blockNode, block *bronutil.Block,
 	state := newBestState(node, blockSize, blockWeight, numTxns,
 		curTotalTxns+numTxns, node.CalcPastMedianTime())
 
+	// Determine whether the utxo cache needs to be flushed.  The flush is
+	// done in the same database transaction as the best state so the utxo
+	// set in the database is always consistent with a block in the main
+	// chain.  The changes in the view are only applied to the cache once
+	// the transaction succeeded, so they are written along with it.
+	flushUtxos := b.utxoCache.needsFlush(flushPeriodic)
+
 	// Atomically insert info into the database.
 	err = b.db.Update(func(dbTx database.Tx) error {
 		// Update best block state.
@@ -624,10 +652,13 @@ func (b *BlockChain) connectBlock(node *blockNode, block *bronutil.Block,
 
 		// Update the utxo set using the state of the utxo view.  This
 		// entails removing all of the utxos spent and adding the new
-		// ones created by the block.
-		err = dbPutUtxoView(dbTx, view)
-		if err != nil {
-			return err
+		// ones created by the block, which only happens in the cache
+		// until it is flushed.
+		if flushUtxos {
+			err = b.utxoCache.writeEntries(dbTx, view, &node.hash)
+			if err != nil {
+				return err
+			}
 		}
 
 		// Update the transaction spend journal by adding a record for
@@ -652,6 +683,10 @@ func (b *BlockChain) connectBlock(node *blockNode, block *bronutil.Block,
 	if err != nil {
 		return err
 	}
+	b.utxoCache.commitView(view, freshOutputsBlock(node, block))
+	if flushUtxos {
+		b.utxoCache.markFlushed(&node.hash)
+	}
 
 	// Prune fully spent entries and mark all entries in the view unmodified
 	// now that the modifications have been committed to the database.
@@ -669,6 +704,14 @@ func (b *BlockChain) connectBlock(node *blockNode, block *bronutil.Block,
 	b.stateSnapshot = state
 	b.stateLock.Unlock()
 
+	// Remove the data for the oldest blocks when pruning is enabled and the
+	// stored blocks exceed the target size.  The block has already been
+	// connected at this point, so failures are only logged since the
+	// pruning will simply be attempted again with the next block.
+	if err := b.maybePruneBlocks(); err != nil {
+		log.Errorf("Unable to prune blocks: %v", err)
+	}
+
 	// Notify the caller that the block was connected to the main chain.
 	// The caller would typically want to react with actions such as
 	// updating wallets.
@@ -720,6 +763,10 @@ func (b *BlockChain) disconnectBlock(node *blockNode, block *bronutil.Block, vie
 	state := newBestState(prevNode, blockSize, blockWeight, numTxns,
 		newTotalTxns, prevNode.CalcPastMedianTime())
 
+	// The utxo cache is always flushed along with the changes in the view
+	// when disconnecting since the spend journal entry needed to undo the
+	// block is removed below, so the utxo set could not otherwise be
+	// rebuilt after an unclean shutdown.
 	err = b.db.Update(func(dbTx database.Tx) error {
 		// Update best block state.
 		err := dbPutBestState(dbTx, state, node.workSum)
@@ -737,7 +784,7 @@ func (b *BlockChain) disconnectBlock(node *blockNode, block *bronutil.Block, vie
 		// Update the utxo set using the state of the utxo view.  This
 		// entails restoring all of the utxos spent and removing the new
 		// ones created by the block.
-		err = dbPutUtxoView(dbTx, view)
+		err = b.utxoCache.writeEntries(dbTx, view, &prevNode.hash)
 		if err != nil {
 			return err
 		}
@@ -771,6 +818,8 @@ func (b *BlockChain) disconnectBlock(node *blockNode, block *bronutil.Block, vie
 	if err != nil {
 		return err
 	}
+	b.utxoCache.commitView(view, nil)
+	b.utxoCache.markFlushed(&prevNode.hash)
 
 	// Prune fully spent entries and mark all entries in the view unmodified
 	// now that the modifications have been committed to the database.
@@ -848,6 +897,16 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List) error
 		}
 	}
 
+	// Flush the utxo cache before disconnecting any blocks since resurrecting
+	// outputs from legacy spend journal entries relies on the utxo set in the
+	// database being current.
+	if detachNodes.Len() != 0 {
+		err := b.utxoCache.flush(flushRequired, &tip.hash)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Track the old and new best chains heads.
 	oldBest := tip
 	newBest := tip
@@ -886,7 +945,7 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List) error
 
 		// Load all of the utxos referenced by the block that aren't
 		// already in the view.
-		err = view.fetchInputUtxos(b.db, block)
+		err = view.fetchInputUtxos(b.utxoCache, block)
 		if err != nil {
 			return err
 		}
@@ -953,7 +1012,7 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List) error
 		// checkConnectBlock gets skipped, we still need to update the UTXO
 		// view.
 		if b.index.NodeStatus(n).KnownValid() {
-			err = view.fetchInputUtxos(b.db, block)
+			err = view.fetchInputUtxos(b.utxoCache, block)
 			if err != nil {
 				return err
 			}
@@ -1005,7 +1064,7 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List) error
 
 		// Load all of the utxos referenced by the block that aren't
 		// already in the view.
-		err := view.fetchInputUtxos(b.db, block)
+		err := view.fetchInputUtxos(b.utxoCache, block)
 		if err != nil {
 			return err
 		}
@@ -1032,7 +1091,7 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List) error
 
 		// Load all of the utxos referenced by the block that aren't
 		// already in the view.
-		err := view.fetchInputUtxos(b.db, block)
+		err := view.fetchInputUtxos(b.utxoCache, block)
 		if err != nil {
 			return err
 		}
@@ -1131,7 +1190,7 @@ func (b *BlockChain) connectBestChain(node *blockNode, block *bronutil.Block, fl
 		// utxos, spend them, and add the new utxos being created by
 		// this block.
 		if fastAdd {
-			err := view.fetchInputUtxos(b.db, block)
+			err := view.fetchInputUtxos(b.utxoCache, block)
 			if err != nil {
 				return false, err
 			}
@@ -1219,6 +1278,7 @@ func (b *BlockChain) connectBestChain(node *blockNode, block *bronutil.Block, fl
 // factors are used to guess, but the key factors that allow the chain to
 // believe it is current are:
 //  - Latest block height is after the latest checkpoint (if enabled)
+//  - Best chain has at least the minimum chain work of the network (if set)
 //  - Latest block has a timestamp newer than 24 hours ago
 //
 // This function MUST be called with the chain state lock held (for reads).
@@ -1230,6 +1290,14 @@ func (b *BlockChain) isCurrent() bool {
 		return false
 	}
 
+	// Not current if the best chain does not have the minimum amount of
+	// work the network is known to have, which prevents leaving the
+	// initial block download on a low-work chain.
+	minChainWork := b.chainParams.MinimumChainWork
+	if minChainWork != nil && b.bestChain.Tip().workSum.Cmp(minChainWork) < 0 {
+		return false
+	}
+
 	// Not current if the latest best block has a timestamp before 24 hours
 	// ago.
 	//
@@ -1243,6 +1311,7 @@ func (b *BlockChain) isCurrent() bool {
 // factors are used to guess, but the key factors that allow the chain to
 // believe it is current are:
 //  - Latest block height is after the latest checkpoint (if enabled)
+//  - Best chain has at least the minimum chain work of the network (if set)
 //  - Latest block has a timestamp newer than 24 hours ago
 //
 // This function is safe for concurrent access.
@@ -1328,6 +1397,21 @@ func (b *BlockChain) BlockHeightByHash(hash *chainhash.Hash) (int32, error) {
 	return node.height, nil
 }
 
+// MedianTimeByHash returns the median time of the block with the given hash
+// in the main chain, which is the median timestamp of it and the blocks
+// preceding it as used to validate the timestamp of the next block.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) MedianTimeByHash(hash *chainhash.Hash) (time.Time, error) {
+	node := b.index.LookupNode(hash)
+	if node == nil || !b.bestChain.Contains(node) {
+		str := fmt.Sprintf("block %s is not in the main chain", hash)
+		return time.Time{}, errNotInMainChain(str)
+	}
+
+	return node.CalcPastMedianTime(), nil
+}
+
 // BlockHashByHeight returns the hash of the block at the given height in the
 // main chain.
 //
@@ -1711,6 +1795,21 @@ type Config struct {
 	// This field can be nil if the caller is not interested in using a
 	// signature cache.
 	HashCache *txscript.HashCache
+
+	// Prune specifies the target size in bytes for the stored block data.
+	// Once it is exceeded, the data for the oldest blocks is deleted from
+	// the database, with the exception of the most recent blocks which are
+	// always kept in order to handle reorganizations and serve them to
+	// peers.
+	//
+	// This field can be zero to keep all blocks.
+	Prune uint64
+
+	// UtxoCacheMaxSize specifies the maximum size in bytes the utxo cache
+	// is allowed to grow to before it is flushed to the database.
+	//
+	// This field can be zero to flush the cache after every block.
+	UtxoCacheMaxSize uint64
 }
 
 // New returns a BlockChain instance using the provided configuration details.
@@ -1761,11 +1860,14 @@ func New(config *Config) (*BlockChain, error) {
 		blocksPerRetarget:   int32(targetTimespan / targetTimePerBlock),
 		index:               newBlockIndex(config.DB, params),
 		hashCache:           config.HashCache,
+		pruneTarget:         config.Prune,
 		bestChain:           newChainView(nil),
+		utxoCache:           newUtxoCache(config.DB, config.UtxoCacheMaxSize),
 		orphans:             make(map[chainhash.Hash]*orphanBlock),
 		prevOrphans:         make(map[chainhash.Hash][]*orphanBlock),
 		warningCaches:       newThresholdCaches(vbNumBits),
 		deploymentCaches:    newThresholdCaches(chaincfg.DefinedDeployments),
+		prunedHeight:        -1,
 	}
 
 	// Initialize the chain state from the passed database.  When the db
@@ -1775,11 +1877,26 @@ func New(config *Config) (*BlockChain, error) {
 		return nil, err
 	}
 
+	// The data of the oldest blocks of a pruned database is gone, so it
+	// can neither be served to peers nor used to build the indexes which
+	// need every block.  Such a database may only be used with pruning.
+	if b.prunedHeight != -1 && config.Prune == 0 {
+		return nil, fmt.Errorf("the block data of the database has been "+
+			"pruned through height %d, so pruning must be enabled",
+			b.prunedHeight)
+	}
+
 	// Perform any upgrades to the various chain-specific buckets as needed.
 	if err := b.maybeUpgradeDbBuckets(config.Interrupt); err != nil {
 		return nil, err
 	}
 
+	// Make sure the utxo set is consistent with the best chain, which
+	// might not be the case after an unclean shutdown.
+	if err := b.initUtxoCache(config.Interrupt); err != nil {
+		return nil, err
+	}
+
 	// Initialize and catch up all of the currently active optional indexes
 	// as needed.
 	if config.IndexManager != nil {
@@ -11,6 +11,7 @@ import (
 
 	"github.com/brsuite/brond/chaincfg"
 	"github.com/brsuite/brond/chaincfg/chainhash"
+	"github.com/brsuite/brond/database"
 	"github.com/brsuite/brond/wire"
 	"github.com/brsuite/bronutil"
 )
@@ -964,3 +965,39 @@ func TestIntervalBlockHashes(t *testing.T) {
 		}
 	}
 }
+
+// TestNewPrunedDatabase ensures a chain instance can only be created for a
+// database whose block data has been pruned when pruning is enabled.
+func TestNewPrunedDatabase(t *testing.T) {
+	chain, teardownFunc, err := chainSetup("newpruneddatabase",
+		&chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatalf("Failed to setup chain instance: %v", err)
+	}
+	defer teardownFunc()
+
+	err = chain.db.Update(func(dbTx database.Tx) error {
+		return dbPutPrunedHeight(dbTx, 10)
+	})
+	if err != nil {
+		t.Fatalf("dbPutPrunedHeight: unexpected error %v", err)
+	}
+
+	config := Config{
+		DB:          chain.db,
+		ChainParams: chain.chainParams,
+		TimeSource:  NewMedianTime(),
+	}
+	if _, err := New(&config); err == nil {
+		t.Fatal("New: no error for pruned database without pruning")
+	}
+	config.Prune = 1
+	pruned, err := New(&config)
+	if err != nil {
+		t.Fatalf("New: unexpected error %v", err)
+	}
+	if !pruned.IsPruned() || pruned.PrunedHeight() != 10 {
+		t.Fatalf("New: got pruned height %d, want 10",
+			pruned.PrunedHeight())
+	}
+}
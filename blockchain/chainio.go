@@ -6,6 +6,7 @@ package blockchain
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
 	"fmt"
 	"math/big"
@@ -51,6 +52,15 @@ var (
 	// chain state.
 	chainStateKeyName = []byte("chainstate")
 
+	// prunedHeightKeyName is the name of the db key used to store the
+	// height of the most recent main chain block whose data was pruned.
+	prunedHeightKeyName = []byte("prunedheight")
+
+	// utxoStateConsistencyKeyName is the name of the db key used to store
+	// the hash of the block the utxo set in the database is consistent
+	// with.
+	utxoStateConsistencyKeyName = []byte("utxostateconsistency")
+
 	// spendJournalVersionKeyName is the name of the db key used to store
 	// the version of the spend journal currently in the database.
 	spendJournalVersionKeyName = []byte("spendjournalversion")
@@ -766,13 +776,13 @@ func dbFetchUtxoEntry(dbTx database.Tx, outpoint wire.OutPoint) (*UtxoEntry, err
 	return entry, nil
 }
 
-// dbPutUtxoView uses an existing database transaction to update the utxo set
-// in the database based on the provided utxo view contents and state.  In
+// dbPutUtxoEntries uses an existing database transaction to update the utxo
+// set in the database based on the provided utxo entries and their state.  In
 // particular, only the entries that have been marked as modified are written
 // to the database.
-func dbPutUtxoView(dbTx database.Tx, view *UtxoViewpoint) error {
+func dbPutUtxoEntries(dbTx database.Tx, entries map[wire.OutPoint]*UtxoEntry) error {
 	utxoBucket := dbTx.Metadata().Bucket(utxoSetBucketName)
-	for outpoint, entry := range view.entries {
+	for outpoint, entry := range entries {
 		// No need to update the database if the entry was not modified.
 		if entry == nil || !entry.isModified() {
 			continue
@@ -809,6 +819,145 @@ func dbPutUtxoView(dbTx database.Tx, view *UtxoViewpoint) error {
 	return nil
 }
 
+// UtxoStats describes the unspent transaction output set as of a block in the
+// main chain.
+type UtxoStats struct {
+	// Hash and Height identify the block the utxo set is consistent with.
+	Hash   chainhash.Hash
+	Height int32
+
+	// Transactions is the number of transactions with unspent outputs and
+	// TxOuts is the number of unspent outputs.
+	Transactions int64
+	TxOuts       int64
+
+	// BogoSize is an estimate of the size of the utxo set which does not
+	// depend on how it is stored, while DiskSize is the number of bytes
+	// of serialized keys and entries in the database.
+	BogoSize int64
+	DiskSize int64
+
+	// TotalAmount is the total amount of all unspent outputs in Bronees.
+	TotalAmount int64
+
+	// SerializedHash commits to the entire utxo set.  It is the double
+	// SHA256 of every unspent output serialized in outpoint order, so any
+	// two nodes with the same utxo set produce the same hash.
+	SerializedHash chainhash.Hash
+}
+
+// dbFetchUtxoStats uses an existing database transaction to walk the entire
+// utxo set and tally the passed stats.  The walk stops early with
+// errInterruptRequested when the interrupt channel is closed.
+//
+// Each output contributes the following to the serialized hash:
+//
+//	Field          Type             Size
+//	txid           chainhash.Hash   chainhash.HashSize
+//	index          uint32           4 bytes
+//	header code    uint32           4 bytes
+//	amount         int64            8 bytes
+//	pkscript       []byte           variable, prefixed with its VarInt length
+//
+// The header code is the height of the block containing the output shifted
+// over one bit with the coinbase flag in the lowest bit, the same as in the
+// serialized utxo entries.  All integers are little endian.
+func dbFetchUtxoStats(dbTx database.Tx, stats *UtxoStats, interrupt <-chan struct{}) error {
+	hasher := sha256.New()
+	var buf [8]byte
+	var prevHash []byte
+	cursor := dbTx.Metadata().Bucket(utxoSetBucketName).Cursor()
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		if interruptRequested(interrupt) {
+			return errInterruptRequested
+		}
+
+		// Since the keys are serialized as <hash><index> with an MSB
+		// encoded index, the outputs of each transaction are next to
+		// each other and in order.
+		key, serialized := cursor.Key(), cursor.Value()
+		if len(key) <= chainhash.HashSize {
+			return database.Error{
+				ErrorCode: database.ErrCorruption,
+				Description: fmt.Sprintf("corrupt utxo key %x",
+					key),
+			}
+		}
+		index, _ := deserializeVLQ(key[chainhash.HashSize:])
+		entry, err := deserializeUtxoEntry(serialized)
+		if err != nil {
+			if isDeserializeErr(err) {
+				return database.Error{
+					ErrorCode: database.ErrCorruption,
+					Description: fmt.Sprintf("corrupt utxo "+
+						"entry for key %x: %v", key, err),
+				}
+			}
+			return err
+		}
+		headerCode, err := utxoEntryHeaderCode(entry)
+		if err != nil {
+			return err
+		}
+
+		txHash := key[:chainhash.HashSize]
+		if !bytes.Equal(txHash, prevHash) {
+			stats.Transactions++
+			prevHash = append(prevHash[:0], txHash...)
+		}
+		stats.TxOuts++
+		stats.BogoSize += 50 + int64(len(entry.PkScript()))
+		stats.DiskSize += int64(len(key) + len(serialized))
+		stats.TotalAmount += entry.Amount()
+
+		hasher.Write(txHash)
+		byteOrder.PutUint32(buf[:4], uint32(index))
+		hasher.Write(buf[:4])
+		byteOrder.PutUint32(buf[:4], uint32(headerCode))
+		hasher.Write(buf[:4])
+		byteOrder.PutUint64(buf[:], uint64(entry.Amount()))
+		hasher.Write(buf[:])
+		err = wire.WriteVarBytes(hasher, 0, entry.PkScript())
+		if err != nil {
+			return err
+		}
+	}
+
+	stats.SerializedHash = chainhash.HashH(hasher.Sum(nil))
+	return nil
+}
+
+// FetchUtxoStats walks the entire utxo set to describe it as of the current
+// best block.  The walk can take a long time, so it stops early with an error
+// when the interrupt channel is closed.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) FetchUtxoStats(interrupt <-chan struct{}) (*UtxoStats, error) {
+	// The utxo set in the database only matches the best chain once the
+	// cache has been flushed.  The chain lock is released as soon as the
+	// database transaction is open since the transaction is a snapshot, so
+	// blocks can be connected while the set is being walked.
+	var unlock sync.Once
+	b.chainLock.Lock()
+	defer unlock.Do(b.chainLock.Unlock)
+
+	tip := b.bestChain.Tip()
+	if err := b.utxoCache.flush(flushRequired, &tip.hash); err != nil {
+		return nil, err
+	}
+
+	stats := &UtxoStats{Hash: tip.hash, Height: tip.height}
+	err := b.db.View(func(dbTx database.Tx) error {
+		unlock.Do(b.chainLock.Unlock)
+		return dbFetchUtxoStats(dbTx, stats, interrupt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
 // -----------------------------------------------------------------------------
 // The block index consists of two buckets with an entry for every block in the
 // main chain.  One bucket is for the hash to height mapping and the other is
@@ -999,6 +1148,59 @@ func dbPutBestState(dbTx database.Tx, snapshot *BestState, workSum *big.Int) err
 	return dbTx.Metadata().Put(chainStateKeyName, serializedData)
 }
 
+// dbPutPrunedHeight uses an existing database transaction to update the height
+// of the most recent main chain block whose data has been pruned.
+func dbPutPrunedHeight(dbTx database.Tx, height int32) error {
+	var serialized [4]byte
+	byteOrder.PutUint32(serialized[:], uint32(height))
+	return dbTx.Metadata().Put(prunedHeightKeyName, serialized[:])
+}
+
+// dbFetchPrunedHeight uses an existing database transaction to fetch the
+// height of the most recent main chain block whose data has been pruned.  It
+// returns -1 when no blocks have been pruned.
+func dbFetchPrunedHeight(dbTx database.Tx) (int32, error) {
+	serialized := dbTx.Metadata().Get(prunedHeightKeyName)
+	if serialized == nil {
+		return -1, nil
+	}
+	if len(serialized) != 4 {
+		return 0, database.Error{
+			ErrorCode:   database.ErrCorruption,
+			Description: "corrupt pruned height",
+		}
+	}
+
+	return int32(byteOrder.Uint32(serialized)), nil
+}
+
+// dbPutUtxoStateConsistency uses an existing database transaction to update
+// the hash of the block the utxo set in the database is consistent with.
+func dbPutUtxoStateConsistency(dbTx database.Tx, hash *chainhash.Hash) error {
+	return dbTx.Metadata().Put(utxoStateConsistencyKeyName, hash[:])
+}
+
+// dbFetchUtxoStateConsistency uses an existing database transaction to fetch
+// the hash of the block the utxo set in the database is consistent with.  It
+// returns nil when the database does not contain the hash, which is the case
+// for databases created before the utxo cache existed.
+func dbFetchUtxoStateConsistency(dbTx database.Tx) (*chainhash.Hash, error) {
+	serialized := dbTx.Metadata().Get(utxoStateConsistencyKeyName)
+	if serialized == nil {
+		return nil, nil
+	}
+
+	hash, err := chainhash.NewHash(serialized)
+	if err != nil {
+		return nil, database.Error{
+			ErrorCode:   database.ErrCorruption,
+			Description: "corrupt utxo state consistency hash",
+		}
+	}
+
+	return hash, nil
+}
+
 // createChainState initializes both the database and the chain state to the
 // genesis block.  This includes creating the necessary buckets and inserting
 // the genesis block, so it must only be called on an uninitialized database.
@@ -1198,6 +1400,14 @@ func (b *BlockChain) initChainState() error {
 			node.status = status
 			b.index.addNode(node)
 
+			// Keep track of the header with the most work, which
+			// may be ahead of the stored block data.
+			if !status.KnownInvalid() && (b.bestHeader == nil ||
+				node.workSum.Cmp(b.bestHeader.workSum) > 0) {
+
+				b.bestHeader = node
+			}
+
 			lastNode = node
 			i++
 		}
@@ -1240,6 +1450,12 @@ func (b *BlockChain) initChainState() error {
 			}
 		}
 
+		// Load the height through which block data has been pruned.
+		b.prunedHeight, err = dbFetchPrunedHeight(dbTx)
+		if err != nil {
+			return err
+		}
+
 		// Initialize the state related to the best block.
 		blockSize := uint64(len(blockBytes))
 		blockWeight := uint64(GetBlockWeight(bronutil.NewBlock(&block)))
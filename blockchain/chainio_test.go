@@ -11,8 +11,11 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/brsuite/brond/chaincfg"
 	"github.com/brsuite/brond/database"
+	"github.com/brsuite/brond/txscript"
 	"github.com/brsuite/brond/wire"
+	"github.com/brsuite/bronutil"
 )
 
 // TestErrNotInMainChain ensures the functions related to errNotInMainChain work
@@ -604,6 +607,74 @@ func TestUtxoEntryDeserializeErrors(t *testing.T) {
 	}
 }
 
+// TestFetchUtxoStats ensures the utxo set stats include the outputs which are
+// only held in the utxo cache and that the serialized hash only depends on the
+// contents of the utxo set.
+func TestFetchUtxoStats(t *testing.T) {
+	// newTx returns a transaction with outputs paying the passed amounts.
+	newTx := func(amounts ...int64) *bronutil.Tx {
+		msgTx := wire.NewMsgTx(wire.TxVersion)
+		msgTx.AddTxIn(&wire.TxIn{})
+		for _, amount := range amounts {
+			msgTx.AddTxOut(wire.NewTxOut(amount,
+				[]byte{txscript.OP_TRUE}))
+		}
+		return bronutil.NewTx(msgTx)
+	}
+	txns := []*bronutil.Tx{newTx(1000, 2000), newTx(3000)}
+
+	// fetchStats adds the outputs of the passed transactions to the utxo
+	// cache of a new chain in the passed order and returns its stats.
+	fetchStats := func(dbName string, txns ...*bronutil.Tx) *UtxoStats {
+		chain, teardownFunc, err := chainSetup(dbName,
+			&chaincfg.RegressionNetParams)
+		if err != nil {
+			t.Fatalf("Failed to setup chain instance: %v", err)
+		}
+		defer teardownFunc()
+
+		for _, tx := range txns {
+			view := NewUtxoViewpoint()
+			view.AddTxOuts(tx, 1)
+			chain.utxoCache.commitView(view, nil)
+		}
+		stats, err := chain.FetchUtxoStats(nil)
+		if err != nil {
+			t.Fatalf("FetchUtxoStats: unexpected error: %v", err)
+		}
+		return stats
+	}
+
+	stats := fetchStats("utxostats", txns[0], txns[1])
+	want := &UtxoStats{
+		Hash:           *chaincfg.RegressionNetParams.GenesisHash,
+		Transactions:   2,
+		TxOuts:         3,
+		BogoSize:       3 * 51,
+		TotalAmount:    6000,
+		SerializedHash: stats.SerializedHash,
+		DiskSize:       stats.DiskSize,
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Fatalf("FetchUtxoStats: wrong stats - got %+v, want %+v",
+			stats, want)
+	}
+
+	// Adding the same outputs in a different order must produce the same
+	// serialized hash, while a different utxo set must not.
+	reordered := fetchStats("utxostatsreordered", txns[1], txns[0])
+	if reordered.SerializedHash != stats.SerializedHash {
+		t.Fatalf("FetchUtxoStats: serialized hash %v depends on the "+
+			"order outputs were added, want %v",
+			reordered.SerializedHash, stats.SerializedHash)
+	}
+	partial := fetchStats("utxostatspartial", txns[0])
+	if partial.SerializedHash == stats.SerializedHash {
+		t.Fatal("FetchUtxoStats: serialized hash does not commit to " +
+			"all outputs")
+	}
+}
+
 // TestBestChainStateSerialization ensures serializing and deserializing the
 // best chain state works as expected.
 func TestBestChainStateSerialization(t *testing.T) {
@@ -0,0 +1,130 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"sort"
+
+	"github.com/brsuite/brond/chaincfg/chainhash"
+)
+
+// ChainTipStatus describes the state of the branch of the block tree that ends
+// in a chain tip.
+type ChainTipStatus byte
+
+// These constants define the possible chain tip states.
+const (
+	// TipActive indicates the tip is the end of the main chain.
+	TipActive ChainTipStatus = iota
+
+	// TipValidFork indicates the tip is on a side chain that has been fully
+	// validated but is not part of the main chain.
+	TipValidFork
+
+	// TipValidHeaders indicates the data for all blocks on the branch is
+	// available, but the tip has not been fully validated.
+	TipValidHeaders
+
+	// TipHeadersOnly indicates the data for one or more blocks on the branch
+	// is not available.
+	TipHeadersOnly
+
+	// TipInvalid indicates the tip or one of its ancestors is invalid.
+	TipInvalid
+)
+
+// Map of chain tip states back to their constant names for pretty printing.
+var chainTipStatusStrings = map[ChainTipStatus]string{
+	TipActive:       "active",
+	TipValidFork:    "valid-fork",
+	TipValidHeaders: "valid-headers",
+	TipHeadersOnly:  "headers-only",
+	TipInvalid:      "invalid",
+}
+
+// String returns the ChainTipStatus in human-readable form.
+func (s ChainTipStatus) String() string {
+	if str, ok := chainTipStatusStrings[s]; ok {
+		return str
+	}
+
+	return "unknown"
+}
+
+// ChainTip describes a block in the block index that does not have any
+// children, along with the length of the branch that connects it to the main
+// chain.
+type ChainTip struct {
+	Height    int32
+	Hash      chainhash.Hash
+	BranchLen int32
+	Status    ChainTipStatus
+}
+
+// ChainTips returns all of the tips of the block tree known to the block index,
+// which includes the end of the main chain as well as the ends of all side
+// chains.  The tips are ordered by descending height.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ChainTips() []ChainTip {
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+
+	// Find all nodes that are not the parent of any other node.  The end
+	// of the main chain is always included even when it has children
+	// since those can only be blocks which could not be connected.
+	bestTip := b.bestChain.Tip()
+	b.index.RLock()
+	hasChildren := make(map[*blockNode]struct{})
+	for _, node := range b.index.index {
+		if node.parent != nil {
+			hasChildren[node.parent] = struct{}{}
+		}
+	}
+	var tipNodes []*blockNode
+	for _, node := range b.index.index {
+		if _, ok := hasChildren[node]; !ok || node == bestTip {
+			tipNodes = append(tipNodes, node)
+		}
+	}
+
+	tips := make([]ChainTip, 0, len(tipNodes))
+	for _, node := range tipNodes {
+		fork := b.bestChain.FindFork(node)
+		tip := ChainTip{
+			Height:    node.height,
+			Hash:      node.hash,
+			BranchLen: node.height - fork.height,
+		}
+
+		switch {
+		case node == bestTip:
+			tip.Status = TipActive
+
+		case node.status.KnownInvalid():
+			tip.Status = TipInvalid
+
+		default:
+			tip.Status = TipValidHeaders
+			if node.status.KnownValid() {
+				tip.Status = TipValidFork
+			}
+			for n := node; n != fork; n = n.parent {
+				if !n.status.HaveData() {
+					tip.Status = TipHeadersOnly
+					break
+				}
+			}
+		}
+
+		tips = append(tips, tip)
+	}
+	b.index.RUnlock()
+
+	sort.Slice(tips, func(i, j int) bool {
+		return tips[i].Height > tips[j].Height
+	})
+	return tips
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brsuite/brond/chaincfg"
+)
+
+// TestChainTips ensures the chain tips reported for a block tree with several
+// branches in various states have the expected branch lengths and statuses,
+// and that the best chain candidate honors invalid blocks, missing block data
+// and blocks marked precious.
+func TestChainTips(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	chain := newFakeChain(params)
+	genesis := chain.bestChain.Genesis()
+	genesis.status = statusDataStored | statusValid
+
+	// fakeBranch adds a branch of the given length with the given status
+	// extending the passed parent to the block index.  The timestamps are
+	// offset by the passed value so branches sharing a parent differ.
+	fakeBranch := func(parent *blockNode, numNodes int, offset time.Duration,
+		status blockStatus) []*blockNode {
+
+		nodes := make([]*blockNode, 0, numNodes)
+		for i := 0; i < numNodes; i++ {
+			timestamp := time.Unix(parent.timestamp, 0).Add(
+				time.Minute*10 + offset)
+			node := newFakeNode(parent, 1, params.PowLimitBits, timestamp)
+			node.status = status
+			chain.index.AddNode(node)
+			nodes = append(nodes, node)
+			parent = node
+		}
+		return nodes
+	}
+
+	// Create a tree of blocks that looks like the following where the
+	// main chain is the top branch:
+	//
+	//   genesis -> 1 -> 2 -> 3 -> 4 -> 5
+	//                     |    \-> 4a -> 5a -> 6a   (valid headers)
+	//                     |-> 3b                    (invalid)
+	//                     \-> 3c -> 4c              (4c data missing)
+	//                          \-> 4d               (valid fork)
+	haveValid := statusDataStored | statusValid
+	mainNodes := fakeBranch(genesis, 5, 0, haveValid)
+	chain.bestChain.SetTip(tstTip(mainNodes))
+	branchA := fakeBranch(mainNodes[2], 3, time.Second, statusDataStored)
+	branchB := fakeBranch(mainNodes[1], 1, 2*time.Second,
+		statusDataStored|statusValidateFailed)
+	branchC := fakeBranch(mainNodes[1], 1, 3*time.Second, haveValid)
+	branchC = append(branchC, fakeBranch(branchC[0], 1, 0, statusNone)...)
+	branchD := fakeBranch(branchC[0], 1, 4*time.Second, haveValid)
+
+	tests := []struct {
+		node      *blockNode
+		branchLen int32
+		status    ChainTipStatus
+	}{
+		{tstTip(branchA), 3, TipValidHeaders},
+		{tstTip(mainNodes), 0, TipActive},
+		{tstTip(branchC), 2, TipHeadersOnly},
+		{tstTip(branchD), 2, TipValidFork},
+		{tstTip(branchB), 1, TipInvalid},
+	}
+
+	tips := chain.ChainTips()
+	if len(tips) != len(tests) {
+		t.Fatalf("ChainTips: unexpected number of tips -- got %d, "+
+			"want %d", len(tips), len(tests))
+	}
+	for _, test := range tests {
+		var found bool
+		for _, tip := range tips {
+			if tip.Hash != test.node.hash {
+				continue
+			}
+			found = true
+
+			if tip.Height != test.node.height {
+				t.Errorf("ChainTips: unexpected height for %v -- "+
+					"got %d, want %d", tip.Hash, tip.Height,
+					test.node.height)
+			}
+			if tip.BranchLen != test.branchLen {
+				t.Errorf("ChainTips: unexpected branch length "+
+					"for %v -- got %d, want %d", tip.Hash,
+					tip.BranchLen, test.branchLen)
+			}
+			if tip.Status != test.status {
+				t.Errorf("ChainTips: unexpected status for %v "+
+					"-- got %v, want %v", tip.Hash, tip.Status,
+					test.status)
+			}
+		}
+		if !found {
+			t.Errorf("ChainTips: tip %v not reported", test.node.hash)
+		}
+	}
+	for i := 1; i < len(tips); i++ {
+		if tips[i].Height > tips[i-1].Height {
+			t.Fatalf("ChainTips: tips not sorted by descending height")
+		}
+	}
+
+	// Branch A has the most work and all of its data is available, so it
+	// is the best candidate.  Once it is invalid, the main chain is best
+	// since the other branches with the same work lack data or are
+	// invalid.
+	if got := chain.bestChainCandidate(); got != tstTip(branchA) {
+		t.Fatalf("bestChainCandidate: got %v, want %v", got,
+			tstTip(branchA))
+	}
+	branchA[0].status |= statusValidateFailed
+	if got := chain.bestChainCandidate(); got != tstTip(mainNodes) {
+		t.Fatalf("bestChainCandidate: got %v, want %v", got,
+			tstTip(mainNodes))
+	}
+
+	// Extending branch D to the same work as the main chain only makes it
+	// the best candidate once it is marked precious.
+	branchD = append(branchD, fakeBranch(tstTip(branchD), 1, 0, haveValid)...)
+	if got := chain.bestChainCandidate(); got != tstTip(mainNodes) {
+		t.Fatalf("bestChainCandidate: got %v, want %v", got,
+			tstTip(mainNodes))
+	}
+	chain.preciousNode = branchD[0]
+	if got := chain.bestChainCandidate(); got != tstTip(branchD) {
+		t.Fatalf("bestChainCandidate: got %v, want %v", got,
+			tstTip(branchD))
+	}
+
+	// All descendants of the fork point of branches C and D are found.
+	descendants := chain.descendants(branchC[0])
+	if len(descendants) != 3 {
+		t.Fatalf("descendants: unexpected number of descendants -- got "+
+			"%d, want 3", len(descendants))
+	}
+}
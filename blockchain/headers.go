@@ -0,0 +1,275 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/brsuite/brond/chaincfg/chainhash"
+	"github.com/brsuite/brond/wire"
+)
+
+// HeaderChain is a chain of validated block headers which connects to a block
+// in the block index, but has not been added to it yet because it does not
+// have enough cumulative work.  See ProcessBlockHeaders.
+type HeaderChain struct {
+	tip *blockNode
+	len int
+}
+
+// Tip returns the hash and height of the last header in the chain.
+func (hc *HeaderChain) Tip() (chainhash.Hash, int32) {
+	return hc.tip.hash, hc.tip.height
+}
+
+// Len returns the number of headers in the chain.
+func (hc *HeaderChain) Len() int {
+	return hc.len
+}
+
+// HeadersNeeded returns an estimate of the number of headers the passed chain
+// of pending headers needs in total to have enough cumulative work to be added
+// to the block index, assuming the headers which follow have the same average
+// work as those in the chain.  Unlike the height claimed by a peer, this is
+// based on work which has been verified, so it can be used to bound the number
+// of pending headers kept for a peer.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) HeadersNeeded(pending *HeaderChain) int64 {
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+
+	required := new(big.Int).Add(b.bestChain.Tip().workSum, bigOne)
+	minChainWork := b.chainParams.MinimumChainWork
+	if minChainWork != nil && minChainWork.Cmp(required) > 0 {
+		required.Set(minChainWork)
+	}
+
+	// The parent of the first pending header is in the block index.
+	tip := pending.tip
+	fork := tip.Ancestor(tip.height - int32(pending.len))
+	pendingWork := new(big.Int).Sub(tip.workSum, fork.workSum)
+	required.Sub(required, fork.workSum)
+
+	// needed = ceil(required * len / pendingWork)
+	needed := required.Mul(required, big.NewInt(int64(pending.len)))
+	needed.Add(needed, pendingWork)
+	needed.Sub(needed, bigOne)
+	needed.Div(needed, pendingWork)
+	if !needed.IsInt64() {
+		return math.MaxInt64
+	}
+	return needed.Int64()
+}
+
+// hasHeaderWork returns whether the chain of headers ending with the passed
+// node has enough cumulative work to be added to the block index, which is the
+// case when it has more work than the current best chain and at least the
+// minimum chain work of the network.
+//
+// This function MUST be called with the chain state lock held (for reads).
+func (b *BlockChain) hasHeaderWork(node *blockNode) bool {
+	if node.workSum.Cmp(b.bestChain.Tip().workSum) <= 0 {
+		return false
+	}
+	minChainWork := b.chainParams.MinimumChainWork
+	return minChainWork == nil || node.workSum.Cmp(minChainWork) >= 0
+}
+
+// ProcessBlockHeaders validates the passed block headers and adds them to the
+// block index ahead of their block data, so that the blocks can be downloaded
+// once their headers are known to be valid.  Each header must connect to the
+// previous one, and the first one to the tip of the passed chain of pending
+// headers, or to a block in the block index when it is nil.  Headers which are
+// already in the block index are skipped.
+//
+// To prevent low-work chains of headers from filling up the block index, the
+// new headers are only added once the chain they form has more work than the
+// current best chain and at least the minimum chain work of the network.
+// Until then, the chain of pending headers is returned so it can be extended
+// with the following headers.  Otherwise, nil is returned.
+//
+// The flags are passed to the sanity and context checks of the headers, so
+// BFFastAdd skips all header checks except those involving checkpoints.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ProcessBlockHeaders(pending *HeaderChain, headers []*wire.BlockHeader, flags BehaviorFlags) (*HeaderChain, error) {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	var tip *blockNode
+	var numPending int
+	if pending != nil {
+		tip, numPending = pending.tip, pending.len
+	}
+	for _, header := range headers {
+		blockHash := header.BlockHash()
+
+		// Find the parent of the header, which is either the previous
+		// header or a block in the block index.
+		prevNode := tip
+		if prevNode == nil {
+			prevNode = b.index.LookupNode(&header.PrevBlock)
+			if prevNode == nil {
+				str := fmt.Sprintf("previous block %s of header %s "+
+					"is unknown", header.PrevBlock, blockHash)
+				return nil, ruleError(ErrPreviousBlockUnknown, str)
+			}
+		} else if header.PrevBlock != prevNode.hash {
+			str := fmt.Sprintf("header %s does not connect to the "+
+				"previous header %s", blockHash, prevNode.hash)
+			return nil, ruleError(ErrPreviousBlockUnknown, str)
+		}
+
+		// Skip headers which are already in the block index, provided
+		// they are not known to be invalid.  Only the headers up to the
+		// first unknown one can be known since pending headers are never
+		// in the block index.
+		if numPending == 0 {
+			if node := b.index.LookupNode(&blockHash); node != nil {
+				if b.index.NodeStatus(node).KnownInvalid() {
+					str := fmt.Sprintf("header %s is of a known "+
+						"invalid block", blockHash)
+					return nil, ruleError(ErrInvalidAncestorBlock, str)
+				}
+				tip = node
+				continue
+			}
+			if b.index.NodeStatus(prevNode).KnownInvalid() {
+				str := fmt.Sprintf("header %s connects to the known "+
+					"invalid block %s", blockHash, prevNode.hash)
+				return nil, ruleError(ErrInvalidAncestorBlock, str)
+			}
+		}
+
+		err := checkBlockHeaderSanity(header, b.chainParams.PowLimit,
+			b.timeSource, flags)
+		if err != nil {
+			return nil, err
+		}
+		err = b.checkHeaderCheckpoint(header, flags)
+		if err != nil {
+			return nil, err
+		}
+		err = b.checkBlockHeaderContext(header, prevNode, flags)
+		if err != nil {
+			return nil, err
+		}
+
+		tip = newBlockNode(header, prevNode)
+		numPending++
+	}
+	if numPending == 0 {
+		return nil, nil
+	}
+	if !b.hasHeaderWork(tip) {
+		return &HeaderChain{tip: tip, len: numPending}, nil
+	}
+
+	// Add the pending headers to the block index from the oldest to the
+	// newest one.  They do not have any status until their block data is
+	// stored.
+	nodes := make([]*blockNode, numPending)
+	for i, node := numPending-1, tip; i >= 0; i, node = i-1, node.parent {
+		nodes[i] = node
+	}
+	for _, node := range nodes {
+		b.index.AddNode(node)
+	}
+	if err := b.index.flushToDB(); err != nil {
+		return nil, err
+	}
+	if b.bestHeader == nil || b.index.NodeStatus(b.bestHeader).KnownInvalid() ||
+		tip.workSum.Cmp(b.bestHeader.workSum) > 0 {
+
+		b.bestHeader = tip
+	}
+
+	log.Debugf("Added %d block headers up to %v (height %d) to the block "+
+		"index", numPending, tip.hash, tip.height)
+
+	return nil, nil
+}
+
+// markInvalidBestHeader marks the headers from the best header back to the
+// passed node as having an invalid ancestor when the passed node is known to be
+// invalid and the best header descends from it.  The blocks of those headers
+// may not have been stored yet, so they would not be marked otherwise.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) markInvalidBestHeader(node *blockNode) {
+	header := b.bestHeader
+	if header == nil || header.height <= node.height ||
+		!b.index.NodeStatus(node).KnownInvalid() ||
+		header.Ancestor(node.height) != node {
+
+		return
+	}
+	for n := header; n != node; n = n.parent {
+		b.index.SetStatusFlags(n, statusInvalidAncestor)
+	}
+	if err := b.index.flushToDB(); err != nil {
+		log.Warnf("Error flushing block index changes to disk: %v", err)
+	}
+}
+
+// BestHeader returns the hash and height of the header with the most
+// cumulative work in the block index which is not known to be invalid, whether
+// or not the data of its block is available.  It is never behind the tip of
+// the main chain.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) BestHeader() (chainhash.Hash, int32) {
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+
+	best := b.bestChain.Tip()
+	header := b.bestHeader
+	if header != nil && header.workSum.Cmp(best.workSum) > 0 &&
+		!b.index.NodeStatus(header).KnownInvalid() {
+
+		best = header
+	}
+	return best.hash, best.height
+}
+
+// HeaderHashesAfter returns the hashes of the blocks after the most recent
+// common ancestor of the blocks with the passed hashes up to and including the
+// end block, along with the height of the first one.  When the start block is
+// an ancestor of the end block, these are its descendants up to the end block.
+// The data of the blocks does not need to be available.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) HeaderHashesAfter(startHash, endHash *chainhash.Hash) (int32, []chainhash.Hash, error) {
+	start := b.index.LookupNode(startHash)
+	if start == nil {
+		return 0, nil, fmt.Errorf("no known block header with hash %v",
+			startHash)
+	}
+	end := b.index.LookupNode(endHash)
+	if end == nil {
+		return 0, nil, fmt.Errorf("no known block header with hash %v",
+			endHash)
+	}
+
+	// Walk back from the end block to the common ancestor, collecting the
+	// hashes in reverse order.
+	var hashes []chainhash.Hash
+	for end.height > start.height {
+		hashes = append(hashes, end.hash)
+		end = end.parent
+	}
+	start = start.Ancestor(end.height)
+	for end != start {
+		hashes = append(hashes, end.hash)
+		end, start = end.parent, start.parent
+	}
+	for i, j := 0, len(hashes)-1; i < j; i, j = i+1, j-1 {
+		hashes[i], hashes[j] = hashes[j], hashes[i]
+	}
+	return end.height + 1, hashes, nil
+}
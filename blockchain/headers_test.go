@@ -0,0 +1,257 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/brsuite/brond/chaincfg"
+	"github.com/brsuite/brond/chaincfg/chainhash"
+	"github.com/brsuite/brond/wire"
+	"github.com/brsuite/bronutil"
+)
+
+// solveTestHeader returns a header with the minimum difficulty of the passed
+// parameters which extends the passed header and is mined to satisfy it.
+func solveTestHeader(params *chaincfg.Params, prev *wire.BlockHeader, spacing time.Duration) *wire.BlockHeader {
+	header := &wire.BlockHeader{
+		Version:   4,
+		PrevBlock: prev.BlockHash(),
+		Timestamp: prev.Timestamp.Add(spacing),
+		Bits:      params.PowLimitBits,
+	}
+	for checkProofOfWork(header, params.PowLimit, BFNone) != nil {
+		header.Nonce++
+	}
+	return header
+}
+
+// TestProcessBlockHeaders ensures block headers are only added to the block
+// index once the chain they form has enough work, that the number of headers a
+// pending chain needs is estimated from its work, that blocks which are only
+// known by their header do not exist yet, and that the best header and the
+// hashes after a block account for them.
+func TestProcessBlockHeaders(t *testing.T) {
+	chain, teardownFunc, err := chainSetup("processblockheaders",
+		&chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatalf("Failed to setup chain instance: %v", err)
+	}
+	defer teardownFunc()
+
+	// Create a chain of headers extending the genesis block and require
+	// the work of all of them before they are added to the block index.
+	//
+	//   genesis -> 1 -> 2 -> 3 -> 4
+	params := chain.chainParams
+	genesis := chain.bestChain.Tip()
+	headers := make([]*wire.BlockHeader, 4)
+	prev := &params.GenesisBlock.Header
+	for i := range headers {
+		headers[i] = solveTestHeader(params, prev, params.TargetTimePerBlock)
+		prev = headers[i]
+	}
+	params.MinimumChainWork = new(big.Int).Mul(
+		CalcWork(params.PowLimitBits), big.NewInt(int64(len(headers))))
+	params.MinimumChainWork.Add(params.MinimumChainWork, genesis.workSum)
+
+	// The first headers do not have enough work yet and are pending.
+	pending, err := chain.ProcessBlockHeaders(nil, headers[:2], BFNone)
+	if err != nil {
+		t.Fatalf("ProcessBlockHeaders: unexpected error %v", err)
+	}
+	if pending == nil || pending.Len() != 2 {
+		t.Fatalf("ProcessBlockHeaders: got pending chain %v, want 2 "+
+			"headers", pending)
+	}
+	tipHash, tipHeight := pending.Tip()
+	if tipHash != headers[1].BlockHash() || tipHeight != 2 {
+		t.Fatalf("pending tip: got %v (height %d), want %v (height 2)",
+			tipHash, tipHeight, headers[1].BlockHash())
+	}
+	if hash := headers[0].BlockHash(); chain.index.LookupNode(&hash) != nil {
+		t.Fatal("pending header was added to the block index")
+	}
+	if needed := chain.HeadersNeeded(pending); needed != int64(len(headers)) {
+		t.Fatalf("HeadersNeeded: got %d, want %d", needed, len(headers))
+	}
+
+	// Headers which do not connect to the pending chain are rejected.
+	_, err = chain.ProcessBlockHeaders(pending, headers[3:], BFNone)
+	if rerr, ok := err.(RuleError); !ok || rerr.ErrorCode != ErrPreviousBlockUnknown {
+		t.Fatalf("ProcessBlockHeaders: got error %v, want %v", err,
+			ErrPreviousBlockUnknown)
+	}
+
+	// Headers which do not satisfy their claimed difficulty are rejected.
+	badHeader := *headers[2]
+	badHeader.Bits = 0x1d00ffff
+	_, err = chain.ProcessBlockHeaders(pending,
+		[]*wire.BlockHeader{&badHeader}, BFNone)
+	if rerr, ok := err.(RuleError); !ok || rerr.ErrorCode != ErrHighHash {
+		t.Fatalf("ProcessBlockHeaders: got error %v, want %v", err,
+			ErrHighHash)
+	}
+
+	// Completing the chain adds all of its headers to the block index
+	// without their block data.
+	pending, err = chain.ProcessBlockHeaders(pending, headers[2:], BFNone)
+	if err != nil {
+		t.Fatalf("ProcessBlockHeaders: unexpected error %v", err)
+	}
+	if pending != nil {
+		t.Fatalf("ProcessBlockHeaders: got pending chain with %d "+
+			"headers, want none", pending.Len())
+	}
+	for i := range headers {
+		hash := headers[i].BlockHash()
+		node := chain.index.LookupNode(&hash)
+		if node == nil || node.height != int32(i+1) {
+			t.Fatalf("header %d is not in the block index", i+1)
+		}
+		if chain.index.NodeStatus(node) != statusNone {
+			t.Fatalf("header %d: got status %v, want %v", i+1,
+				chain.index.NodeStatus(node), statusNone)
+		}
+		if have, err := chain.HaveBlock(&hash); err != nil || have {
+			t.Fatalf("HaveBlock: got %v (err %v) for block %d "+
+				"only known by its header", have, err, i+1)
+		}
+	}
+	bestHash, bestHeight := chain.BestHeader()
+	if bestHash != headers[3].BlockHash() || bestHeight != 4 {
+		t.Fatalf("BestHeader: got %v (height %d), want %v (height 4)",
+			bestHash, bestHeight, headers[3].BlockHash())
+	}
+
+	// Headers which are already known are skipped.
+	pending, err = chain.ProcessBlockHeaders(nil, headers, BFNone)
+	if err != nil || pending != nil {
+		t.Fatalf("ProcessBlockHeaders: got pending chain %v (err %v) "+
+			"for known headers", pending, err)
+	}
+
+	// A competing chain without the minimum chain work is kept pending.
+	//
+	//   genesis -> 1 -> 2 -> 3 -> 4
+	//               \-> 2a
+	forkHeader := solveTestHeader(params, headers[0],
+		2*params.TargetTimePerBlock)
+	pending, err = chain.ProcessBlockHeaders(nil,
+		[]*wire.BlockHeader{forkHeader}, BFNone)
+	if err != nil || pending == nil {
+		t.Fatalf("ProcessBlockHeaders: got pending chain %v (err %v) "+
+			"for a low-work chain", pending, err)
+	}
+
+	hashAfterTests := []struct {
+		name       string
+		start      chainhash.Hash
+		end        chainhash.Hash
+		wantHeight int32
+		wantHashes []chainhash.Hash
+	}{
+		{
+			name:       "descendants of genesis",
+			start:      genesis.hash,
+			end:        headers[3].BlockHash(),
+			wantHeight: 1,
+			wantHashes: []chainhash.Hash{headers[0].BlockHash(),
+				headers[1].BlockHash(), headers[2].BlockHash(),
+				headers[3].BlockHash()},
+		},
+		{
+			name:       "end is an ancestor",
+			start:      headers[3].BlockHash(),
+			end:        headers[1].BlockHash(),
+			wantHeight: 3,
+		},
+	}
+	for _, test := range hashAfterTests {
+		height, hashes, err := chain.HeaderHashesAfter(&test.start,
+			&test.end)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if height != test.wantHeight ||
+			!reflect.DeepEqual(hashes, test.wantHashes) {
+
+			t.Errorf("%s: got height %d and hashes %v, want height "+
+				"%d and hashes %v", test.name, height, hashes,
+				test.wantHeight, test.wantHashes)
+		}
+	}
+}
+
+// TestProcessBlockInvalidHeader ensures the header of a block which was added
+// ahead of its data is marked invalid once the block turns out to be invalid,
+// but not when the block data might only have been mutated.
+func TestProcessBlockInvalidHeader(t *testing.T) {
+	chain, teardownFunc, err := chainSetup("processblockinvalidheader",
+		&chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatalf("Failed to setup chain instance: %v", err)
+	}
+	defer teardownFunc()
+
+	//   genesis -> 1 -> 2
+	params := chain.chainParams
+	params.MinimumChainWork = nil
+	headers := make([]*wire.BlockHeader, 2)
+	prev := &params.GenesisBlock.Header
+	for i := range headers {
+		headers[i] = solveTestHeader(params, prev, params.TargetTimePerBlock)
+		prev = headers[i]
+	}
+	if _, err := chain.ProcessBlockHeaders(nil, headers, BFNone); err != nil {
+		t.Fatalf("ProcessBlockHeaders: unexpected error %v", err)
+	}
+	hash := headers[0].BlockHash()
+	node := chain.index.LookupNode(&hash)
+	if node == nil {
+		t.Fatal("header 1 is not in the block index")
+	}
+
+	// A block with transactions which do not match the merkle root of its
+	// header might have been mutated, so its header is not marked.
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: wire.MaxPrevOutIndex},
+		SignatureScript:  []byte{0x51, 0x51},
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	coinbase.AddTxOut(&wire.TxOut{PkScript: []byte{0x51}})
+	mutated := wire.NewMsgBlock(headers[0])
+	mutated.AddTransaction(coinbase)
+	_, _, err = chain.ProcessBlock(bronutil.NewBlock(mutated), BFNone)
+	if rerr, ok := err.(RuleError); !ok || rerr.ErrorCode != ErrBadMerkleRoot {
+		t.Fatalf("ProcessBlock: got error %v, want %v", err,
+			ErrBadMerkleRoot)
+	}
+	if chain.index.NodeStatus(node).KnownInvalid() {
+		t.Fatal("header of mutated block was marked invalid")
+	}
+
+	// A block without transactions is invalid whatever its data, so its
+	// header is marked along with the best header after it.
+	_, _, err = chain.ProcessBlock(bronutil.NewBlock(
+		wire.NewMsgBlock(headers[0])), BFNone)
+	if rerr, ok := err.(RuleError); !ok || rerr.ErrorCode != ErrNoTransactions {
+		t.Fatalf("ProcessBlock: got error %v, want %v", err,
+			ErrNoTransactions)
+	}
+	if !chain.index.NodeStatus(node).KnownInvalid() {
+		t.Fatal("header of invalid block was not marked invalid")
+	}
+	bestHash, bestHeight := chain.BestHeader()
+	if bestHash != *params.GenesisHash || bestHeight != 0 {
+		t.Fatalf("BestHeader: got %v (height %d), want genesis",
+			bestHash, bestHeight)
+	}
+}
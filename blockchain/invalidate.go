@@ -0,0 +1,248 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"fmt"
+
+	"github.com/brsuite/brond/chaincfg/chainhash"
+)
+
+// descendants returns all nodes in the block index that descend from the
+// passed node.
+//
+// This function MUST be called with the chain state lock held (for reads).
+func (b *BlockChain) descendants(node *blockNode) []*blockNode {
+	b.index.RLock()
+	defer b.index.RUnlock()
+
+	var descendants []*blockNode
+	for _, n := range b.index.index {
+		if n.height <= node.height {
+			continue
+		}
+
+		// Main chain nodes can be checked against the chain view
+		// directly which avoids walking back through their ancestors.
+		var ancestor *blockNode
+		if b.bestChain.Contains(n) {
+			ancestor = b.bestChain.NodeByHeight(node.height)
+		} else {
+			ancestor = n.Ancestor(node.height)
+		}
+		if ancestor == node {
+			descendants = append(descendants, n)
+		}
+	}
+
+	return descendants
+}
+
+// isCandidateBranch returns whether or not the data for all blocks between the
+// passed node and the main chain is available and none of them are known to be
+// invalid.
+//
+// This function MUST be called with the chain state lock held (for reads) and
+// the block index lock held (for reads).
+func (b *BlockChain) isCandidateBranch(node *blockNode) bool {
+	for n := node; n != nil && !b.bestChain.Contains(n); n = n.parent {
+		if n.status.KnownInvalid() || !n.status.HaveData() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// bestChainCandidate returns the node that ends the chain with the most
+// cumulative work which could become the main chain.  That is, the data for all
+// of its blocks is available and none of them are known to be invalid.
+//
+// Ties are resolved in favor of the current best chain unless the other chain
+// contains the block most recently passed to PreciousBlock.
+//
+// This function MUST be called with the chain state lock held (for reads).
+func (b *BlockChain) bestChainCandidate() *blockNode {
+	b.index.RLock()
+	defer b.index.RUnlock()
+
+	// containsPrecious returns whether the chain ending at the passed node
+	// contains the precious block.
+	precious := b.preciousNode
+	containsPrecious := func(n *blockNode) bool {
+		return precious != nil && n.Ancestor(precious.height) == precious
+	}
+
+	best := b.bestChain.Tip()
+	for _, n := range b.index.index {
+		if n.status.KnownInvalid() || !n.status.HaveData() {
+			continue
+		}
+
+		cmp := n.workSum.Cmp(best.workSum)
+		if cmp < 0 {
+			continue
+		}
+		if cmp == 0 && (containsPrecious(best) || !containsPrecious(n)) {
+			continue
+		}
+		if !b.isCandidateBranch(n) {
+			continue
+		}
+
+		best = n
+	}
+
+	return best
+}
+
+// activateBestChain reorganizes the chain to the valid chain with the most
+// cumulative work as determined by bestChainCandidate.  Candidates that turn out
+// to be invalid while reorganizing are marked as such and the next best one is
+// tried instead.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) activateBestChain() error {
+	for {
+		candidate := b.bestChainCandidate()
+		if candidate == b.bestChain.Tip() {
+			return nil
+		}
+
+		detachNodes, attachNodes := b.getReorganizeNodes(candidate)
+		if detachNodes.Len() == 0 && attachNodes.Len() == 0 {
+			return nil
+		}
+
+		log.Infof("REORGANIZE: Block %v is becoming the new best chain "+
+			"tip.", candidate.hash)
+		err := b.reorganizeChain(detachNodes, attachNodes)
+		if writeErr := b.index.flushToDB(); writeErr != nil {
+			log.Warnf("Error flushing block index changes to disk: %v",
+				writeErr)
+		}
+		if err != nil {
+			// The offending block has been marked invalid, so try
+			// the next best candidate.
+			if _, ok := err.(RuleError); ok {
+				log.Warnf("Unable to reorganize to block %v: %v",
+					candidate.hash, err)
+				continue
+			}
+			return err
+		}
+	}
+}
+
+// InvalidateBlock marks the block identified by the passed hash and all of its
+// descendants as invalid.  When the block is part of the main chain, the chain
+// is reorganized to the best remaining valid chain.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) InvalidateBlock(hash *chainhash.Hash) error {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	node := b.index.LookupNode(hash)
+	if node == nil {
+		return fmt.Errorf("block %s is not known", hash)
+	}
+	if node.parent == nil {
+		return fmt.Errorf("block %s is the genesis block and can't be "+
+			"invalidated", hash)
+	}
+
+	b.index.SetStatusFlags(node, statusValidateFailed)
+	for _, n := range b.descendants(node) {
+		b.index.SetStatusFlags(n, statusInvalidAncestor)
+	}
+	if b.preciousNode != nil && b.preciousNode.Ancestor(node.height) == node {
+		b.preciousNode = nil
+	}
+
+	// Disconnect the invalidated block and all of its descendants from the
+	// main chain when needed.
+	if b.bestChain.Contains(node) {
+		detachNodes, attachNodes := b.getReorganizeNodes(node.parent)
+		err := b.reorganizeChain(detachNodes, attachNodes)
+		if err != nil {
+			if writeErr := b.index.flushToDB(); writeErr != nil {
+				log.Warnf("Error flushing block index changes "+
+					"to disk: %v", writeErr)
+			}
+			return err
+		}
+	}
+	if err := b.index.flushToDB(); err != nil {
+		return err
+	}
+
+	log.Infof("Block %v (height %d) was marked invalid", hash, node.height)
+
+	return b.activateBestChain()
+}
+
+// ReconsiderBlock removes the invalid status from the block identified by the
+// passed hash along with all of its ancestors and descendants, which undoes the
+// effect of InvalidateBlock.  The chain is then reorganized to the best valid
+// chain, which might involve validating the reconsidered blocks again.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ReconsiderBlock(hash *chainhash.Hash) error {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	node := b.index.LookupNode(hash)
+	if node == nil {
+		return fmt.Errorf("block %s is not known", hash)
+	}
+
+	const invalidFlags = statusValidateFailed | statusInvalidAncestor
+	for n := node; n != nil; n = n.parent {
+		if b.index.NodeStatus(n).KnownInvalid() {
+			b.index.UnsetStatusFlags(n, invalidFlags)
+		}
+	}
+	for _, n := range b.descendants(node) {
+		if b.index.NodeStatus(n).KnownInvalid() {
+			b.index.UnsetStatusFlags(n, invalidFlags)
+		}
+	}
+	if err := b.index.flushToDB(); err != nil {
+		return err
+	}
+
+	log.Infof("Block %v (height %d) was reconsidered", hash, node.height)
+
+	return b.activateBestChain()
+}
+
+// PreciousBlock treats the block identified by the passed hash as if it was
+// received before any competing block with the same amount of cumulative work.
+// When the block ends a chain with at least as much work as the current best
+// chain, the chain is reorganized to it.  A later call overrides the effect of
+// an earlier one.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) PreciousBlock(hash *chainhash.Hash) error {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	node := b.index.LookupNode(hash)
+	if node == nil {
+		return fmt.Errorf("block %s is not known", hash)
+	}
+
+	// Nothing to do when the block is already part of the main chain or
+	// has less work than it.
+	if b.bestChain.Contains(node) ||
+		node.workSum.Cmp(b.bestChain.Tip().workSum) < 0 {
+
+		return nil
+	}
+
+	b.preciousNode = node
+	return b.activateBestChain()
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/brsuite/brond/chaincfg/chainhash"
 	"github.com/brsuite/brond/database"
+	"github.com/brsuite/brond/wire"
 	"github.com/brsuite/bronutil"
 )
 
@@ -39,8 +40,13 @@ const (
 // This function is safe for concurrent access.
 func (b *BlockChain) blockExists(hash *chainhash.Hash) (bool, error) {
 	// Check block index first (could be main chain or side chain blocks).
-	if b.index.HaveBlock(hash) {
-		return true, nil
+	// Blocks which are only known by their header do not exist yet, while
+	// main chain blocks which have been pruned are still known valid.
+	if node := b.index.LookupNode(hash); node != nil {
+		status := b.index.NodeStatus(node)
+		if status.HaveData() || status.KnownValid() {
+			return true, nil
+		}
 	}
 
 	// Check in the database.
@@ -129,6 +135,96 @@ func (b *BlockChain) processOrphans(hash *chainhash.Hash, flags BehaviorFlags) e
 	return nil
 }
 
+// checkHeaderCheckpoint finds the previous checkpoint and performs some
+// additional checks on the passed block header based on it.  This provides a
+// few nice properties such as preventing old side chain blocks before the last
+// checkpoint, rejecting easy to mine, but otherwise bogus, blocks that could be
+// used to eat memory, and ensuring expected (versus claimed) proof of work
+// requirements since the previous checkpoint are met.
+//
+// The proof of work is not checked against the minimum expected since the
+// previous checkpoint when the BFFastAdd flag is set.
+//
+// This function MUST be called with the chain state lock held (for reads).
+func (b *BlockChain) checkHeaderCheckpoint(header *wire.BlockHeader, flags BehaviorFlags) error {
+	checkpointNode, err := b.findPreviousCheckpoint()
+	if err != nil || checkpointNode == nil {
+		return err
+	}
+
+	// Ensure the block timestamp is after the checkpoint timestamp.
+	checkpointTime := time.Unix(checkpointNode.timestamp, 0)
+	if header.Timestamp.Before(checkpointTime) {
+		str := fmt.Sprintf("block %v has timestamp %v before "+
+			"last checkpoint timestamp %v", header.BlockHash(),
+			header.Timestamp, checkpointTime)
+		return ruleError(ErrCheckpointTimeTooOld, str)
+	}
+	if flags&BFFastAdd == BFFastAdd {
+		return nil
+	}
+
+	// Even though the sanity checks have already ensured the proof of work
+	// exceeds the claimed amount, the claimed amount is a field in the
+	// block header which could be forged.  This check ensures the proof of
+	// work is at least the minimum expected based on elapsed time since the
+	// last checkpoint and maximum adjustment allowed by the retarget rules.
+	duration := header.Timestamp.Sub(checkpointTime)
+	requiredTarget := CompactToBig(b.calcEasiestDifficulty(
+		checkpointNode.bits, duration))
+	currentTarget := CompactToBig(header.Bits)
+	if currentTarget.Cmp(requiredTarget) > 0 {
+		str := fmt.Sprintf("block target difficulty of %064x "+
+			"is too low when compared to the previous "+
+			"checkpoint", currentTarget)
+		return ruleError(ErrDifficultyTooLow, str)
+	}
+	return nil
+}
+
+// isMutationError returns whether the passed error might have been caused by
+// the data of a block differing from the data committed to by its header, in
+// which case the block with the same header might still be valid.  Examples
+// are transactions which do not match the merkle root and witness data which
+// does not match the witness commitment.
+func isMutationError(err error) bool {
+	rerr, ok := err.(RuleError)
+	if !ok {
+		return false
+	}
+	switch rerr.ErrorCode {
+	case ErrBadMerkleRoot, ErrDuplicateTx, ErrUnexpectedWitness,
+		ErrInvalidWitnessCommitment, ErrWitnessCommitmentMismatch:
+		return true
+	}
+	return false
+}
+
+// markBlockFailed marks the header of the block with the passed hash as invalid
+// when it is known in the block index and the passed error shows the block is
+// invalid, as opposed to its data being mutated or something else going wrong.
+// This ensures the blocks of headers which were added ahead of their data are
+// not requested again once they have failed validation.  A block with a
+// timestamp too far in the future is not marked since it might become valid
+// later.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) markBlockFailed(hash *chainhash.Hash, err error) {
+	rerr, ok := err.(RuleError)
+	if !ok || rerr.ErrorCode == ErrTimeTooNew || isMutationError(err) {
+		return
+	}
+	node := b.index.LookupNode(hash)
+	if node == nil {
+		return
+	}
+	b.index.SetStatusFlags(node, statusValidateFailed)
+	if err := b.index.flushToDB(); err != nil {
+		log.Warnf("Error flushing block index changes to disk: %v", err)
+	}
+	b.markInvalidBestHeader(node)
+}
+
 // ProcessBlock is the main workhorse for handling insertion of new blocks into
 // the block chain.  It includes functionality such as rejecting duplicate
 // blocks, ensuring blocks follow all rules, orphan handling, and insertion into
@@ -143,8 +239,6 @@ func (b *BlockChain) ProcessBlock(block *bronutil.Block, flags BehaviorFlags) (b
 	b.chainLock.Lock()
 	defer b.chainLock.Unlock()
 
-	fastAdd := flags&BFFastAdd == BFFastAdd
-
 	blockHash := block.Hash()
 	log.Tracef("Processing block %v", blockHash)
 
@@ -167,48 +261,16 @@ func (b *BlockChain) ProcessBlock(block *bronutil.Block, flags BehaviorFlags) (b
 	// Perform preliminary sanity checks on the block and its transactions.
 	err = checkBlockSanity(block, b.chainParams.PowLimit, b.timeSource, flags)
 	if err != nil {
+		b.markBlockFailed(blockHash, err)
 		return false, false, err
 	}
 
-	// Find the previous checkpoint and perform some additional checks based
-	// on the checkpoint.  This provides a few nice properties such as
-	// preventing old side chain blocks before the last checkpoint,
-	// rejecting easy to mine, but otherwise bogus, blocks that could be
-	// used to eat memory, and ensuring expected (versus claimed) proof of
-	// work requirements since the previous checkpoint are met.
+	// Perform some additional checks based on the previous checkpoint.
 	blockHeader := &block.MsgBlock().Header
-	checkpointNode, err := b.findPreviousCheckpoint()
+	err = b.checkHeaderCheckpoint(blockHeader, flags)
 	if err != nil {
 		return false, false, err
 	}
-	if checkpointNode != nil {
-		// Ensure the block timestamp is after the checkpoint timestamp.
-		checkpointTime := time.Unix(checkpointNode.timestamp, 0)
-		if blockHeader.Timestamp.Before(checkpointTime) {
-			str := fmt.Sprintf("block %v has timestamp %v before "+
-				"last checkpoint timestamp %v", blockHash,
-				blockHeader.Timestamp, checkpointTime)
-			return false, false, ruleError(ErrCheckpointTimeTooOld, str)
-		}
-		if !fastAdd {
-			// Even though the checks prior to now have already ensured the
-			// proof of work exceeds the claimed amount, the claimed amount
-			// is a field in the block header which could be forged.  This
-			// check ensures the proof of work is at least the minimum
-			// expected based on elapsed time since the last checkpoint and
-			// maximum adjustment allowed by the retarget rules.
-			duration := blockHeader.Timestamp.Sub(checkpointTime)
-			requiredTarget := CompactToBig(b.calcEasiestDifficulty(
-				checkpointNode.bits, duration))
-			currentTarget := CompactToBig(blockHeader.Bits)
-			if currentTarget.Cmp(requiredTarget) > 0 {
-				str := fmt.Sprintf("block target difficulty of %064x "+
-					"is too low when compared to the previous "+
-					"checkpoint", currentTarget)
-				return false, false, ruleError(ErrDifficultyTooLow, str)
-			}
-		}
-	}
 
 	// Handle orphan blocks.
 	prevHash := &blockHeader.PrevBlock
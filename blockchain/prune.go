@@ -0,0 +1,118 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"github.com/brsuite/brond/chaincfg/chainhash"
+	"github.com/brsuite/brond/database"
+)
+
+const (
+	// MinBlocksToKeep is the number of most recent main chain blocks whose
+	// data is never removed when pruning.  This is the number of blocks a
+	// node advertising wire.SFNodeNetworkLimited is expected to serve and
+	// also bounds the depth of the reorganizations a pruned node can
+	// handle.
+	MinBlocksToKeep = 288
+)
+
+// maybePruneBlocks removes the data for the oldest blocks from the database
+// when pruning is enabled and the stored block data exceeds the prune target.
+// The most recent MinBlocksToKeep blocks of the main chain are always kept, as
+// are any blocks that have not yet been reflected in the utxo set stored in the
+// database.
+//
+// The spend journal entries for the pruned blocks are removed as well since
+// they are only needed to disconnect blocks, which is no longer possible once
+// their data is gone.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) maybePruneBlocks() error {
+	if b.pruneTarget == 0 {
+		return nil
+	}
+
+	// Nothing to prune until the chain is deeper than the blocks that are
+	// always kept.
+	tip := b.bestChain.Tip()
+	if tip.height <= MinBlocksToKeep {
+		return nil
+	}
+	keepNode := tip.Ancestor(tip.height - MinBlocksToKeep)
+
+	// The blocks connected since the utxo cache was last flushed are
+	// needed to rebuild the utxo set after an unclean shutdown, so they
+	// must be kept as well.
+	flushNode := b.index.LookupNode(&b.utxoCache.lastFlushHash)
+	if flushNode != nil && flushNode.height < keepNode.height {
+		keepNode = flushNode
+	}
+
+	var pruned []chainhash.Hash
+	prunedHeight := b.prunedHeight
+	err := b.db.Update(func(dbTx database.Tx) error {
+		var err error
+		pruned, err = dbTx.PruneBlocks(b.pruneTarget, &keepNode.hash)
+		if err != nil || len(pruned) == 0 {
+			return err
+		}
+
+		for i := range pruned {
+			hash := &pruned[i]
+			err := dbRemoveSpendJournalEntry(dbTx, hash)
+			if err != nil {
+				return err
+			}
+
+			node := b.index.LookupNode(hash)
+			if node != nil && b.bestChain.Contains(node) &&
+				node.height > prunedHeight {
+
+				prunedHeight = node.height
+			}
+		}
+
+		return dbPutPrunedHeight(dbTx, prunedHeight)
+	})
+	if err != nil || len(pruned) == 0 {
+		return err
+	}
+
+	// The data for the pruned blocks is no longer available, so update the
+	// block index accordingly.
+	for i := range pruned {
+		node := b.index.LookupNode(&pruned[i])
+		if node != nil {
+			b.index.UnsetStatusFlags(node, statusDataStored)
+		}
+	}
+	b.prunedHeight = prunedHeight
+
+	log.Infof("Pruned %d blocks (pruned through height %d)", len(pruned),
+		prunedHeight)
+
+	return b.index.flushToDB()
+}
+
+// PrunedHeight returns the height of the most recent main chain block whose
+// data has been removed by pruning.  All main chain blocks at or below this
+// height are no longer available.  It returns -1 when no blocks have been
+// pruned.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) PrunedHeight() int32 {
+	b.chainLock.RLock()
+	prunedHeight := b.prunedHeight
+	b.chainLock.RUnlock()
+	return prunedHeight
+}
+
+// IsPruned returns whether or not the data for any of the blocks in the main
+// chain has been removed by pruning.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) IsPruned() bool {
+	return b.PrunedHeight() != -1
+}
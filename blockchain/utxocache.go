@@ -0,0 +1,428 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/brsuite/brond/chaincfg/chainhash"
+	"github.com/brsuite/brond/database"
+	"github.com/brsuite/brond/wire"
+	"github.com/brsuite/bronutil"
+)
+
+const (
+	// utxoFlushPeriodicInterval is the maximum amount of time the utxo
+	// cache is allowed to go without being flushed to the database.  This
+	// bounds the number of blocks that need to be replayed to rebuild the
+	// utxo set after an unclean shutdown.
+	utxoFlushPeriodicInterval = 5 * time.Minute
+
+	// utxoEntryOverhead is the approximate number of bytes each cached
+	// entry uses in addition to its public key script.  It accounts for
+	// the entry itself (40 bytes), the outpoint key (36 bytes) and the
+	// pointer and bookkeeping the map keeps for every entry.
+	utxoEntryOverhead = 100
+)
+
+// flushMode is used to indicate how urgently the utxo cache is to be flushed.
+type flushMode uint8
+
+const (
+	// flushRequired is the flush mode that forces a flush regardless of
+	// the state of the cache.
+	flushRequired flushMode = iota
+
+	// flushPeriodic is the flush mode that flushes when the cache exceeds
+	// its maximum size or when it has not been flushed for the periodic
+	// flush interval.
+	flushPeriodic
+
+	// flushIfNeeded is the flush mode that only flushes when the cache
+	// exceeds its maximum size.
+	flushIfNeeded
+)
+
+// utxoCache is a size-bounded in-memory cache of the unspent transaction
+// output set that sits in front of the database.  Changes to the utxo set are
+// applied to the cache as blocks are connected and disconnected and are only
+// written to the database in large batches when the cache is flushed.
+//
+// The database stores the hash of the block the utxo set it contains is
+// consistent with so the set can be rebuilt by replaying the blocks connected
+// since then in the case of an unclean shutdown.
+type utxoCache struct {
+	db                  database.DB
+	maxTotalMemoryUsage uint64
+
+	// mtx protects the cached entries since they are populated as entries
+	// are loaded from the database, which happens when the chain lock is
+	// only held for reads.
+	mtx              sync.Mutex
+	cachedEntries    map[wire.OutPoint]*UtxoEntry
+	totalEntryMemory uint64
+
+	// lastFlushHash is the hash of the block the utxo set in the database
+	// is consistent with and lastFlushTime is the time the cache was last
+	// flushed.  They are protected by the chain lock.
+	lastFlushHash chainhash.Hash
+	lastFlushTime time.Time
+}
+
+// newUtxoCache returns a new utxo cache backed by the provided database which
+// is flushed whenever its estimated memory usage exceeds the provided maximum.
+func newUtxoCache(db database.DB, maxTotalMemoryUsage uint64) *utxoCache {
+	return &utxoCache{
+		db:                  db,
+		maxTotalMemoryUsage: maxTotalMemoryUsage,
+		cachedEntries:       make(map[wire.OutPoint]*UtxoEntry),
+		lastFlushTime:       time.Now(),
+	}
+}
+
+// entryMemory returns the approximate number of bytes the passed entry uses
+// while it is in the cache.
+func entryMemory(entry *UtxoEntry) uint64 {
+	return utxoEntryOverhead + uint64(len(entry.pkScript))
+}
+
+// putEntry adds or replaces the cached entry for the passed outpoint while
+// keeping track of the memory used by the cache.
+//
+// This function MUST be called with the cache lock held.
+func (c *utxoCache) putEntry(outpoint wire.OutPoint, entry *UtxoEntry) {
+	if cached, ok := c.cachedEntries[outpoint]; ok {
+		c.totalEntryMemory -= entryMemory(cached)
+	}
+	c.cachedEntries[outpoint] = entry
+	c.totalEntryMemory += entryMemory(entry)
+}
+
+// removeEntry removes the cached entry for the passed outpoint while keeping
+// track of the memory used by the cache.
+//
+// This function MUST be called with the cache lock held.
+func (c *utxoCache) removeEntry(outpoint wire.OutPoint) {
+	if cached, ok := c.cachedEntries[outpoint]; ok {
+		c.totalEntryMemory -= entryMemory(cached)
+		delete(c.cachedEntries, outpoint)
+	}
+}
+
+// fetchEntries populates the passed entries map with copies of the utxo
+// entries for the provided outpoints.  Entries that are not already cached are
+// loaded from the database and added to the cache.  Spent outputs, or those
+// which otherwise don't exist, result in a nil entry.
+//
+// This function is safe for concurrent access.
+func (c *utxoCache) fetchEntries(outpoints map[wire.OutPoint]struct{},
+	entries map[wire.OutPoint]*UtxoEntry) error {
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	// The entries handed out are copies without any of the cache state
+	// flags so that modifying them in a view does not affect the cache
+	// until the view is committed.
+	viewEntry := func(entry *UtxoEntry) *UtxoEntry {
+		if entry == nil || entry.IsSpent() {
+			return nil
+		}
+		clone := entry.Clone()
+		clone.packedFlags &^= tfModified | tfFresh
+		return clone
+	}
+
+	var missing []wire.OutPoint
+	for outpoint := range outpoints {
+		entry, ok := c.cachedEntries[outpoint]
+		if !ok {
+			missing = append(missing, outpoint)
+			continue
+		}
+		entries[outpoint] = viewEntry(entry)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return c.db.View(func(dbTx database.Tx) error {
+		for _, outpoint := range missing {
+			entry, err := dbFetchUtxoEntry(dbTx, outpoint)
+			if err != nil {
+				return err
+			}
+			if entry != nil {
+				c.putEntry(outpoint, entry)
+			}
+
+			entries[outpoint] = viewEntry(entry)
+		}
+
+		return nil
+	})
+}
+
+// commitView applies all of the entries in the passed view that have been
+// modified to the cache.  The passed block, when not nil, is the block the view
+// was just connected with, and the outputs it created are known not to exist
+// in the database.
+//
+// Spent entries that are fresh are removed from the cache outright since the
+// database never knew about them.  All other spent entries are kept as spent
+// so they are removed from the database on the next flush.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (c *utxoCache) commitView(view *UtxoViewpoint, block *bronutil.Block) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	var created map[chainhash.Hash]struct{}
+	if block != nil {
+		created = make(map[chainhash.Hash]struct{},
+			len(block.Transactions()))
+		for _, tx := range block.Transactions() {
+			created[*tx.Hash()] = struct{}{}
+		}
+	}
+
+	for outpoint, entry := range view.entries {
+		if entry == nil || !entry.isModified() {
+			continue
+		}
+
+		// The entry is only fresh when it was created by the connected
+		// block and the cache does not know of a version of it that is
+		// in the database.
+		cached := c.cachedEntries[outpoint]
+		_, isCreated := created[outpoint.Hash]
+		fresh := isCreated && (cached == nil || cached.isFresh())
+		if entry.IsSpent() {
+			if fresh || (cached != nil && cached.isFresh()) {
+				c.removeEntry(outpoint)
+				continue
+			}
+
+			c.putEntry(outpoint, &UtxoEntry{
+				packedFlags: tfSpent | tfModified,
+			})
+			continue
+		}
+
+		newEntry := entry.Clone()
+		newEntry.packedFlags |= tfModified
+		newEntry.packedFlags &^= tfFresh
+		if fresh {
+			newEntry.packedFlags |= tfFresh
+		}
+		c.putEntry(outpoint, newEntry)
+	}
+}
+
+// needsFlush returns whether or not the cache needs to be flushed according to
+// the passed flush mode.
+//
+// This function MUST be called with the chain state lock held.
+func (c *utxoCache) needsFlush(mode flushMode) bool {
+	c.mtx.Lock()
+	totalEntryMemory := c.totalEntryMemory
+	c.mtx.Unlock()
+
+	switch mode {
+	case flushRequired:
+		return true
+
+	case flushPeriodic:
+		if time.Since(c.lastFlushTime) >= utxoFlushPeriodicInterval {
+			return true
+		}
+	}
+
+	return totalEntryMemory > c.maxTotalMemoryUsage
+}
+
+// writeEntries uses an existing database transaction to write all of the
+// modified entries in the cache, followed by those in the passed view when it
+// is not nil, to the database and to mark the utxo set in the database as
+// consistent with the passed block hash.  The cache itself is not changed, so
+// the view must be committed to it with commitView and markFlushed must be
+// called once the transaction succeeded.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (c *utxoCache) writeEntries(dbTx database.Tx, view *UtxoViewpoint,
+	bestHash *chainhash.Hash) error {
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if err := dbPutUtxoEntries(dbTx, c.cachedEntries); err != nil {
+		return err
+	}
+	if view != nil {
+		if err := dbPutUtxoEntries(dbTx, view.entries); err != nil {
+			return err
+		}
+	}
+
+	return dbPutUtxoStateConsistency(dbTx, bestHash)
+}
+
+// markFlushed updates the state of the cache after its entries have been
+// written to the database as of the passed block hash.  Spent entries are
+// removed and all others are marked unmodified.  When the cache still exceeds
+// its maximum size afterwards, all entries are evicted.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (c *utxoCache) markFlushed(bestHash *chainhash.Hash) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	numEntries := len(c.cachedEntries)
+	for outpoint, entry := range c.cachedEntries {
+		if entry.IsSpent() {
+			c.removeEntry(outpoint)
+			continue
+		}
+
+		entry.packedFlags &^= tfModified | tfFresh
+	}
+
+	if c.totalEntryMemory > c.maxTotalMemoryUsage {
+		c.cachedEntries = make(map[wire.OutPoint]*UtxoEntry)
+		c.totalEntryMemory = 0
+	}
+
+	c.lastFlushHash = *bestHash
+	c.lastFlushTime = time.Now()
+
+	log.Debugf("Flushed utxo cache with %d entries to the database at "+
+		"block %v", numEntries, bestHash)
+}
+
+// flush writes the modified entries in the cache to the database according to
+// the passed flush mode and marks the utxo set in the database as consistent
+// with the passed block hash.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (c *utxoCache) flush(mode flushMode, bestHash *chainhash.Hash) error {
+	if !c.needsFlush(mode) {
+		return nil
+	}
+
+	err := c.db.Update(func(dbTx database.Tx) error {
+		return c.writeEntries(dbTx, nil, bestHash)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.markFlushed(bestHash)
+	return nil
+}
+
+// initUtxoCache brings the utxo set in the database in line with the current
+// best chain.  Since the best chain state is written as each block is connected
+// while the utxo set is only written when the cache is flushed, the utxo set
+// lags behind after an unclean shutdown.  In that case the blocks connected
+// since the last flush are replayed to rebuild it.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) initUtxoCache(interrupt <-chan struct{}) error {
+	var consistentHash *chainhash.Hash
+	err := b.db.View(func(dbTx database.Tx) error {
+		var err error
+		consistentHash, err = dbFetchUtxoStateConsistency(dbTx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// The utxo set was written along with every block before the cache
+	// existed, so it is consistent with the tip when there is no hash.
+	tip := b.bestChain.Tip()
+	if consistentHash == nil {
+		return b.utxoCache.flush(flushRequired, &tip.hash)
+	}
+	if *consistentHash == tip.hash {
+		b.utxoCache.lastFlushHash = tip.hash
+		return nil
+	}
+
+	// The utxo set can only be rebuilt when the block it is consistent
+	// with is an ancestor of the current tip since the replay only
+	// connects blocks.
+	node := b.index.LookupNode(consistentHash)
+	if node == nil || !b.bestChain.Contains(node) {
+		return AssertError(fmt.Sprintf("utxo set is consistent with "+
+			"block %v which is not part of the main chain",
+			consistentHash))
+	}
+	b.utxoCache.lastFlushHash = node.hash
+
+	log.Infof("Rebuilding the utxo set from height %d to %d after an "+
+		"unclean shutdown", node.height+1, tip.height)
+
+	for n := b.bestChain.Next(node); n != nil; n = b.bestChain.Next(n) {
+		if interruptRequested(interrupt) {
+			return errInterruptRequested
+		}
+
+		var block *bronutil.Block
+		err := b.db.View(func(dbTx database.Tx) error {
+			var err error
+			block, err = dbFetchBlockByNode(dbTx, n)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		// The block was already validated when it was originally
+		// connected, so it only needs to be applied to the utxo set.
+		view := NewUtxoViewpoint()
+		err = view.fetchInputUtxos(b.utxoCache, block)
+		if err != nil {
+			return err
+		}
+		err = view.connectTransactions(block, nil)
+		if err != nil {
+			return err
+		}
+		b.utxoCache.commitView(view, freshOutputsBlock(n, block))
+
+		err = b.utxoCache.flush(flushIfNeeded, &n.hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return b.utxoCache.flush(flushRequired, &tip.hash)
+}
+
+// freshOutputsBlock returns the block to commit a view connected with the
+// passed block to the utxo cache with.  The outputs created by a block never
+// exist in the database, except for the two blocks BIP0030 exempts since their
+// duplicate transactions overwrote unspent outputs, so nil is returned for them.
+func freshOutputsBlock(node *blockNode, block *bronutil.Block) *bronutil.Block {
+	if isBIP0030Node(node) {
+		return nil
+	}
+	return block
+}
+
+// FlushUtxoCache writes all of the changes to the utxo set that are only held
+// in the utxo cache to the database.  It is typically called on shutdown so
+// the utxo set does not have to be rebuilt on the next start.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) FlushUtxoCache() error {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	return b.utxoCache.flush(flushRequired, &b.bestChain.Tip().hash)
+}
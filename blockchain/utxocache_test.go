@@ -0,0 +1,166 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/brsuite/brond/chaincfg"
+	"github.com/brsuite/brond/chaincfg/chainhash"
+	"github.com/brsuite/brond/database"
+	"github.com/brsuite/brond/txscript"
+	"github.com/brsuite/brond/wire"
+	"github.com/brsuite/bronutil"
+)
+
+// TestUtxoCacheFlush ensures entries in the utxo cache are only written to the
+// database when the cache is flushed, that fresh entries which are spent before
+// a flush never reach the database, and that the consistency hash is updated.
+func TestUtxoCacheFlush(t *testing.T) {
+	chain, teardownFunc, err := chainSetup("utxocacheflush",
+		&chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatalf("Failed to setup chain instance: %v", err)
+	}
+	defer teardownFunc()
+
+	cache := chain.utxoCache
+	cache.maxTotalMemoryUsage = 1024 * 1024
+
+	// dbEntry returns the entry for the passed outpoint in the database.
+	dbEntry := func(outpoint wire.OutPoint) *UtxoEntry {
+		var entry *UtxoEntry
+		err := chain.db.View(func(dbTx database.Tx) error {
+			var err error
+			entry, err = dbFetchUtxoEntry(dbTx, outpoint)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("dbFetchUtxoEntry: unexpected error: %v", err)
+		}
+		return entry
+	}
+
+	// spend loads the passed outpoint through the cache, spends it and
+	// commits the spend to the cache.
+	spend := func(outpoint wire.OutPoint) {
+		view := NewUtxoViewpoint()
+		err := view.fetchUtxosMain(cache, map[wire.OutPoint]struct{}{
+			outpoint: {},
+		})
+		if err != nil {
+			t.Fatalf("fetchUtxosMain: unexpected error: %v", err)
+		}
+		entry := view.LookupEntry(outpoint)
+		if entry == nil {
+			t.Fatalf("fetchUtxosMain: missing entry for %v", outpoint)
+		}
+		entry.Spend()
+		cache.commitView(view, nil)
+	}
+
+	// addOutput adds a new output paying the passed amount to the cache
+	// and returns its outpoint.  The output is committed as created by a
+	// connected block when fromBlock is set.
+	addOutput := func(amount int64, fromBlock bool) wire.OutPoint {
+		msgTx := wire.NewMsgTx(wire.TxVersion)
+		msgTx.AddTxIn(&wire.TxIn{})
+		msgTx.AddTxOut(wire.NewTxOut(amount, []byte{txscript.OP_TRUE}))
+		tx := bronutil.NewTx(msgTx)
+
+		view := NewUtxoViewpoint()
+		view.AddTxOut(tx, 0, 1)
+		var block *bronutil.Block
+		if fromBlock {
+			block = bronutil.NewBlock(&wire.MsgBlock{
+				Transactions: []*wire.MsgTx{msgTx},
+			})
+		}
+		cache.commitView(view, block)
+		return wire.OutPoint{Hash: *tx.Hash(), Index: 0}
+	}
+	newOutput := func(amount int64) wire.OutPoint {
+		return addOutput(amount, true)
+	}
+
+	// A newly created output is fresh and only lives in the cache.
+	fresh := newOutput(1000)
+	entry := cache.cachedEntries[fresh]
+	if entry == nil || !entry.isFresh() || !entry.isModified() {
+		t.Fatalf("new output is not a modified fresh cache entry")
+	}
+	if dbEntry(fresh) != nil {
+		t.Fatalf("new output was written to the database before flush")
+	}
+
+	// Spending a fresh output removes it from the cache outright.
+	spend(fresh)
+	if _, ok := cache.cachedEntries[fresh]; ok {
+		t.Fatalf("spent fresh output is still in the cache")
+	}
+
+	// An output which was not created by a connected block might be in
+	// the database, so spending it must leave a spent entry behind.
+	unknown := addOutput(500, false)
+	entry = cache.cachedEntries[unknown]
+	if entry == nil || entry.isFresh() || !entry.isModified() {
+		t.Fatalf("output not created by a block is not a modified " +
+			"non-fresh cache entry")
+	}
+	spend(unknown)
+	entry = cache.cachedEntries[unknown]
+	if entry == nil || !entry.IsSpent() {
+		t.Fatalf("spent non-fresh output is not a spent cache entry")
+	}
+
+	// Flushing writes new outputs to the database and records the block
+	// the utxo set is consistent with.
+	flushed := newOutput(2000)
+	bestHash := chainhash.Hash{0x01}
+	if err := cache.flush(flushRequired, &bestHash); err != nil {
+		t.Fatalf("flush: unexpected error: %v", err)
+	}
+	if got := dbEntry(flushed); got == nil || got.Amount() != 2000 {
+		t.Fatalf("flushed output not found in the database")
+	}
+	entry = cache.cachedEntries[flushed]
+	if entry == nil || entry.isFresh() || entry.isModified() {
+		t.Fatalf("flushed output is not an unmodified cache entry")
+	}
+	var consistentHash *chainhash.Hash
+	err = chain.db.View(func(dbTx database.Tx) error {
+		var err error
+		consistentHash, err = dbFetchUtxoStateConsistency(dbTx)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("dbFetchUtxoStateConsistency: unexpected error: %v", err)
+	}
+	if consistentHash == nil || *consistentHash != bestHash {
+		t.Fatalf("unexpected consistency hash: got %v, want %v",
+			consistentHash, bestHash)
+	}
+
+	// Spending an output that is in the database hides it from callers
+	// right away, but it is only removed from the database on flush.
+	spend(flushed)
+	if got, err := chain.FetchUtxoEntry(flushed); err != nil || got != nil {
+		t.Fatalf("FetchUtxoEntry: spent output returned (entry %v, "+
+			"err %v)", got, err)
+	}
+	if dbEntry(flushed) == nil {
+		t.Fatalf("spent output removed from database before flush")
+	}
+	if err := cache.flush(flushRequired, &bestHash); err != nil {
+		t.Fatalf("flush: unexpected error: %v", err)
+	}
+	if dbEntry(flushed) != nil {
+		t.Fatalf("spent output still in the database after flush")
+	}
+	if len(cache.cachedEntries) != 0 || cache.totalEntryMemory != 0 {
+		t.Fatalf("cache not empty after flush (%d entries, %d bytes)",
+			len(cache.cachedEntries), cache.totalEntryMemory)
+	}
+}
@@ -28,6 +28,11 @@ const (
 	// tfModified indicates that a txout has been modified since it was
 	// loaded.
 	tfModified
+
+	// tfFresh indicates that a txout was created after it was last known
+	// to be absent from the database, so it does not need to be removed
+	// from the database when it is spent.
+	tfFresh
 )
 
 // UtxoEntry houses details about an individual transaction output in a utxo
@@ -58,6 +63,12 @@ func (entry *UtxoEntry) isModified() bool {
 	return entry.packedFlags&tfModified == tfModified
 }
 
+// isFresh returns whether or not the output is known to not exist in the
+// database.
+func (entry *UtxoEntry) isFresh() bool {
+	return entry.packedFlags&tfFresh == tfFresh
+}
+
 // IsCoinBase returns whether or not the output was contained in a coinbase
 // transaction.
 func (entry *UtxoEntry) IsCoinBase() bool {
@@ -143,6 +154,20 @@ func (view *UtxoViewpoint) LookupEntry(outpoint wire.OutPoint) *UtxoEntry {
 	return view.entries[outpoint]
 }
 
+// FetchPrevOutput returns the transaction output referenced by the passed
+// outpoint according to the current state of the view, or nil when it is not
+// available.  Spent entries are still returned so the view can supply the
+// outputs spent by a block while its scripts are validated.
+//
+// This is part of the txscript.PrevOutputFetcher interface.
+func (view *UtxoViewpoint) FetchPrevOutput(op wire.OutPoint) *wire.TxOut {
+	entry := view.entries[op]
+	if entry == nil {
+		return nil
+	}
+	return wire.NewTxOut(entry.Amount(), entry.PkScript())
+}
+
 // addTxOut adds the specified output to the view if it is not provably
 // unspendable.  When the view already has an entry for the output, it will be
 // marked unspent.  All fields will be updated for existing entries since it's
@@ -281,6 +306,9 @@ func (view *UtxoViewpoint) connectTransactions(block *bronutil.Block, stxos *[]S
 // fetchEntryByHash attempts to find any available utxo for the given hash by
 // searching the entire set of possible outputs for the given hash.  It checks
 // the view first and then falls back to the database if needed.
+//
+// The utxo cache is not consulted, so callers must ensure it has been flushed
+// before calling this function.
 func (view *UtxoViewpoint) fetchEntryByHash(db database.DB, hash *chainhash.Hash) (*UtxoEntry, error) {
 	// First attempt to find a utxo with the provided hash in the view.
 	prevOut := wire.OutPoint{Hash: *hash}
@@ -465,12 +493,13 @@ func (view *UtxoViewpoint) commit() {
 
 // fetchUtxosMain fetches unspent transaction output data about the provided
 // set of outpoints from the point of view of the end of the main chain at the
-// time of the call.
+// time of the call.  The utxo cache is consulted first and any entries it does
+// not have are loaded from the database.
 //
 // Upon completion of this function, the view will contain an entry for each
 // requested outpoint.  Spent outputs, or those which otherwise don't exist,
 // will result in a nil entry in the view.
-func (view *UtxoViewpoint) fetchUtxosMain(db database.DB, outpoints map[wire.OutPoint]struct{}) error {
+func (view *UtxoViewpoint) fetchUtxosMain(cache *utxoCache, outpoints map[wire.OutPoint]struct{}) error {
 	// Nothing to do if there are no requested outputs.
 	if len(outpoints) == 0 {
 		return nil
@@ -483,24 +512,13 @@ func (view *UtxoViewpoint) fetchUtxosMain(db database.DB, outpoints map[wire.Out
 	// will result in nil entries in the view.  This is intentionally done
 	// so other code can use the presence of an entry in the store as a way
 	// to unnecessarily avoid attempting to reload it from the database.
-	return db.View(func(dbTx database.Tx) error {
-		for outpoint := range outpoints {
-			entry, err := dbFetchUtxoEntry(dbTx, outpoint)
-			if err != nil {
-				return err
-			}
-
-			view.entries[outpoint] = entry
-		}
-
-		return nil
-	})
+	return cache.fetchEntries(outpoints, view.entries)
 }
 
 // fetchUtxos loads the unspent transaction outputs for the provided set of
 // outputs into the view from the database as needed unless they already exist
 // in the view in which case they are ignored.
-func (view *UtxoViewpoint) fetchUtxos(db database.DB, outpoints map[wire.OutPoint]struct{}) error {
+func (view *UtxoViewpoint) fetchUtxos(cache *utxoCache, outpoints map[wire.OutPoint]struct{}) error {
 	// Nothing to do if there are no requested outputs.
 	if len(outpoints) == 0 {
 		return nil
@@ -517,8 +535,8 @@ func (view *UtxoViewpoint) fetchUtxos(db database.DB, outpoints map[wire.OutPoin
 		neededSet[outpoint] = struct{}{}
 	}
 
-	// Request the input utxos from the database.
-	return view.fetchUtxosMain(db, neededSet)
+	// Request the input utxos from the cache and database.
+	return view.fetchUtxosMain(cache, neededSet)
 }
 
 // fetchInputUtxos loads the unspent transaction outputs for the inputs
@@ -526,7 +544,7 @@ func (view *UtxoViewpoint) fetchUtxos(db database.DB, outpoints map[wire.OutPoin
 // database as needed.  In particular, referenced entries that are earlier in
 // the block are added to the view and entries that are already in the view are
 // not modified.
-func (view *UtxoViewpoint) fetchInputUtxos(db database.DB, block *bronutil.Block) error {
+func (view *UtxoViewpoint) fetchInputUtxos(cache *utxoCache, block *bronutil.Block) error {
 	// Build a map of in-flight transactions because some of the inputs in
 	// this block could be referencing other transactions earlier in this
 	// block which are not yet in the chain.
@@ -572,8 +590,8 @@ func (view *UtxoViewpoint) fetchInputUtxos(db database.DB, block *bronutil.Block
 		}
 	}
 
-	// Request the input utxos from the database.
-	return view.fetchUtxosMain(db, neededSet)
+	// Request the input utxos from the cache and database.
+	return view.fetchUtxosMain(cache, neededSet)
 }
 
 // NewUtxoViewpoint returns a new empty unspent transaction output view.
@@ -609,7 +627,7 @@ func (b *BlockChain) FetchUtxoView(tx *bronutil.Tx) (*UtxoViewpoint, error) {
 	// chain.
 	view := NewUtxoViewpoint()
 	b.chainLock.RLock()
-	err := view.fetchUtxosMain(b.db, neededSet)
+	err := view.fetchUtxosMain(b.utxoCache, neededSet)
 	b.chainLock.RUnlock()
 	return view, err
 }
@@ -628,15 +646,13 @@ func (b *BlockChain) FetchUtxoEntry(outpoint wire.OutPoint) (*UtxoEntry, error)
 	b.chainLock.RLock()
 	defer b.chainLock.RUnlock()
 
-	var entry *UtxoEntry
-	err := b.db.View(func(dbTx database.Tx) error {
-		var err error
-		entry, err = dbFetchUtxoEntry(dbTx, outpoint)
-		return err
-	})
+	entries := make(map[wire.OutPoint]*UtxoEntry, 1)
+	err := b.utxoCache.fetchEntries(map[wire.OutPoint]struct{}{
+		outpoint: {},
+	}, entries)
 	if err != nil {
 		return nil, err
 	}
 
-	return entry, nil
+	return entries[outpoint], nil
 }
@@ -840,7 +840,7 @@ func (b *BlockChain) checkBIP0030(node *blockNode, block *bronutil.Block, view *
 			fetchSet[prevOut] = struct{}{}
 		}
 	}
-	err := view.fetchUtxos(b.db, fetchSet)
+	err := view.fetchUtxos(b.utxoCache, fetchSet)
 	if err != nil {
 		return err
 	}
@@ -1036,7 +1036,7 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *bronutil.Block, v
 	//
 	// These utxo entries are needed for verification of things such as
 	// transaction inputs, counting pay-to-script-hashes, and scripts.
-	err := view.fetchInputUtxos(b.db, block)
+	err := view.fetchInputUtxos(b.utxoCache, block)
 	if err != nil {
 		return err
 	}
@@ -1219,6 +1219,17 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *bronutil.Block, v
 		scriptFlags |= txscript.ScriptStrictMultiSig
 	}
 
+	// Enforce the taproot soft-fork package once the soft-fork has shifted
+	// into the "active" version bits state.
+	taprootState, err := b.deploymentState(node.parent,
+		chaincfg.DeploymentTaproot)
+	if err != nil {
+		return err
+	}
+	if taprootState == ThresholdActive {
+		scriptFlags |= txscript.ScriptVerifyTaproot
+	}
+
 	// Now that the inexpensive checks are done and have passed, verify the
 	// transactions are actually allowed to spend the coins by running the
 	// expensive ECDSA signature check scripts.  Doing this last helps
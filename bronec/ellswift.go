@@ -0,0 +1,274 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package bronec
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/brsuite/brond/chaincfg/chainhash"
+)
+
+// EllSwiftPubKeyBytesLen is the length of a public key encoded with the
+// ElligatorSwift encoding defined by BIP0324.
+const EllSwiftPubKeyBytesLen = 64
+
+// bip324ECDHTag is the tag of the tagged hash used to derive the shared secret
+// of a BIP0324 key exchange.
+var bip324ECDHTag = []byte("bip324_ellswift_xonly_ecdh")
+
+// fieldOps provides modular arithmetic over the field of the secp256k1 curve
+// on big integers.  The results are always fully reduced.
+type fieldOps struct {
+	p *big.Int
+}
+
+// add returns a+b.
+func (f fieldOps) add(a, b *big.Int) *big.Int {
+	return new(big.Int).Mod(new(big.Int).Add(a, b), f.p)
+}
+
+// sub returns a-b.
+func (f fieldOps) sub(a, b *big.Int) *big.Int {
+	return new(big.Int).Mod(new(big.Int).Sub(a, b), f.p)
+}
+
+// mul returns a*b.
+func (f fieldOps) mul(a, b *big.Int) *big.Int {
+	return new(big.Int).Mod(new(big.Int).Mul(a, b), f.p)
+}
+
+// neg returns -a.
+func (f fieldOps) neg(a *big.Int) *big.Int {
+	return new(big.Int).Mod(new(big.Int).Neg(a), f.p)
+}
+
+// div returns a/b.  The divisor must not be zero.
+func (f fieldOps) div(a, b *big.Int) *big.Int {
+	return f.mul(a, new(big.Int).ModInverse(b, f.p))
+}
+
+// sqrt returns a square root of a, or nil when a is not a square.
+func (f fieldOps) sqrt(a *big.Int) *big.Int {
+	e := new(big.Int).Rsh(new(big.Int).Add(f.p, big.NewInt(1)), 2)
+	r := new(big.Int).Exp(a, e, f.p)
+	if f.mul(r, r).Cmp(new(big.Int).Mod(a, f.p)) != 0 {
+		return nil
+	}
+	return r
+}
+
+// c returns the square root of -3 used by the ElligatorSwift encoding.  It is
+// computed as (P-3)^((P+1)/4) so the same root as BIP0324 is used.
+func (f fieldOps) c() *big.Int {
+	return f.sqrt(new(big.Int).Sub(f.p, big.NewInt(3)))
+}
+
+// curveRHS returns x^3 + 7, the right hand side of the curve equation.
+func (f fieldOps) curveRHS(x *big.Int) *big.Int {
+	return f.add(f.mul(f.mul(x, x), x), big.NewInt(7))
+}
+
+// isValidX returns whether x is the x coordinate of a point on the curve.
+func (f fieldOps) isValidX(x *big.Int) bool {
+	return f.sqrt(f.curveRHS(x)) != nil
+}
+
+// xSwiftEC returns the x coordinate of the point on the curve which the field
+// elements u and t encode as defined by BIP0324.  Every pair of field elements
+// encodes a point, but since u and t may come from a remote peer, an error is
+// returned instead of panicking should none of the candidates be on the curve.
+func xSwiftEC(u, t *big.Int) (*big.Int, error) {
+	f := fieldOps{S256().P}
+	if u.Sign() == 0 {
+		u = big.NewInt(1)
+	}
+	if t.Sign() == 0 {
+		t = big.NewInt(1)
+	}
+	if f.add(f.curveRHS(u), f.mul(t, t)).Sign() == 0 {
+		t = f.add(t, t)
+	}
+
+	// X = (u^3 + 7 - t^2) / (2t)
+	// Y = (X + t) / (c*u)
+	x := f.div(f.sub(f.curveRHS(u), f.mul(t, t)), f.add(t, t))
+	y := f.div(f.add(x, t), f.mul(f.c(), u))
+
+	// Return the first candidate which is on the curve.  One of them always
+	// is by construction.
+	half := new(big.Int).ModInverse(big.NewInt(2), f.p)
+	xy := f.div(x, y)
+	candidates := []*big.Int{
+		f.add(u, f.mul(big.NewInt(4), f.mul(y, y))),
+		f.mul(f.sub(f.neg(xy), u), half),
+		f.mul(f.sub(xy, u), half),
+	}
+	for _, candidate := range candidates {
+		if f.isValidX(candidate) {
+			return candidate, nil
+		}
+	}
+	return nil, errors.New("no valid x coordinate for ElligatorSwift " +
+		"encoding")
+}
+
+// xSwiftECInv returns a field element t such that xSwiftEC(u, t) is x for the
+// passed branch, which is a number from 0 to 7, or nil when there is none.  The
+// x coordinate must be on the curve and u must not be zero.
+func xSwiftECInv(x, u *big.Int, branch int) *big.Int {
+	f := fieldOps{S256().P}
+	half := new(big.Int).ModInverse(big.NewInt(2), f.p)
+	u2 := f.mul(u, u)
+
+	var s, v *big.Int
+	if branch&2 == 0 {
+		// The encoding is only possible when -x-u is not on the curve.
+		if f.isValidX(f.neg(f.add(x, u))) {
+			return nil
+		}
+
+		// s = -(u^3 + 7) / (u^2 + u*v + v^2)
+		v = x
+		d := f.add(f.add(u2, f.mul(u, v)), f.mul(v, v))
+		if d.Sign() == 0 {
+			return nil
+		}
+		s = f.div(f.neg(f.curveRHS(u)), d)
+	} else {
+		s = f.sub(x, u)
+		if s.Sign() == 0 {
+			return nil
+		}
+
+		// r = sqrt(-s * (4*(u^3 + 7) + 3*s*u^2))
+		// v = (r/s - u) / 2
+		q := f.add(f.mul(big.NewInt(4), f.curveRHS(u)),
+			f.mul(big.NewInt(3), f.mul(s, u2)))
+		r := f.sqrt(f.neg(f.mul(s, q)))
+		if r == nil || (branch&1 == 1 && r.Sign() == 0) {
+			return nil
+		}
+		v = f.mul(f.sub(f.div(r, s), u), half)
+	}
+
+	w := f.sqrt(s)
+	if w == nil {
+		return nil
+	}
+
+	one, c := big.NewInt(1), f.c()
+	switch branch & 5 {
+	case 0:
+		return f.neg(f.mul(w, f.add(f.mul(f.mul(u, f.sub(one, c)), half), v)))
+	case 1:
+		return f.mul(w, f.add(f.mul(f.mul(u, f.add(one, c)), half), v))
+	case 4:
+		return f.mul(w, f.add(f.mul(f.mul(u, f.sub(one, c)), half), v))
+	default:
+		return f.neg(f.mul(w, f.add(f.mul(f.mul(u, f.add(one, c)), half), v)))
+	}
+}
+
+// SerializeEllSwift encodes the public key with the ElligatorSwift encoding
+// defined by BIP0324.  Only the x coordinate of the key is encoded.  The
+// encoding is chosen at random among the possible ones for the key, so the
+// result is indistinguishable from 64 uniformly random bytes.
+func (p *PublicKey) SerializeEllSwift() ([]byte, error) {
+	curve := S256()
+	f := fieldOps{curve.P}
+	if p.X == nil || p.X.Cmp(curve.P) >= 0 || !f.isValidX(p.X) {
+		return nil, errors.New("public key is not on the curve")
+	}
+
+	var buf [33]byte
+	for {
+		if _, err := rand.Read(buf[:]); err != nil {
+			return nil, err
+		}
+
+		// Pick a random non-zero u and a random branch, and retry when
+		// there is no encoding for them.  The result is decoded again to
+		// rule out the edge cases of the inverse map which don't round
+		// trip, such as a zero t.
+		u := new(big.Int).SetBytes(buf[:32])
+		if u.Sign() == 0 || u.Cmp(curve.P) >= 0 {
+			continue
+		}
+		t := xSwiftECInv(p.X, u, int(buf[32]&7))
+		if t == nil || t.Sign() == 0 {
+			continue
+		}
+		x, err := xSwiftEC(u, t)
+		if err != nil {
+			return nil, err
+		}
+		if x.Cmp(p.X) != 0 {
+			continue
+		}
+
+		b := make([]byte, 0, EllSwiftPubKeyBytesLen)
+		b = paddedAppend(32, b, u.Bytes())
+		return paddedAppend(32, b, t.Bytes()), nil
+	}
+}
+
+// ParseEllSwiftPubKey decodes a 64-byte public key encoded with the
+// ElligatorSwift encoding defined by BIP0324.  Any 64 bytes are a valid
+// encoding.  The encoding only determines the x coordinate of the key, so the
+// returned key is the point with that x coordinate and an even y coordinate.
+func ParseEllSwiftPubKey(pubKeyStr []byte) (*PublicKey, error) {
+	if len(pubKeyStr) != EllSwiftPubKeyBytesLen {
+		return nil, fmt.Errorf("invalid ellswift pub key length %d",
+			len(pubKeyStr))
+	}
+
+	curve := S256()
+	u := new(big.Int).Mod(new(big.Int).SetBytes(pubKeyStr[:32]), curve.P)
+	t := new(big.Int).Mod(new(big.Int).SetBytes(pubKeyStr[32:]), curve.P)
+	x, err := xSwiftEC(u, t)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decompressPoint(curve, x, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// GenerateEllSwiftSharedSecret generates the shared secret of a BIP0324 key
+// exchange between the private key, whose public key is ours, and their
+// public key.  Both public keys are ElligatorSwift encoded as sent over the
+// wire, and initiator specifies whether our side initiated the connection,
+// which determines the order the public keys are hashed in.
+func GenerateEllSwiftSharedSecret(privKey *PrivateKey, theirs, ours []byte,
+	initiator bool) ([]byte, error) {
+
+	if len(ours) != EllSwiftPubKeyBytesLen {
+		return nil, fmt.Errorf("invalid ellswift pub key length %d",
+			len(ours))
+	}
+	pubKey, err := ParseEllSwiftPubKey(theirs)
+	if err != nil {
+		return nil, err
+	}
+
+	// The shared x coordinate is the same for both sides since the y
+	// coordinates of the public keys don't affect it.
+	x, _ := pubKey.Curve.ScalarMult(pubKey.X, pubKey.Y, privKey.D.Bytes())
+	xBytes := paddedAppend(32, nil, x.Bytes())
+
+	var secret *chainhash.Hash
+	if initiator {
+		secret = chainhash.TaggedHash(bip324ECDHTag, ours, theirs, xBytes)
+	} else {
+		secret = chainhash.TaggedHash(bip324ECDHTag, theirs, ours, xBytes)
+	}
+	return secret[:], nil
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package bronec
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// TestEllSwiftRoundTrip ensures public keys encoded with the ElligatorSwift
+// encoding decode to the key with the same x coordinate and an even y
+// coordinate, and that encoding the same key twice gives different results.
+func TestEllSwiftRoundTrip(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		privKey, err := NewPrivateKey(S256())
+		if err != nil {
+			t.Fatalf("NewPrivateKey: unexpected error %v", err)
+		}
+		pubKey := privKey.PubKey()
+
+		enc, err := pubKey.SerializeEllSwift()
+		if err != nil {
+			t.Fatalf("SerializeEllSwift: unexpected error %v", err)
+		}
+		if len(enc) != EllSwiftPubKeyBytesLen {
+			t.Fatalf("SerializeEllSwift: got %d bytes, want %d",
+				len(enc), EllSwiftPubKeyBytesLen)
+		}
+		decoded, err := ParseEllSwiftPubKey(enc)
+		if err != nil {
+			t.Fatalf("ParseEllSwiftPubKey: unexpected error %v", err)
+		}
+		if decoded.X.Cmp(pubKey.X) != 0 || isOdd(decoded.Y) {
+			t.Fatalf("ParseEllSwiftPubKey: got (%x, %x), want x %x "+
+				"with even y", decoded.X, decoded.Y, pubKey.X)
+		}
+
+		enc2, err := pubKey.SerializeEllSwift()
+		if err != nil {
+			t.Fatalf("SerializeEllSwift: unexpected error %v", err)
+		}
+		if bytes.Equal(enc, enc2) {
+			t.Fatalf("SerializeEllSwift: got the same encoding %x "+
+				"twice", enc)
+		}
+	}
+}
+
+// TestParseEllSwiftPubKey ensures any 64 bytes decode to a point on the curve,
+// including the edge cases of the encoding, to the expected x coordinate when
+// it is known, and that other lengths are rejected.
+func TestParseEllSwiftPubKey(t *testing.T) {
+	p := S256().P
+	pMinus1 := paddedAppend(32, nil, new(big.Int).Sub(p, big.NewInt(1)).Bytes())
+	tests := []struct {
+		name  string
+		enc   []byte
+		wantX string
+	}{
+		{
+			// From the BIP0324 decoding test vectors.
+			name:  "all zero",
+			enc:   make([]byte, 64),
+			wantX: "edd1fd3e327ce90cc7a3542614289aee9682003e9cf7dcc9cf2ca9743be5aa0c",
+		},
+		{name: "all ones", enc: bytes.Repeat([]byte{0xff}, 64)},
+		{name: "u zero", enc: append(make([]byte, 32), pMinus1...)},
+		{name: "t zero", enc: append(pMinus1, make([]byte, 32)...)},
+		{name: "u and t P", enc: append(paddedAppend(32, nil, p.Bytes()),
+			paddedAppend(32, nil, p.Bytes())...)},
+	}
+	for _, test := range tests {
+		pubKey, err := ParseEllSwiftPubKey(test.enc)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if !S256().IsOnCurve(pubKey.X, pubKey.Y) {
+			t.Errorf("%s: decoded point is not on the curve", test.name)
+		}
+		if test.wantX != "" && fmt.Sprintf("%064x", pubKey.X) != test.wantX {
+			t.Errorf("%s: got x %064x, want %s", test.name, pubKey.X,
+				test.wantX)
+		}
+	}
+
+	for _, size := range []int{0, 33, 63, 65} {
+		if _, err := ParseEllSwiftPubKey(make([]byte, size)); err == nil {
+			t.Errorf("ParseEllSwiftPubKey: no error for %d bytes", size)
+		}
+	}
+}
+
+// TestEllSwiftSharedSecret ensures both sides of a key exchange generate the
+// same shared secret, which depends on which side is the initiator.
+func TestEllSwiftSharedSecret(t *testing.T) {
+	initPriv, _ := NewPrivateKey(S256())
+	respPriv, _ := NewPrivateKey(S256())
+	initEnc, err := initPriv.PubKey().SerializeEllSwift()
+	if err != nil {
+		t.Fatalf("SerializeEllSwift: unexpected error %v", err)
+	}
+	respEnc, err := respPriv.PubKey().SerializeEllSwift()
+	if err != nil {
+		t.Fatalf("SerializeEllSwift: unexpected error %v", err)
+	}
+
+	initSecret, err := GenerateEllSwiftSharedSecret(initPriv, respEnc,
+		initEnc, true)
+	if err != nil {
+		t.Fatalf("GenerateEllSwiftSharedSecret: unexpected error %v", err)
+	}
+	respSecret, err := GenerateEllSwiftSharedSecret(respPriv, initEnc,
+		respEnc, false)
+	if err != nil {
+		t.Fatalf("GenerateEllSwiftSharedSecret: unexpected error %v", err)
+	}
+	if !bytes.Equal(initSecret, respSecret) {
+		t.Fatalf("shared secrets differ: %x and %x", initSecret,
+			respSecret)
+	}
+
+	// Swapping the roles gives a different secret.
+	swapped, err := GenerateEllSwiftSharedSecret(initPriv, respEnc,
+		initEnc, false)
+	if err != nil {
+		t.Fatalf("GenerateEllSwiftSharedSecret: unexpected error %v", err)
+	}
+	if bytes.Equal(initSecret, swapped) {
+		t.Fatal("shared secret does not depend on the initiator")
+	}
+}
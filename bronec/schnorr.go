@@ -0,0 +1,230 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package bronec
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/brsuite/brond/chaincfg/chainhash"
+)
+
+// These constants define the lengths of serialized BIP0340 public keys and
+// signatures.
+const (
+	SchnorrPubKeyBytesLen = 32
+	SchnorrSigBytesLen    = 64
+)
+
+var (
+	// bip340AuxTag, bip340NonceTag and bip340ChallengeTag are the tags of
+	// the tagged hashes used when creating and verifying BIP0340
+	// signatures.
+	bip340AuxTag       = []byte("BIP0340/aux")
+	bip340NonceTag     = []byte("BIP0340/nonce")
+	bip340ChallengeTag = []byte("BIP0340/challenge")
+)
+
+// SchnorrSignature is a type representing a BIP0340 Schnorr signature.  R is
+// the x coordinate of the nonce point, which is always the point with the even
+// y coordinate.
+type SchnorrSignature struct {
+	R *big.Int
+	S *big.Int
+}
+
+// ParseSchnorrSignature parses a 64-byte BIP0340 signature into a
+// SchnorrSignature.  An error is returned if R is not a valid x coordinate or
+// S is not less than the order of the curve.
+func ParseSchnorrSignature(sigStr []byte) (*SchnorrSignature, error) {
+	if len(sigStr) != SchnorrSigBytesLen {
+		return nil, fmt.Errorf("malformed schnorr signature: wrong "+
+			"length %d", len(sigStr))
+	}
+
+	curve := S256()
+	r := new(big.Int).SetBytes(sigStr[:32])
+	if r.Cmp(curve.P) >= 0 {
+		return nil, errors.New("signature R is >= curve.P")
+	}
+	s := new(big.Int).SetBytes(sigStr[32:])
+	if s.Cmp(curve.N) >= 0 {
+		return nil, errors.New("signature S is >= curve.N")
+	}
+
+	return &SchnorrSignature{R: r, S: s}, nil
+}
+
+// Serialize returns the signature in the 64-byte format defined by BIP0340,
+// which is the 32-byte big-endian encoding of R followed by that of S.
+func (sig *SchnorrSignature) Serialize() []byte {
+	b := make([]byte, 0, SchnorrSigBytesLen)
+	b = paddedAppend(32, b, sig.R.Bytes())
+	return paddedAppend(32, b, sig.S.Bytes())
+}
+
+// Verify returns whether or not the signature is a valid BIP0340 signature of
+// the 32-byte hash for the public key.  Only the x coordinate of the public
+// key is used as required by BIP0340, so a key and its negation verify the
+// same signatures.
+func (sig *SchnorrSignature) Verify(hash []byte, pubKey *PublicKey) bool {
+	if len(hash) != 32 {
+		return false
+	}
+	return schnorrVerify(sig, hash, pubKey.X)
+}
+
+// IsEqual compares this SchnorrSignature instance to the one passed, returning
+// true if both signatures have the same R and S values.
+func (sig *SchnorrSignature) IsEqual(otherSig *SchnorrSignature) bool {
+	return sig.R.Cmp(otherSig.R) == 0 &&
+		sig.S.Cmp(otherSig.S) == 0
+}
+
+// schnorrChallenge returns the BIP0340 challenge e for the passed serialized
+// nonce x coordinate, public key x coordinate and message.
+func schnorrChallenge(r, pubKeyX, hash []byte) *big.Int {
+	e := chainhash.TaggedHash(bip340ChallengeTag, r, pubKeyX, hash)
+	return new(big.Int).Mod(new(big.Int).SetBytes(e[:]), S256().N)
+}
+
+// schnorrVerify implements the BIP0340 verification algorithm for the public
+// key identified by the passed x coordinate.
+func schnorrVerify(sig *SchnorrSignature, hash []byte, pubKeyX *big.Int) bool {
+	curve := S256()
+
+	// The public key is the point with the x coordinate and an even y
+	// coordinate.
+	if pubKeyX.Cmp(curve.P) >= 0 {
+		return false
+	}
+	py, err := decompressPoint(curve, pubKeyX, false)
+	if err != nil {
+		return false
+	}
+
+	// R = s*G - e*P
+	pxBytes := paddedAppend(32, nil, pubKeyX.Bytes())
+	rBytes := paddedAppend(32, nil, sig.R.Bytes())
+	e := schnorrChallenge(rBytes, pxBytes, hash)
+	sgx, sgy := curve.ScalarBaseMult(sig.S.Bytes())
+	epx, epy := curve.ScalarMult(pubKeyX, py, e.Bytes())
+	if epx.Sign() != 0 || epy.Sign() != 0 {
+		epy = new(big.Int).Sub(curve.P, epy)
+	}
+	rx, ry := curve.Add(sgx, sgy, epx, epy)
+
+	// The signature is only valid when R is not the point at infinity, has
+	// an even y coordinate and its x coordinate matches the signature.
+	if rx.Sign() == 0 && ry.Sign() == 0 {
+		return false
+	}
+	return !isOdd(ry) && rx.Cmp(sig.R) == 0
+}
+
+// ParseSchnorrPubKey parses a 32-byte BIP0340 public key, which only encodes
+// the x coordinate, into a PublicKey.  The returned key is the point with that
+// x coordinate and an even y coordinate.
+func ParseSchnorrPubKey(pubKeyStr []byte) (*PublicKey, error) {
+	if len(pubKeyStr) != SchnorrPubKeyBytesLen {
+		return nil, fmt.Errorf("invalid schnorr pub key length %d",
+			len(pubKeyStr))
+	}
+
+	curve := S256()
+	x := new(big.Int).SetBytes(pubKeyStr)
+	if x.Cmp(curve.P) >= 0 {
+		return nil, errors.New("pubkey X parameter is >= to P")
+	}
+	y, err := decompressPoint(curve, x, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// SerializeSchnorr serializes the public key in the 32-byte format defined by
+// BIP0340, which only consists of the x coordinate.
+func (p *PublicKey) SerializeSchnorr() []byte {
+	b := make([]byte, 0, SchnorrPubKeyBytesLen)
+	return paddedAppend(32, b, p.X.Bytes())
+}
+
+// SignSchnorr generates a BIP0340 signature for the provided 32-byte hash
+// using the private key and the passed 32 bytes of auxiliary random data.  The
+// produced signature is deterministic for a given key, hash and auxiliary
+// data.
+func SignSchnorr(privKey *PrivateKey, hash, auxRand []byte) (*SchnorrSignature, error) {
+	if len(hash) != 32 {
+		return nil, fmt.Errorf("invalid hash length %d", len(hash))
+	}
+	if len(auxRand) != 32 {
+		return nil, fmt.Errorf("invalid auxiliary data length %d",
+			len(auxRand))
+	}
+
+	curve := S256()
+	d := new(big.Int).Set(privKey.D)
+	if d.Sign() == 0 || d.Cmp(curve.N) >= 0 {
+		return nil, errors.New("private key is out of range")
+	}
+
+	// Negate the private key when needed so the public key has an even y
+	// coordinate.
+	px, py := curve.ScalarBaseMult(d.Bytes())
+	if isOdd(py) {
+		d.Sub(curve.N, d)
+	}
+	pxBytes := paddedAppend(32, nil, px.Bytes())
+
+	// Derive the nonce from the private key masked by the hash of the
+	// auxiliary data, the public key and the message.
+	t := paddedAppend(32, nil, d.Bytes())
+	auxHash := chainhash.TaggedHash(bip340AuxTag, auxRand)
+	for i := range t {
+		t[i] ^= auxHash[i]
+	}
+	nonce := chainhash.TaggedHash(bip340NonceTag, t, pxBytes, hash)
+	k := new(big.Int).Mod(new(big.Int).SetBytes(nonce[:]), curve.N)
+	if k.Sign() == 0 {
+		return nil, errors.New("calculated nonce is zero")
+	}
+
+	// Negate the nonce when needed so R has an even y coordinate.
+	rx, ry := curve.ScalarBaseMult(k.Bytes())
+	if isOdd(ry) {
+		k.Sub(curve.N, k)
+	}
+	rBytes := paddedAppend(32, nil, rx.Bytes())
+
+	// s = k + e*d mod N
+	e := schnorrChallenge(rBytes, pxBytes, hash)
+	s := new(big.Int).Mul(e, d)
+	s.Add(s, k)
+	s.Mod(s, curve.N)
+
+	// Ensure the signature verifies to protect against faults during the
+	// computation leaking the private key.
+	sig := &SchnorrSignature{R: rx, S: s}
+	if !schnorrVerify(sig, hash, px) {
+		return nil, errors.New("generated signature does not verify")
+	}
+
+	return sig, nil
+}
+
+// SignSchnorr generates a BIP0340 signature for the provided hash (which should
+// be the result of hashing a larger message) using the private key.  Fresh
+// auxiliary random data is used for each signature as recommended by BIP0340.
+func (p *PrivateKey) SignSchnorr(hash []byte) (*SchnorrSignature, error) {
+	var auxRand [32]byte
+	if _, err := rand.Read(auxRand[:]); err != nil {
+		return nil, err
+	}
+	return SignSchnorr(p, hash, auxRand[:])
+}
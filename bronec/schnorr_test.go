@@ -0,0 +1,200 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package bronec
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestSchnorrSign ensures signing with the BIP0340 test vectors produces the
+// expected public keys and signatures, and that the signatures verify.
+func TestSchnorrSign(t *testing.T) {
+	tests := []struct {
+		name    string
+		privKey string
+		pubKey  string
+		auxRand string
+		msg     string
+		sig     string
+	}{{
+		name:    "bip340 vector 0",
+		privKey: "0000000000000000000000000000000000000000000000000000000000000003",
+		pubKey:  "f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+		auxRand: "0000000000000000000000000000000000000000000000000000000000000000",
+		msg:     "0000000000000000000000000000000000000000000000000000000000000000",
+		sig: "e907831f80848d1069a5371b402410364bdf1c5f8307b0084c55f1ce2dca8215" +
+			"25f66a4a85ea8b71e482a74f382d2ce5ebeee8fdb2172f477df4900d310536c0",
+	}, {
+		name:    "bip340 vector 1",
+		privKey: "b7e151628aed2a6abf7158809cf4f3c762e7160f38b4da56a784d9045190cfef",
+		pubKey:  "dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659",
+		auxRand: "0000000000000000000000000000000000000000000000000000000000000001",
+		msg:     "243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89",
+		sig: "6896bd60eeae296db48a229ff71dfe071bde413e6d43f917dc8dcf8c78de3341" +
+			"8906d11ac976abccb20b091292bff4ea897efcb639ea871cfa95f6de339e4b0a",
+	}, {
+		name:    "bip340 vector 2",
+		privKey: "c90fdaa22168c234c4c6628b80dc1cd129024e088a67cc74020bbea63b14e5c9",
+		pubKey:  "dd308afec5777e13121fa72b9cc1b7cc0139715309b086c960e18fd969774eb8",
+		auxRand: "c87aa53824b4d7ae2eb035a2b5bbbccc080e76cdc6d1692c4b0b62d798e6d906",
+		msg:     "7e2d58d8b3bcdf1abadec7829054f90dda9805aab56c77333024b9d0a508b75c",
+		sig: "5831aaeed7b44bb74e5eab94ba9d4294c49bcf2a60728d8b4c200f50dd313c1b" +
+			"ab745879a5ad954a72c45a91c3a51d3c7adea98d82f8481e0e1e03674a6f3fb7",
+	}, {
+		name:    "bip340 vector 3",
+		privKey: "0b432b2677937381aef05bb02a66ecd012773062cf3fa2549e44f58ed2401710",
+		pubKey:  "25d1dff95105f5253c4022f628a996ad3a0d95fbf21d468a1b33f8c160d8f517",
+		auxRand: "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		msg:     "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		sig: "7eb0509757e246f19449885651611cb965ecc1a187dd51b64fda1edc9637d5ec" +
+			"97582b9cb13db3933705b32ba982af5af25fd78881ebb32771fc5922efc66ea3",
+	}}
+
+	for _, test := range tests {
+		priv, pub := PrivKeyFromBytes(S256(), decodeHex(test.privKey))
+		wantPubKey := decodeHex(test.pubKey)
+		if got := pub.SerializeSchnorr(); !bytes.Equal(got, wantPubKey) {
+			t.Errorf("%s: unexpected public key - got %x, want %x",
+				test.name, got, wantPubKey)
+			continue
+		}
+
+		msg := decodeHex(test.msg)
+		sig, err := SignSchnorr(priv, msg, decodeHex(test.auxRand))
+		if err != nil {
+			t.Errorf("%s: could not sign: %v", test.name, err)
+			continue
+		}
+		wantSig := decodeHex(test.sig)
+		if got := sig.Serialize(); !bytes.Equal(got, wantSig) {
+			t.Errorf("%s: unexpected signature - got %x, want %x",
+				test.name, got, wantSig)
+			continue
+		}
+
+		parsedPub, err := ParseSchnorrPubKey(wantPubKey)
+		if err != nil {
+			t.Errorf("%s: could not parse public key: %v", test.name,
+				err)
+			continue
+		}
+		if !sig.Verify(msg, parsedPub) {
+			t.Errorf("%s: signature does not verify", test.name)
+			continue
+		}
+
+		// Signatures created with fresh auxiliary data must verify
+		// as well.
+		sig, err = priv.SignSchnorr(msg)
+		if err != nil {
+			t.Errorf("%s: could not sign: %v", test.name, err)
+			continue
+		}
+		if !sig.Verify(msg, pub) {
+			t.Errorf("%s: signature with random auxiliary data "+
+				"does not verify", test.name)
+		}
+	}
+}
+
+// TestSchnorrVerify ensures invalid BIP0340 public keys and signatures are
+// rejected when parsing or verifying them.
+func TestSchnorrVerify(t *testing.T) {
+	const (
+		validPubKey = "dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659"
+		validMsg    = "243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89"
+		validSig    = "6896bd60eeae296db48a229ff71dfe071bde413e6d43f917dc8dcf8c78de3341" +
+			"8906d11ac976abccb20b091292bff4ea897efcb639ea871cfa95f6de339e4b0a"
+	)
+
+	tests := []struct {
+		name      string
+		pubKey    string
+		msg       string
+		sig       string
+		parseErr  bool
+		wantValid bool
+	}{{
+		name:      "valid",
+		pubKey:    validPubKey,
+		msg:       validMsg,
+		sig:       validSig,
+		wantValid: true,
+	}, {
+		name:     "public key not on the curve",
+		pubKey:   "eefdea4cdb677750a420fee807eacf21eb9898ae79b9768766e4faa04a2d4a34",
+		msg:      validMsg,
+		sig:      validSig,
+		parseErr: true,
+	}, {
+		name:     "public key exceeds field size",
+		pubKey:   "fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc30",
+		msg:      validMsg,
+		sig:      validSig,
+		parseErr: true,
+	}, {
+		name:   "r exceeds field size",
+		pubKey: validPubKey,
+		msg:    validMsg,
+		sig: "fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f" +
+			"8906d11ac976abccb20b091292bff4ea897efcb639ea871cfa95f6de339e4b0a",
+		parseErr: true,
+	}, {
+		name:   "s equals curve order",
+		pubKey: validPubKey,
+		msg:    validMsg,
+		sig: "6896bd60eeae296db48a229ff71dfe071bde413e6d43f917dc8dcf8c78de3341" +
+			"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141",
+		parseErr: true,
+	}, {
+		name:   "negated s",
+		pubKey: validPubKey,
+		msg:    validMsg,
+		sig: "6896bd60eeae296db48a229ff71dfe071bde413e6d43f917dc8dcf8c78de3341" +
+			"76f92ee5368954334df4f6ed6d400b14312fe030755e191ec53c67ae9c97f637",
+	}, {
+		name:   "different message",
+		pubKey: validPubKey,
+		msg:    "243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c88",
+		sig:    validSig,
+	}, {
+		name:   "different public key",
+		pubKey: "dd308afec5777e13121fa72b9cc1b7cc0139715309b086c960e18fd969774eb8",
+		msg:    validMsg,
+		sig:    validSig,
+	}, {
+		name:     "truncated signature",
+		pubKey:   validPubKey,
+		msg:      validMsg,
+		sig:      validSig[:126],
+		parseErr: true,
+	}}
+
+	for _, test := range tests {
+		pubKey, err := ParseSchnorrPubKey(decodeHex(test.pubKey))
+		var sig *SchnorrSignature
+		if err == nil {
+			sig, err = ParseSchnorrSignature(decodeHex(test.sig))
+		}
+		if test.parseErr {
+			if err == nil {
+				t.Errorf("%s: parsing succeeded when it should "+
+					"have failed", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected parse error: %v", test.name, err)
+			continue
+		}
+
+		valid := sig.Verify(decodeHex(test.msg), pubKey)
+		if valid != test.wantValid {
+			t.Errorf("%s: unexpected verification result - got %v, "+
+				"want %v", test.name, valid, test.wantValid)
+		}
+	}
+}
@@ -41,6 +41,17 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"addnode","params":["127.0.0.1","remove"],"id":1}`,
 			unmarshalled: &bronjson.AddNodeCmd{Addr: "127.0.0.1", SubCmd: bronjson.ANRemove},
 		},
+		{
+			name: "clearbanned",
+			newCmd: func() (interface{}, error) {
+				return bronjson.NewCmd("clearbanned")
+			},
+			staticCmd: func() interface{} {
+				return bronjson.NewClearBannedCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"clearbanned","params":[],"id":1}`,
+			unmarshalled: &bronjson.ClearBannedCmd{},
+		},
 		{
 			name: "createrawtransaction",
 			newCmd: func() (interface{}, error) {
@@ -103,6 +114,34 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"decodescript","params":["00"],"id":1}`,
 			unmarshalled: &bronjson.DecodeScriptCmd{HexScript: "00"},
 		},
+		{
+			name: "estimatesmartfee",
+			newCmd: func() (interface{}, error) {
+				return bronjson.NewCmd("estimatesmartfee", 6)
+			},
+			staticCmd: func() interface{} {
+				return bronjson.NewEstimateSmartFeeCmd(6, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"estimatesmartfee","params":[6],"id":1}`,
+			unmarshalled: &bronjson.EstimateSmartFeeCmd{
+				ConfTarget:   6,
+				EstimateMode: &bronjson.EstimateModeConservative,
+			},
+		},
+		{
+			name: "estimatesmartfee optional",
+			newCmd: func() (interface{}, error) {
+				return bronjson.NewCmd("estimatesmartfee", 6, bronjson.EstimateModeEconomical)
+			},
+			staticCmd: func() interface{} {
+				return bronjson.NewEstimateSmartFeeCmd(6, &bronjson.EstimateModeEconomical)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"estimatesmartfee","params":[6,"ECONOMICAL"],"id":1}`,
+			unmarshalled: &bronjson.EstimateSmartFeeCmd{
+				ConfTarget:   6,
+				EstimateMode: &bronjson.EstimateModeEconomical,
+			},
+		},
 		{
 			name: "getaddednodeinfo",
 			newCmd: func() (interface{}, error) {
@@ -235,6 +274,34 @@ func TestChainSvrCmds(t *testing.T) {
 				Verbose: bronjson.Bool(true),
 			},
 		},
+		{
+			name: "getblockstats height",
+			newCmd: func() (interface{}, error) {
+				return bronjson.NewCmd("getblockstats", "123")
+			},
+			staticCmd: func() interface{} {
+				return bronjson.NewGetBlockStatsCmd("123", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getblockstats","params":[123],"id":1}`,
+			unmarshalled: &bronjson.GetBlockStatsCmd{
+				HashOrHeight: "123",
+			},
+		},
+		{
+			name: "getblockstats hash",
+			newCmd: func() (interface{}, error) {
+				return bronjson.NewCmd("getblockstats", "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f", []string{"totalfee", "txs"})
+			},
+			staticCmd: func() interface{} {
+				stats := []string{"totalfee", "txs"}
+				return bronjson.NewGetBlockStatsCmd("000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f", &stats)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getblockstats","params":["000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f",["totalfee","txs"]],"id":1}`,
+			unmarshalled: &bronjson.GetBlockStatsCmd{
+				HashOrHeight: "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f",
+				Stats:        &[]string{"totalfee", "txs"},
+			},
+		},
 		{
 			name: "getblocktemplate",
 			newCmd: func() (interface{}, error) {
@@ -416,6 +483,62 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"getinfo","params":[],"id":1}`,
 			unmarshalled: &bronjson.GetInfoCmd{},
 		},
+		{
+			name: "getmempoolancestors",
+			newCmd: func() (interface{}, error) {
+				return bronjson.NewCmd("getmempoolancestors", "txhash")
+			},
+			staticCmd: func() interface{} {
+				return bronjson.NewGetMempoolAncestorsCmd("txhash", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getmempoolancestors","params":["txhash"],"id":1}`,
+			unmarshalled: &bronjson.GetMempoolAncestorsCmd{
+				TxID:    "txhash",
+				Verbose: bronjson.Bool(false),
+			},
+		},
+		{
+			name: "getmempoolancestors verbose",
+			newCmd: func() (interface{}, error) {
+				return bronjson.NewCmd("getmempoolancestors", "txhash", true)
+			},
+			staticCmd: func() interface{} {
+				return bronjson.NewGetMempoolAncestorsCmd("txhash", bronjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getmempoolancestors","params":["txhash",true],"id":1}`,
+			unmarshalled: &bronjson.GetMempoolAncestorsCmd{
+				TxID:    "txhash",
+				Verbose: bronjson.Bool(true),
+			},
+		},
+		{
+			name: "getmempooldescendants",
+			newCmd: func() (interface{}, error) {
+				return bronjson.NewCmd("getmempooldescendants", "txhash")
+			},
+			staticCmd: func() interface{} {
+				return bronjson.NewGetMempoolDescendantsCmd("txhash", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getmempooldescendants","params":["txhash"],"id":1}`,
+			unmarshalled: &bronjson.GetMempoolDescendantsCmd{
+				TxID:    "txhash",
+				Verbose: bronjson.Bool(false),
+			},
+		},
+		{
+			name: "getmempooldescendants verbose",
+			newCmd: func() (interface{}, error) {
+				return bronjson.NewCmd("getmempooldescendants", "txhash", true)
+			},
+			staticCmd: func() interface{} {
+				return bronjson.NewGetMempoolDescendantsCmd("txhash", bronjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getmempooldescendants","params":["txhash",true],"id":1}`,
+			unmarshalled: &bronjson.GetMempoolDescendantsCmd{
+				TxID:    "txhash",
+				Verbose: bronjson.Bool(true),
+			},
+		},
 		{
 			name: "getmempoolentry",
 			newCmd: func() (interface{}, error) {
@@ -716,6 +839,28 @@ func TestChainSvrCmds(t *testing.T) {
 				BlockHash: "123",
 			},
 		},
+		{
+			name: "listbanned",
+			newCmd: func() (interface{}, error) {
+				return bronjson.NewCmd("listbanned")
+			},
+			staticCmd: func() interface{} {
+				return bronjson.NewListBannedCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"listbanned","params":[],"id":1}`,
+			unmarshalled: &bronjson.ListBannedCmd{},
+		},
+		{
+			name: "loadmempool",
+			newCmd: func() (interface{}, error) {
+				return bronjson.NewCmd("loadmempool")
+			},
+			staticCmd: func() interface{} {
+				return bronjson.NewLoadMempoolCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"loadmempool","params":[],"id":1}`,
+			unmarshalled: &bronjson.LoadMempoolCmd{},
+		},
 		{
 			name: "ping",
 			newCmd: func() (interface{}, error) {
@@ -753,6 +898,17 @@ func TestChainSvrCmds(t *testing.T) {
 				BlockHash: "123",
 			},
 		},
+		{
+			name: "savemempool",
+			newCmd: func() (interface{}, error) {
+				return bronjson.NewCmd("savemempool")
+			},
+			staticCmd: func() interface{} {
+				return bronjson.NewSaveMempoolCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"savemempool","params":[],"id":1}`,
+			unmarshalled: &bronjson.SaveMempoolCmd{},
+		},
 		{
 			name: "searchrawtransactions",
 			newCmd: func() (interface{}, error) {
@@ -920,6 +1076,39 @@ func TestChainSvrCmds(t *testing.T) {
 				AllowHighFees: bronjson.Bool(false),
 			},
 		},
+		{
+			name: "setban",
+			newCmd: func() (interface{}, error) {
+				return bronjson.NewCmd("setban", "10.0.0.0/8", bronjson.SBAdd)
+			},
+			staticCmd: func() interface{} {
+				return bronjson.NewSetBanCmd("10.0.0.0/8", bronjson.SBAdd, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"setban","params":["10.0.0.0/8","add"],"id":1}`,
+			unmarshalled: &bronjson.SetBanCmd{
+				SubNet:   "10.0.0.0/8",
+				SubCmd:   bronjson.SBAdd,
+				BanTime:  bronjson.Int64(0),
+				Absolute: bronjson.Bool(false),
+			},
+		},
+		{
+			name: "setban optional",
+			newCmd: func() (interface{}, error) {
+				return bronjson.NewCmd("setban", "1.2.3.4", bronjson.SBAdd, 1700000000, true)
+			},
+			staticCmd: func() interface{} {
+				return bronjson.NewSetBanCmd("1.2.3.4", bronjson.SBAdd,
+					bronjson.Int64(1700000000), bronjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"setban","params":["1.2.3.4","add",1700000000,true],"id":1}`,
+			unmarshalled: &bronjson.SetBanCmd{
+				SubNet:   "1.2.3.4",
+				SubCmd:   bronjson.SBAdd,
+				BanTime:  bronjson.Int64(1700000000),
+				Absolute: bronjson.Bool(true),
+			},
+		},
 		{
 			name: "setgenerate",
 			newCmd: func() (interface{}, error) {
@@ -992,6 +1181,35 @@ func TestChainSvrCmds(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "testmempoolaccept",
+			newCmd: func() (interface{}, error) {
+				return bronjson.NewCmd("testmempoolaccept", []string{"1122", "3344"})
+			},
+			staticCmd: func() interface{} {
+				return bronjson.NewTestMempoolAcceptCmd([]string{"1122", "3344"}, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"testmempoolaccept","params":[["1122","3344"]],"id":1}`,
+			unmarshalled: &bronjson.TestMempoolAcceptCmd{
+				RawTxns:    []string{"1122", "3344"},
+				MaxFeeRate: bronjson.Float64(0.1),
+			},
+		},
+		{
+			name: "testmempoolaccept optional",
+			newCmd: func() (interface{}, error) {
+				return bronjson.NewCmd("testmempoolaccept", []string{"1122"}, 0.5)
+			},
+			staticCmd: func() interface{} {
+				return bronjson.NewTestMempoolAcceptCmd([]string{"1122"},
+					bronjson.Float64(0.5))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"testmempoolaccept","params":[["1122"],0.5],"id":1}`,
+			unmarshalled: &bronjson.TestMempoolAcceptCmd{
+				RawTxns:    []string{"1122"},
+				MaxFeeRate: bronjson.Float64(0.5),
+			},
+		},
 		{
 			name: "uptime",
 			newCmd: func() (interface{}, error) {
@@ -1185,6 +1403,12 @@ func TestChainSvrCmdErrors(t *testing.T) {
 			marshalled: `{"sizelimit":"invalid"}`,
 			err:        bronjson.Error{ErrorCode: bronjson.ErrInvalidType},
 		},
+		{
+			name:       "invalid block hash or height",
+			result:     new(bronjson.HashOrHeight),
+			marshalled: `1.5`,
+			err:        bronjson.Error{ErrorCode: bronjson.ErrInvalidType},
+		},
 	}
 
 	t.Logf("Running %d tests", len(tests))
@@ -63,6 +63,14 @@ type DecodeScriptResult struct {
 	P2sh      string   `json:"p2sh,omitempty"`
 }
 
+// EstimateSmartFeeResult models the data returned by the estimatesmartfee
+// command.
+type EstimateSmartFeeResult struct {
+	FeeRate *float64 `json:"feerate,omitempty"`
+	Errors  []string `json:"errors,omitempty"`
+	Blocks  int64    `json:"blocks"`
+}
+
 // GetAddedNodeInfoResultAddr models the data of the addresses portion of the
 // getaddednodeinfo command.
 type GetAddedNodeInfoResultAddr struct {
@@ -130,6 +138,41 @@ type UnifiedSoftForks struct {
 	SoftForks map[string]*UnifiedSoftFork `json:"softforks"`
 }
 
+// GetBlockStatsResult models the data returned from the getblockstats command.
+// Amounts are in brones and fee rates in brones per virtual byte.  Only the
+// selected statistics are returned when the command specifies any.
+type GetBlockStatsResult struct {
+	AverageFee         int64   `json:"avgfee"`
+	AverageFeeRate     int64   `json:"avgfeerate"`
+	AverageTxSize      int64   `json:"avgtxsize"`
+	Hash               string  `json:"blockhash"`
+	FeeRatePercentiles []int64 `json:"feerate_percentiles"`
+	Height             int64   `json:"height"`
+	Ins                int64   `json:"ins"`
+	MaxFee             int64   `json:"maxfee"`
+	MaxFeeRate         int64   `json:"maxfeerate"`
+	MaxTxSize          int64   `json:"maxtxsize"`
+	MedianFee          int64   `json:"medianfee"`
+	MedianTime         int64   `json:"mediantime"`
+	MedianTxSize       int64   `json:"mediantxsize"`
+	MinFee             int64   `json:"minfee"`
+	MinFeeRate         int64   `json:"minfeerate"`
+	MinTxSize          int64   `json:"mintxsize"`
+	Outs               int64   `json:"outs"`
+	Subsidy            int64   `json:"subsidy"`
+	SegWitTotalSize    int64   `json:"swtotal_size"`
+	SegWitTotalWeight  int64   `json:"swtotal_weight"`
+	SegWitTxs          int64   `json:"swtxs"`
+	Time               int64   `json:"time"`
+	TotalOut           int64   `json:"total_out"`
+	TotalSize          int64   `json:"total_size"`
+	TotalWeight        int64   `json:"total_weight"`
+	TotalFee           int64   `json:"totalfee"`
+	Txs                int64   `json:"txs"`
+	UTXOIncrease       int64   `json:"utxo_increase"`
+	UTXOSizeIncrease   int64   `json:"utxo_size_inc"`
+}
+
 // GetBlockChainInfoResult models the data returned from the getblockchaininfo
 // command.
 type GetBlockChainInfoResult struct {
@@ -147,6 +190,14 @@ type GetBlockChainInfoResult struct {
 	*UnifiedSoftForks
 }
 
+// GetChainTipsResult models the data returned from the getchaintips command.
+type GetChainTipsResult struct {
+	Height    int32  `json:"height"`
+	Hash      string `json:"hash"`
+	BranchLen int32  `json:"branchlen"`
+	Status    string `json:"status"`
+}
+
 // GetBlockTemplateResultTx models the transactions field of the
 // getblocktemplate command.
 type GetBlockTemplateResultTx struct {
@@ -209,27 +260,50 @@ type GetBlockTemplateResult struct {
 // GetMempoolEntryResult models the data returned from the getmempoolentry
 // command.
 type GetMempoolEntryResult struct {
-	Size             int32    `json:"size"`
-	Fee              float64  `json:"fee"`
-	ModifiedFee      float64  `json:"modifiedfee"`
-	Time             int64    `json:"time"`
-	Height           int64    `json:"height"`
-	StartingPriority float64  `json:"startingpriority"`
-	CurrentPriority  float64  `json:"currentpriority"`
-	DescendantCount  int64    `json:"descendantcount"`
-	DescendantSize   int64    `json:"descendantsize"`
-	DescendantFees   float64  `json:"descendantfees"`
-	AncestorCount    int64    `json:"ancestorcount"`
-	AncestorSize     int64    `json:"ancestorsize"`
-	AncestorFees     float64  `json:"ancestorfees"`
-	Depends          []string `json:"depends"`
+	Size              int32    `json:"size"`
+	Vsize             int32    `json:"vsize"`
+	Weight            int32    `json:"weight"`
+	Fee               float64  `json:"fee"`
+	ModifiedFee       float64  `json:"modifiedfee"`
+	Time              int64    `json:"time"`
+	Height            int64    `json:"height"`
+	StartingPriority  float64  `json:"startingpriority"`
+	CurrentPriority   float64  `json:"currentpriority"`
+	DescendantCount   int64    `json:"descendantcount"`
+	DescendantSize    int64    `json:"descendantsize"`
+	DescendantFees    float64  `json:"descendantfees"`
+	AncestorCount     int64    `json:"ancestorcount"`
+	AncestorSize      int64    `json:"ancestorsize"`
+	AncestorFees      float64  `json:"ancestorfees"`
+	Depends           []string `json:"depends"`
+	SpentBy           []string `json:"spentby"`
+	BIP125Replaceable bool     `json:"bip125-replaceable"`
 }
 
 // GetMempoolInfoResult models the data returned from the getmempoolinfo
 // command.
 type GetMempoolInfoResult struct {
-	Size  int64 `json:"size"`
-	Bytes int64 `json:"bytes"`
+	Size          int64   `json:"size"`
+	Bytes         int64   `json:"bytes"`
+	MaxMempool    int64   `json:"maxmempool"`
+	MempoolMinFee float64 `json:"mempoolminfee"`
+}
+
+// ListBannedResult models the data returned from the listbanned command.
+type ListBannedResult struct {
+	Address       string `json:"address"`
+	BanCreated    int64  `json:"ban_created"`
+	BannedUntil   int64  `json:"banned_until"`
+	BanDuration   int64  `json:"ban_duration"`
+	TimeRemaining int64  `json:"time_remaining"`
+}
+
+// LoadMempoolResult models the data returned from the loadmempool command.
+type LoadMempoolResult struct {
+	Loaded      int64 `json:"loaded"`
+	Failed      int64 `json:"failed"`
+	Expired     int64 `json:"expired"`
+	AlreadyHave int64 `json:"alreadyhave"`
 }
 
 // NetworksResult models the networks data from the getnetworkinfo command.
@@ -269,27 +343,34 @@ type GetNetworkInfoResult struct {
 
 // GetPeerInfoResult models the data returned from the getpeerinfo command.
 type GetPeerInfoResult struct {
-	ID             int32   `json:"id"`
-	Addr           string  `json:"addr"`
-	AddrLocal      string  `json:"addrlocal,omitempty"`
-	Services       string  `json:"services"`
-	RelayTxes      bool    `json:"relaytxes"`
-	LastSend       int64   `json:"lastsend"`
-	LastRecv       int64   `json:"lastrecv"`
-	BytesSent      uint64  `json:"bytessent"`
-	BytesRecv      uint64  `json:"bytesrecv"`
-	ConnTime       int64   `json:"conntime"`
-	TimeOffset     int64   `json:"timeoffset"`
-	PingTime       float64 `json:"pingtime"`
-	PingWait       float64 `json:"pingwait,omitempty"`
-	Version        uint32  `json:"version"`
-	SubVer         string  `json:"subver"`
-	Inbound        bool    `json:"inbound"`
-	StartingHeight int32   `json:"startingheight"`
-	CurrentHeight  int32   `json:"currentheight,omitempty"`
-	BanScore       int32   `json:"banscore"`
-	FeeFilter      int64   `json:"feefilter"`
-	SyncNode       bool    `json:"syncnode"`
+	ID                 int32             `json:"id"`
+	Addr               string            `json:"addr"`
+	AddrLocal          string            `json:"addrlocal,omitempty"`
+	Network            string            `json:"network"`
+	AddrGroup          string            `json:"addrgroup"`
+	Services           string            `json:"services"`
+	RelayTxes          bool              `json:"relaytxes"`
+	LastSend           int64             `json:"lastsend"`
+	LastRecv           int64             `json:"lastrecv"`
+	BytesSent          uint64            `json:"bytessent"`
+	BytesRecv          uint64            `json:"bytesrecv"`
+	ConnTime           int64             `json:"conntime"`
+	TimeOffset         int64             `json:"timeoffset"`
+	PingTime           float64           `json:"pingtime"`
+	PingWait           float64           `json:"pingwait,omitempty"`
+	Version            uint32            `json:"version"`
+	SubVer             string            `json:"subver"`
+	Inbound            bool              `json:"inbound"`
+	ConnectionType     string            `json:"connection_type"`
+	StartingHeight     int32             `json:"startingheight"`
+	CurrentHeight      int32             `json:"currentheight,omitempty"`
+	BanScore           int32             `json:"banscore"`
+	BanScorePersistent int32             `json:"banscore_persistent"`
+	BanScoreTransient  int32             `json:"banscore_transient"`
+	FeeFilter          int64             `json:"feefilter"`
+	SyncNode           bool              `json:"syncnode"`
+	BytesSentPerMsg    map[string]uint64 `json:"bytessent_per_msg"`
+	BytesRecvPerMsg    map[string]uint64 `json:"bytesrecv_per_msg"`
 }
 
 // GetRawMempoolVerboseResult models the data returned from the getrawmempool
@@ -326,6 +407,18 @@ type GetTxOutResult struct {
 	Coinbase      bool               `json:"coinbase"`
 }
 
+// GetTxOutSetInfoResult models the data from the gettxoutsetinfo command.
+type GetTxOutSetInfoResult struct {
+	Height         int64   `json:"height"`
+	BestBlock      string  `json:"bestblock"`
+	Transactions   int64   `json:"transactions"`
+	TxOuts         int64   `json:"txouts"`
+	BogoSize       int64   `json:"bogosize"`
+	HashSerialized string  `json:"hash_serialized"`
+	DiskSize       int64   `json:"disk_size"`
+	TotalAmount    float64 `json:"total_amount"`
+}
+
 // GetNetTotalsResult models the data returned from the getnettotals command.
 type GetNetTotalsResult struct {
 	TotalBytesRecv uint64 `json:"totalbytesrecv"`
@@ -569,6 +662,23 @@ type SearchRawTransactionsResult struct {
 	Blocktime     int64        `json:"blocktime,omitempty"`
 }
 
+// TestMempoolAcceptFees models the fees field of the testmempoolaccept command
+// results.
+type TestMempoolAcceptFees struct {
+	Base float64 `json:"base"`
+}
+
+// TestMempoolAcceptResult models the data returned for each transaction by the
+// testmempoolaccept command.
+type TestMempoolAcceptResult struct {
+	Txid         string                 `json:"txid"`
+	Wtxid        string                 `json:"wtxid"`
+	Allowed      bool                   `json:"allowed"`
+	Vsize        int32                  `json:"vsize,omitempty"`
+	Fees         *TestMempoolAcceptFees `json:"fees,omitempty"`
+	RejectReason string                 `json:"reject-reason,omitempty"`
+}
+
 // TxRawDecodeResult models the data from the decoderawtransaction command.
 type TxRawDecodeResult struct {
 	Txid     string `json:"txid"`
@@ -126,6 +126,30 @@ func TestChainSvrWsCmds(t *testing.T) {
 				Addresses: []string{"1Address"},
 			},
 		},
+		{
+			name: "notifysince",
+			newCmd: func() (interface{}, error) {
+				return bronjson.NewCmd("notifysince")
+			},
+			staticCmd: func() interface{} {
+				return bronjson.NewNotifySinceCmd(nil)
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"notifysince","params":[],"id":1}`,
+			unmarshalled: &bronjson.NotifySinceCmd{},
+		},
+		{
+			name: "notifysince optional",
+			newCmd: func() (interface{}, error) {
+				return bronjson.NewCmd("notifysince", 42)
+			},
+			staticCmd: func() interface{} {
+				return bronjson.NewNotifySinceCmd(bronjson.Uint64(42))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"notifysince","params":[42],"id":1}`,
+			unmarshalled: &bronjson.NotifySinceCmd{
+				Sequence: bronjson.Uint64(42),
+			},
+		},
 		{
 			name: "notifyspent",
 			newCmd: func() (interface{}, error) {
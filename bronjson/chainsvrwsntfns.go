@@ -75,6 +75,25 @@ const (
 	// from the chain server that inform a client that a transaction that
 	// matches the loaded filter was accepted by the mempool.
 	RelevantTxAcceptedNtfnMethod = "relevanttxaccepted"
+
+	// SeqBlockConnectedNtfnMethod is the method used for sequenced
+	// notifications from the chain server that a block has been connected.
+	SeqBlockConnectedNtfnMethod = "seqblockconnected"
+
+	// SeqBlockDisconnectedNtfnMethod is the method used for sequenced
+	// notifications from the chain server that a block has been
+	// disconnected.
+	SeqBlockDisconnectedNtfnMethod = "seqblockdisconnected"
+
+	// SeqTxAcceptedNtfnMethod is the method used for sequenced
+	// notifications from the chain server that a transaction has been
+	// accepted into the mempool.
+	SeqTxAcceptedNtfnMethod = "seqtxaccepted"
+
+	// SeqTxRemovedNtfnMethod is the method used for sequenced notifications
+	// from the chain server that a transaction has been removed from the
+	// mempool.
+	SeqTxRemovedNtfnMethod = "seqtxremoved"
 )
 
 // BlockConnectedNtfn defines the blockconnected JSON-RPC notification.
@@ -285,6 +304,76 @@ func NewRelevantTxAcceptedNtfn(txHex string) *RelevantTxAcceptedNtfn {
 	return &RelevantTxAcceptedNtfn{Transaction: txHex}
 }
 
+// SeqBlockConnectedNtfn defines the seqblockconnected JSON-RPC notification.
+type SeqBlockConnectedNtfn struct {
+	Sequence uint64
+	Height   int32
+	Header   string
+}
+
+// NewSeqBlockConnectedNtfn returns a new instance which can be used to issue a
+// seqblockconnected JSON-RPC notification.
+func NewSeqBlockConnectedNtfn(sequence uint64, height int32, header string) *SeqBlockConnectedNtfn {
+	return &SeqBlockConnectedNtfn{
+		Sequence: sequence,
+		Height:   height,
+		Header:   header,
+	}
+}
+
+// SeqBlockDisconnectedNtfn defines the seqblockdisconnected JSON-RPC
+// notification.
+type SeqBlockDisconnectedNtfn struct {
+	Sequence uint64
+	Height   int32
+	Header   string
+}
+
+// NewSeqBlockDisconnectedNtfn returns a new instance which can be used to
+// issue a seqblockdisconnected JSON-RPC notification.
+func NewSeqBlockDisconnectedNtfn(sequence uint64, height int32, header string) *SeqBlockDisconnectedNtfn {
+	return &SeqBlockDisconnectedNtfn{
+		Sequence: sequence,
+		Height:   height,
+		Header:   header,
+	}
+}
+
+// SeqTxAcceptedNtfn defines the seqtxaccepted JSON-RPC notification.
+type SeqTxAcceptedNtfn struct {
+	Sequence uint64
+	TxID     string
+	Amount   float64
+}
+
+// NewSeqTxAcceptedNtfn returns a new instance which can be used to issue a
+// seqtxaccepted JSON-RPC notification.
+func NewSeqTxAcceptedNtfn(sequence uint64, txHash string, amount float64) *SeqTxAcceptedNtfn {
+	return &SeqTxAcceptedNtfn{
+		Sequence: sequence,
+		TxID:     txHash,
+		Amount:   amount,
+	}
+}
+
+// SeqTxRemovedNtfn defines the seqtxremoved JSON-RPC notification.  The reason
+// is one of "confirmed", "conflicted", "replaced", "evicted" or "reorg".
+type SeqTxRemovedNtfn struct {
+	Sequence uint64
+	TxID     string
+	Reason   string
+}
+
+// NewSeqTxRemovedNtfn returns a new instance which can be used to issue a
+// seqtxremoved JSON-RPC notification.
+func NewSeqTxRemovedNtfn(sequence uint64, txHash string, reason string) *SeqTxRemovedNtfn {
+	return &SeqTxRemovedNtfn{
+		Sequence: sequence,
+		TxID:     txHash,
+		Reason:   reason,
+	}
+}
+
 func init() {
 	// The commands in this file are only usable by websockets and are
 	// notifications.
@@ -301,4 +390,8 @@ func init() {
 	MustRegisterCmd(TxAcceptedNtfnMethod, (*TxAcceptedNtfn)(nil), flags)
 	MustRegisterCmd(TxAcceptedVerboseNtfnMethod, (*TxAcceptedVerboseNtfn)(nil), flags)
 	MustRegisterCmd(RelevantTxAcceptedNtfnMethod, (*RelevantTxAcceptedNtfn)(nil), flags)
+	MustRegisterCmd(SeqBlockConnectedNtfnMethod, (*SeqBlockConnectedNtfn)(nil), flags)
+	MustRegisterCmd(SeqBlockDisconnectedNtfnMethod, (*SeqBlockDisconnectedNtfn)(nil), flags)
+	MustRegisterCmd(SeqTxAcceptedNtfnMethod, (*SeqTxAcceptedNtfn)(nil), flags)
+	MustRegisterCmd(SeqTxRemovedNtfnMethod, (*SeqTxRemovedNtfn)(nil), flags)
 }
@@ -225,6 +225,66 @@ func TestChainSvrWsNtfns(t *testing.T) {
 				Transaction: "001122",
 			},
 		},
+		{
+			name: "seqblockconnected",
+			newNtfn: func() (interface{}, error) {
+				return bronjson.NewCmd("seqblockconnected", 7, 100000, "header")
+			},
+			staticNtfn: func() interface{} {
+				return bronjson.NewSeqBlockConnectedNtfn(7, 100000, "header")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"seqblockconnected","params":[7,100000,"header"],"id":null}`,
+			unmarshalled: &bronjson.SeqBlockConnectedNtfn{
+				Sequence: 7,
+				Height:   100000,
+				Header:   "header",
+			},
+		},
+		{
+			name: "seqblockdisconnected",
+			newNtfn: func() (interface{}, error) {
+				return bronjson.NewCmd("seqblockdisconnected", 8, 100000, "header")
+			},
+			staticNtfn: func() interface{} {
+				return bronjson.NewSeqBlockDisconnectedNtfn(8, 100000, "header")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"seqblockdisconnected","params":[8,100000,"header"],"id":null}`,
+			unmarshalled: &bronjson.SeqBlockDisconnectedNtfn{
+				Sequence: 8,
+				Height:   100000,
+				Header:   "header",
+			},
+		},
+		{
+			name: "seqtxaccepted",
+			newNtfn: func() (interface{}, error) {
+				return bronjson.NewCmd("seqtxaccepted", 9, "123", 1.5)
+			},
+			staticNtfn: func() interface{} {
+				return bronjson.NewSeqTxAcceptedNtfn(9, "123", 1.5)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"seqtxaccepted","params":[9,"123",1.5],"id":null}`,
+			unmarshalled: &bronjson.SeqTxAcceptedNtfn{
+				Sequence: 9,
+				TxID:     "123",
+				Amount:   1.5,
+			},
+		},
+		{
+			name: "seqtxremoved",
+			newNtfn: func() (interface{}, error) {
+				return bronjson.NewCmd("seqtxremoved", 10, "123", "replaced")
+			},
+			staticNtfn: func() interface{} {
+				return bronjson.NewSeqTxRemovedNtfn(10, "123", "replaced")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"seqtxremoved","params":[10,"123","replaced"],"id":null}`,
+			unmarshalled: &bronjson.SeqTxRemovedNtfn{
+				Sequence: 10,
+				TxID:     "123",
+				Reason:   "replaced",
+			},
+		},
 	}
 
 	t.Logf("Running %d tests", len(tests))
@@ -46,6 +46,7 @@ const (
 	ErrRPCClientNotConnected      RPCErrorCode = -9
 	ErrRPCClientInInitialDownload RPCErrorCode = -10
 	ErrRPCClientNodeNotAdded      RPCErrorCode = -24
+	ErrRPCClientInvalidIPOrSubnet RPCErrorCode = -30
 )
 
 // Wallet JSON errors
@@ -31,3 +31,23 @@ func DoubleHashH(b []byte) Hash {
 	first := sha256.Sum256(b)
 	return Hash(sha256.Sum256(first[:]))
 }
+
+// TaggedHash implements the tagged hash scheme described in BIP0340.  It
+// calculates sha256(sha256(tag) || sha256(tag) || msgs...) and returns the
+// resulting bytes as a Hash.  Prefixing the messages with the hash of a tag
+// that is unique to the context the hash is used in ensures hashes computed
+// for different purposes can never collide.
+func TaggedHash(tag []byte, msgs ...[]byte) *Hash {
+	tagHash := sha256.Sum256(tag)
+
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, msg := range msgs {
+		h.Write(msg)
+	}
+
+	var hash Hash
+	copy(hash[:], h.Sum(nil))
+	return &hash
+}
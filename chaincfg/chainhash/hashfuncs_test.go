@@ -134,3 +134,29 @@ func TestDoubleHashFuncs(t *testing.T) {
 		}
 	}
 }
+
+// TestTaggedHash ensures the tagged hash function which performs
+// hash(hash(tag) || hash(tag) || msgs) works as expected.
+func TestTaggedHash(t *testing.T) {
+	tests := []struct {
+		out  string
+		tag  string
+		msgs [][]byte
+	}{
+		{"c216d352f5818b7b4beacd4ae0a26fe888080823d2a598856661bcd54f1b3713",
+			"BIP0340/challenge", nil},
+		{"a85b2107f791b26a84e7586c28cec7cb61202ed3d01944d832500f363782d675",
+			"TapLeaf", [][]byte{{0xc0}, {0x01}, {0x51}}},
+		{"45514f36ed8411d581fa7f5521f02913c4032b1f67465af2e9591576e8ec087b",
+			"TapSighash", [][]byte{[]byte("ab"), []byte("c")}},
+	}
+
+	for _, test := range tests {
+		h := fmt.Sprintf("%x", TaggedHash([]byte(test.tag), test.msgs...)[:])
+		if h != test.out {
+			t.Errorf("TaggedHash(%q) = %s, want %s", test.tag, h,
+				test.out)
+			continue
+		}
+	}
+}
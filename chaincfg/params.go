@@ -96,6 +96,11 @@ const (
 	// includes the deployment of BIPS 141, 142, 144, 145, 147 and 173.
 	DeploymentSegwit
 
+	// DeploymentTaproot defines the rule change deployment ID for the
+	// Taproot soft-fork package. The taproot package includes the
+	// deployment of BIPS 340, 341 and 342.
+	DeploymentTaproot
+
 	// NOTE: DefinedDeployments must always come last since it is used to
 	// determine how many defined deployments there currently are.
 
@@ -181,6 +186,13 @@ type Params struct {
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints []Checkpoint
 
+	// MinimumChainWork is the amount of cumulative work the best chain of
+	// the network is known to have.  The initial block download is not
+	// considered complete until the best chain has at least this much
+	// work, and chains of block headers with less work are not stored.
+	// A nil value disables the minimum.
+	MinimumChainWork *big.Int
+
 	// These fields are related to voting on consensus rule changes as
 	// defined by BIP0009.
 	//
@@ -281,6 +293,11 @@ var MainNetParams = Params{
 		{560000, newHashFromStr("0000000000000000002c7b276daf6efb2b6aa68e2ce3be67ef925b3264ae7122")},*/
 	},
 
+	// The cumulative work of the chain up to the checkpoint at height
+	// 2016, counting every block after the genesis block at the minimum
+	// difficulty.
+	MinimumChainWork: newBigIntFromHex("000000000000000000000000000000000000000000000000000000007e1007f0"),
+
 	// Consensus rule change deployments.
 	//
 	// The miner confirmation window is defined as:
@@ -303,6 +320,11 @@ var MainNetParams = Params{
 			StartTime:  1479168000, // November 15, 2016 UTC
 			ExpireTime: 1510704000, // November 15, 2017 UTC.
 		},
+		DeploymentTaproot: {
+			BitNumber:  2,
+			StartTime:  1798761600, // January 1, 2027 UTC
+			ExpireTime: 1830297600, // January 1, 2028 UTC
+		},
 	},
 
 	// Mempool parameters
@@ -379,6 +401,11 @@ var RegressionNetParams = Params{
 			StartTime:  0,             // Always available for vote
 			ExpireTime: math.MaxInt64, // Never expires.
 		},
+		DeploymentTaproot: {
+			BitNumber:  2,
+			StartTime:  0,             // Always available for vote
+			ExpireTime: math.MaxInt64, // Never expires.
+		},
 	},
 
 	// Mempool parameters
@@ -451,6 +478,10 @@ var TestNet3Params = Params{
 		{1300007, newHashFromStr("0000000072eab69d54df75107c052b26b0395b44f77578184293bf1bb1dbd9fa")}, */
 	},
 
+	// The cumulative work of the first 2016 blocks after the genesis
+	// block at the minimum difficulty.
+	MinimumChainWork: newBigIntFromHex("000000000000000000000000000000000000000000000000000000007e1007f0"),
+
 	// Consensus rule change deployments.
 	//
 	// The miner confirmation window is defined as:
@@ -473,6 +504,11 @@ var TestNet3Params = Params{
 			StartTime:  1462060800, // May 1, 2016 UTC
 			ExpireTime: 1493596800, // May 1, 2017 UTC.
 		},
+		DeploymentTaproot: {
+			BitNumber:  2,
+			StartTime:  1796083200, // December 1, 2026 UTC
+			ExpireTime: 1827619200, // December 1, 2027 UTC
+		},
 	},
 
 	// Mempool parameters
@@ -553,6 +589,11 @@ var SimNetParams = Params{
 			StartTime:  0,             // Always available for vote
 			ExpireTime: math.MaxInt64, // Never expires.
 		},
+		DeploymentTaproot: {
+			BitNumber:  2,
+			StartTime:  0,             // Always available for vote
+			ExpireTime: math.MaxInt64, // Never expires.
+		},
 	},
 
 	// Mempool parameters
@@ -703,6 +744,17 @@ func newHashFromStr(hexStr string) *chainhash.Hash {
 	return hash
 }
 
+// newBigIntFromHex converts the passed big-endian hex string into a big.Int.
+// Like newHashFromStr, it panics on an error since it must only be called with
+// hard-coded, and therefore known good, values.
+func newBigIntFromHex(hexStr string) *big.Int {
+	n, ok := new(big.Int).SetString(hexStr, 16)
+	if !ok {
+		panic("invalid hex number " + hexStr)
+	}
+	return n
+}
+
 func init() {
 	// Register all default networks when the package is initialized.
 	mustRegister(&MainNetParams)
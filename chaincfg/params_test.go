@@ -33,3 +33,14 @@ func TestMustRegisterPanic(t *testing.T) {
 	// Intentionally try to register duplicate params to force a panic.
 	mustRegister(&MainNetParams)
 }
+
+// TestInvalidBigIntHex ensures the newBigIntFromHex function panics when used
+// with an invalid hex string.
+func TestInvalidBigIntHex(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected panic for invalid hex number, got nil")
+		}
+	}()
+	newBigIntFromHex("banana")
+}
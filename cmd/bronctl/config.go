@@ -97,6 +97,7 @@ type config struct {
 	ConfigFile    string `short:"C" long:"configfile" description:"Path to configuration file"`
 	RPCUser       string `short:"u" long:"rpcuser" description:"RPC username"`
 	RPCPassword   string `short:"P" long:"rpcpass" default-mask:"-" description:"RPC password"`
+	RPCCookieFile string `long:"rpccookiefile" description:"File with the credentials for cookie authentication used when no rpcpass is specified (default: .cookie in the brond data directory)"`
 	RPCServer     string `short:"s" long:"rpcserver" description:"RPC server to connect to"`
 	RPCCert       string `short:"c" long:"rpccert" description:"RPC server certificate chain for validation"`
 	NoTLS         bool   `long:"notls" description:"Disable TLS"`
@@ -271,6 +272,30 @@ func loadConfig() (*config, []string, error) {
 	// Handle environment variable expansion in the RPC certificate path.
 	cfg.RPCCert = cleanAndExpandPath(cfg.RPCCert)
 
+	// Use the credentials for cookie authentication written by brond when
+	// no password was specified.
+	if cfg.RPCPassword == "" && !cfg.Wallet {
+		if cfg.RPCCookieFile == "" {
+			netName := "mainnet"
+			switch {
+			case cfg.TestNet3:
+				netName = "testnet"
+			case cfg.SimNet:
+				netName = "simnet"
+			}
+			cfg.RPCCookieFile = filepath.Join(brondHomeDir, "data",
+				netName, ".cookie")
+		}
+		cookie, err := ioutil.ReadFile(cleanAndExpandPath(cfg.RPCCookieFile))
+		if err == nil {
+			user, password, ok := strings.Cut(
+				strings.TrimSpace(string(cookie)), ":")
+			if ok {
+				cfg.RPCUser, cfg.RPCPassword = user, password
+			}
+		}
+	}
+
 	// Add default port to RPC server based on --testnet and --wallet flags
 	// if needed.
 	cfg.RPCServer = normalizeAddress(cfg.RPCServer, cfg.TestNet3,
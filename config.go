@@ -28,6 +28,7 @@ import (
 	_ "github.com/brsuite/brond/database/ffldb"
 	"github.com/brsuite/brond/mempool"
 	"github.com/brsuite/brond/peer"
+	"github.com/brsuite/brond/zmqpub"
 	"github.com/brsuite/bronutil"
 	"github.com/brsuite/go-socks/socks"
 	flags "github.com/jessevdk/go-flags"
@@ -59,11 +60,14 @@ const (
 	blockMaxWeightMax            = blockchain.MaxBlockWeight - 4000
 	defaultGenerate              = false
 	defaultMaxOrphanTransactions = 100
+	defaultMaxMempoolMiB         = 300
 	defaultMaxOrphanTxSize       = 100000
 	defaultSigCacheMaxSize       = 100000
+	defaultUtxoCacheMaxSizeMiB   = 250
 	sampleConfigFilename         = "sample-brond.conf"
 	defaultTxIndex               = false
 	defaultAddrIndex             = false
+	pruneMinSize                 = 1536
 )
 
 var (
@@ -108,10 +112,15 @@ type config struct {
 	Whitelists           []string      `long:"whitelist" description:"Add an IP network or IP that will not be banned. (eg. 192.168.1.0/24 or ::1)"`
 	AgentBlacklist       []string      `long:"agentblacklist" description:"A comma separated list of user-agent substrings which will cause brond to reject any peers whose user-agent contains any of the blacklisted substrings."`
 	AgentWhitelist       []string      `long:"agentwhitelist" description:"A comma separated list of user-agent substrings which will cause brond to require all peers' user-agents to contain one of the whitelisted substrings. The blacklist is applied before the blacklist, and an empty whitelist will allow all agents that do not fail the blacklist."`
+	V2Transport          bool          `long:"v2transport" description:"Use the encrypted v2 transport protocol (BIP0324) with peers which support it and advertise support for it"`
 	RPCUser              string        `short:"u" long:"rpcuser" description:"Username for RPC connections"`
 	RPCPass              string        `short:"P" long:"rpcpass" default-mask:"-" description:"Password for RPC connections"`
 	RPCLimitUser         string        `long:"rpclimituser" description:"Username for limited RPC connections"`
 	RPCLimitPass         string        `long:"rpclimitpass" default-mask:"-" description:"Password for limited RPC connections"`
+	RPCAuth              []string      `long:"rpcauth" description:"Add a user for RPC connections as <user>:<salt>$<hash> where hash is the hex-encoded HMAC-SHA256 of the password keyed by salt"`
+	RPCCookieFile        string        `long:"rpccookiefile" description:"File to write the credentials for cookie authentication to when no rpcpass is specified (default: .cookie in the data directory)"`
+	RPCWhitelist         []string      `long:"rpcwhitelist" description:"Only allow a user to call some RPC methods as <user>:<method>,<method>,..."`
+	RPCWhitelistNtfn     []string      `long:"rpcwhitelistntfn" description:"Only send some websocket notification types to a user as <user>:<notification>,<notification>,..."`
 	RPCListeners         []string      `long:"rpclisten" description:"Add an interface/port to listen for RPC connections (default port: 8334, testnet: 18334)"`
 	RPCCert              string        `long:"rpccert" description:"File containing the certificate file"`
 	RPCKey               string        `long:"rpckey" description:"File containing the certificate key"`
@@ -119,8 +128,15 @@ type config struct {
 	RPCMaxWebsockets     int           `long:"rpcmaxwebsockets" description:"Max number of RPC websocket connections"`
 	RPCMaxConcurrentReqs int           `long:"rpcmaxconcurrentreqs" description:"Max number of concurrent RPC requests that may be processed concurrently"`
 	RPCQuirks            bool          `long:"rpcquirks" description:"Mirror some JSON-RPC quirks of Brocoin Core -- NOTE: Discouraged unless interoperability issues need to be worked around"`
-	DisableRPC           bool          `long:"norpc" description:"Disable built-in RPC server -- NOTE: The RPC server is disabled by default if no rpcuser/rpcpass or rpclimituser/rpclimitpass is specified"`
+	DisableRPC           bool          `long:"norpc" description:"Disable built-in RPC server"`
+	REST                 bool          `long:"rest" description:"Serve unauthenticated read-only REST requests for blocks, transactions and unspent outputs under /rest/ on the RPC listeners"`
 	DisableTLS           bool          `long:"notls" description:"Disable TLS for the RPC server -- NOTE: This is only allowed if the RPC server is bound to localhost"`
+	ZMQPubHashBlock      string        `long:"zmqpubhashblock" description:"Publish the hashes of connected blocks on a ZeroMQ endpoint (eg. tcp://127.0.0.1:28332)"`
+	ZMQPubHashTx         string        `long:"zmqpubhashtx" description:"Publish the hashes of transactions on a ZeroMQ endpoint"`
+	ZMQPubRawBlock       string        `long:"zmqpubrawblock" description:"Publish serialized connected blocks on a ZeroMQ endpoint"`
+	ZMQPubRawTx          string        `long:"zmqpubrawtx" description:"Publish serialized transactions on a ZeroMQ endpoint"`
+	ZMQPubSequence       string        `long:"zmqpubsequence" description:"Publish block and mempool sequence events on a ZeroMQ endpoint"`
+	ZMQPubHWM            int           `long:"zmqpubhwm" description:"Max number of ZeroMQ messages queued for each subscriber before new messages are dropped"`
 	DisableDNSSeed       bool          `long:"nodnsseed" description:"Disable DNS seeding for peers"`
 	ExternalIPs          []string      `long:"externalip" description:"Add an ip to the list of local addresses we claim to listen on to peers"`
 	Proxy                string        `long:"proxy" description:"Connect via SOCKS5 proxy (eg. 127.0.0.1:9050)"`
@@ -146,6 +162,7 @@ type config struct {
 	NoRelayPriority      bool          `long:"norelaypriority" description:"Do not require free or low-fee transactions to have high priority for relaying"`
 	TrickleInterval      time.Duration `long:"trickleinterval" description:"Minimum time between attempts to send new inventory to a connected peer"`
 	MaxOrphanTxs         int           `long:"maxorphantx" description:"Max number of orphan transactions to keep in memory"`
+	MaxMempool           uint          `long:"maxmempool" description:"Keep the transaction memory pool below the specified size in MiB by evicting the transactions with the lowest fee rate -- 0 disables the limit"`
 	Generate             bool          `long:"generate" description:"Generate (mine) brocoins using the CPU"`
 	MiningAddrs          []string      `long:"miningaddr" description:"Add the specified payment address to the list of addresses to use for generated blocks -- At least one address is required if the generate option is set"`
 	BlockMinSize         uint32        `long:"blockminsize" description:"Mininum block size in bytes to be used when creating a block"`
@@ -158,11 +175,13 @@ type config struct {
 	NoCFilters           bool          `long:"nocfilters" description:"Disable committed filtering (CF) support"`
 	DropCfIndex          bool          `long:"dropcfindex" description:"Deletes the index used for committed filtering (CF) support from the database on start up and then exits."`
 	SigCacheMaxSize      uint          `long:"sigcachemaxsize" description:"The maximum number of entries in the signature verification cache"`
+	UtxoCacheMaxSizeMiB  uint          `long:"utxocachemaxsize" description:"The maximum size in MiB of the UTXO cache"`
 	BlocksOnly           bool          `long:"blocksonly" description:"Do not accept transactions from remote peers."`
 	TxIndex              bool          `long:"txindex" description:"Maintain a full hash-based transaction index which makes all transactions available via the getrawtransaction RPC"`
 	DropTxIndex          bool          `long:"droptxindex" description:"Deletes the hash-based transaction index from the database on start up and then exits."`
 	AddrIndex            bool          `long:"addrindex" description:"Maintain a full address-based transaction index which makes the searchrawtransactions RPC available"`
 	DropAddrIndex        bool          `long:"dropaddrindex" description:"Deletes the address-based transaction index from the database on start up and then exits."`
+	Prune                uint64        `long:"prune" description:"Delete the oldest blocks from the database once the stored blocks exceed the target size in MiB -- Minimum 1536, 0 disables pruning -- NOTE: Incompatible with --txindex and --addrindex"`
 	RelayNonStd          bool          `long:"relaynonstd" description:"Relay non-standard transactions regardless of the default settings for the active network."`
 	RejectNonStd         bool          `long:"rejectnonstd" description:"Reject non-standard transactions regardless of the default settings for the active network."`
 	RejectReplacement    bool          `long:"rejectreplacement" description:"Reject transactions that attempt to replace existing transactions within the mempool through the Replace-By-Fee (RBF) signaling policy."`
@@ -173,6 +192,9 @@ type config struct {
 	miningAddrs          []bronutil.Address
 	minRelayTxFee        bronutil.Amount
 	whitelists           []*net.IPNet
+	rpcAuthUsers         []*rpcUser
+	rpcWhitelist         map[string]map[string]struct{}
+	rpcWhitelistNtfn     map[string]map[string]struct{}
 }
 
 // serviceOptions defines the configuration options for the daemon as a service on
@@ -414,6 +436,7 @@ func loadConfig() (*config, []string, error) {
 		RPCMaxClients:        defaultMaxRPCClients,
 		RPCMaxWebsockets:     defaultMaxRPCWebsockets,
 		RPCMaxConcurrentReqs: defaultMaxRPCConcurrentReqs,
+		ZMQPubHWM:            zmqpub.DefaultHighWaterMark,
 		DataDir:              defaultDataDir,
 		LogDir:               defaultLogDir,
 		DbType:               defaultDbType,
@@ -428,7 +451,9 @@ func loadConfig() (*config, []string, error) {
 		BlockMaxWeight:       defaultBlockMaxWeight,
 		BlockPrioritySize:    mempool.DefaultBlockPrioritySize,
 		MaxOrphanTxs:         defaultMaxOrphanTransactions,
+		MaxMempool:           defaultMaxMempoolMiB,
 		SigCacheMaxSize:      defaultSigCacheMaxSize,
+		UtxoCacheMaxSizeMiB:  defaultUtxoCacheMaxSizeMiB,
 		Generate:             defaultGenerate,
 		TxIndex:              defaultTxIndex,
 		AddrIndex:            defaultAddrIndex,
@@ -723,16 +748,59 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
-	// The RPC server is disabled if no username or password is provided.
-	if (cfg.RPCUser == "" || cfg.RPCPass == "") &&
-		(cfg.RPCLimitUser == "" || cfg.RPCLimitPass == "") {
-		cfg.DisableRPC = true
+	// Parse the users with salted password hashes.
+	for _, auth := range cfg.RPCAuth {
+		user, err := parseRPCAuth(auth)
+		if err != nil {
+			str := "%s: invalid --rpcauth %q: %v"
+			err := fmt.Errorf(str, funcName, auth, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		cfg.rpcAuthUsers = append(cfg.rpcAuthUsers, user)
 	}
 
+	// Parse the RPC methods and websocket notifications available to
+	// users.
+	cfg.rpcWhitelist, err = parseRPCWhitelist(cfg.RPCWhitelist, false)
+	if err != nil {
+		str := "%s: invalid --rpcwhitelist %v"
+		err := fmt.Errorf(str, funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	cfg.rpcWhitelistNtfn, err = parseRPCWhitelist(cfg.RPCWhitelistNtfn, true)
+	if err != nil {
+		str := "%s: invalid --rpcwhitelistntfn %v"
+		err := fmt.Errorf(str, funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Credentials for cookie authentication are written to the data
+	// directory by default when no password is provided.
+	if cfg.RPCCookieFile == "" {
+		cfg.RPCCookieFile = filepath.Join(cfg.DataDir, rpcCookieFilename)
+	}
+	cfg.RPCCookieFile = cleanAndExpandPath(cfg.RPCCookieFile)
+
 	if cfg.DisableRPC {
 		brondLog.Infof("RPC service is disabled")
 	}
 
+	// The REST interface is served by the RPC server.
+	if cfg.REST && cfg.DisableRPC {
+		str := "%s: The rest option requires the RPC server, which " +
+			"is disabled by the norpc option"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Default RPC to listen on localhost only.
 	if !cfg.DisableRPC && len(cfg.RPCListeners) == 0 {
 		addrs, err := net.LookupHost("localhost")
@@ -755,6 +823,16 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// The ZeroMQ high water mark must be positive.
+	if cfg.ZMQPubHWM <= 0 {
+		str := "%s: The zmqpubhwm option must be greater than 0 -- " +
+			"parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.ZMQPubHWM)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Validate the the minrelaytxfee.
 	cfg.minRelayTxFee, err = bronutil.NewAmount(cfg.MinRelayTxFee)
 	if err != nil {
@@ -867,6 +945,29 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Ensure the prune target is large enough to always hold the most
+	// recent blocks which are never pruned.
+	if cfg.Prune != 0 && cfg.Prune < pruneMinSize {
+		err := fmt.Errorf("%s: the --prune option value of %d MiB is "+
+			"below the minimum of %d MiB", funcName, cfg.Prune,
+			pruneMinSize)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --prune and the indexes which require all historical blocks do not
+	// mix.  A database which has been pruned before can't be opened
+	// without --prune, so this keeps the indexes off it as well.
+	if cfg.Prune != 0 && (cfg.TxIndex || cfg.AddrIndex) {
+		err := fmt.Errorf("%s: the --prune option may not be activated "+
+			"at the same time as the --txindex or --addrindex options "+
+			"since they require all historical blocks", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Check mining addresses are valid and saved parsed versions.
 	cfg.miningAddrs = make([]bronutil.Address, 0, len(cfg.MiningAddrs))
 	for _, strAddr := range cfg.MiningAddrs {
@@ -0,0 +1,257 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package connmgr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotBanned is returned when attempting to unban a subnet which is not
+// banned.
+var ErrNotBanned = errors.New("subnet is not banned")
+
+// BanEntry describes a banned subnet.
+type BanEntry struct {
+	// Subnet is the banned subnet.  Bans of individual addresses are
+	// represented by subnets with a full-length mask.
+	Subnet *net.IPNet
+
+	// Created is the time the ban was created.
+	Created time.Time
+
+	// Until is the time the ban expires.
+	Until time.Time
+}
+
+// serializedBanEntry is the representation of a BanEntry in the ban list file.
+type serializedBanEntry struct {
+	Subnet  string `json:"subnet"`
+	Created int64  `json:"created"`
+	Until   int64  `json:"until"`
+}
+
+// BanList keeps track of banned IP addresses and subnets along with the time
+// each ban expires.  When it is backed by a file, every change is written to
+// it so the bans persist across restarts.  Expired bans are removed lazily.
+//
+// It is safe for concurrent access.
+type BanList struct {
+	mtx  sync.Mutex
+	path string
+	bans map[string]*BanEntry
+}
+
+// ParseSubnet parses an IP address or a subnet in CIDR notation.  A single
+// address is treated as a subnet which only contains that address.
+func ParseSubnet(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, subnet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		return subnet, nil
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", s)
+	}
+	return SingleIPSubnet(ip), nil
+}
+
+// SingleIPSubnet returns the subnet which only contains the passed address.
+func SingleIPSubnet(ip net.IP) *net.IPNet {
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: ip.To16(), Mask: net.CIDRMask(128, 128)}
+}
+
+// NewBanList returns a new ban list.  When a path is provided, the bans which
+// were previously saved to the file are loaded and all further changes are
+// written to it.  A missing file is not an error.
+func NewBanList(path string) (*BanList, error) {
+	bl := &BanList{
+		path: path,
+		bans: make(map[string]*BanEntry),
+	}
+	if path == "" {
+		return bl, nil
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return bl, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []serializedBanEntry
+	if err := json.NewDecoder(f).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("unable to decode ban list %s: %v",
+			path, err)
+	}
+	for _, e := range entries {
+		_, subnet, err := net.ParseCIDR(e.Subnet)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subnet in ban list %s: "+
+				"%v", path, err)
+		}
+		bl.bans[subnet.String()] = &BanEntry{
+			Subnet:  subnet,
+			Created: time.Unix(e.Created, 0),
+			Until:   time.Unix(e.Until, 0),
+		}
+	}
+	bl.removeExpired(time.Now())
+
+	return bl, nil
+}
+
+// removeExpired removes all bans which have expired by the passed time and
+// returns whether any were removed.
+//
+// This function MUST be called with the ban list lock held.
+func (bl *BanList) removeExpired(now time.Time) bool {
+	var removed bool
+	for key, ban := range bl.bans {
+		if !now.Before(ban.Until) {
+			log.Debugf("Ban of %v has expired", ban.Subnet)
+			delete(bl.bans, key)
+			removed = true
+		}
+	}
+	return removed
+}
+
+// save writes the ban list to its file if it has one.  The list is written to
+// a temporary file first which then replaces the existing file, so that an
+// interrupted write doesn't leave a truncated file behind.
+//
+// This function MUST be called with the ban list lock held.
+func (bl *BanList) save() error {
+	if bl.path == "" {
+		return nil
+	}
+
+	entries := make([]serializedBanEntry, 0, len(bl.bans))
+	for _, ban := range bl.bans {
+		entries = append(entries, serializedBanEntry{
+			Subnet:  ban.Subnet.String(),
+			Created: ban.Created.Unix(),
+			Until:   ban.Until.Unix(),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Subnet < entries[j].Subnet
+	})
+
+	tmpPath := bl.path + ".new"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	err = json.NewEncoder(f).Encode(entries)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, bl.path)
+}
+
+// Ban bans the passed subnet until the passed time.  An existing ban of the
+// same subnet is replaced.
+func (bl *BanList) Ban(subnet *net.IPNet, until time.Time) error {
+	bl.mtx.Lock()
+	defer bl.mtx.Unlock()
+
+	now := time.Now()
+	bl.removeExpired(now)
+	bl.bans[subnet.String()] = &BanEntry{
+		Subnet:  subnet,
+		Created: now,
+		Until:   until,
+	}
+	return bl.save()
+}
+
+// Unban removes the ban of the passed subnet.  ErrNotBanned is returned when
+// the subnet is not banned.  Note that addresses within the subnet which are
+// banned separately remain banned.
+func (bl *BanList) Unban(subnet *net.IPNet) error {
+	bl.mtx.Lock()
+	defer bl.mtx.Unlock()
+
+	bl.removeExpired(time.Now())
+	key := subnet.String()
+	if _, ok := bl.bans[key]; !ok {
+		return ErrNotBanned
+	}
+	delete(bl.bans, key)
+	return bl.save()
+}
+
+// Clear removes all bans.
+func (bl *BanList) Clear() error {
+	bl.mtx.Lock()
+	defer bl.mtx.Unlock()
+
+	bl.bans = make(map[string]*BanEntry)
+	return bl.save()
+}
+
+// BannedUntil returns the time until which the passed address is banned and
+// whether it is banned at all.  When the address is within several banned
+// subnets, the latest expiry time is returned.
+func (bl *BanList) BannedUntil(ip net.IP) (time.Time, bool) {
+	bl.mtx.Lock()
+	defer bl.mtx.Unlock()
+
+	if bl.removeExpired(time.Now()) {
+		if err := bl.save(); err != nil {
+			log.Errorf("Unable to save ban list: %v", err)
+		}
+	}
+
+	var until time.Time
+	var banned bool
+	for _, ban := range bl.bans {
+		if ban.Subnet.Contains(ip) && ban.Until.After(until) {
+			until = ban.Until
+			banned = true
+		}
+	}
+	return until, banned
+}
+
+// Entries returns all current bans ordered by subnet.
+func (bl *BanList) Entries() []BanEntry {
+	bl.mtx.Lock()
+	defer bl.mtx.Unlock()
+
+	bl.removeExpired(time.Now())
+	entries := make([]BanEntry, 0, len(bl.bans))
+	for _, ban := range bl.bans {
+		entries = append(entries, *ban)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Subnet.String() < entries[j].Subnet.String()
+	})
+	return entries
+}
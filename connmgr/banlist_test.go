@@ -0,0 +1,134 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package connmgr
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestParseSubnet ensures addresses and subnets in CIDR notation are parsed
+// into the expected subnets.
+func TestParseSubnet(t *testing.T) {
+	tests := []struct {
+		in   string // String to parse
+		want string // Expected subnet, empty on error
+	}{
+		{"1.2.3.4", "1.2.3.4/32"},
+		{"1.2.3.4/24", "1.2.3.0/24"},
+		{"2001:db8::1", "2001:db8::1/128"},
+		{"2001:db8::1/32", "2001:db8::/32"},
+		{"::ffff:1.2.3.4", "1.2.3.4/32"},
+		{"1.2.3", ""},
+		{"1.2.3.4/33", ""},
+	}
+
+	for i, test := range tests {
+		subnet, err := ParseSubnet(test.in)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("ParseSubnet #%d (%s): expected error",
+					i, test.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSubnet #%d (%s): unexpected error %v", i,
+				test.in, err)
+			continue
+		}
+		if subnet.String() != test.want {
+			t.Errorf("ParseSubnet #%d (%s): got %s, want %s", i,
+				test.in, subnet, test.want)
+		}
+	}
+}
+
+// TestBanList ensures addresses within banned subnets are reported as banned
+// until the bans expire or are removed, and that bans persist in the ban list
+// file.
+func TestBanList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "banlist.json")
+	bl, err := NewBanList(path)
+	if err != nil {
+		t.Fatalf("NewBanList: unexpected error %v", err)
+	}
+
+	mustParse := func(s string) *net.IPNet {
+		subnet, err := ParseSubnet(s)
+		if err != nil {
+			t.Fatalf("ParseSubnet: unexpected error %v", err)
+		}
+		return subnet
+	}
+	checkBanned := func(bl *BanList, ip string, want bool) {
+		t.Helper()
+		if _, banned := bl.BannedUntil(net.ParseIP(ip)); banned != want {
+			t.Fatalf("BannedUntil(%s): got %v, want %v", ip, banned,
+				want)
+		}
+	}
+
+	// Ban a subnet and a single address along with a ban which has
+	// already expired.
+	until := time.Unix(time.Now().Add(time.Hour).Unix(), 0)
+	if err := bl.Ban(mustParse("10.0.0.0/8"), until); err != nil {
+		t.Fatalf("Ban: unexpected error %v", err)
+	}
+	if err := bl.Ban(mustParse("2001:db8::1"), until); err != nil {
+		t.Fatalf("Ban: unexpected error %v", err)
+	}
+	expired := time.Now().Add(-time.Second)
+	if err := bl.Ban(mustParse("1.2.3.4"), expired); err != nil {
+		t.Fatalf("Ban: unexpected error %v", err)
+	}
+	checkBanned(bl, "10.1.2.3", true)
+	checkBanned(bl, "11.1.2.3", false)
+	checkBanned(bl, "2001:db8::1", true)
+	checkBanned(bl, "2001:db8::2", false)
+	checkBanned(bl, "1.2.3.4", false)
+	if got, _ := bl.BannedUntil(net.ParseIP("10.1.2.3")); !got.Equal(until) {
+		t.Fatalf("BannedUntil: got %v, want %v", got, until)
+	}
+
+	// Ensure the bans are loaded from the file and expired bans are gone.
+	bl, err = NewBanList(path)
+	if err != nil {
+		t.Fatalf("NewBanList: unexpected error %v", err)
+	}
+	entries := bl.Entries()
+	if len(entries) != 2 || entries[0].Subnet.String() != "10.0.0.0/8" ||
+		entries[1].Subnet.String() != "2001:db8::1/128" {
+
+		t.Fatalf("Entries: unexpected bans %v", entries)
+	}
+	if !entries[0].Until.Equal(until) {
+		t.Fatalf("Entries: wrong ban expiry - got %v, want %v",
+			entries[0].Until, until)
+	}
+
+	// Ensure bans can be removed individually and all at once.
+	if err := bl.Unban(mustParse("10.0.0.0/8")); err != nil {
+		t.Fatalf("Unban: unexpected error %v", err)
+	}
+	if err := bl.Unban(mustParse("10.0.0.0/8")); err != ErrNotBanned {
+		t.Fatalf("Unban: got %v, want %v", err, ErrNotBanned)
+	}
+	checkBanned(bl, "10.1.2.3", false)
+	if err := bl.Clear(); err != nil {
+		t.Fatalf("Clear: unexpected error %v", err)
+	}
+	checkBanned(bl, "2001:db8::1", false)
+
+	bl, err = NewBanList(path)
+	if err != nil {
+		t.Fatalf("NewBanList: unexpected error %v", err)
+	}
+	if entries := bl.Entries(); len(entries) != 0 {
+		t.Fatalf("Entries: unexpected bans %v after clear", entries)
+	}
+}
@@ -52,6 +52,37 @@ const (
 	ConnDisconnected
 )
 
+// ConnType represents the type of an outbound connection, which determines
+// what is relayed over it.
+type ConnType uint8
+
+// ConnType can be full relay, block relay or feeler.  Full relay connections
+// relay everything, while block relay connections only relay blocks to make it
+// harder to infer the network topology from transaction and address relay.
+// Feeler connections are short-lived and only test whether an address is
+// reachable.
+const (
+	ConnFullRelay ConnType = iota
+	ConnBlockRelay
+	ConnFeeler
+)
+
+// connTypeStrings is a map of connection types back to their constant names
+// for pretty printing.
+var connTypeStrings = map[ConnType]string{
+	ConnFullRelay:  "outbound-full-relay",
+	ConnBlockRelay: "block-relay-only",
+	ConnFeeler:     "feeler",
+}
+
+// String returns the ConnType as a human-readable name.
+func (t ConnType) String() string {
+	if s, ok := connTypeStrings[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown ConnType (%d)", uint8(t))
+}
+
 // ConnReq is the connection request to a network address. If permanent, the
 // connection will be retried on disconnection.
 type ConnReq struct {
@@ -60,6 +91,7 @@ type ConnReq struct {
 
 	Addr      net.Addr
 	Permanent bool
+	Type      ConnType
 
 	conn       net.Conn
 	state      ConnState
@@ -124,6 +156,16 @@ type Config struct {
 	// maintain. Defaults to 8.
 	TargetOutbound uint32
 
+	// TargetBlockRelay is the number of block relay connections to maintain
+	// in addition to the TargetOutbound connections.  It may be zero.
+	TargetBlockRelay uint32
+
+	// Anchors are the addresses of block relay connections to make on start
+	// up, typically those of the block relay connections at the previous
+	// shutdown.  Only the first TargetBlockRelay addresses are used and the
+	// remaining block relay connections are made to new addresses.
+	Anchors []net.Addr
+
 	// RetryDuration is the duration to wait before retrying connection
 	// requests. Defaults to 5s.
 	RetryDuration time.Duration
@@ -140,6 +182,15 @@ type Config struct {
 	// to.  If nil, no new connections will be made automatically.
 	GetNewAddress func() (net.Addr, error)
 
+	// FeelerInterval is the interval at which a feeler connection is made
+	// once TargetOutbound connections are established.  Feeler connections
+	// are disabled when it is zero.
+	FeelerInterval time.Duration
+
+	// GetFeelerAddress is a way to get an address to make a feeler
+	// connection to.  If nil, no feeler connections are made.
+	GetFeelerAddress func() (net.Addr, error)
+
 	// Dial connects to the address on the named network. It cannot be nil.
 	Dial func(net.Addr) (net.Conn, error)
 }
@@ -187,7 +238,8 @@ type ConnManager struct {
 
 // handleFailedConn handles a connection failed due to a disconnect or any
 // other failure. If permanent, it retries the connection after the configured
-// retry duration. Otherwise, if required, it makes a new connection request.
+// retry duration. Otherwise, if required, it makes a new connection request of
+// the same type, except for feeler connections which are not replaced.
 // After maxFailedConnectionAttempts new connections will be retried after the
 // configured retry duration.
 func (cm *ConnManager) handleFailedConn(c *ConnReq) {
@@ -204,19 +256,40 @@ func (cm *ConnManager) handleFailedConn(c *ConnReq) {
 		time.AfterFunc(d, func() {
 			cm.Connect(c)
 		})
-	} else if cm.cfg.GetNewAddress != nil {
+	} else if c.Type != ConnFeeler && cm.cfg.GetNewAddress != nil {
 		cm.failedAttempts++
 		if cm.failedAttempts >= maxFailedAttempts {
 			log.Debugf("Max failed connection attempts reached: [%d] "+
 				"-- retrying connection in: %v", maxFailedAttempts,
 				cm.cfg.RetryDuration)
 			time.AfterFunc(cm.cfg.RetryDuration, func() {
-				cm.NewConnReq()
+				cm.newConnReq(c.Type)
 			})
 		} else {
-			go cm.NewConnReq()
+			go cm.newConnReq(c.Type)
+		}
+	}
+}
+
+// targetReached returns whether the passed connections include the number of
+// connections of the passed type the connection manager maintains.  It is
+// always true for feeler connections since they are not maintained.
+func (cm *ConnManager) targetReached(conns map[uint64]*ConnReq, t ConnType) bool {
+	var target uint32
+	switch t {
+	case ConnFullRelay:
+		target = cm.cfg.TargetOutbound
+	case ConnBlockRelay:
+		target = cm.cfg.TargetBlockRelay
+	}
+
+	var count uint32
+	for _, connReq := range conns {
+		if connReq.Type == t {
+			count++
 		}
 	}
+	return count >= target
 }
 
 // connHandler handles all connection related requests.  It must be run as a
@@ -234,8 +307,18 @@ func (cm *ConnManager) connHandler() {
 
 		// conns represents the set of all actively connected peers.
 		conns = make(map[uint64]*ConnReq, cm.cfg.TargetOutbound)
+
+		// feelerTicker fires when it is time for a feeler connection.
+		// It is nil, and thus never fires, when they are disabled.
+		feelerTicker <-chan time.Time
 	)
 
+	if cm.cfg.FeelerInterval > 0 && cm.cfg.GetFeelerAddress != nil {
+		ticker := time.NewTicker(cm.cfg.FeelerInterval)
+		defer ticker.Stop()
+		feelerTicker = ticker.C
+	}
+
 out:
 	for {
 		select {
@@ -317,12 +400,12 @@ out:
 				}
 
 				// Otherwise, we will attempt a reconnection if
-				// we do not have enough peers, or if this is a
-				// persistent peer. The connection request is
-				// re added to the pending map, so that
-				// subsequent processing of connections and
+				// we do not have enough peers of its type, or if
+				// this is a persistent peer. The connection
+				// request is re added to the pending map, so
+				// that subsequent processing of connections and
 				// failures do not ignore the request.
-				if uint32(len(conns)) < cm.cfg.TargetOutbound ||
+				if !cm.targetReached(conns, connReq.Type) ||
 					connReq.Permanent {
 
 					connReq.updateState(ConnPending)
@@ -347,6 +430,14 @@ out:
 				cm.handleFailedConn(connReq)
 			}
 
+		// Test an address with a feeler connection, but only once
+		// the outbound connections are established so that they are
+		// not delayed by it.
+		case <-feelerTicker:
+			if cm.targetReached(conns, ConnFullRelay) {
+				go cm.newConnReq(ConnFeeler)
+			}
+
 		case <-cm.quit:
 			break out
 		}
@@ -356,17 +447,34 @@ out:
 	log.Trace("Connection handler done")
 }
 
-// NewConnReq creates a new connection request and connects to the
+// NewConnReq creates a new full relay connection request and connects to the
 // corresponding address.
 func (cm *ConnManager) NewConnReq() {
+	cm.newConnReq(ConnFullRelay)
+}
+
+// NewBlockRelayConnReq creates a new block relay connection request and
+// connects to the corresponding address.
+func (cm *ConnManager) NewBlockRelayConnReq() {
+	cm.newConnReq(ConnBlockRelay)
+}
+
+// newConnReq creates a new connection request of the passed type and connects
+// to the corresponding address.  Feeler connections get their address from
+// GetFeelerAddress and the others from GetNewAddress.
+func (cm *ConnManager) newConnReq(t ConnType) {
 	if atomic.LoadInt32(&cm.stop) != 0 {
 		return
 	}
-	if cm.cfg.GetNewAddress == nil {
+	getAddress := cm.cfg.GetNewAddress
+	if t == ConnFeeler {
+		getAddress = cm.cfg.GetFeelerAddress
+	}
+	if getAddress == nil {
 		return
 	}
 
-	c := &ConnReq{}
+	c := &ConnReq{Type: t}
 	atomic.StoreUint64(&c.id, atomic.AddUint64(&cm.connReqCount, 1))
 
 	// Submit a request of a pending connection attempt to the connection
@@ -388,7 +496,7 @@ func (cm *ConnManager) NewConnReq() {
 		return
 	}
 
-	addr, err := cm.cfg.GetNewAddress()
+	addr, err := getAddress()
 	if err != nil {
 		select {
 		case cm.requests <- handleFailed{c, err}:
@@ -529,6 +637,20 @@ func (cm *ConnManager) Start() {
 	for i := atomic.LoadUint64(&cm.connReqCount); i < uint64(cm.cfg.TargetOutbound); i++ {
 		go cm.NewConnReq()
 	}
+
+	// Reconnect to the anchors as block relay connections and make the
+	// remaining ones to new addresses.  Anchors are not permanent, so
+	// failed ones are replaced like any other block relay connection.
+	anchors := cm.cfg.Anchors
+	if uint32(len(anchors)) > cm.cfg.TargetBlockRelay {
+		anchors = anchors[:cm.cfg.TargetBlockRelay]
+	}
+	for _, addr := range anchors {
+		go cm.Connect(&ConnReq{Addr: addr, Type: ConnBlockRelay})
+	}
+	for i := uint32(len(anchors)); i < cm.cfg.TargetBlockRelay; i++ {
+		go cm.NewBlockRelayConnReq()
+	}
 }
 
 // Wait blocks until the connection manager halts gracefully.
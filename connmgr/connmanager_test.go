@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"reflect"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -216,6 +217,114 @@ func TestTargetOutbound(t *testing.T) {
 	cmgr.Stop()
 }
 
+// TestTargetBlockRelay tests the target number of block relay connections and
+// that they are made to the anchors first.
+//
+// We wait until all connections are established, check their types and
+// addresses, then disconnect a block relay connection and wait for it to be
+// replaced.
+func TestTargetBlockRelay(t *testing.T) {
+	targetOutbound := uint32(2)
+	targetBlockRelay := uint32(2)
+	newAddr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 18555}
+	anchors := []net.Addr{
+		&net.TCPAddr{IP: net.ParseIP("127.0.0.2"), Port: 18555},
+		&net.TCPAddr{IP: net.ParseIP("127.0.0.3"), Port: 18555},
+		&net.TCPAddr{IP: net.ParseIP("127.0.0.4"), Port: 18555},
+	}
+	connected := make(chan *ConnReq)
+	cmgr, err := New(&Config{
+		TargetOutbound:   targetOutbound,
+		TargetBlockRelay: targetBlockRelay,
+		Anchors:          anchors,
+		Dial:             mockDialer,
+		GetNewAddress: func() (net.Addr, error) {
+			return newAddr, nil
+		},
+		OnConnection: func(c *ConnReq, conn net.Conn) {
+			connected <- c
+		},
+	})
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	cmgr.Start()
+
+	gotAddrs := make(map[string]ConnType)
+	var blockRelay *ConnReq
+	for i := uint32(0); i < targetOutbound+targetBlockRelay; i++ {
+		c := <-connected
+		if c.Type == ConnBlockRelay {
+			gotAddrs[c.Addr.String()] = c.Type
+			blockRelay = c
+		}
+	}
+	select {
+	case c := <-connected:
+		t.Fatalf("target block relay: got unexpected connection - %v", c.Addr)
+	case <-time.After(time.Millisecond):
+		break
+	}
+	wantAddrs := map[string]ConnType{
+		anchors[0].String(): ConnBlockRelay,
+		anchors[1].String(): ConnBlockRelay,
+	}
+	if !reflect.DeepEqual(gotAddrs, wantAddrs) {
+		t.Fatalf("target block relay: got block relay connections %v, "+
+			"want %v", gotAddrs, wantAddrs)
+	}
+
+	// A disconnected block relay connection is replaced by a block relay
+	// connection to a new address.
+	cmgr.Disconnect(blockRelay.ID())
+	c := <-connected
+	if c.Type != ConnBlockRelay || c.Addr.String() != newAddr.String() {
+		t.Fatalf("target block relay: got %v connection to %v, want %v "+
+			"connection to %v", c.Type, c.Addr, ConnBlockRelay, newAddr)
+	}
+	cmgr.Stop()
+}
+
+// TestFeelers tests that feeler connections are made to the addresses from
+// GetFeelerAddress once the target number of outbound connections is reached.
+func TestFeelers(t *testing.T) {
+	feelerAddr := &net.TCPAddr{IP: net.ParseIP("127.0.0.2"), Port: 18555}
+	connected := make(chan *ConnReq)
+	cmgr, err := New(&Config{
+		TargetOutbound: 1,
+		RetryDuration:  time.Millisecond,
+		FeelerInterval: time.Millisecond,
+		Dial:           mockDialer,
+		GetNewAddress: func() (net.Addr, error) {
+			return &net.TCPAddr{
+				IP:   net.ParseIP("127.0.0.1"),
+				Port: 18555,
+			}, nil
+		},
+		GetFeelerAddress: func() (net.Addr, error) {
+			return feelerAddr, nil
+		},
+		OnConnection: func(c *ConnReq, conn net.Conn) {
+			connected <- c
+		},
+	})
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	cmgr.Start()
+	c := <-connected
+	if c.Type != ConnFullRelay {
+		t.Fatalf("feelers: got %v connection, want %v", c.Type,
+			ConnFullRelay)
+	}
+	c = <-connected
+	if c.Type != ConnFeeler || c.Addr.String() != feelerAddr.String() {
+		t.Fatalf("feelers: got %v connection to %v, want %v connection "+
+			"to %v", c.Type, c.Addr, ConnFeeler, feelerAddr)
+	}
+	cmgr.Stop()
+}
+
 // TestRetryPermanent tests that permanent connection requests are retried.
 //
 // We make a permanent connection request using Connect, disconnect it using
@@ -87,6 +87,17 @@ func (s *DynamicBanScore) Int() uint32 {
 	return r
 }
 
+// Breakdown returns the persistent and the current decaying parts of the ban
+// score separately.  Their sum is the score returned by Int.
+//
+// This function is safe for concurrent access.
+func (s *DynamicBanScore) Breakdown() (persistent, transient uint32) {
+	s.mtx.Lock()
+	persistent, transient = s.breakdown(time.Now())
+	s.mtx.Unlock()
+	return persistent, transient
+}
+
 // Increase increases both the persistent and decaying scores by the values
 // passed as parameters. The resulting score is returned.
 //
@@ -122,6 +133,14 @@ func (s *DynamicBanScore) int(t time.Time) uint32 {
 	return s.persistent + uint32(s.transient*decayFactor(dt))
 }
 
+// breakdown returns the persistent and the decaying scores at a given point in
+// time.
+//
+// This function is not safe for concurrent access.
+func (s *DynamicBanScore) breakdown(t time.Time) (uint32, uint32) {
+	return s.persistent, s.int(t) - s.persistent
+}
+
 // increase increases the persistent, the decaying or both scores by the values
 // passed as parameters. The resulting score is calculated as if the action was
 // carried out at the point time represented by the third parameter. The
@@ -26,6 +26,11 @@ func TestDynamicBanScoreDecay(t *testing.T) {
 		t.Errorf("Halflife check failed - %d instead of 125", r)
 	}
 
+	p, tr := bs.breakdown(base.Add(time.Minute))
+	if p != 100 || tr != 25 {
+		t.Errorf("Breakdown after 1m - %d + %d instead of 100 + 25", p, tr)
+	}
+
 	r = bs.int(base.Add(7 * time.Minute))
 	if r != 100 {
 		t.Errorf("Decay after 7m - %d instead of 100", r)
@@ -0,0 +1,27 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+/*
+Package eviction implements the selection of the inbound peer to disconnect
+when all inbound connection slots are taken and a new inbound peer connects.
+
+Overview
+
+Rejecting new inbound peers once the connection slots are full would let an
+attacker who fills them first keep them indefinitely.  Instead, an existing
+inbound peer is evicted to make room, chosen so that an attacker can't easily
+protect its own connections at the expense of honest ones.
+
+Peers are protected in turn by properties which are hard to imitate: their
+network group, chosen through a secret key, their ping time, how recently they
+relayed new transactions and blocks, and how long they have been connected.
+The most recently connected peer of the network group with the most remaining
+peers is then evicted, since an attacker is likely to make many connections
+from few network groups.
+
+The selection is a deterministic function of the passed candidates, so the
+caller is responsible for gathering their properties and keying their network
+groups.
+*/
+package eviction
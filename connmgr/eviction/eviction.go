@@ -0,0 +1,206 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package eviction
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// numProtectedNetGroup is the number of peers protected because their
+	// network group has the highest keyed value.
+	numProtectedNetGroup = 4
+
+	// numProtectedPing is the number of peers with the lowest minimum ping
+	// time which are protected.
+	numProtectedPing = 8
+
+	// numProtectedTx is the number of peers which most recently relayed a
+	// new transaction which are protected.
+	numProtectedTx = 4
+
+	// numProtectedBlockRelayOnly is the maximum number of peers which don't
+	// relay transactions that are protected because they most recently
+	// relayed a new block.
+	numProtectedBlockRelayOnly = 8
+
+	// numProtectedBlock is the number of peers which most recently relayed
+	// a new block which are protected.
+	numProtectedBlock = 4
+)
+
+// Candidate describes an inbound peer which may be evicted.
+type Candidate struct {
+	// ID uniquely identifies the peer.
+	ID int32
+
+	// NetGroup is a keyed hash of the network group of the address of the
+	// peer.  The key should be secret and random so an attacker can't
+	// predict which network groups are protected.
+	NetGroup uint64
+
+	// ConnTime is the time the peer connected.
+	ConnTime time.Time
+
+	// MinPing is the lowest ping time of the peer.  Zero means the peer
+	// hasn't answered a ping yet.
+	MinPing time.Duration
+
+	// LastBlockTime is the last time the peer relayed a new block which
+	// extended the best chain.  The zero time means never.
+	LastBlockTime time.Time
+
+	// LastTxTime is the last time the peer relayed a new transaction which
+	// was accepted into the memory pool.  The zero time means never.
+	LastTxTime time.Time
+
+	// RelayTxs indicates whether transactions are relayed to the peer.
+	RelayTxs bool
+}
+
+// older returns whether candidate a connected before candidate b.  The ID is
+// used as a tie breaker so the order is deterministic.
+func older(a, b *Candidate) bool {
+	if !a.ConnTime.Equal(b.ConnTime) {
+		return a.ConnTime.Before(b.ConnTime)
+	}
+	return a.ID < b.ID
+}
+
+// protect sorts the candidates so the ones which should be protected the most
+// come first according to the passed less function and returns the candidates
+// which remain after protecting the first n of them.  Ties are broken by
+// connection time, protecting the older candidates first.  When an eligible
+// function is passed, only the first n candidates it returns true for are
+// protected.
+func protect(candidates []*Candidate, n int, less func(a, b *Candidate) bool,
+	eligible func(c *Candidate) bool) []*Candidate {
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return older(a, b)
+	})
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+	if eligible == nil {
+		return candidates[n:]
+	}
+
+	remaining := make([]*Candidate, 0, len(candidates))
+	remaining = append(remaining, candidates[n:]...)
+	for _, c := range candidates[:n] {
+		if !eligible(c) {
+			remaining = append(remaining, c)
+		}
+	}
+	return remaining
+}
+
+// SelectPeerToEvict selects the inbound peer to disconnect in order to make room
+// for a new inbound peer from the passed candidates, which should exclude any
+// peers which must never be evicted, such as whitelisted ones.
+//
+// It first protects the candidates which would be hard for an attacker to
+// imitate: a few candidates chosen by their keyed network group, those with the
+// lowest ping times, those which most recently relayed new transactions and
+// blocks, with extra room for peers which don't relay transactions, and finally
+// half of the remaining candidates which have been connected the longest.  Of
+// the candidates left, the network group with the most of them is picked, with
+// ties going to the one with the most recent connection, and its most recently
+// connected candidate is selected.
+//
+// The selection only depends on the passed candidates, so it is deterministic.
+// The ID of the selected candidate is returned, and false when all of them are
+// protected.
+func SelectPeerToEvict(candidates []Candidate) (int32, bool) {
+	remaining := make([]*Candidate, 0, len(candidates))
+	for i := range candidates {
+		remaining = append(remaining, &candidates[i])
+	}
+
+	// Protect peers from a few network groups chosen by their keyed value,
+	// so an attacker can't predict which network groups to connect from.
+	remaining = protect(remaining, numProtectedNetGroup, func(a, b *Candidate) bool {
+		return a.NetGroup > b.NetGroup
+	}, nil)
+
+	// Protect the peers with the lowest ping times, which an attacker far
+	// away on the network can't imitate.  Peers which haven't answered a
+	// ping yet come last.
+	remaining = protect(remaining, numProtectedPing, func(a, b *Candidate) bool {
+		if a.MinPing == 0 || b.MinPing == 0 {
+			return a.MinPing != 0 && b.MinPing == 0
+		}
+		return a.MinPing < b.MinPing
+	}, nil)
+
+	// Protect the peers which most recently relayed new transactions.
+	remaining = protect(remaining, numProtectedTx, func(a, b *Candidate) bool {
+		return a.LastTxTime.After(b.LastTxTime)
+	}, nil)
+
+	// Protect up to a number of peers which don't relay transactions and
+	// most recently relayed new blocks, since they have no chance of being
+	// protected for relaying transactions.
+	remaining = protect(remaining, numProtectedBlockRelayOnly, func(a, b *Candidate) bool {
+		if a.RelayTxs != b.RelayTxs {
+			return !a.RelayTxs
+		}
+		return a.LastBlockTime.After(b.LastBlockTime)
+	}, func(c *Candidate) bool {
+		return !c.RelayTxs
+	})
+
+	// Protect the peers which most recently relayed new blocks.
+	remaining = protect(remaining, numProtectedBlock, func(a, b *Candidate) bool {
+		return a.LastBlockTime.After(b.LastBlockTime)
+	}, nil)
+
+	// Protect half of the remaining peers which have been connected the
+	// longest.
+	remaining = protect(remaining, len(remaining)/2, older, nil)
+	if len(remaining) == 0 {
+		return 0, false
+	}
+
+	// Group the remaining candidates by network group and keep track of the
+	// most recently connected candidate of each group.
+	type group struct {
+		count    int
+		youngest *Candidate
+	}
+	groups := make(map[uint64]*group)
+	for _, c := range remaining {
+		g, ok := groups[c.NetGroup]
+		if !ok {
+			g = &group{youngest: c}
+			groups[c.NetGroup] = g
+		}
+		g.count++
+		if older(g.youngest, c) {
+			g.youngest = c
+		}
+	}
+
+	// Select the youngest candidate of the largest group, preferring the
+	// group with the most recent connection when several are as large.
+	var evict *group
+	for _, g := range groups {
+		if evict == nil || g.count > evict.count ||
+			(g.count == evict.count && older(evict.youngest, g.youngest)) {
+
+			evict = g
+		}
+	}
+	return evict.youngest.ID, true
+}
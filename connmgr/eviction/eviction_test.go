@@ -0,0 +1,153 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package eviction
+
+import (
+	"testing"
+	"time"
+)
+
+// testCandidates returns 40 candidates which relay transactions, with the ID
+// of each one as its index.  They are connected a minute apart in the order of
+// their IDs and the first 30 of them are in distinct network groups while the
+// last 10 share network group 1.  The passed function, if any, is called to
+// modify each candidate.
+func testCandidates(modify func(c *Candidate)) []Candidate {
+	base := time.Unix(1600000000, 0)
+	candidates := make([]Candidate, 40)
+	for i := range candidates {
+		c := &candidates[i]
+		c.ID = int32(i)
+		c.ConnTime = base.Add(time.Duration(i) * time.Minute)
+		c.NetGroup = uint64(100 + i)
+		if i >= 30 {
+			c.NetGroup = 1
+		}
+		c.RelayTxs = true
+		if modify != nil {
+			modify(c)
+		}
+	}
+	return candidates
+}
+
+// TestSelectPeerToEvict ensures the expected candidate is selected for eviction
+// regardless of the order the candidates are passed in.
+func TestSelectPeerToEvict(t *testing.T) {
+	recent := time.Unix(1700000000, 0)
+	tests := []struct {
+		name       string
+		candidates []Candidate
+		wantID     int32
+		wantOK     bool
+	}{
+		{
+			name:       "no candidates",
+			candidates: nil,
+			wantOK:     false,
+		},
+		{
+			name:       "all protected",
+			candidates: testCandidates(nil)[:20],
+			wantOK:     false,
+		},
+		{
+			// The candidates in network groups 129 down to 126 are
+			// protected by network group, the oldest 12 ones by
+			// ping, transaction and block relay since there is no
+			// relay at all, and half of the remaining ones by age,
+			// which leaves the shared network group.
+			name:       "youngest in largest network group",
+			candidates: testCandidates(nil),
+			wantID:     39,
+			wantOK:     true,
+		},
+		{
+			name: "lowest ping protected",
+			candidates: testCandidates(func(c *Candidate) {
+				if c.ID == 39 {
+					c.MinPing = time.Millisecond
+				}
+			}),
+			wantID: 38,
+			wantOK: true,
+		},
+		{
+			name: "recent transaction relay protected",
+			candidates: testCandidates(func(c *Candidate) {
+				if c.ID == 39 {
+					c.LastTxTime = recent
+				}
+			}),
+			wantID: 38,
+			wantOK: true,
+		},
+		{
+			name: "recent block relay protected",
+			candidates: testCandidates(func(c *Candidate) {
+				if c.ID == 39 {
+					c.LastBlockTime = recent
+				}
+			}),
+			wantID: 38,
+			wantOK: true,
+		},
+		{
+			// The block-relay-only candidates are protected without
+			// taking any of the slots for recent block relay, which
+			// go to the next oldest candidates.
+			name: "block-relay-only protected",
+			candidates: testCandidates(func(c *Candidate) {
+				if c.ID >= 38 {
+					c.RelayTxs = false
+				}
+			}),
+			wantID: 37,
+			wantOK: true,
+		},
+		{
+			// The peers connected in the last minutes are split
+			// between network groups 1 and 2, but network group 1
+			// has more of them.
+			name: "largest network group over youngest",
+			candidates: testCandidates(func(c *Candidate) {
+				if c.ID >= 36 {
+					c.NetGroup = 2
+				}
+			}),
+			wantID: 35,
+			wantOK: true,
+		},
+		{
+			// Network groups 1 and 2 have as many candidates left,
+			// so the one with the youngest candidate is picked.
+			name: "youngest network group on tie",
+			candidates: testCandidates(func(c *Candidate) {
+				if c.ID >= 30 && c.ID%2 == 1 {
+					c.NetGroup = 2
+				}
+			}),
+			wantID: 39,
+			wantOK: true,
+		},
+	}
+
+	for _, test := range tests {
+		// Select from a copy of the candidates in reverse order too,
+		// which must give the same result.
+		reversed := make([]Candidate, len(test.candidates))
+		for i, c := range test.candidates {
+			reversed[len(reversed)-1-i] = c
+		}
+		for _, candidates := range [][]Candidate{test.candidates, reversed} {
+			id, ok := SelectPeerToEvict(candidates)
+			if ok != test.wantOK || (ok && id != test.wantID) {
+				t.Errorf("%s: got %d (ok %v), want %d (ok %v)",
+					test.name, id, ok, test.wantID,
+					test.wantOK)
+			}
+		}
+	}
+}
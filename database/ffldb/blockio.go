@@ -13,8 +13,11 @@ import (
 	"fmt"
 	"hash/crc32"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/brsuite/brond/chaincfg/chainhash"
@@ -168,6 +171,11 @@ type blockStore struct {
 	// new blocks are written to.
 	writeCursor *writeCursor
 
+	// firstFileNum is the number of the oldest flat file which has not been
+	// deleted by pruning.  It is only modified when committing a write
+	// transaction, of which there can only be one at a time.
+	firstFileNum uint32
+
 	// These functions are set to openFile, openWriteFile, and deleteFile by
 	// default, but are exposed here to allow the whitebox tests to replace
 	// them when working with mock files.
@@ -307,6 +315,58 @@ func (s *blockStore) deleteFile(fileNum uint32) error {
 	return nil
 }
 
+// fileSize returns the size in bytes of the flat file for the passed file
+// number.  Files which do not exist are treated as empty.
+func (s *blockStore) fileSize(fileNum uint32) (uint64, error) {
+	st, err := os.Stat(blockFilePath(s.basePath, fileNum))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, makeDbErr(database.ErrDriverSpecific, err.Error(), err)
+	}
+
+	return uint64(st.Size()), nil
+}
+
+// deleteFilesBefore closes and removes all flat files starting with the oldest
+// remaining file up to, but not including, the passed file number.  It is used
+// to reclaim the space used by pruned blocks once the metadata no longer
+// references them.
+//
+// Any errors are simply logged at a warning level rather than being returned
+// since the metadata has already been updated at this point, so a file which
+// failed to be removed is merely wasted space.
+//
+// This function MUST only be called during a write transaction commit and the
+// passed file number must be before the current write cursor file.
+func (s *blockStore) deleteFilesBefore(fileNum uint32) {
+	for ; s.firstFileNum < fileNum; s.firstFileNum++ {
+		// Close the file if it is currently open for reads.  This is
+		// done under the write lock for the file so it's not closed out
+		// from under any readers currently reading from it.
+		s.obfMutex.Lock()
+		if obf, ok := s.openBlockFiles[s.firstFileNum]; ok {
+			s.lruMutex.Lock()
+			s.openBlocksLRU.Remove(s.fileNumToLRUElem[s.firstFileNum])
+			delete(s.fileNumToLRUElem, s.firstFileNum)
+			s.lruMutex.Unlock()
+
+			obf.Lock()
+			_ = obf.file.Close()
+			obf.Unlock()
+
+			delete(s.openBlockFiles, s.firstFileNum)
+		}
+		s.obfMutex.Unlock()
+
+		if err := s.deleteFileFunc(s.firstFileNum); err != nil {
+			log.Warnf("PRUNE: Failed to delete block file number "+
+				"%d: %v", s.firstFileNum, err)
+		}
+	}
+}
+
 // blockFile attempts to return an existing file handle for the passed flat file
 // number if it is already open as well as marking it as most recently used.  It
 // will also open the file when it's not already open subject to the rules
@@ -712,15 +772,56 @@ func (s *blockStore) handleRollback(oldBlockFileNum, oldBlockOffset uint32) {
 	}
 }
 
+// firstBlockFile searches the database directory for the oldest flat block
+// file.  Older files might have been deleted by pruning, so this is not
+// necessarily the first file ever written.  It returns -1 when there are no
+// block files.
+func firstBlockFile(dbPath string) int {
+	entries, err := ioutil.ReadDir(dbPath)
+	if err != nil {
+		return -1
+	}
+
+	firstFile := -1
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".fdb") {
+			continue
+		}
+		numStr := strings.TrimSuffix(name, ".fdb")
+		if len(numStr) != 9 {
+			continue
+		}
+		fileNum, err := strconv.ParseUint(numStr, 10, 32)
+		if err != nil {
+			continue
+		}
+		if firstFile == -1 || int(fileNum) < firstFile {
+			firstFile = int(fileNum)
+		}
+	}
+
+	return firstFile
+}
+
 // scanBlockFiles searches the database directory for all flat block files to
 // find the end of the most recent file.  This position is considered the
 // current write cursor which is also stored in the metadata.  Thus, it is used
 // to detect unexpected shutdowns in the middle of writes so the block files
 // can be reconciled.
-func scanBlockFiles(dbPath string) (int, uint32) {
+//
+// The scan starts from the oldest block file on disk since older files might
+// have been deleted by pruning.  The number of that file is also returned.
+func scanBlockFiles(dbPath string) (int, int, uint32) {
+	firstFile := firstBlockFile(dbPath)
+	if firstFile == -1 {
+		log.Tracef("Scan found no block files")
+		return -1, -1, 0
+	}
+
 	lastFile := -1
 	fileLen := uint32(0)
-	for i := 0; ; i++ {
+	for i := firstFile; ; i++ {
 		filePath := blockFilePath(dbPath, uint32(i))
 		st, err := os.Stat(filePath)
 		if err != nil {
@@ -731,9 +832,9 @@ func scanBlockFiles(dbPath string) (int, uint32) {
 		fileLen = uint32(st.Size())
 	}
 
-	log.Tracef("Scan found latest block file #%d with length %d", lastFile,
-		fileLen)
-	return lastFile, fileLen
+	log.Tracef("Scan found block files #%d through #%d with latest length "+
+		"%d", firstFile, lastFile, fileLen)
+	return firstFile, lastFile, fileLen
 }
 
 // newBlockStore returns a new block store with the current block file number
@@ -742,8 +843,9 @@ func newBlockStore(basePath string, network wire.BrocoinNet) *blockStore {
 	// Look for the end of the latest block to file to determine what the
 	// write cursor position is from the viewpoing of the block files on
 	// disk.
-	fileNum, fileOff := scanBlockFiles(basePath)
+	firstFileNum, fileNum, fileOff := scanBlockFiles(basePath)
 	if fileNum == -1 {
+		firstFileNum = 0
 		fileNum = 0
 		fileOff = 0
 	}
@@ -755,6 +857,7 @@ func newBlockStore(basePath string, network wire.BrocoinNet) *blockStore {
 		openBlockFiles:   make(map[uint32]*lockableFile),
 		openBlocksLRU:    list.New(),
 		fileNumToLRUElem: make(map[uint32]*list.Element),
+		firstFileNum:     uint32(firstFileNum),
 
 		writeCursor: &writeCursor{
 			curFile:    &lockableFile{},
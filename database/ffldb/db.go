@@ -970,6 +970,11 @@ type transaction struct {
 	pendingKeys   *treap.Mutable
 	pendingRemove *treap.Mutable
 
+	// pruneFileNum is the flat file number before which all block files
+	// need to be deleted on commit.  It is zero when no blocks have been
+	// pruned by the transaction.
+	pruneFileNum uint32
+
 	// Active iterators that need to be notified when the pending keys have
 	// been updated so the cursors can properly handle updates to the
 	// transaction state.
@@ -1583,6 +1588,104 @@ func (tx *transaction) FetchBlockRegions(regions []database.BlockRegion) ([][]by
 	return blockRegions, nil
 }
 
+// PruneBlocks deletes the oldest flat block files until the total size of the
+// remaining files is at or below the provided target size in bytes.  The file
+// which houses the block identified by the keep hash is never deleted, nor is
+// any file after it, including the current write file.  Since blocks are
+// deleted a whole file at a time, the remaining size can exceed the target.
+//
+// The entries for all blocks in the deleted files are removed from the block
+// index and their hashes are returned.  The files themselves are only deleted
+// once the transaction is committed.
+//
+// Returns the following errors as required by the interface contract:
+//   - ErrTxNotWritable if attempted against a read-only transaction
+//   - ErrTxClosed if the transaction has already been closed
+//
+// This function is part of the database.Tx interface implementation.
+func (tx *transaction) PruneBlocks(targetSize uint64, keep *chainhash.Hash) ([]chainhash.Hash, error) {
+	// Ensure transaction state is valid.
+	if err := tx.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	// Ensure the transaction is writable.
+	if !tx.writable {
+		str := "prune blocks requires a writable database transaction"
+		return nil, makeDbErr(database.ErrTxNotWritable, str, nil)
+	}
+
+	// Determine the oldest file which must be kept.  It is the file the
+	// block to keep was written to or the current write file when the
+	// block is unknown or still pending.
+	store := tx.db.store
+	wc := store.writeCursor
+	wc.RLock()
+	curFileNum := wc.curFileNum
+	wc.RUnlock()
+	keepFileNum := curFileNum
+	if keep != nil {
+		if blockRow := tx.blockIdxBucket.Get(keep[:]); blockRow != nil {
+			loc := deserializeBlockLoc(blockRow)
+			if loc.blockFileNum < keepFileNum {
+				keepFileNum = loc.blockFileNum
+			}
+		}
+	}
+
+	// Calculate the total size of the block files and how many of the
+	// oldest ones need to be removed to get under the target.  Files which
+	// are already scheduled to be removed by this transaction are skipped.
+	firstFileNum := store.firstFileNum
+	if tx.pruneFileNum > firstFileNum {
+		firstFileNum = tx.pruneFileNum
+	}
+	fileSizes := make([]uint64, 0, curFileNum-firstFileNum+1)
+	var totalSize uint64
+	for fileNum := firstFileNum; fileNum <= curFileNum; fileNum++ {
+		size, err := store.fileSize(fileNum)
+		if err != nil {
+			return nil, err
+		}
+		fileSizes = append(fileSizes, size)
+		totalSize += size
+	}
+	pruneFileNum := firstFileNum
+	for totalSize > targetSize && pruneFileNum < keepFileNum {
+		totalSize -= fileSizes[pruneFileNum-firstFileNum]
+		pruneFileNum++
+	}
+	if pruneFileNum == firstFileNum {
+		return nil, nil
+	}
+
+	// Gather all blocks stored in the files that will be removed and then
+	// delete their entries from the block index.  The deletes are done
+	// after iterating since they would otherwise invalidate the cursor.
+	var pruned []chainhash.Hash
+	cursor := tx.blockIdxBucket.Cursor()
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		loc := deserializeBlockLoc(cursor.Value())
+		if loc.blockFileNum >= pruneFileNum {
+			continue
+		}
+
+		var hash chainhash.Hash
+		copy(hash[:], cursor.Key())
+		pruned = append(pruned, hash)
+	}
+	for i := range pruned {
+		if err := tx.blockIdxBucket.Delete(pruned[i][:]); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Debugf("Pruning %d blocks in block files #%d through #%d",
+		len(pruned), firstFileNum, pruneFileNum-1)
+	tx.pruneFileNum = pruneFileNum
+	return pruned, nil
+}
+
 // close marks the transaction closed then releases any pending data, the
 // underlying snapshot, the transaction read lock, and the write lock when the
 // transaction is writable.
@@ -1597,6 +1700,9 @@ func (tx *transaction) close() {
 	tx.pendingKeys = nil
 	tx.pendingRemove = nil
 
+	// Clear the pending block file removals.
+	tx.pruneFileNum = 0
+
 	// Release the snapshot.
 	if tx.snapshot != nil {
 		tx.snapshot.Release()
@@ -1666,7 +1772,22 @@ func (tx *transaction) writePendingAndCommit() error {
 
 	// Atomically update the database cache.  The cache automatically
 	// handles flushing to the underlying persistent storage database.
-	return tx.db.cache.commitTx(tx)
+	if err := tx.db.cache.commitTx(tx); err != nil {
+		return err
+	}
+
+	// Remove the block files for any pruned blocks.  The cache is flushed
+	// first to ensure the removed block index entries are persisted, since
+	// otherwise an unexpected shutdown could leave the metadata referencing
+	// blocks that no longer exist on disk.
+	if tx.pruneFileNum > tx.db.store.firstFileNum {
+		if err := tx.db.cache.flush(); err != nil {
+			return err
+		}
+		tx.db.store.deleteFilesBefore(tx.pruneFileNum)
+	}
+
+	return nil
 }
 
 // Commit commits all changes that have been made to the root metadata bucket
@@ -1923,6 +1923,13 @@ func testClosedTxInterface(tc *testContext, tx database.Tx) bool {
 		return false
 	}
 
+	// Ensure PruneBlocks returns expected error.
+	testName = "PruneBlocks on closed tx"
+	_, err = tx.PruneBlocks(0, nil)
+	if !checkDbError(tc.t, testName, err, wantErrCode) {
+		return false
+	}
+
 	// ---------------
 	// Commit/Rollback
 	// ---------------
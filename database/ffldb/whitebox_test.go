@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/brsuite/brond/chaincfg"
+	"github.com/brsuite/brond/chaincfg/chainhash"
 	"github.com/brsuite/brond/database"
 	"github.com/brsuite/brond/wire"
 	"github.com/brsuite/bronutil"
@@ -704,3 +705,128 @@ func TestFailureScenarios(t *testing.T) {
 	// Test various corruption scenarios.
 	testCorruption(tc)
 }
+
+// TestPruneBlocks ensures pruning removes the oldest block files along with the
+// block index entries for the blocks they house while never touching the file
+// which houses the block to keep.
+func TestPruneBlocks(t *testing.T) {
+	t.Parallel()
+
+	// Create a new database to run tests against.
+	dbPath := filepath.Join(os.TempDir(), "ffldb-pruneblocks")
+	_ = os.RemoveAll(dbPath)
+	idb, err := database.Create(dbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Errorf("Failed to create test database (%s) %v", dbType, err)
+		return
+	}
+	defer os.RemoveAll(dbPath)
+	defer idb.Close()
+
+	// Change the maximum file size to a small value to force multiple flat
+	// files with the test data set.
+	store := idb.(*db).store
+	store.maxBlockFileSize = 1024 // 1KiB
+
+	blocks, err := loadBlocks(t, blockDataFile, blockDataNet)
+	if err != nil {
+		t.Errorf("loadBlocks: Unexpected error: %v", err)
+		return
+	}
+	err = idb.Update(func(tx database.Tx) error {
+		for _, block := range blocks {
+			if err := tx.StoreBlock(block); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("StoreBlock: Unexpected error: %v", err)
+		return
+	}
+
+	// Ensure attempting to prune with a read-only transaction fails with
+	// the expected error.
+	err = idb.View(func(tx database.Tx) error {
+		_, err := tx.PruneBlocks(0, nil)
+		return err
+	})
+	if !checkDbError(t, "PruneBlocks on read-only tx", err,
+		database.ErrTxNotWritable) {
+		return
+	}
+
+	// Prune everything possible while keeping the block at index 200 and
+	// everything after it.
+	keepIdx := 200
+	if len(blocks) <= keepIdx {
+		t.Fatalf("loadBlocks: got %d blocks, need more than %d",
+			len(blocks), keepIdx)
+	}
+	keepHash := blocks[keepIdx].Hash()
+	var pruned []chainhash.Hash
+	err = idb.Update(func(tx database.Tx) error {
+		var err error
+		pruned, err = tx.PruneBlocks(0, keepHash)
+		return err
+	})
+	if err != nil {
+		t.Errorf("PruneBlocks: Unexpected error: %v", err)
+		return
+	}
+	if len(pruned) == 0 || len(pruned) > keepIdx {
+		t.Errorf("PruneBlocks: unexpected number of pruned blocks - "+
+			"got %d, want between 1 and %d", len(pruned), keepIdx)
+		return
+	}
+
+	// Ensure the pruned blocks are the oldest ones and no longer exist while
+	// all of the remaining ones, including the block to keep, still do.
+	err = idb.View(func(tx database.Tx) error {
+		for i, block := range blocks {
+			hasBlock, err := tx.HasBlock(block.Hash())
+			if err != nil {
+				return err
+			}
+			if wantBlock := i >= len(pruned); hasBlock != wantBlock {
+				return fmt.Errorf("HasBlock #%d: got %v, want %v",
+					i, hasBlock, wantBlock)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+
+	// Ensure the files were removed from disk and the first file tracked by
+	// the store was advanced accordingly.
+	if _, err := os.Stat(blockFilePath(dbPath, 0)); !os.IsNotExist(err) {
+		t.Errorf("block file 0 still exists after pruning: %v", err)
+		return
+	}
+	firstFile, _, _ := scanBlockFiles(dbPath)
+	if uint32(firstFile) != store.firstFileNum {
+		t.Errorf("unexpected first block file - got %d, want %d",
+			store.firstFileNum, firstFile)
+		return
+	}
+
+	// Ensure pruning again does not remove anything else since the block
+	// to keep is already in the oldest remaining file.
+	err = idb.Update(func(tx database.Tx) error {
+		var err error
+		pruned, err = tx.PruneBlocks(0, keepHash)
+		return err
+	})
+	if err != nil {
+		t.Errorf("PruneBlocks: Unexpected error: %v", err)
+		return
+	}
+	if len(pruned) != 0 {
+		t.Errorf("PruneBlocks: unexpected pruned blocks on second "+
+			"prune - got %d, want 0", len(pruned))
+	}
+}
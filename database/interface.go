@@ -389,6 +389,25 @@ type Tx interface {
 	// implementations.
 	FetchBlockRegions(regions []BlockRegion) ([][]byte, error)
 
+	// PruneBlocks deletes the oldest stored blocks until the total size of
+	// the remaining block data is at or below the provided target size in
+	// bytes.  The block identified by the keep hash, along with every block
+	// that was stored after it, is never deleted regardless of the target
+	// size.  The hashes of all blocks that were deleted are returned.
+	//
+	// Depending on the backend implementation, blocks might only be
+	// removable in groups, so the total size after pruning can be larger
+	// than the target size.
+	//
+	// The interface contract guarantees at least the following errors will
+	// be returned (other implementation-specific errors are possible):
+	//   - ErrTxNotWritable if attempted against a read-only transaction
+	//   - ErrTxClosed if the transaction has already been closed
+	//
+	// NOTE: The blocks are not actually removed from the underlying storage
+	// until the transaction is committed.
+	PruneBlocks(targetSize uint64, keep *chainhash.Hash) ([]chainhash.Hash, error)
+
 	// ******************************************************************
 	// Methods related to both atomic metadata storage and block storage.
 	// ******************************************************************
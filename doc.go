@@ -41,10 +41,23 @@ Application Options:
                             banning misbehaving peers.
       --whitelist=          Add an IP network or IP that will not be banned.
                             (eg. 192.168.1.0/24 or ::1)
+      --v2transport         Use the encrypted v2 transport protocol (BIP0324)
+                            with peers which support it and advertise support
+                            for it
   -u, --rpcuser=            Username for RPC connections
   -P, --rpcpass=            Password for RPC connections
       --rpclimituser=       Username for limited RPC connections
       --rpclimitpass=       Password for limited RPC connections
+      --rpcauth=            Add a user for RPC connections as
+                            <user>:<salt>$<hash> where hash is the hex-encoded
+                            HMAC-SHA256 of the password keyed by salt
+      --rpccookiefile=      File to write the credentials for cookie
+                            authentication to when no rpcpass is specified
+                            (default: .cookie in the data directory)
+      --rpcwhitelist=       Only allow a user to call some RPC methods as
+                            <user>:<method>,<method>,...
+      --rpcwhitelistntfn=   Only send some websocket notification types to a
+                            user as <user>:<notification>,<notification>,...
       --rpclisten=          Add an interface/port to listen for RPC connections
                             (default port: 8334, testnet: 18334)
       --rpccert=            File containing the certificate file
@@ -55,11 +68,24 @@ Application Options:
       --rpcquirks           Mirror some JSON-RPC quirks of Brocoin Core -- NOTE:
                             Discouraged unless interoperability issues need to
                             be worked around
-      --norpc               Disable built-in RPC server -- NOTE: The RPC server
-                            is disabled by default if no rpcuser/rpcpass or
-                            rpclimituser/rpclimitpass is specified
+      --norpc               Disable built-in RPC server
+      --rest                Serve unauthenticated read-only REST requests for
+                            blocks, transactions and unspent outputs under
+                            /rest/ on the RPC listeners
       --notls               Disable TLS for the RPC server -- NOTE: This is only
                             allowed if the RPC server is bound to localhost
+      --zmqpubhashblock=    Publish the hashes of connected blocks on a ZeroMQ
+                            endpoint (eg. tcp://127.0.0.1:28332)
+      --zmqpubhashtx=       Publish the hashes of transactions on a ZeroMQ
+                            endpoint
+      --zmqpubrawblock=     Publish serialized connected blocks on a ZeroMQ
+                            endpoint
+      --zmqpubrawtx=        Publish serialized transactions on a ZeroMQ
+                            endpoint
+      --zmqpubsequence=     Publish block and mempool sequence events on a
+                            ZeroMQ endpoint
+      --zmqpubhwm=          Max number of ZeroMQ messages queued for each
+                            subscriber before new messages are dropped (1000)
       --nodnsseed           Disable DNS seeding for peers
       --externalip=         Add an ip to the list of local addresses we claim to
                             listen on to peers
@@ -100,6 +126,9 @@ Application Options:
                             high priority for relaying
       --maxorphantx=        Max number of orphan transactions to keep in memory
                             (100)
+      --maxmempool=         Keep the transaction memory pool below the specified
+                            size in MiB by evicting the transactions with the
+                            lowest fee rate -- 0 disables the limit (300)
       --generate            Generate (mine) brocoins using the CPU
       --miningaddr=         Add the specified payment address to the list of
                             addresses to use for generated blocks -- At least
@@ -115,6 +144,11 @@ Application Options:
       --nocfilters          Disable committed filtering (CF) support.
       --sigcachemaxsize=    The maximum number of entries in the signature
                             verification cache.
+      --utxocachemaxsize=   The maximum size in MiB of the UTXO cache (250)
+      --prune=              Delete the oldest blocks from the database once the
+                            stored blocks exceed the target size in MiB --
+                            Minimum 1536, 0 disables pruning -- NOTE:
+                            Incompatible with --txindex and --addrindex
       --blocksonly          Do not accept transactions from remote peers.
       --relaynonstd         Relay non-standard transactions regardless of the
                             default settings for the active network.
@@ -21,6 +21,7 @@ import (
 	"github.com/brsuite/brond/netsync"
 	"github.com/brsuite/brond/peer"
 	"github.com/brsuite/brond/txscript"
+	"github.com/brsuite/brond/zmqpub"
 
 	"github.com/brsuite/bronlog"
 	"github.com/jrick/logrotate/rotator"
@@ -69,6 +70,7 @@ var (
 	srvrLog = backendLog.Logger("SRVR")
 	syncLog = backendLog.Logger("SYNC")
 	txmpLog = backendLog.Logger("TXMP")
+	zmqpLog = backendLog.Logger("ZMQP")
 )
 
 // Initialize package-global logger variables.
@@ -84,6 +86,7 @@ func init() {
 	txscript.UseLogger(scrpLog)
 	netsync.UseLogger(syncLog)
 	mempool.UseLogger(txmpLog)
+	zmqpub.UseLogger(zmqpLog)
 }
 
 // subsystemLoggers maps each subsystem identifier to its associated logger.
@@ -103,6 +106,7 @@ var subsystemLoggers = map[string]bronlog.Logger{
 	"SRVR": srvrLog,
 	"SYNC": syncLog,
 	"TXMP": txmpLog,
+	"ZMQP": zmqpLog,
 }
 
 // initLogRotator initializes the logging rotater to write logs to logFile and
@@ -56,6 +56,8 @@ be an exhaustive list.
    - Max signature operations per transaction
    - Max orphan transaction size
    - Max number of orphan transactions allowed
+   - Max total size of the pool with eviction of the lowest fee rate
+     transactions and a rolling minimum fee
  - Additional metadata tracking for each transaction
    - Timestamp when the transaction was added to the pool
    - Most recent block height when the transaction was added to the pool
@@ -9,11 +9,8 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"io"
 	"math"
-	"math/rand"
 	"sort"
-	"strings"
 	"sync"
 
 	"github.com/brsuite/brond/chaincfg/chainhash"
@@ -21,29 +18,66 @@ import (
 	"github.com/brsuite/bronutil"
 )
 
-// TODO incorporate Alex Morcos' modifications to Gavin's initial model
-// https://lists.linuxfoundation.org/pipermail/brocoin-dev/2014-October/006824.html
+// The fee estimator sorts the transactions it observes entering the mempool
+// into buckets of exponentially increasing fee rates and keeps track of how
+// many blocks it took for them to be mined.  The counts are kept as moving
+// averages which decay with every block, so recent blocks have the most
+// influence on the estimates, for three horizons of increasing length and
+// resolution.  An estimate for a target is the median fee rate of the lowest
+// range of buckets in which a sufficient share of the transactions was mined
+// within the target, taking into account the transactions which left the
+// mempool without being mined and those still waiting in it.
+//
+// This follows the design of the estimator used by Brocoin Core.
 
 const (
-	// estimateFeeDepth is the maximum number of blocks before a transaction
-	// is confirmed that we want to track.
-	estimateFeeDepth = 25
-
-	// estimateFeeBinSize is the number of txs stored in each bin.
-	estimateFeeBinSize = 100
-
-	// estimateFeeMaxReplacements is the max number of replacements that
-	// can be made by the txs found in a given block.
-	estimateFeeMaxReplacements = 10
-
-	// DefaultEstimateFeeMaxRollback is the default number of rollbacks
-	// allowed by the fee estimator for orphaned blocks.
-	DefaultEstimateFeeMaxRollback = 2
-
-	// DefaultEstimateFeeMinRegisteredBlocks is the default minimum
-	// number of blocks which must be observed by the fee estimator before
-	// it will provide fee estimations.
-	DefaultEstimateFeeMinRegisteredBlocks = 3
+	// minBucketFeeRate and maxBucketFeeRate are the lowest and highest
+	// upper bounds in bronees per kilobyte of the fee rate buckets.  An
+	// additional bucket catches all transactions paying more than the
+	// highest bound.
+	minBucketFeeRate = 1000
+	maxBucketFeeRate = 1e7
+
+	// feeSpacing is the ratio between the upper bounds of adjacent fee
+	// rate buckets.
+	feeSpacing = 1.05
+
+	// The short horizon tracks confirmations within 12 blocks with a half
+	// life of 18 blocks, the medium horizon within 48 blocks in periods of
+	// 2 blocks with a half life of 144 blocks and the long horizon within
+	// 1008 blocks in periods of 24 blocks with a half life of 1008 blocks.
+	shortBlockPeriods = 12
+	shortScale        = 1
+	shortDecay        = .962
+	medBlockPeriods   = 24
+	medScale          = 2
+	medDecay          = .9952
+	longBlockPeriods  = 42
+	longScale         = 24
+	longDecay         = .99931
+
+	// halfSuccessPct, successPct and doubleSuccessPct are the shares of
+	// transactions which must have been mined within half, all and double
+	// the target respectively for a fee rate to be considered sufficient.
+	halfSuccessPct   = .6
+	successPct       = .85
+	doubleSuccessPct = .95
+
+	// sufficientFeeTxs and sufficientTxsShort are the average number of
+	// transactions per block a range of buckets must have seen for its
+	// success rate to be meaningful in the medium and long, and the short
+	// horizon respectively.
+	sufficientFeeTxs   = .1
+	sufficientTxsShort = .5
+
+	// oldestEstimateHistory is the number of blocks after which the
+	// statistics of an estimator which has not seen any blocks in the
+	// meantime are discarded.
+	oldestEstimateHistory = 6 * 1008
+
+	// MaxEstimateFeeTarget is the highest confirmation target in blocks
+	// fees can be estimated for.
+	MaxEstimateFeeTarget = longBlockPeriods * longScale
 
 	bytePerKb = 1000
 
@@ -54,12 +88,16 @@ var (
 	// EstimateFeeDatabaseKey is the key that we use to
 	// store the fee estimator in the database.
 	EstimateFeeDatabaseKey = []byte("estimatefee")
+
+	// errInsufficientFeeData is returned when there is not enough data to
+	// provide a fee estimate.
+	errInsufficientFeeData = errors.New("insufficient data or no feerate found")
 )
 
 // BroneesPerByte is number with units of bronees per byte.
 type BroneesPerByte float64
 
-//BronPerKilobyte is number with units of brocoins per kilobyte.
+// BronPerKilobyte is number with units of brocoins per kilobyte.
 type BronPerKilobyte float64
 
 // ToBronPerKb returns a float value that represents the given
@@ -90,237 +128,408 @@ func NewBroneesPerByte(fee bronutil.Amount, size uint32) BroneesPerByte {
 	return BroneesPerByte(float64(fee) / float64(size))
 }
 
-// observedTransaction represents an observed transaction and some
-// additional data required for the fee estimation algorithm.
-type observedTransaction struct {
-	// A transaction hash.
-	hash chainhash.Hash
+// feeBuckets returns the upper bounds of the fee rate buckets in bronees per
+// kilobyte.
+func feeBuckets() []float64 {
+	var buckets []float64
+	for bound := float64(minBucketFeeRate); bound <= maxBucketFeeRate; bound *= feeSpacing {
+		buckets = append(buckets, bound)
+	}
+	return append(buckets, math.Inf(1))
+}
 
-	// The fee per byte of the transaction in bronees.
-	feeRate BroneesPerByte
+// txConfirmStats tracks the moving averages of the number of transactions
+// which were mined within a number of blocks per fee rate bucket for a single
+// horizon, along with the transactions which are still unconfirmed.
+type txConfirmStats struct {
+	buckets []float64
+	decay   float64
+	scale   int32
+
+	// confAvg holds the moving average of the number of transactions in
+	// each bucket which were mined within (period+1)*scale blocks, indexed
+	// by period and bucket.  failAvg is the moving average of those which
+	// left the mempool without being mined after waiting at least as many
+	// blocks.
+	confAvg [][]float64
+	failAvg [][]float64
+
+	// txCtAvg and feeRateAvg are the moving averages of the number of
+	// mined transactions and the sum of their fee rates in each bucket.
+	txCtAvg    []float64
+	feeRateAvg []float64
+
+	// unconfTxs holds the number of transactions in each bucket which are
+	// still in the mempool, indexed by the height they entered it at
+	// modulo the maximum number of tracked confirmations.  Transactions
+	// which have been unconfirmed for longer are counted in oldUnconfTxs.
+	unconfTxs    [][]int
+	oldUnconfTxs []int
+}
 
-	// The block height when it was observed.
-	observed int32
+// newTxConfirmStats returns the statistics for a horizon which tracks
+// confirmations within periods*scale blocks and whose counts decay by the
+// passed factor every block.
+func newTxConfirmStats(buckets []float64, periods int, scale int32, decay float64) *txConfirmStats {
+	s := &txConfirmStats{
+		buckets:      buckets,
+		decay:        decay,
+		scale:        scale,
+		confAvg:      make([][]float64, periods),
+		failAvg:      make([][]float64, periods),
+		txCtAvg:      make([]float64, len(buckets)),
+		feeRateAvg:   make([]float64, len(buckets)),
+		unconfTxs:    make([][]int, periods*int(scale)),
+		oldUnconfTxs: make([]int, len(buckets)),
+	}
+	for i := range s.confAvg {
+		s.confAvg[i] = make([]float64, len(buckets))
+		s.failAvg[i] = make([]float64, len(buckets))
+	}
+	for i := range s.unconfTxs {
+		s.unconfTxs[i] = make([]int, len(buckets))
+	}
+	return s
+}
 
-	// The height of the block in which it was mined.
-	// If the transaction has not yet been mined, it is zero.
-	mined int32
+// maxConfirms returns the highest number of blocks to confirm tracked by the
+// horizon.
+func (s *txConfirmStats) maxConfirms() int32 {
+	return s.scale * int32(len(s.confAvg))
 }
 
-func (o *observedTransaction) Serialize(w io.Writer) {
-	binary.Write(w, binary.BigEndian, o.hash)
-	binary.Write(w, binary.BigEndian, o.feeRate)
-	binary.Write(w, binary.BigEndian, o.observed)
-	binary.Write(w, binary.BigEndian, o.mined)
+// clearCurrent moves the transactions which entered the mempool
+// maxConfirms blocks before the passed height to the old unconfirmed
+// transactions, so their slot can be reused for the passed height.
+func (s *txConfirmStats) clearCurrent(height int32) {
+	slot := s.unconfTxs[height%int32(len(s.unconfTxs))]
+	for i := range slot {
+		s.oldUnconfTxs[i] += slot[i]
+		slot[i] = 0
+	}
 }
 
-func deserializeObservedTransaction(r io.Reader) (*observedTransaction, error) {
-	ot := observedTransaction{}
+// updateMovingAverages decays all moving averages by one block.
+func (s *txConfirmStats) updateMovingAverages() {
+	for i := range s.confAvg {
+		for j := range s.confAvg[i] {
+			s.confAvg[i][j] *= s.decay
+			s.failAvg[i][j] *= s.decay
+		}
+	}
+	for j := range s.txCtAvg {
+		s.txCtAvg[j] *= s.decay
+		s.feeRateAvg[j] *= s.decay
+	}
+}
 
-	// The first 32 bytes should be a hash.
-	binary.Read(r, binary.BigEndian, &ot.hash)
+// newTx records a transaction in the passed bucket which entered the mempool
+// at the passed height.
+func (s *txConfirmStats) newTx(height int32, bucket int) {
+	s.unconfTxs[height%int32(len(s.unconfTxs))][bucket]++
+}
 
-	// The next 8 are BroneesPerByte
-	binary.Read(r, binary.BigEndian, &ot.feeRate)
+// removeTx removes a transaction in the passed bucket which entered the
+// mempool at entryHeight from the unconfirmed transactions.  When it was not
+// mined, it is recorded as a failure for all the periods it has waited for.
+func (s *txConfirmStats) removeTx(entryHeight, bestHeight int32, bucket int, mined bool) {
+	blocksAgo := bestHeight - entryHeight
+	if blocksAgo < 0 {
+		return
+	}
 
-	// And next there are two uint32's.
-	binary.Read(r, binary.BigEndian, &ot.observed)
-	binary.Read(r, binary.BigEndian, &ot.mined)
+	if blocksAgo >= int32(len(s.unconfTxs)) {
+		if s.oldUnconfTxs[bucket] > 0 {
+			s.oldUnconfTxs[bucket]--
+		}
+	} else {
+		slot := s.unconfTxs[entryHeight%int32(len(s.unconfTxs))]
+		if slot[bucket] > 0 {
+			slot[bucket]--
+		}
+	}
 
-	return &ot, nil
+	if !mined && blocksAgo >= s.scale {
+		periodsAgo := int(blocksAgo / s.scale)
+		for i := 0; i < periodsAgo && i < len(s.failAvg); i++ {
+			s.failAvg[i][bucket]++
+		}
+	}
 }
 
-// registeredBlock has the hash of a block and the list of transactions
-// it mined which had been previously observed by the FeeEstimator. It
-// is used if Rollback is called to reverse the effect of registering
-// a block.
-type registeredBlock struct {
-	hash         chainhash.Hash
-	transactions []*observedTransaction
+// record records a transaction with the passed fee rate in bronees per
+// kilobyte which was mined blocksToConfirm blocks after it entered the
+// mempool.
+func (s *txConfirmStats) record(blocksToConfirm int32, bucket int, feeRate float64) {
+	if blocksToConfirm < 1 {
+		return
+	}
+
+	periodsToConfirm := int((blocksToConfirm + s.scale - 1) / s.scale)
+	for i := periodsToConfirm; i <= len(s.confAvg); i++ {
+		s.confAvg[i-1][bucket]++
+	}
+	s.txCtAvg[bucket]++
+	s.feeRateAvg[bucket] += feeRate
 }
 
-func (rb *registeredBlock) serialize(w io.Writer, txs map[*observedTransaction]uint32) {
-	binary.Write(w, binary.BigEndian, rb.hash)
+// estimateMedianVal returns the median fee rate in bronees per kilobyte of the
+// lowest range of buckets in which at least successBreakPoint of the
+// transactions were mined within confTarget blocks.  Buckets are combined into
+// ranges, starting from the highest fee rates, until they have seen at least
+// sufficientTxVal transactions per block on average.  The transactions which
+// are still unconfirmed after confTarget blocks and those which left the
+// mempool unconfirmed count against the success rate.  -1 is returned when no
+// range of buckets satisfies the requirements.
+func (s *txConfirmStats) estimateMedianVal(confTarget int32, sufficientTxVal,
+	successBreakPoint float64, bestHeight int32) float64 {
+
+	var nConf, totalNum, failNum, extraNum float64
+	periodTarget := int((confTarget + s.scale - 1) / s.scale)
+	maxBucket := len(s.buckets) - 1
+	curNearBucket, bestNearBucket := maxBucket, maxBucket
+	curFarBucket, bestFarBucket := maxBucket, maxBucket
+	bins := int32(len(s.unconfTxs))
+	newBucketRange := true
+	foundAnswer := false
+
+	// Start counting from the highest fee rate bucket and combine buckets
+	// until there are enough transactions to judge their success rate.
+	for bucket := maxBucket; bucket >= 0; bucket-- {
+		if newBucketRange {
+			curNearBucket = bucket
+			newBucketRange = false
+		}
+		curFarBucket = bucket
+		nConf += s.confAvg[periodTarget-1][bucket]
+		totalNum += s.txCtAvg[bucket]
+		failNum += s.failAvg[periodTarget-1][bucket]
+		for confct := confTarget; confct < s.maxConfirms(); confct++ {
+			if bestHeight < confct {
+				break
+			}
+			extraNum += float64(s.unconfTxs[(bestHeight-confct)%bins][bucket])
+		}
+		extraNum += float64(s.oldUnconfTxs[bucket])
+
+		// Only judge the range once there are enough transactions in
+		// it.  When it passes, remember it and start a new range for
+		// the lower fee rates.  Once a range fails, the remaining lower
+		// fee rates are added to it.
+		if totalNum < sufficientTxVal/(1-s.decay) {
+			continue
+		}
+		curPct := nConf / (totalNum + failNum + extraNum)
+		if curPct < successBreakPoint {
+			continue
+		}
+		foundAnswer = true
+		nConf, totalNum, failNum, extraNum = 0, 0, 0, 0
+		bestNearBucket = curNearBucket
+		bestFarBucket = curFarBucket
+		newBucketRange = true
+	}
+	if !foundAnswer {
+		return -1
+	}
 
-	binary.Write(w, binary.BigEndian, uint32(len(rb.transactions)))
-	for _, o := range rb.transactions {
-		binary.Write(w, binary.BigEndian, txs[o])
+	// Find the bucket with the median transaction of the passing range
+	// and return its average fee rate.
+	var txSum float64
+	for j := bestFarBucket; j <= bestNearBucket; j++ {
+		txSum += s.txCtAvg[j]
+	}
+	if txSum == 0 {
+		return -1
 	}
+	txSum /= 2
+	for j := bestFarBucket; j <= bestNearBucket; j++ {
+		if s.txCtAvg[j] < txSum {
+			txSum -= s.txCtAvg[j]
+			continue
+		}
+		return s.feeRateAvg[j] / s.txCtAvg[j]
+	}
+	return -1
+}
+
+// trackedTx describes a mempool transaction tracked by the fee estimator.
+type trackedTx struct {
+	height  int32
+	bucket  int
+	feeRate float64
 }
 
 // FeeEstimator manages the data necessary to create
 // fee estimations. It is safe for concurrent access.
 type FeeEstimator struct {
-	maxRollback uint32
-	binSize     int32
+	mtx     sync.Mutex
+	buckets []float64
+	short   *txConfirmStats
+	medium  *txConfirmStats
+	long    *txConfirmStats
+
+	// tracked holds the transactions in the mempool which are tracked.
+	tracked map[chainhash.Hash]trackedTx
+
+	// firstHeight and lastKnownHeight are the heights of the first and
+	// the last block registered with the estimator.
+	firstHeight     int32
+	lastKnownHeight int32
+}
 
-	// The maximum number of replacements that can be made in a single
-	// bin per block. Default is estimateFeeMaxReplacements
-	maxReplacements int32
+// NewFeeEstimator returns a new fee estimator without any statistics.
+func NewFeeEstimator() *FeeEstimator {
+	ef := &FeeEstimator{
+		buckets: feeBuckets(),
+		tracked: make(map[chainhash.Hash]trackedTx),
+	}
+	ef.reset()
+	return ef
+}
 
-	// The minimum number of blocks that can be registered with the fee
-	// estimator before it will provide answers.
-	minRegisteredBlocks uint32
+// reset discards all statistics of the fee estimator.
+func (ef *FeeEstimator) reset() {
+	ef.short = newTxConfirmStats(ef.buckets, shortBlockPeriods, shortScale,
+		shortDecay)
+	ef.medium = newTxConfirmStats(ef.buckets, medBlockPeriods, medScale,
+		medDecay)
+	ef.long = newTxConfirmStats(ef.buckets, longBlockPeriods, longScale,
+		longDecay)
+	ef.tracked = make(map[chainhash.Hash]trackedTx)
+	ef.firstHeight = mining.UnminedHeight
+	ef.lastKnownHeight = mining.UnminedHeight
+}
 
-	// The last known height.
-	lastKnownHeight int32
+// allStats returns the statistics of all horizons.
+func (ef *FeeEstimator) allStats() [3]*txConfirmStats {
+	return [3]*txConfirmStats{ef.short, ef.medium, ef.long}
+}
 
-	// The number of blocks that have been registered.
-	numBlocksRegistered uint32
+// ObserveTransaction is called when a new transaction is observed in the
+// mempool.  Only transactions which entered the mempool at the height of the
+// last registered block are tracked, since the number of blocks it takes to
+// mine the others is unknown.
+func (ef *FeeEstimator) ObserveTransaction(t *TxDesc) {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
 
-	mtx      sync.RWMutex
-	observed map[chainhash.Hash]*observedTransaction
-	bin      [estimateFeeDepth][]*observedTransaction
+	if ef.lastKnownHeight == mining.UnminedHeight ||
+		t.Height != ef.lastKnownHeight {
 
-	// The cached estimates.
-	cached []BroneesPerByte
+		return
+	}
 
-	// Transactions that have been removed from the bins. This allows us to
-	// revert in case of an orphaned block.
-	dropped []*registeredBlock
-}
+	hash := *t.Tx.Hash()
+	if _, ok := ef.tracked[hash]; ok {
+		return
+	}
 
-// NewFeeEstimator creates a FeeEstimator for which at most maxRollback blocks
-// can be unregistered and which returns an error unless minRegisteredBlocks
-// have been registered with it.
-func NewFeeEstimator(maxRollback, minRegisteredBlocks uint32) *FeeEstimator {
-	return &FeeEstimator{
-		maxRollback:         maxRollback,
-		minRegisteredBlocks: minRegisteredBlocks,
-		lastKnownHeight:     mining.UnminedHeight,
-		binSize:             estimateFeeBinSize,
-		maxReplacements:     estimateFeeMaxReplacements,
-		observed:            make(map[chainhash.Hash]*observedTransaction),
-		dropped:             make([]*registeredBlock, 0, maxRollback),
+	size := GetTxVirtualSize(t.Tx)
+	if size <= 0 {
+		return
+	}
+	feeRate := float64(t.Fee) * bytePerKb / float64(size)
+	bucket := sort.SearchFloat64s(ef.buckets, feeRate)
+	for _, stats := range ef.allStats() {
+		stats.newTx(t.Height, bucket)
+	}
+	ef.tracked[hash] = trackedTx{
+		height:  t.Height,
+		bucket:  bucket,
+		feeRate: feeRate,
 	}
 }
 
-// ObserveTransaction is called when a new transaction is observed in the mempool.
-func (ef *FeeEstimator) ObserveTransaction(t *TxDesc) {
+// RemoveTransaction is called when a transaction leaves the mempool for any
+// other reason than being mined.  The transaction counts as having failed to
+// be mined within the number of blocks it has waited.
+func (ef *FeeEstimator) RemoveTransaction(hash *chainhash.Hash) {
 	ef.mtx.Lock()
 	defer ef.mtx.Unlock()
 
-	// If we haven't seen a block yet we don't know when this one arrived,
-	// so we ignore it.
-	if ef.lastKnownHeight == mining.UnminedHeight {
+	tx, ok := ef.tracked[*hash]
+	if !ok {
 		return
 	}
-
-	hash := *t.Tx.Hash()
-	if _, ok := ef.observed[hash]; !ok {
-		size := uint32(GetTxVirtualSize(t.Tx))
-
-		ef.observed[hash] = &observedTransaction{
-			hash:     hash,
-			feeRate:  NewBroneesPerByte(bronutil.Amount(t.Fee), size),
-			observed: t.Height,
-			mined:    mining.UnminedHeight,
-		}
+	for _, stats := range ef.allStats() {
+		stats.removeTx(tx.height, ef.lastKnownHeight, tx.bucket, false)
 	}
+	delete(ef.tracked, *hash)
 }
 
 // RegisterBlock informs the fee estimator of a new block to take into account.
+//
+// Blocks at or below the height of the last registered block, such as those
+// connected while reorganizing the chain, do not update the statistics; their
+// transactions are only no longer tracked.
 func (ef *FeeEstimator) RegisterBlock(block *bronutil.Block) error {
 	ef.mtx.Lock()
 	defer ef.mtx.Unlock()
 
-	// The previous sorted list is invalid, so delete it.
-	ef.cached = nil
-
 	height := block.Height()
-	if height != ef.lastKnownHeight+1 && ef.lastKnownHeight != mining.UnminedHeight {
-		return fmt.Errorf("intermediate block not recorded; current height is %d; new height is %d",
-			ef.lastKnownHeight, height)
+	if height == bronutil.BlockHeightUnknown {
+		return errors.New("cannot register block with unknown height")
 	}
 
-	// Update the last known height.
-	ef.lastKnownHeight = height
-	ef.numBlocksRegistered++
+	if ef.lastKnownHeight != mining.UnminedHeight &&
+		height <= ef.lastKnownHeight {
 
-	// Randomly order txs in block.
-	transactions := make(map[*bronutil.Tx]struct{})
-	for _, t := range block.Transactions() {
-		transactions[t] = struct{}{}
+		for _, tx := range block.Transactions() {
+			ttx, ok := ef.tracked[*tx.Hash()]
+			if !ok {
+				continue
+			}
+			for _, stats := range ef.allStats() {
+				stats.removeTx(ttx.height, ef.lastKnownHeight,
+					ttx.bucket, true)
+			}
+			delete(ef.tracked, *tx.Hash())
+		}
+		return nil
 	}
 
-	// Count the number of replacements we make per bin so that we don't
-	// replace too many.
-	var replacementCounts [estimateFeeDepth]int
+	// Discard statistics which have not been updated for too long.
+	if ef.lastKnownHeight != mining.UnminedHeight &&
+		height-ef.lastKnownHeight > oldestEstimateHistory {
 
-	// Keep track of which txs were dropped in case of an orphan block.
-	dropped := &registeredBlock{
-		hash:         *block.Hash(),
-		transactions: make([]*observedTransaction, 0, 100),
+		log.Infof("Discarding fee estimation data last updated at "+
+			"height %d", ef.lastKnownHeight)
+		ef.reset()
+	}
+	if ef.firstHeight == mining.UnminedHeight {
+		ef.firstHeight = height
 	}
 
-	// Go through the txs in the block.
-	for t := range transactions {
-		hash := *t.Hash()
-
-		// Have we observed this tx in the mempool?
-		o, ok := ef.observed[hash]
-		if !ok {
-			continue
+	// Move the transactions which have been unconfirmed for longer than
+	// tracked to the old unconfirmed transactions and decay the averages.
+	for _, stats := range ef.allStats() {
+		from := height
+		if ef.lastKnownHeight != mining.UnminedHeight {
+			from = ef.lastKnownHeight + 1
 		}
-
-		// Put the observed tx in the oppropriate bin.
-		blocksToConfirm := height - o.observed - 1
-
-		// This shouldn't happen if the fee estimator works correctly,
-		// but return an error if it does.
-		if o.mined != mining.UnminedHeight {
-			log.Error("Estimate fee: transaction ", hash.String(), " has already been mined")
-			return errors.New("Transaction has already been mined")
+		if height-from >= stats.maxConfirms() {
+			from = height - stats.maxConfirms() + 1
 		}
-
-		// This shouldn't happen but check just in case to avoid
-		// an out-of-bounds array index later.
-		if blocksToConfirm >= estimateFeeDepth {
-			continue
+		for h := from; h <= height; h++ {
+			stats.clearCurrent(h)
 		}
+		stats.updateMovingAverages()
+	}
+	ef.lastKnownHeight = height
 
-		// Make sure we do not replace too many transactions per min.
-		if replacementCounts[blocksToConfirm] == int(ef.maxReplacements) {
+	// Record the time it took to mine the tracked transactions.
+	for _, tx := range block.Transactions() {
+		ttx, ok := ef.tracked[*tx.Hash()]
+		if !ok {
 			continue
 		}
-
-		o.mined = height
-
-		replacementCounts[blocksToConfirm]++
-
-		bin := ef.bin[blocksToConfirm]
-
-		// Remove a random element and replace it with this new tx.
-		if len(bin) == int(ef.binSize) {
-			// Don't drop transactions we have just added from this same block.
-			l := int(ef.binSize) - replacementCounts[blocksToConfirm]
-			drop := rand.Intn(l)
-			dropped.transactions = append(dropped.transactions, bin[drop])
-
-			bin[drop] = bin[l-1]
-			bin[l-1] = o
-		} else {
-			bin = append(bin, o)
+		blocksToConfirm := height - ttx.height
+		for _, stats := range ef.allStats() {
+			stats.removeTx(ttx.height, height, ttx.bucket, true)
+			stats.record(blocksToConfirm, ttx.bucket, ttx.feeRate)
 		}
-		ef.bin[blocksToConfirm] = bin
-	}
-
-	// Go through the mempool for txs that have been in too long.
-	for hash, o := range ef.observed {
-		if o.mined == mining.UnminedHeight && height-o.observed >= estimateFeeDepth {
-			delete(ef.observed, hash)
-		}
-	}
-
-	// Add dropped list to history.
-	if ef.maxRollback == 0 {
-		return nil
-	}
-
-	if uint32(len(ef.dropped)) == ef.maxRollback {
-		ef.dropped = append(ef.dropped[1:], dropped)
-	} else {
-		ef.dropped = append(ef.dropped, dropped)
+		delete(ef.tracked, *tx.Hash())
 	}
 
 	return nil
@@ -334,287 +543,217 @@ func (ef *FeeEstimator) LastKnownHeight() int32 {
 	return ef.lastKnownHeight
 }
 
-// Rollback unregisters a recently registered block from the FeeEstimator.
-// This can be used to reverse the effect of an orphaned block on the fee
-// estimator. The maximum number of rollbacks allowed is given by
-// maxRollbacks.
-//
-// Note: not everything can be rolled back because some transactions are
-// deleted if they have been observed too long ago. That means the result
-// of Rollback won't always be exactly the same as if the last block had not
-// happened, but it should be close enough.
-func (ef *FeeEstimator) Rollback(hash *chainhash.Hash) error {
-	ef.mtx.Lock()
-	defer ef.mtx.Unlock()
-
-	// Find this block in the stack of recent registered blocks.
-	var n int
-	for n = 1; n <= len(ef.dropped); n++ {
-		if ef.dropped[len(ef.dropped)-n].hash.IsEqual(hash) {
-			break
-		}
-	}
-
-	if n > len(ef.dropped) {
-		return errors.New("no such block was recently registered")
+// maxUsableEstimate returns the highest target an estimate can be provided
+// for, which is limited by the number of blocks seen by the estimator.
+func (ef *FeeEstimator) maxUsableEstimate() int32 {
+	if ef.firstHeight == mining.UnminedHeight {
+		return 0
 	}
-
-	for i := 0; i < n; i++ {
-		ef.rollback()
+	span := (ef.lastKnownHeight - ef.firstHeight) / 2
+	if span > ef.long.maxConfirms() {
+		return ef.long.maxConfirms()
 	}
-
-	return nil
+	return span
 }
 
-// rollback rolls back the effect of the last block in the stack
-// of registered blocks.
-func (ef *FeeEstimator) rollback() {
-	// The previous sorted list is invalid, so delete it.
-	ef.cached = nil
-
-	// pop the last list of dropped txs from the stack.
-	last := len(ef.dropped) - 1
-	if last == -1 {
-		// Cannot really happen because the exported calling function
-		// only rolls back a block already known to be in the list
-		// of dropped transactions.
-		return
-	}
-
-	dropped := ef.dropped[last]
-
-	// where we are in each bin as we replace txs?
-	var replacementCounters [estimateFeeDepth]int
-
-	// Go through the txs in the dropped block.
-	for _, o := range dropped.transactions {
-		// Which bin was this tx in?
-		blocksToConfirm := o.mined - o.observed - 1
-
-		bin := ef.bin[blocksToConfirm]
-
-		var counter = replacementCounters[blocksToConfirm]
-
-		// Continue to go through that bin where we left off.
-		for {
-			if counter >= len(bin) {
-				// Panic, as we have entered an unrecoverable invalid state.
-				panic(errors.New("illegal state: cannot rollback dropped transaction"))
-			}
-
-			prev := bin[counter]
-
-			if prev.mined == ef.lastKnownHeight {
-				prev.mined = mining.UnminedHeight
-
-				bin[counter] = o
-
-				counter++
-				break
-			}
-
-			counter++
+// estimateCombinedFee returns the fee rate for the passed target from the
+// horizon with the highest resolution which tracks it.  When checkShorter is
+// set, a lower fee rate for the maximum target of a shorter horizon is
+// preferred as it reflects more recent blocks.
+func (ef *FeeEstimator) estimateCombinedFee(confTarget int32,
+	successThreshold float64, checkShorter bool) float64 {
+
+	if confTarget < 1 || confTarget > ef.long.maxConfirms() {
+		return -1
+	}
+
+	var estimate float64
+	switch {
+	case confTarget <= ef.short.maxConfirms():
+		estimate = ef.short.estimateMedianVal(confTarget,
+			sufficientTxsShort, successThreshold, ef.lastKnownHeight)
+	case confTarget <= ef.medium.maxConfirms():
+		estimate = ef.medium.estimateMedianVal(confTarget,
+			sufficientFeeTxs, successThreshold, ef.lastKnownHeight)
+	default:
+		estimate = ef.long.estimateMedianVal(confTarget,
+			sufficientFeeTxs, successThreshold, ef.lastKnownHeight)
+	}
+	if !checkShorter {
+		return estimate
+	}
+
+	if confTarget > ef.medium.maxConfirms() {
+		medMax := ef.medium.estimateMedianVal(ef.medium.maxConfirms(),
+			sufficientFeeTxs, successThreshold, ef.lastKnownHeight)
+		if medMax > 0 && (estimate == -1 || medMax < estimate) {
+			estimate = medMax
 		}
-
-		replacementCounters[blocksToConfirm] = counter
 	}
-
-	// Continue going through bins to find other txs to remove
-	// which did not replace any other when they were entered.
-	for i, j := range replacementCounters {
-		for {
-			l := len(ef.bin[i])
-			if j >= l {
-				break
-			}
-
-			prev := ef.bin[i][j]
-
-			if prev.mined == ef.lastKnownHeight {
-				prev.mined = mining.UnminedHeight
-
-				newBin := append(ef.bin[i][0:j], ef.bin[i][j+1:l]...)
-				// TODO This line should prevent an unintentional memory
-				// leak but it causes a panic when it is uncommented.
-				// ef.bin[i][j] = nil
-				ef.bin[i] = newBin
-
-				continue
-			}
-
-			j++
+	if confTarget > ef.short.maxConfirms() {
+		shortMax := ef.short.estimateMedianVal(ef.short.maxConfirms(),
+			sufficientTxsShort, successThreshold, ef.lastKnownHeight)
+		if shortMax > 0 && (estimate == -1 || shortMax < estimate) {
+			estimate = shortMax
 		}
 	}
-
-	ef.dropped = ef.dropped[0:last]
-
-	// The number of blocks the fee estimator has seen is decrimented.
-	ef.numBlocksRegistered--
-	ef.lastKnownHeight--
+	return estimate
 }
 
-// estimateFeeSet is a set of txs that can that is sorted
-// by the fee per kb rate.
-type estimateFeeSet struct {
-	feeRate []BroneesPerByte
-	bin     [estimateFeeDepth]uint32
-}
-
-func (b *estimateFeeSet) Len() int { return len(b.feeRate) }
-
-func (b *estimateFeeSet) Less(i, j int) bool {
-	return b.feeRate[i] > b.feeRate[j]
+// estimateConservativeFee returns the fee rate required to have been mined
+// within the passed target in the medium and long horizons.
+func (ef *FeeEstimator) estimateConservativeFee(doubleTarget int32) float64 {
+	estimate := float64(-1)
+	if doubleTarget <= ef.short.maxConfirms() {
+		estimate = ef.medium.estimateMedianVal(doubleTarget,
+			sufficientFeeTxs, doubleSuccessPct, ef.lastKnownHeight)
+	}
+	if doubleTarget <= ef.medium.maxConfirms() {
+		longEstimate := ef.long.estimateMedianVal(doubleTarget,
+			sufficientFeeTxs, doubleSuccessPct, ef.lastKnownHeight)
+		if longEstimate > estimate {
+			estimate = longEstimate
+		}
+	}
+	return estimate
 }
 
-func (b *estimateFeeSet) Swap(i, j int) {
-	b.feeRate[i], b.feeRate[j] = b.feeRate[j], b.feeRate[i]
-}
+// EstimateSmartFee estimates the fee rate required for a transaction to be
+// mined within the passed number of blocks.  The estimate is the highest of
+// the fee rates required to be mined within half the target with a high
+// probability, within the target with a higher probability and within double
+// the target with a very high probability.  Conservative estimates also
+// consider the longer horizons, so they react more slowly to falling fees.
+//
+// The target is reduced to the highest target there is enough data for, and
+// the target the estimate applies to is returned along with the estimate.
+func (ef *FeeEstimator) EstimateSmartFee(confTarget uint32, conservative bool) (BronPerKilobyte, uint32, error) {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
 
-// estimateFee returns the estimated fee for a transaction
-// to confirm in confirmations blocks from now, given
-// the data set we have collected.
-func (b *estimateFeeSet) estimateFee(confirmations int) BroneesPerByte {
-	if confirmations <= 0 {
-		return BroneesPerByte(math.Inf(1))
+	if confTarget == 0 || confTarget > MaxEstimateFeeTarget {
+		return -1, 0, fmt.Errorf("confirmation target must be between "+
+			"1 and %d", MaxEstimateFeeTarget)
 	}
 
-	if confirmations > estimateFeeDepth {
-		return 0
+	// Transactions are never mined in the block after they were seen
+	// often enough to provide an estimate for a single block.
+	target := int32(confTarget)
+	if target == 1 {
+		target = 2
 	}
-
-	// We don't have any transactions!
-	if len(b.feeRate) == 0 {
-		return 0
+	if maxUsable := ef.maxUsableEstimate(); target > maxUsable {
+		target = maxUsable
 	}
-
-	var min, max int = 0, 0
-	for i := 0; i < confirmations-1; i++ {
-		min += int(b.bin[i])
+	if target <= 1 {
+		return -1, 0, errInsufficientFeeData
 	}
 
-	max = min + int(b.bin[confirmations-1]) - 1
-	if max < min {
-		max = min
+	median := ef.estimateCombinedFee(target/2, halfSuccessPct, true)
+	actualEst := ef.estimateCombinedFee(target, successPct, true)
+	if actualEst > median {
+		median = actualEst
 	}
-	feeIndex := (min + max) / 2
-	if feeIndex >= len(b.feeRate) {
-		feeIndex = len(b.feeRate) - 1
+	doubleTarget := 2 * target
+	if doubleTarget > ef.long.maxConfirms() {
+		doubleTarget = ef.long.maxConfirms()
 	}
-
-	return b.feeRate[feeIndex]
-}
-
-// newEstimateFeeSet creates a temporary data structure that
-// can be used to find all fee estimates.
-func (ef *FeeEstimator) newEstimateFeeSet() *estimateFeeSet {
-	set := &estimateFeeSet{}
-
-	capacity := 0
-	for i, b := range ef.bin {
-		l := len(b)
-		set.bin[i] = uint32(l)
-		capacity += l
+	doubleEst := ef.estimateCombinedFee(doubleTarget, doubleSuccessPct,
+		!conservative)
+	if doubleEst > median {
+		median = doubleEst
 	}
-
-	set.feeRate = make([]BroneesPerByte, capacity)
-
-	i := 0
-	for _, b := range ef.bin {
-		for _, o := range b {
-			set.feeRate[i] = o.feeRate
-			i++
+	if conservative || median == -1 {
+		consEst := ef.estimateConservativeFee(doubleTarget)
+		if consEst > median {
+			median = consEst
 		}
 	}
-
-	sort.Sort(set)
-
-	return set
-}
-
-// estimates returns the set of all fee estimates from 1 to estimateFeeDepth
-// confirmations from now.
-func (ef *FeeEstimator) estimates() []BroneesPerByte {
-	set := ef.newEstimateFeeSet()
-
-	estimates := make([]BroneesPerByte, estimateFeeDepth)
-	for i := 0; i < estimateFeeDepth; i++ {
-		estimates[i] = set.estimateFee(i + 1)
+	if median < 0 {
+		return -1, uint32(target), errInsufficientFeeData
 	}
 
-	return estimates
+	return BronPerKilobyte(median * bronPerBronees), uint32(target), nil
 }
 
-// EstimateFee estimates the fee per byte to have a tx confirmed a given
-// number of blocks from now.
+// EstimateFee estimates the fee per kilobyte required to have a transaction
+// mined within the passed number of blocks with a very high probability using
+// the medium horizon only.  -1 is returned when there is not enough data.
 func (ef *FeeEstimator) EstimateFee(numBlocks uint32) (BronPerKilobyte, error) {
 	ef.mtx.Lock()
 	defer ef.mtx.Unlock()
 
-	// If the number of registered blocks is below the minimum, return
-	// an error.
-	if ef.numBlocksRegistered < ef.minRegisteredBlocks {
-		return -1, errors.New("not enough blocks have been observed")
-	}
-
 	if numBlocks == 0 {
 		return -1, errors.New("cannot confirm transaction in zero blocks")
 	}
 
-	if numBlocks > estimateFeeDepth {
+	if numBlocks > uint32(ef.medium.maxConfirms()) {
 		return -1, fmt.Errorf(
 			"can only estimate fees for up to %d blocks from now",
-			estimateFeeBinSize)
+			ef.medium.maxConfirms())
 	}
 
-	// If there are no cached results, generate them.
-	if ef.cached == nil {
-		ef.cached = ef.estimates()
+	feeRate := ef.medium.estimateMedianVal(int32(numBlocks),
+		sufficientFeeTxs, doubleSuccessPct, ef.lastKnownHeight)
+	if feeRate < 0 {
+		return -1, nil
 	}
-
-	return ef.cached[int(numBlocks)-1].ToBronPerKb(), nil
+	return BronPerKilobyte(feeRate * bronPerBronees), nil
 }
 
 // In case the format for the serialized version of the FeeEstimator changes,
 // we use a version number. If the version number changes, it does not make
 // sense to try to upgrade a previous version to a new version. Instead, just
 // start fee estimation over.
-const estimateFeeSaveVersion = 1
-
-func deserializeRegisteredBlock(r io.Reader, txs map[uint32]*observedTransaction) (*registeredBlock, error) {
-	var lenTransactions uint32
-
-	rb := &registeredBlock{}
-	binary.Read(r, binary.BigEndian, &rb.hash)
-	binary.Read(r, binary.BigEndian, &lenTransactions)
-
-	rb.transactions = make([]*observedTransaction, lenTransactions)
-
-	for i := uint32(0); i < lenTransactions; i++ {
-		var index uint32
-		binary.Read(r, binary.BigEndian, &index)
-		rb.transactions[i] = txs[index]
-	}
-
-	return rb, nil
-}
+const estimateFeeSaveVersion = 2
 
 // FeeEstimatorState represents a saved FeeEstimator that can be
 // restored with data from an earlier session of the program.
 type FeeEstimatorState []byte
 
-// observedTxSet is a set of txs that can that is sorted
-// by hash. It exists for serialization purposes so that
-// a serialized state always comes out the same.
-type observedTxSet []*observedTransaction
-
-func (q observedTxSet) Len() int { return len(q) }
-
-func (q observedTxSet) Less(i, j int) bool {
-	return strings.Compare(q[i].hash.String(), q[j].hash.String()) < 0
+// serialize writes the moving averages of the horizon to w.  The unconfirmed
+// transactions are not saved since they are not tracked across restarts.
+func (s *txConfirmStats) serialize(w *bytes.Buffer) {
+	binary.Write(w, binary.BigEndian, uint32(len(s.confAvg)))
+	binary.Write(w, binary.BigEndian, s.txCtAvg)
+	binary.Write(w, binary.BigEndian, s.feeRateAvg)
+	for i := range s.confAvg {
+		binary.Write(w, binary.BigEndian, s.confAvg[i])
+	}
+	for i := range s.failAvg {
+		binary.Write(w, binary.BigEndian, s.failAvg[i])
+	}
 }
 
-func (q observedTxSet) Swap(i, j int) {
-	q[i], q[j] = q[j], q[i]
+// deserialize reads the moving averages of the horizon written by serialize
+// from r.
+func (s *txConfirmStats) deserialize(r *bytes.Reader) error {
+	var periods uint32
+	if err := binary.Read(r, binary.BigEndian, &periods); err != nil {
+		return err
+	}
+	if periods != uint32(len(s.confAvg)) {
+		return fmt.Errorf("unexpected number of periods %d, expected %d",
+			periods, len(s.confAvg))
+	}
+
+	if err := binary.Read(r, binary.BigEndian, s.txCtAvg); err != nil {
+		return err
+	}
+	if err := binary.Read(r, binary.BigEndian, s.feeRateAvg); err != nil {
+		return err
+	}
+	for i := range s.confAvg {
+		if err := binary.Read(r, binary.BigEndian, s.confAvg[i]); err != nil {
+			return err
+		}
+	}
+	for i := range s.failAvg {
+		if err := binary.Read(r, binary.BigEndian, s.failAvg[i]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Save records the current state of the FeeEstimator to a []byte that
@@ -623,55 +762,15 @@ func (ef *FeeEstimator) Save() FeeEstimatorState {
 	ef.mtx.Lock()
 	defer ef.mtx.Unlock()
 
-	// TODO figure out what the capacity should be.
-	w := bytes.NewBuffer(make([]byte, 0))
-
+	w := new(bytes.Buffer)
 	binary.Write(w, binary.BigEndian, uint32(estimateFeeSaveVersion))
-
-	// Insert basic parameters.
-	binary.Write(w, binary.BigEndian, &ef.maxRollback)
-	binary.Write(w, binary.BigEndian, &ef.binSize)
-	binary.Write(w, binary.BigEndian, &ef.maxReplacements)
-	binary.Write(w, binary.BigEndian, &ef.minRegisteredBlocks)
-	binary.Write(w, binary.BigEndian, &ef.lastKnownHeight)
-	binary.Write(w, binary.BigEndian, &ef.numBlocksRegistered)
-
-	// Put all the observed transactions in a sorted list.
-	var txCount uint32
-	ots := make([]*observedTransaction, len(ef.observed))
-	for hash := range ef.observed {
-		ots[txCount] = ef.observed[hash]
-		txCount++
-	}
-
-	sort.Sort(observedTxSet(ots))
-
-	txCount = 0
-	observed := make(map[*observedTransaction]uint32)
-	binary.Write(w, binary.BigEndian, uint32(len(ef.observed)))
-	for _, ot := range ots {
-		ot.Serialize(w)
-		observed[ot] = txCount
-		txCount++
-	}
-
-	// Save all the right bins.
-	for _, list := range ef.bin {
-
-		binary.Write(w, binary.BigEndian, uint32(len(list)))
-
-		for _, o := range list {
-			binary.Write(w, binary.BigEndian, observed[o])
-		}
-	}
-
-	// Dropped transactions.
-	binary.Write(w, binary.BigEndian, uint32(len(ef.dropped)))
-	for _, registered := range ef.dropped {
-		registered.serialize(w, observed)
+	binary.Write(w, binary.BigEndian, ef.firstHeight)
+	binary.Write(w, binary.BigEndian, ef.lastKnownHeight)
+	binary.Write(w, binary.BigEndian, uint32(len(ef.buckets)))
+	for _, stats := range ef.allStats() {
+		stats.serialize(w)
 	}
 
-	// Commit the tx and return.
 	return FeeEstimatorState(w.Bytes())
 }
 
@@ -690,60 +789,29 @@ func RestoreFeeEstimator(data FeeEstimatorState) (*FeeEstimator, error) {
 		return nil, fmt.Errorf("Incorrect version: expected %d found %d", estimateFeeSaveVersion, version)
 	}
 
-	ef := &FeeEstimator{
-		observed: make(map[chainhash.Hash]*observedTransaction),
-	}
-
-	// Read basic parameters.
-	binary.Read(r, binary.BigEndian, &ef.maxRollback)
-	binary.Read(r, binary.BigEndian, &ef.binSize)
-	binary.Read(r, binary.BigEndian, &ef.maxReplacements)
-	binary.Read(r, binary.BigEndian, &ef.minRegisteredBlocks)
-	binary.Read(r, binary.BigEndian, &ef.lastKnownHeight)
-	binary.Read(r, binary.BigEndian, &ef.numBlocksRegistered)
-
-	// Read transactions.
-	var numObserved uint32
-	observed := make(map[uint32]*observedTransaction)
-	binary.Read(r, binary.BigEndian, &numObserved)
-	for i := uint32(0); i < numObserved; i++ {
-		ot, err := deserializeObservedTransaction(r)
-		if err != nil {
-			return nil, err
-		}
-		observed[i] = ot
-		ef.observed[ot.hash] = ot
-	}
-
-	// Read bins.
-	for i := 0; i < estimateFeeDepth; i++ {
-		var numTransactions uint32
-		binary.Read(r, binary.BigEndian, &numTransactions)
-		bin := make([]*observedTransaction, numTransactions)
-		for j := uint32(0); j < numTransactions; j++ {
-			var index uint32
-			binary.Read(r, binary.BigEndian, &index)
-
-			var exists bool
-			bin[j], exists = observed[index]
-			if !exists {
-				return nil, fmt.Errorf("Invalid transaction reference %d", index)
-			}
-		}
-		ef.bin[i] = bin
+	ef := NewFeeEstimator()
+	var numBuckets uint32
+	if err := binary.Read(r, binary.BigEndian, &ef.firstHeight); err != nil {
+		return nil, err
 	}
-
-	// Read dropped transactions.
-	var numDropped uint32
-	binary.Read(r, binary.BigEndian, &numDropped)
-	ef.dropped = make([]*registeredBlock, numDropped)
-	for i := uint32(0); i < numDropped; i++ {
-		var err error
-		ef.dropped[int(i)], err = deserializeRegisteredBlock(r, observed)
-		if err != nil {
+	if err := binary.Read(r, binary.BigEndian, &ef.lastKnownHeight); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, binary.BigEndian, &numBuckets); err != nil {
+		return nil, err
+	}
+	if numBuckets != uint32(len(ef.buckets)) {
+		return nil, fmt.Errorf("unexpected number of fee rate buckets "+
+			"%d, expected %d", numBuckets, len(ef.buckets))
+	}
+	for _, stats := range ef.allStats() {
+		if err := stats.deserialize(r); err != nil {
 			return nil, err
 		}
 	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%d unexpected trailing bytes", r.Len())
+	}
 
 	return ef, nil
 }
@@ -6,419 +6,361 @@ package mempool
 
 import (
 	"bytes"
-	"math/rand"
+	"encoding/binary"
+	"math"
 	"testing"
 
-	"github.com/brsuite/brond/chaincfg/chainhash"
 	"github.com/brsuite/brond/mining"
 	"github.com/brsuite/brond/wire"
 	"github.com/brsuite/bronutil"
 )
 
-// newTestFeeEstimator creates a feeEstimator with some different parameters
-// for testing purposes.
-func newTestFeeEstimator(binSize, maxReplacements, maxRollback uint32) *FeeEstimator {
-	return &FeeEstimator{
-		maxRollback:         maxRollback,
-		lastKnownHeight:     0,
-		binSize:             int32(binSize),
-		minRegisteredBlocks: 0,
-		maxReplacements:     int32(maxReplacements),
-		observed:            make(map[chainhash.Hash]*observedTransaction),
-		dropped:             make([]*registeredBlock, 0, maxRollback),
-	}
-}
+// feeHistoryTxs describes transactions which enter the mempool at every block
+// of a synthetic history.
+type feeHistoryTxs struct {
+	// feeRate is the fee rate of the transactions in bronees per kilobyte.
+	feeRate int64
+
+	// count is the number of transactions entering the mempool per block.
+	count int
 
-// lastBlock is a linked list of the block hashes which have been
-// processed by the test FeeEstimator.
-type lastBlock struct {
-	hash *chainhash.Hash
-	prev *lastBlock
+	// minedAfter and evictedAfter are the number of blocks after which
+	// the transactions are mined or removed from the mempool without being
+	// mined respectively.  Zero means never.
+	minedAfter   int32
+	evictedAfter int32
 }
 
-// estimateFeeTester interacts with the FeeEstimator to keep track
-// of its expected state.
-type estimateFeeTester struct {
-	ef      *FeeEstimator
+// feeHistory replays a synthetic history of blocks and mempool transactions
+// against a fee estimator.
+type feeHistory struct {
 	t       *testing.T
-	version int32
+	ef      *FeeEstimator
 	height  int32
-	last    *lastBlock
+	nextTx  uint32
+	mined   map[int32][]*TxDesc
+	evicted map[int32][]*TxDesc
+}
+
+// newFeeHistory returns a history which starts by registering an empty block
+// at the passed height with a new fee estimator.
+func newFeeHistory(t *testing.T, height int32) *feeHistory {
+	h := &feeHistory{
+		t:       t,
+		ef:      NewFeeEstimator(),
+		height:  height - 1,
+		mined:   make(map[int32][]*TxDesc),
+		evicted: make(map[int32][]*TxDesc),
+	}
+	h.connectBlock(nil)
+	return h
 }
 
-func (eft *estimateFeeTester) testTx(fee bronutil.Amount) *TxDesc {
-	eft.version++
+// newTx returns a transaction entering the mempool at the current height with
+// the passed fee rate.  All transactions have a virtual size of 10 bytes.
+func (h *feeHistory) newTx(feeRate int64) *TxDesc {
+	h.nextTx++
+	tx := bronutil.NewTx(&wire.MsgTx{Version: 1, LockTime: h.nextTx})
 	return &TxDesc{
 		TxDesc: mining.TxDesc{
-			Tx: bronutil.NewTx(&wire.MsgTx{
-				Version: eft.version,
-			}),
-			Height: eft.height,
-			Fee:    int64(fee),
+			Tx:     tx,
+			Height: h.height,
+			Fee:    feeRate * GetTxVirtualSize(tx) / bytePerKb,
 		},
-		StartingPriority: 0,
 	}
 }
 
-func expectedFeePerKilobyte(t *TxDesc)BronPerKilobyte {
-	size := float64(t.TxDesc.Tx.MsgTx().SerializeSize())
-	fee := float64(t.TxDesc.Fee)
-
-	return BroneesPerByte(fee / size).ToBronPerKb()
-}
-
-func (eft *estimateFeeTester) newBlock(txs []*wire.MsgTx) {
-	eft.height++
-
-	block := bronutil.NewBlock(&wire.MsgBlock{
-		Transactions: txs,
-	})
-	block.SetHeight(eft.height)
-
-	eft.last = &lastBlock{block.Hash(), eft.last}
-
-	eft.ef.RegisterBlock(block)
-}
-
-func (eft *estimateFeeTester) rollback() {
-	if eft.last == nil {
-		return
+// connectBlock registers a block at the next height containing the passed
+// transactions with the fee estimator.
+func (h *feeHistory) connectBlock(txs []*TxDesc) *bronutil.Block {
+	h.height++
+	msgBlock := &wire.MsgBlock{}
+	for _, tx := range txs {
+		msgBlock.AddTransaction(tx.Tx.MsgTx())
 	}
-
-	err := eft.ef.Rollback(eft.last.hash)
-
-	if err != nil {
-		eft.t.Errorf("Could not rollback: %v", err)
+	block := bronutil.NewBlock(msgBlock)
+	block.SetHeight(h.height)
+	if err := h.ef.RegisterBlock(block); err != nil {
+		h.t.Fatalf("RegisterBlock: unexpected error %v", err)
 	}
-
-	eft.height--
-	eft.last = eft.last.prev
+	return block
 }
 
-// TestEstimateFee tests basic functionality in the FeeEstimator.
-func TestEstimateFee(t *testing.T) {
-	ef := newTestFeeEstimator(5, 3, 1)
-	eft := estimateFeeTester{ef: ef, t: t}
-
-	// Try with no txs and get zero for all queries.
-	expected :=BronPerKilobyte(0.0)
-	for i := uint32(1); i <= estimateFeeDepth; i++ {
-		estimated, _ := ef.EstimateFee(i)
-
-		if estimated != expected {
-			t.Errorf("Estimate fee error: expected %f when estimator is empty; got %f", expected, estimated)
+// replay adds the described transactions to the mempool and connects a block
+// for the passed number of blocks.
+func (h *feeHistory) replay(blocks int, txs []feeHistoryTxs) {
+	for i := 0; i < blocks; i++ {
+		for _, desc := range txs {
+			for j := 0; j < desc.count; j++ {
+				tx := h.newTx(desc.feeRate)
+				h.ef.ObserveTransaction(tx)
+				if desc.minedAfter > 0 {
+					height := h.height + desc.minedAfter
+					h.mined[height] = append(h.mined[height], tx)
+				}
+				if desc.evictedAfter > 0 {
+					height := h.height + desc.evictedAfter
+					h.evicted[height] = append(h.evicted[height], tx)
+				}
+			}
 		}
-	}
-
-	// Now insert a tx.
-	tx := eft.testTx(1000000)
-	ef.ObserveTransaction(tx)
-
-	// Expected should still be zero because this is still in the mempool.
-	expected =BronPerKilobyte(0.0)
-	for i := uint32(1); i <= estimateFeeDepth; i++ {
-		estimated, _ := ef.EstimateFee(i)
 
-		if estimated != expected {
-			t.Errorf("Estimate fee error: expected %f when estimator has one tx in mempool; got %f", expected, estimated)
+		h.connectBlock(h.mined[h.height+1])
+		delete(h.mined, h.height)
+		for _, tx := range h.evicted[h.height] {
+			h.ef.RemoveTransaction(tx.Tx.Hash())
 		}
+		delete(h.evicted, h.height)
 	}
+}
 
-	// Change minRegisteredBlocks to make sure that works. Error return
-	// value expected.
-	ef.minRegisteredBlocks = 1
-	expected =BronPerKilobyte(-1.0)
-	for i := uint32(1); i <= estimateFeeDepth; i++ {
-		estimated, _ := ef.EstimateFee(i)
+// feeRateEqual returns whether the passed fee rates are equal within the
+// precision lost by the moving averages.
+func feeRateEqual(a, b BronPerKilobyte) bool {
+	return math.Abs(float64(a-b)) <= math.Abs(float64(b))*1e-9
+}
 
-		if estimated != expected {
-			t.Errorf("Estimate fee error: expected %f before any blocks have been registered; got %f", expected, estimated)
-		}
+// TestEstimateSmartFee ensures the fee estimates for synthetic histories of
+// blocks and mempool transactions are the expected ones.
+func TestEstimateSmartFee(t *testing.T) {
+	type estimate struct {
+		target       uint32
+		conservative bool
+		wantTarget   uint32
+		wantFeeRate  BronPerKilobyte
 	}
-
-	// Record a block with the new tx.
-	eft.newBlock([]*wire.MsgTx{tx.Tx.MsgTx()})
-	expected = expectedFeePerKilobyte(tx)
-	for i := uint32(1); i <= estimateFeeDepth; i++ {
-		estimated, _ := ef.EstimateFee(i)
-
-		if estimated != expected {
-			t.Errorf("Estimate fee error: expected %f when one tx is binned; got %f", expected, estimated)
-		}
+	tests := []struct {
+		name      string
+		txs       []feeHistoryTxs
+		estimates []estimate
+	}{
+		{
+			name: "steady fee rates",
+			txs: []feeHistoryTxs{
+				{feeRate: 50000, count: 10, minedAfter: 1},
+				{feeRate: 10000, count: 10, minedAfter: 6},
+				{feeRate: 2000, count: 10, evictedAfter: 30},
+			},
+			estimates: []estimate{
+				{1, false, 2, 0.0005},
+				{2, true, 2, 0.0005},
+				{12, false, 12, 0.0001},
+				{12, true, 12, 0.0001},
+				{MaxEstimateFeeTarget, false, 100, 0.0001},
+			},
+		},
+		{
+			name: "all mined in next block",
+			txs: []feeHistoryTxs{
+				{feeRate: 50000, count: 10, minedAfter: 1},
+				{feeRate: 20000, count: 10, minedAfter: 1},
+			},
+			estimates: []estimate{
+				{2, false, 2, 0.0002},
+				{6, true, 6, 0.0002},
+			},
+		},
+		{
+			name: "evictions count against fee rate",
+			txs: []feeHistoryTxs{
+				{feeRate: 50000, count: 10, minedAfter: 1},
+				{feeRate: 20000, count: 5, minedAfter: 1},
+				{feeRate: 20000, count: 5, evictedAfter: 3},
+			},
+			estimates: []estimate{
+				{2, false, 2, 0.0005},
+			},
+		},
 	}
 
-	// Roll back the last block; this was an orphan block.
-	ef.minRegisteredBlocks = 0
-	eft.rollback()
-	expected =BronPerKilobyte(0.0)
-	for i := uint32(1); i <= estimateFeeDepth; i++ {
-		estimated, _ := ef.EstimateFee(i)
+	for _, test := range tests {
+		h := newFeeHistory(t, 1000)
+		h.replay(200, test.txs)
+
+		for _, est := range test.estimates {
+			feeRate, target, err := h.ef.EstimateSmartFee(est.target,
+				est.conservative)
+			if err != nil {
+				t.Errorf("%s: EstimateSmartFee(%d, %v): unexpected "+
+					"error %v", test.name, est.target,
+					est.conservative, err)
+				continue
+			}
+			if target != est.wantTarget ||
+				!feeRateEqual(feeRate, est.wantFeeRate) {
 
-		if estimated != expected {
-			t.Errorf("Estimate fee error: expected %f after rolling back block; got %f", expected, estimated)
-		}
-	}
+				t.Errorf("%s: EstimateSmartFee(%d, %v): got %v for "+
+					"target %d, want %v for target %d", test.name,
+					est.target, est.conservative, feeRate, target,
+					est.wantFeeRate, est.wantTarget)
+			}
 
-	// Record an empty block and then a block with the new tx.
-	// This test was made because of a bug that only appeared when there
-	// were no transactions in the first bin.
-	eft.newBlock([]*wire.MsgTx{})
-	eft.newBlock([]*wire.MsgTx{tx.Tx.MsgTx()})
-	expected = expectedFeePerKilobyte(tx)
-	for i := uint32(1); i <= estimateFeeDepth; i++ {
-		estimated, _ := ef.EstimateFee(i)
-
-		if estimated != expected {
-			t.Errorf("Estimate fee error: expected %f when one tx is binned; got %f", expected, estimated)
+			// Conservative estimates are never lower than economical
+			// ones.
+			if est.conservative {
+				continue
+			}
+			consFeeRate, _, err := h.ef.EstimateSmartFee(est.target, true)
+			if err != nil || consFeeRate < feeRate {
+				t.Errorf("%s: conservative estimate %v for target "+
+					"%d below economical %v (err %v)", test.name,
+					consFeeRate, est.target, feeRate, err)
+			}
 		}
 	}
+}
 
-	// Create some more transactions.
-	txA := eft.testTx(500000)
-	txB := eft.testTx(2000000)
-	txC := eft.testTx(4000000)
-	ef.ObserveTransaction(txA)
-	ef.ObserveTransaction(txB)
-	ef.ObserveTransaction(txC)
-
-	// Record 7 empty blocks.
-	for i := 0; i < 7; i++ {
-		eft.newBlock([]*wire.MsgTx{})
-	}
+// TestEstimateSmartFeeErrors ensures estimates are refused for invalid targets
+// and until enough blocks have been registered.
+func TestEstimateSmartFeeErrors(t *testing.T) {
+	h := newFeeHistory(t, 1000)
+	txs := []feeHistoryTxs{{feeRate: 50000, count: 10, minedAfter: 1}}
 
-	// Mine the first tx.
-	eft.newBlock([]*wire.MsgTx{txA.Tx.MsgTx()})
-
-	// Now the estimated amount should depend on the value
-	// of the argument to estimate fee.
-	for i := uint32(1); i <= estimateFeeDepth; i++ {
-		estimated, _ := ef.EstimateFee(i)
-		if i > 2 {
-			expected = expectedFeePerKilobyte(txA)
-		} else {
-			expected = expectedFeePerKilobyte(tx)
-		}
-		if estimated != expected {
-			t.Errorf("Estimate fee error: expected %f on round %d; got %f", expected, i, estimated)
+	for _, target := range []uint32{0, MaxEstimateFeeTarget + 1} {
+		if _, _, err := h.ef.EstimateSmartFee(target, false); err == nil {
+			t.Errorf("EstimateSmartFee(%d): did not fail", target)
 		}
 	}
 
-	// Record 5 more empty blocks.
-	for i := 0; i < 5; i++ {
-		eft.newBlock([]*wire.MsgTx{})
+	// Four blocks are not enough for a target of two blocks.
+	h.replay(3, txs)
+	_, _, err := h.ef.EstimateSmartFee(2, false)
+	if err != errInsufficientFeeData {
+		t.Fatalf("EstimateSmartFee: got error %v, want %v", err,
+			errInsufficientFeeData)
 	}
 
-	// Mine the next tx.
-	eft.newBlock([]*wire.MsgTx{txB.Tx.MsgTx()})
-
-	// Now the estimated amount should depend on the value
-	// of the argument to estimate fee.
-	for i := uint32(1); i <= estimateFeeDepth; i++ {
-		estimated, _ := ef.EstimateFee(i)
-		if i <= 2 {
-			expected = expectedFeePerKilobyte(txB)
-		} else if i <= 8 {
-			expected = expectedFeePerKilobyte(tx)
-		} else {
-			expected = expectedFeePerKilobyte(txA)
-		}
-
-		if estimated != expected {
-			t.Errorf("Estimate fee error: expected %f on round %d; got %f", expected, i, estimated)
-		}
+	h.replay(20, txs)
+	feeRate, target, err := h.ef.EstimateSmartFee(2, false)
+	if err != nil || target != 2 || !feeRateEqual(feeRate, 0.0005) {
+		t.Fatalf("EstimateSmartFee: got %v for target %d (err %v), "+
+			"want 0.0005 for target 2", feeRate, target, err)
 	}
+}
 
-	// Record 9 more empty blocks.
-	for i := 0; i < 10; i++ {
-		eft.newBlock([]*wire.MsgTx{})
+// TestEstimateFee ensures the fee estimates provided for the estimatefee
+// command use the medium horizon.
+func TestEstimateFee(t *testing.T) {
+	h := newFeeHistory(t, 1000)
+	if feeRate, err := h.ef.EstimateFee(1); err != nil || feeRate != -1 {
+		t.Fatalf("EstimateFee: got %v (err %v) without data, want -1",
+			feeRate, err)
 	}
 
-	// Mine txC.
-	eft.newBlock([]*wire.MsgTx{txC.Tx.MsgTx()})
-
-	// This should have no effect on the outcome because too
-	// many blocks have been mined for txC to be recorded.
-	for i := uint32(1); i <= estimateFeeDepth; i++ {
-		estimated, _ := ef.EstimateFee(i)
-		if i <= 2 {
-			expected = expectedFeePerKilobyte(txC)
-		} else if i <= 8 {
-			expected = expectedFeePerKilobyte(txB)
-		} else if i <= 8+6 {
-			expected = expectedFeePerKilobyte(tx)
-		} else {
-			expected = expectedFeePerKilobyte(txA)
-		}
-
-		if estimated != expected {
-			t.Errorf("Estimate fee error: expected %f on round %d; got %f", expected, i, estimated)
-		}
+	h.replay(200, []feeHistoryTxs{
+		{feeRate: 50000, count: 10, minedAfter: 1},
+		{feeRate: 10000, count: 10, minedAfter: 6},
+	})
+	tests := []struct {
+		numBlocks uint32
+		want      BronPerKilobyte
+	}{
+		{numBlocks: 1, want: 0.0005},
+		{numBlocks: 4, want: 0.0005},
+		{numBlocks: 5, want: 0.0001},
+		{numBlocks: 6, want: 0.0001},
+		{numBlocks: 48, want: 0.0001},
 	}
-}
-
-func (eft *estimateFeeTester) estimates() [estimateFeeDepth]BronPerKilobyte {
-
-	// Generate estimates
-	var estimates [estimateFeeDepth]BronPerKilobyte
-	for i := 0; i < estimateFeeDepth; i++ {
-		estimates[i], _ = eft.ef.EstimateFee(uint32(i + 1))
+	for _, test := range tests {
+		feeRate, err := h.ef.EstimateFee(test.numBlocks)
+		if err != nil || !feeRateEqual(feeRate, test.want) {
+			t.Errorf("EstimateFee(%d): got %v (err %v), want %v",
+				test.numBlocks, feeRate, err, test.want)
+		}
 	}
 
-	// Check that all estimated fee results go in descending order.
-	for i := 1; i < estimateFeeDepth; i++ {
-		if estimates[i] > estimates[i-1] {
-			eft.t.Error("Estimates not in descending order; got ",
-				estimates[i], " for estimate ", i, " and ", estimates[i-1], " for ", (i - 1))
-			panic("invalid state.")
+	for _, numBlocks := range []uint32{0, 49} {
+		if _, err := h.ef.EstimateFee(numBlocks); err == nil {
+			t.Errorf("EstimateFee(%d): did not fail", numBlocks)
 		}
 	}
-
-	return estimates
 }
 
-func (eft *estimateFeeTester) round(txHistory [][]*TxDesc,
-	estimateHistory [][estimateFeeDepth]BronPerKilobyte,
-	txPerRound, txPerBlock uint32) ([][]*TxDesc, [][estimateFeeDepth]BronPerKilobyte) {
-
-	// generate new txs.
-	var newTxs []*TxDesc
-	for i := uint32(0); i < txPerRound; i++ {
-		newTx := eft.testTx(bronutil.Amount(rand.Intn(1000000)))
-		eft.ef.ObserveTransaction(newTx)
-		newTxs = append(newTxs, newTx)
+// TestEstimateFeeReorg ensures blocks registered at or below the last known
+// height do not change the statistics but stop their transactions from being
+// tracked.
+func TestEstimateFeeReorg(t *testing.T) {
+	h := newFeeHistory(t, 1000)
+	h.replay(20, []feeHistoryTxs{{feeRate: 10000, count: 2, minedAfter: 5}})
+	state := h.ef.Save()
+
+	// Mine the pending transactions in a block replacing the current tip.
+	var pending []*TxDesc
+	for _, txs := range h.mined {
+		pending = append(pending, txs...)
 	}
-
-	// Generate mempool.
-	mempool := make(map[*observedTransaction]*TxDesc)
-	for _, h := range txHistory {
-		for _, t := range h {
-			if o, exists := eft.ef.observed[*t.Tx.Hash()]; exists && o.mined == mining.UnminedHeight {
-				mempool[o] = t
-			}
-		}
+	if len(h.ef.tracked) != len(pending) {
+		t.Fatalf("got %d tracked transactions, want %d",
+			len(h.ef.tracked), len(pending))
 	}
+	h.height--
+	h.connectBlock(pending)
 
-	// generate new block, with no duplicates.
-	i := uint32(0)
-	newBlockList := make([]*wire.MsgTx, 0, txPerBlock)
-	for _, t := range mempool {
-		newBlockList = append(newBlockList, t.TxDesc.Tx.MsgTx())
-		i++
-
-		if i == txPerBlock {
-			break
-		}
+	if len(h.ef.tracked) != 0 {
+		t.Fatalf("got %d tracked transactions after reorg, want 0",
+			len(h.ef.tracked))
 	}
-
-	// Register a new block.
-	eft.newBlock(newBlockList)
-
-	// return results.
-	estimates := eft.estimates()
-
-	// Return results
-	return append(txHistory, newTxs), append(estimateHistory, estimates)
-}
-
-// TestEstimateFeeRollback tests the rollback function, which undoes the
-// effect of a adding a new block.
-func TestEstimateFeeRollback(t *testing.T) {
-	txPerRound := uint32(7)
-	txPerBlock := uint32(5)
-	binSize := uint32(6)
-	maxReplacements := uint32(4)
-	stepsBack := 2
-	rounds := 30
-
-	eft := estimateFeeTester{ef: newTestFeeEstimator(binSize, maxReplacements, uint32(stepsBack)), t: t}
-	var txHistory [][]*TxDesc
-	estimateHistory := [][estimateFeeDepth]BronPerKilobyte{eft.estimates()}
-
-	for round := 0; round < rounds; round++ {
-		// Go forward a few rounds.
-		for step := 0; step <= stepsBack; step++ {
-			txHistory, estimateHistory =
-				eft.round(txHistory, estimateHistory, txPerRound, txPerBlock)
-		}
-
-		// Now go back.
-		for step := 0; step < stepsBack; step++ {
-			eft.rollback()
-
-			// After rolling back, we should have the same estimated
-			// fees as before.
-			expected := estimateHistory[len(estimateHistory)-step-2]
-			estimates := eft.estimates()
-
-			// Ensure that these are both the same.
-			for i := 0; i < estimateFeeDepth; i++ {
-				if expected[i] != estimates[i] {
-					t.Errorf("Rollback value mismatch. Expected %f, got %f. ",
-						expected[i], estimates[i])
-					return
-				}
-			}
-		}
-
-		// Erase history.
-		txHistory = txHistory[0 : len(txHistory)-stepsBack]
-		estimateHistory = estimateHistory[0 : len(estimateHistory)-stepsBack]
+	if !bytes.Equal(h.ef.Save(), state) {
+		t.Fatal("statistics changed by reorganized block")
 	}
 }
 
-func (eft *estimateFeeTester) checkSaveAndRestore(
-	previousEstimates [estimateFeeDepth]BronPerKilobyte) {
-
-	// Get the save state.
-	save := eft.ef.Save()
+// TestFeeEstimatorSaveRestore ensures a saved fee estimator is restored with
+// the same statistics and that invalid states are rejected.
+func TestFeeEstimatorSaveRestore(t *testing.T) {
+	h := newFeeHistory(t, 1000)
+	h.replay(100, []feeHistoryTxs{
+		{feeRate: 50000, count: 10, minedAfter: 1},
+		{feeRate: 10000, count: 10, minedAfter: 6},
+		{feeRate: 2000, count: 10, evictedAfter: 30},
+	})
 
-	// Save and restore database.
-	var err error
-	eft.ef, err = RestoreFeeEstimator(save)
+	state := h.ef.Save()
+	ef, err := RestoreFeeEstimator(state)
 	if err != nil {
-		eft.t.Fatalf("Could not restore database: %s", err)
+		t.Fatalf("RestoreFeeEstimator: unexpected error %v", err)
 	}
-
-	// Save again and check that it matches the previous one.
-	redo := eft.ef.Save()
-	if !bytes.Equal(save, redo) {
-		eft.t.Fatalf("Restored states do not match: %v %v", save, redo)
+	if !bytes.Equal(ef.Save(), state) {
+		t.Fatal("restored state does not match saved state")
 	}
-
-	// Check that the results match.
-	newEstimates := eft.estimates()
-
-	for i, prev := range previousEstimates {
-		if prev != newEstimates[i] {
-			eft.t.Error("Mismatch in estimate ", i, " after restore; got ", newEstimates[i], " but expected ", prev)
+	if ef.LastKnownHeight() != h.height {
+		t.Fatalf("got last known height %d, want %d",
+			ef.LastKnownHeight(), h.height)
+	}
+	for _, target := range []uint32{2, 6, 12, 48} {
+		for _, conservative := range []bool{false, true} {
+			want, wantTarget, wantErr := h.ef.EstimateSmartFee(target,
+				conservative)
+			got, gotTarget, err := ef.EstimateSmartFee(target,
+				conservative)
+			if got != want || gotTarget != wantTarget || err != wantErr {
+				t.Errorf("EstimateSmartFee(%d, %v): got %v for "+
+					"target %d (err %v) after restore, want %v "+
+					"for target %d (err %v)", target, conservative,
+					got, gotTarget, err, want, wantTarget, wantErr)
+			}
 		}
 	}
-}
-
-// TestSave tests saving and restoring to a []byte.
-func TestDatabase(t *testing.T) {
 
-	txPerRound := uint32(7)
-	txPerBlock := uint32(5)
-	binSize := uint32(6)
-	maxReplacements := uint32(4)
-	rounds := 8
-
-	eft := estimateFeeTester{ef: newTestFeeEstimator(binSize, maxReplacements, uint32(rounds)+1), t: t}
-	var txHistory [][]*TxDesc
-	estimateHistory := [][estimateFeeDepth]BronPerKilobyte{eft.estimates()}
-
-	for round := 0; round < rounds; round++ {
-		eft.checkSaveAndRestore(estimateHistory[len(estimateHistory)-1])
-
-		// Go forward one step.
-		txHistory, estimateHistory =
-			eft.round(txHistory, estimateHistory, txPerRound, txPerBlock)
+	// The restored estimator continues with the next block.
+	h.ef = ef
+	h.mined = make(map[int32][]*TxDesc)
+	h.evicted = make(map[int32][]*TxDesc)
+	h.replay(1, nil)
+
+	oldVersion := append(FeeEstimatorState(nil), state...)
+	binary.BigEndian.PutUint32(oldVersion, 1)
+	invalid := []struct {
+		name  string
+		state FeeEstimatorState
+	}{
+		{name: "old version", state: oldVersion},
+		{name: "truncated", state: state[:len(state)-1]},
+		{name: "trailing bytes", state: append(state, 0)},
 	}
-
-	// Reverse the process and try again.
-	for round := 1; round <= rounds; round++ {
-		eft.rollback()
-		eft.checkSaveAndRestore(estimateHistory[len(estimateHistory)-round-1])
+	for _, test := range invalid {
+		if _, err := RestoreFeeEstimator(test.state); err == nil {
+			t.Errorf("%s: RestoreFeeEstimator did not fail", test.name)
+		}
 	}
 }
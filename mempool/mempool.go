@@ -5,6 +5,7 @@
 package mempool
 
 import (
+	"bytes"
 	"container/list"
 	"fmt"
 	"math"
@@ -48,6 +49,12 @@ const (
 	// can be evicted from the mempool when accepting a transaction
 	// replacement.
 	MaxReplacementEvictions = 100
+
+	// rollingFeeHalfLife is the time it takes for the rolling minimum fee
+	// to halve once transactions are no longer being evicted from a full
+	// pool.  It is shortened while the pool is well below its size limit
+	// so the minimum fee falls back to the relay fee more quickly.
+	rollingFeeHalfLife = time.Hour * 12
 )
 
 // Tag represents an identifier to use for tagging orphan transactions.  The
@@ -55,6 +62,52 @@ const (
 // so that orphans can be identified by which peer first relayed them.
 type Tag uint64
 
+// RemovalReason describes why a transaction was removed from the memory pool.
+type RemovalReason int
+
+// These constants define the reasons a transaction can be removed from the
+// memory pool.
+const (
+	// RemovedConfirmed indicates the transaction was included in a block
+	// connected to the main chain.
+	RemovedConfirmed RemovalReason = iota
+
+	// RemovedConflicted indicates the transaction, or one of its
+	// ancestors, spends an output which was spent by a transaction in a
+	// block connected to the main chain.
+	RemovedConflicted
+
+	// RemovedReplaced indicates the transaction, or one of its ancestors,
+	// was replaced by a transaction paying a higher fee.
+	RemovedReplaced
+
+	// RemovedEvicted indicates the transaction was evicted to keep the
+	// pool within its size limit or otherwise removed by policy.
+	RemovedEvicted
+
+	// RemovedReorg indicates the transaction is no longer valid after a
+	// block which included it was disconnected from the main chain.
+	RemovedReorg
+)
+
+// removalReasonStrings is a map of removal reasons back to their constant
+// names for pretty printing.
+var removalReasonStrings = map[RemovalReason]string{
+	RemovedConfirmed:  "confirmed",
+	RemovedConflicted: "conflicted",
+	RemovedReplaced:   "replaced",
+	RemovedEvicted:    "evicted",
+	RemovedReorg:      "reorg",
+}
+
+// String returns the RemovalReason as a human-readable name.
+func (r RemovalReason) String() string {
+	if s, ok := removalReasonStrings[r]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown RemovalReason (%d)", int(r))
+}
+
 // Config is a descriptor containing the memory pool configuration.
 type Config struct {
 	// Policy defines the various mempool configuration options related
@@ -103,6 +156,14 @@ type Config struct {
 	// FeeEstimatator provides a feeEstimator. If it is not nil, the mempool
 	// records all new transactions it observes into the feeEstimator.
 	FeeEstimator *FeeEstimator
+
+	// OnTxRemoved defines an optional function which is invoked with each
+	// transaction removed from the main pool along with the reason it was
+	// removed.  Transactions which are removed because one of their
+	// ancestors was removed are reported with the same reason.  It is
+	// called with the mempool lock held, so it must not call back into the
+	// pool.
+	OnTxRemoved func(tx *bronutil.Tx, reason RemovalReason)
 }
 
 // Policy houses the policy (configuration parameters) which is used to
@@ -148,6 +209,12 @@ type Policy struct {
 	// transactions using the Replace-By-Fee (RBF) signaling policy into
 	// the mempool.
 	RejectReplacement bool
+
+	// MaxPoolSize is the maximum total serialized size in bytes of the
+	// transactions in the main pool.  Once it is exceeded, the packages
+	// with the lowest descendant fee rate are evicted.  A value of zero
+	// disables the limit.
+	MaxPoolSize int64
 }
 
 // TxDesc is a descriptor containing a transaction in the mempool along with
@@ -158,6 +225,44 @@ type TxDesc struct {
 	// StartingPriority is the priority of the transaction when it was added
 	// to the pool.
 	StartingPriority float64
+
+	// FeeDelta is the amount in Bronees added to the fee of the transaction
+	// when its fee rate is compared against other transactions in the pool
+	// to decide which ones to evict.  It is preserved when the pool is
+	// saved and reloaded.
+	FeeDelta int64
+
+	// descendantFee and descendantSize are the total fee and virtual size
+	// of the package made up of the transaction and all of its descendants
+	// in the pool.  They are kept up to date as transactions are added and
+	// removed so the packages to evict when the pool is full can be found
+	// without determining the descendants of every transaction.
+	descendantFee  int64
+	descendantSize int64
+}
+
+// modifiedFee returns the fee of the transaction along with its fee delta.
+func (txD *TxDesc) modifiedFee() int64 {
+	return txD.Fee + txD.FeeDelta
+}
+
+// packageTotals is the total fee and virtual size of a package of transactions.
+type packageTotals struct {
+	fee  int64
+	size int64
+}
+
+// feeRate returns the fee rate of the package in Bronees/kB.
+func (p packageTotals) feeRate() float64 {
+	return float64(p.fee) * 1000 / float64(p.size)
+}
+
+// trimEviction is a package selected for eviction to keep the pool within its
+// size limit.  It is identified by the transaction at its root, and rate is the
+// fee rate of the package when it was selected.
+type trimEviction struct {
+	txD  *TxDesc
+	rate float64
 }
 
 // orphanTx is normal transaction that references an ancestor transaction
@@ -184,6 +289,14 @@ type TxPool struct {
 	outpoints     map[wire.OutPoint]*bronutil.Tx
 	pennyTotal    float64 // exponentially decaying total for penny spends.
 	lastPennyUnix int64   // unix time of last ``penny spend''
+	poolSize      int64   // total serialized size of the pool transactions
+
+	// rollingMinFee is the fee rate in Bronees/kB transactions must pay
+	// after others have been evicted to keep the pool within its size
+	// limit.  It decays over time and is only brought up to date, as of
+	// lastRollingFeeUpdate, when it is accessed.
+	rollingMinFee        float64
+	lastRollingFeeUpdate time.Time
 
 	// nextExpireScan is the time after which the orphan pool will be
 	// scanned in order to evict orphans.  This is NOT a hard deadline as
@@ -466,14 +579,16 @@ func (mp *TxPool) HaveTransaction(hash *chainhash.Hash) bool {
 // RemoveTransaction.  See the comment for RemoveTransaction for more details.
 //
 // This function MUST be called with the mempool lock held (for writes).
-func (mp *TxPool) removeTransaction(tx *bronutil.Tx, removeRedeemers bool) {
+func (mp *TxPool) removeTransaction(tx *bronutil.Tx, removeRedeemers bool,
+	reason RemovalReason) {
+
 	txHash := tx.Hash()
 	if removeRedeemers {
 		// Remove any transactions which rely on this one.
 		for i := uint32(0); i < uint32(len(tx.MsgTx().TxOut)); i++ {
 			prevOut := wire.OutPoint{Hash: *txHash, Index: i}
 			if txRedeemer, exists := mp.outpoints[prevOut]; exists {
-				mp.removeTransaction(txRedeemer, true)
+				mp.removeTransaction(txRedeemer, true, reason)
 			}
 		}
 	}
@@ -486,25 +601,58 @@ func (mp *TxPool) removeTransaction(tx *bronutil.Tx, removeRedeemers bool) {
 			mp.cfg.AddrIndex.RemoveUnconfirmedTx(txHash)
 		}
 
+		// The ancestors of the transaction are determined before it is
+		// removed so their descendant totals can be updated afterwards.
+		ancestors := mp.txAncestors(tx, nil)
+		hasDescendants := mp.hasDescendants(tx)
+
 		// Mark the referenced outpoints as unspent by the pool.
 		for _, txIn := range txDesc.Tx.MsgTx().TxIn {
 			delete(mp.outpoints, txIn.PreviousOutPoint)
 		}
 		delete(mp.pool, *txHash)
+		mp.poolSize -= int64(tx.MsgTx().SerializeSize())
 		atomic.StoreInt64(&mp.lastUpdated, time.Now().Unix())
+
+		// Take the transaction out of the descendant totals of its
+		// ancestors.  When its descendants are left in the pool, the
+		// ancestors might no longer be connected to them, so their
+		// totals are recomputed instead.
+		for hash := range ancestors {
+			ancestor := mp.pool[hash]
+			if hasDescendants {
+				mp.resetDescendantTotals(ancestor)
+				continue
+			}
+			ancestor.descendantFee -= txDesc.modifiedFee()
+			ancestor.descendantSize -= GetTxVirtualSize(tx)
+		}
+
+		// Transactions which are not mined count against the fee
+		// rate they paid for fee estimation.
+		if mp.cfg.FeeEstimator != nil && reason != RemovedConfirmed {
+			mp.cfg.FeeEstimator.RemoveTransaction(txHash)
+		}
+
+		if mp.cfg.OnTxRemoved != nil {
+			mp.cfg.OnTxRemoved(tx, reason)
+		}
 	}
 }
 
 // RemoveTransaction removes the passed transaction from the mempool. When the
 // removeRedeemers flag is set, any transactions that redeem outputs from the
 // removed transaction will also be removed recursively from the mempool, as
-// they would otherwise become orphans.
+// they would otherwise become orphans.  The reason is reported to the
+// OnTxRemoved callback for each removed transaction.
 //
 // This function is safe for concurrent access.
-func (mp *TxPool) RemoveTransaction(tx *bronutil.Tx, removeRedeemers bool) {
+func (mp *TxPool) RemoveTransaction(tx *bronutil.Tx, removeRedeemers bool,
+	reason RemovalReason) {
+
 	// Protect concurrent access.
 	mp.mtx.Lock()
-	mp.removeTransaction(tx, removeRedeemers)
+	mp.removeTransaction(tx, removeRedeemers, reason)
 	mp.mtx.Unlock()
 }
 
@@ -521,7 +669,8 @@ func (mp *TxPool) RemoveDoubleSpends(tx *bronutil.Tx) {
 	for _, txIn := range tx.MsgTx().TxIn {
 		if txRedeemer, ok := mp.outpoints[txIn.PreviousOutPoint]; ok {
 			if !txRedeemer.Hash().IsEqual(tx.Hash()) {
-				mp.removeTransaction(txRedeemer, true)
+				mp.removeTransaction(txRedeemer, true,
+					RemovedConflicted)
 			}
 		}
 	}
@@ -551,8 +700,25 @@ func (mp *TxPool) addTransaction(utxoView *blockchain.UtxoViewpoint, tx *bronuti
 	for _, txIn := range tx.MsgTx().TxIn {
 		mp.outpoints[txIn.PreviousOutPoint] = tx
 	}
+	mp.poolSize += int64(tx.MsgTx().SerializeSize())
 	atomic.StoreInt64(&mp.lastUpdated, time.Now().Unix())
 
+	// Add the transaction to the descendant totals of its ancestors.  A
+	// transaction added back from a disconnected block may already have
+	// descendants in the pool, which might be counted by the ancestors
+	// through other transactions, so their totals are recomputed instead.
+	mp.resetDescendantTotals(txD)
+	hasDescendants := mp.hasDescendants(tx)
+	for hash := range mp.txAncestors(tx, nil) {
+		ancestor := mp.pool[hash]
+		if hasDescendants {
+			mp.resetDescendantTotals(ancestor)
+			continue
+		}
+		ancestor.descendantFee += fee
+		ancestor.descendantSize += GetTxVirtualSize(tx)
+	}
+
 	// Add unconfirmed address index entries associated with the transaction
 	// if enabled.
 	if mp.cfg.AddrIndex != nil {
@@ -567,6 +733,212 @@ func (mp *TxPool) addTransaction(utxoView *blockchain.UtxoViewpoint, tx *bronuti
 	return txD
 }
 
+// hasDescendants returns whether any of the outputs of the passed transaction
+// are spent by transactions in the pool.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) hasDescendants(tx *bronutil.Tx) bool {
+	op := wire.OutPoint{Hash: *tx.Hash()}
+	for i := range tx.MsgTx().TxOut {
+		op.Index = uint32(i)
+		if _, ok := mp.outpoints[op]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// resetDescendantTotals recomputes the descendant totals of the passed pool
+// transaction from all of its descendants in the pool.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) resetDescendantTotals(txD *TxDesc) {
+	txD.descendantFee = txD.modifiedFee()
+	txD.descendantSize = GetTxVirtualSize(txD.Tx)
+	for hash, descendant := range mp.txDescendants(txD.Tx, nil) {
+		txD.descendantFee += mp.pool[hash].modifiedFee()
+		txD.descendantSize += GetTxVirtualSize(descendant)
+	}
+}
+
+// setFeeDelta sets the fee delta of the passed pool transaction and updates the
+// descendant totals of it and its ancestors accordingly.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) setFeeDelta(txD *TxDesc, feeDelta int64) {
+	change := feeDelta - txD.FeeDelta
+	txD.FeeDelta = feeDelta
+	txD.descendantFee += change
+	for hash := range mp.txAncestors(txD.Tx, nil) {
+		mp.pool[hash].descendantFee += change
+	}
+}
+
+// planTrim returns the packages to evict, in order, to bring the total size of
+// the pool within the configured limit.  The package with the lowest fee rate
+// is evicted first, and a package is made up of a transaction and all of its
+// descendants so evicting one never leaves transactions with missing parents
+// behind.
+//
+// The plan is made as if the passed removed transactions, which must include
+// all of their descendants, were no longer in the pool and the passed new
+// transaction, when not nil, had been added to it.  The returned bool reports
+// whether the new transaction would be evicted, in which case the plan ends
+// there.  Ties are broken by hash, so the plan does not change once those
+// changes are actually made.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) planTrim(newTxD *TxDesc, removed map[chainhash.Hash]*bronutil.Tx) ([]trimEviction, bool) {
+	maxSize := mp.cfg.Policy.MaxPoolSize
+	if maxSize <= 0 {
+		return nil, false
+	}
+
+	// The descendant totals of the transactions in the pool are left
+	// untouched, so the ones which change as the removal of transactions
+	// is simulated are tracked separately.
+	poolSize := mp.poolSize
+	gone := make(map[chainhash.Hash]struct{}, len(removed))
+	changed := make(map[chainhash.Hash]packageTotals)
+	totals := func(txD *TxDesc) packageTotals {
+		if t, ok := changed[*txD.Tx.Hash()]; ok {
+			return t
+		}
+		return packageTotals{txD.descendantFee, txD.descendantSize}
+	}
+	remove := func(txns map[chainhash.Hash]*bronutil.Tx) {
+		for hash := range txns {
+			gone[hash] = struct{}{}
+		}
+		for hash, tx := range txns {
+			poolSize -= int64(tx.MsgTx().SerializeSize())
+			for ancestorHash := range mp.txAncestors(tx, nil) {
+				if _, ok := gone[ancestorHash]; ok {
+					continue
+				}
+				t := totals(mp.pool[ancestorHash])
+				t.fee -= mp.pool[hash].modifiedFee()
+				t.size -= GetTxVirtualSize(tx)
+				changed[ancestorHash] = t
+			}
+		}
+	}
+	remove(removed)
+
+	var newAncestors map[chainhash.Hash]*bronutil.Tx
+	var newTotals packageTotals
+	if newTxD != nil {
+		newTotals = packageTotals{newTxD.Fee, GetTxVirtualSize(newTxD.Tx)}
+		poolSize += int64(newTxD.Tx.MsgTx().SerializeSize())
+		newAncestors = mp.txAncestors(newTxD.Tx, nil)
+		for hash := range newAncestors {
+			t := totals(mp.pool[hash])
+			t.fee += newTotals.fee
+			t.size += newTotals.size
+			changed[hash] = t
+		}
+	}
+
+	var plan []trimEviction
+	for poolSize > maxSize {
+		var worst *TxDesc
+		var worstRate float64
+		consider := func(txD *TxDesc, t packageTotals) {
+			rate := t.feeRate()
+			if worst == nil || rate < worstRate || (rate == worstRate &&
+				bytes.Compare(txD.Tx.Hash()[:], worst.Tx.Hash()[:]) < 0) {
+
+				worst, worstRate = txD, rate
+			}
+		}
+		for hash, txD := range mp.pool {
+			if _, ok := gone[hash]; !ok {
+				consider(txD, totals(txD))
+			}
+		}
+		if newTxD != nil {
+			consider(newTxD, newTotals)
+		}
+		if worst == nil {
+			break
+		}
+
+		// The new transaction is evicted along with the package of any
+		// of its ancestors.
+		_, isNewAncestor := newAncestors[*worst.Tx.Hash()]
+		if worst == newTxD || isNewAncestor {
+			return plan, true
+		}
+
+		pkg := map[chainhash.Hash]*bronutil.Tx{*worst.Tx.Hash(): worst.Tx}
+		for hash, descendant := range mp.txDescendants(worst.Tx, nil) {
+			if _, ok := gone[hash]; !ok {
+				pkg[hash] = descendant
+			}
+		}
+		plan = append(plan, trimEviction{txD: worst, rate: worstRate})
+		remove(pkg)
+	}
+
+	return plan, false
+}
+
+// trimToSize evicts the packages with the lowest descendant fee rate until the
+// total size of the pool is within the configured limit as determined by
+// planTrim.  The rolling minimum fee is raised above the fee rate of each
+// evicted package so that transactions which would be evicted again right away
+// are rejected instead.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) trimToSize() {
+	plan, _ := mp.planTrim(nil, nil)
+	for _, eviction := range plan {
+		log.Debugf("Evicting transaction %v and its descendants "+
+			"(fee_rate=%.0f sat/kb) since the pool size %d exceeds "+
+			"the limit of %d bytes", eviction.txD.Tx.Hash(),
+			eviction.rate, mp.poolSize, mp.cfg.Policy.MaxPoolSize)
+		mp.removeTransaction(eviction.txD.Tx, true, RemovedEvicted)
+
+		minFee := eviction.rate + float64(mp.cfg.Policy.MinRelayTxFee)
+		if minFee > mp.decayRollingMinFee() {
+			mp.rollingMinFee = minFee
+			mp.lastRollingFeeUpdate = time.Now()
+		}
+	}
+}
+
+// decayRollingMinFee brings the rolling minimum fee up to date by applying the
+// exponential decay since it was last updated and returns it.  The decay speeds
+// up while the pool is below half and a quarter of its size limit, and the fee
+// is reset once it falls below half the minimum relay fee.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) decayRollingMinFee() float64 {
+	if mp.rollingMinFee == 0 {
+		return 0
+	}
+
+	halfLife := rollingFeeHalfLife
+	if maxSize := mp.cfg.Policy.MaxPoolSize; maxSize > 0 {
+		switch {
+		case mp.poolSize < maxSize/4:
+			halfLife /= 4
+		case mp.poolSize < maxSize/2:
+			halfLife /= 2
+		}
+	}
+
+	now := time.Now()
+	elapsed := now.Sub(mp.lastRollingFeeUpdate)
+	mp.rollingMinFee /= math.Pow(2, elapsed.Seconds()/halfLife.Seconds())
+	mp.lastRollingFeeUpdate = now
+	if mp.rollingMinFee < float64(mp.cfg.Policy.MinRelayTxFee)/2 {
+		mp.rollingMinFee = 0
+	}
+
+	return mp.rollingMinFee
+}
+
 // checkPoolDoubleSpend checks whether or not the passed transaction is
 // attempting to spend coins already spent by other transactions in the pool.
 // If it does, we'll check whether each of those transactions are signaling for
@@ -919,12 +1291,35 @@ func (mp *TxPool) validateReplacement(tx *bronutil.Tx,
 	return conflicts, nil
 }
 
-// maybeAcceptTransaction is the internal function which implements the public
-// MaybeAcceptTransaction.  See the comment for MaybeAcceptTransaction for
-// more details.
+// txValidation holds the results of validateTransaction which are needed to add
+// a valid transaction to the pool.
+type txValidation struct {
+	utxoView   *blockchain.UtxoViewpoint
+	bestHeight int32
+	fee        int64
+	vsize      int64
+	conflicts  map[chainhash.Hash]*bronutil.Tx
+}
+
+// testPackage tracks the transactions passed to TestMempoolAccept which would
+// be accepted so far, so that the transactions tested after them may spend
+// their outputs.
+type testPackage struct {
+	txns  map[chainhash.Hash]*bronutil.Tx
+	spent map[wire.OutPoint]*bronutil.Tx
+}
+
+// validateTransaction performs all of the checks required for the passed
+// transaction to be accepted into the pool without modifying the pool, aside
+// from the penny-flooding rate limiter when rateLimit is set.  When the
+// transaction is an orphan, its missing parents are returned instead.
+//
+// The optional package holds transactions which are treated as if they were
+// in the pool, so the transaction may spend their outputs but not any of the
+// outputs they spend.
 //
 // This function MUST be called with the mempool lock held (for writes).
-func (mp *TxPool) maybeAcceptTransaction(tx *bronutil.Tx, isNew, rateLimit, rejectDupOrphans bool) ([]*chainhash.Hash, *TxDesc, error) {
+func (mp *TxPool) validateTransaction(tx *bronutil.Tx, isNew, rateLimit, rejectDupOrphans bool, pkg *testPackage) ([]*chainhash.Hash, *txValidation, error) {
 	txHash := tx.Hash()
 
 	// If a transaction has iwtness data, and segwit isn't active yet, If
@@ -980,12 +1375,20 @@ func (mp *TxPool) maybeAcceptTransaction(tx *bronutil.Tx, isNew, rateLimit, reje
 
 	medianTimePast := mp.cfg.MedianTimePast()
 
+	// Outputs paying to taproot scripts are only standard, and spends of
+	// them are only validated under the taproot rules, once the taproot
+	// soft-fork is active.
+	taprootActive, err := mp.cfg.IsDeploymentActive(chaincfg.DeploymentTaproot)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Don't allow non-standard transactions if the network parameters
 	// forbid their acceptance.
 	if !mp.cfg.Policy.AcceptNonStd {
 		err = checkTransactionStandard(tx, nextBlockHeight,
 			medianTimePast, mp.cfg.Policy.MinRelayTxFee,
-			mp.cfg.Policy.MaxTxVersion)
+			mp.cfg.Policy.MaxTxVersion, taprootActive)
 		if err != nil {
 			// Attempt to extract a reject code from the error so
 			// it can be retained.  When not possible, fall back to
@@ -1014,6 +1417,21 @@ func (mp *TxPool) maybeAcceptTransaction(tx *bronutil.Tx, isNew, rateLimit, reje
 		return nil, nil, err
 	}
 
+	// Likewise, the transaction may not spend the same outputs as any of
+	// the other transactions in the package being tested.
+	if pkg != nil {
+		for _, txIn := range tx.MsgTx().TxIn {
+			spender, ok := pkg.spent[txIn.PreviousOutPoint]
+			if !ok {
+				continue
+			}
+			str := fmt.Sprintf("output %v already spent by "+
+				"transaction %v in the package",
+				txIn.PreviousOutPoint, spender.Hash())
+			return nil, nil, txRuleError(wire.RejectDuplicate, str)
+		}
+	}
+
 	// Fetch all of the unspent transaction outputs referenced by the inputs
 	// to this transaction.  This function also attempts to fetch the
 	// transaction itself to be used for detecting a duplicate transaction
@@ -1026,6 +1444,22 @@ func (mp *TxPool) maybeAcceptTransaction(tx *bronutil.Tx, isNew, rateLimit, reje
 		return nil, nil, err
 	}
 
+	// Outputs of the transactions in the package being tested are treated
+	// the same as those of transactions in the pool.
+	if pkg != nil {
+		for _, txIn := range tx.MsgTx().TxIn {
+			prevOut := &txIn.PreviousOutPoint
+			entry := utxoView.LookupEntry(*prevOut)
+			if entry != nil && !entry.IsSpent() {
+				continue
+			}
+			if parent, exists := pkg.txns[prevOut.Hash]; exists {
+				utxoView.AddTxOut(parent, prevOut.Index,
+					mining.UnminedHeight)
+			}
+		}
+	}
+
 	// Don't allow the transaction if it exists in the main chain and is not
 	// not already fully spent.
 	prevOut := wire.OutPoint{Hash: *txHash}
@@ -1164,6 +1598,21 @@ func (mp *TxPool) maybeAcceptTransaction(tx *bronutil.Tx, isNew, rateLimit, reje
 		}
 	}
 
+	// Once transactions have been evicted to keep the pool within its size
+	// limit, new transactions must also pay the rolling minimum fee since
+	// they would otherwise be the next ones to be evicted.  Transactions
+	// which are being added back from disconnected blocks are exempted.
+	if rollingMinFee := mp.decayRollingMinFee(); isNew && rollingMinFee > 0 {
+		requiredFee := calcMinRequiredTxRelayFee(serializedSize,
+			bronutil.Amount(rollingMinFee))
+		if txFee < requiredFee {
+			str := fmt.Sprintf("transaction %v has %d fees which is "+
+				"under the mempool minimum fee of %d", txHash,
+				txFee, requiredFee)
+			return nil, nil, txRuleError(wire.RejectInsufficientFee, str)
+		}
+	}
+
 	// Free-to-relay transactions are rate limited here to prevent
 	// penny-flooding with tiny transactions as a form of attack.
 	if rateLimit && txFee < minFee {
@@ -1200,9 +1649,15 @@ func (mp *TxPool) maybeAcceptTransaction(tx *bronutil.Tx, isNew, rateLimit, reje
 
 	// Verify crypto signatures for each input and reject the transaction if
 	// any don't verify.
-	err = blockchain.ValidateTransactionScripts(tx, utxoView,
-		txscript.StandardVerifyFlags, mp.cfg.SigCache,
-		mp.cfg.HashCache)
+	scriptFlags := txscript.StandardVerifyFlags
+	if !taprootActive {
+		scriptFlags &^= txscript.ScriptVerifyTaproot |
+			txscript.ScriptVerifyDiscourageUpgradeableTaprootVersion |
+			txscript.ScriptVerifyDiscourageOpSuccess |
+			txscript.ScriptVerifyDiscourageUpgradeablePubkeyType
+	}
+	err = blockchain.ValidateTransactionScripts(tx, utxoView, scriptFlags,
+		mp.cfg.SigCache, mp.cfg.HashCache)
 	if err != nil {
 		if cerr, ok := err.(blockchain.RuleError); ok {
 			return nil, nil, chainRuleError(cerr)
@@ -1210,21 +1665,62 @@ func (mp *TxPool) maybeAcceptTransaction(tx *bronutil.Tx, isNew, rateLimit, reje
 		return nil, nil, err
 	}
 
+	return nil, &txValidation{
+		utxoView:   utxoView,
+		bestHeight: bestHeight,
+		fee:        txFee,
+		vsize:      serializedSize,
+		conflicts:  conflicts,
+	}, nil
+}
+
+// maybeAcceptTransaction is the internal function which implements the public
+// MaybeAcceptTransaction.  See the comment for MaybeAcceptTransaction for
+// more details.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) maybeAcceptTransaction(tx *bronutil.Tx, isNew, rateLimit, rejectDupOrphans bool) ([]*chainhash.Hash, *TxDesc, error) {
+	txHash := tx.Hash()
+	missingParents, v, err := mp.validateTransaction(tx, isNew, rateLimit,
+		rejectDupOrphans, nil)
+	if err != nil || len(missingParents) > 0 {
+		return missingParents, nil, err
+	}
+
+	// Reject the transaction if it would be evicted right away to keep the
+	// pool within its size limit.  This is determined before anything is
+	// removed from the pool, so any transactions it would replace are kept.
+	newTxD := &TxDesc{TxDesc: mining.TxDesc{Tx: tx, Fee: v.fee}}
+	if _, evicted := mp.planTrim(newTxD, v.conflicts); evicted {
+		str := fmt.Sprintf("transaction %v would be evicted since its "+
+			"fee rate is too low for the full mempool", txHash)
+		return nil, nil, txRuleError(wire.RejectInsufficientFee, str)
+	}
+
 	// Now that we've deemed the transaction as valid, we can add it to the
 	// mempool. If it ended up replacing any transactions, we'll remove them
 	// first.
-	for _, conflict := range conflicts {
+	for _, conflict := range v.conflicts {
 		log.Debugf("Replacing transaction %v (fee_rate=%v sat/kb) "+
 			"with %v (fee_rate=%v sat/kb)\n", conflict.Hash(),
 			mp.pool[*conflict.Hash()].FeePerKB, tx.Hash(),
-			txFee*1000/serializedSize)
+			v.fee*1000/v.vsize)
 
 		// The conflict set should already include the descendants for
 		// each one, so we don't need to remove the redeemers within
 		// this call as they'll be removed eventually.
-		mp.removeTransaction(conflict, false)
+		mp.removeTransaction(conflict, false, RemovedReplaced)
+	}
+	txD := mp.addTransaction(v.utxoView, tx, v.bestHeight, v.fee)
+
+	// Evict the packages with the lowest fee rate if the pool has grown
+	// beyond its size limit.  The new transaction is not among them as
+	// checked above.
+	if maxSize := mp.cfg.Policy.MaxPoolSize; maxSize > 0 &&
+		mp.poolSize > maxSize {
+
+		mp.trimToSize()
 	}
-	txD := mp.addTransaction(utxoView, tx, bestHeight, txFee)
 
 	log.Debugf("Accepted transaction %v (pool size: %v)", txHash,
 		len(mp.pool))
@@ -1252,6 +1748,73 @@ func (mp *TxPool) MaybeAcceptTransaction(tx *bronutil.Tx, isNew, rateLimit bool)
 	return hashes, txD, err
 }
 
+// TestAcceptResult describes whether a transaction passed to TestMempoolAccept
+// would be accepted into the pool.
+type TestAcceptResult struct {
+	// Tx is the tested transaction.
+	Tx *bronutil.Tx
+
+	// Vsize is the virtual size of the transaction.
+	Vsize int64
+
+	// Fee is the fee paid by the transaction in Bronees.  It is only set
+	// when the transaction would be accepted.
+	Fee int64
+
+	// Err is the reason the transaction would be rejected.  It is nil when
+	// the transaction would be accepted.
+	Err error
+}
+
+// TestMempoolAccept runs the checks MaybeAcceptTransaction performs for each of
+// the passed transactions, in order, without adding any of them to the pool.
+// A transaction may spend the outputs of the transactions before it which
+// would be accepted, so a chain of transactions can be tested before any of
+// them are submitted.  Transactions with missing inputs are rejected rather
+// than being treated as orphans, and the penny-flooding rate limiter is not
+// applied.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) TestMempoolAccept(txns []*bronutil.Tx) []*TestAcceptResult {
+	// The write lock is needed since the rolling minimum fee is decayed as
+	// part of the checks.
+	mp.mtx.Lock()
+	defer mp.mtx.Unlock()
+
+	pkg := &testPackage{
+		txns:  make(map[chainhash.Hash]*bronutil.Tx),
+		spent: make(map[wire.OutPoint]*bronutil.Tx),
+	}
+	results := make([]*TestAcceptResult, 0, len(txns))
+	for _, tx := range txns {
+		result := &TestAcceptResult{Tx: tx, Vsize: GetTxVirtualSize(tx)}
+		results = append(results, result)
+
+		missingParents, v, err := mp.validateTransaction(tx, true, false,
+			true, pkg)
+		switch {
+		case err != nil:
+			result.Err = err
+			continue
+
+		case len(missingParents) > 0:
+			str := fmt.Sprintf("transaction %v references outputs "+
+				"of unknown or fully-spent transaction %v",
+				tx.Hash(), missingParents[0])
+			result.Err = txRuleError(wire.RejectDuplicate, str)
+			continue
+		}
+
+		result.Fee = v.fee
+		pkg.txns[*tx.Hash()] = tx
+		for _, txIn := range tx.MsgTx().TxIn {
+			pkg.spent[txIn.PreviousOutPoint] = tx
+		}
+	}
+
+	return results
+}
+
 // processOrphans is the internal function which implements the public
 // ProcessOrphans.  See the comment for ProcessOrphans for more details.
 //
@@ -1531,6 +2094,168 @@ func (mp *TxPool) RawMempoolVerbose() map[string]*bronjson.GetRawMempoolVerboseR
 	return result
 }
 
+// mempoolEntry returns the passed pool transaction as a fully populated
+// bronjson result, including the totals of its unconfirmed ancestors and
+// descendants.  Like Brocoin Core, the totals include the transaction itself,
+// and the sizes are virtual sizes.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) mempoolEntry(txD *TxDesc) *bronjson.GetMempoolEntryResult {
+	// Calculate the current priority based on the inputs to the
+	// transaction.  Use zero if one or more of the input transactions
+	// can't be found for some reason.
+	tx := txD.Tx
+	var currentPriority float64
+	utxos, err := mp.fetchInputUtxos(tx)
+	if err == nil {
+		currentPriority = mining.CalcPriority(tx.MsgTx(), utxos,
+			mp.cfg.BestHeight()+1)
+	}
+
+	vsize := GetTxVirtualSize(tx)
+	modifiedFee := txD.Fee + txD.FeeDelta
+	entry := &bronjson.GetMempoolEntryResult{
+		Size:              int32(tx.MsgTx().SerializeSize()),
+		Vsize:             int32(vsize),
+		Weight:            int32(blockchain.GetTransactionWeight(tx)),
+		Fee:               bronutil.Amount(txD.Fee).ToBRON(),
+		ModifiedFee:       bronutil.Amount(modifiedFee).ToBRON(),
+		Time:              txD.Added.Unix(),
+		Height:            int64(txD.Height),
+		StartingPriority:  txD.StartingPriority,
+		CurrentPriority:   currentPriority,
+		Depends:           make([]string, 0),
+		SpentBy:           make([]string, 0),
+		BIP125Replaceable: mp.signalsReplacement(tx, nil),
+	}
+
+	// Sum up the sizes and modified fees of the ancestors and descendants
+	// along with the transaction itself.
+	sumPackage := func(txns map[chainhash.Hash]*bronutil.Tx) (int64, int64, int64) {
+		count, size, fees := int64(1), vsize, modifiedFee
+		for hash, pkgTx := range txns {
+			count++
+			size += GetTxVirtualSize(pkgTx)
+			if desc, ok := mp.pool[hash]; ok {
+				fees += desc.Fee + desc.FeeDelta
+			}
+		}
+		return count, size, fees
+	}
+	var fees int64
+	entry.AncestorCount, entry.AncestorSize, fees = sumPackage(
+		mp.txAncestors(tx, nil))
+	entry.AncestorFees = bronutil.Amount(fees).ToBRON()
+	entry.DescendantCount, entry.DescendantSize, fees = sumPackage(
+		mp.txDescendants(tx, nil))
+	entry.DescendantFees = bronutil.Amount(fees).ToBRON()
+
+	// The direct parents and children of the transaction are the pool
+	// transactions which create its inputs and spend its outputs.
+	seen := make(map[chainhash.Hash]struct{})
+	for _, txIn := range tx.MsgTx().TxIn {
+		hash := txIn.PreviousOutPoint.Hash
+		if _, ok := seen[hash]; ok || !mp.haveTransaction(&hash) {
+			continue
+		}
+		seen[hash] = struct{}{}
+		entry.Depends = append(entry.Depends, hash.String())
+	}
+	op := wire.OutPoint{Hash: *tx.Hash()}
+	for i := range tx.MsgTx().TxOut {
+		op.Index = uint32(i)
+		child, ok := mp.outpoints[op]
+		if !ok {
+			continue
+		}
+		if _, ok := seen[*child.Hash()]; ok {
+			continue
+		}
+		seen[*child.Hash()] = struct{}{}
+		entry.SpentBy = append(entry.SpentBy, child.Hash().String())
+	}
+
+	return entry
+}
+
+// MempoolEntry returns the transaction in the main pool with the passed hash
+// as a fully populated bronjson result.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) MempoolEntry(txHash *chainhash.Hash) (*bronjson.GetMempoolEntryResult, error) {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	txD, exists := mp.pool[*txHash]
+	if !exists {
+		return nil, fmt.Errorf("transaction is not in the pool")
+	}
+	return mp.mempoolEntry(txD), nil
+}
+
+// MempoolAncestors returns the unconfirmed ancestors in the main pool of the
+// transaction with the passed hash as fully populated bronjson results keyed
+// by transaction hash.  The transaction itself must be in the main pool.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) MempoolAncestors(txHash *chainhash.Hash) (map[string]*bronjson.GetMempoolEntryResult, error) {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	txD, exists := mp.pool[*txHash]
+	if !exists {
+		return nil, fmt.Errorf("transaction is not in the pool")
+	}
+	return mp.mempoolEntries(mp.txAncestors(txD.Tx, nil)), nil
+}
+
+// MempoolDescendants returns the unconfirmed descendants in the main pool of
+// the transaction with the passed hash as fully populated bronjson results
+// keyed by transaction hash.  The transaction itself must be in the main pool.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) MempoolDescendants(txHash *chainhash.Hash) (map[string]*bronjson.GetMempoolEntryResult, error) {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	txD, exists := mp.pool[*txHash]
+	if !exists {
+		return nil, fmt.Errorf("transaction is not in the pool")
+	}
+	return mp.mempoolEntries(mp.txDescendants(txD.Tx, nil)), nil
+}
+
+// mempoolEntries returns the passed pool transactions as fully populated
+// bronjson results keyed by transaction hash.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) mempoolEntries(txns map[chainhash.Hash]*bronutil.Tx) map[string]*bronjson.GetMempoolEntryResult {
+	entries := make(map[string]*bronjson.GetMempoolEntryResult, len(txns))
+	for hash := range txns {
+		if txD, ok := mp.pool[hash]; ok {
+			entries[hash.String()] = mp.mempoolEntry(txD)
+		}
+	}
+	return entries
+}
+
+// MinFee returns the minimum fee rate in Bronees/kB transactions must pay to be
+// accepted into the pool.  It is the greater of the minimum relay fee and the
+// rolling minimum fee which is raised whenever transactions are evicted to keep
+// the pool within its size limit.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) MinFee() bronutil.Amount {
+	mp.mtx.Lock()
+	minFee := bronutil.Amount(mp.decayRollingMinFee())
+	mp.mtx.Unlock()
+
+	if minFee < mp.cfg.Policy.MinRelayTxFee {
+		return mp.cfg.Policy.MinRelayTxFee
+	}
+	return minFee
+}
+
 // LastUpdated returns the last time a transaction was added to or removed from
 // the main pool.  It does not include the orphan pool.
 //
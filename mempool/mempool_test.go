@@ -106,6 +106,12 @@ func (s *fakeChain) CalcSequenceLock(tx *bronutil.Tx,
 	}, nil
 }
 
+// IsDeploymentActive returns whether the passed deployment is active for the
+// fake chain instance.  All deployments are considered active.
+func (s *fakeChain) IsDeploymentActive(deploymentID uint32) (bool, error) {
+	return true, nil
+}
+
 // spendableOutput is a convenience type that houses a particular utxo and the
 // amount associated with it.
 type spendableOutput struct {
@@ -325,13 +331,14 @@ func newPoolHarness(chainParams *chaincfg.Params) (*poolHarness, []spendableOutp
 				MinRelayTxFee:        1000, // 1 Bronees per byte
 				MaxTxVersion:         1,
 			},
-			ChainParams:      chainParams,
-			FetchUtxoView:    chain.FetchUtxoView,
-			BestHeight:       chain.BestHeight,
-			MedianTimePast:   chain.MedianTimePast,
-			CalcSequenceLock: chain.CalcSequenceLock,
-			SigCache:         nil,
-			AddrIndex:        nil,
+			ChainParams:        chainParams,
+			FetchUtxoView:      chain.FetchUtxoView,
+			BestHeight:         chain.BestHeight,
+			MedianTimePast:     chain.MedianTimePast,
+			CalcSequenceLock:   chain.CalcSequenceLock,
+			IsDeploymentActive: chain.IsDeploymentActive,
+			SigCache:           nil,
+			AddrIndex:          nil,
 		}),
 	}
 
@@ -1452,6 +1459,117 @@ func TestAncestorsDescendants(t *testing.T) {
 	}
 }
 
+// TestMempoolEntry ensures the entries returned for pool transactions describe
+// their relationships with the other pool transactions.
+func TestMempoolEntry(t *testing.T) {
+	t.Parallel()
+
+	harness, outputs, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+	txPool := harness.txPool
+
+	// Create a transaction A with two children B and C, where only B
+	// signals replacement.
+	a := ctx.addSignedTx(outputs[:1], 2, 1000, false, false)
+	b := ctx.addSignedTx([]spendableOutput{
+		txOutToSpendableOut(a, 0),
+	}, 1, 2000, true, false)
+	c := ctx.addSignedTx([]spendableOutput{
+		txOutToSpendableOut(a, 1),
+	}, 1, 3000, false, false)
+
+	entry, err := txPool.MempoolEntry(a.Hash())
+	if err != nil {
+		t.Fatalf("MempoolEntry: unexpected error %v", err)
+	}
+	wantSize := GetTxVirtualSize(a) + GetTxVirtualSize(b) +
+		GetTxVirtualSize(c)
+	switch {
+	case entry.AncestorCount != 1 || entry.DescendantCount != 3:
+		t.Fatalf("MempoolEntry: wrong counts - got %d ancestors and "+
+			"%d descendants, want 1 and 3", entry.AncestorCount,
+			entry.DescendantCount)
+
+	case entry.DescendantSize != wantSize:
+		t.Fatalf("MempoolEntry: wrong descendant size - got %d, "+
+			"want %d", entry.DescendantSize, wantSize)
+
+	case entry.DescendantFees != bronutil.Amount(6000).ToBRON():
+		t.Fatalf("MempoolEntry: wrong descendant fees - got %v, "+
+			"want %v", entry.DescendantFees,
+			bronutil.Amount(6000).ToBRON())
+
+	case len(entry.Depends) != 0:
+		t.Fatalf("MempoolEntry: unexpected depends %v", entry.Depends)
+
+	case !reflect.DeepEqual(entry.SpentBy, []string{
+		b.Hash().String(), c.Hash().String(),
+	}):
+		t.Fatalf("MempoolEntry: wrong spentby %v", entry.SpentBy)
+
+	case entry.BIP125Replaceable:
+		t.Fatal("MempoolEntry: transaction A is not replaceable")
+	}
+
+	entry, err = txPool.MempoolEntry(b.Hash())
+	if err != nil {
+		t.Fatalf("MempoolEntry: unexpected error %v", err)
+	}
+	switch {
+	case entry.AncestorCount != 2 || entry.DescendantCount != 1:
+		t.Fatalf("MempoolEntry: wrong counts - got %d ancestors and "+
+			"%d descendants, want 2 and 1", entry.AncestorCount,
+			entry.DescendantCount)
+
+	case entry.AncestorFees != bronutil.Amount(3000).ToBRON():
+		t.Fatalf("MempoolEntry: wrong ancestor fees - got %v, "+
+			"want %v", entry.AncestorFees,
+			bronutil.Amount(3000).ToBRON())
+
+	case !reflect.DeepEqual(entry.Depends, []string{a.Hash().String()}):
+		t.Fatalf("MempoolEntry: wrong depends %v", entry.Depends)
+
+	case !entry.BIP125Replaceable:
+		t.Fatal("MempoolEntry: transaction B is replaceable")
+	}
+
+	// Ensure the ancestors and descendants are keyed by their hashes.
+	ancestors, err := txPool.MempoolAncestors(c.Hash())
+	if err != nil {
+		t.Fatalf("MempoolAncestors: unexpected error %v", err)
+	}
+	if _, ok := ancestors[a.Hash().String()]; !ok || len(ancestors) != 1 {
+		t.Fatalf("MempoolAncestors: wrong ancestors %v", ancestors)
+	}
+	descendants, err := txPool.MempoolDescendants(a.Hash())
+	if err != nil {
+		t.Fatalf("MempoolDescendants: unexpected error %v", err)
+	}
+	if len(descendants) != 2 {
+		t.Fatalf("MempoolDescendants: wrong descendants %v",
+			descendants)
+	}
+	for _, tx := range []*bronutil.Tx{b, c} {
+		if _, ok := descendants[tx.Hash().String()]; !ok {
+			t.Fatalf("MempoolDescendants: missing descendant %v",
+				tx.Hash())
+		}
+	}
+
+	// Transactions which are not in the pool must be rejected.
+	txPool.RemoveTransaction(c, false, RemovedEvicted)
+	if _, err := txPool.MempoolEntry(c.Hash()); err == nil {
+		t.Fatal("MempoolEntry: no error for transaction not in pool")
+	}
+	if _, err := txPool.MempoolDescendants(c.Hash()); err == nil {
+		t.Fatal("MempoolDescendants: no error for transaction not " +
+			"in pool")
+	}
+}
+
 // TestRBF tests the different cases required for a transaction to properly
 // replace its conflicts given that they all signal replacement.
 func TestRBF(t *testing.T) {
@@ -1810,3 +1928,291 @@ func TestRBF(t *testing.T) {
 		}
 	}
 }
+
+// TestPoolSizeLimit ensures the transactions with the lowest fee rate are
+// evicted once the pool exceeds its size limit, and that the rolling minimum
+// fee is raised afterwards and decays over time.
+func TestPoolSizeLimit(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+	txPool := harness.txPool
+	minRelayTxFee := txPool.cfg.Policy.MinRelayTxFee
+
+	coinbase := ctx.addCoinbaseTx(5)
+	a := ctx.addSignedTx([]spendableOutput{
+		txOutToSpendableOut(coinbase, 0),
+	}, 1, 1000, false, false)
+	b := ctx.addSignedTx([]spendableOutput{
+		txOutToSpendableOut(coinbase, 1),
+	}, 1, 5000, false, false)
+
+	// Limit the pool to less than the size of three transactions and
+	// ensure the one with the lowest fee rate is evicted when another one
+	// with a higher fee rate is added.
+	if got := txPool.MinFee(); got != minRelayTxFee {
+		t.Fatalf("MinFee: wrong fee before eviction - got %v, want %v",
+			got, minRelayTxFee)
+	}
+	txPool.cfg.Policy.MaxPoolSize = txPool.poolSize + 100
+	c := ctx.addSignedTx([]spendableOutput{
+		txOutToSpendableOut(coinbase, 2),
+	}, 1, 20000, false, false)
+	testPoolMembership(ctx, a, false, false)
+	testPoolMembership(ctx, b, false, true)
+	if txPool.poolSize > txPool.cfg.Policy.MaxPoolSize {
+		t.Fatalf("pool size %d exceeds the limit of %d bytes",
+			txPool.poolSize, txPool.cfg.Policy.MaxPoolSize)
+	}
+
+	// The rolling minimum fee must now exceed the fee rate of the evicted
+	// transaction, so another one paying the same fee is rejected.
+	aFeeRate := bronutil.Amount(1000 * 1000 / GetTxVirtualSize(a))
+	if got := txPool.MinFee(); got <= aFeeRate {
+		t.Fatalf("MinFee: wrong fee after eviction - got %v, want "+
+			"more than %v", got, aFeeRate)
+	}
+	d, err := harness.CreateSignedTx([]spendableOutput{
+		txOutToSpendableOut(coinbase, 3),
+	}, 1, 1000, false)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	_, err = txPool.ProcessTransaction(d, false, false, 0)
+	if code, _ := extractRejectCode(err); code != wire.RejectInsufficientFee {
+		t.Fatalf("ProcessTransaction: unexpected reject code -- got "+
+			"%v, want %v", code, wire.RejectInsufficientFee)
+	}
+	testPoolMembership(ctx, d, false, false)
+
+	// Ensure the rolling minimum fee decays back to the minimum relay fee
+	// once enough time has passed.
+	txPool.lastRollingFeeUpdate = time.Now().Add(-rollingFeeHalfLife * 4)
+	if got := txPool.MinFee(); got != minRelayTxFee {
+		t.Fatalf("MinFee: wrong fee after decay - got %v, want %v",
+			got, minRelayTxFee)
+	}
+
+	// Finally, ensure a transaction which has the lowest fee rate once
+	// added to the full pool is evicted right away and rejected.
+	e, err := harness.CreateSignedTx([]spendableOutput{
+		txOutToSpendableOut(coinbase, 4),
+	}, 1, 3000, false)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	_, err = txPool.ProcessTransaction(e, false, false, 0)
+	if code, _ := extractRejectCode(err); code != wire.RejectInsufficientFee {
+		t.Fatalf("ProcessTransaction: unexpected reject code -- got "+
+			"%v, want %v", code, wire.RejectInsufficientFee)
+	}
+	testPoolMembership(ctx, e, false, false)
+	testPoolMembership(ctx, b, false, true)
+	testPoolMembership(ctx, c, false, true)
+
+	// Add a transaction signaling replacement, which evicts the one with
+	// the lowest fee rate, and then ensure a replacement for it which
+	// would be evicted right away is rejected without removing it from
+	// the pool.
+	f := ctx.addSignedTx([]spendableOutput{
+		txOutToSpendableOut(coinbase, 3),
+	}, 1, 10000, true, false)
+	testPoolMembership(ctx, b, false, false)
+	txPool.cfg.Policy.MaxPoolSize = txPool.poolSize - 1
+	g, err := harness.CreateSignedTx([]spendableOutput{
+		txOutToSpendableOut(coinbase, 3),
+	}, 1, 15000, false)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	_, err = txPool.ProcessTransaction(g, false, false, 0)
+	if code, _ := extractRejectCode(err); code != wire.RejectInsufficientFee {
+		t.Fatalf("ProcessTransaction: unexpected reject code -- got "+
+			"%v, want %v", code, wire.RejectInsufficientFee)
+	}
+	testPoolMembership(ctx, g, false, false)
+	testPoolMembership(ctx, f, false, true)
+	testPoolMembership(ctx, c, false, true)
+}
+
+// TestTestMempoolAccept ensures transactions are tested against the pool
+// without being added to it, and that a transaction may spend the outputs of
+// the transactions tested before it.
+func TestTestMempoolAccept(t *testing.T) {
+	t.Parallel()
+
+	harness, outputs, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+	txPool := harness.txPool
+
+	// Create a transaction which is already in the pool, along with a
+	// parent and child which are not, a transaction which double spends
+	// the parent, and a child of a transaction which isn't tested.
+	coinbase := ctx.addCoinbaseTx(2)
+	inPool := ctx.addSignedTx(outputs, 1, 1000, false, false)
+	parent, err := harness.CreateSignedTx([]spendableOutput{
+		txOutToSpendableOut(coinbase, 0),
+	}, 1, 1000, false)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	child, err := harness.CreateSignedTx([]spendableOutput{
+		txOutToSpendableOut(parent, 0),
+	}, 1, 2000, false)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	doubleSpend, err := harness.CreateSignedTx([]spendableOutput{
+		txOutToSpendableOut(coinbase, 0),
+	}, 1, 5000, false)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	untested, err := harness.CreateSignedTx([]spendableOutput{
+		txOutToSpendableOut(coinbase, 1),
+	}, 1, 1000, false)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	orphan, err := harness.CreateSignedTx([]spendableOutput{
+		txOutToSpendableOut(untested, 0),
+	}, 1, 1000, false)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+
+	tests := []struct {
+		tx      *bronutil.Tx
+		allowed bool
+		fee     int64
+	}{
+		{inPool, false, 0},
+		{parent, true, 1000},
+		{child, true, 2000},
+		{doubleSpend, false, 0},
+		{orphan, false, 0},
+	}
+	txns := make([]*bronutil.Tx, 0, len(tests))
+	for _, test := range tests {
+		txns = append(txns, test.tx)
+	}
+	results := txPool.TestMempoolAccept(txns)
+	if len(results) != len(tests) {
+		t.Fatalf("TestMempoolAccept: got %d results, want %d",
+			len(results), len(tests))
+	}
+	for i, test := range tests {
+		result := results[i]
+		if result.Tx != test.tx {
+			t.Fatalf("TestMempoolAccept #%d: wrong transaction %v", i,
+				result.Tx.Hash())
+		}
+		if allowed := result.Err == nil; allowed != test.allowed {
+			t.Fatalf("TestMempoolAccept #%d: got allowed %v (%v), "+
+				"want %v", i, allowed, result.Err, test.allowed)
+		}
+		if result.Fee != test.fee {
+			t.Fatalf("TestMempoolAccept #%d: got fee %d, want %d", i,
+				result.Fee, test.fee)
+		}
+		if result.Vsize != GetTxVirtualSize(test.tx) {
+			t.Fatalf("TestMempoolAccept #%d: got vsize %d, want %d",
+				i, result.Vsize, GetTxVirtualSize(test.tx))
+		}
+	}
+
+	// Ensure none of the tested transactions were added to the pool.
+	if count := txPool.Count(); count != 1 {
+		t.Fatalf("TestMempoolAccept: pool has %d transactions, want 1",
+			count)
+	}
+	testPoolMembership(ctx, parent, false, false)
+	testPoolMembership(ctx, child, false, false)
+	testPoolMembership(ctx, orphan, false, false)
+}
+
+// TestRemovalNotifications ensures the OnTxRemoved callback is invoked with
+// the expected reason for each transaction removed from the pool, including
+// the descendants which are removed along with it.
+func TestRemovalNotifications(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+	txPool := harness.txPool
+
+	removed := make(map[chainhash.Hash]RemovalReason)
+	txPool.cfg.OnTxRemoved = func(tx *bronutil.Tx, reason RemovalReason) {
+		removed[*tx.Hash()] = reason
+	}
+	checkRemoved := func(want map[*bronutil.Tx]RemovalReason) {
+		t.Helper()
+		if len(removed) != len(want) {
+			t.Fatalf("OnTxRemoved: got %d removals, want %d",
+				len(removed), len(want))
+		}
+		for tx, reason := range want {
+			got, ok := removed[*tx.Hash()]
+			if !ok {
+				t.Fatalf("OnTxRemoved: missing removal of %v",
+					tx.Hash())
+			}
+			if got != reason {
+				t.Fatalf("OnTxRemoved: wrong reason for %v - got "+
+					"%v, want %v", tx.Hash(), got, reason)
+			}
+		}
+		removed = make(map[chainhash.Hash]RemovalReason)
+	}
+
+	coinbase := ctx.addCoinbaseTx(2)
+	parent := ctx.addSignedTx([]spendableOutput{
+		txOutToSpendableOut(coinbase, 0),
+	}, 1, 1000, false, false)
+	child := ctx.addSignedTx([]spendableOutput{
+		txOutToSpendableOut(parent, 0),
+	}, 1, 1000, false, false)
+	other := ctx.addSignedTx([]spendableOutput{
+		txOutToSpendableOut(coinbase, 1),
+	}, 1, 1000, false, false)
+
+	// Removing a transaction which is not in the pool must not be
+	// reported.
+	unknown, err := harness.CreateSignedTx([]spendableOutput{
+		txOutToSpendableOut(other, 0),
+	}, 1, 1000, false)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	txPool.RemoveTransaction(unknown, true, RemovedConfirmed)
+	checkRemoved(nil)
+
+	// A block containing a transaction which double spends the parent
+	// removes the parent along with its child as conflicted.
+	doubleSpend, err := harness.CreateSignedTx([]spendableOutput{
+		txOutToSpendableOut(coinbase, 0),
+	}, 1, 2000, false)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	txPool.RemoveDoubleSpends(doubleSpend)
+	checkRemoved(map[*bronutil.Tx]RemovalReason{
+		parent: RemovedConflicted,
+		child:  RemovedConflicted,
+	})
+
+	txPool.RemoveTransaction(other, false, RemovedConfirmed)
+	checkRemoved(map[*bronutil.Tx]RemovalReason{
+		other: RemovedConfirmed,
+	})
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/brsuite/brond/chaincfg/chainhash"
+	"github.com/brsuite/brond/wire"
+	"github.com/brsuite/bronutil"
+)
+
+const (
+	// persistSaveVersion is the version of the format used by Save.
+	persistSaveVersion = 1
+
+	// persistedTxExpiry is the maximum amount of time a saved transaction
+	// may have spent in the pool for it to be reloaded by Load.  Older
+	// transactions are unlikely to ever be mined.
+	persistedTxExpiry = time.Hour * 24 * 14
+)
+
+// LoadStats describes what happened to the transactions read by Load.
+type LoadStats struct {
+	// Loaded is the number of transactions added to the pool.
+	Loaded int
+
+	// Failed is the number of transactions which were rejected, for
+	// example because they conflict with the main chain.
+	Failed int
+
+	// Expired is the number of transactions which were skipped because
+	// they were added to the pool longer than persistedTxExpiry ago.
+	Expired int
+
+	// AlreadyHave is the number of transactions which were already in the
+	// pool.
+	AlreadyHave int
+}
+
+// Save writes all of the transactions in the main pool to w along with the time
+// each of them was added and their fee delta, so they can be reloaded with Load
+// after a restart.  Transactions are written after the pool transactions they
+// depend on so that reloading them doesn't create orphans.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) Save(w io.Writer) error {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	// A transaction always has more ancestors in the pool than any of its
+	// parents, so ordering by the number of ancestors puts parents first.
+	descs := make([]*TxDesc, 0, len(mp.pool))
+	numAncestors := make(map[*TxDesc]int, len(mp.pool))
+	cache := make(map[chainhash.Hash]map[chainhash.Hash]*bronutil.Tx)
+	for _, txD := range mp.pool {
+		descs = append(descs, txD)
+		numAncestors[txD] = len(mp.txAncestors(txD.Tx, cache))
+	}
+	sort.Slice(descs, func(i, j int) bool {
+		return numAncestors[descs[i]] < numAncestors[descs[j]]
+	})
+
+	err := binary.Write(w, binary.BigEndian, uint32(persistSaveVersion))
+	if err != nil {
+		return err
+	}
+	err = binary.Write(w, binary.BigEndian, uint32(len(descs)))
+	if err != nil {
+		return err
+	}
+	for _, txD := range descs {
+		if err := txD.Tx.MsgTx().Serialize(w); err != nil {
+			return err
+		}
+		err := binary.Write(w, binary.BigEndian, txD.Added.Unix())
+		if err != nil {
+			return err
+		}
+		err = binary.Write(w, binary.BigEndian, txD.FeeDelta)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Load reads transactions previously written by Save from r and submits each
+// of them to the pool through ProcessTransaction.  Transactions which were
+// added to the pool too long ago are skipped, as are those which are no longer
+// valid, such as ones which conflict with transactions mined in the meantime.
+// The time each accepted transaction was originally added to the pool and its
+// fee delta are restored.  Loading stops early without an error when the
+// interrupt channel is closed.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) Load(r io.Reader, interrupt <-chan struct{}) (*LoadStats, error) {
+	var version uint32
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return nil, err
+	}
+	if version != persistSaveVersion {
+		return nil, fmt.Errorf("unsupported mempool file version %d, "+
+			"expected %d", version, persistSaveVersion)
+	}
+
+	var numTxns uint32
+	if err := binary.Read(r, binary.BigEndian, &numTxns); err != nil {
+		return nil, err
+	}
+
+	var stats LoadStats
+	now := time.Now()
+	for i := uint32(0); i < numTxns; i++ {
+		select {
+		case <-interrupt:
+			return &stats, nil
+		default:
+		}
+
+		var msgTx wire.MsgTx
+		if err := msgTx.Deserialize(r); err != nil {
+			return nil, err
+		}
+		var addedUnix, feeDelta int64
+		err := binary.Read(r, binary.BigEndian, &addedUnix)
+		if err != nil {
+			return nil, err
+		}
+		if err := binary.Read(r, binary.BigEndian, &feeDelta); err != nil {
+			return nil, err
+		}
+
+		tx := bronutil.NewTx(&msgTx)
+		added := time.Unix(addedUnix, 0)
+		switch {
+		case now.Sub(added) > persistedTxExpiry:
+			stats.Expired++
+			continue
+
+		case mp.HaveTransaction(tx.Hash()):
+			stats.AlreadyHave++
+			continue
+		}
+
+		_, err = mp.ProcessTransaction(tx, false, false, 0)
+		if err != nil {
+			log.Debugf("Unable to reload transaction %v: %v",
+				tx.Hash(), err)
+			stats.Failed++
+			continue
+		}
+
+		mp.mtx.Lock()
+		if txD, exists := mp.pool[*tx.Hash()]; exists {
+			txD.Added = added
+			mp.setFeeDelta(txD, feeDelta)
+		}
+		mp.mtx.Unlock()
+
+		// The height the transaction originally entered the pool at is
+		// unknown, so it must not be tracked for fee estimation.  It has
+		// not waited for any blocks yet, so this does not count against
+		// its fee rate.
+		if mp.cfg.FeeEstimator != nil {
+			mp.cfg.FeeEstimator.RemoveTransaction(tx.Hash())
+		}
+		stats.Loaded++
+	}
+
+	return &stats, nil
+}
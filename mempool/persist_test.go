@@ -0,0 +1,94 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/brsuite/brond/chaincfg"
+)
+
+// TestSaveLoad ensures transactions saved from the pool are reloaded along with
+// the time they were added and their fee delta, and that expired and duplicate
+// transactions are skipped.
+func TestSaveLoad(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+	txPool := harness.txPool
+
+	// Create a parent and child along with an unrelated transaction which
+	// has been in the pool for too long to be reloaded.
+	coinbase := ctx.addCoinbaseTx(2)
+	parent := ctx.addSignedTx([]spendableOutput{
+		txOutToSpendableOut(coinbase, 0),
+	}, 1, 1000, false, false)
+	child := ctx.addSignedTx([]spendableOutput{
+		txOutToSpendableOut(parent, 0),
+	}, 1, 1000, false, false)
+	expired := ctx.addSignedTx([]spendableOutput{
+		txOutToSpendableOut(coinbase, 1),
+	}, 1, 1000, false, false)
+
+	childAdded := time.Unix(time.Now().Add(-time.Hour).Unix(), 0)
+	txPool.pool[*child.Hash()].Added = childAdded
+	txPool.setFeeDelta(txPool.pool[*child.Hash()], 5000)
+	txPool.pool[*expired.Hash()].Added = time.Now().Add(-persistedTxExpiry * 2)
+
+	var buf bytes.Buffer
+	if err := txPool.Save(&buf); err != nil {
+		t.Fatalf("Save: unexpected error %v", err)
+	}
+	saved := buf.Bytes()
+
+	// Empty the pool and reload the saved transactions.  The child must
+	// be reloaded even though it was saved along with its parent.
+	txPool.RemoveTransaction(parent, true, RemovedEvicted)
+	txPool.RemoveTransaction(expired, true, RemovedEvicted)
+	stats, err := txPool.Load(bytes.NewReader(saved), nil)
+	if err != nil {
+		t.Fatalf("Load: unexpected error %v", err)
+	}
+	want := &LoadStats{Loaded: 2, Expired: 1}
+	if !reflect.DeepEqual(stats, want) {
+		t.Fatalf("Load: wrong stats - got %+v, want %+v", stats, want)
+	}
+	testPoolMembership(ctx, parent, false, true)
+	testPoolMembership(ctx, child, false, true)
+	testPoolMembership(ctx, expired, false, false)
+
+	childDesc := txPool.pool[*child.Hash()]
+	if !childDesc.Added.Equal(childAdded) {
+		t.Fatalf("Load: wrong added time - got %v, want %v",
+			childDesc.Added, childAdded)
+	}
+	if childDesc.FeeDelta != 5000 {
+		t.Fatalf("Load: wrong fee delta - got %d, want %d",
+			childDesc.FeeDelta, 5000)
+	}
+
+	// Loading the same transactions again must not change the pool.
+	stats, err = txPool.Load(bytes.NewReader(saved), nil)
+	if err != nil {
+		t.Fatalf("Load: unexpected error %v", err)
+	}
+	want = &LoadStats{AlreadyHave: 2, Expired: 1}
+	if !reflect.DeepEqual(stats, want) {
+		t.Fatalf("Load: wrong stats - got %+v, want %+v", stats, want)
+	}
+
+	// Ensure a file with an unknown version is rejected.
+	saved[3]++
+	if _, err := txPool.Load(bytes.NewReader(saved), nil); err == nil {
+		t.Fatal("Load: did not fail on unknown version")
+	}
+}
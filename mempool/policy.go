@@ -126,8 +126,11 @@ func checkInputsStandard(tx *bronutil.Tx, utxoView *blockchain.UtxoViewpoint) er
 // script (public key script) to ensure it is a "standard" public key script.
 // A standard public key script is one that is a recognized form, and for
 // multi-signature scripts, only contains from 1 to maxStandardMultiSigKeys
-// public keys.
-func checkPkScriptStandard(pkScript []byte, scriptClass txscript.ScriptClass) error {
+// public keys.  Pay-to-taproot scripts are only standard once the taproot
+// soft-fork is active as indicated by the taprootActive flag.
+func checkPkScriptStandard(pkScript []byte, scriptClass txscript.ScriptClass,
+	taprootActive bool) error {
+
 	switch scriptClass {
 	case txscript.MultiSigTy:
 		numPubKeys, numSigs, err := txscript.CalcMultiSigStats(pkScript)
@@ -164,6 +167,12 @@ func checkPkScriptStandard(pkScript []byte, scriptClass txscript.ScriptClass) er
 			return txRuleError(wire.RejectNonstandard, str)
 		}
 
+	case txscript.WitnessV1TaprootTy:
+		if !taprootActive {
+			return txRuleError(wire.RejectNonstandard,
+				"pay-to-taproot script before taproot is active")
+		}
+
 	case txscript.NonStandardTy:
 		return txRuleError(wire.RejectNonstandard,
 			"non-standard script form")
@@ -274,10 +283,11 @@ func isDust(txOut *wire.TxOut, minRelayTxFee bronutil.Amount) bool {
 // "sane" transaction such as having a version in the supported range, being
 // finalized, conforming to more stringent size constraints, having scripts
 // of recognized forms, and not containing "dust" outputs (those that are
-// so small it costs more to process them than they are worth).
+// so small it costs more to process them than they are worth).  Outputs paying
+// to taproot scripts are only considered standard when taprootActive is set.
 func checkTransactionStandard(tx *bronutil.Tx, height int32,
 	medianTimePast time.Time, minRelayTxFee bronutil.Amount,
-	maxTxVersion int32) error {
+	maxTxVersion int32, taprootActive bool) error {
 
 	// The transaction must be a currently supported version.
 	msgTx := tx.MsgTx()
@@ -333,7 +343,8 @@ func checkTransactionStandard(tx *bronutil.Tx, height int32,
 	numNullDataOutputs := 0
 	for i, txOut := range msgTx.TxOut {
 		scriptClass := txscript.GetScriptClass(txOut.PkScript)
-		err := checkPkScriptStandard(txOut.PkScript, scriptClass)
+		err := checkPkScriptStandard(txOut.PkScript, scriptClass,
+			taprootActive)
 		if err != nil {
 			// Attempt to extract a reject code from the error so
 			// it can be retained.  When not possible, fall back to
@@ -193,7 +193,7 @@ func TestCheckPkScriptStandard(t *testing.T) {
 			continue
 		}
 		scriptClass := txscript.GetScriptClass(script)
-		got := checkPkScriptStandard(script, scriptClass)
+		got := checkPkScriptStandard(script, scriptClass, true)
 		if (test.isStandard && got != nil) ||
 			(!test.isStandard && got == nil) {
 
@@ -306,12 +306,20 @@ func TestCheckTransactionStandard(t *testing.T) {
 		PkScript: dummyPkScript,
 	}
 
+	taprootPkScript := append([]byte{txscript.OP_1, txscript.OP_DATA_32},
+		bytes.Repeat([]byte{0x01}, 32)...)
+	taprootTxOut := wire.TxOut{
+		Value:    100000000, // 1 BRON
+		PkScript: taprootPkScript,
+	}
+
 	tests := []struct {
-		name       string
-		tx         wire.MsgTx
-		height     int32
-		isStandard bool
-		code       wire.RejectCode
+		name          string
+		tx            wire.MsgTx
+		height        int32
+		taprootActive bool
+		isStandard    bool
+		code          wire.RejectCode
 	}{
 		{
 			name: "Typical pay-to-pubkey-hash transaction",
@@ -464,13 +472,38 @@ func TestCheckTransactionStandard(t *testing.T) {
 			height:     300000,
 			isStandard: true,
 		},
+		{
+			name: "Pay-to-taproot output with taproot active",
+			tx: wire.MsgTx{
+				Version:  1,
+				TxIn:     []*wire.TxIn{&dummyTxIn},
+				TxOut:    []*wire.TxOut{&taprootTxOut},
+				LockTime: 0,
+			},
+			height:        300000,
+			taprootActive: true,
+			isStandard:    true,
+		},
+		{
+			name: "Pay-to-taproot output with taproot inactive",
+			tx: wire.MsgTx{
+				Version:  1,
+				TxIn:     []*wire.TxIn{&dummyTxIn},
+				TxOut:    []*wire.TxOut{&taprootTxOut},
+				LockTime: 0,
+			},
+			height:     300000,
+			isStandard: false,
+			code:       wire.RejectNonstandard,
+		},
 	}
 
 	pastMedianTime := time.Now()
 	for _, test := range tests {
 		// Ensure standardness is as expected.
 		err := checkTransactionStandard(bronutil.NewTx(&test.tx),
-			test.height, pastMedianTime, DefaultMinRelayTxFee, 1)
+			test.height, pastMedianTime, DefaultMinRelayTxFee, 1,
+			test.taprootActive)
 		if err == nil && test.isStandard {
 			// Test passes since function returned standard for a
 			// transaction which is intended to be standard.
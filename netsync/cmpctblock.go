@@ -0,0 +1,153 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package netsync
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/brsuite/brond/blockchain"
+	"github.com/brsuite/brond/chaincfg/chainhash"
+	"github.com/brsuite/brond/mempool"
+	"github.com/brsuite/brond/wire"
+	"github.com/brsuite/bronutil"
+)
+
+// errShortIDCollision is returned when a compact block can't be reconstructed
+// because two of its short transaction ids are the same.  The full block must
+// be requested instead.
+var errShortIDCollision = errors.New("compact block contains duplicate " +
+	"short transaction ids")
+
+// partialBlock is a block announced via a cmpctblock message which is being
+// reconstructed from the transactions in the memory pool.  The transactions
+// which could not be found are requested from the peer with a getblocktxn
+// message.
+type partialBlock struct {
+	header  wire.BlockHeader
+	version uint64
+	txns    []*wire.MsgTx
+	missing []uint32
+}
+
+// newPartialBlock creates a partial block from the passed compact block by
+// matching the short transaction ids against the passed memory pool
+// transactions.  The version is the compact block version negotiated with the
+// peer and determines whether the short ids were calculated from transaction
+// or witness transaction hashes.
+func newPartialBlock(msg *wire.MsgCmpctBlock, version uint64,
+	pool []*mempool.TxDesc) (*partialBlock, error) {
+
+	total := msg.TotalTxns()
+	if total == 0 {
+		return nil, fmt.Errorf("compact block %v has no transactions",
+			msg.Header.BlockHash())
+	}
+
+	// Place the prefilled transactions and assign the short ids to the
+	// remaining indexes in order.
+	pb := &partialBlock{
+		header:  msg.Header,
+		version: version,
+		txns:    make([]*wire.MsgTx, total),
+	}
+	for _, ptx := range msg.PrefilledTxns {
+		pb.txns[ptx.Index] = ptx.Tx
+	}
+	shortIDs := make(map[uint64]int, len(msg.ShortIDs))
+	next := 0
+	for _, id := range msg.ShortIDs {
+		for pb.txns[next] != nil {
+			next++
+		}
+		if _, exists := shortIDs[id]; exists {
+			return nil, errShortIDCollision
+		}
+		shortIDs[id] = next
+		next++
+	}
+
+	// Fill in the transactions from the memory pool.  A short id which
+	// matches more than one transaction is ambiguous, so it is left for
+	// the peer to provide.
+	k0, k1 := msg.ShortIDKeys()
+	ambiguous := make(map[int]struct{})
+	for _, txD := range pool {
+		var hash *chainhash.Hash
+		if version >= wire.CmpctBlockVersion2 {
+			hash = txD.Tx.WitnessHash()
+		} else {
+			hash = txD.Tx.Hash()
+		}
+		index, ok := shortIDs[wire.ShortTxID(k0, k1, hash)]
+		if !ok {
+			continue
+		}
+		if pb.txns[index] != nil {
+			ambiguous[index] = struct{}{}
+			continue
+		}
+		pb.txns[index] = txD.Tx.MsgTx()
+	}
+	for index := range ambiguous {
+		pb.txns[index] = nil
+	}
+
+	for i, tx := range pb.txns {
+		if tx == nil {
+			pb.missing = append(pb.missing, uint32(i))
+		}
+	}
+	return pb, nil
+}
+
+// hash returns the hash of the block being reconstructed.
+func (pb *partialBlock) hash() chainhash.Hash {
+	return pb.header.BlockHash()
+}
+
+// fill adds the transactions provided by the peer in a blocktxn message in
+// place of the missing transactions.
+func (pb *partialBlock) fill(txns []*wire.MsgTx) error {
+	if len(txns) != len(pb.missing) {
+		return fmt.Errorf("blocktxn message provides %d transactions, "+
+			"expected %d", len(txns), len(pb.missing))
+	}
+
+	for i, index := range pb.missing {
+		pb.txns[index] = txns[i]
+	}
+	pb.missing = nil
+	return nil
+}
+
+// block returns the reconstructed block once all of its transactions are
+// known.  The merkle root and, for compact blocks with witness data, the
+// witness commitment are checked so that a wrongly matched transaction results
+// in an error rather than the block being rejected as invalid.
+func (pb *partialBlock) block() (*bronutil.Block, error) {
+	if len(pb.missing) != 0 {
+		return nil, fmt.Errorf("block is missing %d transactions",
+			len(pb.missing))
+	}
+
+	msgBlock := &wire.MsgBlock{
+		Header:       pb.header,
+		Transactions: pb.txns,
+	}
+	block := bronutil.NewBlock(msgBlock)
+	merkles := blockchain.BuildMerkleTreeStore(block.Transactions(), false)
+	if !merkles[len(merkles)-1].IsEqual(&pb.header.MerkleRoot) {
+		return nil, fmt.Errorf("reconstructed block %v has a mismatched "+
+			"merkle root", block.Hash())
+	}
+	if pb.version >= wire.CmpctBlockVersion2 {
+		if err := blockchain.ValidateWitnessCommitment(block); err != nil {
+			return nil, err
+		}
+	}
+
+	return block, nil
+}
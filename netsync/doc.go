@@ -6,8 +6,12 @@
 Package netsync implements a concurrency safe block syncing protocol. The
 SyncManager communicates with connected peers to perform an initial block
 download, keep the chain and unconfirmed transaction pool in sync, and announce
-new blocks connected to the chain. Currently the sync manager selects a single
-sync peer that it downloads all blocks from until it is up to date with the
-longest chain the sync peer is aware of.
+new blocks connected to the chain. The sync manager selects a single sync peer
+that it downloads headers and block inventory from until it is up to date with
+the longest chain the sync peer is aware of. In headers-first mode, the headers
+of the sync peer are validated and added to the block index up to the tip of
+its chain, as long as the chain has enough work, and the blocks of a moving
+window following the best block are downloaded from all sync candidates in
+parallel and processed in order.
 */
 package netsync
@@ -27,6 +27,29 @@ type PeerNotifier interface {
 	TransactionConfirmed(tx *bronutil.Tx)
 }
 
+// syncChain is the part of the block chain which is used by the SyncManager.
+// It is implemented by *blockchain.BlockChain and allows the download logic to
+// be tested without a database.
+type syncChain interface {
+	BestHeader() (chainhash.Hash, int32)
+	BestSnapshot() *blockchain.BestState
+	BlockHeightByHash(hash *chainhash.Hash) (int32, error)
+	BlockLocatorFromHash(hash *chainhash.Hash) blockchain.BlockLocator
+	FetchUtxoEntry(outpoint wire.OutPoint) (*blockchain.UtxoEntry, error)
+	GetOrphanRoot(hash *chainhash.Hash) *chainhash.Hash
+	HaveBlock(hash *chainhash.Hash) (bool, error)
+	HeaderHashesAfter(startHash, endHash *chainhash.Hash) (int32, []chainhash.Hash, error)
+	HeadersNeeded(pending *blockchain.HeaderChain) int64
+	InvalidateBlock(hash *chainhash.Hash) error
+	IsCurrent() bool
+	IsDeploymentActive(deploymentID uint32) (bool, error)
+	IsKnownOrphan(hash *chainhash.Hash) bool
+	LatestBlockLocator() (blockchain.BlockLocator, error)
+	LatestCheckpoint() *chaincfg.Checkpoint
+	ProcessBlock(block *bronutil.Block, flags blockchain.BehaviorFlags) (bool, bool, error)
+	ProcessBlockHeaders(pending *blockchain.HeaderChain, headers []*wire.BlockHeader, flags blockchain.BehaviorFlags) (*blockchain.HeaderChain, error)
+}
+
 // Config is a configuration struct used to initialize a new SyncManager.
 type Config struct {
 	PeerNotifier PeerNotifier
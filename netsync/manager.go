@@ -23,10 +23,42 @@ import (
 )
 
 const (
-	// minInFlightBlocks is the minimum number of blocks that should be
-	// in the request queue for headers-first mode before requesting
-	// more.
-	minInFlightBlocks = 10
+	// blockDownloadWindow is the maximum number of blocks of the header
+	// list, starting with the next block to process, which are downloaded
+	// at the same time in headers-first mode.  Blocks received ahead of the
+	// next block to process are held in memory until they can be processed
+	// in order, so this also bounds the memory used by them.  A serialized
+	// block takes at most blockchain.MaxBlockWeight bytes, so the window is
+	// kept small enough for them to never need more than 256 MB, while
+	// still allowing maxBlocksInFlightPerPeer blocks from four peers.
+	blockDownloadWindow = 64
+
+	// maxPendingHeaders is the maximum number of validated block headers
+	// which are kept in memory for the chain of the sync peer while it
+	// does not have enough work to be added to the block index.  The
+	// chain is dropped as soon as it would need more headers than this to
+	// get enough work at the average work of its headers so far.
+	maxPendingHeaders = 10 * wire.MaxBlockHeadersPerMsg
+
+	// maxBlocksInFlightPerPeer is the maximum number of blocks which may be
+	// requested from a single peer at once in headers-first mode.
+	maxBlocksInFlightPerPeer = 16
+
+	// blockRequestTimeout is the time after which a block of the download
+	// window which has not been received yet is requested from another
+	// peer.
+	blockRequestTimeout = time.Minute
+
+	// blockStallTimeout is the time after which the peer which was asked
+	// for the next block to process is disconnected when the download
+	// window can't move forward without it.
+	blockStallTimeout = 10 * time.Second
+
+	// maxBlockFailures is the number of peers a block of the download
+	// window may fail to process from before it is marked invalid, so the
+	// header list no longer leads through it.  A single peer could have
+	// mutated the block, so the block is requested from other peers first.
+	maxBlockFailures = 3
 
 	// maxRejectedTxns is the maximum number of rejected transactions
 	// hashes to store in memory.
@@ -46,7 +78,11 @@ const (
 
 	// stallSampleInterval the interval at which we will check to see if our
 	// sync has stalled.
-	stallSampleInterval = 30 * time.Second
+	stallSampleInterval = 5 * time.Second
+
+	// maxHighBandwidthPeers is the maximum number of peers which are asked
+	// to announce new blocks by sending compact blocks directly.
+	maxHighBandwidthPeers = 3
 )
 
 // zeroHash is the zero value hash (all zeros).  It is defined as a convenience.
@@ -65,6 +101,22 @@ type blockMsg struct {
 	reply chan struct{}
 }
 
+// cmpctBlockMsg packages a brocoin cmpctblock message and the peer it came
+// from together so the block handler has access to that information.
+type cmpctBlockMsg struct {
+	cmpctBlock *wire.MsgCmpctBlock
+	peer       *peerpkg.Peer
+	reply      chan struct{}
+}
+
+// blockTxnsMsg packages a brocoin blocktxn message and the peer it came from
+// together so the block handler has access to that information.
+type blockTxnsMsg struct {
+	blockTxns *wire.MsgBlockTxns
+	peer      *peerpkg.Peer
+	reply     chan struct{}
+}
+
 // invMsg packages a brocoin inv message and the peer it came from together
 // so the block handler has access to that information.
 type invMsg struct {
@@ -131,13 +183,20 @@ type pauseMsg struct {
 	unpause <-chan struct{}
 }
 
-// headerNode is used as a node in a list of headers that are linked together
-// between checkpoints.
+// headerNode is used as a node in the list of headers of the best header chain
+// whose blocks are downloaded in headers-first mode.
 type headerNode struct {
 	height int32
 	hash   *chainhash.Hash
 }
 
+// blockRequest describes a request for a block of the download window in
+// headers-first mode.
+type blockRequest struct {
+	peer      *peerpkg.Peer
+	requested time.Time
+}
+
 // peerSyncState stores additional information that the SyncManager tracks
 // about a peer.
 type peerSyncState struct {
@@ -145,6 +204,7 @@ type peerSyncState struct {
 	requestQueue    []*wire.InvVect
 	requestedTxns   map[chainhash.Hash]struct{}
 	requestedBlocks map[chainhash.Hash]struct{}
+	partialBlock    *partialBlock
 }
 
 // SyncManager is used to communicate block related messages with peers. The
@@ -156,7 +216,7 @@ type SyncManager struct {
 	peerNotifier   PeerNotifier
 	started        int32
 	shutdown       int32
-	chain          *blockchain.BlockChain
+	chain          syncChain
 	txMemPool      *mempool.TxPool
 	chainParams    *chaincfg.Params
 	progressLogger *blockProgressLogger
@@ -172,11 +232,31 @@ type SyncManager struct {
 	peerStates       map[*peerpkg.Peer]*peerSyncState
 	lastProgressTime time.Time
 
-	// The following fields are used for headers-first mode.
+	// highBandwidthPeers holds the peers which were asked to announce new
+	// blocks via compact blocks, ordered from the least to the most
+	// recently selected.
+	highBandwidthPeers []*peerpkg.Peer
+
+	// The following fields are used for headers-first mode.  The headers
+	// received from the sync peer are added to the block index, and the
+	// blocks of the download window of the header list, which starts with
+	// startHeader, are requested from all sync candidates in parallel.
+	// blocksInFlight holds the pending requests and blockBuffer the blocks
+	// received ahead of the next block to process.  pendingHeaders holds
+	// the headers which do not have enough work to be added to the block
+	// index yet, and headersDone is set once the sync peer has sent all of
+	// its headers.
 	headersFirstMode bool
 	headerList       *list.List
 	startHeader      *list.Element
-	nextCheckpoint   *chaincfg.Checkpoint
+	blocksInFlight   map[chainhash.Hash]*blockRequest
+	blockBuffer      map[chainhash.Hash]*blockMsg
+	pendingHeaders   *blockchain.HeaderChain
+	headersDone      bool
+
+	// blockFailures holds the number of peers each block of the download
+	// window has failed to process from.
+	blockFailures map[chainhash.Hash]int
 
 	// An optional fee estimator.
 	feeEstimator *mempool.FeeEstimator
@@ -184,46 +264,14 @@ type SyncManager struct {
 
 // resetHeaderState sets the headers-first mode state to values appropriate for
 // syncing from a new peer.
-func (sm *SyncManager) resetHeaderState(newestHash *chainhash.Hash, newestHeight int32) {
+func (sm *SyncManager) resetHeaderState() {
 	sm.headersFirstMode = false
 	sm.headerList.Init()
 	sm.startHeader = nil
-
-	// When there is a next checkpoint, add an entry for the latest known
-	// block into the header pool.  This allows the next downloaded header
-	// to prove it links to the chain properly.
-	if sm.nextCheckpoint != nil {
-		node := headerNode{height: newestHeight, hash: newestHash}
-		sm.headerList.PushBack(&node)
-	}
-}
-
-// findNextHeaderCheckpoint returns the next checkpoint after the passed height.
-// It returns nil when there is not one either because the height is already
-// later than the final checkpoint or some other reason such as disabled
-// checkpoints.
-func (sm *SyncManager) findNextHeaderCheckpoint(height int32) *chaincfg.Checkpoint {
-	checkpoints := sm.chain.Checkpoints()
-	if len(checkpoints) == 0 {
-		return nil
-	}
-
-	// There is no next checkpoint if the height is already after the final
-	// checkpoint.
-	finalCheckpoint := &checkpoints[len(checkpoints)-1]
-	if height >= finalCheckpoint.Height {
-		return nil
-	}
-
-	// Find the next checkpoint.
-	nextCheckpoint := finalCheckpoint
-	for i := len(checkpoints) - 2; i >= 0; i-- {
-		if height >= checkpoints[i].Height {
-			break
-		}
-		nextCheckpoint = &checkpoints[i]
-	}
-	return nextCheckpoint
+	sm.blocksInFlight = make(map[chainhash.Hash]*blockRequest)
+	sm.blockBuffer = make(map[chainhash.Hash]*blockMsg)
+	sm.pendingHeaders = nil
+	sm.headersDone = false
 }
 
 // startSync will choose the best peer among the available candidate peers to
@@ -287,7 +335,7 @@ func (sm *SyncManager) startSync() {
 	// falling back to a random peer of the same height if none are greater.
 	//
 	// TODO(conner): Use a better algorithm to ranking peers based on
-	// observed metrics and/or sync in parallel.
+	// observed metrics.
 	var bestPeer *peerpkg.Peer
 	switch {
 	case len(higherPeers) > 0:
@@ -314,32 +362,22 @@ func (sm *SyncManager) startSync() {
 		log.Infof("Syncing to block height %d from peer %v",
 			bestPeer.LastBlock(), bestPeer.Addr())
 
-		// When the current height is less than a known checkpoint we
-		// can use block headers to learn about which blocks comprise
-		// the chain up to the checkpoint and perform less validation
-		// for them.  This is possible since each header contains the
-		// hash of the previous header and a merkle root.  Therefore if
-		// we validate all of the received headers link together
-		// properly and the checkpoint hashes match, we can be sure the
-		// hashes for the blocks in between are accurate.  Further, once
-		// the full blocks are downloaded, the merkle root is computed
-		// and compared against the value in the header which proves the
-		// full block hasn't been tampered with.
+		// Use block headers to learn about which blocks comprise the
+		// best chain of the peer and download the blocks once their
+		// headers are known to be valid.  This is possible since each
+		// header contains the hash of the previous header and a merkle
+		// root.  Therefore, once the headers are validated and added to
+		// the block index, the blocks can be downloaded from all sync
+		// candidates in parallel, and once the full blocks are
+		// downloaded, the merkle root is computed and compared against
+		// the value in the header which proves the full block hasn't
+		// been tampered with.
 		//
-		// Once we have passed the final checkpoint, or checkpoints are
-		// disabled, use standard inv messages learn about the blocks
-		// and fully validate them.  Finally, regression test mode does
-		// not support the headers-first approach so do normal block
-		// downloads when in regression test mode.
-		if sm.nextCheckpoint != nil &&
-			best.Height < sm.nextCheckpoint.Height &&
-			sm.chainParams != &chaincfg.RegressionNetParams {
-
-			bestPeer.PushGetHeadersMsg(locator, sm.nextCheckpoint.Hash)
-			sm.headersFirstMode = true
-			log.Infof("Downloading headers for blocks %d to "+
-				"%d from peer %s", best.Height+1,
-				sm.nextCheckpoint.Height, bestPeer.Addr())
+		// Regression test mode does not support the headers-first
+		// approach so do normal block downloads when in regression test
+		// mode.
+		if sm.chainParams != &chaincfg.RegressionNetParams {
+			sm.startHeadersFirst(bestPeer)
 		} else {
 			bestPeer.PushGetBlocksMsg(locator, &zeroHash)
 		}
@@ -419,7 +457,8 @@ func (sm *SyncManager) handleNewPeerMsg(peer *peerpkg.Peer) {
 // handleStallSample will switch to a new sync peer if the current one has
 // stalled. This is detected when by comparing the last progress timestamp with
 // the current time, and disconnecting the peer if we stalled before reaching
-// their highest advertised block.
+// their highest advertised block.  In headers-first mode, it also handles
+// stalls of the block download.
 func (sm *SyncManager) handleStallSample() {
 	if atomic.LoadInt32(&sm.shutdown) != 0 {
 		return
@@ -430,6 +469,10 @@ func (sm *SyncManager) handleStallSample() {
 		return
 	}
 
+	if sm.startHeader != nil {
+		sm.handleBlockDownloadStalls()
+	}
+
 	// If the stall timeout has not elapsed, exit early.
 	if time.Since(sm.lastProgressTime) <= maxStallDuration {
 		return
@@ -441,7 +484,7 @@ func (sm *SyncManager) handleStallSample() {
 		return
 	}
 
-	sm.clearRequestedState(state)
+	sm.clearRequestedState(sm.syncPeer, state)
 
 	disconnectSyncPeer := sm.shouldDCStalledSyncPeer()
 	sm.updateSyncPeer(disconnectSyncPeer)
@@ -469,6 +512,66 @@ func (sm *SyncManager) shouldDCStalledSyncPeer() bool {
 	return peerHeight > best.Height
 }
 
+// handleBlockDownloadStalls requests the blocks of the download window whose
+// requests timed out from other peers.  Also, when the next block to process
+// has not been received within blockStallTimeout and all other blocks of the
+// window are already in flight or received, the single peer it was requested
+// from blocks the window from moving forward, so it is disconnected to have the
+// block requested from another peer.
+func (sm *SyncManager) handleBlockDownloadStalls() {
+	now := time.Now()
+	startNode := sm.startHeader.Value.(*headerNode)
+	var blocking *blockRequest
+	windowFull := true
+	for e := sm.startHeader; e != nil; e = e.Next() {
+		node := e.Value.(*headerNode)
+		if node.height-startNode.height >= blockDownloadWindow {
+			break
+		}
+		if _, ok := sm.blockBuffer[*node.hash]; ok {
+			continue
+		}
+		req, ok := sm.blocksInFlight[*node.hash]
+		if !ok {
+			windowFull = false
+			continue
+		}
+		if blocking == nil && node == startNode {
+			blocking = req
+		}
+
+		if now.Sub(req.requested) <= blockRequestTimeout {
+			continue
+		}
+		peer := sm.downloadPeer(node.height, req.peer)
+		if peer == nil {
+			continue
+		}
+		log.Debugf("Request for block %v from %s timed out -- requesting "+
+			"it from %s", node.hash, req.peer, peer)
+		gdmsg := wire.NewMsgGetData()
+		gdmsg.AddInvVect(sm.requestHeaderBlock(peer, node.hash))
+		peer.QueueMessage(gdmsg, nil)
+	}
+
+	if blocking == nil || !windowFull ||
+		now.Sub(blocking.requested) <= blockStallTimeout {
+
+		return
+	}
+
+	// Keep the peer when there is no other peer to download from.
+	for peer, state := range sm.peerStates {
+		if peer != blocking.peer && state.syncCandidate {
+			log.Infof("Peer %s stalls the block download at height "+
+				"%d -- disconnecting", blocking.peer,
+				startNode.height)
+			blocking.peer.Disconnect()
+			return
+		}
+	}
+}
+
 // handleDonePeerMsg deals with peers that have signalled they are done.  It
 // removes the peer as a candidate for syncing and in the case where it was
 // the current sync peer, attempts to select a new best peer to sync from.  It
@@ -485,19 +588,32 @@ func (sm *SyncManager) handleDonePeerMsg(peer *peerpkg.Peer) {
 
 	log.Infof("Lost peer %s", peer)
 
-	sm.clearRequestedState(state)
+	sm.clearRequestedState(peer, state)
+
+	for i, p := range sm.highBandwidthPeers {
+		if p == peer {
+			sm.highBandwidthPeers = append(sm.highBandwidthPeers[:i],
+				sm.highBandwidthPeers[i+1:]...)
+			break
+		}
+	}
 
 	if peer == sm.syncPeer {
 		// Update the sync peer. The server has already disconnected the
 		// peer before signaling to the sync manager.
 		sm.updateSyncPeer(false)
 	}
+
+	// Request the blocks which were in flight from the peer from others.
+	if sm.startHeader != nil {
+		sm.fetchHeaderBlocks()
+	}
 }
 
 // clearRequestedState wipes all expected transactions and blocks from the sync
 // manager's requested maps that were requested under a peer's sync state, This
 // allows them to be rerequested by a subsequent sync peer.
-func (sm *SyncManager) clearRequestedState(state *peerSyncState) {
+func (sm *SyncManager) clearRequestedState(peer *peerpkg.Peer, state *peerSyncState) {
 	// Remove requested transactions from the global map so that they will
 	// be fetched from elsewhere next time we get an inv.
 	for txHash := range state.requestedTxns {
@@ -510,6 +626,11 @@ func (sm *SyncManager) clearRequestedState(state *peerSyncState) {
 	// and request them now to speed things up a little.
 	for blockHash := range state.requestedBlocks {
 		delete(sm.requestedBlocks, blockHash)
+
+		req, ok := sm.blocksInFlight[blockHash]
+		if ok && req.peer == peer {
+			delete(sm.blocksInFlight, blockHash)
+		}
 	}
 }
 
@@ -528,8 +649,7 @@ func (sm *SyncManager) updateSyncPeer(dcSyncPeer bool) {
 
 	// Reset any header state before we choose our next active sync peer.
 	if sm.headersFirstMode {
-		best := sm.chain.BestSnapshot()
-		sm.resetHeaderState(&best.Hash, best.Height)
+		sm.resetHeaderState()
 	}
 
 	sm.syncPeer = nil
@@ -601,6 +721,12 @@ func (sm *SyncManager) handleTxMsg(tmsg *txMsg) {
 		return
 	}
 
+	// Peers which provide new transactions are protected from inbound
+	// eviction.
+	if len(acceptedTxs) > 0 {
+		peer.UpdateLastNewTxTime()
+	}
+
 	sm.peerNotifier.AnnounceNewTransactions(acceptedTxs)
 }
 
@@ -650,39 +776,116 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		}
 	}
 
-	// When in headers-first mode, if the block matches the hash of the
-	// first header in the list of headers that are being fetched, it's
-	// eligible for less validation since the headers have already been
-	// verified to link together and are valid up to the next checkpoint.
-	// Also, remove the list entry for all blocks except the checkpoint
-	// since it is needed to verify the next round of headers links
-	// properly.
-	isCheckpointBlock := false
-	behaviorFlags := blockchain.BFNone
+	// Remove block from request maps. Either chain will know about it and
+	// so we shouldn't have any more instances of trying to fetch it, or we
+	// will fail the insert and thus we'll retry next time we get an inv.
+	delete(state.requestedBlocks, *blockHash)
+	delete(sm.requestedBlocks, *blockHash)
+
+	// In headers-first mode, the blocks of the download window arrive from
+	// multiple peers in any order, so they are buffered until all blocks
+	// before them have been processed.  A block which was requested again
+	// after its request timed out is only processed once.
 	if sm.headersFirstMode {
-		firstNodeEl := sm.headerList.Front()
-		if firstNodeEl != nil {
-			firstNode := firstNodeEl.Value.(*headerNode)
-			if blockHash.IsEqual(firstNode.hash) {
-				behaviorFlags |= blockchain.BFFastAdd
-				if firstNode.hash.IsEqual(sm.nextCheckpoint.Hash) {
-					isCheckpointBlock = true
-				} else {
-					sm.headerList.Remove(firstNodeEl)
+		if _, ok := sm.blocksInFlight[*blockHash]; ok {
+			delete(sm.blocksInFlight, *blockHash)
+			sm.blockBuffer[*blockHash] = bmsg
+			sm.processBufferedBlocks()
+			return
+		}
+		_, buffered := sm.blockBuffer[*blockHash]
+		if have, _ := sm.chain.HaveBlock(blockHash); buffered || have {
+			log.Debugf("Ignoring duplicate block %v from %s",
+				blockHash, peer)
+			return
+		}
+	}
+
+	sm.processBlock(bmsg, blockchain.BFNone)
+}
+
+// processBufferedBlocks processes the buffered blocks of the download window in
+// order and requests more blocks as the window moves forward.  Once the blocks
+// of all headers of the sync peer have been processed, it switches to normal
+// mode.
+func (sm *SyncManager) processBufferedBlocks() {
+	// The blocks up to the latest checkpoint are eligible for less
+	// validation once the header list reaches it, since their headers have
+	// been verified to link together up to the checkpoint.
+	var fastAddHeight int32 = -1
+	checkpoint := sm.chain.LatestCheckpoint()
+	if back := sm.headerList.Back(); checkpoint != nil && back != nil &&
+		back.Value.(*headerNode).height >= checkpoint.Height {
+
+		fastAddHeight = checkpoint.Height
+	}
+
+	for sm.startHeader != nil {
+		node := sm.startHeader.Value.(*headerNode)
+		bmsg, ok := sm.blockBuffer[*node.hash]
+		if !ok {
+			break
+		}
+		delete(sm.blockBuffer, *node.hash)
+
+		// The peer which sent a block that fails to process is
+		// disconnected and no longer asked for blocks, and the block is
+		// requested again from another one, since the peer might only
+		// have mutated it.  Once it has failed from maxBlockFailures
+		// peers, the block is marked invalid, so the header list no
+		// longer leads through it and it is not requested forever.
+		flags := blockchain.BFNone
+		if node.height <= fastAddHeight {
+			flags = blockchain.BFFastAdd
+		}
+		if !sm.processBlock(bmsg, flags) {
+			log.Infof("Disconnecting peer %s which sent block %v "+
+				"that failed to process", bmsg.peer, node.hash)
+			if state, ok := sm.peerStates[bmsg.peer]; ok {
+				state.syncCandidate = false
+			}
+			bmsg.peer.Disconnect()
+
+			sm.blockFailures[*node.hash]++
+			if sm.blockFailures[*node.hash] >= maxBlockFailures {
+				log.Warnf("Block %v failed to process from %d "+
+					"peers -- marking it invalid", node.hash,
+					maxBlockFailures)
+				delete(sm.blockFailures, *node.hash)
+				err := sm.chain.InvalidateBlock(node.hash)
+				if err != nil {
+					log.Warnf("Failed to mark block %v "+
+						"invalid: %v", node.hash, err)
 				}
 			}
+			sm.updateHeaderList()
+			break
 		}
+		delete(sm.blockFailures, *node.hash)
+		sm.lastProgressTime = time.Now()
+
+		next := sm.startHeader.Next()
+		sm.headerList.Remove(sm.startHeader)
+		sm.startHeader = next
 	}
 
-	// Remove block from request maps. Either chain will know about it and
-	// so we shouldn't have any more instances of trying to fetch it, or we
-	// will fail the insert and thus we'll retry next time we get an inv.
-	delete(state.requestedBlocks, *blockHash)
-	delete(sm.requestedBlocks, *blockHash)
+	if sm.startHeader != nil {
+		sm.fetchHeaderBlocks()
+	} else if sm.headersDone {
+		sm.switchToNormalMode()
+	}
+}
+
+// processBlock processes the passed block received from a peer with the passed
+// behavior flags and updates the state of the peer and the chain accordingly.
+// It returns whether the block was processed without error.
+func (sm *SyncManager) processBlock(bmsg *blockMsg, flags blockchain.BehaviorFlags) bool {
+	peer := bmsg.peer
+	blockHash := bmsg.block.Hash()
 
 	// Process the block to include validation, best chain selection, orphan
 	// handling, etc.
-	_, isOrphan, err := sm.chain.ProcessBlock(bmsg.block, behaviorFlags)
+	_, isOrphan, err := sm.chain.ProcessBlock(bmsg.block, flags)
 	if err != nil {
 		// When the error is a rule error, it means the block was simply
 		// rejected as opposed to something actually going wrong, so log
@@ -704,7 +907,7 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		// send it.
 		code, reason := mempool.ErrToRejectErr(err)
 		peer.PushRejectMsg(wire.CmdBlock, code, reason, blockHash, false)
-		return
+		return false
 	}
 
 	// Meta-data about the new block this peer is reporting. We use this
@@ -764,6 +967,16 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		heightUpdate = best.Height
 		blkHashUpdate = &best.Hash
 
+		// Peers which are the first to provide new blocks are the
+		// best candidates to announce future blocks via compact
+		// blocks, and are protected from inbound eviction.
+		if best.Hash.IsEqual(blockHash) {
+			peer.UpdateLastNewBlockTime()
+			if sm.current() {
+				sm.updateHighBandwidthPeers(peer)
+			}
+		}
+
 		// Clear the rejected transactions.
 		sm.rejectedTxns = make(map[chainhash.Hash]struct{})
 	}
@@ -771,69 +984,250 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 	// Update the block height for this peer. But only send a message to
 	// the server for updating peer heights if this is an orphan or our
 	// chain is "current". This avoids sending a spammy amount of messages
-	// if we're syncing the chain from scratch.
+	// if we're syncing the chain from scratch.  The height is never lowered,
+	// since a peer which sent a block of the download window in
+	// headers-first mode usually has more blocks than have been processed,
+	// and it would no longer be asked for them otherwise.
 	if blkHashUpdate != nil && heightUpdate != 0 {
-		peer.UpdateLastBlockHeight(heightUpdate)
+		if heightUpdate > peer.LastBlock() {
+			peer.UpdateLastBlockHeight(heightUpdate)
+		}
 		if isOrphan || sm.current() {
 			go sm.peerNotifier.UpdatePeerHeights(blkHashUpdate, heightUpdate,
 				peer)
 		}
 	}
 
-	// Nothing more to do if we aren't in headers-first mode.
-	if !sm.headersFirstMode {
+	return true
+}
+
+// startHeadersFirst switches to headers-first mode and asks the passed peer for
+// the headers following the best known header.  The blocks of the headers which
+// are already known are downloaded while the headers are received.
+func (sm *SyncManager) startHeadersFirst(peer *peerpkg.Peer) {
+	bestHash, bestHeight := sm.chain.BestHeader()
+	locator := sm.chain.BlockLocatorFromHash(&bestHash)
+	err := peer.PushGetHeadersMsg(locator, &zeroHash)
+	if err != nil {
+		log.Warnf("Failed to send getheaders message to peer %s: %v",
+			peer.Addr(), err)
 		return
 	}
+	sm.headersFirstMode = true
+	log.Infof("Downloading headers after height %d from peer %s",
+		bestHeight, peer.Addr())
 
-	// This is headers-first mode, so if the block is not a checkpoint
-	// request more blocks using the header list when the request queue is
-	// getting short.
-	if !isCheckpointBlock {
-		if sm.startHeader != nil &&
-			len(state.requestedBlocks) < minInFlightBlocks {
-			sm.fetchHeaderBlocks()
-		}
+	sm.updateHeaderList()
+	if sm.startHeader != nil {
+		sm.progressLogger.SetLastLogTime(time.Now())
+		sm.fetchHeaderBlocks()
+	}
+}
+
+// switchToNormalMode leaves headers-first mode once the blocks of all headers
+// of the sync peer have been processed, and requests the blocks which were
+// found since then from the sync peer with standard inv messages.
+func (sm *SyncManager) switchToNormalMode() {
+	sm.headersFirstMode = false
+	sm.headersDone = false
+	sm.headerList.Init()
+	log.Infof("Downloaded the blocks of all headers -- switching to " +
+		"normal mode")
+
+	locator, err := sm.chain.LatestBlockLocator()
+	if err != nil {
+		log.Errorf("Failed to get block locator for the latest "+
+			"block: %v", err)
 		return
 	}
+	err = sm.syncPeer.PushGetBlocksMsg(locator, &zeroHash)
+	if err != nil {
+		log.Warnf("Failed to send getblocks message to peer %s: %v",
+			sm.syncPeer.Addr(), err)
+	}
+}
 
-	// This is headers-first mode and the block is a checkpoint.  When
-	// there is a next checkpoint, get the next round of headers by asking
-	// for headers starting from the block after this one up to the next
-	// checkpoint.
-	prevHeight := sm.nextCheckpoint.Height
-	prevHash := sm.nextCheckpoint.Hash
-	sm.nextCheckpoint = sm.findNextHeaderCheckpoint(prevHeight)
-	if sm.nextCheckpoint != nil {
-		locator := blockchain.BlockLocator([]*chainhash.Hash{prevHash})
-		err := peer.PushGetHeadersMsg(locator, sm.nextCheckpoint.Hash)
-		if err != nil {
-			log.Warnf("Failed to send getheaders message to "+
-				"peer %s: %v", peer.Addr(), err)
-			return
+// handleCmpctBlockMsg handles cmpctblock messages from all peers.  The block
+// is reconstructed from the transactions in the memory pool and the missing
+// transactions are requested from the peer.  When the block can't be
+// reconstructed, the full block is requested instead.
+func (sm *SyncManager) handleCmpctBlockMsg(cmsg *cmpctBlockMsg) {
+	peer := cmsg.peer
+	state, exists := sm.peerStates[peer]
+	if !exists {
+		log.Warnf("Received cmpctblock message from unknown peer %s", peer)
+		return
+	}
+
+	// Compact blocks are only accepted from peers which agreed on a
+	// compact block version.
+	version := peer.CmpctBlockVersion()
+	if version == 0 {
+		log.Debugf("Ignoring cmpctblock from %s which did not send "+
+			"sendcmpct", peer)
+		return
+	}
+
+	msg := cmsg.cmpctBlock
+	blockHash := msg.Header.BlockHash()
+	haveBlock, err := sm.chain.HaveBlock(&blockHash)
+	if err != nil {
+		log.Warnf("Unexpected failure when checking for existing "+
+			"block %v: %v", blockHash, err)
+		return
+	}
+	if haveBlock {
+		delete(state.requestedBlocks, blockHash)
+		delete(sm.requestedBlocks, blockHash)
+		return
+	}
+
+	// Check the proof of work of the header and that its parent is known
+	// before doing the comparatively expensive work of reconstructing the
+	// block from the mempool, so unsolicited junk is rejected cheaply.  A
+	// block with an unknown parent is requested in full when it was
+	// requested from the peer so it is handled like any other orphan
+	// block.
+	headerBlock := bronutil.NewBlock(&wire.MsgBlock{Header: msg.Header})
+	err = blockchain.CheckProofOfWork(headerBlock, sm.chainParams.PowLimit)
+	if err != nil {
+		log.Infof("Rejected compact block %v from %s: %v -- "+
+			"disconnecting", blockHash, peer, err)
+		peer.Disconnect()
+		return
+	}
+	haveParent, err := sm.chain.HaveBlock(&msg.Header.PrevBlock)
+	if err != nil {
+		log.Warnf("Unexpected failure when checking for parent %v of "+
+			"block %v: %v", msg.Header.PrevBlock, blockHash, err)
+		return
+	}
+	if !haveParent {
+		log.Debugf("Ignoring compact block %v from %s with unknown "+
+			"parent %v", blockHash, peer, msg.Header.PrevBlock)
+		if _, ok := state.requestedBlocks[blockHash]; ok {
+			sm.requestFullBlock(peer, &blockHash)
 		}
-		log.Infof("Downloading headers for blocks %d to %d from "+
-			"peer %s", prevHeight+1, sm.nextCheckpoint.Height,
-			sm.syncPeer.Addr())
 		return
 	}
 
-	// This is headers-first mode, the block is a checkpoint, and there are
-	// no more checkpoints, so switch to normal mode by requesting blocks
-	// from the block after this one up to the end of the chain (zero hash).
-	sm.headersFirstMode = false
-	sm.headerList.Init()
-	log.Infof("Reached the final checkpoint -- switching to normal mode")
-	locator := blockchain.BlockLocator([]*chainhash.Hash{blockHash})
-	err = peer.PushGetBlocksMsg(locator, &zeroHash)
+	// High-bandwidth peers send compact blocks without them being
+	// requested, so treat the block as requested from this peer.
+	if _, exists := sm.requestedBlocks[blockHash]; !exists {
+		sm.requestedBlocks[blockHash] = struct{}{}
+		sm.limitMap(sm.requestedBlocks, maxRequestedBlocks)
+	}
+	state.requestedBlocks[blockHash] = struct{}{}
+
+	pb, err := newPartialBlock(msg, version, sm.txMemPool.TxDescs())
 	if err != nil {
-		log.Warnf("Failed to send getblocks message to peer %s: %v",
-			peer.Addr(), err)
+		log.Debugf("Unable to reconstruct compact block %v from %s: "+
+			"%v -- requesting full block", blockHash, peer, err)
+		sm.requestFullBlock(peer, &blockHash)
+		return
+	}
+	if len(pb.missing) == 0 {
+		sm.processPartialBlock(peer, pb)
 		return
 	}
+
+	log.Debugf("Requesting %d of %d transactions of compact block %v "+
+		"from %s", len(pb.missing), len(pb.txns), blockHash, peer)
+	state.partialBlock = pb
+	gbtmsg := wire.NewMsgGetBlockTxns(&blockHash)
+	gbtmsg.Indexes = append(gbtmsg.Indexes, pb.missing...)
+	peer.QueueMessage(gbtmsg, nil)
+}
+
+// handleBlockTxnsMsg handles blocktxn messages from all peers.  The provided
+// transactions complete the compact block previously received from the peer.
+func (sm *SyncManager) handleBlockTxnsMsg(bmsg *blockTxnsMsg) {
+	peer := bmsg.peer
+	state, exists := sm.peerStates[peer]
+	if !exists {
+		log.Warnf("Received blocktxn message from unknown peer %s", peer)
+		return
+	}
+
+	msg := bmsg.blockTxns
+	pb := state.partialBlock
+	if pb == nil || pb.hash() != msg.BlockHash {
+		log.Debugf("Ignoring unrequested blocktxn for block %v from %s",
+			msg.BlockHash, peer)
+		return
+	}
+	state.partialBlock = nil
+
+	if err := pb.fill(msg.Transactions); err != nil {
+		log.Debugf("Unable to complete compact block %v from %s: %v "+
+			"-- requesting full block", msg.BlockHash, peer, err)
+		sm.requestFullBlock(peer, &msg.BlockHash)
+		return
+	}
+	sm.processPartialBlock(peer, pb)
+}
+
+// processPartialBlock processes a fully reconstructed compact block as if it
+// had been received in a block message.  The full block is requested from the
+// peer when the reconstructed block does not match its header.
+func (sm *SyncManager) processPartialBlock(peer *peerpkg.Peer, pb *partialBlock) {
+	block, err := pb.block()
+	if err != nil {
+		blockHash := pb.hash()
+		log.Debugf("Failed to reconstruct compact block %v from %s: %v "+
+			"-- requesting full block", blockHash, peer, err)
+		sm.requestFullBlock(peer, &blockHash)
+		return
+	}
+
+	sm.handleBlockMsg(&blockMsg{block: block, peer: peer})
+}
+
+// requestFullBlock requests the block with the passed hash from the peer,
+// including witness data when the peer supports it.  The block must already be
+// marked as requested from the peer.
+func (sm *SyncManager) requestFullBlock(peer *peerpkg.Peer, hash *chainhash.Hash) {
+	iv := wire.NewInvVect(wire.InvTypeBlock, hash)
+	if peer.IsWitnessEnabled() {
+		iv.Type = wire.InvTypeWitnessBlock
+	}
+	gdmsg := wire.NewMsgGetData()
+	gdmsg.AddInvVect(iv)
+	peer.QueueMessage(gdmsg, nil)
 }
 
-// fetchHeaderBlocks creates and sends a request to the syncPeer for the next
-// list of blocks to be downloaded based on the current list of headers.
+// updateHighBandwidthPeers asks the passed peer, which just provided a new best
+// block, to announce new blocks by sending compact blocks.  When there are
+// already maxHighBandwidthPeers such peers, the least recently selected one is
+// switched back to announcing new blocks via inventory vectors or headers.
+func (sm *SyncManager) updateHighBandwidthPeers(peer *peerpkg.Peer) {
+	if peer.CmpctBlockVersion() == 0 {
+		return
+	}
+
+	// Move a peer which is already selected to the end of the list.
+	for i, p := range sm.highBandwidthPeers {
+		if p == peer {
+			sm.highBandwidthPeers = append(sm.highBandwidthPeers[:i],
+				sm.highBandwidthPeers[i+1:]...)
+			sm.highBandwidthPeers = append(sm.highBandwidthPeers, peer)
+			return
+		}
+	}
+
+	if len(sm.highBandwidthPeers) >= maxHighBandwidthPeers {
+		oldest := sm.highBandwidthPeers[0]
+		oldest.PushSendCmpctMsg(false)
+		sm.highBandwidthPeers = sm.highBandwidthPeers[1:]
+	}
+	sm.highBandwidthPeers = append(sm.highBandwidthPeers, peer)
+	peer.PushSendCmpctMsg(true)
+}
+
+// fetchHeaderBlocks requests the blocks of the download window, which starts
+// with the header of the next block to process, that are neither in flight nor
+// received yet.  The requests are spread across the sync candidates, always
+// picking the one with the fewest blocks in flight.
 func (sm *SyncManager) fetchHeaderBlocks() {
 	// Nothing to do if there is no start header.
 	if sm.startHeader == nil {
@@ -841,56 +1235,134 @@ func (sm *SyncManager) fetchHeaderBlocks() {
 		return
 	}
 
-	// Build up a getdata request for the list of blocks the headers
-	// describe.  The size hint will be limited to wire.MaxInvPerMsg by
-	// the function, so no need to double check it here.
-	gdmsg := wire.NewMsgGetDataSizeHint(uint(sm.headerList.Len()))
-	numRequested := 0
+	startNode := sm.startHeader.Value.(*headerNode)
+	requests := make(map[*peerpkg.Peer]*wire.MsgGetData)
 	for e := sm.startHeader; e != nil; e = e.Next() {
 		node, ok := e.Value.(*headerNode)
 		if !ok {
 			log.Warn("Header list node type is not a headerNode")
 			continue
 		}
-
-		iv := wire.NewInvVect(wire.InvTypeBlock, node.hash)
-		haveInv, err := sm.haveInventory(iv)
-		if err != nil {
-			log.Warnf("Unexpected failure when checking for "+
-				"existing inventory during header block "+
-				"fetch: %v", err)
+		if node.height-startNode.height >= blockDownloadWindow {
+			break
+		}
+		if _, ok := sm.blocksInFlight[*node.hash]; ok {
+			continue
+		}
+		if _, ok := sm.blockBuffer[*node.hash]; ok {
+			continue
 		}
-		if !haveInv {
-			syncPeerState := sm.peerStates[sm.syncPeer]
 
-			sm.requestedBlocks[*node.hash] = struct{}{}
-			syncPeerState.requestedBlocks[*node.hash] = struct{}{}
+		// Stop once no peer may be asked for more blocks.
+		peer := sm.downloadPeer(node.height, nil)
+		if peer == nil {
+			break
+		}
+		gdmsg, ok := requests[peer]
+		if !ok {
+			gdmsg = wire.NewMsgGetData()
+			requests[peer] = gdmsg
+		}
+		gdmsg.AddInvVect(sm.requestHeaderBlock(peer, node.hash))
+	}
+	for peer, gdmsg := range requests {
+		peer.QueueMessage(gdmsg, nil)
+	}
+}
 
-			// If we're fetching from a witness enabled peer
-			// post-fork, then ensure that we receive all the
-			// witness data in the blocks.
-			if sm.syncPeer.IsWitnessEnabled() {
-				iv.Type = wire.InvTypeWitnessBlock
-			}
+// downloadPeer returns the sync candidate other than the ignored peer which
+// has the fewest blocks in flight and is known to have the block at the passed
+// height.  It returns nil when all such peers already have
+// maxBlocksInFlightPerPeer blocks in flight.
+func (sm *SyncManager) downloadPeer(height int32, ignore *peerpkg.Peer) *peerpkg.Peer {
+	var bestPeer *peerpkg.Peer
+	bestInFlight := maxBlocksInFlightPerPeer
+	for peer, state := range sm.peerStates {
+		if peer == ignore || !state.syncCandidate ||
+			peer.LastBlock() < height {
 
-			gdmsg.AddInvVect(iv)
-			numRequested++
+			continue
 		}
-		sm.startHeader = e.Next()
-		if numRequested >= wire.MaxInvPerMsg {
+		if len(state.requestedBlocks) < bestInFlight {
+			bestPeer = peer
+			bestInFlight = len(state.requestedBlocks)
+		}
+	}
+	return bestPeer
+}
+
+// requestHeaderBlock marks the block of the download window with the passed
+// hash as requested from the peer and returns the inventory vector to request
+// it with.
+func (sm *SyncManager) requestHeaderBlock(peer *peerpkg.Peer, hash *chainhash.Hash) *wire.InvVect {
+	sm.requestedBlocks[*hash] = struct{}{}
+	sm.peerStates[peer].requestedBlocks[*hash] = struct{}{}
+	sm.blocksInFlight[*hash] = &blockRequest{
+		peer:      peer,
+		requested: time.Now(),
+	}
+
+	// If we're fetching from a witness enabled peer post-fork, then ensure
+	// that we receive all the witness data in the blocks.
+	iv := wire.NewInvVect(wire.InvTypeBlock, hash)
+	if peer.IsWitnessEnabled() {
+		iv.Type = wire.InvTypeWitnessBlock
+	}
+	return iv
+}
+
+// updateHeaderList brings the header list in line with the best header chain.
+// Headers which are no longer on it are removed, and the headers following the
+// last header of the list, or the best block when it is empty, are appended so
+// their blocks are downloaded.
+func (sm *SyncManager) updateHeaderList() {
+	bestHash, _ := sm.chain.BestHeader()
+	startHash := sm.chain.BestSnapshot().Hash
+	if back := sm.headerList.Back(); back != nil {
+		startHash = *back.Value.(*headerNode).hash
+	}
+	height, hashes, err := sm.chain.HeaderHashesAfter(&startHash, &bestHash)
+	if err != nil {
+		log.Warnf("Failed to get the headers after block %v: %v",
+			startHash, err)
+		return
+	}
+
+	// Remove the headers from the fork point with the best header chain
+	// on along with any state of their blocks.
+	for back := sm.headerList.Back(); back != nil; back = sm.headerList.Back() {
+		node := back.Value.(*headerNode)
+		if node.height < height {
 			break
 		}
+		if back == sm.startHeader {
+			sm.startHeader = nil
+		}
+		delete(sm.blocksInFlight, *node.hash)
+		delete(sm.blockBuffer, *node.hash)
+		delete(sm.blockFailures, *node.hash)
+		sm.headerList.Remove(back)
 	}
-	if len(gdmsg.InvList) > 0 {
-		sm.syncPeer.QueueMessage(gdmsg, nil)
+
+	for i := range hashes {
+		// Skip blocks which were already stored, such as side chain
+		// blocks of a chain which now has the most work.
+		if have, _ := sm.chain.HaveBlock(&hashes[i]); have {
+			continue
+		}
+		node := headerNode{height: height + int32(i), hash: &hashes[i]}
+		sm.headerList.PushBack(&node)
+	}
+	if sm.startHeader == nil {
+		sm.startHeader = sm.headerList.Front()
 	}
 }
 
 // handleHeadersMsg handles block header messages from all peers.  Headers are
-// requested when performing a headers-first sync.
+// requested from the sync peer when performing a headers-first sync.
 func (sm *SyncManager) handleHeadersMsg(hmsg *headersMsg) {
 	peer := hmsg.peer
-	_, exists := sm.peerStates[peer]
+	state, exists := sm.peerStates[peer]
 	if !exists {
 		log.Warnf("Received headers message from unknown peer %s", peer)
 		return
@@ -906,92 +1378,81 @@ func (sm *SyncManager) handleHeadersMsg(hmsg *headersMsg) {
 		return
 	}
 
-	// Nothing to do for an empty headers message.
-	if numHeaders == 0 {
+	// Headers requested from a previous sync peer are of no use anymore.
+	if peer != sm.syncPeer {
+		log.Debugf("Ignoring %d headers from %s which is no longer the "+
+			"sync peer", numHeaders, peer)
 		return
 	}
 
-	// Process all of the received headers ensuring each one connects to the
-	// previous and that checkpoints match.
-	receivedCheckpoint := false
-	var finalHash *chainhash.Hash
-	for _, blockHeader := range msg.Headers {
-		blockHash := blockHeader.BlockHash()
-		finalHash = &blockHash
-
-		// Ensure there is a previous header to compare against.
-		prevNodeEl := sm.headerList.Back()
-		if prevNodeEl == nil {
-			log.Warnf("Header list does not contain a previous" +
-				"element as expected -- disconnecting peer")
-			peer.Disconnect()
-			return
-		}
+	// Validate the headers and add them to the block index once the chain
+	// they form has enough work.  Until then, they are kept in memory
+	// without requesting their blocks.
+	var err error
+	sm.pendingHeaders, err = sm.chain.ProcessBlockHeaders(sm.pendingHeaders,
+		msg.Headers, blockchain.BFNone)
+	if err != nil {
+		log.Warnf("Received invalid block headers from peer %s: %v "+
+			"-- disconnecting", peer.Addr(), err)
+		peer.Disconnect()
+		return
+	}
+	if numHeaders > 0 {
+		sm.lastProgressTime = time.Now()
+	}
 
-		// Ensure the header properly connects to the previous one and
-		// add it to the list of headers.
-		node := headerNode{hash: &blockHash}
-		prevNode := prevNodeEl.Value.(*headerNode)
-		if prevNode.hash.IsEqual(&blockHeader.PrevBlock) {
-			node.height = prevNode.height + 1
-			e := sm.headerList.PushBack(&node)
-			if sm.startHeader == nil {
-				sm.startHeader = e
-			}
-		} else {
-			log.Warnf("Received block header that does not "+
-				"properly connect to the chain from peer %s "+
-				"-- disconnecting", peer.Addr())
+	// Don't let a peer keep sending low-work headers, since they would
+	// pile up in memory.  The number of pending headers is bounded by the
+	// work they have, rather than the height the peer advertised, so a
+	// chain which can't get enough work within maxPendingHeaders headers
+	// is dropped right away.
+	if sm.pendingHeaders != nil {
+		needed := sm.chain.HeadersNeeded(sm.pendingHeaders)
+		if sm.pendingHeaders.Len() > maxPendingHeaders ||
+			needed > maxPendingHeaders {
+
+			log.Warnf("Received %d low-work block headers from "+
+				"peer %s which would need %d headers to have "+
+				"enough work -- disconnecting",
+				sm.pendingHeaders.Len(), peer.Addr(), needed)
 			peer.Disconnect()
 			return
 		}
-
-		// Verify the header at the next checkpoint height matches.
-		if node.height == sm.nextCheckpoint.Height {
-			if node.hash.IsEqual(sm.nextCheckpoint.Hash) {
-				receivedCheckpoint = true
-				log.Infof("Verified downloaded block "+
-					"header against checkpoint at height "+
-					"%d/hash %s", node.height, node.hash)
-			} else {
-				log.Warnf("Block header at height %d/hash "+
-					"%s from peer %s does NOT match "+
-					"expected checkpoint hash of %s -- "+
-					"disconnecting", node.height,
-					node.hash, peer.Addr(),
-					sm.nextCheckpoint.Hash)
-				peer.Disconnect()
-				return
-			}
-			break
+	} else if numHeaders > 0 {
+		sm.updateHeaderList()
+		if sm.startHeader != nil {
+			sm.fetchHeaderBlocks()
 		}
 	}
 
-	// When this header is a checkpoint, switch to fetching the blocks for
-	// all of the headers since the last checkpoint.
-	if receivedCheckpoint {
-		// Since the first entry of the list is always the final block
-		// that is already in the database and is only used to ensure
-		// the next header links properly, it must be removed before
-		// fetching the blocks.
-		sm.headerList.Remove(sm.headerList.Front())
-		log.Infof("Received %v block headers: Fetching blocks",
-			sm.headerList.Len())
-		sm.progressLogger.SetLastLogTime(time.Now())
-		sm.fetchHeaderBlocks()
+	// Request the next batch of headers when the message was full, since
+	// the peer likely has more of them.
+	if numHeaders == wire.MaxBlockHeadersPerMsg {
+		finalHash := msg.Headers[numHeaders-1].BlockHash()
+		locator := blockchain.BlockLocator([]*chainhash.Hash{&finalHash})
+		err := peer.PushGetHeadersMsg(locator, &zeroHash)
+		if err != nil {
+			log.Warnf("Failed to send getheaders message to "+
+				"peer %s: %v", peer.Addr(), err)
+		}
 		return
 	}
 
-	// This header is not a checkpoint, so request the next batch of
-	// headers starting from the latest known header and ending with the
-	// next checkpoint.
-	locator := blockchain.BlockLocator([]*chainhash.Hash{finalHash})
-	err := peer.PushGetHeadersMsg(locator, sm.nextCheckpoint.Hash)
-	if err != nil {
-		log.Warnf("Failed to send getheaders message to "+
-			"peer %s: %v", peer.Addr(), err)
+	// The peer has sent all of its headers.  When they still do not have
+	// enough work, the chain of the peer is of no use, so it is dropped and
+	// the peer is no longer considered for syncing.
+	sm.headersDone = true
+	if sm.pendingHeaders != nil {
+		log.Infof("Peer %s only knows a chain with too little work -- "+
+			"choosing another sync peer", peer)
+		state.syncCandidate = false
+		sm.updateSyncPeer(false)
 		return
 	}
+	log.Infof("Received all block headers from peer %s", peer)
+	if sm.startHeader == nil {
+		sm.switchToNormalMode()
+	}
 }
 
 // haveInventory returns whether or not the inventory represented by the passed
@@ -1206,7 +1667,13 @@ func (sm *SyncManager) handleInvMsg(imsg *invMsg) {
 				sm.limitMap(sm.requestedBlocks, maxRequestedBlocks)
 				state.requestedBlocks[iv.Hash] = struct{}{}
 
-				if peer.IsWitnessEnabled() {
+				// Request newly announced blocks as compact
+				// blocks from peers which support them once
+				// the chain is current.
+				switch {
+				case sm.current() && peer.CmpctBlockVersion() != 0:
+					iv.Type = wire.InvTypeCmpctBlock
+				case peer.IsWitnessEnabled():
 					iv.Type = wire.InvTypeWitnessBlock
 				}
 
@@ -1289,6 +1756,14 @@ out:
 				sm.handleBlockMsg(msg)
 				msg.reply <- struct{}{}
 
+			case *cmpctBlockMsg:
+				sm.handleCmpctBlockMsg(msg)
+				msg.reply <- struct{}{}
+
+			case *blockTxnsMsg:
+				sm.handleBlockTxnsMsg(msg)
+				msg.reply <- struct{}{}
+
 			case *invMsg:
 				sm.handleInvMsg(msg)
 
@@ -1384,7 +1859,8 @@ func (sm *SyncManager) handleBlockchainNotification(notification *blockchain.Not
 		// transaction are NOT removed recursively because they are still
 		// valid.
 		for _, tx := range block.Transactions()[1:] {
-			sm.txMemPool.RemoveTransaction(tx, false)
+			sm.txMemPool.RemoveTransaction(tx, false,
+				mempool.RemovedConfirmed)
 			sm.txMemPool.RemoveDoubleSpends(tx)
 			sm.txMemPool.RemoveOrphan(tx)
 			sm.peerNotifier.TransactionConfirmed(tx)
@@ -1400,9 +1876,7 @@ func (sm *SyncManager) handleBlockchainNotification(notification *blockchain.Not
 			// has entered an invalid state. Since it doesn't know how
 			// to recover, create a new one.
 			if err != nil {
-				sm.feeEstimator = mempool.NewFeeEstimator(
-					mempool.DefaultEstimateFeeMaxRollback,
-					mempool.DefaultEstimateFeeMinRegisteredBlocks)
+				sm.feeEstimator = mempool.NewFeeEstimator()
 			}
 		}
 
@@ -1423,14 +1897,14 @@ func (sm *SyncManager) handleBlockchainNotification(notification *blockchain.Not
 				// Remove the transaction and all transactions
 				// that depend on it if it wasn't accepted into
 				// the transaction pool.
-				sm.txMemPool.RemoveTransaction(tx, true)
+				sm.txMemPool.RemoveTransaction(tx, true,
+					mempool.RemovedReorg)
 			}
 		}
 
-		// Rollback previous block recorded by the fee estimator.
-		if sm.feeEstimator != nil {
-			sm.feeEstimator.Rollback(block.Hash())
-		}
+		// Note that the fee estimator is not informed about disconnected
+		// blocks.  It ignores the blocks connected while reorganizing up
+		// to its last known height instead.
 	}
 }
 
@@ -1469,6 +1943,32 @@ func (sm *SyncManager) QueueBlock(block *bronutil.Block, peer *peerpkg.Peer, don
 	sm.msgChan <- &blockMsg{block: block, peer: peer, reply: done}
 }
 
+// QueueCmpctBlock adds the passed cmpctblock message and peer to the block
+// handling queue. Responds to the done channel argument after the cmpctblock
+// message is processed.
+func (sm *SyncManager) QueueCmpctBlock(msg *wire.MsgCmpctBlock, peer *peerpkg.Peer, done chan struct{}) {
+	// Don't accept more blocks if we're shutting down.
+	if atomic.LoadInt32(&sm.shutdown) != 0 {
+		done <- struct{}{}
+		return
+	}
+
+	sm.msgChan <- &cmpctBlockMsg{cmpctBlock: msg, peer: peer, reply: done}
+}
+
+// QueueBlockTxns adds the passed blocktxn message and peer to the block
+// handling queue. Responds to the done channel argument after the blocktxn
+// message is processed.
+func (sm *SyncManager) QueueBlockTxns(msg *wire.MsgBlockTxns, peer *peerpkg.Peer, done chan struct{}) {
+	// Don't accept more blocks if we're shutting down.
+	if atomic.LoadInt32(&sm.shutdown) != 0 {
+		done <- struct{}{}
+		return
+	}
+
+	sm.msgChan <- &blockTxnsMsg{blockTxns: msg, peer: peer, reply: done}
+}
+
 // QueueInv adds the passed inv message and peer to the block handling queue.
 func (sm *SyncManager) QueueInv(inv *wire.MsgInv, peer *peerpkg.Peer) {
 	// No channel handling here because peers do not need to block on inv
@@ -1576,24 +2076,20 @@ func New(config *Config) (*SyncManager, error) {
 		requestedBlocks: make(map[chainhash.Hash]struct{}),
 		peerStates:      make(map[*peerpkg.Peer]*peerSyncState),
 		progressLogger:  newBlockProgressLogger("Processed", log),
+		blocksInFlight:  make(map[chainhash.Hash]*blockRequest),
+		blockBuffer:     make(map[chainhash.Hash]*blockMsg),
+		blockFailures:   make(map[chainhash.Hash]int),
 		msgChan:         make(chan interface{}, config.MaxPeers*3),
 		headerList:      list.New(),
 		quit:            make(chan struct{}),
 		feeEstimator:    config.FeeEstimator,
 	}
 
-	best := sm.chain.BestSnapshot()
-	if !config.DisableCheckpoints {
-		// Initialize the next checkpoint based on the current height.
-		sm.nextCheckpoint = sm.findNextHeaderCheckpoint(best.Height)
-		if sm.nextCheckpoint != nil {
-			sm.resetHeaderState(&best.Hash, best.Height)
-		}
-	} else {
+	if config.DisableCheckpoints {
 		log.Info("Checkpoints are disabled")
 	}
 
-	sm.chain.Subscribe(sm.handleBlockchainNotification)
+	config.Chain.Subscribe(sm.handleBlockchainNotification)
 
 	return &sm, nil
 }
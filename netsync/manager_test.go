@@ -0,0 +1,450 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package netsync
+
+import (
+	"container/list"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/brsuite/brond/blockchain"
+	"github.com/brsuite/brond/chaincfg"
+	"github.com/brsuite/brond/chaincfg/chainhash"
+	peerpkg "github.com/brsuite/brond/peer"
+	"github.com/brsuite/brond/wire"
+	"github.com/brsuite/bronutil"
+)
+
+// fakeChain is a block chain of a single branch of headers which is used to
+// test the block download.  The blocks of the headers are processed in the
+// order they are passed to ProcessBlock, which records them.  Methods which are
+// not used by the tests are provided by the nil embedded interface and panic.
+type fakeChain struct {
+	syncChain
+
+	blocks      []*bronutil.Block
+	bestHeight  int32
+	tipHeight   int32
+	invalid     map[chainhash.Hash]struct{}
+	processed   []chainhash.Hash
+	invalidated []chainhash.Hash
+}
+
+// newFakeChain returns a fake chain with the passed number of headers after the
+// genesis block whose blocks have not been processed yet.
+func newFakeChain(numHeaders int) *fakeChain {
+	genesis := chaincfg.MainNetParams.GenesisBlock
+	blocks := []*bronutil.Block{bronutil.NewBlock(genesis)}
+	prev := &genesis.Header
+	for i := 0; i < numHeaders; i++ {
+		header := wire.NewBlockHeader(1, blockHash(prev), &chainhash.Hash{},
+			0, uint32(i))
+		header.Timestamp = prev.Timestamp.Add(10 * time.Minute)
+		blocks = append(blocks, bronutil.NewBlock(wire.NewMsgBlock(header)))
+		prev = header
+	}
+	return &fakeChain{
+		blocks:     blocks,
+		bestHeight: int32(numHeaders),
+		invalid:    make(map[chainhash.Hash]struct{}),
+	}
+}
+
+// blockHash returns the hash of the passed header.
+func blockHash(header *wire.BlockHeader) *chainhash.Hash {
+	hash := header.BlockHash()
+	return &hash
+}
+
+// hash returns the hash of the block at the passed height.
+func (c *fakeChain) hash(height int32) chainhash.Hash {
+	return *c.blocks[height].Hash()
+}
+
+// height returns the height of the block with the passed hash, or -1 when it
+// is not known.
+func (c *fakeChain) height(hash *chainhash.Hash) int32 {
+	for i, block := range c.blocks {
+		if block.Hash().IsEqual(hash) {
+			return int32(i)
+		}
+	}
+	return -1
+}
+
+func (c *fakeChain) BestHeader() (chainhash.Hash, int32) {
+	return c.hash(c.bestHeight), c.bestHeight
+}
+
+func (c *fakeChain) BestSnapshot() *blockchain.BestState {
+	return &blockchain.BestState{
+		Hash:   c.hash(c.tipHeight),
+		Height: c.tipHeight,
+	}
+}
+
+func (c *fakeChain) HaveBlock(hash *chainhash.Hash) (bool, error) {
+	height := c.height(hash)
+	return height != -1 && height <= c.tipHeight, nil
+}
+
+func (c *fakeChain) HeaderHashesAfter(startHash, endHash *chainhash.Hash) (int32, []chainhash.Hash, error) {
+	start, end := c.height(startHash), c.height(endHash)
+	if start == -1 || end == -1 {
+		return 0, nil, fmt.Errorf("unknown block header")
+	}
+	if end < start {
+		return end + 1, nil, nil
+	}
+	var hashes []chainhash.Hash
+	for height := start + 1; height <= end; height++ {
+		hashes = append(hashes, c.hash(height))
+	}
+	return start + 1, hashes, nil
+}
+
+func (c *fakeChain) InvalidateBlock(hash *chainhash.Hash) error {
+	c.invalidated = append(c.invalidated, *hash)
+	if height := c.height(hash); height <= c.bestHeight {
+		c.bestHeight = height - 1
+	}
+	return nil
+}
+
+func (c *fakeChain) IsCurrent() bool {
+	return false
+}
+
+func (c *fakeChain) LatestCheckpoint() *chaincfg.Checkpoint {
+	return nil
+}
+
+func (c *fakeChain) ProcessBlock(block *bronutil.Block, flags blockchain.BehaviorFlags) (bool, bool, error) {
+	if _, ok := c.invalid[*block.Hash()]; ok {
+		return false, false, blockchain.RuleError{
+			ErrorCode:   blockchain.ErrNoTransactions,
+			Description: "block does not contain any transactions",
+		}
+	}
+	c.processed = append(c.processed, *block.Hash())
+	c.tipHeight++
+	return true, false, nil
+}
+
+// newTestSyncManager returns a sync manager in headers-first mode whose header
+// list holds the headers of the passed chain.  The package logger is not set up
+// by default, so logging is disabled.
+func newTestSyncManager(chain *fakeChain) *SyncManager {
+	DisableLog()
+	sm := &SyncManager{
+		chain:           chain,
+		chainParams:     &chaincfg.MainNetParams,
+		progressLogger:  newBlockProgressLogger("Processed", log),
+		rejectedTxns:    make(map[chainhash.Hash]struct{}),
+		requestedTxns:   make(map[chainhash.Hash]struct{}),
+		requestedBlocks: make(map[chainhash.Hash]struct{}),
+		peerStates:      make(map[*peerpkg.Peer]*peerSyncState),
+		blockFailures:   make(map[chainhash.Hash]int),
+		headerList:      list.New(),
+		quit:            make(chan struct{}),
+	}
+	sm.resetHeaderState()
+	sm.headersFirstMode = true
+	sm.updateHeaderList()
+	return sm
+}
+
+// addTestPeer adds a sync candidate which knows the blocks up to the passed
+// height to the sync manager.  The peer is never connected, so the messages
+// queued for it are dropped.
+func addTestPeer(t *testing.T, sm *SyncManager, lastBlock int32) *peerpkg.Peer {
+	peer := peerpkg.NewInboundPeer(&peerpkg.Config{})
+	peer.UpdateLastBlockHeight(lastBlock)
+	sm.peerStates[peer] = &peerSyncState{
+		syncCandidate:   true,
+		requestedTxns:   make(map[chainhash.Hash]struct{}),
+		requestedBlocks: make(map[chainhash.Hash]struct{}),
+	}
+	t.Cleanup(peer.Disconnect)
+	return peer
+}
+
+// isDisconnected returns whether the passed peer was disconnected.
+func isDisconnected(peer *peerpkg.Peer) bool {
+	done := make(chan struct{})
+	go func() {
+		peer.WaitForDisconnect()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(100 * time.Millisecond):
+		return false
+	}
+}
+
+// inFlightHeights returns the sorted heights of the blocks in flight.
+func inFlightHeights(sm *SyncManager, chain *fakeChain) []int32 {
+	var heights []int32
+	for hash := range sm.blocksInFlight {
+		heights = append(heights, chain.height(&hash))
+	}
+	sort.Slice(heights, func(i, j int) bool {
+		return heights[i] < heights[j]
+	})
+	return heights
+}
+
+// heightRange returns the heights from first through last.
+func heightRange(first, last int32) []int32 {
+	var heights []int32
+	for height := first; height <= last; height++ {
+		heights = append(heights, height)
+	}
+	return heights
+}
+
+// deliverBlock hands the block at the passed height to the sync manager as if
+// it was received from the peer it was requested from.
+func deliverBlock(t *testing.T, sm *SyncManager, chain *fakeChain, height int32) *peerpkg.Peer {
+	hash := chain.hash(height)
+	req, ok := sm.blocksInFlight[hash]
+	if !ok {
+		t.Fatalf("block %d is not in flight", height)
+	}
+	sm.handleBlockMsg(&blockMsg{block: chain.blocks[height], peer: req.peer})
+	return req.peer
+}
+
+// TestFetchHeaderBlocksWindow ensures only the blocks of the download window
+// are requested, and that the window moves forward as the next block to process
+// is received, but not when a block ahead of it is received.  The peer which
+// sent the next block must keep its height so it is still asked for blocks.
+func TestFetchHeaderBlocksWindow(t *testing.T) {
+	chain := newFakeChain(2 * blockDownloadWindow)
+	sm := newTestSyncManager(chain)
+	for i := 0; i <= blockDownloadWindow/maxBlocksInFlightPerPeer; i++ {
+		addTestPeer(t, sm, chain.bestHeight)
+	}
+
+	sm.fetchHeaderBlocks()
+	want := heightRange(1, blockDownloadWindow)
+	if got := inFlightHeights(sm, chain); !reflect.DeepEqual(got, want) {
+		t.Fatalf("initial window: got blocks %v in flight, want %v",
+			got, want)
+	}
+
+	sender := deliverBlock(t, sm, chain, 1)
+	if chain.tipHeight != 1 {
+		t.Fatalf("got tip height %d, want 1", chain.tipHeight)
+	}
+	if sender.LastBlock() != chain.bestHeight {
+		t.Fatalf("got height %d of the peer which sent block 1, want "+
+			"%d", sender.LastBlock(), chain.bestHeight)
+	}
+	want = heightRange(2, blockDownloadWindow+1)
+	if got := inFlightHeights(sm, chain); !reflect.DeepEqual(got, want) {
+		t.Fatalf("moved window: got blocks %v in flight, want %v",
+			got, want)
+	}
+
+	deliverBlock(t, sm, chain, 3)
+	if chain.tipHeight != 1 || len(sm.blockBuffer) != 1 {
+		t.Fatalf("got tip height %d and %d buffered blocks, want 1 "+
+			"and 1", chain.tipHeight, len(sm.blockBuffer))
+	}
+	want = append([]int32{2}, heightRange(4, blockDownloadWindow+1)...)
+	if got := inFlightHeights(sm, chain); !reflect.DeepEqual(got, want) {
+		t.Fatalf("window after buffering: got blocks %v in flight, "+
+			"want %v", got, want)
+	}
+}
+
+// TestFetchHeaderBlocksPeerLimit ensures no more than maxBlocksInFlightPerPeer
+// blocks are requested from a peer, and that peers are not asked for blocks
+// they do not have.
+func TestFetchHeaderBlocksPeerLimit(t *testing.T) {
+	chain := newFakeChain(2 * blockDownloadWindow)
+	sm := newTestSyncManager(chain)
+	peers := []*peerpkg.Peer{
+		addTestPeer(t, sm, chain.bestHeight),
+		addTestPeer(t, sm, chain.bestHeight),
+	}
+	behind := addTestPeer(t, sm, 0)
+
+	sm.fetchHeaderBlocks()
+	want := heightRange(1, 2*maxBlocksInFlightPerPeer)
+	if got := inFlightHeights(sm, chain); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got blocks %v in flight, want %v", got, want)
+	}
+	for i, peer := range peers {
+		got := len(sm.peerStates[peer].requestedBlocks)
+		if got != maxBlocksInFlightPerPeer {
+			t.Errorf("peer %d: got %d blocks in flight, want %d", i,
+				got, maxBlocksInFlightPerPeer)
+		}
+	}
+	if got := len(sm.peerStates[behind].requestedBlocks); got != 0 {
+		t.Errorf("peer without the blocks: got %d blocks in flight, "+
+			"want 0", got)
+	}
+}
+
+// TestBlockRequestTimeout ensures a block whose request timed out is requested
+// from another peer.
+func TestBlockRequestTimeout(t *testing.T) {
+	chain := newFakeChain(10)
+	sm := newTestSyncManager(chain)
+	addTestPeer(t, sm, chain.bestHeight)
+	addTestPeer(t, sm, chain.bestHeight)
+	sm.fetchHeaderBlocks()
+
+	hash := chain.hash(2)
+	req := sm.blocksInFlight[hash]
+	timedOut := req.peer
+	req.requested = time.Now().Add(-blockRequestTimeout - time.Second)
+
+	sm.handleBlockDownloadStalls()
+	req = sm.blocksInFlight[hash]
+	if req.peer == timedOut {
+		t.Fatal("timed out block was not requested from another peer")
+	}
+	if _, ok := sm.peerStates[req.peer].requestedBlocks[hash]; !ok {
+		t.Fatal("timed out block is not in flight from the new peer")
+	}
+	if isDisconnected(timedOut) {
+		t.Fatal("peer whose request timed out was disconnected")
+	}
+}
+
+// TestBlockDownloadStall ensures the peer which holds up the next block to
+// process is disconnected once all other blocks of the window have been
+// received, unless there is no other peer to download the block from.
+func TestBlockDownloadStall(t *testing.T) {
+	tests := []struct {
+		name         string
+		numPeers     int
+		disconnected bool
+	}{
+		{name: "other peers", numPeers: 2, disconnected: true},
+		{name: "single peer", numPeers: 1, disconnected: false},
+	}
+	for _, test := range tests {
+		chain := newFakeChain(10)
+		sm := newTestSyncManager(chain)
+		for i := 0; i < test.numPeers; i++ {
+			addTestPeer(t, sm, chain.bestHeight)
+		}
+		sm.fetchHeaderBlocks()
+
+		// Leave the next block to process in flight while the rest of
+		// the window is received.
+		for height := chain.bestHeight; height > 1; height-- {
+			deliverBlock(t, sm, chain, height)
+		}
+		req := sm.blocksInFlight[chain.hash(1)]
+		req.requested = time.Now().Add(-blockStallTimeout - time.Second)
+
+		sm.handleBlockDownloadStalls()
+		if got := isDisconnected(req.peer); got != test.disconnected {
+			t.Errorf("%s: got stalling peer disconnected %v, want %v",
+				test.name, got, test.disconnected)
+		}
+		for peer := range sm.peerStates {
+			if peer != req.peer && isDisconnected(peer) {
+				t.Errorf("%s: peer which is not stalling was "+
+					"disconnected", test.name)
+			}
+		}
+	}
+}
+
+// TestProcessBufferedBlocksInOrder ensures blocks received out of order are
+// buffered and processed in the order of the header list.
+func TestProcessBufferedBlocksInOrder(t *testing.T) {
+	chain := newFakeChain(10)
+	sm := newTestSyncManager(chain)
+	addTestPeer(t, sm, chain.bestHeight)
+	addTestPeer(t, sm, chain.bestHeight)
+	sm.fetchHeaderBlocks()
+
+	for height := chain.bestHeight; height > 1; height-- {
+		deliverBlock(t, sm, chain, height)
+	}
+	if len(chain.processed) != 0 {
+		t.Fatalf("got %d blocks processed before the first one was "+
+			"received", len(chain.processed))
+	}
+	if len(sm.blockBuffer) != int(chain.bestHeight)-1 {
+		t.Fatalf("got %d buffered blocks, want %d", len(sm.blockBuffer),
+			chain.bestHeight-1)
+	}
+
+	deliverBlock(t, sm, chain, 1)
+	var want []chainhash.Hash
+	for height := int32(1); height <= chain.bestHeight; height++ {
+		want = append(want, chain.hash(height))
+	}
+	if !reflect.DeepEqual(chain.processed, want) {
+		t.Fatalf("got blocks %v processed, want %v", chain.processed,
+			want)
+	}
+	if len(sm.blockBuffer) != 0 || sm.headerList.Len() != 0 ||
+		sm.startHeader != nil {
+
+		t.Fatalf("got %d buffered blocks and %d headers left, want "+
+			"none", len(sm.blockBuffer), sm.headerList.Len())
+	}
+}
+
+// TestProcessBufferedBlocksFailure ensures the peer which sent a block that
+// fails to process is disconnected and the block is requested from another
+// peer, until it has failed from maxBlockFailures peers and is marked invalid.
+func TestProcessBufferedBlocksFailure(t *testing.T) {
+	chain := newFakeChain(5)
+	sm := newTestSyncManager(chain)
+	for i := 0; i <= maxBlockFailures; i++ {
+		addTestPeer(t, sm, chain.bestHeight)
+	}
+	invalidHash := chain.hash(3)
+	chain.invalid[invalidHash] = struct{}{}
+	sm.fetchHeaderBlocks()
+
+	deliverBlock(t, sm, chain, 1)
+	deliverBlock(t, sm, chain, 2)
+	for i := 0; i < maxBlockFailures; i++ {
+		if len(chain.invalidated) != 0 {
+			t.Fatalf("block was marked invalid after %d failures", i)
+		}
+		peer := deliverBlock(t, sm, chain, 3)
+		if !isDisconnected(peer) {
+			t.Fatalf("peer %d which sent the invalid block was not "+
+				"disconnected", i)
+		}
+		if sm.peerStates[peer].syncCandidate {
+			t.Fatalf("peer %d which sent the invalid block is "+
+				"still a sync candidate", i)
+		}
+		sm.handleDonePeerMsg(peer)
+	}
+
+	want := []chainhash.Hash{invalidHash}
+	if !reflect.DeepEqual(chain.invalidated, want) {
+		t.Fatalf("got blocks %v marked invalid, want %v",
+			chain.invalidated, want)
+	}
+	if sm.headerList.Len() != 0 || len(sm.blocksInFlight) != 0 ||
+		len(sm.blockBuffer) != 0 || len(sm.blockFailures) != 0 {
+
+		t.Fatalf("got %d headers, %d blocks in flight, %d buffered "+
+			"blocks and %d failed blocks left, want none",
+			sm.headerList.Len(), len(sm.blocksInFlight),
+			len(sm.blockBuffer), len(sm.blockFailures))
+	}
+}
@@ -73,6 +73,16 @@ Disconnect to disconnect from the peer and clean up all resources.
 WaitForDisconnect can be used to block until peer disconnection and resource
 cleanup has completed.
 
+Transport Protocols
+
+By default, messages are exchanged in plaintext with the original v1 transport
+protocol.  Setting the V2Transport field of the Config struct enables the
+encrypted v2 transport protocol defined by BIP0324.  Inbound peers then accept
+both protocols, while outbound peers attempt the v2 protocol, which peers that
+only support the v1 protocol reject by disconnecting.  The V2HandshakeFailed
+function reports when this happened, so the caller can connect again with the
+v2 transport protocol disabled.
+
 Callbacks
 
 In order to do anything useful with a peer, it is necessary to react to brocoin
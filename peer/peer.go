@@ -21,6 +21,7 @@ import (
 	"github.com/brsuite/brond/blockchain"
 	"github.com/brsuite/brond/chaincfg"
 	"github.com/brsuite/brond/chaincfg/chainhash"
+	"github.com/brsuite/brond/v2transport"
 	"github.com/brsuite/brond/wire"
 	"github.com/brsuite/go-socks/socks"
 	"github.com/davecgh/go-spew/spew"
@@ -28,7 +29,7 @@ import (
 
 const (
 	// MaxProtocolVersion is the max protocol version the peer supports.
-	MaxProtocolVersion = wire.FeeFilterVersion
+	MaxProtocolVersion = wire.AddrV2Version
 
 	// DefaultTrickleInterval is the min time between attempts to send an
 	// inv message to a peer.
@@ -106,6 +107,9 @@ type MessageListeners struct {
 	// OnAddr is invoked when a peer receives an addr brocoin message.
 	OnAddr func(p *Peer, msg *wire.MsgAddr)
 
+	// OnAddrV2 is invoked when a peer receives an addrv2 brocoin message.
+	OnAddrV2 func(p *Peer, msg *wire.MsgAddrV2)
+
 	// OnPing is invoked when a peer receives a ping brocoin message.
 	OnPing func(p *Peer, msg *wire.MsgPing)
 
@@ -186,6 +190,18 @@ type MessageListeners struct {
 	// message.
 	OnMerkleBlock func(p *Peer, msg *wire.MsgMerkleBlock)
 
+	// OnCmpctBlock is invoked when a peer receives a cmpctblock brocoin
+	// message.
+	OnCmpctBlock func(p *Peer, msg *wire.MsgCmpctBlock)
+
+	// OnGetBlockTxns is invoked when a peer receives a getblocktxn brocoin
+	// message.
+	OnGetBlockTxns func(p *Peer, msg *wire.MsgGetBlockTxns)
+
+	// OnBlockTxns is invoked when a peer receives a blocktxn brocoin
+	// message.
+	OnBlockTxns func(p *Peer, msg *wire.MsgBlockTxns)
+
 	// OnVersion is invoked when a peer receives a version brocoin message.
 	// The caller may return a reject message in which case the message will
 	// be sent to the peer and the peer will be disconnected.
@@ -201,6 +217,14 @@ type MessageListeners struct {
 	// message.
 	OnSendHeaders func(p *Peer, msg *wire.MsgSendHeaders)
 
+	// OnSendCmpct is invoked when a peer receives a sendcmpct brocoin
+	// message.
+	OnSendCmpct func(p *Peer, msg *wire.MsgSendCmpct)
+
+	// OnSendAddrV2 is invoked when a peer receives a sendaddrv2 brocoin
+	// message during the initial handshake.
+	OnSendAddrV2 func(p *Peer, msg *wire.MsgSendAddrV2)
+
 	// OnRead is invoked when a peer receives a brocoin message.  It
 	// consists of the number of bytes read, the message, and whether or not
 	// an error in the read occurred.  Typically, callers will opt to use
@@ -275,6 +299,14 @@ type Config struct {
 	// TrickleInterval is the duration of the ticker which trickles down the
 	// inventory to a peer.
 	TrickleInterval time.Duration
+
+	// V2Transport specifies whether to use the encrypted v2 transport
+	// protocol defined by BIP0324.  Inbound peers detect which protocol the
+	// remote peer uses, while outbound peers always attempt the v2
+	// protocol, so it should only be set for outbound peers when the
+	// remote peer advertises wire.SFNodeP2PV2.  See V2HandshakeFailed for
+	// how to fall back to the v1 protocol.
+	V2Transport bool
 }
 
 // minUint32 is a helper function to return the minimum of two uint32s.
@@ -287,14 +319,14 @@ func minUint32(a, b uint32) uint32 {
 }
 
 // newNetAddress attempts to extract the IP address and port from the passed
-// net.Addr interface and create a brocoin NetAddress structure using that
+// net.Addr interface and create a brocoin NetAddressV2 structure using that
 // information.
-func newNetAddress(addr net.Addr, services wire.ServiceFlag) (*wire.NetAddress, error) {
+func newNetAddress(addr net.Addr, services wire.ServiceFlag) (*wire.NetAddressV2, error) {
 	// addr will be a net.TCPAddr when not using a proxy.
 	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
 		ip := tcpAddr.IP
 		port := uint16(tcpAddr.Port)
-		na := wire.NewNetAddressIPPort(ip, port, services)
+		na := wire.NewNetAddressV2IPPort(ip, port, services)
 		return na, nil
 	}
 
@@ -305,7 +337,7 @@ func newNetAddress(addr net.Addr, services wire.ServiceFlag) (*wire.NetAddress,
 			ip = net.ParseIP("0.0.0.0")
 		}
 		port := uint16(proxiedAddr.Port)
-		na := wire.NewNetAddressIPPort(ip, port, services)
+		na := wire.NewNetAddressV2IPPort(ip, port, services)
 		return na, nil
 	}
 
@@ -321,7 +353,7 @@ func newNetAddress(addr net.Addr, services wire.ServiceFlag) (*wire.NetAddress,
 	if err != nil {
 		return nil, err
 	}
-	na := wire.NewNetAddressIPPort(ip, uint16(port), services)
+	na := wire.NewNetAddressV2IPPort(ip, uint16(port), services)
 	return na, nil
 }
 
@@ -379,6 +411,12 @@ type StatsSnap struct {
 	LastPingNonce  uint64
 	LastPingTime   time.Time
 	LastPingMicros int64
+
+	// BytesSentPerMsg and BytesRecvPerMsg hold the total number of bytes
+	// sent and received keyed by message command.  Messages that could not
+	// be decoded are accounted for under the "*other*" key.
+	BytesSentPerMsg map[string]uint64
+	BytesRecvPerMsg map[string]uint64
 }
 
 // HashFunc is a function which returns a block hash, height and error
@@ -386,12 +424,12 @@ type StatsSnap struct {
 type HashFunc func() (hash *chainhash.Hash, height int32, err error)
 
 // AddrFunc is a func which takes an address and returns a related address.
-type AddrFunc func(remoteAddr *wire.NetAddress) *wire.NetAddress
+type AddrFunc func(remoteAddr *wire.NetAddressV2) *wire.NetAddressV2
 
 // HostToNetAddrFunc is a func which takes a host, port, services and returns
 // the netaddress.
 type HostToNetAddrFunc func(host string, port uint16,
-	services wire.ServiceFlag) (*wire.NetAddress, error)
+	services wire.ServiceFlag) (*wire.NetAddressV2, error)
 
 // NOTE: The overall data flow of a peer is split into 3 goroutines.  Inbound
 // messages are read via the inHandler goroutine and generally dispatched to
@@ -430,6 +468,14 @@ type Peer struct {
 
 	conn net.Conn
 
+	// connReader is where v1 messages are read from, which is the
+	// connection itself unless the bytes read while detecting the transport
+	// protocol of an inbound peer must be read first.  transport is the v2
+	// transport protocol session, or nil when the connection uses the v1
+	// protocol.  Both are only modified during the protocol negotiation.
+	connReader io.Reader
+	transport  *v2transport.Transport
+
 	// These fields are set at creation time and never modified, so they are
 	// safe to read from concurrently without a mutex.
 	addr    string
@@ -437,7 +483,7 @@ type Peer struct {
 	inbound bool
 
 	flagsMtx             sync.Mutex // protects the peer flags below
-	na                   *wire.NetAddress
+	na                   *wire.NetAddressV2
 	id                   int32
 	userAgent            string
 	services             wire.ServiceFlag
@@ -445,8 +491,13 @@ type Peer struct {
 	advertisedProtoVer   uint32 // protocol version advertised by remote
 	protocolVersion      uint32 // negotiated protocol version
 	sendHeadersPreferred bool   // peer sent a sendheaders message
+	sendAddrV2           bool   // peer sent a sendaddrv2 message
+	cmpctBlockVersion    uint64 // compact block version agreed via sendcmpct
+	cmpctHighBandwidth   bool   // peer wants blocks announced via cmpctblock
 	verAckReceived       bool
 	witnessEnabled       bool
+	v2Transport          bool // connection uses the v2 transport protocol
+	v2HandshakeFailed    bool // outbound v2 transport handshake failed
 
 	wireEncoding wire.MessageEncoding
 
@@ -469,6 +520,11 @@ type Peer struct {
 	lastPingNonce      uint64    // Set to nonce if we have a pending ping.
 	lastPingTime       time.Time // Time we sent last ping.
 	lastPingMicros     int64     // Time for last ping to return.
+	minPingMicros      int64     // Lowest time for a ping to return.
+	lastNewBlockTime   time.Time // Time the peer last provided a new block.
+	lastNewTxTime      time.Time // Time the peer last provided a new tx.
+	bytesSentPerMsg    map[string]uint64
+	bytesRecvPerMsg    map[string]uint64
 
 	stallControl  chan stallControlMsg
 	outputQueue   chan outMsg
@@ -500,6 +556,26 @@ func (p *Peer) UpdateLastBlockHeight(newHeight int32) {
 	p.statsMtx.Unlock()
 }
 
+// UpdateLastNewBlockTime records that the peer just provided a new block which
+// extended the best chain.
+//
+// This function is safe for concurrent access.
+func (p *Peer) UpdateLastNewBlockTime() {
+	p.statsMtx.Lock()
+	p.lastNewBlockTime = time.Now()
+	p.statsMtx.Unlock()
+}
+
+// UpdateLastNewTxTime records that the peer just provided a new transaction
+// which was accepted into the memory pool.
+//
+// This function is safe for concurrent access.
+func (p *Peer) UpdateLastNewTxTime() {
+	p.statsMtx.Lock()
+	p.lastNewTxTime = time.Now()
+	p.statsMtx.Unlock()
+}
+
 // UpdateLastAnnouncedBlock updates meta-data about the last block hash this
 // peer is known to have announced.
 //
@@ -520,6 +596,14 @@ func (p *Peer) AddKnownInventory(invVect *wire.InvVect) {
 	p.knownInventory.Add(invVect)
 }
 
+// IsKnownInventory returns whether the passed inventory is already known to the
+// peer, either because it was announced by or relayed to the peer.
+//
+// This function is safe for concurrent access.
+func (p *Peer) IsKnownInventory(invVect *wire.InvVect) bool {
+	return p.knownInventory.Exists(invVect)
+}
+
 // StatsSnapshot returns a snapshot of the current peer flags and statistics.
 //
 // This function is safe for concurrent access.
@@ -554,6 +638,14 @@ func (p *Peer) StatsSnapshot() *StatsSnap {
 		LastPingMicros: p.lastPingMicros,
 		LastPingTime:   p.lastPingTime,
 	}
+	statsSnap.BytesSentPerMsg = make(map[string]uint64, len(p.bytesSentPerMsg))
+	for command, n := range p.bytesSentPerMsg {
+		statsSnap.BytesSentPerMsg[command] = n
+	}
+	statsSnap.BytesRecvPerMsg = make(map[string]uint64, len(p.bytesRecvPerMsg))
+	for command, n := range p.bytesRecvPerMsg {
+		statsSnap.BytesRecvPerMsg[command] = n
+	}
 
 	p.statsMtx.RUnlock()
 	return statsSnap
@@ -573,7 +665,7 @@ func (p *Peer) ID() int32 {
 // NA returns the peer network address.
 //
 // This function is safe for concurrent access.
-func (p *Peer) NA() *wire.NetAddress {
+func (p *Peer) NA() *wire.NetAddressV2 {
 	p.flagsMtx.Lock()
 	na := p.na
 	p.flagsMtx.Unlock()
@@ -663,6 +755,42 @@ func (p *Peer) LastPingMicros() int64 {
 	return lastPingMicros
 }
 
+// MinPingMicros returns the lowest ping micros of the remote peer, or zero when
+// no ping has been answered yet.
+//
+// This function is safe for concurrent access.
+func (p *Peer) MinPingMicros() int64 {
+	p.statsMtx.RLock()
+	minPingMicros := p.minPingMicros
+	p.statsMtx.RUnlock()
+
+	return minPingMicros
+}
+
+// LastNewBlockTime returns the last time the peer provided a new block which
+// extended the best chain, or the zero time if it never did.
+//
+// This function is safe for concurrent access.
+func (p *Peer) LastNewBlockTime() time.Time {
+	p.statsMtx.RLock()
+	lastNewBlockTime := p.lastNewBlockTime
+	p.statsMtx.RUnlock()
+
+	return lastNewBlockTime
+}
+
+// LastNewTxTime returns the last time the peer provided a new transaction which
+// was accepted into the memory pool, or the zero time if it never did.
+//
+// This function is safe for concurrent access.
+func (p *Peer) LastNewTxTime() time.Time {
+	p.statsMtx.RLock()
+	lastNewTxTime := p.lastNewTxTime
+	p.statsMtx.RUnlock()
+
+	return lastNewTxTime
+}
+
 // VersionKnown returns the whether or not the version of a peer is known
 // locally.
 //
@@ -687,6 +815,33 @@ func (p *Peer) VerAckReceived() bool {
 	return verAckReceived
 }
 
+// V2Transport returns whether the connection to the peer uses the encrypted v2
+// transport protocol.
+//
+// This function is safe for concurrent access.
+func (p *Peer) V2Transport() bool {
+	p.flagsMtx.Lock()
+	v2Transport := p.v2Transport
+	p.flagsMtx.Unlock()
+
+	return v2Transport
+}
+
+// V2HandshakeFailed returns whether the peer is an outbound peer which attempted
+// the v2 transport protocol and failed to complete its handshake, which is what
+// happens with remote peers that only support the v1 protocol.  The caller
+// should connect to the remote peer again with the V2Transport field of the
+// config unset once the peer disconnected.
+//
+// This function is safe for concurrent access.
+func (p *Peer) V2HandshakeFailed() bool {
+	p.flagsMtx.Lock()
+	v2HandshakeFailed := p.v2HandshakeFailed
+	p.flagsMtx.Unlock()
+
+	return v2HandshakeFailed
+}
+
 // ProtocolVersion returns the negotiated peer protocol version.
 //
 // This function is safe for concurrent access.
@@ -796,6 +951,55 @@ func (p *Peer) WantsHeaders() bool {
 	return sendHeadersPreferred
 }
 
+// WantsAddrV2 returns if the peer supports addrv2 messages instead of addr
+// messages.
+//
+// This function is safe for concurrent access.
+func (p *Peer) WantsAddrV2() bool {
+	p.flagsMtx.Lock()
+	sendAddrV2 := p.sendAddrV2
+	p.flagsMtx.Unlock()
+
+	return sendAddrV2
+}
+
+// CmpctBlockVersion returns the compact block version the peer agreed to use
+// via a sendcmpct message, or zero if it does not support compact blocks.
+//
+// This function is safe for concurrent access.
+func (p *Peer) CmpctBlockVersion() uint64 {
+	p.flagsMtx.Lock()
+	version := p.cmpctBlockVersion
+	p.flagsMtx.Unlock()
+
+	return version
+}
+
+// WantsCmpctBlocks returns if the peer wants new blocks to be announced by
+// sending cmpctblock messages rather than inventory vectors or headers, also
+// known as the high-bandwidth mode of BIP0152.
+//
+// This function is safe for concurrent access.
+func (p *Peer) WantsCmpctBlocks() bool {
+	p.flagsMtx.Lock()
+	highBandwidth := p.cmpctHighBandwidth
+	p.flagsMtx.Unlock()
+
+	return highBandwidth
+}
+
+// preferredCmpctBlockVersion returns the only compact block version used with
+// the peer.  Witness enabled peers must use version 2 so that the witness data
+// of the block transactions is relayed.
+//
+// This function MUST be called with the flags mutex held.
+func (p *Peer) preferredCmpctBlockVersion() uint64 {
+	if p.witnessEnabled {
+		return wire.CmpctBlockVersion2
+	}
+	return wire.CmpctBlockVersion1
+}
+
 // IsWitnessEnabled returns true if the peer has signalled that it supports
 // segregated witness.
 //
@@ -844,6 +1048,41 @@ func (p *Peer) PushAddrMsg(addresses []*wire.NetAddress) ([]*wire.NetAddress, er
 	return msg.AddrList, nil
 }
 
+// PushAddrV2Msg sends an addrv2 message to the connected peer using the
+// provided addresses.  It is the addrv2 counterpart of PushAddrMsg and must
+// only be used for peers which want addrv2 messages as reported by
+// WantsAddrV2.  It returns the addresses that were actually sent and no
+// message will be sent if there are no entries in the provided addresses slice.
+//
+// This function is safe for concurrent access.
+func (p *Peer) PushAddrV2Msg(addresses []*wire.NetAddressV2) ([]*wire.NetAddressV2, error) {
+	addressCount := len(addresses)
+
+	// Nothing to send.
+	if addressCount == 0 {
+		return nil, nil
+	}
+
+	msg := wire.NewMsgAddrV2()
+	msg.AddrList = make([]*wire.NetAddressV2, addressCount)
+	copy(msg.AddrList, addresses)
+
+	// Randomize the addresses sent if there are more than the maximum allowed.
+	if addressCount > wire.MaxAddrPerMsg {
+		// Shuffle the address list.
+		for i := 0; i < wire.MaxAddrPerMsg; i++ {
+			j := i + rand.Intn(addressCount-i)
+			msg.AddrList[i], msg.AddrList[j] = msg.AddrList[j], msg.AddrList[i]
+		}
+
+		// Truncate it to the maximum size.
+		msg.AddrList = msg.AddrList[:wire.MaxAddrPerMsg]
+	}
+
+	p.QueueMessage(msg, nil)
+	return msg.AddrList, nil
+}
+
 // PushGetBlocksMsg sends a getblocks message for the provided block locator
 // and stop hash.  It will ignore back-to-back duplicate requests.
 //
@@ -933,6 +1172,25 @@ func (p *Peer) PushGetHeadersMsg(locator blockchain.BlockLocator, stopHash *chai
 	return nil
 }
 
+// PushSendCmpctMsg sends a sendcmpct message to the connected peer announcing
+// support for compact blocks.  When announce is true, the peer is asked to
+// announce new blocks by sending cmpctblock messages (high-bandwidth mode),
+// otherwise by sending inventory vectors or headers (low-bandwidth mode).  No
+// message is sent to peers which do not support compact blocks.
+//
+// This function is safe for concurrent access.
+func (p *Peer) PushSendCmpctMsg(announce bool) {
+	if p.ProtocolVersion() < wire.ShortIDsBlocksVersion {
+		return
+	}
+
+	p.flagsMtx.Lock()
+	version := p.preferredCmpctBlockVersion()
+	p.flagsMtx.Unlock()
+
+	p.QueueMessage(wire.NewMsgSendCmpct(announce, version), nil)
+}
+
 // PushRejectMsg sends a reject message for the provided command, reject code,
 // reject reason, and hash.  The hash will only be used when the command is a tx
 // or block and should be nil in other cases.  The wait parameter will cause the
@@ -999,16 +1257,46 @@ func (p *Peer) handlePongMsg(msg *wire.MsgPong) {
 			p.lastPingMicros = time.Since(p.lastPingTime).Nanoseconds()
 			p.lastPingMicros /= 1000 // convert to usec.
 			p.lastPingNonce = 0
+			if p.minPingMicros == 0 || p.lastPingMicros < p.minPingMicros {
+				p.minPingMicros = p.lastPingMicros
+			}
 		}
 		p.statsMtx.Unlock()
 	}
 }
 
+// addMsgBytes adds the number of bytes read or written for the passed message
+// to the per-message counters.  Bytes of messages which could not be decoded
+// are accounted for under the "*other*" key.
+func (p *Peer) addMsgBytes(counters map[string]uint64, msg wire.Message, n int) {
+	if n == 0 {
+		return
+	}
+	command := "*other*"
+	if msg != nil {
+		command = msg.Command()
+	}
+
+	p.statsMtx.Lock()
+	counters[command] += uint64(n)
+	p.statsMtx.Unlock()
+}
+
 // readMessage reads the next brocoin message from the peer with logging.
 func (p *Peer) readMessage(encoding wire.MessageEncoding) (wire.Message, []byte, error) {
-	n, msg, buf, err := wire.ReadMessageWithEncodingN(p.conn,
-		p.ProtocolVersion(), p.cfg.ChainParams.Net, encoding)
+	var n int
+	var msg wire.Message
+	var buf []byte
+	var err error
+	if p.transport != nil {
+		n, msg, buf, err = p.transport.ReadMessage(p.ProtocolVersion(),
+			encoding)
+	} else {
+		n, msg, buf, err = wire.ReadMessageWithEncodingN(p.connReader,
+			p.ProtocolVersion(), p.cfg.ChainParams.Net, encoding)
+	}
 	atomic.AddUint64(&p.bytesReceived, uint64(n))
+	p.addMsgBytes(p.bytesRecvPerMsg, msg, n)
 	if p.cfg.Listeners.OnRead != nil {
 		p.cfg.Listeners.OnRead(p, n, msg, err)
 	}
@@ -1069,9 +1357,16 @@ func (p *Peer) writeMessage(msg wire.Message, enc wire.MessageEncoding) error {
 	}))
 
 	// Write the message to the peer.
-	n, err := wire.WriteMessageWithEncodingN(p.conn, msg,
-		p.ProtocolVersion(), p.cfg.ChainParams.Net, enc)
+	var n int
+	var err error
+	if p.transport != nil {
+		n, err = p.transport.WriteMessage(msg, p.ProtocolVersion(), enc)
+	} else {
+		n, err = wire.WriteMessageWithEncodingN(p.conn, msg,
+			p.ProtocolVersion(), p.cfg.ChainParams.Net, enc)
+	}
 	atomic.AddUint64(&p.bytesSent, uint64(n))
+	p.addMsgBytes(p.bytesSentPerMsg, msg, n)
 	if p.cfg.Listeners.OnWrite != nil {
 		p.cfg.Listeners.OnWrite(p, n, msg, err)
 	}
@@ -1153,12 +1448,18 @@ func (p *Peer) maybeAddDeadline(pendingResponses map[string]time.Time, msgCmd st
 		pendingResponses[wire.CmdInv] = deadline
 
 	case wire.CmdGetData:
-		// Expects a block, merkleblock, tx, or notfound message.
+		// Expects a block, cmpctblock, merkleblock, tx, or notfound
+		// message.
 		pendingResponses[wire.CmdBlock] = deadline
+		pendingResponses[wire.CmdCmpctBlock] = deadline
 		pendingResponses[wire.CmdMerkleBlock] = deadline
 		pendingResponses[wire.CmdTx] = deadline
 		pendingResponses[wire.CmdNotFound] = deadline
 
+	case wire.CmdGetBlockTxns:
+		// Expects a blocktxn message.
+		pendingResponses[wire.CmdBlockTxns] = deadline
+
 	case wire.CmdGetHeaders:
 		// Expects a headers message.  Use a longer deadline since it
 		// can take a while for the remote peer to load all of the
@@ -1212,12 +1513,15 @@ out:
 				switch msgCmd := msg.message.Command(); msgCmd {
 				case wire.CmdBlock:
 					fallthrough
+				case wire.CmdCmpctBlock:
+					fallthrough
 				case wire.CmdMerkleBlock:
 					fallthrough
 				case wire.CmdTx:
 					fallthrough
 				case wire.CmdNotFound:
 					delete(pendingResponses, wire.CmdBlock)
+					delete(pendingResponses, wire.CmdCmpctBlock)
 					delete(pendingResponses, wire.CmdMerkleBlock)
 					delete(pendingResponses, wire.CmdTx)
 					delete(pendingResponses, wire.CmdNotFound)
@@ -1394,6 +1698,17 @@ out:
 				p.cfg.Listeners.OnAddr(p, msg)
 			}
 
+		case *wire.MsgAddrV2:
+			if p.cfg.Listeners.OnAddrV2 != nil {
+				p.cfg.Listeners.OnAddrV2(p, msg)
+			}
+
+		case *wire.MsgSendAddrV2:
+			// BIP0155 requires sendaddrv2 to be sent before verack.
+			p.PushRejectMsg(msg.Command(), wire.RejectInvalid,
+				"sendaddrv2 message after verack", nil, true)
+			break out
+
 		case *wire.MsgPing:
 			p.handlePingMsg(msg)
 			if p.cfg.Listeners.OnPing != nil {
@@ -1506,6 +1821,21 @@ out:
 				p.cfg.Listeners.OnMerkleBlock(p, msg)
 			}
 
+		case *wire.MsgCmpctBlock:
+			if p.cfg.Listeners.OnCmpctBlock != nil {
+				p.cfg.Listeners.OnCmpctBlock(p, msg)
+			}
+
+		case *wire.MsgGetBlockTxns:
+			if p.cfg.Listeners.OnGetBlockTxns != nil {
+				p.cfg.Listeners.OnGetBlockTxns(p, msg)
+			}
+
+		case *wire.MsgBlockTxns:
+			if p.cfg.Listeners.OnBlockTxns != nil {
+				p.cfg.Listeners.OnBlockTxns(p, msg)
+			}
+
 		case *wire.MsgReject:
 			if p.cfg.Listeners.OnReject != nil {
 				p.cfg.Listeners.OnReject(p, msg)
@@ -1520,6 +1850,22 @@ out:
 				p.cfg.Listeners.OnSendHeaders(p, msg)
 			}
 
+		case *wire.MsgSendCmpct:
+			// Compact blocks are only exchanged using the preferred
+			// version for the peer, so announcements of other
+			// versions are ignored.  Later announcements of the
+			// same version only switch the announcement mode.
+			p.flagsMtx.Lock()
+			if msg.CmpctBlockVersion == p.preferredCmpctBlockVersion() {
+				p.cmpctBlockVersion = msg.CmpctBlockVersion
+				p.cmpctHighBandwidth = msg.AnnounceUsingCmpctBlock
+			}
+			p.flagsMtx.Unlock()
+
+			if p.cfg.Listeners.OnSendCmpct != nil {
+				p.cfg.Listeners.OnSendCmpct(p, msg)
+			}
+
 		default:
 			log.Debugf("Received unhandled message of type %v "+
 				"from %v", rmsg.Command(), p)
@@ -1966,38 +2312,60 @@ func (p *Peer) readRemoteVersionMsg() error {
 	return nil
 }
 
-// readRemoteVerAckMsg waits for the next message to arrive from the remote
-// peer. If this message is not a verack message, then an error is returned.
-// This method is to be used as part of the version negotiation upon a new
-// connection.
+// readRemoteVerAckMsg waits for the verack message to arrive from the remote
+// peer.  A sendaddrv2 message may precede it as defined by BIP0155, however
+// any other message results in an error.  This method is to be used as part
+// of the version negotiation upon a new connection.
 func (p *Peer) readRemoteVerAckMsg() error {
-	// Read the next message from the wire.
-	remoteMsg, _, err := p.readMessage(wire.LatestEncoding)
-	if err != nil {
-		return err
-	}
+	for {
+		// Read the next message from the wire.
+		remoteMsg, _, err := p.readMessage(wire.LatestEncoding)
+		if err != nil {
+			return err
+		}
 
-	// It should be a verack message, otherwise send a reject message to the
-	// peer explaining why.
-	msg, ok := remoteMsg.(*wire.MsgVerAck)
-	if !ok {
-		reason := "a verack message must follow version"
-		rejectMsg := wire.NewMsgReject(
-			msg.Command(), wire.RejectMalformed, reason,
-		)
-		_ = p.writeMessage(rejectMsg, wire.LatestEncoding)
-		return errors.New(reason)
-	}
+		switch msg := remoteMsg.(type) {
+		case *wire.MsgSendAddrV2:
+			p.flagsMtx.Lock()
+			p.sendAddrV2 = true
+			p.flagsMtx.Unlock()
 
-	p.flagsMtx.Lock()
-	p.verAckReceived = true
-	p.flagsMtx.Unlock()
+			if p.cfg.Listeners.OnSendAddrV2 != nil {
+				p.cfg.Listeners.OnSendAddrV2(p, msg)
+			}
+
+		case *wire.MsgVerAck:
+			p.flagsMtx.Lock()
+			p.verAckReceived = true
+			p.flagsMtx.Unlock()
+
+			if p.cfg.Listeners.OnVerAck != nil {
+				p.cfg.Listeners.OnVerAck(p, msg)
+			}
+			return nil
+
+		default:
+			// Send a reject message to the peer explaining why.
+			reason := "a verack message must follow version"
+			rejectMsg := wire.NewMsgReject(
+				remoteMsg.Command(), wire.RejectMalformed, reason,
+			)
+			_ = p.writeMessage(rejectMsg, wire.LatestEncoding)
+			return errors.New(reason)
+		}
+	}
+}
 
-	if p.cfg.Listeners.OnVerAck != nil {
-		p.cfg.Listeners.OnVerAck(p, msg)
+// writeSendAddrV2Msg signals to the remote peer that we would like to receive
+// addrv2 messages when the negotiated protocol version supports them.  It must
+// be called after the version messages have been exchanged and before our
+// verack is sent.
+func (p *Peer) writeSendAddrV2Msg() error {
+	if p.ProtocolVersion() < wire.AddrV2Version {
+		return nil
 	}
 
-	return nil
+	return p.writeMessage(wire.NewMsgSendAddrV2(), wire.LatestEncoding)
 }
 
 // localVersionMsg creates a version message that can be used to send to the
@@ -2012,7 +2380,13 @@ func (p *Peer) localVersionMsg() (*wire.MsgVersion, error) {
 		}
 	}
 
-	theirNA := p.na
+	// The version message can only describe IPv4 and IPv6 addresses, so
+	// an unroutable address is used for peers on other networks.
+	theirNA, ok := p.na.ToLegacy()
+	if !ok {
+		theirNA = wire.NewNetAddressIPPort(net.IP([]byte{0, 0, 0, 0}), 0,
+			p.na.Services)
+	}
 
 	// If we are behind a proxy and the connection comes from the proxy then
 	// we return an unroutable address as their address. This is to prevent
@@ -2020,7 +2394,7 @@ func (p *Peer) localVersionMsg() (*wire.MsgVersion, error) {
 	if p.cfg.Proxy != "" {
 		proxyaddress, _, err := net.SplitHostPort(p.cfg.Proxy)
 		// invalid proxy means poorly configured, be on the safe side.
-		if err != nil || p.na.IP.String() == proxyaddress {
+		if err != nil || p.na.IP().String() == proxyaddress {
 			theirNA = wire.NewNetAddressIPPort(net.IP([]byte{0, 0, 0, 0}), 0,
 				theirNA.Services)
 		}
@@ -2078,8 +2452,9 @@ func (p *Peer) writeLocalVersionMsg() error {
 //
 //   1. Remote peer sends their version.
 //   2. We send our version.
-//   3. We send our verack.
-//   4. Remote peer sends their verack.
+//   3. We send our sendaddrv2 if the protocol version supports it.
+//   4. We send our verack.
+//   5. Remote peer sends their sendaddrv2 (optional) and verack.
 func (p *Peer) negotiateInboundProtocol() error {
 	if err := p.readRemoteVersionMsg(); err != nil {
 		return err
@@ -2089,6 +2464,10 @@ func (p *Peer) negotiateInboundProtocol() error {
 		return err
 	}
 
+	if err := p.writeSendAddrV2Msg(); err != nil {
+		return err
+	}
+
 	err := p.writeMessage(wire.NewMsgVerAck(), wire.LatestEncoding)
 	if err != nil {
 		return err
@@ -2103,8 +2482,9 @@ func (p *Peer) negotiateInboundProtocol() error {
 //
 //   1. We send our version.
 //   2. Remote peer sends their version.
-//   3. Remote peer sends their verack.
-//   4. We send our verack.
+//   3. Remote peer sends their sendaddrv2 (optional) and verack.
+//   4. We send our sendaddrv2 if the protocol version supports it.
+//   5. We send our verack.
 func (p *Peer) negotiateOutboundProtocol() error {
 	if err := p.writeLocalVersionMsg(); err != nil {
 		return err
@@ -2118,15 +2498,51 @@ func (p *Peer) negotiateOutboundProtocol() error {
 		return err
 	}
 
+	if err := p.writeSendAddrV2Msg(); err != nil {
+		return err
+	}
+
 	return p.writeMessage(wire.NewMsgVerAck(), wire.LatestEncoding)
 }
 
+// negotiateTransport performs the handshake of the v2 transport protocol when
+// it is enabled.  Inbound peers fall back to the v1 protocol when the remote
+// peer turns out to use it.
+func (p *Peer) negotiateTransport() error {
+	if !p.cfg.V2Transport {
+		return nil
+	}
+
+	transport := v2transport.NewTransport(p.conn, p.cfg.ChainParams.Net,
+		!p.inbound)
+	err := transport.Handshake()
+	if err == v2transport.ErrV1Peer {
+		log.Debugf("Peer %s uses the v1 transport protocol", p)
+		p.connReader = io.MultiReader(
+			bytes.NewReader(transport.V1Prefix()), p.conn)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("v2 transport handshake failed: %v", err)
+	}
+
+	p.transport = transport
+	p.flagsMtx.Lock()
+	p.v2Transport = true
+	p.flagsMtx.Unlock()
+	return nil
+}
+
 // start begins processing input and output messages.
 func (p *Peer) start() error {
 	log.Tracef("Starting peer %s", p)
 
 	negotiateErr := make(chan error, 1)
 	go func() {
+		if err := p.negotiateTransport(); err != nil {
+			negotiateErr <- err
+			return
+		}
 		if p.inbound {
 			negotiateErr <- p.negotiateInboundProtocol()
 		} else {
@@ -2138,11 +2554,11 @@ func (p *Peer) start() error {
 	select {
 	case err := <-negotiateErr:
 		if err != nil {
-			p.Disconnect()
+			p.failNegotiation()
 			return err
 		}
 	case <-time.After(negotiateTimeout):
-		p.Disconnect()
+		p.failNegotiation()
 		return errors.New("protocol negotiation timeout")
 	}
 	log.Debugf("Connected to %s", p.Addr())
@@ -2158,6 +2574,19 @@ func (p *Peer) start() error {
 	return nil
 }
 
+// failNegotiation disconnects the peer after the protocol negotiation failed.
+// Outbound peers which attempted the v2 transport protocol and didn't complete
+// its handshake are marked so the caller can retry with the v1 protocol.
+func (p *Peer) failNegotiation() {
+	p.flagsMtx.Lock()
+	if p.cfg.V2Transport && !p.inbound && !p.v2Transport {
+		p.v2HandshakeFailed = true
+	}
+	p.flagsMtx.Unlock()
+
+	p.Disconnect()
+}
+
 // AssociateConnection associates the given conn to the peer.   Calling this
 // function when the peer is already connected will have no effect.
 func (p *Peer) AssociateConnection(conn net.Conn) {
@@ -2167,6 +2596,7 @@ func (p *Peer) AssociateConnection(conn net.Conn) {
 	}
 
 	p.conn = conn
+	p.connReader = conn
 	p.timeConnected = time.Now()
 
 	if p.inbound {
@@ -2237,6 +2667,8 @@ func newPeerBase(origCfg *Config, inbound bool) *Peer {
 		cfg:             cfg, // Copy so caller can't mutate.
 		services:        cfg.Services,
 		protocolVersion: cfg.ProtocolVersion,
+		bytesSentPerMsg: make(map[string]uint64),
+		bytesRecvPerMsg: make(map[string]uint64),
 	}
 	return &p
 }
@@ -2269,7 +2701,7 @@ func NewOutboundPeer(cfg *Config, addr string) (*Peer, error) {
 		}
 		p.na = na
 	} else {
-		p.na = wire.NewNetAddressIPPort(net.ParseIP(host), uint16(port), 0)
+		p.na = wire.NewNetAddressV2IPPort(net.ParseIP(host), uint16(port), 0)
 	}
 
 	return p, nil
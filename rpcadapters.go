@@ -5,10 +5,13 @@
 package main
 
 import (
+	"net"
 	"sync/atomic"
+	"time"
 
 	"github.com/brsuite/brond/blockchain"
 	"github.com/brsuite/brond/chaincfg/chainhash"
+	"github.com/brsuite/brond/connmgr"
 	"github.com/brsuite/brond/mempool"
 	"github.com/brsuite/brond/netsync"
 	"github.com/brsuite/brond/peer"
@@ -52,6 +55,15 @@ func (p *rpcPeer) BanScore() uint32 {
 	return (*serverPeer)(p).banScore.Int()
 }
 
+// BanScoreBreakdown returns the persistent and the decaying parts of the
+// peer's ban score.
+//
+// This function is safe for concurrent access and is part of the rpcserverPeer
+// interface implementation.
+func (p *rpcPeer) BanScoreBreakdown() (uint32, uint32) {
+	return (*serverPeer)(p).banScore.Breakdown()
+}
+
 // FeeFilter returns the requested current minimum fee rate for which
 // transactions should be announced.
 //
@@ -61,6 +73,25 @@ func (p *rpcPeer) FeeFilter() int64 {
 	return atomic.LoadInt64(&(*serverPeer)(p).feeFilter)
 }
 
+// ConnectionType returns how the connection to the peer was established.  It
+// is one of "inbound", "manual" for peers added by the user, or the type of the
+// connection for outbound peers chosen automatically, which is one of
+// "outbound-full-relay", "block-relay-only" or "feeler".
+//
+// This function is safe for concurrent access and is part of the rpcserverPeer
+// interface implementation.
+func (p *rpcPeer) ConnectionType() string {
+	sp := (*serverPeer)(p)
+	switch {
+	case sp.Inbound():
+		return "inbound"
+	case sp.persistent:
+		return "manual"
+	default:
+		return sp.connReq.Type.String()
+	}
+}
+
 // rpcConnManager provides a connection manager for use with the RPC server and
 // implements the rpcserverConnManager interface.
 type rpcConnManager struct {
@@ -223,6 +254,55 @@ func (cm *rpcConnManager) RelayTransactions(txns []*mempool.TxDesc) {
 	cm.server.relayTransactions(txns)
 }
 
+// Ban bans the provided subnet until the provided time and disconnects all
+// connected peers within it.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverConnManager interface implementation.
+func (cm *rpcConnManager) Ban(subnet *net.IPNet, until time.Time) error {
+	if err := cm.server.banList.Ban(subnet, until); err != nil {
+		return err
+	}
+
+	replyChan := make(chan []*serverPeer)
+	cm.server.query <- getPeersMsg{reply: replyChan}
+	for _, sp := range <-replyChan {
+		host, _, err := net.SplitHostPort(sp.Addr())
+		if err != nil {
+			continue
+		}
+		if ip := net.ParseIP(host); ip != nil && subnet.Contains(ip) {
+			srvrLog.Infof("Disconnecting banned peer %v", sp)
+			sp.Disconnect()
+		}
+	}
+	return nil
+}
+
+// Unban removes the ban of the provided subnet.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverConnManager interface implementation.
+func (cm *rpcConnManager) Unban(subnet *net.IPNet) error {
+	return cm.server.banList.Unban(subnet)
+}
+
+// BannedSubnets returns all currently banned subnets.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverConnManager interface implementation.
+func (cm *rpcConnManager) BannedSubnets() []connmgr.BanEntry {
+	return cm.server.banList.Entries()
+}
+
+// ClearBanned removes all bans.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverConnManager interface implementation.
+func (cm *rpcConnManager) ClearBanned() error {
+	return cm.server.banList.Clear()
+}
+
 // rpcSyncMgr provides a block manager for use with the RPC server and
 // implements the rpcserverSyncManager interface.
 type rpcSyncMgr struct {
@@ -0,0 +1,204 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/brsuite/brond/bronjson"
+)
+
+const (
+	// rpcCookieUsername is the username of the credentials generated for
+	// cookie authentication.
+	rpcCookieUsername = "__cookie__"
+
+	// rpcCookieFilename is the name of the file the credentials for cookie
+	// authentication are written to in the data directory by default.
+	rpcCookieFilename = ".cookie"
+)
+
+// rpcUser describes a user of the RPC server along with the RPC methods it may
+// call and the websocket notifications it may receive.
+type rpcUser struct {
+	name string
+
+	// authsha is the SHA-256 hash of the plain-text credentials of a user
+	// configured with a password or generated for cookie authentication.
+	// Users configured with rpcauth only have the salt and the HMAC-SHA256
+	// of their password keyed by it instead.
+	authsha [sha256.Size]byte
+	salt    string
+	hash    []byte
+
+	// methods and ntfns are the sets of RPC methods and websocket
+	// notification types which are available to the user.  A nil set
+	// means all of them are available.
+	methods map[string]struct{}
+	ntfns   map[string]struct{}
+}
+
+// newRPCUser returns a user with the passed plain-text credentials which may
+// only call the passed methods, or all of them when nil.
+func newRPCUser(name, password string, methods map[string]struct{}) *rpcUser {
+	return &rpcUser{
+		name:    name,
+		authsha: sha256.Sum256([]byte(name + ":" + password)),
+		methods: methods,
+	}
+}
+
+// parseRPCAuth parses the credentials of a user in the rpcauth format
+// <user>:<salt>$<hash>, where hash is the hex-encoded HMAC-SHA256 of the
+// password of the user keyed by salt.
+func parseRPCAuth(s string) (*rpcUser, error) {
+	name, saltHash, ok := strings.Cut(s, ":")
+	if !ok || name == "" {
+		return nil, errors.New("missing username")
+	}
+	salt, hexHash, ok := strings.Cut(saltHash, "$")
+	if !ok || salt == "" {
+		return nil, errors.New("missing salt")
+	}
+	hash, err := hex.DecodeString(hexHash)
+	if err != nil || len(hash) != sha256.Size {
+		return nil, errors.New("password hash is not a hex-encoded " +
+			"HMAC-SHA256")
+	}
+	return &rpcUser{name: name, salt: salt, hash: hash}, nil
+}
+
+// parseRPCWhitelist parses entries in the format <user>:<name>,<name>,... into
+// the sets of RPC methods, or websocket notification types when ntfns is set,
+// available to each user.  Multiple entries for the same user only allow the
+// names present in all of them.
+func parseRPCWhitelist(entries []string, ntfns bool) (map[string]map[string]struct{}, error) {
+	kind := "method"
+	if ntfns {
+		kind = "notification"
+	}
+	whitelists := make(map[string]map[string]struct{})
+	for _, entry := range entries {
+		user, list, ok := strings.Cut(entry, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("%q: missing username", entry)
+		}
+
+		allowed := make(map[string]struct{})
+		for _, name := range strings.Split(list, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			flags, err := bronjson.MethodUsageFlags(name)
+			isNtfn := flags&bronjson.UFNotification != 0
+			if err != nil || isNtfn != ntfns {
+				return nil, fmt.Errorf("%q: unknown %s %q", entry,
+					kind, name)
+			}
+			allowed[name] = struct{}{}
+		}
+
+		if prev, ok := whitelists[user]; ok {
+			allowed = intersectNames(prev, allowed)
+		}
+		whitelists[user] = allowed
+	}
+	return whitelists, nil
+}
+
+// intersectNames returns the names present in both of the passed sets, where a
+// nil set contains all names.
+func intersectNames(a, b map[string]struct{}) map[string]struct{} {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	names := make(map[string]struct{})
+	for name := range a {
+		if _, ok := b[name]; ok {
+			names[name] = struct{}{}
+		}
+	}
+	return names
+}
+
+// restrict limits the RPC methods and websocket notification types available
+// to the user to the passed sets, which do not restrict anything when nil.
+func (u *rpcUser) restrict(methods, ntfns map[string]struct{}) {
+	u.methods = intersectNames(u.methods, methods)
+	u.ntfns = intersectNames(u.ntfns, ntfns)
+}
+
+// checkPassword returns whether the passed credentials are the ones of the
+// user.  The password is checked in constant time.
+func (u *rpcUser) checkPassword(name, password string) bool {
+	if u.hash == nil {
+		authsha := sha256.Sum256([]byte(name + ":" + password))
+		return subtle.ConstantTimeCompare(authsha[:], u.authsha[:]) == 1
+	}
+
+	mac := hmac.New(sha256.New, []byte(u.salt))
+	mac.Write([]byte(password))
+	return hmac.Equal(mac.Sum(nil), u.hash) && name == u.name
+}
+
+// methodAllowed returns whether the user may call the passed RPC method.
+func (u *rpcUser) methodAllowed(method string) bool {
+	if u.methods == nil {
+		return true
+	}
+	_, ok := u.methods[method]
+	return ok
+}
+
+// ntfnAllowed returns whether the user may receive websocket notifications of
+// the passed type.
+func (u *rpcUser) ntfnAllowed(ntfn string) bool {
+	if u.ntfns == nil {
+		return true
+	}
+	_, ok := u.ntfns[ntfn]
+	return ok
+}
+
+// genRPCCookie generates random credentials for cookie authentication and
+// writes them to the passed file, which only the current user may read.
+func genRPCCookie(path string) (*rpcUser, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	password := hex.EncodeToString(secret)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	// Write the cookie to a new temporary file first so it is never
+	// readable by others or only partially written.
+	tmpPath := path + ".tmp"
+	os.Remove(tmpPath)
+	cookie := []byte(rpcCookieUsername + ":" + password)
+	if err := ioutil.WriteFile(tmpPath, cookie, 0600); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return nil, err
+	}
+	return newRPCUser(rpcCookieUsername, password, nil), nil
+}
@@ -0,0 +1,217 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestParseRPCAuth ensures credentials in the rpcauth format are parsed and
+// only accept the password they were generated for.
+func TestParseRPCAuth(t *testing.T) {
+	t.Parallel()
+
+	// The hash of "correct horse battery staple" keyed by the salt.
+	const auth = "alice:f7efda5c189b999524f151318c0c86$" +
+		"6b6ed61c6493f679f7268562a06ae487a25f460efdb11d0002b4d4824aa0b064"
+	user, err := parseRPCAuth(auth)
+	if err != nil {
+		t.Fatalf("parseRPCAuth: unexpected error %v", err)
+	}
+	passwordTests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{name: "alice", password: "correct horse battery staple", want: true},
+		{name: "alice", password: "correct horse battery", want: false},
+		{name: "bob", password: "correct horse battery staple", want: false},
+	}
+	for _, test := range passwordTests {
+		got := user.checkPassword(test.name, test.password)
+		if got != test.want {
+			t.Errorf("checkPassword(%q, %q): got %v, want %v",
+				test.name, test.password, got, test.want)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"f7efda5c189b999524f151318c0c86$6b6ed61c",
+		":salt$6b6ed61c6493f679f7268562a06ae487a25f460efdb11d0002b4d4824aa0b064",
+		"alice:6b6ed61c6493f679f7268562a06ae487a25f460efdb11d0002b4d4824aa0b064",
+		"alice:salt$6b6ed61c",
+		"alice:salt$zz6ed61c6493f679f7268562a06ae487a25f460efdb11d0002b4d4824aa0b064",
+	}
+	for _, auth := range invalid {
+		if _, err := parseRPCAuth(auth); err == nil {
+			t.Errorf("parseRPCAuth(%q): did not fail", auth)
+		}
+	}
+}
+
+// TestParseRPCWhitelist ensures whitelist entries are parsed into the expected
+// sets of methods and notification types and that unknown names are rejected.
+func TestParseRPCWhitelist(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		entries []string
+		ntfns   bool
+		want    map[string]map[string]struct{}
+		wantErr bool
+	}{
+		{
+			name:    "methods",
+			entries: []string{"alice:getblockcount, getblockhash", "bob:"},
+			want: map[string]map[string]struct{}{
+				"alice": {"getblockcount": {}, "getblockhash": {}},
+				"bob":   {},
+			},
+		},
+		{
+			name: "intersected entries",
+			entries: []string{
+				"alice:getblockcount,getblockhash",
+				"alice:getblockhash,notifyblocks",
+			},
+			want: map[string]map[string]struct{}{
+				"alice": {"getblockhash": {}},
+			},
+		},
+		{
+			name:    "notifications",
+			entries: []string{"alice:blockconnected,txaccepted"},
+			ntfns:   true,
+			want: map[string]map[string]struct{}{
+				"alice": {"blockconnected": {}, "txaccepted": {}},
+			},
+		},
+		{
+			name:    "missing username",
+			entries: []string{"getblockcount"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown method",
+			entries: []string{"alice:getblockcount,nosuchmethod"},
+			wantErr: true,
+		},
+		{
+			name:    "notification as method",
+			entries: []string{"alice:blockconnected"},
+			wantErr: true,
+		},
+		{
+			name:    "method as notification",
+			entries: []string{"alice:notifyblocks"},
+			ntfns:   true,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		got, err := parseRPCWhitelist(test.entries, test.ntfns)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: did not fail", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// TestRPCUserRestrict ensures whitelists restrict the methods and notification
+// types available to users without extending the set of a limited user.
+func TestRPCUserRestrict(t *testing.T) {
+	t.Parallel()
+
+	admin := newRPCUser("admin", "pass", nil)
+	admin.restrict(map[string]struct{}{"getblockcount": {}}, nil)
+	if !admin.methodAllowed("getblockcount") || admin.methodAllowed("stop") {
+		t.Error("restricted admin user has unexpected methods")
+	}
+	if !admin.ntfnAllowed("blockconnected") {
+		t.Error("admin user without notification whitelist is restricted")
+	}
+
+	limited := newRPCUser("limited", "pass", rpcLimited)
+	limited.restrict(map[string]struct{}{"getblockcount": {}, "stop": {}},
+		map[string]struct{}{"blockconnected": {}})
+	if !limited.methodAllowed("getblockcount") || limited.methodAllowed("stop") {
+		t.Error("restricted limited user has unexpected methods")
+	}
+	if !limited.ntfnAllowed("blockconnected") ||
+		limited.ntfnAllowed("txaccepted") {
+
+		t.Error("restricted limited user has unexpected notifications")
+	}
+	if _, ok := rpcLimited["stop"]; ok {
+		t.Error("restricting limited user modified limited methods")
+	}
+}
+
+// TestGenRPCCookie ensures the credentials for cookie authentication are
+// written to a file only readable by the current user.
+func TestGenRPCCookie(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "brond")
+	if err != nil {
+		t.Fatalf("Failed creating a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "mainnet", rpcCookieFilename)
+	user, err := genRPCCookie(path)
+	if err != nil {
+		t.Fatalf("genRPCCookie: unexpected error %v", err)
+	}
+	cookie, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed reading cookie file: %v", err)
+	}
+	name, password, ok := strings.Cut(string(cookie), ":")
+	if !ok || name != rpcCookieUsername || len(password) != 64 {
+		t.Fatalf("unexpected cookie %q", cookie)
+	}
+	if !user.checkPassword(name, password) {
+		t.Fatal("cookie credentials not accepted")
+	}
+
+	// A new cookie replaces the previous credentials.
+	if _, err := genRPCCookie(path); err != nil {
+		t.Fatalf("genRPCCookie: unexpected error %v", err)
+	}
+	cookie2, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed reading cookie file: %v", err)
+	}
+	if string(cookie2) == string(cookie) {
+		t.Fatal("cookie was not regenerated")
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat cookie file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Fatalf("got cookie file permissions %o, want 600", perm)
+	}
+}
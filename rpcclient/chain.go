@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"encoding/hex"
 	"encoding/json"
+	"strconv"
 
 	"github.com/brsuite/brond/bronjson"
 	"github.com/brsuite/brond/chaincfg/chainhash"
@@ -481,6 +482,70 @@ func (c *Client) GetBlockHeaderVerbose(blockHash *chainhash.Hash) (*bronjson.Get
 	return c.GetBlockHeaderVerboseAsync(blockHash).Receive()
 }
 
+// FutureGetBlockStatsResult is a future promise to deliver the result of a
+// GetBlockStatsAsync or GetBlockStatsByHeightAsync RPC invocation (or an
+// applicable error).
+type FutureGetBlockStatsResult chan *response
+
+// Receive waits for the response promised by the future and returns the
+// statistics of the requested block.  Statistics which were not selected are
+// left as their zero values.
+func (r FutureGetBlockStatsResult) Receive() (*bronjson.GetBlockStatsResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as a getblockstats result object.
+	var stats bronjson.GetBlockStatsResult
+	err = json.Unmarshal(res, &stats)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// GetBlockStatsAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on the
+// returned instance.
+//
+// See GetBlockStats for the blocking version and more details.
+func (c *Client) GetBlockStatsAsync(blockHash *chainhash.Hash, stats *[]string) FutureGetBlockStatsResult {
+	hash := ""
+	if blockHash != nil {
+		hash = blockHash.String()
+	}
+
+	cmd := bronjson.NewGetBlockStatsCmd(bronjson.HashOrHeight(hash), stats)
+	return c.sendCmd(cmd)
+}
+
+// GetBlockStats returns fee, size and spent output statistics of the block with
+// the given hash in the main chain.  Only the named statistics are returned
+// when stats is not nil.
+func (c *Client) GetBlockStats(blockHash *chainhash.Hash, stats *[]string) (*bronjson.GetBlockStatsResult, error) {
+	return c.GetBlockStatsAsync(blockHash, stats).Receive()
+}
+
+// GetBlockStatsByHeightAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetBlockStatsByHeight for the blocking version and more details.
+func (c *Client) GetBlockStatsByHeightAsync(blockHeight int64, stats *[]string) FutureGetBlockStatsResult {
+	height := bronjson.HashOrHeight(strconv.FormatInt(blockHeight, 10))
+	cmd := bronjson.NewGetBlockStatsCmd(height, stats)
+	return c.sendCmd(cmd)
+}
+
+// GetBlockStatsByHeight returns fee, size and spent output statistics of the
+// block at the given height in the main chain.  Only the named statistics are
+// returned when stats is not nil.
+func (c *Client) GetBlockStatsByHeight(blockHeight int64, stats *[]string) (*bronjson.GetBlockStatsResult, error) {
+	return c.GetBlockStatsByHeightAsync(blockHeight, stats).Receive()
+}
+
 // FutureGetMempoolEntryResult is a future promise to deliver the result of a
 // GetMempoolEntryAsync RPC invocation (or an applicable error).
 type FutureGetMempoolEntryResult chan *response
@@ -520,6 +585,152 @@ func (c *Client) GetMempoolEntry(txHash string) (*bronjson.GetMempoolEntryResult
 	return c.GetMempoolEntryAsync(txHash).Receive()
 }
 
+// FutureGetMempoolAncestorsResult is a future promise to deliver the result of
+// a GetMempoolAncestorsAsync RPC invocation (or an applicable error).
+type FutureGetMempoolAncestorsResult chan *response
+
+// Receive waits for the response promised by the future and returns the hashes
+// of the ancestors of the transaction in the memory pool.
+func (r FutureGetMempoolAncestorsResult) Receive() ([]*chainhash.Hash, error) {
+	return FutureGetRawMempoolResult(r).Receive()
+}
+
+// GetMempoolAncestorsAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetMempoolAncestors for the blocking version and more details.
+func (c *Client) GetMempoolAncestorsAsync(txHash string) FutureGetMempoolAncestorsResult {
+	cmd := bronjson.NewGetMempoolAncestorsCmd(txHash, bronjson.Bool(false))
+	return c.sendCmd(cmd)
+}
+
+// GetMempoolAncestors returns the hashes of the unconfirmed ancestors in the
+// memory pool of the transaction in the memory pool given its hash.
+//
+// See GetMempoolAncestorsVerbose to retrieve data structures with information
+// about the ancestors instead.
+func (c *Client) GetMempoolAncestors(txHash string) ([]*chainhash.Hash, error) {
+	return c.GetMempoolAncestorsAsync(txHash).Receive()
+}
+
+// FutureGetMempoolAncestorsVerboseResult is a future promise to deliver the
+// result of a GetMempoolAncestorsVerboseAsync RPC invocation (or an applicable
+// error).
+type FutureGetMempoolAncestorsVerboseResult chan *response
+
+// Receive waits for the response promised by the future and returns a map of
+// transaction hashes to an associated data structure with information about the
+// transaction for all ancestors of the transaction in the memory pool.
+func (r FutureGetMempoolAncestorsVerboseResult) Receive() (map[string]bronjson.GetMempoolEntryResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal the result as a map of strings (tx shas) to their detailed
+	// results.
+	var entries map[string]bronjson.GetMempoolEntryResult
+	err = json.Unmarshal(res, &entries)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetMempoolAncestorsVerboseAsync returns an instance of a type that can be
+// used to get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetMempoolAncestorsVerbose for the blocking version and more details.
+func (c *Client) GetMempoolAncestorsVerboseAsync(txHash string) FutureGetMempoolAncestorsVerboseResult {
+	cmd := bronjson.NewGetMempoolAncestorsCmd(txHash, bronjson.Bool(true))
+	return c.sendCmd(cmd)
+}
+
+// GetMempoolAncestorsVerbose returns a map of transaction hashes to an
+// associated data structure with information about the transaction for all
+// unconfirmed ancestors in the memory pool of the transaction in the memory
+// pool given its hash.
+//
+// See GetMempoolAncestors to retrieve only the transaction hashes instead.
+func (c *Client) GetMempoolAncestorsVerbose(txHash string) (map[string]bronjson.GetMempoolEntryResult, error) {
+	return c.GetMempoolAncestorsVerboseAsync(txHash).Receive()
+}
+
+// FutureGetMempoolDescendantsResult is a future promise to deliver the result
+// of a GetMempoolDescendantsAsync RPC invocation (or an applicable error).
+type FutureGetMempoolDescendantsResult chan *response
+
+// Receive waits for the response promised by the future and returns the hashes
+// of the descendants of the transaction in the memory pool.
+func (r FutureGetMempoolDescendantsResult) Receive() ([]*chainhash.Hash, error) {
+	return FutureGetRawMempoolResult(r).Receive()
+}
+
+// GetMempoolDescendantsAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetMempoolDescendants for the blocking version and more details.
+func (c *Client) GetMempoolDescendantsAsync(txHash string) FutureGetMempoolDescendantsResult {
+	cmd := bronjson.NewGetMempoolDescendantsCmd(txHash, bronjson.Bool(false))
+	return c.sendCmd(cmd)
+}
+
+// GetMempoolDescendants returns the hashes of the unconfirmed descendants in
+// the memory pool of the transaction in the memory pool given its hash.
+//
+// See GetMempoolDescendantsVerbose to retrieve data structures with information
+// about the descendants instead.
+func (c *Client) GetMempoolDescendants(txHash string) ([]*chainhash.Hash, error) {
+	return c.GetMempoolDescendantsAsync(txHash).Receive()
+}
+
+// FutureGetMempoolDescendantsVerboseResult is a future promise to deliver the
+// result of a GetMempoolDescendantsVerboseAsync RPC invocation (or an
+// applicable error).
+type FutureGetMempoolDescendantsVerboseResult chan *response
+
+// Receive waits for the response promised by the future and returns a map of
+// transaction hashes to an associated data structure with information about the
+// transaction for all descendants of the transaction in the memory pool.
+func (r FutureGetMempoolDescendantsVerboseResult) Receive() (map[string]bronjson.GetMempoolEntryResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal the result as a map of strings (tx shas) to their detailed
+	// results.
+	var entries map[string]bronjson.GetMempoolEntryResult
+	err = json.Unmarshal(res, &entries)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetMempoolDescendantsVerboseAsync returns an instance of a type that can be
+// used to get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetMempoolDescendantsVerbose for the blocking version and more details.
+func (c *Client) GetMempoolDescendantsVerboseAsync(txHash string) FutureGetMempoolDescendantsVerboseResult {
+	cmd := bronjson.NewGetMempoolDescendantsCmd(txHash, bronjson.Bool(true))
+	return c.sendCmd(cmd)
+}
+
+// GetMempoolDescendantsVerbose returns a map of transaction hashes to an
+// associated data structure with information about the transaction for all
+// unconfirmed descendants in the memory pool of the transaction in the memory
+// pool given its hash.
+//
+// See GetMempoolDescendants to retrieve only the transaction hashes instead.
+func (c *Client) GetMempoolDescendantsVerbose(txHash string) (map[string]bronjson.GetMempoolEntryResult, error) {
+	return c.GetMempoolDescendantsVerboseAsync(txHash).Receive()
+}
+
 // FutureGetRawMempoolResult is a future promise to deliver the result of a
 // GetRawMempoolAsync RPC invocation (or an applicable error).
 type FutureGetRawMempoolResult chan *response
@@ -649,6 +860,43 @@ func (c *Client) EstimateFee(numBlocks int64) (float64, error) {
 	return c.EstimateFeeAsync(numBlocks).Receive()
 }
 
+// FutureEstimateSmartFeeResult is a future promise to deliver the result of a
+// EstimateSmartFeeAsync RPC invocation (or an applicable error).
+type FutureEstimateSmartFeeResult chan *response
+
+// Receive waits for the response promised by the future and returns the
+// estimated fee rate along with the number of blocks it was made for.
+func (r FutureEstimateSmartFeeResult) Receive() (*bronjson.EstimateSmartFeeResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var estimate bronjson.EstimateSmartFeeResult
+	err = json.Unmarshal(res, &estimate)
+	if err != nil {
+		return nil, err
+	}
+	return &estimate, nil
+}
+
+// EstimateSmartFeeAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See EstimateSmartFee for the blocking version and more details.
+func (c *Client) EstimateSmartFeeAsync(confTarget int64, mode *bronjson.EstimateSmartFeeMode) FutureEstimateSmartFeeResult {
+	cmd := bronjson.NewEstimateSmartFeeCmd(confTarget, mode)
+	return c.sendCmd(cmd)
+}
+
+// EstimateSmartFee requests the server to estimate a fee rate in brocoins per
+// kilobyte for a transaction to be mined within confTarget blocks.  A nil mode
+// requests a conservative estimate.
+func (c *Client) EstimateSmartFee(confTarget int64, mode *bronjson.EstimateSmartFeeMode) (*bronjson.EstimateSmartFeeResult, error) {
+	return c.EstimateSmartFeeAsync(confTarget, mode).Receive()
+}
+
 // FutureVerifyChainResult is a future promise to deliver the result of a
 // VerifyChainAsync, VerifyChainLevelAsyncRPC, or VerifyChainBlocksAsync
 // invocation (or an applicable error).
@@ -787,6 +1035,45 @@ func (c *Client) GetTxOut(txHash *chainhash.Hash, index uint32, mempool bool) (*
 	return c.GetTxOutAsync(txHash, index, mempool).Receive()
 }
 
+// FutureGetTxOutSetInfoResult is a future promise to deliver the result of a
+// GetTxOutSetInfoAsync RPC invocation (or an applicable error).
+type FutureGetTxOutSetInfoResult chan *response
+
+// Receive waits for the response promised by the future and returns
+// statistics about the unspent transaction output set.
+func (r FutureGetTxOutSetInfoResult) Receive() (*bronjson.GetTxOutSetInfoResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as a gettxoutsetinfo result object.
+	var txOutSetInfo bronjson.GetTxOutSetInfoResult
+	err = json.Unmarshal(res, &txOutSetInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &txOutSetInfo, nil
+}
+
+// GetTxOutSetInfoAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See GetTxOutSetInfo for the blocking version and more details.
+func (c *Client) GetTxOutSetInfoAsync() FutureGetTxOutSetInfoResult {
+	cmd := bronjson.NewGetTxOutSetInfoCmd()
+	return c.sendCmd(cmd)
+}
+
+// GetTxOutSetInfo returns statistics about the unspent transaction output set
+// as of the best block, including a hash of the set which can be compared
+// against other nodes.
+func (c *Client) GetTxOutSetInfo() (*bronjson.GetTxOutSetInfoResult, error) {
+	return c.GetTxOutSetInfoAsync().Receive()
+}
+
 // FutureRescanBlocksResult is a future promise to deliver the result of a
 // RescanBlocksAsync RPC invocation (or an applicable error).
 //
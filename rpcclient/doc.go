@@ -57,6 +57,14 @@ the Receive method on the returned instance will either return the result
 immediately if it has already arrived, or block until it has.  This is useful
 since it provides the caller with greater control over concurrency.
 
+Batch Requests
+
+A client created with NewBatch queues the commands issued through the
+asynchronous API instead of sending them right away.  Calling Send then issues
+all of the queued commands in a single JSON-RPC batch request over HTTP POST and
+delivers the results to the returned futures.  This avoids the overhead of a
+separate HTTP request for every call when many commands are issued at once.
+
 Notifications
 
 The first important part of notifications is to realize that they will only
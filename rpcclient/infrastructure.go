@@ -70,6 +70,18 @@ var (
 	// client having already connected to the RPC server.
 	ErrClientAlreadyConnected = errors.New("websocket client has already " +
 		"connected")
+
+	// ErrNotHTTPPostClient is an error to describe the condition of
+	// creating a batch client with a configuration that does not enable
+	// HTTP POST mode, which batch requests require.
+	ErrNotHTTPPostClient = errors.New("client is not configured for " +
+		"HTTP POST mode")
+
+	// ErrNotBatchClient is an error to describe the condition of calling a
+	// Client method intended for a batch client when the client sends
+	// each request on its own instead.
+	ErrNotBatchClient = errors.New("client is not configured for batch " +
+		"requests")
 )
 
 const (
@@ -161,6 +173,11 @@ type Client struct {
 	// reconnect to the RPC server.
 	retryCount int64
 
+	// batch indicates whether the client queues requests until Send is
+	// called rather than sending each of them right away.  The queued
+	// requests are tracked along with the others by ID.
+	batch bool
+
 	// Track command and their response channels by ID.
 	requestLock sync.Mutex
 	requestMap  map[uint64]*list.Element
@@ -249,8 +266,9 @@ func (c *Client) removeAllRequests() {
 
 // trackRegisteredNtfns examines the passed command to see if it is one of
 // the notification commands and updates the notification state that is used
-// to automatically re-establish registered notifications on reconnects.
-func (c *Client) trackRegisteredNtfns(cmd interface{}) {
+// to automatically re-establish registered notifications on reconnects.  The
+// result of the command is nil when it failed.
+func (c *Client) trackRegisteredNtfns(cmd interface{}, result []byte) {
 	// Nothing to do if the caller is not interested in notifications.
 	if c.ntfnHandlers == nil {
 		return
@@ -280,6 +298,21 @@ func (c *Client) trackRegisteredNtfns(cmd interface{}) {
 		for _, addr := range bcmd.Addresses {
 			c.ntfnState.notifyReceived[addr] = struct{}{}
 		}
+
+	case *bronjson.NotifySinceCmd:
+		var lastSeq uint64
+		if err := json.Unmarshal(result, &lastSeq); err != nil {
+			break
+		}
+		c.ntfnState.notifySince = true
+
+		// The stream only continues from the last event received
+		// when it was resumed.  Otherwise it starts over from the
+		// latest event, which may even be older than the last one
+		// received when the server restarted.
+		if bcmd.Sequence == nil || lastSeq > c.ntfnState.lastSeq {
+			c.ntfnState.lastSeq = lastSeq
+		}
 	}
 }
 
@@ -307,6 +340,14 @@ type (
 		Result json.RawMessage   `json:"result"`
 		Error  *bronjson.RPCError `json:"error"`
 	}
+
+	// batchResponse is a partially-unmarshaled reply to one of the
+	// requests of a JSON-RPC batch.  The ID associates it with the request
+	// since the replies may be in any order.
+	batchResponse struct {
+		ID *float64 `json:"id"`
+		rawResponse
+	}
 )
 
 // response is the raw bytes of a JSON-RPC result, or the error if the response
@@ -388,10 +429,10 @@ func (c *Client) handleMessage(msg []byte) {
 	// Since the command was successful, examine it to see if it's a
 	// notification, and if is, add it to the notification state so it
 	// can automatically be re-established on reconnect.
-	c.trackRegisteredNtfns(request.cmd)
+	result, err := in.rawResponse.result()
+	c.trackRegisteredNtfns(request.cmd, result)
 
 	// Deliver the response.
-	result, err := in.rawResponse.result()
 	request.responseChan <- &response{result: result, err: err}
 }
 
@@ -573,6 +614,26 @@ func (c *Client) reregisterNtfns() error {
 		}
 	}
 
+	// Resume the sequenced notifications after the last event received if
+	// needed.  When the missed events are no longer available, such as
+	// after the server restarted, start over from the latest event rather
+	// than failing to reconnect.  The gap shows in the sequence numbers.
+	if stateCopy.notifySince {
+		lastSeq := stateCopy.lastSeq
+		log.Debugf("Reregistering [notifysince] (sequence=%d)", lastSeq)
+		_, err := c.NotifySince(&lastSeq)
+		if rpcErr, ok := err.(*bronjson.RPCError); ok &&
+			rpcErr.Code == bronjson.ErrRPCInvalidParameter {
+
+			log.Warnf("Unable to resume notifications after "+
+				"sequence number %d: %v", lastSeq, err)
+			_, err = c.NotifySince(nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -826,23 +887,17 @@ func receiveFuture(f chan *response) ([]byte, error) {
 	return r.result, r.err
 }
 
-// sendPost sends the passed request to the server by issuing an HTTP POST
-// request using the provided response channel for the reply.  Typically a new
-// connection is opened and closed for each command when using this method,
-// however, the underlying HTTP client might coalesce multiple commands
-// depending on several factors including the remote server configuration.
-func (c *Client) sendPost(jReq *jsonRequest) {
-	// Generate a request to the configured RPC server.
+// newPostRequest returns an HTTP POST request to the configured RPC server with
+// the passed body.
+func (c *Client) newPostRequest(body []byte) (*http.Request, error) {
 	protocol := "http"
 	if !c.config.DisableTLS {
 		protocol = "https"
 	}
 	url := protocol + "://" + c.config.Host
-	bodyReader := bytes.NewReader(jReq.marshalledJSON)
-	httpReq, err := http.NewRequest("POST", url, bodyReader)
+	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(body))
 	if err != nil {
-		jReq.responseChan <- &response{result: nil, err: err}
-		return
+		return nil, err
 	}
 	httpReq.Close = true
 	httpReq.Header.Set("Content-Type", "application/json")
@@ -850,6 +905,21 @@ func (c *Client) sendPost(jReq *jsonRequest) {
 	// Configure basic access authorization.
 	httpReq.SetBasicAuth(c.config.User, c.config.Pass)
 
+	return httpReq, nil
+}
+
+// sendPost sends the passed request to the server by issuing an HTTP POST
+// request using the provided response channel for the reply.  Typically a new
+// connection is opened and closed for each command when using this method,
+// however, the underlying HTTP client might coalesce multiple commands
+// depending on several factors including the remote server configuration.
+func (c *Client) sendPost(jReq *jsonRequest) {
+	httpReq, err := c.newPostRequest(jReq.marshalledJSON)
+	if err != nil {
+		jReq.responseChan <- &response{result: nil, err: err}
+		return
+	}
+
 	log.Tracef("Sending command [%s] with id %d", jReq.method, jReq.id)
 	c.sendPostRequest(httpReq, jReq)
 }
@@ -858,6 +928,14 @@ func (c *Client) sendPost(jReq *jsonRequest) {
 // provided response channel for the reply.  It handles both websocket and HTTP
 // POST mode depending on the configuration of the client.
 func (c *Client) sendRequest(jReq *jsonRequest) {
+	// Queue the request until Send is called when running in batch mode.
+	if c.batch {
+		if err := c.addRequest(jReq); err != nil {
+			jReq.responseChan <- &response{err: err}
+		}
+		return
+	}
+
 	// Choose which marshal and send function to use depending on whether
 	// the client running in HTTP POST mode or not.  When running in HTTP
 	// POST mode, the command is issued via an HTTP client.  Otherwise,
@@ -929,6 +1007,119 @@ func (c *Client) sendCmdAndWait(cmd interface{}) (interface{}, error) {
 	return receiveFuture(c.sendCmd(cmd))
 }
 
+// Send sends all of the commands queued by a batch client to the server in a
+// single JSON-RPC batch request and delivers each reply to the future which was
+// returned when the command was issued.  Commands issued while Send is running
+// are queued for the next batch.
+//
+// An error is returned when the batch as a whole fails, for example because
+// the server could not be reached, in which case the error is also delivered
+// to all of the futures of the batch.  Errors specific to a single command
+// are only delivered to its future.
+//
+// This method will error if the client was not created with NewBatch.
+func (c *Client) Send() error {
+	if !c.batch {
+		return ErrNotBatchClient
+	}
+
+	// Take over all of the queued requests.
+	c.requestLock.Lock()
+	requests := make(map[uint64]*jsonRequest, c.requestList.Len())
+	batch := make([]json.RawMessage, 0, c.requestList.Len())
+	for e := c.requestList.Front(); e != nil; e = e.Next() {
+		jReq := e.Value.(*jsonRequest)
+		requests[jReq.id] = jReq
+		batch = append(batch, jReq.marshalledJSON)
+	}
+	c.removeAllRequests()
+	c.requestLock.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if err := c.sendBatch(batch, requests); err != nil {
+		for _, jReq := range requests {
+			jReq.responseChan <- &response{err: err}
+		}
+		return err
+	}
+	return nil
+}
+
+// sendBatch sends the passed marshalled requests to the server in a single
+// HTTP POST request and delivers the replies to the matching requests, which
+// are removed from the passed map.  Requests the server did not reply to
+// receive an error.
+func (c *Client) sendBatch(batch []json.RawMessage, requests map[uint64]*jsonRequest) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	httpReq, err := c.newPostRequest(body)
+	if err != nil {
+		return err
+	}
+
+	log.Tracef("Sending batch of %d commands", len(batch))
+	httpResponse, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+
+	// Read the raw bytes and close the response.
+	respBytes, err := ioutil.ReadAll(httpResponse.Body)
+	httpResponse.Body.Close()
+	if err != nil {
+		return fmt.Errorf("error reading json reply: %v", err)
+	}
+
+	var replies []batchResponse
+	if err := json.Unmarshal(respBytes, &replies); err != nil {
+		// The server replies with a single error response when it
+		// rejects the batch as a whole.  Otherwise, the response isn't
+		// a valid JSON-RPC response at all, so return an error which
+		// includes the HTTP status code and raw response bytes.
+		var resp rawResponse
+		if json.Unmarshal(respBytes, &resp) == nil && resp.Error != nil {
+			return resp.Error
+		}
+		return fmt.Errorf("status code: %d, response: %q",
+			httpResponse.StatusCode, string(respBytes))
+	}
+
+	for _, reply := range replies {
+		if reply.ID == nil || *reply.ID < 0 ||
+			*reply.ID != math.Trunc(*reply.ID) {
+
+			log.Warnf("Received batch reply without a valid id: %v",
+				reply.Error)
+			continue
+		}
+		id := uint64(*reply.ID)
+		jReq, ok := requests[id]
+		if !ok {
+			log.Warnf("Received unexpected batch reply: %s (id %d)",
+				reply.Result, id)
+			continue
+		}
+		delete(requests, id)
+
+		result, err := reply.result()
+		jReq.responseChan <- &response{result: result, err: err}
+	}
+	for id, jReq := range requests {
+		delete(requests, id)
+		jReq.responseChan <- &response{
+			err: fmt.Errorf("no reply to %s command in batch",
+				jReq.method),
+		}
+	}
+
+	return nil
+}
+
 // Disconnected returns whether or not the server is disconnected.  If a
 // websocket client was created but never connected, this also returns false.
 func (c *Client) Disconnected() bool {
@@ -1304,6 +1495,28 @@ func New(config *ConnConfig, ntfnHandlers *NotificationHandlers) (*Client, error
 	return client, nil
 }
 
+// NewBatch creates a new RPC client in batch mode based on the provided
+// connection configuration details, which must enable HTTP POST mode.
+//
+// Commands issued through a batch client are queued rather than sent right
+// away.  Send then sends all of the queued commands to the server in a single
+// JSON-RPC batch request, after which their futures deliver the results.  This
+// avoids a round trip to the server per command when issuing many of them.
+// Since the results are only delivered by Send, the Async versions of the
+// methods must be used with a batch client rather than the blocking ones.
+func NewBatch(config *ConnConfig) (*Client, error) {
+	if !config.HTTPPostMode {
+		return nil, ErrNotHTTPPostClient
+	}
+
+	client, err := New(config, nil)
+	if err != nil {
+		return nil, err
+	}
+	client.batch = true
+	return client, nil
+}
+
 // Connect establishes the initial websocket connection.  This is necessary when
 // a client was created after setting the DisableConnectOnNew field of the
 // Config struct.
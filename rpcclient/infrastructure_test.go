@@ -0,0 +1,96 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brsuite/brond/bronjson"
+)
+
+// TestBatch ensures the commands issued through a batch client are sent in a
+// single request and the replies are delivered to the matching futures.
+func TestBatch(t *testing.T) {
+	var numRequests int
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			numRequests++
+			var batch []bronjson.Request
+			if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+				t.Errorf("unable to decode batch: %v", err)
+				return
+			}
+
+			// Reply in reverse order with an error for the
+			// getblockhash command.
+			replies := make([]json.RawMessage, 0, len(batch))
+			for i := len(batch) - 1; i >= 0; i-- {
+				var result interface{}
+				var rpcErr *bronjson.RPCError
+				switch batch[i].Method {
+				case "getblockcount":
+					result = 100
+				default:
+					rpcErr = bronjson.NewRPCError(
+						bronjson.ErrRPCOutOfRange,
+						"Block number out of range")
+				}
+				reply, err := bronjson.MarshalResponse(batch[i].ID,
+					result, rpcErr)
+				if err != nil {
+					t.Errorf("unable to marshal reply: %v", err)
+					return
+				}
+				replies = append(replies, reply)
+			}
+			json.NewEncoder(w).Encode(replies)
+		}))
+	defer server.Close()
+
+	client, err := NewBatch(&ConnConfig{
+		Host:         strings.TrimPrefix(server.URL, "http://"),
+		HTTPPostMode: true,
+		DisableTLS:   true,
+	})
+	if err != nil {
+		t.Fatalf("NewBatch: unexpected error %v", err)
+	}
+	defer client.Shutdown()
+
+	blockCount := client.GetBlockCountAsync()
+	blockHash := client.GetBlockHashAsync(1000)
+	if err := client.Send(); err != nil {
+		t.Fatalf("Send: unexpected error %v", err)
+	}
+	if numRequests != 1 {
+		t.Fatalf("Send: got %d requests, want 1", numRequests)
+	}
+
+	count, err := blockCount.Receive()
+	if err != nil {
+		t.Fatalf("GetBlockCount: unexpected error %v", err)
+	}
+	if count != 100 {
+		t.Fatalf("GetBlockCount: got %d, want %d", count, 100)
+	}
+	_, err = blockHash.Receive()
+	rpcErr, ok := err.(*bronjson.RPCError)
+	if !ok || rpcErr.Code != bronjson.ErrRPCOutOfRange {
+		t.Fatalf("GetBlockHash: got error %v, want code %d", err,
+			bronjson.ErrRPCOutOfRange)
+	}
+
+	// Sending without any queued commands must not contact the server.
+	if err := client.Send(); err != nil {
+		t.Fatalf("Send: unexpected error %v", err)
+	}
+	if numRequests != 1 {
+		t.Fatalf("Send: got %d requests, want 1", numRequests)
+	}
+}
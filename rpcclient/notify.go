@@ -37,6 +37,8 @@ type notificationState struct {
 	notifyNewTxVerbose bool
 	notifyReceived     map[string]struct{}
 	notifySpent        map[bronjson.OutPoint]struct{}
+	notifySince        bool
+	lastSeq            uint64
 }
 
 // Copy returns a deep copy of the receiver.
@@ -45,6 +47,8 @@ func (s *notificationState) Copy() *notificationState {
 	stateCopy.notifyBlocks = s.notifyBlocks
 	stateCopy.notifyNewTx = s.notifyNewTx
 	stateCopy.notifyNewTxVerbose = s.notifyNewTxVerbose
+	stateCopy.notifySince = s.notifySince
+	stateCopy.lastSeq = s.lastSeq
 	stateCopy.notifyReceived = make(map[string]struct{})
 	for addr := range s.notifyReceived {
 		stateCopy.notifyReceived[addr] = struct{}{}
@@ -179,6 +183,35 @@ type NotificationHandlers struct {
 	// made to register for the notification and the function is non-nil.
 	OnTxAcceptedVerbose func(txDetails *bronjson.TxRawResult)
 
+	// OnSeqBlockConnected is invoked when a block is connected to the
+	// longest (best) chain.  It will only be invoked if a preceding call to
+	// NotifySince has been made to register for the notification and the
+	// function is non-nil.  The sequence number orders it with respect to
+	// the other sequenced notifications.
+	OnSeqBlockConnected func(seq uint64, height int32,
+		header *wire.BlockHeader)
+
+	// OnSeqBlockDisconnected is invoked when a block is disconnected from
+	// the longest (best) chain.  It will only be invoked if a preceding
+	// call to NotifySince has been made to register for the notification
+	// and the function is non-nil.
+	OnSeqBlockDisconnected func(seq uint64, height int32,
+		header *wire.BlockHeader)
+
+	// OnSeqTxAccepted is invoked when a transaction is accepted into the
+	// memory pool.  It will only be invoked if a preceding call to
+	// NotifySince has been made to register for the notification and the
+	// function is non-nil.
+	OnSeqTxAccepted func(seq uint64, hash *chainhash.Hash,
+		amount bronutil.Amount)
+
+	// OnSeqTxRemoved is invoked when a transaction is removed from the
+	// memory pool.  The reason is one of "confirmed", "conflicted",
+	// "replaced", "evicted" or "reorg".  It will only be invoked if a
+	// preceding call to NotifySince has been made to register for the
+	// notification and the function is non-nil.
+	OnSeqTxRemoved func(seq uint64, hash *chainhash.Hash, reason string)
+
 	// OnBrondConnected is invoked when a wallet connects or disconnects from
 	// brond.
 	//
@@ -409,6 +442,81 @@ func (c *Client) handleNotification(ntfn *rawNotification) {
 
 		c.ntfnHandlers.OnTxAcceptedVerbose(rawTx)
 
+	// OnSeqBlockConnected
+	case bronjson.SeqBlockConnectedNtfnMethod:
+		// The sequence number is tracked even when the client is not
+		// interested in the notification so the stream resumes from
+		// the right event on reconnect.
+		seq, height, header, err := parseSeqBlockNtfnParams(ntfn.Params)
+		if err != nil {
+			log.Warnf("Received invalid seq block connected "+
+				"notification: %v", err)
+			return
+		}
+		c.trackNtfnSequence(seq)
+
+		// Ignore the notification if the client is not interested in
+		// it.
+		if c.ntfnHandlers.OnSeqBlockConnected == nil {
+			return
+		}
+
+		c.ntfnHandlers.OnSeqBlockConnected(seq, height, header)
+
+	// OnSeqBlockDisconnected
+	case bronjson.SeqBlockDisconnectedNtfnMethod:
+		seq, height, header, err := parseSeqBlockNtfnParams(ntfn.Params)
+		if err != nil {
+			log.Warnf("Received invalid seq block disconnected "+
+				"notification: %v", err)
+			return
+		}
+		c.trackNtfnSequence(seq)
+
+		// Ignore the notification if the client is not interested in
+		// it.
+		if c.ntfnHandlers.OnSeqBlockDisconnected == nil {
+			return
+		}
+
+		c.ntfnHandlers.OnSeqBlockDisconnected(seq, height, header)
+
+	// OnSeqTxAccepted
+	case bronjson.SeqTxAcceptedNtfnMethod:
+		seq, hash, amt, err := parseSeqTxAcceptedNtfnParams(ntfn.Params)
+		if err != nil {
+			log.Warnf("Received invalid seq tx accepted "+
+				"notification: %v", err)
+			return
+		}
+		c.trackNtfnSequence(seq)
+
+		// Ignore the notification if the client is not interested in
+		// it.
+		if c.ntfnHandlers.OnSeqTxAccepted == nil {
+			return
+		}
+
+		c.ntfnHandlers.OnSeqTxAccepted(seq, hash, amt)
+
+	// OnSeqTxRemoved
+	case bronjson.SeqTxRemovedNtfnMethod:
+		seq, hash, reason, err := parseSeqTxRemovedNtfnParams(ntfn.Params)
+		if err != nil {
+			log.Warnf("Received invalid seq tx removed "+
+				"notification: %v", err)
+			return
+		}
+		c.trackNtfnSequence(seq)
+
+		// Ignore the notification if the client is not interested in
+		// it.
+		if c.ntfnHandlers.OnSeqTxRemoved == nil {
+			return
+		}
+
+		c.ntfnHandlers.OnSeqTxRemoved(seq, hash, reason)
+
 	// OnBrondConnected
 	case bronjson.BrondConnectedNtfnMethod:
 		// Ignore the notification if the client is not interested in
@@ -472,6 +580,16 @@ func (c *Client) handleNotification(ntfn *rawNotification) {
 	}
 }
 
+// trackNtfnSequence records the sequence number of a received sequenced
+// notification so the event stream can be resumed after it on reconnect.
+func (c *Client) trackNtfnSequence(seq uint64) {
+	c.ntfnStateLock.Lock()
+	if seq > c.ntfnState.lastSeq {
+		c.ntfnState.lastSeq = seq
+	}
+	c.ntfnStateLock.Unlock()
+}
+
 // wrongNumParams is an error type describing an unparseable JSON-RPC
 // notificiation due to an incorrect number of parameters for the
 // expected notification type.  The value is the number of parameters
@@ -753,6 +871,96 @@ func parseTxAcceptedNtfnParams(params []json.RawMessage) (*chainhash.Hash,
 	return txHash, amt, nil
 }
 
+// parseSeqBlockNtfnParams parses out the sequence number, height and header of
+// a block from the parameters of seqblockconnected and seqblockdisconnected
+// notifications.
+func parseSeqBlockNtfnParams(params []json.RawMessage) (uint64, int32,
+	*wire.BlockHeader, error) {
+
+	if len(params) != 3 {
+		return 0, 0, nil, wrongNumParams(len(params))
+	}
+
+	// Unmarshal first parameter as an unsigned integer.
+	var seq uint64
+	err := json.Unmarshal(params[0], &seq)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	// The remaining parameters match filteredblockdisconnected.
+	height, header, err := parseFilteredBlockDisconnectedParams(params[1:])
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	return seq, height, header, nil
+}
+
+// parseSeqTxAcceptedNtfnParams parses out the sequence number, transaction
+// hash and total amount from the parameters of a seqtxaccepted notification.
+func parseSeqTxAcceptedNtfnParams(params []json.RawMessage) (uint64,
+	*chainhash.Hash, bronutil.Amount, error) {
+
+	if len(params) != 3 {
+		return 0, nil, 0, wrongNumParams(len(params))
+	}
+
+	// Unmarshal first parameter as an unsigned integer.
+	var seq uint64
+	err := json.Unmarshal(params[0], &seq)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+
+	// The remaining parameters match txaccepted.
+	txHash, amt, err := parseTxAcceptedNtfnParams(params[1:])
+	if err != nil {
+		return 0, nil, 0, err
+	}
+
+	return seq, txHash, amt, nil
+}
+
+// parseSeqTxRemovedNtfnParams parses out the sequence number, transaction hash
+// and removal reason from the parameters of a seqtxremoved notification.
+func parseSeqTxRemovedNtfnParams(params []json.RawMessage) (uint64,
+	*chainhash.Hash, string, error) {
+
+	if len(params) != 3 {
+		return 0, nil, "", wrongNumParams(len(params))
+	}
+
+	// Unmarshal first parameter as an unsigned integer.
+	var seq uint64
+	err := json.Unmarshal(params[0], &seq)
+	if err != nil {
+		return 0, nil, "", err
+	}
+
+	// Unmarshal second parameter as a string.
+	var txHashStr string
+	err = json.Unmarshal(params[1], &txHashStr)
+	if err != nil {
+		return 0, nil, "", err
+	}
+
+	// Unmarshal third parameter as a string.
+	var reason string
+	err = json.Unmarshal(params[2], &reason)
+	if err != nil {
+		return 0, nil, "", err
+	}
+
+	// Decode string encoding of transaction sha.
+	txHash, err := chainhash.NewHashFromStr(txHashStr)
+	if err != nil {
+		return 0, nil, "", err
+	}
+
+	return seq, txHash, reason, nil
+}
+
 // parseTxAcceptedVerboseNtfnParams parses out details about a raw transaction
 // from the parameters of a txacceptedverbose notification.
 func parseTxAcceptedVerboseNtfnParams(params []json.RawMessage) (*bronjson.TxRawResult,
@@ -1037,6 +1245,78 @@ func (c *Client) NotifyNewTransactions(verbose bool) error {
 	return c.NotifyNewTransactionsAsync(verbose).Receive()
 }
 
+// FutureNotifySinceResult is a future promise to deliver the result of a
+// NotifySinceAsync RPC invocation (or an applicable error).
+type FutureNotifySinceResult chan *response
+
+// Receive waits for the response promised by the future and returns the
+// sequence number of the latest event or an error if the registration was not
+// successful.
+func (r FutureNotifySinceResult) Receive() (uint64, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return 0, err
+	}
+
+	// The result is nil when the client is not interested in
+	// notifications.
+	if res == nil {
+		return 0, nil
+	}
+
+	// Unmarshal result as an unsigned integer.
+	var seq uint64
+	err = json.Unmarshal(res, &seq)
+	if err != nil {
+		return 0, err
+	}
+
+	return seq, nil
+}
+
+// NotifySinceAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See NotifySince for the blocking version and more details.
+//
+// NOTE: This is a brond extension and requires a websocket connection.
+func (c *Client) NotifySinceAsync(seq *uint64) FutureNotifySinceResult {
+	// Not supported in HTTP POST mode.
+	if c.config.HTTPPostMode {
+		return newFutureError(ErrWebsocketsRequired)
+	}
+
+	// Ignore the notification if the client is not interested in
+	// notifications.
+	if c.ntfnHandlers == nil {
+		return newNilFutureResult()
+	}
+
+	cmd := bronjson.NewNotifySinceCmd(seq)
+	return c.sendCmd(cmd)
+}
+
+// NotifySince registers the client to receive sequenced notifications for
+// every block connected to or disconnected from the main chain and every
+// transaction accepted into or removed from the memory pool.  When a sequence
+// number is passed, the events which followed it are delivered first, so a
+// client which stored the sequence number of the last event it processed can
+// resume the stream without missing events.  Otherwise only new events are
+// delivered.  The sequence number of the latest event is returned.
+//
+// The client keeps track of the sequence numbers it receives and
+// automatically resumes the stream after the last one on reconnect.
+//
+// The notifications delivered as a result of this call will be via one of
+// OnSeqBlockConnected, OnSeqBlockDisconnected, OnSeqTxAccepted or
+// OnSeqTxRemoved.
+//
+// NOTE: This is a brond extension and requires a websocket connection.
+func (c *Client) NotifySince(seq *uint64) (uint64, error) {
+	return c.NotifySinceAsync(seq).Receive()
+}
+
 // FutureNotifyReceivedResult is a future promise to deliver the result of a
 // NotifyReceivedAsync RPC invocation (or an applicable error).
 //
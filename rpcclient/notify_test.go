@@ -0,0 +1,113 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcclient
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/brsuite/brond/bronjson"
+	"github.com/brsuite/brond/chaincfg/chainhash"
+	"github.com/brsuite/brond/wire"
+	"github.com/brsuite/bronutil"
+)
+
+// TestSeqNotifications ensures sequenced notifications are delivered to their
+// handlers and the sequence number to resume from on reconnect is tracked.
+func TestSeqNotifications(t *testing.T) {
+	var gotSeqs []uint64
+	var gotHeight int32
+	var gotAmount bronutil.Amount
+	var gotReason string
+	client := &Client{
+		ntfnHandlers: &NotificationHandlers{
+			OnSeqBlockConnected: func(seq uint64, height int32,
+				header *wire.BlockHeader) {
+
+				gotSeqs = append(gotSeqs, seq)
+				gotHeight = height
+			},
+			OnSeqTxAccepted: func(seq uint64, hash *chainhash.Hash,
+				amount bronutil.Amount) {
+
+				gotSeqs = append(gotSeqs, seq)
+				gotAmount = amount
+			},
+			OnSeqTxRemoved: func(seq uint64, hash *chainhash.Hash,
+				reason string) {
+
+				gotSeqs = append(gotSeqs, seq)
+				gotReason = reason
+			},
+		},
+		ntfnState: newNotificationState(),
+	}
+	deliver := func(ntfn interface{}) {
+		t.Helper()
+		marshalled, err := bronjson.MarshalCmd(nil, ntfn)
+		if err != nil {
+			t.Fatalf("MarshalCmd: unexpected error %v", err)
+		}
+		var raw rawNotification
+		if err := json.Unmarshal(marshalled, &raw); err != nil {
+			t.Fatalf("Unmarshal: unexpected error %v", err)
+		}
+		client.handleNotification(&raw)
+	}
+	checkLastSeq := func(want uint64) {
+		t.Helper()
+		if client.ntfnState.lastSeq != want {
+			t.Fatalf("lastSeq: got %d, want %d",
+				client.ntfnState.lastSeq, want)
+		}
+	}
+
+	// Registering without a sequence number starts from the latest event.
+	client.trackRegisteredNtfns(bronjson.NewNotifySinceCmd(nil),
+		[]byte("4"))
+	if !client.ntfnState.notifySince {
+		t.Fatal("trackRegisteredNtfns: notifysince not tracked")
+	}
+	checkLastSeq(4)
+
+	var header bytes.Buffer
+	if err := (&wire.BlockHeader{}).Serialize(&header); err != nil {
+		t.Fatalf("Serialize: unexpected error %v", err)
+	}
+	txHash := chainhash.Hash{0x01}.String()
+	deliver(bronjson.NewSeqBlockConnectedNtfn(5, 100,
+		hex.EncodeToString(header.Bytes())))
+	deliver(bronjson.NewSeqTxAcceptedNtfn(6, txHash, 1.5))
+	deliver(bronjson.NewSeqTxRemovedNtfn(7, txHash, "replaced"))
+
+	// Notifications without a handler must still be tracked.
+	deliver(bronjson.NewSeqBlockDisconnectedNtfn(8, 100,
+		hex.EncodeToString(header.Bytes())))
+	checkLastSeq(8)
+
+	if len(gotSeqs) != 3 || gotSeqs[0] != 5 || gotSeqs[2] != 7 {
+		t.Fatalf("handlers: got sequence numbers %v, want [5 6 7]",
+			gotSeqs)
+	}
+	if gotHeight != 100 || gotAmount != 150000000 || gotReason != "replaced" {
+		t.Fatalf("handlers: got height %d, amount %v and reason %q",
+			gotHeight, gotAmount, gotReason)
+	}
+
+	// Resuming must not move the sequence number back, while starting
+	// over does, such as after the server restarted.
+	client.trackRegisteredNtfns(bronjson.NewNotifySinceCmd(
+		bronjson.Uint64(5)), []byte("7"))
+	checkLastSeq(8)
+	client.trackRegisteredNtfns(bronjson.NewNotifySinceCmd(nil),
+		[]byte("2"))
+	checkLastSeq(2)
+
+	// A failed registration must not change anything.
+	client.trackRegisteredNtfns(bronjson.NewNotifySinceCmd(nil), nil)
+	checkLastSeq(2)
+}
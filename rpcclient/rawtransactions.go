@@ -336,6 +336,58 @@ func (c *Client) SendRawTransaction(tx *wire.MsgTx, allowHighFees bool) (*chainh
 	return c.SendRawTransactionAsync(tx, allowHighFees).Receive()
 }
 
+// FutureTestMempoolAcceptResult is a future promise to deliver the result
+// of a TestMempoolAcceptAsync RPC invocation (or an applicable error).
+type FutureTestMempoolAcceptResult chan *response
+
+// Receive waits for the response promised by the future and returns whether
+// each of the tested transactions would be accepted into the memory pool of
+// the server.
+func (r FutureTestMempoolAcceptResult) Receive() ([]*bronjson.TestMempoolAcceptResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as an array of testmempoolaccept result objects.
+	var results []*bronjson.TestMempoolAcceptResult
+	err = json.Unmarshal(res, &results)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// TestMempoolAcceptAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See TestMempoolAccept for the blocking version and more details.
+func (c *Client) TestMempoolAcceptAsync(txns []*wire.MsgTx, maxFeeRate float64) FutureTestMempoolAcceptResult {
+	rawTxns := make([]string, 0, len(txns))
+	for _, tx := range txns {
+		// Serialize the transaction and convert to hex string.
+		buf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
+		if err := tx.Serialize(buf); err != nil {
+			return newFutureError(err)
+		}
+		rawTxns = append(rawTxns, hex.EncodeToString(buf.Bytes()))
+	}
+
+	cmd := bronjson.NewTestMempoolAcceptCmd(rawTxns, &maxFeeRate)
+	return c.sendCmd(cmd)
+}
+
+// TestMempoolAccept returns whether the passed transactions would be accepted
+// into the memory pool of the server without submitting them.  Each
+// transaction may spend the outputs of the transactions before it, so a chain
+// of transactions can be tested at once.  Transactions paying a fee rate in
+// BRON/kvB higher than maxFeeRate are rejected, unless it is 0.
+func (c *Client) TestMempoolAccept(txns []*wire.MsgTx, maxFeeRate float64) ([]*bronjson.TestMempoolAcceptResult, error) {
+	return c.TestMempoolAcceptAsync(txns, maxFeeRate).Receive()
+}
+
 // FutureSignRawTransactionResult is a future promise to deliver the result
 // of one of the SignRawTransactionAsync family of RPC invocations (or an
 // applicable error).
@@ -0,0 +1,491 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/brsuite/brond/bronjson"
+	"github.com/brsuite/brond/chaincfg/chainhash"
+	"github.com/brsuite/brond/database"
+	"github.com/brsuite/brond/mining"
+	"github.com/brsuite/brond/wire"
+	"github.com/brsuite/bronutil"
+)
+
+const (
+	// restMaxHeaders is the maximum number of headers returned by a single
+	// headers REST request.
+	restMaxHeaders = 2000
+
+	// restMaxOutPoints is the maximum number of outpoints which can be
+	// queried by a single getutxos REST request.
+	restMaxOutPoints = 15
+)
+
+// restFormat is the format of a REST response, which is selected by the
+// extension of the request path.
+type restFormat int
+
+const (
+	restFormatBinary restFormat = iota
+	restFormatHex
+	restFormatJSON
+)
+
+// restFormats maps the extensions of REST request paths to the format of the
+// response.
+var restFormats = map[string]restFormat{
+	"bin":  restFormatBinary,
+	"hex":  restFormatHex,
+	"json": restFormatJSON,
+}
+
+// restError is an error which answers a REST request with an HTTP status code.
+type restError struct {
+	code    int
+	message string
+}
+
+// Error returns the message of the error.
+func (e *restError) Error() string {
+	return e.message
+}
+
+// restHandler describes a callback function used to handle a REST request.
+// The arguments are the remainder of the request path after the prefix of the
+// handler and without the extension.  The result must be a byte slice for the
+// binary and hex formats, which is hex-encoded by the caller for the latter.
+type restHandler func(s *rpcServer, args string, format restFormat, closeChan <-chan struct{}) (interface{}, error)
+
+// restHandlers maps the prefixes of REST request paths to the handlers serving
+// them.  Handlers of prefixes without a trailing slash do not take arguments.
+var restHandlers = []struct {
+	prefix  string
+	handler restHandler
+}{
+	{"block/", handleRESTBlock},
+	{"headers/", handleRESTHeaders},
+	{"tx/", handleRESTTx},
+	{"getutxos/", handleRESTGetUtxos},
+	{"mempool/info", handleRESTMempoolInfo},
+	{"chaininfo", handleRESTChainInfo},
+}
+
+// parseRESTPath splits the passed REST request path, without the leading
+// /rest/, into the path without extension and the requested response format.
+func parseRESTPath(path string) (string, restFormat, error) {
+	dot := strings.LastIndexByte(path, '.')
+	if dot == -1 || strings.IndexByte(path[dot:], '/') != -1 {
+		return "", 0, &restError{http.StatusNotFound, "output format " +
+			"not found (available: bin, hex, json)"}
+	}
+	format, ok := restFormats[path[dot+1:]]
+	if !ok {
+		return "", 0, &restError{http.StatusNotFound, "output format " +
+			"not found (available: bin, hex, json)"}
+	}
+	return path[:dot], format, nil
+}
+
+// restRPCError converts an error returned by an RPC handler to an error with
+// the HTTP status code matching its code.
+func restRPCError(err error) error {
+	var rpcErr *bronjson.RPCError
+	if !errors.As(err, &rpcErr) {
+		return err
+	}
+	code := http.StatusInternalServerError
+	switch rpcErr.Code {
+	// Unknown transactions share the code of unknown blocks.
+	case bronjson.ErrRPCBlockNotFound:
+		code = http.StatusNotFound
+	case bronjson.ErrRPCDecodeHexString, bronjson.ErrRPCInvalidParameter:
+		code = http.StatusBadRequest
+	}
+	return &restError{code, rpcErr.Message}
+}
+
+// restJSONOnly returns an error for REST requests of results which are only
+// available in the JSON format.
+func restJSONOnly(format restFormat) error {
+	if format == restFormatJSON {
+		return nil
+	}
+	return &restError{http.StatusNotFound, "output format not found " +
+		"(available: json)"}
+}
+
+// handleREST handles REST requests.  They do not require authentication and
+// only provide information which is otherwise available to limited RPC users.
+func (s *rpcServer) handleREST(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Connection", "close")
+	r.Close = true
+
+	// Limit the number of connections to max allowed.
+	if s.limitConnections(w, r.RemoteAddr) {
+		return
+	}
+
+	// Keep track of the number of connected clients.
+	s.incrementClients()
+	defer s.decrementClients()
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "405 Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result, format, err := s.processRESTRequest(
+		strings.TrimPrefix(r.URL.Path, "/rest/"), r.Context().Done())
+	if err != nil {
+		code := http.StatusInternalServerError
+		var restErr *restError
+		if errors.As(err, &restErr) {
+			code = restErr.code
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	var body []byte
+	switch format {
+	case restFormatBinary:
+		w.Header().Set("Content-Type", "application/octet-stream")
+		body = result.([]byte)
+	case restFormatHex:
+		w.Header().Set("Content-Type", "text/plain")
+		body = []byte(hex.EncodeToString(result.([]byte)) + "\n")
+	case restFormatJSON:
+		w.Header().Set("Content-Type", "application/json")
+		body, err = json.Marshal(result)
+		if err != nil {
+			rpcsLog.Errorf("Failed to marshal REST reply: %v", err)
+			http.Error(w, "500 Internal server error.",
+				http.StatusInternalServerError)
+			return
+		}
+		body = append(body, '\n')
+	}
+	if _, err := w.Write(body); err != nil {
+		rpcsLog.Errorf("Failed to write REST reply: %v", err)
+	}
+}
+
+// processRESTRequest runs the handler of the passed REST request path, without
+// the leading /rest/, and returns its result along with the requested format.
+func (s *rpcServer) processRESTRequest(path string, closeChan <-chan struct{}) (interface{}, restFormat, error) {
+	path, format, err := parseRESTPath(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, route := range restHandlers {
+		if !strings.HasPrefix(path, route.prefix) {
+			continue
+		}
+		args := path[len(route.prefix):]
+		if !strings.HasSuffix(route.prefix, "/") && args != "" {
+			continue
+		}
+		result, err := route.handler(s, args, format, closeChan)
+		return result, format, err
+	}
+	return nil, 0, &restError{http.StatusNotFound, "not found"}
+}
+
+// handleRESTBlock handles /rest/block/<hash> requests.
+func handleRESTBlock(s *rpcServer, args string, format restFormat, closeChan <-chan struct{}) (interface{}, error) {
+	if format == restFormatJSON {
+		result, err := handleGetBlock(s, &bronjson.GetBlockCmd{
+			Hash:      args,
+			Verbose:   bronjson.Bool(true),
+			VerboseTx: bronjson.Bool(true),
+		}, closeChan)
+		return result, restRPCError(err)
+	}
+
+	hash, err := chainhash.NewHashFromStr(args)
+	if err != nil {
+		return nil, &restError{http.StatusBadRequest, "invalid hash: " +
+			args}
+	}
+	var blkBytes []byte
+	err = s.cfg.DB.View(func(dbTx database.Tx) error {
+		var err error
+		blkBytes, err = dbTx.FetchBlock(hash)
+		return err
+	})
+	if err != nil {
+		return nil, &restError{http.StatusNotFound, args + " not found"}
+	}
+	return blkBytes, nil
+}
+
+// handleRESTHeaders handles /rest/headers/<count>/<hash> requests, which return
+// up to count headers of the main chain starting with the one of the block with
+// the passed hash.
+func handleRESTHeaders(s *rpcServer, args string, format restFormat, closeChan <-chan struct{}) (interface{}, error) {
+	countStr, hashStr, ok := strings.Cut(args, "/")
+	if !ok {
+		return nil, &restError{http.StatusBadRequest, "invalid URI " +
+			"format.  Expected /rest/headers/<count>/<hash>.<ext>"}
+	}
+	count, err := strconv.Atoi(countStr)
+	if err != nil || count < 1 || count > restMaxHeaders {
+		return nil, &restError{http.StatusBadRequest, fmt.Sprintf(
+			"header count is invalid or out of acceptable range "+
+				"(1-%d): %s", restMaxHeaders, countStr)}
+	}
+	hash, err := chainhash.NewHashFromStr(hashStr)
+	if err != nil {
+		return nil, &restError{http.StatusBadRequest, "invalid hash: " +
+			hashStr}
+	}
+
+	height, err := s.cfg.Chain.BlockHeightByHash(hash)
+	if err != nil {
+		return nil, &restError{http.StatusNotFound, hashStr + " not found"}
+	}
+	best := s.cfg.Chain.BestSnapshot()
+	hashes := []*chainhash.Hash{hash}
+	for h := height + 1; h <= best.Height && len(hashes) < count; h++ {
+		hash, err := s.cfg.Chain.BlockHashByHeight(h)
+		if err != nil {
+			// The chain was reorganized while collecting the
+			// hashes, so return the ones still in the main chain.
+			break
+		}
+		hashes = append(hashes, hash)
+	}
+
+	if format == restFormatJSON {
+		headers := make([]interface{}, 0, len(hashes))
+		for _, hash := range hashes {
+			header, err := handleGetBlockHeader(s,
+				&bronjson.GetBlockHeaderCmd{
+					Hash:    hash.String(),
+					Verbose: bronjson.Bool(true),
+				}, closeChan)
+			if err != nil {
+				return nil, restRPCError(err)
+			}
+			headers = append(headers, header)
+		}
+		return headers, nil
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(hashes) * wire.MaxBlockHeaderPayload)
+	for _, hash := range hashes {
+		header, err := s.cfg.Chain.HeaderByHash(hash)
+		if err != nil {
+			return nil, &restError{http.StatusNotFound,
+				hash.String() + " not found"}
+		}
+		if err := header.Serialize(&buf); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// handleRESTTx handles /rest/tx/<txid> requests.
+func handleRESTTx(s *rpcServer, args string, format restFormat, closeChan <-chan struct{}) (interface{}, error) {
+	verbose := 0
+	if format == restFormatJSON {
+		verbose = 1
+	}
+	result, err := handleGetRawTransaction(s, &bronjson.GetRawTransactionCmd{
+		Txid:    args,
+		Verbose: &verbose,
+	}, closeChan)
+	if err != nil {
+		return nil, restRPCError(err)
+	}
+	if format == restFormatJSON {
+		return result, nil
+	}
+	return hex.DecodeString(result.(string))
+}
+
+// restUtxo describes an unspent output in the JSON response of a getutxos
+// REST request.
+type restUtxo struct {
+	Height       int32                       `json:"height"`
+	Value        float64                     `json:"value"`
+	ScriptPubKey bronjson.ScriptPubKeyResult `json:"scriptPubKey"`
+}
+
+// restGetUtxosResult is the JSON response of a getutxos REST request.
+type restGetUtxosResult struct {
+	ChainHeight  int32      `json:"chainHeight"`
+	ChainTipHash string     `json:"chaintipHash"`
+	Bitmap       string     `json:"bitmap"`
+	Utxos        []restUtxo `json:"utxos"`
+}
+
+// parseRESTOutPoints parses the arguments of a getutxos REST request, which are
+// an optional checkmempool followed by outpoints as <txid>-<index> separated by
+// slashes.
+func parseRESTOutPoints(args string) (bool, []wire.OutPoint, error) {
+	parts := strings.Split(args, "/")
+	checkMempool := parts[0] == "checkmempool"
+	if checkMempool {
+		parts = parts[1:]
+	}
+	if len(parts) == 0 || len(parts) == 1 && parts[0] == "" {
+		return false, nil, &restError{http.StatusBadRequest,
+			"no outpoints specified"}
+	}
+	if len(parts) > restMaxOutPoints {
+		return false, nil, &restError{http.StatusBadRequest, fmt.Sprintf(
+			"too many outpoints, at most %d are allowed",
+			restMaxOutPoints)}
+	}
+
+	ops := make([]wire.OutPoint, 0, len(parts))
+	for _, part := range parts {
+		txid, indexStr, ok := strings.Cut(part, "-")
+		hash, err := chainhash.NewHashFromStr(txid)
+		if !ok || err != nil {
+			return false, nil, &restError{http.StatusBadRequest,
+				"invalid outpoint: " + part}
+		}
+		index, err := strconv.ParseUint(indexStr, 10, 32)
+		if err != nil {
+			return false, nil, &restError{http.StatusBadRequest,
+				"invalid outpoint: " + part}
+		}
+		ops = append(ops, *wire.NewOutPoint(hash, uint32(index)))
+	}
+	return checkMempool, ops, nil
+}
+
+// serializeRESTUtxos returns the binary response of a getutxos REST request in
+// the format used by Brocoin Core.  The bitmap has a bit set for each queried
+// outpoint which is unspent, whose heights and outputs are passed in order.
+func serializeRESTUtxos(chainHeight int32, chainTip *chainhash.Hash,
+	bitmap []byte, heights []int32, txOuts []*wire.TxOut) ([]byte, error) {
+
+	var buf bytes.Buffer
+	var scratch [4]byte
+	binary.LittleEndian.PutUint32(scratch[:], uint32(chainHeight))
+	buf.Write(scratch[:])
+	buf.Write(chainTip[:])
+	if err := wire.WriteVarBytes(&buf, 0, bitmap); err != nil {
+		return nil, err
+	}
+	if err := wire.WriteVarInt(&buf, 0, uint64(len(txOuts))); err != nil {
+		return nil, err
+	}
+	for i, txOut := range txOuts {
+		// Each output is preceded by an unused transaction version.
+		binary.LittleEndian.PutUint32(scratch[:], 0)
+		buf.Write(scratch[:])
+		binary.LittleEndian.PutUint32(scratch[:], uint32(heights[i]))
+		buf.Write(scratch[:])
+		if err := wire.WriteTxOut(&buf, 0, 0, txOut); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// handleRESTGetUtxos handles /rest/getutxos/[checkmempool/]<txid>-<index>/...
+// requests.  When checkmempool is given, outputs spent by transactions in the
+// mempool are reported as spent and outputs of transactions in the mempool as
+// unspent with the height used for unmined transactions.
+func handleRESTGetUtxos(s *rpcServer, args string, format restFormat, closeChan <-chan struct{}) (interface{}, error) {
+	checkMempool, ops, err := parseRESTOutPoints(args)
+	if err != nil {
+		return nil, err
+	}
+
+	best := s.cfg.Chain.BestSnapshot()
+	bitmap := make([]byte, (len(ops)+7)/8)
+	var heights []int32
+	var txOuts []*wire.TxOut
+	for i, op := range ops {
+		if checkMempool && s.cfg.TxMemPool.CheckSpend(op) != nil {
+			continue
+		}
+
+		var txOut *wire.TxOut
+		var height int32
+		if checkMempool && s.cfg.TxMemPool.HaveTransaction(&op.Hash) {
+			tx, err := s.cfg.TxMemPool.FetchTransaction(&op.Hash)
+			if err == nil && op.Index < uint32(len(tx.MsgTx().TxOut)) {
+				txOut = tx.MsgTx().TxOut[op.Index]
+				height = mining.UnminedHeight
+			}
+		} else {
+			entry, err := s.cfg.Chain.FetchUtxoEntry(op)
+			if err != nil {
+				return nil, err
+			}
+			if entry != nil && !entry.IsSpent() {
+				txOut = wire.NewTxOut(entry.Amount(), entry.PkScript())
+				height = entry.BlockHeight()
+			}
+		}
+		if txOut == nil {
+			continue
+		}
+		bitmap[i/8] |= 1 << (uint(i) % 8)
+		heights = append(heights, height)
+		txOuts = append(txOuts, txOut)
+	}
+
+	if format != restFormatJSON {
+		return serializeRESTUtxos(best.Height, &best.Hash, bitmap,
+			heights, txOuts)
+	}
+
+	result := &restGetUtxosResult{
+		ChainHeight:  best.Height,
+		ChainTipHash: best.Hash.String(),
+		Utxos:        make([]restUtxo, 0, len(txOuts)),
+	}
+	var bitmapStr strings.Builder
+	for i := range ops {
+		bitmapStr.WriteByte('0' + bitmap[i/8]>>(uint(i)%8)&1)
+	}
+	result.Bitmap = bitmapStr.String()
+	vouts := createVoutList(&wire.MsgTx{TxOut: txOuts}, s.cfg.ChainParams,
+		nil)
+	for i, vout := range vouts {
+		result.Utxos = append(result.Utxos, restUtxo{
+			Height:       heights[i],
+			Value:        bronutil.Amount(txOuts[i].Value).ToBRON(),
+			ScriptPubKey: vout.ScriptPubKey,
+		})
+	}
+	return result, nil
+}
+
+// handleRESTMempoolInfo handles /rest/mempool/info requests.
+func handleRESTMempoolInfo(s *rpcServer, args string, format restFormat, closeChan <-chan struct{}) (interface{}, error) {
+	if err := restJSONOnly(format); err != nil {
+		return nil, err
+	}
+	return handleGetMempoolInfo(s, nil, closeChan)
+}
+
+// handleRESTChainInfo handles /rest/chaininfo requests.
+func handleRESTChainInfo(s *rpcServer, args string, format restFormat, closeChan <-chan struct{}) (interface{}, error) {
+	if err := restJSONOnly(format); err != nil {
+		return nil, err
+	}
+	result, err := handleGetBlockChainInfo(s, nil, closeChan)
+	return result, restRPCError(err)
+}
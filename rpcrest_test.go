@@ -0,0 +1,160 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/brsuite/brond/chaincfg/chainhash"
+	"github.com/brsuite/brond/wire"
+)
+
+// restErrorCode returns the HTTP status code of the passed REST error or zero
+// when it is not one.
+func restErrorCode(err error) int {
+	var restErr *restError
+	if errors.As(err, &restErr) {
+		return restErr.code
+	}
+	return 0
+}
+
+// TestParseRESTPath ensures the format of REST requests is selected by the
+// extension of their path.
+func TestParseRESTPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		path     string
+		want     string
+		format   restFormat
+		wantCode int
+	}{
+		{path: "block/00ff.bin", want: "block/00ff", format: restFormatBinary},
+		{path: "headers/5/00ff.hex", want: "headers/5/00ff", format: restFormatHex},
+		{path: "mempool/info.json", want: "mempool/info", format: restFormatJSON},
+		{path: "chaininfo", wantCode: http.StatusNotFound},
+		{path: "chaininfo.xml", wantCode: http.StatusNotFound},
+		{path: "block.json/00ff", wantCode: http.StatusNotFound},
+	}
+	for _, test := range tests {
+		got, format, err := parseRESTPath(test.path)
+		if test.wantCode != 0 {
+			if code := restErrorCode(err); code != test.wantCode {
+				t.Errorf("%q: got status %d, want %d", test.path,
+					code, test.wantCode)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", test.path, err)
+			continue
+		}
+		if got != test.want || format != test.format {
+			t.Errorf("%q: got (%q, %d), want (%q, %d)", test.path, got,
+				format, test.want, test.format)
+		}
+	}
+}
+
+// TestParseRESTOutPoints ensures the outpoints queried by getutxos REST
+// requests are parsed along with the checkmempool option.
+func TestParseRESTOutPoints(t *testing.T) {
+	t.Parallel()
+
+	const txid = "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"
+	hash, err := chainhash.NewHashFromStr(txid)
+	if err != nil {
+		t.Fatalf("NewHashFromStr: unexpected error %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		args         string
+		checkMempool bool
+		ops          []wire.OutPoint
+		wantErr      bool
+	}{
+		{
+			name: "single outpoint",
+			args: txid + "-0",
+			ops:  []wire.OutPoint{{Hash: *hash, Index: 0}},
+		},
+		{
+			name:         "checkmempool",
+			args:         "checkmempool/" + txid + "-1/" + txid + "-2",
+			checkMempool: true,
+			ops: []wire.OutPoint{
+				{Hash: *hash, Index: 1},
+				{Hash: *hash, Index: 2},
+			},
+		},
+		{name: "no outpoints", args: "", wantErr: true},
+		{name: "only checkmempool", args: "checkmempool", wantErr: true},
+		{name: "missing index", args: txid, wantErr: true},
+		{name: "invalid index", args: txid + "-x", wantErr: true},
+		{name: "invalid txid", args: "zz-0", wantErr: true},
+		{
+			name: "too many outpoints",
+			args: strings.Repeat(txid+"-0/", restMaxOutPoints) +
+				txid + "-0",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		checkMempool, ops, err := parseRESTOutPoints(test.args)
+		if test.wantErr {
+			if code := restErrorCode(err); code != http.StatusBadRequest {
+				t.Errorf("%s: got status %d, want %d", test.name,
+					code, http.StatusBadRequest)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if checkMempool != test.checkMempool ||
+			!reflect.DeepEqual(ops, test.ops) {
+
+			t.Errorf("%s: got (%v, %v), want (%v, %v)", test.name,
+				checkMempool, ops, test.checkMempool, test.ops)
+		}
+	}
+}
+
+// TestSerializeRESTUtxos ensures the binary response of getutxos REST requests
+// is serialized in the expected format.
+func TestSerializeRESTUtxos(t *testing.T) {
+	t.Parallel()
+
+	var tip chainhash.Hash
+	tip[0] = 0x01
+	txOuts := []*wire.TxOut{wire.NewTxOut(5000, []byte{0x51})}
+	got, err := serializeRESTUtxos(100, &tip, []byte{0x02}, []int32{99},
+		txOuts)
+	if err != nil {
+		t.Fatalf("serializeRESTUtxos: unexpected error %v", err)
+	}
+
+	want, _ := hex.DecodeString(
+		"64000000" + // chain height
+			"01" + strings.Repeat("00", 31) + // chain tip
+			"0102" + // bitmap
+			"01" + // number of outputs
+			"00000000" + // unused version
+			"63000000" + // height
+			"8813000000000000" + // value
+			"0151") // pkScript
+	if !bytes.Equal(got, want) {
+		t.Fatalf("got %x, want %x", got, want)
+	}
+}
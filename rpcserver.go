@@ -7,8 +7,6 @@ package main
 
 import (
 	"bytes"
-	"crypto/sha256"
-	"crypto/subtle"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
@@ -21,18 +19,21 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/brsuite/brond/addrmgr"
 	"github.com/brsuite/brond/blockchain"
 	"github.com/brsuite/brond/blockchain/indexers"
 	"github.com/brsuite/brond/bronec"
 	"github.com/brsuite/brond/bronjson"
 	"github.com/brsuite/brond/chaincfg"
 	"github.com/brsuite/brond/chaincfg/chainhash"
+	"github.com/brsuite/brond/connmgr"
 	"github.com/brsuite/brond/database"
 	"github.com/brsuite/brond/mempool"
 	"github.com/brsuite/brond/mining"
@@ -75,6 +76,10 @@ const (
 
 	// maxProtocolVersion is the max protocol version the server supports.
 	maxProtocolVersion = 70002
+
+	// maxTestMempoolAcceptTxns is the maximum number of transactions which
+	// can be tested at once by the testmempoolaccept RPC.
+	maxTestMempoolAcceptTxns = 25
 )
 
 var (
@@ -128,11 +133,13 @@ type commandHandler func(*rpcServer, interface{}, <-chan struct{}) (interface{},
 var rpcHandlers map[string]commandHandler
 var rpcHandlersBeforeInit = map[string]commandHandler{
 	"addnode":               handleAddNode,
+	"clearbanned":           handleClearBanned,
 	"createrawtransaction":  handleCreateRawTransaction,
 	"debuglevel":            handleDebugLevel,
 	"decoderawtransaction":  handleDecodeRawTransaction,
 	"decodescript":          handleDecodeScript,
 	"estimatefee":           handleEstimateFee,
+	"estimatesmartfee":      handleEstimateSmartFee,
 	"generate":              handleGenerate,
 	"getaddednodeinfo":      handleGetAddedNodeInfo,
 	"getbestblock":          handleGetBestBlock,
@@ -142,9 +149,11 @@ var rpcHandlersBeforeInit = map[string]commandHandler{
 	"getblockcount":         handleGetBlockCount,
 	"getblockhash":          handleGetBlockHash,
 	"getblockheader":        handleGetBlockHeader,
+	"getblockstats":         handleGetBlockStats,
 	"getblocktemplate":      handleGetBlockTemplate,
 	"getcfilter":            handleGetCFilter,
 	"getcfilterheader":      handleGetCFilterHeader,
+	"getchaintips":          handleGetChainTips,
 	"getconnectioncount":    handleGetConnectionCount,
 	"getcurrentnet":         handleGetCurrentNet,
 	"getdifficulty":         handleGetDifficulty,
@@ -152,22 +161,35 @@ var rpcHandlersBeforeInit = map[string]commandHandler{
 	"gethashespersec":       handleGetHashesPerSec,
 	"getheaders":            handleGetHeaders,
 	"getinfo":               handleGetInfo,
+	"getmempoolancestors":   handleGetMempoolAncestors,
+	"getmempooldescendants": handleGetMempoolDescendants,
+	"getmempoolentry":       handleGetMempoolEntry,
 	"getmempoolinfo":        handleGetMempoolInfo,
 	"getmininginfo":         handleGetMiningInfo,
 	"getnettotals":          handleGetNetTotals,
 	"getnetworkhashps":      handleGetNetworkHashPS,
+	"getnetworkinfo":        handleGetNetworkInfo,
 	"getpeerinfo":           handleGetPeerInfo,
 	"getrawmempool":         handleGetRawMempool,
 	"getrawtransaction":     handleGetRawTransaction,
 	"gettxout":              handleGetTxOut,
+	"gettxoutsetinfo":       handleGetTxOutSetInfo,
 	"help":                  handleHelp,
+	"invalidateblock":       handleInvalidateBlock,
+	"listbanned":            handleListBanned,
+	"loadmempool":           handleLoadMempool,
 	"node":                  handleNode,
 	"ping":                  handlePing,
+	"preciousblock":         handlePreciousBlock,
+	"reconsiderblock":       handleReconsiderBlock,
+	"savemempool":           handleSaveMempool,
 	"searchrawtransactions": handleSearchRawTransactions,
 	"sendrawtransaction":    handleSendRawTransaction,
+	"setban":                handleSetBan,
 	"setgenerate":           handleSetGenerate,
 	"stop":                  handleStop,
 	"submitblock":           handleSubmitBlock,
+	"testmempoolaccept":     handleTestMempoolAccept,
 	"uptime":                handleUptime,
 	"validateaddress":       handleValidateAddress,
 	"verifychain":           handleVerifyChain,
@@ -195,7 +217,6 @@ var rpcAskWallet = map[string]struct{}{
 	"getreceivedbyaccount":   {},
 	"getreceivedbyaddress":   {},
 	"gettransaction":         {},
-	"gettxoutsetinfo":        {},
 	"getunconfirmedbalance":  {},
 	"getwalletinfo":          {},
 	"importprivkey":          {},
@@ -226,13 +247,7 @@ var rpcAskWallet = map[string]struct{}{
 // Commands that are currently unimplemented, but should ultimately be.
 var rpcUnimplemented = map[string]struct{}{
 	"estimatepriority": {},
-	"getchaintips":     {},
-	"getmempoolentry":  {},
-	"getnetworkinfo":   {},
 	"getwork":          {},
-	"invalidateblock":  {},
-	"preciousblock":    {},
-	"reconsiderblock":  {},
 }
 
 // Commands that are available to a limited user
@@ -255,26 +270,34 @@ var rpcLimited = map[string]struct{}{
 	"decoderawtransaction":  {},
 	"decodescript":          {},
 	"estimatefee":           {},
+	"estimatesmartfee":      {},
 	"getbestblock":          {},
 	"getbestblockhash":      {},
 	"getblock":              {},
 	"getblockcount":         {},
 	"getblockhash":          {},
 	"getblockheader":        {},
+	"getblockstats":         {},
 	"getcfilter":            {},
 	"getcfilterheader":      {},
+	"getchaintips":          {},
 	"getcurrentnet":         {},
 	"getdifficulty":         {},
 	"getheaders":            {},
 	"getinfo":               {},
+	"getmempoolancestors":   {},
+	"getmempooldescendants": {},
+	"getmempoolentry":       {},
 	"getnettotals":          {},
 	"getnetworkhashps":      {},
+	"getnetworkinfo":        {},
 	"getrawmempool":         {},
 	"getrawtransaction":     {},
 	"gettxout":              {},
 	"searchrawtransactions": {},
 	"sendrawtransaction":    {},
 	"submitblock":           {},
+	"testmempoolaccept":     {},
 	"uptime":                {},
 	"validateaddress":       {},
 	"verifymessage":         {},
@@ -508,6 +531,16 @@ func messageToHex(msg wire.Message) (string, error) {
 	return hex.EncodeToString(buf.Bytes()), nil
 }
 
+// handleClearBanned implements the clearbanned command.
+func handleClearBanned(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if err := s.cfg.ConnMgr.ClearBanned(); err != nil {
+		return nil, internalRPCError(err.Error(),
+			"Unable to save the ban list")
+	}
+
+	return nil, nil
+}
+
 // handleCreateRawTransaction handles createrawtransaction commands.
 func handleCreateRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*bronjson.CreateRawTransactionCmd)
@@ -877,6 +910,59 @@ func handleEstimateFee(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 	return float64(feeRate), nil
 }
 
+// handleEstimateSmartFee handles estimatesmartfee commands.
+func handleEstimateSmartFee(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*bronjson.EstimateSmartFeeCmd)
+
+	if s.cfg.FeeEstimator == nil {
+		return nil, errors.New("Fee estimation disabled")
+	}
+
+	if c.ConfTarget < 1 || c.ConfTarget > mempool.MaxEstimateFeeTarget {
+		return nil, &bronjson.RPCError{
+			Code: bronjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Invalid conf_target, must be "+
+				"between 1 and %d", mempool.MaxEstimateFeeTarget),
+		}
+	}
+
+	// Unless economical estimates are requested, estimates are made
+	// conservatively.
+	conservative := true
+	if c.EstimateMode != nil {
+		switch bronjson.EstimateSmartFeeMode(strings.ToUpper(string(*c.EstimateMode))) {
+		case bronjson.EstimateModeUnset, bronjson.EstimateModeConservative:
+		case bronjson.EstimateModeEconomical:
+			conservative = false
+		default:
+			return nil, &bronjson.RPCError{
+				Code:    bronjson.ErrRPCInvalidParameter,
+				Message: "Invalid estimate_mode parameter",
+			}
+		}
+	}
+
+	feeRate, blocks, err := s.cfg.FeeEstimator.EstimateSmartFee(
+		uint32(c.ConfTarget), conservative)
+	if err != nil {
+		return &bronjson.EstimateSmartFeeResult{
+			Errors: []string{err.Error()},
+			Blocks: int64(blocks),
+		}, nil
+	}
+
+	// Transactions paying less than the minimum relay fee are not accepted
+	// to the mempool, regardless of the estimate.
+	estimate := float64(feeRate)
+	if minRelayFee := cfg.minRelayTxFee.ToBRON(); estimate < minRelayFee {
+		estimate = minRelayFee
+	}
+	return &bronjson.EstimateSmartFeeResult{
+		FeeRate: &estimate,
+		Blocks:  int64(blocks),
+	}, nil
+}
+
 // handleGenerate handles generate commands.
 func handleGenerate(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	// Respond with an error if there are no addresses to pay the
@@ -1189,19 +1275,23 @@ func handleGetBlockChainInfo(s *rpcServer, cmd interface{}, closeChan <-chan str
 	params := s.cfg.ChainParams
 	chain := s.cfg.Chain
 	chainSnapshot := chain.BestSnapshot()
+	_, headersHeight := chain.BestHeader()
 
 	chainInfo := &bronjson.GetBlockChainInfoResult{
 		Chain:         params.Name,
 		Blocks:        chainSnapshot.Height,
-		Headers:       chainSnapshot.Height,
+		Headers:       headersHeight,
 		BestBlockHash: chainSnapshot.Hash.String(),
 		Difficulty:    getDifficultyRatio(chainSnapshot.Bits, params),
 		MedianTime:    chainSnapshot.MedianTime.Unix(),
-		Pruned:        false,
+		Pruned:        cfg.Prune != 0 || chain.IsPruned(),
 		SoftForks: &bronjson.SoftForks{
 			Bip9SoftForks: make(map[string]*bronjson.Bip9SoftForkDescription),
 		},
 	}
+	if chainInfo.Pruned {
+		chainInfo.PruneHeight = chain.PrunedHeight() + 1
+	}
 
 	// Next, populate the response with information describing the current
 	// status of soft-forks deployed via the super-majority block
@@ -1253,6 +1343,9 @@ func handleGetBlockChainInfo(s *rpcServer, cmd interface{}, closeChan <-chan str
 		case chaincfg.DeploymentSegwit:
 			forkName = "segwit"
 
+		case chaincfg.DeploymentTaproot:
+			forkName = "taproot"
+
 		default:
 			return nil, &bronjson.RPCError{
 				Code: bronjson.ErrRPCInternal.Code,
@@ -1382,6 +1475,264 @@ func handleGetBlockHeader(s *rpcServer, cmd interface{}, closeChan <-chan struct
 	return blockHeaderReply, nil
 }
 
+// blockStatsUtxoOverhead is the number of bytes added to the serialized size of
+// an output when estimating how much it grows the utxo set.  It accounts for
+// the outpoint along with the height and coinbase flag of the utxo entry.
+const blockStatsUtxoOverhead = 41
+
+// feeRateWeight is the fee rate of a transaction paired with its weight, which
+// is used to calculate the fee rate percentiles of a block.
+type feeRateWeight struct {
+	feeRate int64
+	weight  int64
+}
+
+// medianInt64 returns the median of the passed values, rounded down when there
+// is an even number of them, or 0 when there are none.  The values are sorted
+// in place.
+func medianInt64(values []int64) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		return values[i] < values[j]
+	})
+	mid := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[mid-1] + values[mid]) / 2
+	}
+	return values[mid]
+}
+
+// feeRatePercentiles returns the 10th, 25th, 50th, 75th and 90th percentile
+// fee rates of the passed transactions weighted by their weight.  The fee
+// rates are sorted in place.
+func feeRatePercentiles(feeRates []feeRateWeight, totalWeight int64) []int64 {
+	percentiles := make([]int64, 5)
+	if len(feeRates) == 0 {
+		return percentiles
+	}
+
+	sort.Slice(feeRates, func(i, j int) bool {
+		return feeRates[i].feeRate < feeRates[j].feeRate
+	})
+	thresholds := [...]float64{
+		float64(totalWeight) / 10,
+		float64(totalWeight) / 4,
+		float64(totalWeight) / 2,
+		float64(totalWeight) * 3 / 4,
+		float64(totalWeight) * 9 / 10,
+	}
+	var next int
+	var cumulativeWeight int64
+	for _, fr := range feeRates {
+		cumulativeWeight += fr.weight
+		for next < len(thresholds) &&
+			float64(cumulativeWeight) >= thresholds[next] {
+
+			percentiles[next] = fr.feeRate
+			next++
+		}
+	}
+
+	// Fill any remaining percentiles with the highest fee rate.
+	for ; next < len(percentiles); next++ {
+		percentiles[next] = feeRates[len(feeRates)-1].feeRate
+	}
+	return percentiles
+}
+
+// calcBlockStats returns the statistics of the passed block which can be
+// calculated from its transactions and the outputs they spend.  The spent
+// outputs must be in the order they are spent by the block as returned by
+// the spend journal.
+func calcBlockStats(block *bronutil.Block, stxos []blockchain.SpentTxOut) (*bronjson.GetBlockStatsResult, error) {
+	var stats bronjson.GetBlockStatsResult
+	var fees, txSizes []int64
+	var feeRates []feeRateWeight
+	var stxoIdx int
+	txns := block.Transactions()
+	for _, tx := range txns {
+		msgTx := tx.MsgTx()
+
+		// All outputs grow the utxo set, including those of the
+		// coinbase.
+		var txOut int64
+		stats.Outs += int64(len(msgTx.TxOut))
+		for _, out := range msgTx.TxOut {
+			txOut += out.Value
+			stats.UTXOSizeIncrease += int64(out.SerializeSize() +
+				blockStatsUtxoOverhead)
+		}
+
+		// The coinbase has no real inputs and pays no fee, so it is
+		// excluded from the remaining statistics.
+		if blockchain.IsCoinBase(tx) {
+			continue
+		}
+		stats.Ins += int64(len(msgTx.TxIn))
+		stats.TotalOut += txOut
+
+		size := int64(msgTx.SerializeSize())
+		weight := blockchain.GetTransactionWeight(tx)
+		txSizes = append(txSizes, size)
+		stats.TotalSize += size
+		stats.TotalWeight += weight
+		if size > stats.MaxTxSize {
+			stats.MaxTxSize = size
+		}
+		if stats.MinTxSize == 0 || size < stats.MinTxSize {
+			stats.MinTxSize = size
+		}
+		if msgTx.HasWitness() {
+			stats.SegWitTxs++
+			stats.SegWitTotalSize += size
+			stats.SegWitTotalWeight += weight
+		}
+
+		// Calculate the fee from the spent outputs.
+		if stxoIdx+len(msgTx.TxIn) > len(stxos) {
+			return nil, fmt.Errorf("spend journal of block %v is "+
+				"missing outputs spent by %v", block.Hash(),
+				tx.Hash())
+		}
+		var txIn int64
+		for _, stxo := range stxos[stxoIdx : stxoIdx+len(msgTx.TxIn)] {
+			txIn += stxo.Amount
+			out := wire.TxOut{Value: stxo.Amount, PkScript: stxo.PkScript}
+			stats.UTXOSizeIncrease -= int64(out.SerializeSize() +
+				blockStatsUtxoOverhead)
+		}
+		stxoIdx += len(msgTx.TxIn)
+
+		fee := txIn - txOut
+		feeRate := fee * blockchain.WitnessScaleFactor / weight
+		fees = append(fees, fee)
+		feeRates = append(feeRates, feeRateWeight{feeRate, weight})
+		stats.TotalFee += fee
+		if len(fees) == 1 || fee > stats.MaxFee {
+			stats.MaxFee = fee
+		}
+		if len(fees) == 1 || fee < stats.MinFee {
+			stats.MinFee = fee
+		}
+		if len(fees) == 1 || feeRate > stats.MaxFeeRate {
+			stats.MaxFeeRate = feeRate
+		}
+		if len(fees) == 1 || feeRate < stats.MinFeeRate {
+			stats.MinFeeRate = feeRate
+		}
+	}
+
+	stats.Txs = int64(len(txns))
+	if numTxns := int64(len(fees)); numTxns > 0 {
+		stats.AverageFee = stats.TotalFee / numTxns
+		stats.AverageTxSize = stats.TotalSize / numTxns
+	}
+	if stats.TotalWeight > 0 {
+		stats.AverageFeeRate = stats.TotalFee *
+			blockchain.WitnessScaleFactor / stats.TotalWeight
+	}
+	stats.MedianFee = medianInt64(fees)
+	stats.MedianTxSize = medianInt64(txSizes)
+	stats.FeeRatePercentiles = feeRatePercentiles(feeRates,
+		stats.TotalWeight)
+	stats.UTXOIncrease = stats.Outs - stats.Ins
+	return &stats, nil
+}
+
+// handleGetBlockStats implements the getblockstats command.
+func handleGetBlockStats(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*bronjson.GetBlockStatsCmd)
+
+	// Look up the hash of the block when it is identified by its height.
+	var hash *chainhash.Hash
+	if height, ok := c.HashOrHeight.Height(); ok {
+		best := s.cfg.Chain.BestSnapshot()
+		if height < 0 || height > int64(best.Height) {
+			return nil, &bronjson.RPCError{
+				Code: bronjson.ErrRPCOutOfRange,
+				Message: fmt.Sprintf("Block height %d out of "+
+					"range", height),
+			}
+		}
+		var err error
+		hash, err = s.cfg.Chain.BlockHashByHeight(int32(height))
+		if err != nil {
+			return nil, &bronjson.RPCError{
+				Code:    bronjson.ErrRPCOutOfRange,
+				Message: "Block number out of range",
+			}
+		}
+	} else {
+		var err error
+		hash, err = chainhash.NewHashFromStr(string(c.HashOrHeight))
+		if err != nil {
+			return nil, rpcDecodeHexError(string(c.HashOrHeight))
+		}
+	}
+
+	// The spend journal only holds the outputs spent by blocks in the main
+	// chain, so the fees of other blocks can't be calculated.
+	block, err := s.cfg.Chain.BlockByHash(hash)
+	if err != nil {
+		return nil, &bronjson.RPCError{
+			Code:    bronjson.ErrRPCBlockNotFound,
+			Message: "Block not found in the main chain",
+		}
+	}
+	stxos, err := s.cfg.Chain.FetchSpendJournal(block)
+	if err != nil {
+		context := "Failed to fetch spent outputs"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	medianTime, err := s.cfg.Chain.MedianTimeByHash(hash)
+	if err != nil {
+		context := "Failed to obtain median time"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	stats, err := calcBlockStats(block, stxos)
+	if err != nil {
+		return nil, internalRPCError(err.Error(), "")
+	}
+	stats.Hash = hash.String()
+	stats.Height = int64(block.Height())
+	stats.Time = block.MsgBlock().Header.Timestamp.Unix()
+	stats.MedianTime = medianTime.Unix()
+	stats.Subsidy = blockchain.CalcBlockSubsidy(block.Height(),
+		s.cfg.ChainParams)
+
+	// Return all statistics unless some are selected.
+	if c.Stats == nil || len(*c.Stats) == 0 {
+		return stats, nil
+	}
+	serialized, err := json.Marshal(stats)
+	if err != nil {
+		context := "Failed to marshal block stats"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	var allStats map[string]json.RawMessage
+	if err := json.Unmarshal(serialized, &allStats); err != nil {
+		context := "Failed to unmarshal block stats"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	selected := make(map[string]json.RawMessage, len(*c.Stats))
+	for _, name := range *c.Stats {
+		stat, ok := allStats[name]
+		if !ok {
+			return nil, &bronjson.RPCError{
+				Code: bronjson.ErrRPCInvalidParameter,
+				Message: fmt.Sprintf("Invalid selected statistic "+
+					"%s", name),
+			}
+		}
+		selected[name] = stat
+	}
+	return selected, nil
+}
+
 // encodeTemplateID encodes the passed details into an ID that can be used to
 // uniquely identify a block template.
 func encodeTemplateID(prevHash *chainhash.Hash, lastGenerated time.Time) string {
@@ -2234,6 +2585,22 @@ func handleGetCFilterHeader(s *rpcServer, cmd interface{}, closeChan <-chan stru
 	return hash.String(), nil
 }
 
+// handleGetChainTips implements the getchaintips command.
+func handleGetChainTips(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	tips := s.cfg.Chain.ChainTips()
+	results := make([]bronjson.GetChainTipsResult, 0, len(tips))
+	for _, tip := range tips {
+		results = append(results, bronjson.GetChainTipsResult{
+			Height:    tip.Height,
+			Hash:      tip.Hash.String(),
+			BranchLen: tip.BranchLen,
+			Status:    tip.Status.String(),
+		})
+	}
+
+	return results, nil
+}
+
 // handleGetConnectionCount implements the getconnectioncount command.
 func handleGetConnectionCount(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	return s.cfg.ConnMgr.ConnectedCount(), nil
@@ -2320,6 +2687,76 @@ func handleGetInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (in
 	return ret, nil
 }
 
+// rpcNotInMempoolError is a convenience function for returning a nicely
+// formatted RPC error which indicates the provided transaction is not in the
+// memory pool.
+func rpcNotInMempoolError(txHash *chainhash.Hash) *bronjson.RPCError {
+	return bronjson.NewRPCError(bronjson.ErrRPCInvalidAddressOrKey,
+		fmt.Sprintf("Transaction %v not in mempool", txHash))
+}
+
+// mempoolEntriesReply returns the reply to the getmempoolancestors and
+// getmempooldescendants commands for the passed entries.  It is either the
+// entries keyed by transaction hash or, when the verbose flag is not set, the
+// sorted transaction hashes.
+func mempoolEntriesReply(entries map[string]*bronjson.GetMempoolEntryResult, verbose *bool) interface{} {
+	if verbose != nil && *verbose {
+		return entries
+	}
+
+	hashStrings := make([]string, 0, len(entries))
+	for hash := range entries {
+		hashStrings = append(hashStrings, hash)
+	}
+	sort.Strings(hashStrings)
+	return hashStrings
+}
+
+// handleGetMempoolAncestors implements the getmempoolancestors command.
+func handleGetMempoolAncestors(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*bronjson.GetMempoolAncestorsCmd)
+	txHash, err := chainhash.NewHashFromStr(c.TxID)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.TxID)
+	}
+
+	ancestors, err := s.cfg.TxMemPool.MempoolAncestors(txHash)
+	if err != nil {
+		return nil, rpcNotInMempoolError(txHash)
+	}
+	return mempoolEntriesReply(ancestors, c.Verbose), nil
+}
+
+// handleGetMempoolDescendants implements the getmempooldescendants command.
+func handleGetMempoolDescendants(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*bronjson.GetMempoolDescendantsCmd)
+	txHash, err := chainhash.NewHashFromStr(c.TxID)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.TxID)
+	}
+
+	descendants, err := s.cfg.TxMemPool.MempoolDescendants(txHash)
+	if err != nil {
+		return nil, rpcNotInMempoolError(txHash)
+	}
+	return mempoolEntriesReply(descendants, c.Verbose), nil
+}
+
+// handleGetMempoolEntry implements the getmempoolentry command.
+func handleGetMempoolEntry(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*bronjson.GetMempoolEntryCmd)
+	txHash, err := chainhash.NewHashFromStr(c.TxID)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.TxID)
+	}
+
+	entry, err := s.cfg.TxMemPool.MempoolEntry(txHash)
+	if err != nil {
+		return nil, rpcNotInMempoolError(txHash)
+	}
+	return entry, nil
+}
+
 // handleGetMempoolInfo implements the getmempoolinfo command.
 func handleGetMempoolInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	mempoolTxns := s.cfg.TxMemPool.TxDescs()
@@ -2330,8 +2767,10 @@ func handleGetMempoolInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct
 	}
 
 	ret := &bronjson.GetMempoolInfoResult{
-		Size:  int64(len(mempoolTxns)),
-		Bytes: numBytes,
+		Size:          int64(len(mempoolTxns)),
+		Bytes:         numBytes,
+		MaxMempool:    int64(cfg.MaxMempool) * 1024 * 1024,
+		MempoolMinFee: s.cfg.TxMemPool.MinFee().ToBRON(),
 	}
 
 	return ret, nil
@@ -2478,6 +2917,99 @@ func handleGetNetworkHashPS(s *rpcServer, cmd interface{}, closeChan <-chan stru
 	return hashesPerSec.Int64(), nil
 }
 
+// networkName returns the name of the network the passed address belongs to as
+// reported by the getnetworkinfo and getpeerinfo commands.
+func networkName(na *wire.NetAddressV2) string {
+	switch {
+	case na == nil:
+		return ""
+	case na.NetworkID == wire.NetTorV3 || addrmgr.IsOnionCatTor(na):
+		return "onion"
+	case !addrmgr.IsRoutable(na):
+		return "not_publicly_routable"
+	case addrmgr.IsIPv4(na):
+		return "ipv4"
+	default:
+		return "ipv6"
+	}
+}
+
+// handleGetNetworkInfo implements the getnetworkinfo command.
+func handleGetNetworkInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// Build the user agent the same way it is advertised to peers.
+	msgVersion := wire.MsgVersion{UserAgent: wire.DefaultUserAgent}
+	err := msgVersion.AddUserAgent(userAgentName, userAgentVersion,
+		cfg.UserAgentComments...)
+	if err != nil {
+		return nil, internalRPCError(err.Error(),
+			"Could not build user agent")
+	}
+
+	// IPv4 and IPv6 peers are always reachable, optionally through the
+	// configured proxy.  Onion peers are only reachable through either the
+	// onion specific proxy or the general one.
+	onionProxy := cfg.OnionProxy
+	if onionProxy == "" {
+		onionProxy = cfg.Proxy
+	}
+	onionReachable := !cfg.NoOnion && onionProxy != ""
+	if !onionReachable {
+		onionProxy = ""
+	}
+	networks := []bronjson.NetworksResult{
+		{
+			Name:                      "ipv4",
+			Reachable:                 true,
+			Proxy:                     cfg.Proxy,
+			ProxyRandomizeCredentials: cfg.Proxy != "" && cfg.TorIsolation,
+		},
+		{
+			Name:                      "ipv6",
+			Reachable:                 true,
+			Proxy:                     cfg.Proxy,
+			ProxyRandomizeCredentials: cfg.Proxy != "" && cfg.TorIsolation,
+		},
+		{
+			Name:                      "onion",
+			Limited:                   !onionReachable,
+			Reachable:                 onionReachable,
+			Proxy:                     onionProxy,
+			ProxyRandomizeCredentials: onionReachable && cfg.TorIsolation,
+		},
+	}
+
+	localAddrs := s.cfg.AddrMgr.LocalAddresses()
+	localAddresses := make([]bronjson.LocalAddressesResult, 0, len(localAddrs))
+	for _, la := range localAddrs {
+		host, _, err := net.SplitHostPort(addrmgr.NetAddressKey(la.NetAddress))
+		if err != nil {
+			continue
+		}
+		localAddresses = append(localAddresses, bronjson.LocalAddressesResult{
+			Address: host,
+			Port:    la.NetAddress.Port,
+			Score:   int32(la.Priority),
+		})
+	}
+
+	ret := &bronjson.GetNetworkInfoResult{
+		Version:         int32(1000000*appMajor + 10000*appMinor + 100*appPatch),
+		SubVersion:      msgVersion.UserAgent,
+		ProtocolVersion: int32(peer.MaxProtocolVersion),
+		LocalServices:   fmt.Sprintf("%016x", uint64(s.cfg.Services)),
+		LocalRelay:      !cfg.BlocksOnly,
+		TimeOffset:      int64(s.cfg.TimeSource.Offset().Seconds()),
+		Connections:     s.cfg.ConnMgr.ConnectedCount(),
+		NetworkActive:   true,
+		Networks:        networks,
+		RelayFee:        cfg.minRelayTxFee.ToBRON(),
+		IncrementalFee:  cfg.minRelayTxFee.ToBRON(),
+		LocalAddresses:  localAddresses,
+		Warnings:        "",
+	}
+	return ret, nil
+}
+
 // handleGetPeerInfo implements the getpeerinfo command.
 func handleGetPeerInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	peers := s.cfg.ConnMgr.ConnectedPeers()
@@ -2485,27 +3017,37 @@ func handleGetPeerInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 	infos := make([]*bronjson.GetPeerInfoResult, 0, len(peers))
 	for _, p := range peers {
 		statsSnap := p.ToPeer().StatsSnapshot()
+		persistentScore, transientScore := p.BanScoreBreakdown()
 		info := &bronjson.GetPeerInfoResult{
-			ID:             statsSnap.ID,
-			Addr:           statsSnap.Addr,
-			AddrLocal:      p.ToPeer().LocalAddr().String(),
-			Services:       fmt.Sprintf("%08d", uint64(statsSnap.Services)),
-			RelayTxes:      !p.IsTxRelayDisabled(),
-			LastSend:       statsSnap.LastSend.Unix(),
-			LastRecv:       statsSnap.LastRecv.Unix(),
-			BytesSent:      statsSnap.BytesSent,
-			BytesRecv:      statsSnap.BytesRecv,
-			ConnTime:       statsSnap.ConnTime.Unix(),
-			PingTime:       float64(statsSnap.LastPingMicros),
-			TimeOffset:     statsSnap.TimeOffset,
-			Version:        statsSnap.Version,
-			SubVer:         statsSnap.UserAgent,
-			Inbound:        statsSnap.Inbound,
-			StartingHeight: statsSnap.StartingHeight,
-			CurrentHeight:  statsSnap.LastBlock,
-			BanScore:       int32(p.BanScore()),
-			FeeFilter:      p.FeeFilter(),
-			SyncNode:       statsSnap.ID == syncPeerID,
+			ID:                 statsSnap.ID,
+			Addr:               statsSnap.Addr,
+			AddrLocal:          p.ToPeer().LocalAddr().String(),
+			Network:            networkName(p.ToPeer().NA()),
+			Services:           fmt.Sprintf("%08d", uint64(statsSnap.Services)),
+			RelayTxes:          !p.IsTxRelayDisabled(),
+			LastSend:           statsSnap.LastSend.Unix(),
+			LastRecv:           statsSnap.LastRecv.Unix(),
+			BytesSent:          statsSnap.BytesSent,
+			BytesRecv:          statsSnap.BytesRecv,
+			ConnTime:           statsSnap.ConnTime.Unix(),
+			PingTime:           float64(statsSnap.LastPingMicros),
+			TimeOffset:         statsSnap.TimeOffset,
+			Version:            statsSnap.Version,
+			SubVer:             statsSnap.UserAgent,
+			Inbound:            statsSnap.Inbound,
+			ConnectionType:     p.ConnectionType(),
+			StartingHeight:     statsSnap.StartingHeight,
+			CurrentHeight:      statsSnap.LastBlock,
+			BanScore:           int32(persistentScore + transientScore),
+			BanScorePersistent: int32(persistentScore),
+			BanScoreTransient:  int32(transientScore),
+			FeeFilter:          p.FeeFilter(),
+			SyncNode:           statsSnap.ID == syncPeerID,
+			BytesSentPerMsg:    statsSnap.BytesSentPerMsg,
+			BytesRecvPerMsg:    statsSnap.BytesRecvPerMsg,
+		}
+		if na := p.ToPeer().NA(); na != nil {
+			info.AddrGroup = addrmgr.GroupKey(na)
 		}
 		if p.ToPeer().LastPingNonce() != 0 {
 			wait := float64(time.Since(statsSnap.LastPingTime).Nanoseconds())
@@ -2762,6 +3304,26 @@ func handleGetTxOut(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 	return txOutReply, nil
 }
 
+// handleGetTxOutSetInfo implements the gettxoutsetinfo command.
+func handleGetTxOutSetInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	stats, err := s.cfg.Chain.FetchUtxoStats(closeChan)
+	if err != nil {
+		context := "Failed to fetch utxo set stats"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	return &bronjson.GetTxOutSetInfoResult{
+		Height:         int64(stats.Height),
+		BestBlock:      stats.Hash.String(),
+		Transactions:   stats.Transactions,
+		TxOuts:         stats.TxOuts,
+		BogoSize:       stats.BogoSize,
+		HashSerialized: stats.SerializedHash.String(),
+		DiskSize:       stats.DiskSize,
+		TotalAmount:    bronutil.Amount(stats.TotalAmount).ToBRON(),
+	}, nil
+}
+
 // handleHelp implements the help command.
 func handleHelp(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*bronjson.HelpCmd)
@@ -2801,6 +3363,57 @@ func handleHelp(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (inter
 	return help, nil
 }
 
+// handleInvalidateBlock implements the invalidateblock command.
+func handleInvalidateBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*bronjson.InvalidateBlockCmd)
+	hash, err := lookupBlockHash(s, c.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.cfg.Chain.InvalidateBlock(hash)
+	if err != nil {
+		return nil, internalRPCError(err.Error(),
+			"Unable to invalidate block")
+	}
+
+	return nil, nil
+}
+
+// handleListBanned implements the listbanned command.
+func handleListBanned(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	now := time.Now().Unix()
+	bans := s.cfg.ConnMgr.BannedSubnets()
+	results := make([]bronjson.ListBannedResult, 0, len(bans))
+	for _, ban := range bans {
+		results = append(results, bronjson.ListBannedResult{
+			Address:       ban.Subnet.String(),
+			BanCreated:    ban.Created.Unix(),
+			BannedUntil:   ban.Until.Unix(),
+			BanDuration:   ban.Until.Unix() - ban.Created.Unix(),
+			TimeRemaining: ban.Until.Unix() - now,
+		})
+	}
+
+	return results, nil
+}
+
+// handleLoadMempool implements the loadmempool command.
+func handleLoadMempool(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	stats, err := s.cfg.LoadMempool(closeChan)
+	if err != nil {
+		return nil, internalRPCError(err.Error(),
+			"Unable to load the memory pool")
+	}
+
+	return &bronjson.LoadMempoolResult{
+		Loaded:      int64(stats.Loaded),
+		Failed:      int64(stats.Failed),
+		Expired:     int64(stats.Expired),
+		AlreadyHave: int64(stats.AlreadyHave),
+	}, nil
+}
+
 // handlePing implements the ping command.
 func handlePing(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	// Ask server to ping \o_
@@ -3035,6 +3648,68 @@ func fetchMempoolTxnsForAddress(s *rpcServer, addr bronutil.Address, numToSkip,
 	return mpTxns[numToSkip:rangeEnd], numToSkip
 }
 
+// handlePreciousBlock implements the preciousblock command.
+func handlePreciousBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*bronjson.PreciousBlockCmd)
+	hash, err := lookupBlockHash(s, c.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.cfg.Chain.PreciousBlock(hash)
+	if err != nil {
+		return nil, internalRPCError(err.Error(),
+			"Unable to mark block precious")
+	}
+
+	return nil, nil
+}
+
+// handleReconsiderBlock implements the reconsiderblock command.
+func handleReconsiderBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*bronjson.ReconsiderBlockCmd)
+	hash, err := lookupBlockHash(s, c.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.cfg.Chain.ReconsiderBlock(hash)
+	if err != nil {
+		return nil, internalRPCError(err.Error(),
+			"Unable to reconsider block")
+	}
+
+	return nil, nil
+}
+
+// lookupBlockHash decodes the passed hex-encoded block hash and ensures the
+// block is known to the block index.  An appropriate RPC error is returned
+// when that is not the case.
+func lookupBlockHash(s *rpcServer, blockHash string) (*chainhash.Hash, error) {
+	hash, err := chainhash.NewHashFromStr(blockHash)
+	if err != nil {
+		return nil, rpcDecodeHexError(blockHash)
+	}
+	if _, err := s.cfg.Chain.HeaderByHash(hash); err != nil {
+		return nil, &bronjson.RPCError{
+			Code:    bronjson.ErrRPCBlockNotFound,
+			Message: "Block not found",
+		}
+	}
+
+	return hash, nil
+}
+
+// handleSaveMempool implements the savemempool command.
+func handleSaveMempool(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if err := s.cfg.SaveMempool(); err != nil {
+		return nil, internalRPCError(err.Error(),
+			"Unable to save the memory pool")
+	}
+
+	return nil, nil
+}
+
 // handleSearchRawTransactions implements the searchrawtransactions command.
 func handleSearchRawTransactions(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	// Respond with an error if the address index is not enabled.
@@ -3376,7 +4051,8 @@ func handleSendRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan st
 	// Also, since an error is being returned to the caller, ensure the
 	// transaction is removed from the memory pool.
 	if len(acceptedTxs) == 0 || !acceptedTxs[0].Tx.Hash().IsEqual(tx.Hash()) {
-		s.cfg.TxMemPool.RemoveTransaction(tx, true)
+		s.cfg.TxMemPool.RemoveTransaction(tx, true,
+			mempool.RemovedEvicted)
 
 		errStr := fmt.Sprintf("transaction %v is not in accepted list",
 			tx.Hash())
@@ -3401,6 +4077,67 @@ func handleSendRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan st
 	return tx.Hash().String(), nil
 }
 
+// handleSetBan implements the setban command.
+func handleSetBan(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*bronjson.SetBanCmd)
+
+	subnet, err := connmgr.ParseSubnet(c.SubNet)
+	if err != nil {
+		return nil, &bronjson.RPCError{
+			Code:    bronjson.ErrRPCClientInvalidIPOrSubnet,
+			Message: "Invalid IP address or subnet: " + err.Error(),
+		}
+	}
+
+	switch c.SubCmd {
+	case bronjson.SBAdd:
+		// The ban time is either a duration in seconds or, when the
+		// absolute flag is set, a unix timestamp.  The configured ban
+		// duration is used when it is not specified.
+		var until time.Time
+		switch {
+		case c.BanTime == nil || *c.BanTime <= 0:
+			until = time.Now().Add(cfg.BanDuration)
+		case c.Absolute != nil && *c.Absolute:
+			until = time.Unix(*c.BanTime, 0)
+		default:
+			until = time.Now().Add(time.Duration(*c.BanTime) * time.Second)
+		}
+		if !until.After(time.Now()) {
+			return nil, &bronjson.RPCError{
+				Code:    bronjson.ErrRPCInvalidParameter,
+				Message: "Ban time is in the past",
+			}
+		}
+
+		if err := s.cfg.ConnMgr.Ban(subnet, until); err != nil {
+			return nil, internalRPCError(err.Error(),
+				"Unable to save the ban list")
+		}
+
+	case bronjson.SBRemove:
+		err := s.cfg.ConnMgr.Unban(subnet)
+		if err == connmgr.ErrNotBanned {
+			return nil, &bronjson.RPCError{
+				Code:    bronjson.ErrRPCClientInvalidIPOrSubnet,
+				Message: "IP address or subnet was not banned",
+			}
+		}
+		if err != nil {
+			return nil, internalRPCError(err.Error(),
+				"Unable to save the ban list")
+		}
+
+	default:
+		return nil, &bronjson.RPCError{
+			Code:    bronjson.ErrRPCInvalidParameter,
+			Message: "invalid subcommand for setban",
+		}
+	}
+
+	return nil, nil
+}
+
 // handleSetGenerate implements the setgenerate command.
 func handleSetGenerate(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*bronjson.SetGenerateCmd)
@@ -3479,6 +4216,91 @@ func handleSubmitBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 	return nil, nil
 }
 
+// handleTestMempoolAccept implements the testmempoolaccept command.
+func handleTestMempoolAccept(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*bronjson.TestMempoolAcceptCmd)
+
+	if len(c.RawTxns) == 0 || len(c.RawTxns) > maxTestMempoolAcceptTxns {
+		return nil, &bronjson.RPCError{
+			Code: bronjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Array must contain between 1 and "+
+				"%d transactions", maxTestMempoolAcceptTxns),
+		}
+	}
+
+	var maxFeeRate bronutil.Amount
+	if c.MaxFeeRate != nil {
+		var err error
+		maxFeeRate, err = bronutil.NewAmount(*c.MaxFeeRate)
+		if err != nil || maxFeeRate < 0 {
+			return nil, &bronjson.RPCError{
+				Code:    bronjson.ErrRPCInvalidParameter,
+				Message: "Invalid maximum fee rate",
+			}
+		}
+	}
+
+	txns := make([]*bronutil.Tx, 0, len(c.RawTxns))
+	for _, hexStr := range c.RawTxns {
+		if len(hexStr)%2 != 0 {
+			hexStr = "0" + hexStr
+		}
+		serializedTx, err := hex.DecodeString(hexStr)
+		if err != nil {
+			return nil, rpcDecodeHexError(hexStr)
+		}
+		var msgTx wire.MsgTx
+		err = msgTx.Deserialize(bytes.NewReader(serializedTx))
+		if err != nil {
+			return nil, &bronjson.RPCError{
+				Code:    bronjson.ErrRPCDeserialization,
+				Message: "TX decode failed: " + err.Error(),
+			}
+		}
+		txns = append(txns, bronutil.NewTx(&msgTx))
+	}
+
+	results := s.cfg.TxMemPool.TestMempoolAccept(txns)
+	reply := make([]bronjson.TestMempoolAcceptResult, 0, len(results))
+	for _, result := range results {
+		tx := result.Tx
+		txResult := bronjson.TestMempoolAcceptResult{
+			Txid:  tx.Hash().String(),
+			Wtxid: tx.WitnessHash().String(),
+		}
+		if result.Err != nil {
+			if _, ok := result.Err.(mempool.RuleError); !ok {
+				context := "Failed to test transaction"
+				return nil, internalRPCError(result.Err.Error(),
+					context)
+			}
+			txResult.RejectReason = result.Err.Error()
+			reply = append(reply, txResult)
+			continue
+		}
+
+		// Reject transactions paying a fee rate above the maximum, which
+		// most likely means the fee was set by mistake.
+		feeRate := bronutil.Amount(result.Fee * 1000 / result.Vsize)
+		if maxFeeRate > 0 && feeRate > maxFeeRate {
+			txResult.RejectReason = fmt.Sprintf("fee rate of %v per "+
+				"kvB exceeds the maximum of %v", feeRate,
+				maxFeeRate)
+			reply = append(reply, txResult)
+			continue
+		}
+
+		txResult.Allowed = true
+		txResult.Vsize = int32(result.Vsize)
+		txResult.Fees = &bronjson.TestMempoolAcceptFees{
+			Base: bronutil.Amount(result.Fee).ToBRON(),
+		}
+		reply = append(reply, txResult)
+	}
+
+	return reply, nil
+}
+
 // handleUptime implements the uptime command.
 func handleUptime(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	return time.Now().Unix() - s.cfg.StartupTime, nil
@@ -3635,8 +4457,8 @@ type rpcServer struct {
 	started                int32
 	shutdown               int32
 	cfg                    rpcserverConfig
-	authsha                [sha256.Size]byte
-	limitauthsha           [sha256.Size]byte
+	users                  []*rpcUser
+	cookieFile             string
 	ntfnMgr                *wsNotificationManager
 	numClients             int32
 	statusLines            map[int]string
@@ -3721,6 +4543,12 @@ func (s *rpcServer) Stop() error {
 	s.ntfnMgr.WaitForShutdown()
 	close(s.quit)
 	s.wg.Wait()
+	if s.cookieFile != "" {
+		if err := os.Remove(s.cookieFile); err != nil {
+			rpcsLog.Errorf("Unable to remove RPC authentication "+
+				"cookie: %v", err)
+		}
+	}
 	rpcsLog.Infof("RPC server shutdown complete")
 	return nil
 }
@@ -3780,47 +4608,48 @@ func (s *rpcServer) decrementClients() {
 	atomic.AddInt32(&s.numClients, -1)
 }
 
+// authenticate returns the user with the passed credentials, or nil when they
+// do not match any of the users of the RPC server.
+//
+// The credentials are checked against all users, each in constant time.
+func (s *rpcServer) authenticate(name, password string) *rpcUser {
+	var match *rpcUser
+	for _, user := range s.users {
+		if user.checkPassword(name, password) && match == nil {
+			match = user
+		}
+	}
+	return match
+}
+
 // checkAuth checks the HTTP Basic authentication supplied by a wallet
 // or RPC client in the HTTP request r.  If the supplied authentication
-// does not match the username and password expected, a non-nil error is
-// returned.
-//
-// This check is time-constant.
+// does not match the credentials of any user, a non-nil error is returned.
 //
-// The first bool return value signifies auth success (true if successful) and
-// the second bool return value specifies whether the user can change the state
-// of the server (true) or whether the user is limited (false). The second is
-// always false if the first is.
-func (s *rpcServer) checkAuth(r *http.Request, require bool) (bool, bool, error) {
-	authhdr := r.Header["Authorization"]
-	if len(authhdr) <= 0 {
+// The returned user determines the RPC methods and notifications available to
+// the client.  It is nil without an error when no authentication is supplied
+// and it is not required.
+func (s *rpcServer) checkAuth(r *http.Request, require bool) (*rpcUser, error) {
+	if len(r.Header["Authorization"]) <= 0 {
 		if require {
 			rpcsLog.Warnf("RPC authentication failure from %s",
 				r.RemoteAddr)
-			return false, false, errors.New("auth failure")
+			return nil, errors.New("auth failure")
 		}
 
-		return false, false, nil
-	}
-
-	authsha := sha256.Sum256([]byte(authhdr[0]))
-
-	// Check for limited auth first as in environments with limited users, those
-	// are probably expected to have a higher volume of calls
-	limitcmp := subtle.ConstantTimeCompare(authsha[:], s.limitauthsha[:])
-	if limitcmp == 1 {
-		return true, false, nil
+		return nil, nil
 	}
 
-	// Check for admin-level auth
-	cmp := subtle.ConstantTimeCompare(authsha[:], s.authsha[:])
-	if cmp == 1 {
-		return true, true, nil
+	name, password, ok := r.BasicAuth()
+	if ok {
+		if user := s.authenticate(name, password); user != nil {
+			return user, nil
+		}
 	}
 
-	// Request's auth doesn't match either user
+	// Request's auth doesn't match any user
 	rpcsLog.Warnf("RPC authentication failure from %s", r.RemoteAddr)
-	return false, false, errors.New("auth failure")
+	return nil, errors.New("auth failure")
 }
 
 // parsedRPCCmd represents a JSON-RPC request object that has been parsed into
@@ -3905,8 +4734,110 @@ func createMarshalledReply(id, result interface{}, replyErr error) ([]byte, erro
 	return bronjson.MarshalResponse(id, result, jsonErr)
 }
 
+// marshalReply marshals a JSON-RPC reply with the passed id, result and error.
+// Nil is returned when marshalling fails, in which case the error is logged.
+func marshalReply(id, result interface{}, replyErr error) []byte {
+	msg, err := createMarshalledReply(id, result, replyErr)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal reply: %v", err)
+		return nil
+	}
+	return msg
+}
+
+// processRequest runs the command of a single JSON-RPC request and returns the
+// marshalled reply.  Nil is returned for notifications since they must not be
+// responded to.
+func (s *rpcServer) processRequest(request *bronjson.Request, user *rpcUser, closeChan <-chan struct{}) []byte {
+	// The JSON-RPC 1.0 spec defines that notifications must have their "id"
+	// set to null and states that notifications do not have a response.
+	//
+	// A JSON-RPC 2.0 notification is a request with "json-rpc":"2.0", and
+	// without an "id" member. The specification states that notifications
+	// must not be responded to. JSON-RPC 2.0 permits the null value as a
+	// valid request id, therefore such requests are not notifications.
+	//
+	// Brocoin Core serves requests with "id":null or even an absent "id",
+	// and responds to such requests with "id":null in the response.
+	//
+	// Brond does not respond to any request without and "id" or "id":null,
+	// regardless the indicated JSON-RPC protocol version unless RPC quirks
+	// are enabled. With RPC quirks enabled, such requests will be responded
+	// to if the reqeust does not indicate JSON-RPC version.
+	//
+	// RPC quirks can be enabled by the user to avoid compatibility issues
+	// with software relying on Core's behavior.
+	if request.ID == nil && !(cfg.RPCQuirks && request.Jsonrpc == "") {
+		return nil
+	}
+
+	// Set error if the user is not authorized to call the method.
+	if !user.methodAllowed(request.Method) {
+		return marshalReply(request.ID, nil, &bronjson.RPCError{
+			Code:    bronjson.ErrRPCInvalidParams.Code,
+			Message: "user not authorized for this method",
+		})
+	}
+
+	// Attempt to parse the JSON-RPC request into a known concrete command.
+	parsedCmd := parseCmd(request)
+	if parsedCmd.err != nil {
+		return marshalReply(request.ID, nil, parsedCmd.err)
+	}
+	result, err := s.standardCmdResult(parsedCmd, closeChan)
+	return marshalReply(request.ID, result, err)
+}
+
+// processBatch runs the commands of a JSON-RPC batch, which is an array of
+// request objects, and returns the marshalled array of their replies.  Each
+// request is handled as if it had been sent on its own, so the authorization
+// of the user is checked for each of them and an invalid request only
+// results in an error reply in its place.  Nil is returned when the batch only
+// consists of notifications.
+func (s *rpcServer) processBatch(body []byte, user *rpcUser, closeChan <-chan struct{}) []byte {
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return marshalReply(nil, nil, &bronjson.RPCError{
+			Code:    bronjson.ErrRPCParse.Code,
+			Message: "Failed to parse request: " + err.Error(),
+		})
+	}
+	if len(batch) == 0 {
+		return marshalReply(nil, nil, &bronjson.RPCError{
+			Code:    bronjson.ErrRPCInvalidRequest.Code,
+			Message: "Invalid request: empty batch",
+		})
+	}
+
+	replies := make([][]byte, 0, len(batch))
+	for _, rawRequest := range batch {
+		var reply []byte
+		var request bronjson.Request
+		if err := json.Unmarshal(rawRequest, &request); err != nil {
+			reply = marshalReply(nil, nil, &bronjson.RPCError{
+				Code:    bronjson.ErrRPCInvalidRequest.Code,
+				Message: "Invalid request: " + err.Error(),
+			})
+		} else {
+			reply = s.processRequest(&request, user, closeChan)
+		}
+		if reply != nil {
+			replies = append(replies, reply)
+		}
+	}
+	if len(replies) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	buf.Write(bytes.Join(replies, []byte{','}))
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
+
 // jsonRPCRead handles reading and responding to RPC messages.
-func (s *rpcServer) jsonRPCRead(w http.ResponseWriter, r *http.Request, isAdmin bool) {
+func (s *rpcServer) jsonRPCRead(w http.ResponseWriter, r *http.Request, user *rpcUser) {
 	if atomic.LoadInt32(&s.shutdown) != 0 {
 		return
 	}
@@ -3946,80 +4877,36 @@ func (s *rpcServer) jsonRPCRead(w http.ResponseWriter, r *http.Request, isAdmin
 	defer buf.Flush()
 	conn.SetReadDeadline(timeZeroVal)
 
-	// Attempt to parse the raw body into a JSON-RPC request.
-	var responseID interface{}
-	var jsonErr error
-	var result interface{}
-	var request bronjson.Request
-	if err := json.Unmarshal(body, &request); err != nil {
-		jsonErr = &bronjson.RPCError{
-			Code:    bronjson.ErrRPCParse.Code,
-			Message: "Failed to parse request: " + err.Error(),
-		}
-	}
-	if jsonErr == nil {
-		// The JSON-RPC 1.0 spec defines that notifications must have their "id"
-		// set to null and states that notifications do not have a response.
-		//
-		// A JSON-RPC 2.0 notification is a request with "json-rpc":"2.0", and
-		// without an "id" member. The specification states that notifications
-		// must not be responded to. JSON-RPC 2.0 permits the null value as a
-		// valid request id, therefore such requests are not notifications.
-		//
-		// Brocoin Core serves requests with "id":null or even an absent "id",
-		// and responds to such requests with "id":null in the response.
-		//
-		// Brond does not respond to any request without and "id" or "id":null,
-		// regardless the indicated JSON-RPC protocol version unless RPC quirks
-		// are enabled. With RPC quirks enabled, such requests will be responded
-		// to if the reqeust does not indicate JSON-RPC version.
-		//
-		// RPC quirks can be enabled by the user to avoid compatibility issues
-		// with software relying on Core's behavior.
-		if request.ID == nil && !(cfg.RPCQuirks && request.Jsonrpc == "") {
-			return
+	// Setup a close notifier.  Since the connection is hijacked,
+	// the CloseNotifer on the ResponseWriter is not available.
+	closeChan := make(chan struct{}, 1)
+	go func() {
+		_, err := conn.Read(make([]byte, 1))
+		if err != nil {
+			close(closeChan)
 		}
+	}()
 
-		// The parse was at least successful enough to have an ID so
-		// set it for the response.
-		responseID = request.ID
+	// A JSON array is a batch of requests which are each processed in turn
+	// and answered with an array of the individual responses.  Anything
+	// else is treated as a single request.
+	var msg []byte
+	if trimmed := bytes.TrimLeft(body, " \t\r\n"); len(trimmed) > 0 &&
+		trimmed[0] == '[' {
 
-		// Setup a close notifier.  Since the connection is hijacked,
-		// the CloseNotifer on the ResponseWriter is not available.
-		closeChan := make(chan struct{}, 1)
-		go func() {
-			_, err := conn.Read(make([]byte, 1))
-			if err != nil {
-				close(closeChan)
-			}
-		}()
-
-		// Check if the user is limited and set error if method unauthorized
-		if !isAdmin {
-			if _, ok := rpcLimited[request.Method]; !ok {
-				jsonErr = &bronjson.RPCError{
-					Code:    bronjson.ErrRPCInvalidParams.Code,
-					Message: "limited user not authorized for this method",
-				}
-			}
-		}
-
-		if jsonErr == nil {
-			// Attempt to parse the JSON-RPC request into a known concrete
-			// command.
-			parsedCmd := parseCmd(&request)
-			if parsedCmd.err != nil {
-				jsonErr = parsedCmd.err
-			} else {
-				result, jsonErr = s.standardCmdResult(parsedCmd, closeChan)
-			}
+		msg = s.processBatch(body, user, closeChan)
+	} else {
+		var request bronjson.Request
+		if err := json.Unmarshal(body, &request); err != nil {
+			msg = marshalReply(nil, nil, &bronjson.RPCError{
+				Code:    bronjson.ErrRPCParse.Code,
+				Message: "Failed to parse request: " + err.Error(),
+			})
+		} else {
+			msg = s.processRequest(&request, user, closeChan)
 		}
 	}
-
-	// Marshal the response.
-	msg, err := createMarshalledReply(responseID, result, jsonErr)
-	if err != nil {
-		rpcsLog.Errorf("Failed to marshal reply: %v", err)
+	if msg == nil {
 		return
 	}
 
@@ -4073,19 +4960,24 @@ func (s *rpcServer) Start() {
 		// Keep track of the number of connected clients.
 		s.incrementClients()
 		defer s.decrementClients()
-		_, isAdmin, err := s.checkAuth(r, true)
+		user, err := s.checkAuth(r, true)
 		if err != nil {
 			jsonAuthFail(w)
 			return
 		}
 
 		// Read and respond to the request.
-		s.jsonRPCRead(w, r, isAdmin)
+		s.jsonRPCRead(w, r, user)
 	})
 
+	// Unauthenticated REST endpoints.
+	if cfg.REST {
+		rpcServeMux.HandleFunc("/rest/", s.handleREST)
+	}
+
 	// Websocket endpoint.
 	rpcServeMux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		authenticated, isAdmin, err := s.checkAuth(r, false)
+		user, err := s.checkAuth(r, false)
 		if err != nil {
 			jsonAuthFail(w)
 			return
@@ -4102,7 +4994,7 @@ func (s *rpcServer) Start() {
 			http.Error(w, "400 Bad Request.", http.StatusBadRequest)
 			return
 		}
-		s.WebsocketHandler(ws, r.RemoteAddr, authenticated, isAdmin)
+		s.WebsocketHandler(ws, r.RemoteAddr, user)
 	})
 
 	for _, listener := range s.cfg.Listeners {
@@ -4158,9 +5050,17 @@ type rpcserverPeer interface {
 	// the peer is to being banned.
 	BanScore() uint32
 
+	// BanScoreBreakdown returns the persistent and the decaying parts of
+	// the ban score.
+	BanScoreBreakdown() (uint32, uint32)
+
 	// FeeFilter returns the requested current minimum fee rate for which
 	// transactions should be announced.
 	FeeFilter() int64
+
+	// ConnectionType returns how the connection to the peer was
+	// established.
+	ConnectionType() string
 }
 
 // rpcserverConnManager represents a connection manager for use with the RPC
@@ -4222,6 +5122,20 @@ type rpcserverConnManager interface {
 	// RelayTransactions generates and relays inventory vectors for all of
 	// the passed transactions to all connected peers.
 	RelayTransactions(txns []*mempool.TxDesc)
+
+	// Ban bans the provided subnet until the provided time and
+	// disconnects all connected peers within it.
+	Ban(subnet *net.IPNet, until time.Time) error
+
+	// Unban removes the ban of the provided subnet.  Attempting to unban
+	// a subnet which is not banned will return connmgr.ErrNotBanned.
+	Unban(subnet *net.IPNet) error
+
+	// BannedSubnets returns all currently banned subnets.
+	BannedSubnets() []connmgr.BanEntry
+
+	// ClearBanned removes all bans.
+	ClearBanned() error
 }
 
 // rpcserverSyncManager represents a sync manager for use with the RPC server.
@@ -4282,6 +5196,12 @@ type rpcserverConfig struct {
 	// TxMemPool defines the transaction memory pool to interact with.
 	TxMemPool *mempool.TxPool
 
+	// SaveMempool writes the transactions in the memory pool to the
+	// mempool file in the data directory and LoadMempool reloads them from
+	// it.
+	SaveMempool func() error
+	LoadMempool func(interrupt <-chan struct{}) (*mempool.LoadStats, error)
+
 	// These fields allow the RPC server to interface with mining.
 	//
 	// Generator produces block templates and the CPUMiner solves them using
@@ -4299,6 +5219,11 @@ type rpcserverConfig struct {
 	// The fee estimator keeps track of how long transactions are left in
 	// the mempool before they are mined into blocks.
 	FeeEstimator *mempool.FeeEstimator
+
+	// AddrMgr provides the local addresses the server advertises and
+	// Services the services it advertises to peers.
+	AddrMgr  *addrmgr.AddrManager
+	Services wire.ServiceFlag
 }
 
 // newRPCServer returns a new instance of the rpcServer struct.
@@ -4312,14 +5237,32 @@ func newRPCServer(config *rpcserverConfig) (*rpcServer, error) {
 		quit:                   make(chan int),
 	}
 	if cfg.RPCUser != "" && cfg.RPCPass != "" {
-		login := cfg.RPCUser + ":" + cfg.RPCPass
-		auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
-		rpc.authsha = sha256.Sum256([]byte(auth))
+		rpc.users = append(rpc.users, newRPCUser(cfg.RPCUser,
+			cfg.RPCPass, nil))
 	}
 	if cfg.RPCLimitUser != "" && cfg.RPCLimitPass != "" {
-		login := cfg.RPCLimitUser + ":" + cfg.RPCLimitPass
-		auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
-		rpc.limitauthsha = sha256.Sum256([]byte(auth))
+		rpc.users = append(rpc.users, newRPCUser(cfg.RPCLimitUser,
+			cfg.RPCLimitPass, rpcLimited))
+	}
+	rpc.users = append(rpc.users, cfg.rpcAuthUsers...)
+
+	// Generate credentials for cookie authentication when no password is
+	// configured for the admin user.
+	if cfg.RPCPass == "" {
+		user, err := genRPCCookie(cfg.RPCCookieFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to write RPC "+
+				"authentication cookie: %v", err)
+		}
+		rpc.users = append(rpc.users, user)
+		rpc.cookieFile = cfg.RPCCookieFile
+		rpcsLog.Infof("Generated RPC authentication cookie %s",
+			cfg.RPCCookieFile)
+	}
+
+	for _, user := range rpc.users {
+		user.restrict(cfg.rpcWhitelist[user.name],
+			cfg.rpcWhitelistNtfn[user.name])
 	}
 	rpc.ntfnMgr = newWsNotificationManager(&rpc)
 	rpc.cfg.Chain.Subscribe(rpc.handleBlockchainNotification)
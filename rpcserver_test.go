@@ -0,0 +1,113 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/brsuite/brond/wire"
+)
+
+// TestBlockStatsAggregates ensures the medians and fee rate percentiles
+// returned by getblockstats are calculated as expected.
+func TestBlockStatsAggregates(t *testing.T) {
+	t.Parallel()
+
+	medianTests := []struct {
+		values []int64
+		want   int64
+	}{
+		{values: nil, want: 0},
+		{values: []int64{7}, want: 7},
+		{values: []int64{9, 1, 5}, want: 5},
+		{values: []int64{8, 1, 4, 3}, want: 3},
+	}
+	for _, test := range medianTests {
+		if got := medianInt64(test.values); got != test.want {
+			t.Errorf("medianInt64(%v): got %d, want %d", test.values,
+				got, test.want)
+		}
+	}
+
+	percentileTests := []struct {
+		name        string
+		feeRates    []feeRateWeight
+		totalWeight int64
+		want        []int64
+	}{
+		{
+			name: "no transactions",
+			want: []int64{0, 0, 0, 0, 0},
+		},
+		{
+			name:        "single transaction",
+			feeRates:    []feeRateWeight{{feeRate: 12, weight: 400}},
+			totalWeight: 400,
+			want:        []int64{12, 12, 12, 12, 12},
+		},
+		{
+			name: "weighted",
+			feeRates: []feeRateWeight{
+				{feeRate: 50, weight: 100},
+				{feeRate: 1, weight: 100},
+				{feeRate: 10, weight: 600},
+				{feeRate: 20, weight: 200},
+			},
+			totalWeight: 1000,
+			want:        []int64{1, 10, 10, 20, 20},
+		},
+	}
+	for _, test := range percentileTests {
+		got := feeRatePercentiles(test.feeRates, test.totalWeight)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got percentiles %v, want %v", test.name,
+				got, test.want)
+		}
+	}
+}
+
+// TestNetworkName ensures addresses are reported with the expected network
+// name by getnetworkinfo and getpeerinfo.
+func TestNetworkName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		na   *wire.NetAddressV2
+		want string
+	}{
+		{
+			na:   wire.NewNetAddressV2IPPort(net.ParseIP("204.124.1.1"), 8333, 0),
+			want: "ipv4",
+		},
+		{
+			na:   wire.NewNetAddressV2IPPort(net.ParseIP("2620:100::1"), 8333, 0),
+			want: "ipv6",
+		},
+		{
+			na:   wire.NewNetAddressV2IPPort(net.ParseIP("fd87:d87e:eb43::1"), 8333, 0),
+			want: "onion",
+		},
+		{
+			na:   wire.NewNetAddressV2IPPort(net.ParseIP("192.168.0.1"), 8333, 0),
+			want: "not_publicly_routable",
+		},
+		{
+			na: &wire.NetAddressV2{
+				NetworkID: wire.NetTorV3,
+				Addr:      make([]byte, 32),
+				Port:      8333,
+			},
+			want: "onion",
+		},
+	}
+	for _, test := range tests {
+		if got := networkName(test.na); got != test.want {
+			t.Errorf("networkName(%v): got %q, want %q",
+				test.na.Addr, got, test.want)
+		}
+	}
+}
@@ -44,6 +44,9 @@ var helpDescsEnUS = map[string]string{
 	"transactioninput-txid": "The hash of the input transaction",
 	"transactioninput-vout": "The specific output of the input transaction to redeem",
 
+	// ClearBannedCmd help.
+	"clearbanned--synopsis": "Removes all banned IP addresses and subnets.",
+
 	// CreateRawTransactionCmd help.
 	"createrawtransaction--synopsis": "Returns a new transaction spending the provided inputs and sending to the provided addresses.\n" +
 		"The transaction inputs are not signed in the created transaction.\n" +
@@ -124,6 +127,27 @@ var helpDescsEnUS = map[string]string{
 	"estimatefee--result0": "Estimated fee per kilobyte in bronees for a block to " +
 		"be mined in the next NumBlocks blocks.",
 
+	// EstimateSmartFeeCmd help.
+	"estimatesmartfee--synopsis": "Estimate the fee rate per kilobyte in BRON " +
+		"required for a transaction to be mined within conf_target blocks " +
+		"from the fee rates of the transactions mined in recent blocks.",
+	"estimatesmartfee-conftarget": "The number of blocks the transaction " +
+		"should be mined within (1 to 1008)",
+	"estimatesmartfee-estimatemode": "The fee estimate mode, one of ECONOMICAL " +
+		"or CONSERVATIVE (default). Conservative estimates take a longer " +
+		"history into account and are less responsive to short-term drops " +
+		"in the fee market",
+
+	// EstimateSmartFeeResult help.
+	"estimatesmartfeeresult-feerate": "Estimated fee rate in BRON per kilobyte, " +
+		"never below the minimum relay fee (only present when an estimate " +
+		"is available)",
+	"estimatesmartfeeresult-errors": "Errors encountered while estimating " +
+		"(only present when no estimate is available)",
+	"estimatesmartfeeresult-blocks": "The number of blocks the estimate was " +
+		"made for, which differs from conf_target when a single block was " +
+		"requested or not enough blocks have been seen for it",
+
 	// GenerateCmd help
 	"generate--synopsis": "Generates a set number of blocks (simnet or regtest only) and returns a JSON\n" +
 		" array of their hashes.",
@@ -286,6 +310,43 @@ var helpDescsEnUS = map[string]string{
 	"getblockheaderverboseresult-previousblockhash": "The hash of the previous block",
 	"getblockheaderverboseresult-nextblockhash":     "The hash of the next block (only if there is one)",
 
+	// GetBlockStatsCmd help.
+	"getblockstats--synopsis": "Returns statistics about the fees, sizes and spent outputs of a block in the main chain.\n" +
+		"Amounts are in brones and fee rates in brones per virtual byte.",
+	"getblockstats-hashorheight": "The hash or height of the block",
+	"getblockstats-stats":        "The names of the statistics to return (default: all)",
+
+	// GetBlockStatsResult help.
+	"getblockstatsresult-avgfee":              "The average fee of the transactions, excluding the coinbase",
+	"getblockstatsresult-avgfeerate":          "The average fee rate of the transactions, excluding the coinbase",
+	"getblockstatsresult-avgtxsize":           "The average size of the transactions, excluding the coinbase",
+	"getblockstatsresult-blockhash":           "The hash of the block",
+	"getblockstatsresult-feerate_percentiles": "The 10th, 25th, 50th, 75th and 90th percentile fee rates weighted by transaction weight",
+	"getblockstatsresult-height":              "The height of the block",
+	"getblockstatsresult-ins":                 "The number of inputs, excluding the coinbase",
+	"getblockstatsresult-maxfee":              "The highest fee of the transactions",
+	"getblockstatsresult-maxfeerate":          "The highest fee rate of the transactions",
+	"getblockstatsresult-maxtxsize":           "The size of the largest transaction",
+	"getblockstatsresult-medianfee":           "The median fee of the transactions",
+	"getblockstatsresult-mediantime":          "The median time of the block and the blocks preceding it",
+	"getblockstatsresult-mediantxsize":        "The median size of the transactions",
+	"getblockstatsresult-minfee":              "The lowest fee of the transactions",
+	"getblockstatsresult-minfeerate":          "The lowest fee rate of the transactions",
+	"getblockstatsresult-mintxsize":           "The size of the smallest transaction",
+	"getblockstatsresult-outs":                "The number of outputs, including those of the coinbase",
+	"getblockstatsresult-subsidy":             "The block subsidy",
+	"getblockstatsresult-swtotal_size":        "The total size of the transactions with witness data",
+	"getblockstatsresult-swtotal_weight":      "The total weight of the transactions with witness data",
+	"getblockstatsresult-swtxs":               "The number of transactions with witness data",
+	"getblockstatsresult-time":                "The block time in seconds since 1 Jan 1970 GMT",
+	"getblockstatsresult-total_out":           "The total amount of the outputs, excluding the coinbase",
+	"getblockstatsresult-total_size":          "The total size of the transactions, excluding the coinbase",
+	"getblockstatsresult-total_weight":        "The total weight of the transactions, excluding the coinbase",
+	"getblockstatsresult-totalfee":            "The total fee of the transactions",
+	"getblockstatsresult-txs":                 "The number of transactions, including the coinbase",
+	"getblockstatsresult-utxo_increase":       "The number of outputs created minus the number spent",
+	"getblockstatsresult-utxo_size_inc":       "The estimated number of bytes the block adds to the unspent transaction output set",
+
 	// TemplateRequest help.
 	"templaterequest-mode":         "This is 'template', 'proposal', or omitted",
 	"templaterequest-capabilities": "List of capabilities",
@@ -356,6 +417,15 @@ var helpDescsEnUS = map[string]string{
 	"getcfilterheader-hash":       "The hash of the block",
 	"getcfilterheader--result0":   "The block's gcs filter header",
 
+	// GetChainTipsCmd help.
+	"getchaintips--synopsis": "Returns information about all known tips in the block tree, including the main chain as well as orphaned branches.",
+
+	// GetChainTipsResult help.
+	"getchaintipsresult-height":    "The height of the chain tip",
+	"getchaintipsresult-hash":      "The block hash of the chain tip",
+	"getchaintipsresult-branchlen": "The length of the branch connecting the tip to the main chain (zero for the main chain)",
+	"getchaintipsresult-status":    "The status of the chain ('active', 'valid-fork', 'valid-headers', 'headers-only' or 'invalid')",
+
 	// GetConnectionCountCmd help.
 	"getconnectioncount--synopsis": "Returns the number of active connections to other peers.",
 	"getconnectioncount--result0":  "The number of connections",
@@ -415,12 +485,54 @@ var helpDescsEnUS = map[string]string{
 	// GetInfoCmd help.
 	"getinfo--synopsis": "Returns a JSON object containing various state info.",
 
+	// GetMempoolAncestorsCmd help.
+	"getmempoolancestors--synopsis":   "Returns all unconfirmed ancestors in the memory pool of a transaction in the memory pool.",
+	"getmempoolancestors-txid":        "The hash of the transaction",
+	"getmempoolancestors-verbose":     "Returns JSON objects keyed by transaction hash when true or an array of transaction hashes when false",
+	"getmempoolancestors--condition0": "verbose=false",
+	"getmempoolancestors--condition1": "verbose=true",
+	"getmempoolancestors--result0":    "Array of transaction hashes",
+
+	// GetMempoolDescendantsCmd help.
+	"getmempooldescendants--synopsis":   "Returns all unconfirmed descendants in the memory pool of a transaction in the memory pool.",
+	"getmempooldescendants-txid":        "The hash of the transaction",
+	"getmempooldescendants-verbose":     "Returns JSON objects keyed by transaction hash when true or an array of transaction hashes when false",
+	"getmempooldescendants--condition0": "verbose=false",
+	"getmempooldescendants--condition1": "verbose=true",
+	"getmempooldescendants--result0":    "Array of transaction hashes",
+
+	// GetMempoolEntryCmd help.
+	"getmempoolentry--synopsis": "Returns information about a transaction in the memory pool.",
+	"getmempoolentry-txid":      "The hash of the transaction",
+
+	// GetMempoolEntryResult help.
+	"getmempoolentryresult-size":               "Transaction size in bytes",
+	"getmempoolentryresult-vsize":              "The virtual size of the transaction",
+	"getmempoolentryresult-weight":             "The transaction's weight (between vsize*4-3 and vsize*4)",
+	"getmempoolentryresult-fee":                "Transaction fee in brocoins",
+	"getmempoolentryresult-modifiedfee":        "Transaction fee in brocoins including the fee delta used when deciding which transactions to evict",
+	"getmempoolentryresult-time":               "Local time transaction entered pool in seconds since 1 Jan 1970 GMT",
+	"getmempoolentryresult-height":             "Block height when transaction entered the pool",
+	"getmempoolentryresult-startingpriority":   "Priority when transaction entered the pool",
+	"getmempoolentryresult-currentpriority":    "Current priority",
+	"getmempoolentryresult-descendantcount":    "Number of transactions in the memory pool which descend from the transaction, including itself",
+	"getmempoolentryresult-descendantsize":     "Virtual size of the transaction and its descendants in the memory pool",
+	"getmempoolentryresult-descendantfees":     "Modified fees of the transaction and its descendants in the memory pool in brocoins",
+	"getmempoolentryresult-ancestorcount":      "Number of transactions in the memory pool the transaction descends from, including itself",
+	"getmempoolentryresult-ancestorsize":       "Virtual size of the transaction and its ancestors in the memory pool",
+	"getmempoolentryresult-ancestorfees":       "Modified fees of the transaction and its ancestors in the memory pool in brocoins",
+	"getmempoolentryresult-depends":            "Unconfirmed transactions used as inputs for this transaction",
+	"getmempoolentryresult-spentby":            "Unconfirmed transactions spending outputs of this transaction",
+	"getmempoolentryresult-bip125-replaceable": "Whether the transaction or one of its unconfirmed ancestors signals BIP125 replaceability",
+
 	// GetMempoolInfoCmd help.
 	"getmempoolinfo--synopsis": "Returns memory pool information",
 
 	// GetMempoolInfoResult help.
-	"getmempoolinforesult-bytes": "Size in bytes of the mempool",
-	"getmempoolinforesult-size":  "Number of transactions in the mempool",
+	"getmempoolinforesult-bytes":         "Size in bytes of the mempool",
+	"getmempoolinforesult-size":          "Number of transactions in the mempool",
+	"getmempoolinforesult-maxmempool":    "Maximum size in bytes of the mempool, 0 if unlimited",
+	"getmempoolinforesult-mempoolminfee": "Minimum fee rate in BRON/kB for transactions to be accepted into the mempool",
 
 	// GetMiningInfoResult help.
 	"getmininginforesult-blocks":             "Height of the latest best block",
@@ -445,6 +557,36 @@ var helpDescsEnUS = map[string]string{
 	"getnetworkhashps-height":    "Perform estimate ending with this height or -1 for current best chain block height",
 	"getnetworkhashps--result0":  "Estimated hashes per second",
 
+	// GetNetworkInfoCmd help.
+	"getnetworkinfo--synopsis": "Returns a JSON object containing various state info regarding P2P networking.",
+
+	// GetNetworkInfoResult help.
+	"getnetworkinforesult-version":         "The version of the server",
+	"getnetworkinforesult-subversion":      "The user agent the server advertises to peers",
+	"getnetworkinforesult-protocolversion": "The latest supported protocol version",
+	"getnetworkinforesult-localservices":   "Hex encoded services bitmask which represents the services offered to peers",
+	"getnetworkinforesult-localrelay":      "Whether or not transactions are requested to be relayed by peers",
+	"getnetworkinforesult-timeoffset":      "The time offset",
+	"getnetworkinforesult-connections":     "The number of connected peers",
+	"getnetworkinforesult-networkactive":   "Whether or not P2P networking is enabled",
+	"getnetworkinforesult-networks":        "Information per network",
+	"getnetworkinforesult-relayfee":        "The minimum relay fee for non-free transactions in BRON/KB",
+	"getnetworkinforesult-incrementalfee":  "The minimum fee rate increase for replacing transactions in BRON/KB",
+	"getnetworkinforesult-localaddresses":  "The local addresses advertised to peers",
+	"getnetworkinforesult-warnings":        "Any current warnings",
+
+	// NetworksResult help.
+	"networksresult-name":                        "The network name (ipv4, ipv6 or onion)",
+	"networksresult-limited":                     "Whether or not connections to the network are disabled",
+	"networksresult-reachable":                   "Whether or not peers on the network can be connected to",
+	"networksresult-proxy":                       "The proxy used for the network or empty when none is used",
+	"networksresult-proxy_randomize_credentials": "Whether or not random credentials are used with the proxy for stream isolation",
+
+	// LocalAddressesResult help.
+	"localaddressesresult-address": "The local address",
+	"localaddressesresult-port":    "The local port",
+	"localaddressesresult-score":   "The priority the address is advertised with",
+
 	// GetNetTotalsCmd help.
 	"getnettotals--synopsis": "Returns a JSON object containing network traffic statistics.",
 
@@ -454,27 +596,40 @@ var helpDescsEnUS = map[string]string{
 	"getnettotalsresult-timemillis":     "Number of milliseconds since 1 Jan 1970 GMT",
 
 	// GetPeerInfoResult help.
-	"getpeerinforesult-id":             "A unique node ID",
-	"getpeerinforesult-addr":           "The ip address and port of the peer",
-	"getpeerinforesult-addrlocal":      "Local address",
-	"getpeerinforesult-services":       "Services bitmask which represents the services supported by the peer",
-	"getpeerinforesult-relaytxes":      "Peer has requested transactions be relayed to it",
-	"getpeerinforesult-lastsend":       "Time the last message was received in seconds since 1 Jan 1970 GMT",
-	"getpeerinforesult-lastrecv":       "Time the last message was sent in seconds since 1 Jan 1970 GMT",
-	"getpeerinforesult-bytessent":      "Total bytes sent",
-	"getpeerinforesult-bytesrecv":      "Total bytes received",
-	"getpeerinforesult-conntime":       "Time the connection was made in seconds since 1 Jan 1970 GMT",
-	"getpeerinforesult-timeoffset":     "The time offset of the peer",
-	"getpeerinforesult-pingtime":       "Number of microseconds the last ping took",
-	"getpeerinforesult-pingwait":       "Number of microseconds a queued ping has been waiting for a response",
-	"getpeerinforesult-version":        "The protocol version of the peer",
-	"getpeerinforesult-subver":         "The user agent of the peer",
-	"getpeerinforesult-inbound":        "Whether or not the peer is an inbound connection",
-	"getpeerinforesult-startingheight": "The latest block height the peer knew about when the connection was established",
-	"getpeerinforesult-currentheight":  "The current height of the peer",
-	"getpeerinforesult-banscore":       "The ban score",
-	"getpeerinforesult-feefilter":      "The requested minimum fee a transaction must have to be announced to the peer",
-	"getpeerinforesult-syncnode":       "Whether or not the peer is the sync peer",
+	"getpeerinforesult-id":                       "A unique node ID",
+	"getpeerinforesult-addr":                     "The ip address and port of the peer",
+	"getpeerinforesult-addrlocal":                "Local address",
+	"getpeerinforesult-network":                  "The network of the peer (ipv4, ipv6, onion or not_publicly_routable)",
+	"getpeerinforesult-addrgroup":                "The address manager group the peer is mapped to",
+	"getpeerinforesult-services":                 "Services bitmask which represents the services supported by the peer",
+	"getpeerinforesult-relaytxes":                "Peer has requested transactions be relayed to it",
+	"getpeerinforesult-lastsend":                 "Time the last message was received in seconds since 1 Jan 1970 GMT",
+	"getpeerinforesult-lastrecv":                 "Time the last message was sent in seconds since 1 Jan 1970 GMT",
+	"getpeerinforesult-bytessent":                "Total bytes sent",
+	"getpeerinforesult-bytesrecv":                "Total bytes received",
+	"getpeerinforesult-conntime":                 "Time the connection was made in seconds since 1 Jan 1970 GMT",
+	"getpeerinforesult-timeoffset":               "The time offset of the peer",
+	"getpeerinforesult-pingtime":                 "Number of microseconds the last ping took",
+	"getpeerinforesult-pingwait":                 "Number of microseconds a queued ping has been waiting for a response",
+	"getpeerinforesult-version":                  "The protocol version of the peer",
+	"getpeerinforesult-subver":                   "The user agent of the peer",
+	"getpeerinforesult-inbound":                  "Whether or not the peer is an inbound connection",
+	"getpeerinforesult-connection_type":          "How the connection was established (inbound, manual, outbound-full-relay, block-relay-only or feeler)",
+	"getpeerinforesult-startingheight":           "The latest block height the peer knew about when the connection was established",
+	"getpeerinforesult-currentheight":            "The current height of the peer",
+	"getpeerinforesult-banscore":                 "The ban score",
+	"getpeerinforesult-banscore_persistent":      "The persistent part of the ban score",
+	"getpeerinforesult-banscore_transient":       "The decaying part of the ban score",
+	"getpeerinforesult-feefilter":                "The requested minimum fee a transaction must have to be announced to the peer",
+	"getpeerinforesult-syncnode":                 "Whether or not the peer is the sync peer",
+	"getpeerinforesult-bytessent_per_msg":        "Total bytes sent keyed by message type",
+	"getpeerinforesult-bytessent_per_msg--key":   "command",
+	"getpeerinforesult-bytessent_per_msg--value": "n",
+	"getpeerinforesult-bytessent_per_msg--desc":  "The message command as the key and the bytes sent as the value",
+	"getpeerinforesult-bytesrecv_per_msg":        "Total bytes received keyed by message type",
+	"getpeerinforesult-bytesrecv_per_msg--key":   "command",
+	"getpeerinforesult-bytesrecv_per_msg--value": "n",
+	"getpeerinforesult-bytesrecv_per_msg--desc":  "The message command as the key and the bytes received as the value",
 
 	// GetPeerInfoCmd help.
 	"getpeerinfo--synopsis": "Returns data about each connected network peer as an array of json objects.",
@@ -519,6 +674,20 @@ var helpDescsEnUS = map[string]string{
 	"gettxout-vout":           "The index of the output",
 	"gettxout-includemempool": "Include the mempool when true",
 
+	// GetTxOutSetInfoCmd help.
+	"gettxoutsetinfo--synopsis": "Returns statistics about the unspent transaction output set as of the best block.\n" +
+		"The serialized hash can be compared against other nodes to check they have the same set.",
+
+	// GetTxOutSetInfoResult help.
+	"gettxoutsetinforesult-height":          "The height of the best block",
+	"gettxoutsetinforesult-bestblock":       "The hash of the best block",
+	"gettxoutsetinforesult-transactions":    "The number of transactions with unspent outputs",
+	"gettxoutsetinforesult-txouts":          "The number of unspent transaction outputs",
+	"gettxoutsetinforesult-bogosize":        "An estimate of the size of the set which does not depend on how it is stored",
+	"gettxoutsetinforesult-hash_serialized": "The double SHA256 of the serialized set, which commits to every unspent output",
+	"gettxoutsetinforesult-disk_size":       "The number of bytes the set uses in the database",
+	"gettxoutsetinforesult-total_amount":    "The total amount of all unspent outputs in BRON",
+
 	// HelpCmd help.
 	"help--synopsis":   "Returns a list of all commands or help for a specified command.",
 	"help-command":     "The command to retrieve help for",
@@ -527,10 +696,47 @@ var helpDescsEnUS = map[string]string{
 	"help--result0":    "List of commands",
 	"help--result1":    "Help for specified command",
 
+	// InvalidateBlockCmd help.
+	"invalidateblock--synopsis": "Permanently marks a block as invalid, as if it violated a consensus rule.",
+	"invalidateblock-blockhash": "The hash of the block to mark as invalid",
+
+	// ListBannedCmd help.
+	"listbanned--synopsis": "Returns all banned IP addresses and subnets.",
+
+	// ListBannedResult help.
+	"listbannedresult-address":        "The banned IP address or subnet in CIDR notation",
+	"listbannedresult-ban_created":    "Time the ban was created in seconds since 1 Jan 1970 GMT",
+	"listbannedresult-banned_until":   "Time the ban expires in seconds since 1 Jan 1970 GMT",
+	"listbannedresult-ban_duration":   "Total duration of the ban in seconds",
+	"listbannedresult-time_remaining": "Number of seconds until the ban expires",
+
+	// LoadMempoolCmd help.
+	"loadmempool--synopsis": "Reloads the transactions saved to the mempool.dat file in the data directory into the memory pool.\n" +
+		"Transactions which have expired or are no longer valid are skipped.",
+
+	// LoadMempoolResult help.
+	"loadmempoolresult-loaded":      "Number of transactions added to the memory pool",
+	"loadmempoolresult-failed":      "Number of transactions which were rejected",
+	"loadmempoolresult-expired":     "Number of transactions which were skipped since they were added to the memory pool too long ago",
+	"loadmempoolresult-alreadyhave": "Number of transactions which were already in the memory pool",
+
 	// PingCmd help.
 	"ping--synopsis": "Queues a ping to be sent to each connected peer.\n" +
 		"Ping times are provided by getpeerinfo via the pingtime and pingwait fields.",
 
+	// PreciousBlockCmd help.
+	"preciousblock--synopsis": "Treats a block as if it were received before others with the same work.\n" +
+		"A later preciousblock call can override the effect of an earlier one.",
+	"preciousblock-blockhash": "The hash of the block to mark as precious",
+
+	// ReconsiderBlockCmd help.
+	"reconsiderblock--synopsis": "Removes invalidity status of a block, its ancestors and its descendants, reconsidering them for activation.\n" +
+		"This can be used to undo the effects of invalidateblock.",
+	"reconsiderblock-blockhash": "The hash of the block to reconsider",
+
+	// SaveMempoolCmd help.
+	"savemempool--synopsis": "Writes the transactions in the memory pool to the mempool.dat file in the data directory.",
+
 	// SearchRawTransactionsCmd help.
 	"searchrawtransactions--synopsis": "Returns raw data for transactions involving the passed address.\n" +
 		"Returned transactions are pulled from both the database, and transactions currently in the mempool.\n" +
@@ -555,6 +761,14 @@ var helpDescsEnUS = map[string]string{
 	"sendrawtransaction-maxfeerate":    "Used by brocoind on or after v0.19.0",
 	"sendrawtransaction--result0":      "The hash of the transaction",
 
+	// SetBanCmd help.
+	"setban--synopsis": "Adds or removes an IP address or subnet from the ban list.\n" +
+		"Connected peers within a newly banned subnet are disconnected.",
+	"setban-subnet":   "The IP address or subnet in CIDR notation to operate on",
+	"setban-subcmd":   "'add' to ban the IP address or subnet, or 'remove' to remove an existing ban",
+	"setban-bantime":  "Number of seconds the ban lasts, or the unix time it expires at when absolute is set (0 = use the banduration option)",
+	"setban-absolute": "Whether the ban time is an absolute unix time",
+
 	// SetGenerateCmd help.
 	"setgenerate--synopsis":    "Set the server to generate coins (mine) or not.",
 	"setgenerate-generate":     "Use true to enable generation, false to disable it",
@@ -575,6 +789,23 @@ var helpDescsEnUS = map[string]string{
 	"submitblock--condition1": "Block rejected",
 	"submitblock--result1":    "The reason the block was rejected",
 
+	// TestMempoolAcceptCmd help.
+	"testmempoolaccept--synopsis": "Returns whether the passed raw transactions would be accepted into the memory pool without submitting them.\n" +
+		"Each transaction may spend the outputs of the transactions before it which would be accepted.",
+	"testmempoolaccept-rawtxns":    "Serialized, hex-encoded transactions to test",
+	"testmempoolaccept-maxfeerate": "Reject transactions whose fee rate in BRON/kvB is higher than this (0 = no limit)",
+
+	// TestMempoolAcceptFees help.
+	"testmempoolacceptfees-base": "The fee paid by the transaction in BRON",
+
+	// TestMempoolAcceptResult help.
+	"testmempoolacceptresult-txid":          "The hash of the transaction",
+	"testmempoolacceptresult-wtxid":         "The witness hash of the transaction",
+	"testmempoolacceptresult-allowed":       "Whether the transaction would be accepted into the memory pool",
+	"testmempoolacceptresult-vsize":         "The virtual size of the transaction (only when allowed is true)",
+	"testmempoolacceptresult-fees":          "The fees of the transaction (only when allowed is true)",
+	"testmempoolacceptresult-reject-reason": "The reason the transaction would be rejected (only when allowed is false)",
+
 	// ValidateAddressResult help.
 	"validateaddresschainresult-isvalid": "Whether or not the address is valid",
 	"validateaddresschainresult-address": "The brocoin address (only when isvalid is true)",
@@ -619,6 +850,12 @@ var helpDescsEnUS = map[string]string{
 	// StopNotifyNewTransactionsCmd help.
 	"stopnotifynewtransactions--synopsis": "Stop sending either a txaccepted or a txacceptedverbose notification when a new transaction is accepted into the mempool.",
 
+	// NotifySinceCmd help.
+	"notifysince--synopsis": "Send sequenced seqblockconnected, seqblockdisconnected, seqtxaccepted and seqtxremoved notifications for block and mempool events.\n" +
+		"Every event is assigned a sequence number one greater than the previous one and the most recent events are kept, so a client which reconnects can resume the stream without missing events.",
+	"notifysince-sequence": "Sequence number of the last event received; the events which follow it are sent before any new ones.  Only new events are sent when omitted",
+	"notifysince--result0": "The sequence number of the latest event",
+
 	// NotifyReceivedCmd help.
 	"notifyreceived--synopsis": "Send a recvtx notification when a transaction added to mempool or appears in a newly-attached block contains a txout pkScript sending to any of the passed addresses.\n" +
 		"Matching outpoints are automatically registered for redeemingtx notifications.",
@@ -689,11 +926,13 @@ var helpDescsEnUS = map[string]string{
 // pointer to the type (or nil to indicate no return value).
 var rpcResultTypes = map[string][]interface{}{
 	"addnode":               nil,
+	"clearbanned":           nil,
 	"createrawtransaction":  {(*string)(nil)},
 	"debuglevel":            {(*string)(nil), (*string)(nil)},
 	"decoderawtransaction":  {(*bronjson.TxRawDecodeResult)(nil)},
 	"decodescript":          {(*bronjson.DecodeScriptResult)(nil)},
 	"estimatefee":           {(*float64)(nil)},
+	"estimatesmartfee":      {(*bronjson.EstimateSmartFeeResult)(nil)},
 	"generate":              {(*[]string)(nil)},
 	"getaddednodeinfo":      {(*[]string)(nil), (*[]bronjson.GetAddedNodeInfoResult)(nil)},
 	"getbestblock":          {(*bronjson.GetBestBlockResult)(nil)},
@@ -702,10 +941,12 @@ var rpcResultTypes = map[string][]interface{}{
 	"getblockcount":         {(*int64)(nil)},
 	"getblockhash":          {(*string)(nil)},
 	"getblockheader":        {(*string)(nil), (*bronjson.GetBlockHeaderVerboseResult)(nil)},
+	"getblockstats":         {(*bronjson.GetBlockStatsResult)(nil)},
 	"getblocktemplate":      {(*bronjson.GetBlockTemplateResult)(nil), (*string)(nil), nil},
 	"getblockchaininfo":     {(*bronjson.GetBlockChainInfoResult)(nil)},
 	"getcfilter":            {(*string)(nil)},
 	"getcfilterheader":      {(*string)(nil)},
+	"getchaintips":          {(*[]bronjson.GetChainTipsResult)(nil)},
 	"getconnectioncount":    {(*int32)(nil)},
 	"getcurrentnet":         {(*uint32)(nil)},
 	"getdifficulty":         {(*float64)(nil)},
@@ -713,22 +954,35 @@ var rpcResultTypes = map[string][]interface{}{
 	"gethashespersec":       {(*float64)(nil)},
 	"getheaders":            {(*[]string)(nil)},
 	"getinfo":               {(*bronjson.InfoChainResult)(nil)},
+	"getmempoolancestors":   {(*[]string)(nil), (*bronjson.GetMempoolEntryResult)(nil)},
+	"getmempooldescendants": {(*[]string)(nil), (*bronjson.GetMempoolEntryResult)(nil)},
+	"getmempoolentry":       {(*bronjson.GetMempoolEntryResult)(nil)},
 	"getmempoolinfo":        {(*bronjson.GetMempoolInfoResult)(nil)},
 	"getmininginfo":         {(*bronjson.GetMiningInfoResult)(nil)},
 	"getnettotals":          {(*bronjson.GetNetTotalsResult)(nil)},
 	"getnetworkhashps":      {(*int64)(nil)},
+	"getnetworkinfo":        {(*bronjson.GetNetworkInfoResult)(nil)},
 	"getpeerinfo":           {(*[]bronjson.GetPeerInfoResult)(nil)},
 	"getrawmempool":         {(*[]string)(nil), (*bronjson.GetRawMempoolVerboseResult)(nil)},
 	"getrawtransaction":     {(*string)(nil), (*bronjson.TxRawResult)(nil)},
 	"gettxout":              {(*bronjson.GetTxOutResult)(nil)},
+	"gettxoutsetinfo":       {(*bronjson.GetTxOutSetInfoResult)(nil)},
 	"node":                  nil,
 	"help":                  {(*string)(nil), (*string)(nil)},
+	"invalidateblock":       nil,
+	"listbanned":            {(*[]bronjson.ListBannedResult)(nil)},
+	"loadmempool":           {(*bronjson.LoadMempoolResult)(nil)},
 	"ping":                  nil,
+	"preciousblock":         nil,
+	"reconsiderblock":       nil,
+	"savemempool":           nil,
 	"searchrawtransactions": {(*string)(nil), (*[]bronjson.SearchRawTransactionsResult)(nil)},
 	"sendrawtransaction":    {(*string)(nil)},
+	"setban":                nil,
 	"setgenerate":           nil,
 	"stop":                  {(*string)(nil)},
 	"submitblock":           {nil, (*string)(nil)},
+	"testmempoolaccept":     {(*[]bronjson.TestMempoolAcceptResult)(nil)},
 	"uptime":                {(*int64)(nil)},
 	"validateaddress":       {(*bronjson.ValidateAddressChainResult)(nil)},
 	"verifychain":           {(*bool)(nil)},
@@ -744,6 +998,7 @@ var rpcResultTypes = map[string][]interface{}{
 	"stopnotifynewtransactions": nil,
 	"notifyreceived":            nil,
 	"stopnotifyreceived":        nil,
+	"notifysince":               {(*uint64)(nil)},
 	"notifyspent":               nil,
 	"stopnotifyspent":           nil,
 	"rescan":                    nil,
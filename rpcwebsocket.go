@@ -8,9 +8,6 @@ package main
 import (
 	"bytes"
 	"container/list"
-	"crypto/sha256"
-	"crypto/subtle"
-	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -25,6 +22,7 @@ import (
 	"github.com/brsuite/brond/chaincfg"
 	"github.com/brsuite/brond/chaincfg/chainhash"
 	"github.com/brsuite/brond/database"
+	"github.com/brsuite/brond/mempool"
 	"github.com/brsuite/brond/txscript"
 	"github.com/brsuite/brond/wire"
 	"github.com/brsuite/bronutil"
@@ -39,6 +37,11 @@ const (
 	// handler since notifications have their own queuing mechanism
 	// independent of the send channel buffer.
 	websocketSendBufferSize = 50
+
+	// wsEventReplayBufferSize is the number of the most recent sequenced
+	// chain and mempool events which are kept so websocket clients can
+	// resume the event stream with notifysince after reconnecting.
+	wsEventReplayBufferSize = 10000
 )
 
 type semaphore chan struct{}
@@ -68,6 +71,7 @@ var wsHandlersBeforeInit = map[string]wsCommandHandler{
 	"notifyblocks":              handleNotifyBlocks,
 	"notifynewtransactions":     handleNotifyNewTransactions,
 	"notifyreceived":            handleNotifyReceived,
+	"notifysince":               handleNotifySince,
 	"notifyspent":               handleNotifySpent,
 	"session":                   handleSession,
 	"stopnotifyblocks":          handleStopNotifyBlocks,
@@ -82,9 +86,10 @@ var wsHandlersBeforeInit = map[string]wsCommandHandler{
 // starting it, and blocking until the connection closes.  Since it blocks, it
 // must be run in a separate goroutine.  It should be invoked from the websocket
 // server handler which runs each new connection in a new goroutine thereby
-// satisfying the requirement.
+// satisfying the requirement.  The passed user is nil when the client has not
+// been authenticated yet.
 func (s *rpcServer) WebsocketHandler(conn *websocket.Conn, remoteAddr string,
-	authenticated bool, isAdmin bool) {
+	user *rpcUser) {
 
 	// Clear the read deadline that was set before the websocket hijacked
 	// the connection.
@@ -103,7 +108,7 @@ func (s *rpcServer) WebsocketHandler(conn *websocket.Conn, remoteAddr string,
 	// Create a new websocket client to handle the new websocket connection
 	// and wait for it to shutdown.  Once it has shutdown (and hence
 	// disconnected), remove it and any notifications it registered for.
-	client, err := newWebsocketClient(s, conn, remoteAddr, authenticated, isAdmin)
+	client, err := newWebsocketClient(s, conn, remoteAddr, user)
 	if err != nil {
 		rpcsLog.Errorf("Failed to serve client %s: %v", remoteAddr, err)
 		conn.Close()
@@ -246,6 +251,86 @@ func (m *wsNotificationManager) NotifyMempoolTx(tx *bronutil.Tx, isNew bool) {
 	}
 }
 
+// NotifyMempoolTxRemoved passes a transaction removed from the mempool to the
+// notification manager for sequenced event processing.
+func (m *wsNotificationManager) NotifyMempoolTxRemoved(tx *bronutil.Tx,
+	reason mempool.RemovalReason) {
+
+	n := &notificationTxRemovedFromMempool{
+		reason: reason,
+		tx:     tx,
+	}
+
+	// As NotifyMempoolTxRemoved will be called by mempool and the RPC
+	// server may no longer be running, use a select statement to unblock
+	// enqueuing the notification once the RPC server has begun shutting
+	// down.
+	select {
+	case m.queueNotification <- n:
+	case <-m.quit:
+	}
+}
+
+// wsEventBuffer is a ring buffer of the most recent marshalled sequenced
+// notifications.  Sequence numbers start at one and increase by one for each
+// event, so the sequence number of every buffered event can be derived from
+// the sequence number of the last one.
+type wsEventBuffer struct {
+	events  [][]byte
+	next    int
+	lastSeq uint64
+}
+
+// newWSEventBuffer returns a new event buffer which holds up to size events.
+func newWSEventBuffer(size int) *wsEventBuffer {
+	return &wsEventBuffer{events: make([][]byte, 0, size)}
+}
+
+// add appends the passed marshalled event, which must have been created with
+// the sequence number following lastSeq, evicting the oldest event when the
+// buffer is full.
+func (b *wsEventBuffer) add(marshalledJSON []byte) {
+	if len(b.events) < cap(b.events) {
+		b.events = append(b.events, marshalledJSON)
+	} else {
+		b.events[b.next] = marshalledJSON
+		b.next = (b.next + 1) % len(b.events)
+	}
+	b.lastSeq++
+}
+
+// since returns the buffered events with a sequence number greater than the
+// passed one in order.  An error is returned when the sequence number has not
+// been reached yet or when some of the following events are no longer
+// buffered.
+func (b *wsEventBuffer) since(seq uint64) ([][]byte, error) {
+	if seq > b.lastSeq {
+		str := fmt.Sprintf("sequence number %d has not been reached, "+
+			"the latest is %d", seq, b.lastSeq)
+		return nil, &bronjson.RPCError{
+			Code:    bronjson.ErrRPCInvalidParameter,
+			Message: str,
+		}
+	}
+	missed := b.lastSeq - seq
+	if missed > uint64(len(b.events)) {
+		str := fmt.Sprintf("events after sequence number %d are no "+
+			"longer available, the oldest is %d", seq,
+			b.lastSeq-uint64(len(b.events))+1)
+		return nil, &bronjson.RPCError{
+			Code:    bronjson.ErrRPCInvalidParameter,
+			Message: str,
+		}
+	}
+
+	events := make([][]byte, 0, missed)
+	start := b.next + len(b.events) - int(missed)
+	for i := 0; i < int(missed); i++ {
+		events = append(events, b.events[(start+i)%len(b.events)])
+	}
+	return events, nil
+}
+
 // wsClientFilter tracks relevant addresses for each websocket client for
 // the `rescanblocks` extension. It is modified by the `loadtxfilter` command.
 //
@@ -451,6 +536,10 @@ type notificationTxAcceptedByMempool struct {
 	isNew bool
 	tx    *bronutil.Tx
 }
+type notificationTxRemovedFromMempool struct {
+	reason mempool.RemovalReason
+	tx     *bronutil.Tx
+}
 
 // Notification control requests
 type notificationRegisterClient wsClient
@@ -475,6 +564,17 @@ type notificationUnregisterAddr struct {
 	wsc  *wsClient
 	addr string
 }
+type notificationRegisterEvents struct {
+	wsc   *wsClient
+	since *uint64
+	reply chan registerEventsReply
+}
+
+// registerEventsReply is the reply to a notificationRegisterEvents request.
+type registerEventsReply struct {
+	lastSeq uint64
+	err     error
+}
 
 // notificationHandler reads notifications and control messages from the queue
 // handler and processes one at a time.
@@ -494,6 +594,12 @@ func (m *wsNotificationManager) notificationHandler() {
 	watchedOutPoints := make(map[wire.OutPoint]map[chan struct{}]*wsClient)
 	watchedAddrs := make(map[string]map[chan struct{}]*wsClient)
 
+	// Sequenced events are assigned their sequence number and buffered
+	// whether or not any clients are registered for them, so clients can
+	// resume the stream from any event which is still buffered.
+	eventClients := make(map[chan struct{}]*wsClient)
+	events := newWSEventBuffer(wsEventReplayBufferSize)
+
 out:
 	for {
 		select {
@@ -521,6 +627,7 @@ out:
 					m.notifyFilteredBlockConnected(blockNotifications,
 						block)
 				}
+				m.notifySeqBlock(eventClients, events, block, true)
 
 			case *notificationBlockDisconnected:
 				block := (*bronutil.Block)(n)
@@ -531,6 +638,7 @@ out:
 					m.notifyFilteredBlockDisconnected(blockNotifications,
 						block)
 				}
+				m.notifySeqBlock(eventClients, events, block, false)
 
 			case *notificationTxAcceptedByMempool:
 				if n.isNew && len(txNotifications) != 0 {
@@ -538,6 +646,11 @@ out:
 				}
 				m.notifyForTx(watchedOutPoints, watchedAddrs, n.tx, nil)
 				m.notifyRelevantTxAccepted(n.tx, clients)
+				m.notifySeqTxAccepted(eventClients, events, n.tx)
+
+			case *notificationTxRemovedFromMempool:
+				m.notifySeqTxRemoved(eventClients, events, n.tx,
+					n.reason)
 
 			case *notificationRegisterBlocks:
 				wsc := (*wsClient)(n)
@@ -557,6 +670,7 @@ out:
 				// the client itself.
 				delete(blockNotifications, wsc.quit)
 				delete(txNotifications, wsc.quit)
+				delete(eventClients, wsc.quit)
 				for k := range wsc.spentRequests {
 					op := k
 					m.removeSpentRequest(watchedOutPoints, wsc, &op)
@@ -586,6 +700,26 @@ out:
 				wsc := (*wsClient)(n)
 				delete(txNotifications, wsc.quit)
 
+			case *notificationRegisterEvents:
+				// Queue the missed events before registering the
+				// client so it receives every event exactly once
+				// and in order.
+				var err error
+				if n.since != nil {
+					var missed [][]byte
+					missed, err = events.since(*n.since)
+					for _, marshalledJSON := range missed {
+						n.wsc.QueueNotification(marshalledJSON)
+					}
+				}
+				if err == nil {
+					eventClients[n.wsc.quit] = n.wsc
+				}
+				n.reply <- registerEventsReply{
+					lastSeq: events.lastSeq,
+					err:     err,
+				}
+
 			default:
 				rpcsLog.Warn("Unhandled notification type")
 			}
@@ -625,6 +759,99 @@ func (m *wsNotificationManager) UnregisterBlockUpdates(wsc *wsClient) {
 	m.queueNotification <- (*notificationUnregisterBlocks)(wsc)
 }
 
+// RegisterEventUpdates requests sequenced block and mempool event
+// notifications to the passed websocket client.  When a sequence number is
+// passed, the buffered events which follow it are delivered first.  The
+// sequence number of the latest event is returned.
+func (m *wsNotificationManager) RegisterEventUpdates(wsc *wsClient,
+	since *uint64) (uint64, error) {
+
+	n := &notificationRegisterEvents{
+		wsc:   wsc,
+		since: since,
+		reply: make(chan registerEventsReply, 1),
+	}
+	select {
+	case m.queueNotification <- n:
+	case <-m.quit:
+		return 0, ErrClientQuit
+	}
+
+	select {
+	case reply := <-n.reply:
+		return reply.lastSeq, reply.err
+	case <-m.quit:
+		return 0, ErrClientQuit
+	}
+}
+
+// notifySeqEvent assigns the next sequence number to the notification created
+// by newNtfn, adds it to the event buffer and sends it to the websocket
+// clients which have registered for sequenced events.
+func (*wsNotificationManager) notifySeqEvent(clients map[chan struct{}]*wsClient,
+	events *wsEventBuffer, newNtfn func(seq uint64) interface{}) {
+
+	marshalledJSON, err := bronjson.MarshalCmd(nil, newNtfn(events.lastSeq+1))
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal sequenced notification: %v",
+			err)
+		return
+	}
+	events.add(marshalledJSON)
+	for _, wsc := range clients {
+		wsc.QueueNotification(marshalledJSON)
+	}
+}
+
+// notifySeqBlock creates a sequenced notification for a block which has been
+// connected to or disconnected from the main chain.
+func (m *wsNotificationManager) notifySeqBlock(clients map[chan struct{}]*wsClient,
+	events *wsEventBuffer, block *bronutil.Block, connected bool) {
+
+	var w bytes.Buffer
+	err := block.MsgBlock().Header.Serialize(&w)
+	if err != nil {
+		rpcsLog.Errorf("Failed to serialize header for sequenced block "+
+			"notification: %v", err)
+		return
+	}
+	header := hex.EncodeToString(w.Bytes())
+	m.notifySeqEvent(clients, events, func(seq uint64) interface{} {
+		if connected {
+			return bronjson.NewSeqBlockConnectedNtfn(seq,
+				block.Height(), header)
+		}
+		return bronjson.NewSeqBlockDisconnectedNtfn(seq, block.Height(),
+			header)
+	})
+}
+
+// notifySeqTxAccepted creates a sequenced notification for a transaction
+// which has been accepted into the memory pool.
+func (m *wsNotificationManager) notifySeqTxAccepted(clients map[chan struct{}]*wsClient,
+	events *wsEventBuffer, tx *bronutil.Tx) {
+
+	var amount int64
+	for _, txOut := range tx.MsgTx().TxOut {
+		amount += txOut.Value
+	}
+	m.notifySeqEvent(clients, events, func(seq uint64) interface{} {
+		return bronjson.NewSeqTxAcceptedNtfn(seq, tx.Hash().String(),
+			bronutil.Amount(amount).ToBRON())
+	})
+}
+
+// notifySeqTxRemoved creates a sequenced notification for a transaction which
+// has been removed from the memory pool.
+func (m *wsNotificationManager) notifySeqTxRemoved(clients map[chan struct{}]*wsClient,
+	events *wsEventBuffer, tx *bronutil.Tx, reason mempool.RemovalReason) {
+
+	m.notifySeqEvent(clients, events, func(seq uint64) interface{} {
+		return bronjson.NewSeqTxRemovedNtfn(seq, tx.Hash().String(),
+			reason.String())
+	})
+}
+
 // subscribedClients returns the set of all websocket client quit channels that
 // are registered to receive notifications regarding tx, either due to tx
 // spending a watched output or outputting to a watched address.  Matching
@@ -1264,9 +1491,9 @@ type wsClient struct {
 	// and therefore is allowed to communicated over the websocket.
 	authenticated bool
 
-	// isAdmin specifies whether a client may change the state of the server;
-	// false means its access is only to the limited set of RPC calls.
-	isAdmin bool
+	// user is the user the client authenticated as, which determines the
+	// RPC calls and notifications available to it.
+	user *rpcUser
 
 	// sessionID is a random ID generated for each client when connected.
 	// These IDs may be queried by a client using the session RPC.  A change
@@ -1402,17 +1629,14 @@ out:
 			break out
 		case !c.authenticated:
 			// Check credentials.
-			login := authCmd.Username + ":" + authCmd.Passphrase
-			auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
-			authSha := sha256.Sum256([]byte(auth))
-			cmp := subtle.ConstantTimeCompare(authSha[:], c.server.authsha[:])
-			limitcmp := subtle.ConstantTimeCompare(authSha[:], c.server.limitauthsha[:])
-			if cmp != 1 && limitcmp != 1 {
+			user := c.server.authenticate(authCmd.Username,
+				authCmd.Passphrase)
+			if user == nil {
 				rpcsLog.Warnf("Auth failure.")
 				break out
 			}
 			c.authenticated = true
-			c.isAdmin = cmp == 1
+			c.user = user
 
 			// Marshal and send response.
 			reply, err := createMarshalledReply(cmd.id, nil, nil)
@@ -1425,24 +1649,22 @@ out:
 			continue
 		}
 
-		// Check if the client is using limited RPC credentials and
-		// error when not authorized to call this RPC.
-		if !c.isAdmin {
-			if _, ok := rpcLimited[request.Method]; !ok {
-				jsonErr := &bronjson.RPCError{
-					Code:    bronjson.ErrRPCInvalidParams.Code,
-					Message: "limited user not authorized for this method",
-				}
-				// Marshal and send response.
-				reply, err := createMarshalledReply(request.ID, nil, jsonErr)
-				if err != nil {
-					rpcsLog.Errorf("Failed to marshal parse failure "+
-						"reply: %v", err)
-					continue
-				}
-				c.SendMessage(reply, nil)
+		// Error when the user of the client is not authorized to call
+		// this RPC.
+		if !c.user.methodAllowed(request.Method) {
+			jsonErr := &bronjson.RPCError{
+				Code:    bronjson.ErrRPCInvalidParams.Code,
+				Message: "user not authorized for this method",
+			}
+			// Marshal and send response.
+			reply, err := createMarshalledReply(request.ID, nil, jsonErr)
+			if err != nil {
+				rpcsLog.Errorf("Failed to marshal parse failure "+
+					"reply: %v", err)
 				continue
 			}
+			c.SendMessage(reply, nil)
+			continue
 		}
 
 		// Asynchronously handle the request.  A semaphore is used to
@@ -1647,6 +1869,8 @@ var ErrClientQuit = errors.New("client quit")
 // as the memory pool and block manager, from blocking even when the send
 // channel is full.
 //
+// Notifications of a type the user of the client may not receive are dropped.
+//
 // If the client is in the process of shutting down, this function returns
 // ErrClientQuit.  This is intended to be checked by long-running notification
 // handlers to stop processing if there is no more work needed to be done.
@@ -1656,6 +1880,18 @@ func (c *wsClient) QueueNotification(marshalledJSON []byte) error {
 		return ErrClientQuit
 	}
 
+	// Only decode the notification type when the user is restricted to
+	// some of them.
+	if c.user != nil && c.user.ntfns != nil {
+		var ntfn struct {
+			Method string `json:"method"`
+		}
+		err := json.Unmarshal(marshalledJSON, &ntfn)
+		if err != nil || !c.user.ntfnAllowed(ntfn.Method) {
+			return nil
+		}
+	}
+
 	c.ntfnChan <- marshalledJSON
 	return nil
 }
@@ -1703,13 +1939,13 @@ func (c *wsClient) WaitForShutdown() {
 }
 
 // newWebsocketClient returns a new websocket client given the notification
-// manager, websocket connection, remote address, and whether or not the client
-// has already been authenticated (via HTTP Basic access authentication).  The
-// returned client is ready to start.  Once started, the client will process
-// incoming and outgoing messages in separate goroutines complete with queuing
-// and asynchrous handling for long-running operations.
+// manager, websocket connection, remote address, and the user the client has
+// already been authenticated as (via HTTP Basic access authentication), if
+// any.  The returned client is ready to start.  Once started, the client will
+// process incoming and outgoing messages in separate goroutines complete with
+// queuing and asynchrous handling for long-running operations.
 func newWebsocketClient(server *rpcServer, conn *websocket.Conn,
-	remoteAddr string, authenticated bool, isAdmin bool) (*wsClient, error) {
+	remoteAddr string, user *rpcUser) (*wsClient, error) {
 
 	sessionID, err := wire.RandomUint64()
 	if err != nil {
@@ -1719,8 +1955,8 @@ func newWebsocketClient(server *rpcServer, conn *websocket.Conn,
 	client := &wsClient{
 		conn:              conn,
 		addr:              remoteAddr,
-		authenticated:     authenticated,
-		isAdmin:           isAdmin,
+		authenticated:     user != nil,
+		user:              user,
 		sessionID:         sessionID,
 		server:            server,
 		addrRequests:      make(map[string]struct{}),
@@ -1875,6 +2111,17 @@ func handleNotifyNewTransactions(wsc *wsClient, icmd interface{}) (interface{},
 	return nil, nil
 }
 
+// handleNotifySince implements the notifysince command extension for
+// websocket connections.
+func handleNotifySince(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*bronjson.NotifySinceCmd)
+	if !ok {
+		return nil, bronjson.ErrRPCInternal
+	}
+
+	return wsc.server.ntfnMgr.RegisterEventUpdates(wsc, cmd.Sequence)
+}
+
 // handleStopNotifyNewTransations implements the stopnotifynewtransactions
 // command extension for websocket connections.
 func handleStopNotifyNewTransactions(wsc *wsClient, icmd interface{}) (interface{}, error) {
@@ -0,0 +1,67 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+// TestWSEventBuffer ensures the event buffer returns the events following a
+// sequence number in order and rejects sequence numbers which have not been
+// reached yet or whose following events were evicted.
+func TestWSEventBuffer(t *testing.T) {
+	t.Parallel()
+
+	buf := newWSEventBuffer(3)
+	addEvents := func(n int) {
+		for i := 0; i < n; i++ {
+			event := strconv.FormatUint(buf.lastSeq+1, 10)
+			buf.add([]byte(event))
+		}
+	}
+	checkSince := func(seq uint64, want []string) {
+		t.Helper()
+		events, err := buf.since(seq)
+		if want == nil {
+			if err == nil {
+				t.Fatalf("since(%d): expected error", seq)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("since(%d): unexpected error %v", seq, err)
+		}
+		if len(events) != len(want) {
+			t.Fatalf("since(%d): got %d events, want %d", seq,
+				len(events), len(want))
+		}
+		for i, event := range events {
+			if string(event) != want[i] {
+				t.Fatalf("since(%d): event #%d is %s, want %s",
+					seq, i, event, want[i])
+			}
+		}
+	}
+
+	// All events can be replayed before the buffer is full.
+	checkSince(0, []string{})
+	addEvents(2)
+	checkSince(0, []string{"1", "2"})
+	checkSince(1, []string{"2"})
+	checkSince(2, []string{})
+	checkSince(3, nil)
+
+	// Only the most recent events are kept once it wraps around.
+	addEvents(5)
+	if buf.lastSeq != 7 {
+		t.Fatalf("lastSeq: got %d, want 7", buf.lastSeq)
+	}
+	checkSince(3, nil)
+	checkSince(4, []string{"5", "6", "7"})
+	checkSince(6, []string{"7"})
+	checkSince(7, []string{})
+	checkSince(8, nil)
+}
@@ -6,14 +6,18 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/rand"
 	"crypto/tls"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math"
 	"net"
+	"os"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"strconv"
@@ -28,6 +32,7 @@ import (
 	"github.com/brsuite/brond/chaincfg"
 	"github.com/brsuite/brond/chaincfg/chainhash"
 	"github.com/brsuite/brond/connmgr"
+	"github.com/brsuite/brond/connmgr/eviction"
 	"github.com/brsuite/brond/database"
 	"github.com/brsuite/brond/mempool"
 	"github.com/brsuite/brond/mining"
@@ -36,6 +41,7 @@ import (
 	"github.com/brsuite/brond/peer"
 	"github.com/brsuite/brond/txscript"
 	"github.com/brsuite/brond/wire"
+	"github.com/brsuite/brond/zmqpub"
 	"github.com/brsuite/bronutil"
 	"github.com/brsuite/bronutil/bloom"
 )
@@ -53,10 +59,43 @@ const (
 	// defaultTargetOutbound is the default number of outbound peers to target.
 	defaultTargetOutbound = 8
 
+	// defaultTargetBlockRelay is the default number of block-relay-only
+	// outbound peers to target in addition to the full relay ones.
+	defaultTargetBlockRelay = 2
+
+	// feelerInterval is the interval at which a short-lived feeler
+	// connection is made to an address which hasn't been connected to yet
+	// to test whether it is reachable.
+	feelerInterval = 2 * time.Minute
+
 	// connectionRetryInterval is the base amount of time to wait in between
 	// retries when connecting to persistent peers.  It is adjusted by the
 	// number of retries such that there is a retry backoff.
 	connectionRetryInterval = time.Second * 5
+
+	// maxCmpctBlockDepth is the maximum depth in the main chain of blocks
+	// which are served as compact blocks or via blocktxn messages.  Deeper
+	// blocks are unlikely to be reconstructed from the memory pool of the
+	// peer, so they are served in full as recommended by BIP0152.
+	maxCmpctBlockDepth = 10
+
+	// feeFilterInterval is the interval at which peers are sent a new
+	// feefilter message when the minimum fee rate accepted by the memory
+	// pool has changed significantly since the last one was sent.
+	feeFilterInterval = time.Minute
+
+	// mempoolFilename is the name of the file in the data directory the
+	// transactions in the memory pool are saved to on shutdown.
+	mempoolFilename = "mempool.dat"
+
+	// banListFilename is the name of the file in the data directory the
+	// banned addresses and subnets are stored in.
+	banListFilename = "banlist.json"
+
+	// anchorsFilename is the name of the file in the data directory the
+	// addresses of the block-relay-only peers are saved to on shutdown, so
+	// they can be reconnected to on the next start.
+	anchorsFilename = "anchors.dat"
 )
 
 var (
@@ -151,12 +190,11 @@ type updatePeerHeightsMsg struct {
 }
 
 // peerState maintains state of inbound, persistent, outbound peers as well
-// as banned peers and outbound groups.
+// as outbound groups.
 type peerState struct {
 	inboundPeers    map[int32]*serverPeer
 	outboundPeers   map[int32]*serverPeer
 	persistentPeers map[int32]*serverPeer
-	banned          map[string]time.Time
 	outboundGroups  map[string]int
 }
 
@@ -204,10 +242,12 @@ type server struct {
 	shutdown      int32
 	shutdownSched int32
 	startupTime   int64
+	mempoolLoaded int32
 
 	chainParams          *chaincfg.Params
 	addrManager          *addrmgr.AddrManager
 	connManager          *connmgr.ConnManager
+	banList              *connmgr.BanList
 	sigCache             *txscript.SigCache
 	hashCache            *txscript.HashCache
 	rpcServer            *rpcServer
@@ -242,6 +282,11 @@ type server struct {
 	// the mempool before they are mined into blocks.
 	feeEstimator *mempool.FeeEstimator
 
+	// zmqPublisher publishes block and transaction notifications to
+	// ZeroMQ subscribers.  It is nil when no ZeroMQ endpoints are
+	// configured.
+	zmqPublisher *zmqpub.Publisher
+
 	// cfCheckptCaches stores a cached slice of filter headers for cfcheckpt
 	// messages for each filter type.
 	cfCheckptCaches    map[wire.FilterType][]cfHeaderKV
@@ -254,19 +299,33 @@ type server struct {
 	// agentWhitelist is a list of whitelisted user agent substrings, no
 	// whitelisting will be applied if the list is empty or nil.
 	agentWhitelist []string
+
+	// netGroupKey is a random key the network groups of inbound peers are
+	// hashed with, so the ones protected from eviction can't be predicted.
+	netGroupKey [32]byte
+
+	// v2Addrs are the addresses picked for outbound connections which
+	// advertise support for the v2 transport protocol, while v1Addrs are
+	// the addresses of outbound peers which failed its handshake and are
+	// connected to with the v1 protocol instead.
+	transportMtx sync.Mutex
+	v2Addrs      map[string]struct{}
+	v1Addrs      map[string]struct{}
 }
 
 // serverPeer extends the peer to maintain state shared by the server and
 // the blockmanager.
 type serverPeer struct {
 	// The following variables must only be used atomically
-	feeFilter int64
+	feeFilter     int64
+	sentFeeFilter int64
 
 	*peer.Peer
 
 	connReq        *connmgr.ConnReq
 	server         *server
 	persistent     bool
+	v2Transport    bool
 	continueHash   *chainhash.Hash
 	relayMtx       sync.Mutex
 	disableRelayTx bool
@@ -305,7 +364,7 @@ func (sp *serverPeer) newestBlock() (*chainhash.Hash, int32, error) {
 
 // addKnownAddresses adds the given addresses to the set of known addresses to
 // the peer to prevent sending duplicate addresses.
-func (sp *serverPeer) addKnownAddresses(addresses []*wire.NetAddress) {
+func (sp *serverPeer) addKnownAddresses(addresses []*wire.NetAddressV2) {
 	sp.addressesMtx.Lock()
 	for _, na := range addresses {
 		sp.knownAddresses[addrmgr.NetAddressKey(na)] = struct{}{}
@@ -314,7 +373,7 @@ func (sp *serverPeer) addKnownAddresses(addresses []*wire.NetAddress) {
 }
 
 // addressKnown true if the given address is already known to the peer.
-func (sp *serverPeer) addressKnown(na *wire.NetAddress) bool {
+func (sp *serverPeer) addressKnown(na *wire.NetAddressV2) bool {
 	sp.addressesMtx.RLock()
 	_, exists := sp.knownAddresses[addrmgr.NetAddressKey(na)]
 	sp.addressesMtx.RUnlock()
@@ -330,33 +389,71 @@ func (sp *serverPeer) setDisableRelayTx(disable bool) {
 }
 
 // relayTxDisabled returns whether or not relaying of transactions for the given
-// peer is disabled.
+// peer is disabled.  It is always disabled for block-relay-only peers.
 // It is safe for concurrent access.
 func (sp *serverPeer) relayTxDisabled() bool {
 	sp.relayMtx.Lock()
 	isDisabled := sp.disableRelayTx
 	sp.relayMtx.Unlock()
 
-	return isDisabled
+	return isDisabled || sp.isBlockRelayOnly()
 }
 
-// pushAddrMsg sends an addr message to the connected peer using the provided
-// addresses.
-func (sp *serverPeer) pushAddrMsg(addresses []*wire.NetAddress) {
+// isBlockRelayOnly returns whether the peer is an outbound block-relay-only
+// peer, which transactions and addresses are never relayed to or accepted from.
+func (sp *serverPeer) isBlockRelayOnly() bool {
+	return sp.connReq != nil && sp.connReq.Type == connmgr.ConnBlockRelay
+}
+
+// isFeeler returns whether the peer is an outbound feeler peer, which is only
+// connected to in order to test whether its address is reachable and is
+// disconnected right after the version handshake.
+func (sp *serverPeer) isFeeler() bool {
+	return sp.connReq != nil && sp.connReq.Type == connmgr.ConnFeeler
+}
+
+// pushAddrMsg sends an addr or addrv2 message, depending on which the peer
+// wants, to the connected peer using the provided addresses.  Addresses that
+// can't be represented in an addr message are skipped for peers which don't
+// support addrv2.
+func (sp *serverPeer) pushAddrMsg(addresses []*wire.NetAddressV2) {
 	// Filter addresses already known to the peer.
-	addrs := make([]*wire.NetAddress, 0, len(addresses))
+	addrs := make([]*wire.NetAddressV2, 0, len(addresses))
 	for _, addr := range addresses {
 		if !sp.addressKnown(addr) {
 			addrs = append(addrs, addr)
 		}
 	}
-	known, err := sp.PushAddrMsg(addrs)
+
+	if sp.WantsAddrV2() {
+		known, err := sp.PushAddrV2Msg(addrs)
+		if err != nil {
+			peerLog.Errorf("Can't push addrv2 message to %s: %v",
+				sp.Peer, err)
+			sp.Disconnect()
+			return
+		}
+		sp.addKnownAddresses(known)
+		return
+	}
+
+	legacyAddrs := make([]*wire.NetAddress, 0, len(addrs))
+	for _, addr := range addrs {
+		if legacyAddr, ok := addr.ToLegacy(); ok {
+			legacyAddrs = append(legacyAddrs, legacyAddr)
+		}
+	}
+	known, err := sp.PushAddrMsg(legacyAddrs)
 	if err != nil {
 		peerLog.Errorf("Can't push address message to %s: %v", sp.Peer, err)
 		sp.Disconnect()
 		return
 	}
-	sp.addKnownAddresses(known)
+	knownV2 := make([]*wire.NetAddressV2, 0, len(known))
+	for _, na := range known {
+		knownV2 = append(knownV2, wire.NetAddressV2FromLegacy(na))
+	}
+	sp.addKnownAddresses(knownV2)
 }
 
 // addBanScore increases the persistent and decaying ban score fields by the
@@ -478,6 +575,47 @@ func (sp *serverPeer) OnVersion(_ *peer.Peer, msg *wire.MsgVersion) *wire.MsgRej
 // to kick start communication with them.
 func (sp *serverPeer) OnVerAck(_ *peer.Peer, _ *wire.MsgVerAck) {
 	sp.server.AddPeer(sp)
+
+	// Feeler peers are disconnected by the server once added, so there is
+	// nothing to negotiate with them.
+	if sp.isFeeler() {
+		return
+	}
+
+	// Announce support for compact blocks in low-bandwidth mode.  The sync
+	// manager later switches the peers which relay new blocks the fastest
+	// to high-bandwidth mode.
+	sp.PushSendCmpctMsg(false)
+
+	// Let the peer know the minimum fee rate of transactions that will be
+	// accepted into the memory pool so it doesn't relay any others.
+	sp.pushFeeFilterMsg()
+}
+
+// pushFeeFilterMsg sends a feefilter message advertising the minimum fee rate
+// accepted by the memory pool to the peer.  Since the rolling minimum fee of
+// the memory pool decays continuously, a new message is only sent once the fee
+// rate differs by more than a quarter from the one last sent.
+func (sp *serverPeer) pushFeeFilterMsg() {
+	// Peers which don't support feefilter messages or which transactions
+	// are never accepted from, including all of them when the node doesn't
+	// accept transactions from peers at all, are skipped.
+	if cfg.BlocksOnly || sp.isBlockRelayOnly() ||
+		sp.ProtocolVersion() < wire.FeeFilterVersion {
+
+		return
+	}
+
+	minFee := int64(sp.server.txMemPool.MinFee())
+	sentFee := atomic.LoadInt64(&sp.sentFeeFilter)
+	if minFee == sentFee || (sentFee > 0 && minFee*4 > sentFee*3 &&
+		minFee*3 < sentFee*4) {
+
+		return
+	}
+
+	atomic.StoreInt64(&sp.sentFeeFilter, minFee)
+	sp.QueueMessage(wire.NewMsgFeeFilter(minFee), nil)
 }
 
 // OnMemPool is invoked when a peer receives a mempool brocoin message.
@@ -539,6 +677,15 @@ func (sp *serverPeer) OnTx(_ *peer.Peer, msg *wire.MsgTx) {
 		return
 	}
 
+	// Block-relay-only peers were told not to relay transactions, so they
+	// are misbehaving when they do.
+	if sp.isBlockRelayOnly() {
+		peerLog.Infof("Block-relay-only peer %v sent tx %v -- "+
+			"disconnecting", sp, msg.TxHash())
+		sp.Disconnect()
+		return
+	}
+
 	// Add the transaction to the known inventory for the peer.
 	// Convert the raw MsgTx to a bronutil.Tx which provides some convenience
 	// methods and things such as hash caching.
@@ -581,12 +728,77 @@ func (sp *serverPeer) OnBlock(_ *peer.Peer, msg *wire.MsgBlock, buf []byte) {
 	<-sp.blockProcessed
 }
 
+// OnCmpctBlock is invoked when a peer receives a cmpctblock brocoin message.
+// It blocks until the compact block has been handled by the sync manager,
+// which reconstructs it or requests the missing transactions.
+func (sp *serverPeer) OnCmpctBlock(_ *peer.Peer, msg *wire.MsgCmpctBlock) {
+	// Add the block to the known inventory for the peer.
+	blockHash := msg.Header.BlockHash()
+	iv := wire.NewInvVect(wire.InvTypeBlock, &blockHash)
+	sp.AddKnownInventory(iv)
+
+	sp.server.syncManager.QueueCmpctBlock(msg, sp.Peer, sp.blockProcessed)
+	<-sp.blockProcessed
+}
+
+// OnBlockTxns is invoked when a peer receives a blocktxn brocoin message.  It
+// blocks until the transactions have been handled by the sync manager.
+func (sp *serverPeer) OnBlockTxns(_ *peer.Peer, msg *wire.MsgBlockTxns) {
+	sp.server.syncManager.QueueBlockTxns(msg, sp.Peer, sp.blockProcessed)
+	<-sp.blockProcessed
+}
+
+// OnGetBlockTxns is invoked when a peer receives a getblocktxn brocoin message.
+// The requested transactions of recent blocks are sent in a blocktxn message,
+// while deeper blocks are sent in full.
+func (sp *serverPeer) OnGetBlockTxns(_ *peer.Peer, msg *wire.MsgGetBlockTxns) {
+	version := sp.CmpctBlockVersion()
+	if version == 0 {
+		peerLog.Debugf("Ignoring getblocktxn from %v which did not send "+
+			"sendcmpct", sp)
+		return
+	}
+
+	chain := sp.server.chain
+	height, err := chain.BlockHeightByHash(&msg.BlockHash)
+	if err != nil {
+		peerLog.Debugf("Unable to serve getblocktxn for block %v to "+
+			"%v: %v", msg.BlockHash, sp, err)
+		return
+	}
+	encoding := cmpctBlockEncoding(version)
+	if chain.BestSnapshot().Height-height >= maxCmpctBlockDepth {
+		sp.server.pushBlockMsg(sp, &msg.BlockHash, nil, nil, encoding)
+		return
+	}
+
+	block, err := chain.BlockByHash(&msg.BlockHash)
+	if err != nil {
+		peerLog.Debugf("Unable to fetch block %v for getblocktxn from "+
+			"%v: %v", msg.BlockHash, sp, err)
+		return
+	}
+	txns := block.MsgBlock().Transactions
+	blockTxns := wire.NewMsgBlockTxns(&msg.BlockHash)
+	for _, index := range msg.Indexes {
+		if int(index) >= len(txns) {
+			peerLog.Debugf("Peer %v requested transaction %d of "+
+				"block %v which only has %d transactions", sp,
+				index, msg.BlockHash, len(txns))
+			sp.addBanScore(100, 0, wire.CmdGetBlockTxns)
+			return
+		}
+		blockTxns.AddTransaction(txns[index])
+	}
+	sp.QueueMessageWithEncoding(blockTxns, nil, encoding)
+}
+
 // OnInv is invoked when a peer receives an inv brocoin message and is
 // used to examine the inventory being advertised by the remote peer and react
 // accordingly.  We pass the message down to blockmanager which will call
 // QueueMessage with any appropriate responses.
 func (sp *serverPeer) OnInv(_ *peer.Peer, msg *wire.MsgInv) {
-	if !cfg.BlocksOnly {
+	if !cfg.BlocksOnly && !sp.isBlockRelayOnly() {
 		if len(msg.InvList) > 0 {
 			sp.server.syncManager.QueueInv(msg, sp.Peer)
 		}
@@ -597,7 +809,7 @@ func (sp *serverPeer) OnInv(_ *peer.Peer, msg *wire.MsgInv) {
 	for _, invVect := range msg.InvList {
 		if invVect.Type == wire.InvTypeTx {
 			peerLog.Tracef("Ignoring tx %v in inv from %v -- "+
-				"blocksonly enabled", invVect.Hash, sp)
+				"transaction relay disabled", invVect.Hash, sp)
 			if sp.ProtocolVersion() >= wire.BIP0037Version {
 				peerLog.Infof("Peer %v is announcing "+
 					"transactions -- disconnecting", sp)
@@ -670,6 +882,8 @@ func (sp *serverPeer) OnGetData(_ *peer.Peer, msg *wire.MsgGetData) {
 			err = sp.server.pushMerkleBlockMsg(sp, &iv.Hash, c, waitChan, wire.WitnessEncoding)
 		case wire.InvTypeFilteredBlock:
 			err = sp.server.pushMerkleBlockMsg(sp, &iv.Hash, c, waitChan, wire.BaseEncoding)
+		case wire.InvTypeCmpctBlock:
+			err = sp.server.pushCmpctBlockMsg(sp, &iv.Hash, c, waitChan)
 		default:
 			peerLog.Warnf("Unknown type in inventory request %d",
 				iv.Type)
@@ -1266,7 +1480,46 @@ func (sp *serverPeer) OnAddr(_ *peer.Peer, msg *wire.MsgAddr) {
 		return
 	}
 
+	addrs := make([]*wire.NetAddressV2, 0, len(msg.AddrList))
 	for _, na := range msg.AddrList {
+		addrs = append(addrs, wire.NetAddressV2FromLegacy(na))
+	}
+	sp.addAdvertisedAddresses(addrs)
+}
+
+// OnAddrV2 is invoked when a peer receives an addrv2 brocoin message and is
+// used to notify the server about advertised addresses.
+func (sp *serverPeer) OnAddrV2(_ *peer.Peer, msg *wire.MsgAddrV2) {
+	// Ignore addresses when running on the simulation test network.  This
+	// helps prevent the network from becoming another public test network
+	// since it will not be able to learn about other peers that have not
+	// specifically been provided.
+	if cfg.SimNet {
+		return
+	}
+
+	// A message that has no addresses is invalid.
+	if len(msg.AddrList) == 0 {
+		peerLog.Errorf("Command [%s] from %s does not contain any addresses",
+			msg.Command(), sp.Peer)
+		sp.Disconnect()
+		return
+	}
+
+	sp.addAdvertisedAddresses(msg.AddrList)
+}
+
+// addAdvertisedAddresses adds the addresses advertised by the peer in an addr
+// or addrv2 message to the known addresses of the peer and the server address
+// manager.
+func (sp *serverPeer) addAdvertisedAddresses(addrs []*wire.NetAddressV2) {
+	// Ignore addresses from block-relay-only peers since addresses are not
+	// relayed over those connections.
+	if sp.isBlockRelayOnly() {
+		return
+	}
+
+	for _, na := range addrs {
 		// Don't add more address if we're disconnecting.
 		if !sp.Connected() {
 			return
@@ -1281,7 +1534,7 @@ func (sp *serverPeer) OnAddr(_ *peer.Peer, msg *wire.MsgAddr) {
 		}
 
 		// Add address to known addresses for this peer.
-		sp.addKnownAddresses([]*wire.NetAddress{na})
+		sp.addKnownAddresses([]*wire.NetAddressV2{na})
 	}
 
 	// Add addresses to server address manager.  The address manager handles
@@ -1289,7 +1542,7 @@ func (sp *serverPeer) OnAddr(_ *peer.Peer, msg *wire.MsgAddr) {
 	// addresses, and last seen updates.
 	// XXX brocoind gives a 2 hour time penalty here, do we want to do the
 	// same?
-	sp.server.addrManager.AddAddresses(msg.AddrList, sp.NA())
+	sp.server.addrManager.AddAddresses(addrs, sp.NA())
 }
 
 // OnRead is invoked when a peer receives a message and it is used to update
@@ -1367,6 +1620,50 @@ func (s *server) AnnounceNewTransactions(txns []*mempool.TxDesc) {
 	if s.rpcServer != nil {
 		s.rpcServer.NotifyNewTransactions(txns)
 	}
+
+	// Publish the transactions to ZeroMQ subscribers.
+	if s.zmqPublisher != nil {
+		for _, txD := range txns {
+			s.zmqPublisher.TransactionAccepted(txD.Tx)
+		}
+	}
+}
+
+// TransactionRemoved notifies websocket clients and ZeroMQ subscribers of a
+// transaction which was removed from the mempool.  It is invoked by the
+// mempool with its lock held.
+func (s *server) TransactionRemoved(tx *bronutil.Tx, reason mempool.RemovalReason) {
+	if s.rpcServer != nil {
+		s.rpcServer.ntfnMgr.NotifyMempoolTxRemoved(tx, reason)
+	}
+
+	// ZeroMQ subscribers learn about confirmed transactions from the
+	// connected block instead.
+	if s.zmqPublisher != nil && reason != mempool.RemovedConfirmed {
+		s.zmqPublisher.TransactionRemoved(tx)
+	}
+}
+
+// handleBlockchainNotification publishes blocks connected to and disconnected
+// from the main chain to ZeroMQ subscribers.
+func (s *server) handleBlockchainNotification(notification *blockchain.Notification) {
+	switch notification.Type {
+	case blockchain.NTBlockConnected:
+		block, ok := notification.Data.(*bronutil.Block)
+		if !ok {
+			srvrLog.Warnf("Chain connected notification is not a block.")
+			break
+		}
+		s.zmqPublisher.BlockConnected(block)
+
+	case blockchain.NTBlockDisconnected:
+		block, ok := notification.Data.(*bronutil.Block)
+		if !ok {
+			srvrLog.Warnf("Chain disconnected notification is not a block.")
+			break
+		}
+		s.zmqPublisher.BlockDisconnected(block)
+	}
 }
 
 // Transaction has one confirmation on the main chain. Now we can mark it as no
@@ -1415,6 +1712,22 @@ func (s *server) pushTxMsg(sp *serverPeer, hash *chainhash.Hash, doneChan chan<-
 func (s *server) pushBlockMsg(sp *serverPeer, hash *chainhash.Hash, doneChan chan<- struct{},
 	waitChan <-chan struct{}, encoding wire.MessageEncoding) error {
 
+	// Refuse to serve blocks whose data has been pruned.
+	chain := sp.server.chain
+	if chain.IsPruned() {
+		height, err := chain.BlockHeightByHash(hash)
+		if err == nil && height <= chain.PrunedHeight() {
+			peerLog.Debugf("Unable to serve requested block hash %v "+
+				"to %v: block data has been pruned", hash, sp)
+
+			if doneChan != nil {
+				doneChan <- struct{}{}
+			}
+			return fmt.Errorf("block %v at height %d has been pruned",
+				hash, height)
+		}
+	}
+
 	// Fetch the raw block bytes from the database.
 	var blockBytes []byte
 	err := sp.server.db.View(func(dbTx database.Tx) error {
@@ -1476,6 +1789,63 @@ func (s *server) pushBlockMsg(sp *serverPeer, hash *chainhash.Hash, doneChan cha
 	return nil
 }
 
+// cmpctBlockEncoding returns the encoding of the transactions in compact block
+// related messages for the passed compact block version.
+func cmpctBlockEncoding(version uint64) wire.MessageEncoding {
+	if version >= wire.CmpctBlockVersion2 {
+		return wire.WitnessEncoding
+	}
+	return wire.BaseEncoding
+}
+
+// pushCmpctBlockMsg sends a cmpctblock message for the provided block hash to
+// the connected peer.  Blocks deeper than maxCmpctBlockDepth in the main chain,
+// as well as blocks requested by peers which did not agree on a compact block
+// version, are sent in full instead.  An error is returned if the block hash is
+// not known.
+func (s *server) pushCmpctBlockMsg(sp *serverPeer, hash *chainhash.Hash,
+	doneChan chan<- struct{}, waitChan <-chan struct{}) error {
+
+	version := sp.CmpctBlockVersion()
+	height, err := s.chain.BlockHeightByHash(hash)
+	if version == 0 || err != nil ||
+		s.chain.BestSnapshot().Height-height >= maxCmpctBlockDepth {
+
+		encoding := wire.BaseEncoding
+		if sp.IsWitnessEnabled() {
+			encoding = wire.WitnessEncoding
+		}
+		return s.pushBlockMsg(sp, hash, doneChan, waitChan, encoding)
+	}
+
+	block, err := s.chain.BlockByHash(hash)
+	if err != nil {
+		peerLog.Tracef("Unable to fetch requested block hash %v: %v",
+			hash, err)
+
+		if doneChan != nil {
+			doneChan <- struct{}{}
+		}
+		return err
+	}
+	nonce, err := wire.RandomUint64()
+	if err != nil {
+		if doneChan != nil {
+			doneChan <- struct{}{}
+		}
+		return err
+	}
+	msg := wire.NewMsgCmpctBlockFromBlock(block.MsgBlock(), nonce, version)
+
+	// Once we have fetched data wait for any previous operation to finish.
+	if waitChan != nil {
+		<-waitChan
+	}
+
+	sp.QueueMessageWithEncoding(msg, doneChan, cmpctBlockEncoding(version))
+	return nil
+}
+
 // pushMerkleBlockMsg sends a merkleblock message for the provided block hash to
 // the connected peer.  Since a merkle block requires the peer to have a filter
 // loaded, this call will simply be ignored if there is no filter loaded.  An
@@ -1592,22 +1962,31 @@ func (s *server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 		sp.Disconnect()
 		return false
 	}
-	if banEnd, ok := state.banned[host]; ok {
-		if time.Now().Before(banEnd) {
+	if ip := net.ParseIP(host); ip != nil {
+		if banEnd, banned := s.banList.BannedUntil(ip); banned {
 			srvrLog.Debugf("Peer %s is banned for another %v - disconnecting",
 				host, time.Until(banEnd))
 			sp.Disconnect()
 			return false
 		}
+	}
 
-		srvrLog.Infof("Peer %s is no longer banned", host)
-		delete(state.banned, host)
+	// Feeler peers are only connected to in order to test whether their
+	// address is reachable, so mark it as good now that the handshake has
+	// completed and disconnect.
+	if sp.isFeeler() {
+		srvrLog.Debugf("Feeler peer %s is reachable - disconnecting", sp)
+		s.addrManager.Good(sp.NA())
+		sp.Disconnect()
+		return false
 	}
 
 	// TODO: Check for max peers from a single IP.
 
-	// Limit max number of total peers.
-	if state.Count() >= cfg.MaxPeers {
+	// Limit max number of total peers.  When the limit is reached, an
+	// inbound peer is evicted to make room for a new inbound peer unless
+	// all of them are protected.
+	if state.Count() >= cfg.MaxPeers && !(sp.Inbound() && s.evictInboundPeer(state)) {
 		srvrLog.Infof("Max peers reached [%d] - disconnecting peer %s",
 			cfg.MaxPeers, sp)
 		sp.Disconnect()
@@ -1642,17 +2021,20 @@ func (s *server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 	// remote peer for outbound connections. This is skipped when running on
 	// the simulation test network since it is only intended to connect to
 	// specified peers and actively avoids advertising and connecting to
-	// discovered peers.
+	// discovered peers.  Addresses are neither advertised to nor requested
+	// from block-relay-only peers.
 	if !cfg.SimNet && !sp.Inbound() {
+		relayAddrs := !sp.isBlockRelayOnly()
+
 		// Advertise the local address when the server accepts incoming
 		// connections and it believes itself to be close to the best
 		// known tip.
-		if !cfg.DisableListen && s.syncManager.IsCurrent() {
+		if relayAddrs && !cfg.DisableListen && s.syncManager.IsCurrent() {
 			// Get address that best matches.
 			lna := s.addrManager.GetBestLocalAddress(sp.NA())
 			if addrmgr.IsRoutable(lna) {
 				// Filter addresses the peer already knows about.
-				addresses := []*wire.NetAddress{lna}
+				addresses := []*wire.NetAddressV2{lna}
 				sp.pushAddrMsg(addresses)
 			}
 		}
@@ -1661,7 +2043,7 @@ func (s *server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 		// more and the peer has a protocol version new enough to
 		// include a timestamp with addresses.
 		hasTimestamp := sp.ProtocolVersion() >= wire.NetAddressTimeVersion
-		if s.addrManager.NeedMoreAddresses() && hasTimestamp {
+		if relayAddrs && s.addrManager.NeedMoreAddresses() && hasTimestamp {
 			sp.QueueMessage(wire.NewMsgGetAddr(), nil)
 		}
 
@@ -1672,6 +2054,41 @@ func (s *server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 	return true
 }
 
+// evictInboundPeer selects an inbound peer to evict in order to make room for
+// a new inbound peer, disconnects it and removes it from the peer state.
+// Whitelisted peers are never evicted.  It returns whether a peer was evicted.
+// It is invoked from the peerHandler goroutine.
+func (s *server) evictInboundPeer(state *peerState) bool {
+	candidates := make([]eviction.Candidate, 0, len(state.inboundPeers))
+	for _, sp := range state.inboundPeers {
+		if sp.isWhitelisted || !sp.Connected() {
+			continue
+		}
+		group := addrmgr.GroupKey(sp.NA())
+		groupHash := chainhash.HashB(append(s.netGroupKey[:], group...))
+		candidates = append(candidates, eviction.Candidate{
+			ID:            sp.ID(),
+			NetGroup:      binary.LittleEndian.Uint64(groupHash),
+			ConnTime:      sp.TimeConnected(),
+			MinPing:       time.Duration(sp.MinPingMicros()) * time.Microsecond,
+			LastBlockTime: sp.LastNewBlockTime(),
+			LastTxTime:    sp.LastNewTxTime(),
+			RelayTxs:      !sp.relayTxDisabled(),
+		})
+	}
+
+	id, ok := eviction.SelectPeerToEvict(candidates)
+	if !ok {
+		return false
+	}
+	evicted := state.inboundPeers[id]
+	srvrLog.Infof("Max peers reached [%d] - evicting inbound peer %s",
+		cfg.MaxPeers, evicted)
+	evicted.Disconnect()
+	delete(state.inboundPeers, id)
+	return true
+}
+
 // handleDonePeerMsg deals with peers that have signalled they are done.  It is
 // invoked from the peerHandler goroutine.
 func (s *server) handleDonePeerMsg(state *peerState, sp *serverPeer) {
@@ -1688,11 +2105,30 @@ func (s *server) handleDonePeerMsg(state *peerState, sp *serverPeer) {
 	// our connection manager about the disconnection. This can happen if we
 	// process a peer's `done` message before its `add`.
 	if !sp.Inbound() {
+		// Peers which failed the handshake of the v2 transport
+		// protocol are connected to again with the v1 protocol, since
+		// they likely don't support the v2 one.
+		v2HandshakeFailed := sp.V2HandshakeFailed()
+		if v2HandshakeFailed {
+			srvrLog.Debugf("Falling back to the v1 transport protocol "+
+				"for %s", sp.connReq.Addr)
+			s.transportMtx.Lock()
+			s.v1Addrs[sp.connReq.Addr.String()] = struct{}{}
+			s.transportMtx.Unlock()
+		}
+
 		if sp.persistent {
 			s.connManager.Disconnect(sp.connReq.ID())
 		} else {
 			s.connManager.Remove(sp.connReq.ID())
-			go s.connManager.NewConnReq()
+			if v2HandshakeFailed && !sp.isFeeler() {
+				go s.connManager.Connect(&connmgr.ConnReq{
+					Addr: sp.connReq.Addr,
+					Type: sp.connReq.Type,
+				})
+			} else {
+				s.replaceConnReq(sp.connReq)
+			}
 		}
 	}
 
@@ -1714,20 +2150,72 @@ func (s *server) handleBanPeerMsg(state *peerState, sp *serverPeer) {
 		srvrLog.Debugf("can't split ban peer %s %v", sp.Addr(), err)
 		return
 	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		srvrLog.Debugf("can't ban peer %s without an IP address", host)
+		return
+	}
+	err = s.banList.Ban(connmgr.SingleIPSubnet(ip),
+		time.Now().Add(cfg.BanDuration))
+	if err != nil {
+		srvrLog.Errorf("Unable to save ban of peer %s: %v", host, err)
+	}
 	direction := directionString(sp.Inbound())
 	srvrLog.Infof("Banned peer %s (%s) for %v", host, direction,
 		cfg.BanDuration)
-	state.banned[host] = time.Now().Add(cfg.BanDuration)
 }
 
 // handleRelayInvMsg deals with relaying inventory to peers that are not already
 // known to have it.  It is invoked from the peerHandler goroutine.
 func (s *server) handleRelayInvMsg(state *peerState, msg relayMsg) {
+	// Compact blocks for peers in high-bandwidth mode are only created the
+	// first time they are needed for each compact block version.
+	var block *bronutil.Block
+	cmpctBlocks := make(map[uint64]*wire.MsgCmpctBlock)
+	cmpctBlock := func(version uint64) *wire.MsgCmpctBlock {
+		if cmpctMsg, ok := cmpctBlocks[version]; ok {
+			return cmpctMsg
+		}
+		if block == nil {
+			var err error
+			block, err = s.chain.BlockByHash(&msg.invVect.Hash)
+			if err != nil {
+				peerLog.Debugf("Unable to fetch block %v for "+
+					"compact block relay: %v",
+					msg.invVect.Hash, err)
+				return nil
+			}
+		}
+		nonce, err := wire.RandomUint64()
+		if err != nil {
+			return nil
+		}
+		cmpctBlocks[version] = wire.NewMsgCmpctBlockFromBlock(
+			block.MsgBlock(), nonce, version)
+		return cmpctBlocks[version]
+	}
+
 	state.forAllPeers(func(sp *serverPeer) {
 		if !sp.Connected() {
 			return
 		}
 
+		// If the inventory is a block and the peer asked for new
+		// blocks to be announced via compact blocks, send it one
+		// directly unless the peer already knows about the block.
+		if msg.invVect.Type == wire.InvTypeBlock && sp.WantsCmpctBlocks() {
+			if sp.IsKnownInventory(msg.invVect) {
+				return
+			}
+			version := sp.CmpctBlockVersion()
+			if cmpctMsg := cmpctBlock(version); cmpctMsg != nil {
+				sp.AddKnownInventory(msg.invVect)
+				sp.QueueMessageWithEncoding(cmpctMsg, nil,
+					cmpctBlockEncoding(version))
+				return
+			}
+		}
+
 		// If the inventory is a block and the peer prefers headers,
 		// generate and send a headers message instead of an inventory
 		// message.
@@ -1982,6 +2470,9 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 			OnMemPool:      sp.OnMemPool,
 			OnTx:           sp.OnTx,
 			OnBlock:        sp.OnBlock,
+			OnCmpctBlock:   sp.OnCmpctBlock,
+			OnGetBlockTxns: sp.OnGetBlockTxns,
+			OnBlockTxns:    sp.OnBlockTxns,
 			OnInv:          sp.OnInv,
 			OnHeaders:      sp.OnHeaders,
 			OnGetData:      sp.OnGetData,
@@ -1996,6 +2487,7 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 			OnFilterLoad:   sp.OnFilterLoad,
 			OnGetAddr:      sp.OnGetAddr,
 			OnAddr:         sp.OnAddr,
+			OnAddrV2:       sp.OnAddrV2,
 			OnRead:         sp.OnRead,
 			OnWrite:        sp.OnWrite,
 
@@ -2013,9 +2505,10 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 		UserAgentComments: cfg.UserAgentComments,
 		ChainParams:       sp.server.chainParams,
 		Services:          sp.server.services,
-		DisableRelayTx:    cfg.BlocksOnly,
+		DisableRelayTx:    cfg.BlocksOnly || sp.isBlockRelayOnly() || sp.isFeeler(),
 		ProtocolVersion:   peer.MaxProtocolVersion,
 		TrickleInterval:   cfg.TrickleInterval,
+		V2Transport:       sp.v2Transport,
 	}
 }
 
@@ -2024,8 +2517,20 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 // instance, associates it with the connection, and starts a goroutine to wait
 // for disconnection.
 func (s *server) inboundPeerConnected(conn net.Conn) {
+	// Reject connections from banned addresses right away instead of
+	// going through the version handshake first.
+	if tcpAddr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		if _, banned := s.banList.BannedUntil(tcpAddr.IP); banned {
+			srvrLog.Debugf("Rejecting connection from banned address "+
+				"%s", tcpAddr.IP)
+			conn.Close()
+			return
+		}
+	}
+
 	sp := newServerPeer(s, false)
 	sp.isWhitelisted = isWhitelisted(conn.RemoteAddr())
+	sp.v2Transport = cfg.V2Transport
 	sp.Peer = peer.NewInboundPeer(newPeerConfig(sp))
 	sp.AssociateConnection(conn)
 	go s.peerDoneHandler(sp)
@@ -2038,6 +2543,8 @@ func (s *server) inboundPeerConnected(conn net.Conn) {
 // manager of the attempt.
 func (s *server) outboundPeerConnected(c *connmgr.ConnReq, conn net.Conn) {
 	sp := newServerPeer(s, c.Permanent)
+	sp.connReq = c
+	sp.v2Transport = s.useV2Transport(c)
 	p, err := peer.NewOutboundPeer(newPeerConfig(sp), c.Addr.String())
 	if err != nil {
 		srvrLog.Debugf("Cannot create outbound peer %s: %v", c.Addr, err)
@@ -2045,17 +2552,52 @@ func (s *server) outboundPeerConnected(c *connmgr.ConnReq, conn net.Conn) {
 			s.connManager.Disconnect(c.ID())
 		} else {
 			s.connManager.Remove(c.ID())
-			go s.connManager.NewConnReq()
+			s.replaceConnReq(c)
 		}
 		return
 	}
 	sp.Peer = p
-	sp.connReq = c
 	sp.isWhitelisted = isWhitelisted(conn.RemoteAddr())
 	sp.AssociateConnection(conn)
 	go s.peerDoneHandler(sp)
 }
 
+// replaceConnReq requests a new outbound connection of the same type as the
+// passed non-permanent connection request which was removed.  Feeler
+// connections are not replaced since they are made periodically.
+func (s *server) replaceConnReq(c *connmgr.ConnReq) {
+	switch c.Type {
+	case connmgr.ConnFullRelay:
+		go s.connManager.NewConnReq()
+	case connmgr.ConnBlockRelay:
+		go s.connManager.NewBlockRelayConnReq()
+	}
+}
+
+// useV2Transport returns whether to attempt the v2 transport protocol for the
+// passed outbound connection request.  It is attempted with addresses which
+// advertise support for it and with permanent peers, unless the address failed
+// its handshake before.  Non-permanent peers fall back to the v1 protocol for
+// a single connection.
+func (s *server) useV2Transport(c *connmgr.ConnReq) bool {
+	if !cfg.V2Transport {
+		return false
+	}
+
+	addr := c.Addr.String()
+	s.transportMtx.Lock()
+	defer s.transportMtx.Unlock()
+	_, advertised := s.v2Addrs[addr]
+	delete(s.v2Addrs, addr)
+	if _, ok := s.v1Addrs[addr]; ok {
+		if !c.Permanent {
+			delete(s.v1Addrs, addr)
+		}
+		return false
+	}
+	return advertised || c.Permanent
+}
+
 // peerDoneHandler handles peer disconnects by notifiying the server that it's
 // done along with other performing other desirable cleanup.
 func (s *server) peerDoneHandler(sp *serverPeer) {
@@ -2063,7 +2605,8 @@ func (s *server) peerDoneHandler(sp *serverPeer) {
 	s.donePeers <- sp
 
 	// Only tell sync manager we are gone if we ever told it we existed.
-	if sp.VerAckReceived() {
+	// Feeler peers are never added to it.
+	if sp.VerAckReceived() && !sp.isFeeler() {
 		s.syncManager.DonePeer(sp.Peer)
 
 		// Evict any remaining orphans that were sent by the peer.
@@ -2095,14 +2638,13 @@ func (s *server) peerHandler() {
 		inboundPeers:    make(map[int32]*serverPeer),
 		persistentPeers: make(map[int32]*serverPeer),
 		outboundPeers:   make(map[int32]*serverPeer),
-		banned:          make(map[string]time.Time),
 		outboundGroups:  make(map[string]int),
 	}
 
 	if !cfg.DisableDNSSeed {
 		// Add peers discovered through DNS to the address manager.
 		connmgr.SeedFromDNS(activeNetParams.Params, defaultRequiredServices,
-			brondLookup, func(addrs []*wire.NetAddress) {
+			brondLookup, func(addrs []*wire.NetAddressV2) {
 				// Brocoind uses a lookup of the dns seeder here. This
 				// is rather strange since the values looked up by the
 				// DNS seed lookups will vary quite a lot.
@@ -2113,6 +2655,8 @@ func (s *server) peerHandler() {
 	}
 	go s.connManager.Start()
 
+	feeFilterTicker := time.NewTicker(feeFilterInterval)
+
 out:
 	for {
 		select {
@@ -2144,7 +2688,22 @@ out:
 		case qmsg := <-s.query:
 			s.handleQuery(state, qmsg)
 
+		// Advertise changes to the minimum fee rate accepted by the
+		// memory pool to the connected peers.
+		case <-feeFilterTicker.C:
+			state.forAllPeers(func(sp *serverPeer) {
+				if sp.Connected() {
+					sp.pushFeeFilterMsg()
+				}
+			})
+
 		case <-s.quit:
+			// Save the addresses of the block-relay-only peers so
+			// they are reconnected to on the next start.
+			if err := saveAnchors(state); err != nil {
+				srvrLog.Errorf("Unable to save anchors: %v", err)
+			}
+
 			// Disconnect all peers on server shutdown.
 			state.forAllPeers(func(sp *serverPeer) {
 				srvrLog.Tracef("Shutdown peer %s", sp)
@@ -2154,6 +2713,7 @@ out:
 		}
 	}
 
+	feeFilterTicker.Stop()
 	s.connManager.Stop()
 	s.syncManager.Stop()
 	s.addrManager.Stop()
@@ -2219,6 +2779,73 @@ func (s *server) OutboundGroupCount(key string) int {
 	return <-replyChan
 }
 
+// pickOutboundAddress returns an address to make an outbound connection to
+// from those returned by the passed address manager function.  Addresses in
+// the same network group as an existing outbound peer are skipped, and recently
+// attempted addresses and addresses with a nondefault port are only accepted
+// after a number of tries.  An attempt is marked for the returned address.
+func (s *server) pickOutboundAddress(getAddress func() *addrmgr.KnownAddress) (net.Addr, error) {
+	for tries := 0; tries < 100; tries++ {
+		addr := getAddress()
+		if addr == nil {
+			break
+		}
+
+		// Address will not be invalid, local or unroutable
+		// because addrmanager rejects those on addition.
+		// Just check that we don't already have an address
+		// in the same group so that we are not connecting
+		// to the same network segment at the expense of
+		// others.
+		key := addrmgr.GroupKey(addr.NetAddress())
+		if s.OutboundGroupCount(key) != 0 {
+			continue
+		}
+
+		// Outbound connections to I2P and CJDNS peers are
+		// not supported, so only relay their addresses.
+		switch addr.NetAddress().NetworkID {
+		case wire.NetI2P, wire.NetCJDNS:
+			continue
+		}
+
+		// only allow recent nodes (10mins) after we failed 30
+		// times
+		if tries < 30 && time.Since(addr.LastAttempt()) < 10*time.Minute {
+			continue
+		}
+
+		// allow nondefault ports after 50 failed tries.
+		if tries < 50 && fmt.Sprintf("%d", addr.NetAddress().Port) !=
+			activeNetParams.DefaultPort {
+			continue
+		}
+
+		// Mark an attempt for the valid address.
+		s.addrManager.Attempt(addr.NetAddress())
+
+		addrString := addrmgr.NetAddressKey(addr.NetAddress())
+		netAddr, err := addrStringToNetAddr(addrString)
+		if err != nil {
+			return nil, err
+		}
+
+		// Remember whether the address advertises support for the v2
+		// transport protocol for when the connection is established.
+		s.transportMtx.Lock()
+		if addr.Services()&wire.SFNodeP2PV2 != 0 {
+			s.v2Addrs[netAddr.String()] = struct{}{}
+		} else {
+			delete(s.v2Addrs, netAddr.String())
+		}
+		s.transportMtx.Unlock()
+
+		return netAddr, nil
+	}
+
+	return nil, errors.New("no valid connect address")
+}
+
 // AddBytesSent adds the passed number of bytes to the total bytes sent counter
 // for the server.  It is safe for concurrent access.
 func (s *server) AddBytesSent(bytesSent uint64) {
@@ -2308,6 +2935,131 @@ cleanup:
 	s.wg.Done()
 }
 
+// saveMempool writes the transactions in the memory pool to the mempool file
+// in the data directory.  The transactions are written to a temporary file
+// first which then replaces the existing file, so that an interrupted save
+// doesn't leave a truncated file behind.
+//
+// The memory pool is not saved before the transactions saved on the last
+// shutdown have been reloaded since they would otherwise be lost.
+func (s *server) saveMempool() error {
+	if atomic.LoadInt32(&s.mempoolLoaded) == 0 {
+		return errors.New("the memory pool has not been loaded yet")
+	}
+
+	path := filepath.Join(cfg.DataDir, mempoolFilename)
+	tmpPath := path + ".new"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = s.txMemPool.Save(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// loadMempool reloads the transactions from the mempool file in the data
+// directory into the memory pool.  Loading is stopped early when the interrupt
+// channel is closed.
+func (s *server) loadMempool(interrupt <-chan struct{}) (*mempool.LoadStats, error) {
+	f, err := os.Open(filepath.Join(cfg.DataDir, mempoolFilename))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return s.txMemPool.Load(bufio.NewReader(f), interrupt)
+}
+
+// saveAnchors writes the addresses of the connected block-relay-only peers to
+// the anchors file in the data directory, one per line, so they can be
+// reconnected to on the next start.  Nothing is written when there are none.
+func saveAnchors(state *peerState) error {
+	var anchors []string
+	state.forAllOutboundPeers(func(sp *serverPeer) {
+		if sp.isBlockRelayOnly() && sp.Connected() {
+			anchors = append(anchors, sp.connReq.Addr.String())
+		}
+	})
+	if len(anchors) == 0 {
+		return nil
+	}
+
+	path := filepath.Join(cfg.DataDir, anchorsFilename)
+	data := []byte(strings.Join(anchors, "\n") + "\n")
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// loadAnchors reads the addresses saved to the anchors file in the data
+// directory on the last shutdown and removes the file.  Removing it ensures
+// the same anchors aren't reconnected to over and over should the node keep
+// crashing before it is shut down cleanly.  Addresses which can't be parsed
+// are skipped.
+func loadAnchors() ([]net.Addr, error) {
+	path := filepath.Join(cfg.DataDir, anchorsFilename)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := os.Remove(path); err != nil {
+		return nil, err
+	}
+
+	var anchors []net.Addr
+	for _, line := range strings.Fields(string(data)) {
+		addr, err := addrStringToNetAddr(line)
+		if err != nil {
+			srvrLog.Warnf("Skipping invalid anchor %q: %v", line, err)
+			continue
+		}
+		anchors = append(anchors, addr)
+	}
+	return anchors, nil
+}
+
+// mempoolLoadHandler reloads the transactions saved to the mempool file on the
+// last shutdown.  It runs in the background since validating the transactions
+// may take a while.
+//
+// It MUST be run as a goroutine.
+func (s *server) mempoolLoadHandler() {
+	stats, err := s.loadMempool(s.quit)
+	switch {
+	case os.IsNotExist(err):
+		atomic.StoreInt32(&s.mempoolLoaded, 1)
+
+	case err != nil:
+		// The file is left in place so it isn't replaced by the
+		// transactions received until shutdown.
+		srvrLog.Errorf("Unable to load the memory pool: %v", err)
+
+	default:
+		srvrLog.Infof("Loaded %d transactions into the memory pool "+
+			"(%d failed, %d expired)", stats.Loaded, stats.Failed,
+			stats.Expired)
+		atomic.StoreInt32(&s.mempoolLoaded, 1)
+	}
+
+	s.wg.Done()
+}
+
 // Start begins accepting connections from peers.
 func (s *server) Start() {
 	// Already started?
@@ -2330,6 +3082,9 @@ func (s *server) Start() {
 		go s.upnpUpdateThread()
 	}
 
+	s.wg.Add(1)
+	go s.mempoolLoadHandler()
+
 	if !cfg.DisableRPC {
 		s.wg.Add(1)
 
@@ -2340,6 +3095,11 @@ func (s *server) Start() {
 		s.rpcServer.Start()
 	}
 
+	// Start the ZeroMQ publisher if any endpoints are configured.
+	if s.zmqPublisher != nil {
+		s.zmqPublisher.Start()
+	}
+
 	// Start the CPU miner if generation is enabled.
 	if cfg.Generate {
 		s.cpuMiner.Start()
@@ -2365,6 +3125,26 @@ func (s *server) Stop() error {
 		s.rpcServer.Stop()
 	}
 
+	// Shutdown the ZeroMQ publisher if it's enabled.
+	if s.zmqPublisher != nil {
+		s.zmqPublisher.Stop()
+	}
+
+	// Save the transactions in the memory pool so they can be reloaded on
+	// the next start.  Nothing is saved when they weren't loaded on this
+	// start, for example because the server is stopped while loading them.
+	if atomic.LoadInt32(&s.mempoolLoaded) != 0 {
+		if err := s.saveMempool(); err != nil {
+			srvrLog.Errorf("Unable to save the memory pool: %v", err)
+		}
+	}
+
+	// Write the changes to the utxo set that are only held in memory to the
+	// database so it does not have to be rebuilt on the next start.
+	if err := s.chain.FlushUtxoCache(); err != nil {
+		srvrLog.Errorf("Unable to flush the utxo cache: %v", err)
+	}
+
 	// Save fee estimator state in the database.
 	s.db.Update(func(tx database.Tx) error {
 		metadata := tx.Metadata()
@@ -2495,7 +3275,7 @@ out:
 					srvrLog.Warnf("UPnP can't get external address: %v", err)
 					continue out
 				}
-				na := wire.NewNetAddressIPPort(externalip, uint16(listenPort),
+				na := wire.NewNetAddressV2IPPort(externalip, uint16(listenPort),
 					s.services)
 				err = s.addrManager.AddLocalAddress(na, addrmgr.UpnpPrio)
 				if err != nil {
@@ -2584,9 +3364,24 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 	if cfg.NoCFilters {
 		services &^= wire.SFNodeCF
 	}
+	// A database whose blocks have been pruned can't be opened without
+	// --prune, so all blocks are available when it is not set.
+	if cfg.Prune != 0 {
+		services &^= wire.SFNodeNetwork
+		services |= wire.SFNodeNetworkLimited
+	}
+	if cfg.V2Transport {
+		services |= wire.SFNodeP2PV2
+	}
 
 	amgr := addrmgr.New(cfg.DataDir, brondLookup)
 
+	banList, err := connmgr.NewBanList(filepath.Join(cfg.DataDir,
+		banListFilename))
+	if err != nil {
+		return nil, err
+	}
+
 	var listeners []net.Listener
 	var nat NAT
 	if !cfg.DisableListen {
@@ -2610,6 +3405,7 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 	s := server{
 		chainParams:          chainParams,
 		addrManager:          amgr,
+		banList:              banList,
 		newPeers:             make(chan *serverPeer, cfg.MaxPeers),
 		donePeers:            make(chan *serverPeer, cfg.MaxPeers),
 		banPeers:             make(chan *serverPeer, cfg.MaxPeers),
@@ -2628,6 +3424,11 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		cfCheckptCaches:      make(map[wire.FilterType][]cfHeaderKV),
 		agentBlacklist:       agentBlacklist,
 		agentWhitelist:       agentWhitelist,
+		v2Addrs:              make(map[string]struct{}),
+		v1Addrs:              make(map[string]struct{}),
+	}
+	if _, err := rand.Read(s.netGroupKey[:]); err != nil {
+		return nil, err
 	}
 
 	// Create the transaction and address indexes if needed.
@@ -2675,16 +3476,17 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 	}
 
 	// Create a new block chain instance with the appropriate configuration.
-	var err error
 	s.chain, err = blockchain.New(&blockchain.Config{
-		DB:           s.db,
-		Interrupt:    interrupt,
-		ChainParams:  s.chainParams,
-		Checkpoints:  checkpoints,
-		TimeSource:   s.timeSource,
-		SigCache:     s.sigCache,
-		IndexManager: indexManager,
-		HashCache:    s.hashCache,
+		DB:               s.db,
+		Interrupt:        interrupt,
+		ChainParams:      s.chainParams,
+		Checkpoints:      checkpoints,
+		TimeSource:       s.timeSource,
+		SigCache:         s.sigCache,
+		IndexManager:     indexManager,
+		HashCache:        s.hashCache,
+		Prune:            cfg.Prune * 1024 * 1024,
+		UtxoCacheMaxSize: uint64(cfg.UtxoCacheMaxSizeMiB) * 1024 * 1024,
 	})
 	if err != nil {
 		return nil, err
@@ -2713,11 +3515,10 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 	})
 
 	// If no feeEstimator has been found, or if the one that has been found
-	// is behind somehow, create a new one and start over.
-	if s.feeEstimator == nil || s.feeEstimator.LastKnownHeight() != s.chain.BestSnapshot().Height {
-		s.feeEstimator = mempool.NewFeeEstimator(
-			mempool.DefaultEstimateFeeMaxRollback,
-			mempool.DefaultEstimateFeeMinRegisteredBlocks)
+	// is ahead of the chain somehow, create a new one and start over.  One
+	// which is behind simply continues with the next block registered.
+	if s.feeEstimator == nil || s.feeEstimator.LastKnownHeight() > s.chain.BestSnapshot().Height {
+		s.feeEstimator = mempool.NewFeeEstimator()
 	}
 
 	txC := mempool.Config{
@@ -2731,6 +3532,7 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 			MinRelayTxFee:        cfg.minRelayTxFee,
 			MaxTxVersion:         2,
 			RejectReplacement:    cfg.RejectReplacement,
+			MaxPoolSize:          int64(cfg.MaxMempool) * 1024 * 1024,
 		},
 		ChainParams:    chainParams,
 		FetchUtxoView:  s.chain.FetchUtxoView,
@@ -2744,6 +3546,7 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		HashCache:          s.hashCache,
 		AddrIndex:          s.addrIndex,
 		FeeEstimator:       s.feeEstimator,
+		OnTxRemoved:        s.TransactionRemoved,
 	}
 	s.txMemPool = mempool.New(&txC)
 
@@ -2791,46 +3594,23 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 	// specified peers and actively avoid advertising and connecting to
 	// discovered peers in order to prevent it from becoming a public test
 	// network.
-	var newAddressFunc func() (net.Addr, error)
+	//
+	// Feeler connections test whether addresses which haven't been
+	// connected to yet are reachable, so their addresses are only picked
+	// from those.
+	var newAddressFunc, feelerAddressFunc func() (net.Addr, error)
+	var anchors []net.Addr
 	if !cfg.SimNet && len(cfg.ConnectPeers) == 0 {
 		newAddressFunc = func() (net.Addr, error) {
-			for tries := 0; tries < 100; tries++ {
-				addr := s.addrManager.GetAddress()
-				if addr == nil {
-					break
-				}
-
-				// Address will not be invalid, local or unroutable
-				// because addrmanager rejects those on addition.
-				// Just check that we don't already have an address
-				// in the same group so that we are not connecting
-				// to the same network segment at the expense of
-				// others.
-				key := addrmgr.GroupKey(addr.NetAddress())
-				if s.OutboundGroupCount(key) != 0 {
-					continue
-				}
-
-				// only allow recent nodes (10mins) after we failed 30
-				// times
-				if tries < 30 && time.Since(addr.LastAttempt()) < 10*time.Minute {
-					continue
-				}
-
-				// allow nondefault ports after 50 failed tries.
-				if tries < 50 && fmt.Sprintf("%d", addr.NetAddress().Port) !=
-					activeNetParams.DefaultPort {
-					continue
-				}
-
-				// Mark an attempt for the valid address.
-				s.addrManager.Attempt(addr.NetAddress())
-
-				addrString := addrmgr.NetAddressKey(addr.NetAddress())
-				return addrStringToNetAddr(addrString)
-			}
+			return s.pickOutboundAddress(s.addrManager.GetAddress)
+		}
+		feelerAddressFunc = func() (net.Addr, error) {
+			return s.pickOutboundAddress(s.addrManager.GetNewTableAddress)
+		}
 
-			return nil, errors.New("no valid connect address")
+		anchors, err = loadAnchors()
+		if err != nil {
+			srvrLog.Errorf("Unable to load anchors: %v", err)
 		}
 	}
 
@@ -2839,14 +3619,22 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 	if cfg.MaxPeers < targetOutbound {
 		targetOutbound = cfg.MaxPeers
 	}
+	targetBlockRelay := defaultTargetBlockRelay
+	if cfg.MaxPeers-targetOutbound < targetBlockRelay {
+		targetBlockRelay = cfg.MaxPeers - targetOutbound
+	}
 	cmgr, err := connmgr.New(&connmgr.Config{
-		Listeners:      listeners,
-		OnAccept:       s.inboundPeerConnected,
-		RetryDuration:  connectionRetryInterval,
-		TargetOutbound: uint32(targetOutbound),
-		Dial:           brondDial,
-		OnConnection:   s.outboundPeerConnected,
-		GetNewAddress:  newAddressFunc,
+		Listeners:        listeners,
+		OnAccept:         s.inboundPeerConnected,
+		RetryDuration:    connectionRetryInterval,
+		TargetOutbound:   uint32(targetOutbound),
+		TargetBlockRelay: uint32(targetBlockRelay),
+		Anchors:          anchors,
+		Dial:             brondDial,
+		OnConnection:     s.outboundPeerConnected,
+		GetNewAddress:    newAddressFunc,
+		FeelerInterval:   feelerInterval,
+		GetFeelerAddress: feelerAddressFunc,
 	})
 	if err != nil {
 		return nil, err
@@ -2891,12 +3679,16 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 			ChainParams:  chainParams,
 			DB:           db,
 			TxMemPool:    s.txMemPool,
+			SaveMempool:  s.saveMempool,
+			LoadMempool:  s.loadMempool,
 			Generator:    blockTemplateGenerator,
 			CPUMiner:     s.cpuMiner,
 			TxIndex:      s.txIndex,
 			AddrIndex:    s.addrIndex,
 			CfIndex:      s.cfIndex,
 			FeeEstimator: s.feeEstimator,
+			AddrMgr:      s.addrManager,
+			Services:     s.services,
 		})
 		if err != nil {
 			return nil, err
@@ -2909,6 +3701,30 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		}()
 	}
 
+	// Setup the ZeroMQ publisher when any of its endpoints are configured.
+	zmqEndpoints := make(map[string]string)
+	for topic, endpoint := range map[string]string{
+		zmqpub.TopicHashBlock: cfg.ZMQPubHashBlock,
+		zmqpub.TopicHashTx:    cfg.ZMQPubHashTx,
+		zmqpub.TopicRawBlock:  cfg.ZMQPubRawBlock,
+		zmqpub.TopicRawTx:     cfg.ZMQPubRawTx,
+		zmqpub.TopicSequence:  cfg.ZMQPubSequence,
+	} {
+		if endpoint != "" {
+			zmqEndpoints[topic] = endpoint
+		}
+	}
+	if len(zmqEndpoints) > 0 {
+		s.zmqPublisher, err = zmqpub.New(&zmqpub.Config{
+			Endpoints:     zmqEndpoints,
+			HighWaterMark: cfg.ZMQPubHWM,
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.chain.Subscribe(s.handleBlockchainNotification)
+	}
+
 	return &s, nil
 }
 
@@ -3069,7 +3885,7 @@ func addLocalAddress(addrMgr *addrmgr.AddrManager, addr string, services wire.Se
 				continue
 			}
 
-			netAddr := wire.NewNetAddressIPPort(ifaceIP, uint16(port), services)
+			netAddr := wire.NewNetAddressV2IPPort(ifaceIP, uint16(port), services)
 			addrMgr.AddLocalAddress(netAddr, addrmgr.BoundPrio)
 		}
 	} else {
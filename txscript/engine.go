@@ -94,6 +94,23 @@ const (
 	// operation whose public key isn't serialized in a compressed format
 	// non-standard.
 	ScriptVerifyWitnessPubKeyType
+
+	// ScriptVerifyTaproot defines whether or not to verify a transaction
+	// output spending a version 1 witness program according to the
+	// taproot rules.  This is BIP0341 and BIP0342.
+	ScriptVerifyTaproot
+
+	// ScriptVerifyDiscourageUpgradeableTaprootVersion makes taproot script
+	// path spends using an unknown leaf version non-standard.
+	ScriptVerifyDiscourageUpgradeableTaprootVersion
+
+	// ScriptVerifyDiscourageOpSuccess makes tapscripts containing any of
+	// the OP_SUCCESSx opcodes non-standard.
+	ScriptVerifyDiscourageOpSuccess
+
+	// ScriptVerifyDiscourageUpgradeablePubkeyType makes signature checks
+	// within tapscripts using public keys of an unknown type non-standard.
+	ScriptVerifyDiscourageUpgradeablePubkeyType
 )
 
 const (
@@ -111,6 +128,10 @@ const (
 	// payToWitnessScriptHashDataSize is the size of the witness program's
 	// data push for a pay-to-witness-script-hash output.
 	payToWitnessScriptHashDataSize = 32
+
+	// payToTaprootDataSize is the size of the witness program's data push
+	// for a pay-to-taproot output.
+	payToTaprootDataSize = 32
 )
 
 // halforder is used to tame ECDSA malleability (see BIP0062).
@@ -136,6 +157,8 @@ type Engine struct {
 	witnessVersion  int
 	witnessProgram  []byte
 	inputAmount     int64
+	prevOutFetcher  PrevOutputFetcher
+	taprootCtx      *taprootExecutionCtx // set when spending taproot
 }
 
 // hasFlag returns whether the script engine instance has the passed flag set.
@@ -173,9 +196,10 @@ func (vm *Engine) executeOpcode(pop *parsedOpcode) error {
 	}
 
 	// Note that this includes OP_RESERVED which counts as a push operation.
+	// The operation limit does not apply to tapscripts.
 	if pop.opcode.value > OP_16 {
 		vm.numOps++
-		if vm.numOps > MaxOpsPerScript {
+		if vm.numOps > MaxOpsPerScript && !vm.isTapscript() {
 			str := fmt.Sprintf("exceeded max operation limit of %d",
 				MaxOpsPerScript)
 			return scriptError(ErrTooManyOperations, str)
@@ -249,10 +273,17 @@ func (vm *Engine) isWitnessVersionActive(version uint) bool {
 	return vm.witnessProgram != nil && uint(vm.witnessVersion) == version
 }
 
+// isTapscript returns true if the engine is executing a tapscript as part of a
+// taproot script path spend.
+func (vm *Engine) isTapscript() bool {
+	return vm.taprootCtx != nil && vm.taprootCtx.tapLeafHash != nil
+}
+
 // verifyWitnessProgram validates the stored witness program using the passed
 // witness as input.
 func (vm *Engine) verifyWitnessProgram(witness [][]byte) error {
-	if vm.isWitnessVersionActive(0) {
+	switch {
+	case vm.isWitnessVersionActive(0):
 		switch len(vm.witnessProgram) {
 		case payToWitnessPubKeyHashDataSize: // P2WKH
 			// The witness stack should consist of exactly two
@@ -329,11 +360,20 @@ func (vm *Engine) verifyWitnessProgram(witness [][]byte) error {
 				len(vm.witnessProgram))
 			return scriptError(ErrWitnessProgramWrongLength, errStr)
 		}
-	} else if vm.hasFlag(ScriptVerifyDiscourageUpgradeableWitnessProgram) {
+
+	// Version 1 witness programs of the expected size which are not nested
+	// within a pay-to-script-hash are taproot outputs.
+	case vm.hasFlag(ScriptVerifyTaproot) && vm.isWitnessVersionActive(1) &&
+		len(vm.witnessProgram) == payToTaprootDataSize && !vm.bip16:
+
+		return vm.verifyTaprootSpend(witness)
+
+	case vm.hasFlag(ScriptVerifyDiscourageUpgradeableWitnessProgram):
 		errStr := fmt.Sprintf("new witness program versions "+
 			"invalid: %v", vm.witnessProgram)
 		return scriptError(ErrDiscourageUpgradableWitnessProgram, errStr)
-	} else {
+
+	default:
 		// If we encounter an unknown witness program version and we
 		// aren't discouraging future unknown witness based soft-forks,
 		// then we de-activate the segwit behavior within the VM for
@@ -396,6 +436,20 @@ func (vm *Engine) CheckErrorCondition(finalScript bool) error {
 			"error check when script unfinished")
 	}
 
+	// Taproot key path spends and tapscripts containing an OP_SUCCESSx
+	// opcode succeed without any further checks.
+	if vm.taprootCtx != nil && vm.taprootCtx.mustSucceed {
+		return nil
+	}
+
+	// Tapscripts, like version zero witness programs, require a clean
+	// stack once the final script is done.
+	if finalScript && vm.isTapscript() && vm.dstack.Depth() != 1 {
+		str := fmt.Sprintf("tapscript must have clean stack, has %d "+
+			"items", vm.dstack.Depth())
+		return scriptError(ErrCleanStack, str)
+	}
+
 	// If we're in version zero witness execution mode, and this was the
 	// final script, then the stack MUST be clean in order to maintain
 	// compatibility with BIP16.
@@ -852,8 +906,13 @@ func (vm *Engine) SetAltStack(data [][]byte) {
 // NewEngine returns a new script engine for the provided public key script,
 // transaction, and input index.  The flags modify the behavior of the script
 // engine according to the description provided by each flag.
+//
+// The fetcher supplies the outputs spent by the transaction, which are needed
+// to validate taproot spends.  It may be nil when ScriptVerifyTaproot is not
+// set or the transaction does not spend any taproot outputs.
 func NewEngine(scriptPubKey []byte, tx *wire.MsgTx, txIdx int, flags ScriptFlags,
-	sigCache *SigCache, hashCache *TxSigHashes, inputAmount int64) (*Engine, error) {
+	sigCache *SigCache, hashCache *TxSigHashes, inputAmount int64,
+	prevOutFetcher PrevOutputFetcher) (*Engine, error) {
 
 	// The provided transaction input index must refer to a valid input.
 	if txIdx < 0 || txIdx >= len(tx.TxIn) {
@@ -883,7 +942,7 @@ func NewEngine(scriptPubKey []byte, tx *wire.MsgTx, txIdx int, flags ScriptFlags
 	// when it should be. The same goes for segwit which will pull in
 	// additional scripts for execution from the witness stack.
 	vm := Engine{flags: flags, sigCache: sigCache, hashCache: hashCache,
-		inputAmount: inputAmount}
+		inputAmount: inputAmount, prevOutFetcher: prevOutFetcher}
 	if vm.hasFlag(ScriptVerifyCleanStack) && (!vm.hasFlag(ScriptBip16) &&
 		!vm.hasFlag(ScriptVerifyWitness)) {
 		return nil, scriptError(ErrInvalidFlags,
@@ -338,6 +338,53 @@ const (
 	// serialized in a compressed format.
 	ErrWitnessPubKeyType
 
+	// ----------------------------------------
+	// Failures related to taproot.
+	// ----------------------------------------
+
+	// ErrDiscourageUpgradeableTaprootVersion is returned if
+	// ScriptVerifyDiscourageUpgradeableTaprootVersion is set and a taproot
+	// script path spend uses an unknown leaf version.
+	ErrDiscourageUpgradeableTaprootVersion
+
+	// ErrDiscourageOpSuccess is returned if ScriptVerifyDiscourageOpSuccess
+	// is set and a tapscript contains an OP_SUCCESSx opcode.
+	ErrDiscourageOpSuccess
+
+	// ErrDiscourageUpgradeablePubKeyType is returned if
+	// ScriptVerifyDiscourageUpgradeablePubkeyType is set and a signature
+	// check within a tapscript uses a public key of an unknown type.
+	ErrDiscourageUpgradeablePubKeyType
+
+	// ErrTapscriptCheckMultisig is returned if OP_CHECKMULTISIG or
+	// OP_CHECKMULTISIGVERIFY is executed within a tapscript.
+	ErrTapscriptCheckMultisig
+
+	// ErrTaprootSigInvalid is returned if a taproot key path spend or a
+	// non-empty signature checked within a tapscript is not valid.
+	ErrTaprootSigInvalid
+
+	// ErrInvalidTaprootSigLen is returned if a taproot signature is neither
+	// 64 nor 65 bytes, or is 65 bytes with an explicit SigHashDefault.
+	ErrInvalidTaprootSigLen
+
+	// ErrTaprootPubkeyIsEmpty is returned if a signature check within a
+	// tapscript uses an empty public key.
+	ErrTaprootPubkeyIsEmpty
+
+	// ErrTaprootMaxSigOps is returned if the signature checks within a
+	// tapscript exceed the budget granted by the size of the witness.
+	ErrTaprootMaxSigOps
+
+	// ErrControlBlockInvalidLength is returned if the control block of a
+	// taproot script path spend has an invalid length.
+	ErrControlBlockInvalidLength
+
+	// ErrTaprootMerkleProofInvalid is returned if the control block of a
+	// taproot script path spend does not prove the script is committed to
+	// by the output key.
+	ErrTaprootMerkleProofInvalid
+
 	// numErrorCodes is the maximum error code number used in tests.  This
 	// entry MUST be the last entry in the enum.
 	numErrorCodes
@@ -345,70 +392,80 @@ const (
 
 // Map of ErrorCode values back to their constant names for pretty printing.
 var errorCodeStrings = map[ErrorCode]string{
-	ErrInternal:                           "ErrInternal",
-	ErrInvalidFlags:                       "ErrInvalidFlags",
-	ErrInvalidIndex:                       "ErrInvalidIndex",
-	ErrUnsupportedAddress:                 "ErrUnsupportedAddress",
-	ErrNotMultisigScript:                  "ErrNotMultisigScript",
-	ErrTooManyRequiredSigs:                "ErrTooManyRequiredSigs",
-	ErrTooMuchNullData:                    "ErrTooMuchNullData",
-	ErrEarlyReturn:                        "ErrEarlyReturn",
-	ErrEmptyStack:                         "ErrEmptyStack",
-	ErrEvalFalse:                          "ErrEvalFalse",
-	ErrScriptUnfinished:                   "ErrScriptUnfinished",
-	ErrInvalidProgramCounter:              "ErrInvalidProgramCounter",
-	ErrScriptTooBig:                       "ErrScriptTooBig",
-	ErrElementTooBig:                      "ErrElementTooBig",
-	ErrTooManyOperations:                  "ErrTooManyOperations",
-	ErrStackOverflow:                      "ErrStackOverflow",
-	ErrInvalidPubKeyCount:                 "ErrInvalidPubKeyCount",
-	ErrInvalidSignatureCount:              "ErrInvalidSignatureCount",
-	ErrNumberTooBig:                       "ErrNumberTooBig",
-	ErrVerify:                             "ErrVerify",
-	ErrEqualVerify:                        "ErrEqualVerify",
-	ErrNumEqualVerify:                     "ErrNumEqualVerify",
-	ErrCheckSigVerify:                     "ErrCheckSigVerify",
-	ErrCheckMultiSigVerify:                "ErrCheckMultiSigVerify",
-	ErrDisabledOpcode:                     "ErrDisabledOpcode",
-	ErrReservedOpcode:                     "ErrReservedOpcode",
-	ErrMalformedPush:                      "ErrMalformedPush",
-	ErrInvalidStackOperation:              "ErrInvalidStackOperation",
-	ErrUnbalancedConditional:              "ErrUnbalancedConditional",
-	ErrMinimalData:                        "ErrMinimalData",
-	ErrInvalidSigHashType:                 "ErrInvalidSigHashType",
-	ErrSigTooShort:                        "ErrSigTooShort",
-	ErrSigTooLong:                         "ErrSigTooLong",
-	ErrSigInvalidSeqID:                    "ErrSigInvalidSeqID",
-	ErrSigInvalidDataLen:                  "ErrSigInvalidDataLen",
-	ErrSigMissingSTypeID:                  "ErrSigMissingSTypeID",
-	ErrSigMissingSLen:                     "ErrSigMissingSLen",
-	ErrSigInvalidSLen:                     "ErrSigInvalidSLen",
-	ErrSigInvalidRIntID:                   "ErrSigInvalidRIntID",
-	ErrSigZeroRLen:                        "ErrSigZeroRLen",
-	ErrSigNegativeR:                       "ErrSigNegativeR",
-	ErrSigTooMuchRPadding:                 "ErrSigTooMuchRPadding",
-	ErrSigInvalidSIntID:                   "ErrSigInvalidSIntID",
-	ErrSigZeroSLen:                        "ErrSigZeroSLen",
-	ErrSigNegativeS:                       "ErrSigNegativeS",
-	ErrSigTooMuchSPadding:                 "ErrSigTooMuchSPadding",
-	ErrSigHighS:                           "ErrSigHighS",
-	ErrNotPushOnly:                        "ErrNotPushOnly",
-	ErrSigNullDummy:                       "ErrSigNullDummy",
-	ErrPubKeyType:                         "ErrPubKeyType",
-	ErrCleanStack:                         "ErrCleanStack",
-	ErrNullFail:                           "ErrNullFail",
-	ErrDiscourageUpgradableNOPs:           "ErrDiscourageUpgradableNOPs",
-	ErrNegativeLockTime:                   "ErrNegativeLockTime",
-	ErrUnsatisfiedLockTime:                "ErrUnsatisfiedLockTime",
-	ErrWitnessProgramEmpty:                "ErrWitnessProgramEmpty",
-	ErrWitnessProgramMismatch:             "ErrWitnessProgramMismatch",
-	ErrWitnessProgramWrongLength:          "ErrWitnessProgramWrongLength",
-	ErrWitnessMalleated:                   "ErrWitnessMalleated",
-	ErrWitnessMalleatedP2SH:               "ErrWitnessMalleatedP2SH",
-	ErrWitnessUnexpected:                  "ErrWitnessUnexpected",
-	ErrMinimalIf:                          "ErrMinimalIf",
-	ErrWitnessPubKeyType:                  "ErrWitnessPubKeyType",
-	ErrDiscourageUpgradableWitnessProgram: "ErrDiscourageUpgradableWitnessProgram",
+	ErrInternal:                            "ErrInternal",
+	ErrInvalidFlags:                        "ErrInvalidFlags",
+	ErrInvalidIndex:                        "ErrInvalidIndex",
+	ErrUnsupportedAddress:                  "ErrUnsupportedAddress",
+	ErrNotMultisigScript:                   "ErrNotMultisigScript",
+	ErrTooManyRequiredSigs:                 "ErrTooManyRequiredSigs",
+	ErrTooMuchNullData:                     "ErrTooMuchNullData",
+	ErrEarlyReturn:                         "ErrEarlyReturn",
+	ErrEmptyStack:                          "ErrEmptyStack",
+	ErrEvalFalse:                           "ErrEvalFalse",
+	ErrScriptUnfinished:                    "ErrScriptUnfinished",
+	ErrInvalidProgramCounter:               "ErrInvalidProgramCounter",
+	ErrScriptTooBig:                        "ErrScriptTooBig",
+	ErrElementTooBig:                       "ErrElementTooBig",
+	ErrTooManyOperations:                   "ErrTooManyOperations",
+	ErrStackOverflow:                       "ErrStackOverflow",
+	ErrInvalidPubKeyCount:                  "ErrInvalidPubKeyCount",
+	ErrInvalidSignatureCount:               "ErrInvalidSignatureCount",
+	ErrNumberTooBig:                        "ErrNumberTooBig",
+	ErrVerify:                              "ErrVerify",
+	ErrEqualVerify:                         "ErrEqualVerify",
+	ErrNumEqualVerify:                      "ErrNumEqualVerify",
+	ErrCheckSigVerify:                      "ErrCheckSigVerify",
+	ErrCheckMultiSigVerify:                 "ErrCheckMultiSigVerify",
+	ErrDisabledOpcode:                      "ErrDisabledOpcode",
+	ErrReservedOpcode:                      "ErrReservedOpcode",
+	ErrMalformedPush:                       "ErrMalformedPush",
+	ErrInvalidStackOperation:               "ErrInvalidStackOperation",
+	ErrUnbalancedConditional:               "ErrUnbalancedConditional",
+	ErrMinimalData:                         "ErrMinimalData",
+	ErrInvalidSigHashType:                  "ErrInvalidSigHashType",
+	ErrSigTooShort:                         "ErrSigTooShort",
+	ErrSigTooLong:                          "ErrSigTooLong",
+	ErrSigInvalidSeqID:                     "ErrSigInvalidSeqID",
+	ErrSigInvalidDataLen:                   "ErrSigInvalidDataLen",
+	ErrSigMissingSTypeID:                   "ErrSigMissingSTypeID",
+	ErrSigMissingSLen:                      "ErrSigMissingSLen",
+	ErrSigInvalidSLen:                      "ErrSigInvalidSLen",
+	ErrSigInvalidRIntID:                    "ErrSigInvalidRIntID",
+	ErrSigZeroRLen:                         "ErrSigZeroRLen",
+	ErrSigNegativeR:                        "ErrSigNegativeR",
+	ErrSigTooMuchRPadding:                  "ErrSigTooMuchRPadding",
+	ErrSigInvalidSIntID:                    "ErrSigInvalidSIntID",
+	ErrSigZeroSLen:                         "ErrSigZeroSLen",
+	ErrSigNegativeS:                        "ErrSigNegativeS",
+	ErrSigTooMuchSPadding:                  "ErrSigTooMuchSPadding",
+	ErrSigHighS:                            "ErrSigHighS",
+	ErrNotPushOnly:                         "ErrNotPushOnly",
+	ErrSigNullDummy:                        "ErrSigNullDummy",
+	ErrPubKeyType:                          "ErrPubKeyType",
+	ErrCleanStack:                          "ErrCleanStack",
+	ErrNullFail:                            "ErrNullFail",
+	ErrDiscourageUpgradableNOPs:            "ErrDiscourageUpgradableNOPs",
+	ErrNegativeLockTime:                    "ErrNegativeLockTime",
+	ErrUnsatisfiedLockTime:                 "ErrUnsatisfiedLockTime",
+	ErrWitnessProgramEmpty:                 "ErrWitnessProgramEmpty",
+	ErrWitnessProgramMismatch:              "ErrWitnessProgramMismatch",
+	ErrWitnessProgramWrongLength:           "ErrWitnessProgramWrongLength",
+	ErrWitnessMalleated:                    "ErrWitnessMalleated",
+	ErrWitnessMalleatedP2SH:                "ErrWitnessMalleatedP2SH",
+	ErrWitnessUnexpected:                   "ErrWitnessUnexpected",
+	ErrMinimalIf:                           "ErrMinimalIf",
+	ErrWitnessPubKeyType:                   "ErrWitnessPubKeyType",
+	ErrDiscourageUpgradableWitnessProgram:  "ErrDiscourageUpgradableWitnessProgram",
+	ErrDiscourageUpgradeableTaprootVersion: "ErrDiscourageUpgradeableTaprootVersion",
+	ErrDiscourageOpSuccess:                 "ErrDiscourageOpSuccess",
+	ErrDiscourageUpgradeablePubKeyType:     "ErrDiscourageUpgradeablePubKeyType",
+	ErrTapscriptCheckMultisig:              "ErrTapscriptCheckMultisig",
+	ErrTaprootSigInvalid:                   "ErrTaprootSigInvalid",
+	ErrInvalidTaprootSigLen:                "ErrInvalidTaprootSigLen",
+	ErrTaprootPubkeyIsEmpty:                "ErrTaprootPubkeyIsEmpty",
+	ErrTaprootMaxSigOps:                    "ErrTaprootMaxSigOps",
+	ErrControlBlockInvalidLength:           "ErrControlBlockInvalidLength",
+	ErrTaprootMerkleProofInvalid:           "ErrTaprootMerkleProofInvalid",
 }
 
 // String returns the ErrorCode as a human-readable name.
@@ -80,6 +80,16 @@ func TestErrorCodeStringer(t *testing.T) {
 		{ErrMinimalIf, "ErrMinimalIf"},
 		{ErrWitnessPubKeyType, "ErrWitnessPubKeyType"},
 		{ErrDiscourageUpgradableWitnessProgram, "ErrDiscourageUpgradableWitnessProgram"},
+		{ErrDiscourageUpgradeableTaprootVersion, "ErrDiscourageUpgradeableTaprootVersion"},
+		{ErrDiscourageOpSuccess, "ErrDiscourageOpSuccess"},
+		{ErrDiscourageUpgradeablePubKeyType, "ErrDiscourageUpgradeablePubKeyType"},
+		{ErrTapscriptCheckMultisig, "ErrTapscriptCheckMultisig"},
+		{ErrTaprootSigInvalid, "ErrTaprootSigInvalid"},
+		{ErrInvalidTaprootSigLen, "ErrInvalidTaprootSigLen"},
+		{ErrTaprootPubkeyIsEmpty, "ErrTaprootPubkeyIsEmpty"},
+		{ErrTaprootMaxSigOps, "ErrTaprootMaxSigOps"},
+		{ErrControlBlockInvalidLength, "ErrControlBlockInvalidLength"},
+		{ErrTaprootMerkleProofInvalid, "ErrTaprootMerkleProofInvalid"},
 		{0xffff, "Unknown ErrorCode (65535)"},
 	}
 
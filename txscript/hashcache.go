@@ -11,24 +11,145 @@ import (
 	"github.com/brsuite/brond/wire"
 )
 
+// PrevOutputFetcher is an interface used to supply the sighash cache and the
+// script engine with the outputs spent by a transaction.  The outputs are
+// needed to compute the signature hashes defined by BIP0341, which commit to
+// the amounts and public key scripts of all spent outputs.
+type PrevOutputFetcher interface {
+	// FetchPrevOutput returns the output spent by the passed outpoint or
+	// nil if it is not known.
+	FetchPrevOutput(wire.OutPoint) *wire.TxOut
+}
+
+// CannedPrevOutputFetcher is an implementation of PrevOutputFetcher that
+// returns the same output for any outpoint.  It is only suitable for
+// transactions with a single input.
+type CannedPrevOutputFetcher struct {
+	pkScript []byte
+	amt      int64
+}
+
+// NewCannedPrevOutputFetcher returns a new instance of a
+// CannedPrevOutputFetcher for the passed public key script and amount.
+func NewCannedPrevOutputFetcher(pkScript []byte, amt int64) *CannedPrevOutputFetcher {
+	return &CannedPrevOutputFetcher{
+		pkScript: pkScript,
+		amt:      amt,
+	}
+}
+
+// FetchPrevOutput returns the canned output regardless of the passed outpoint.
+//
+// This is part of the PrevOutputFetcher interface.
+func (c *CannedPrevOutputFetcher) FetchPrevOutput(wire.OutPoint) *wire.TxOut {
+	return wire.NewTxOut(c.amt, c.pkScript)
+}
+
+// A compile-time assertion to ensure CannedPrevOutputFetcher implements the
+// PrevOutputFetcher interface.
+var _ PrevOutputFetcher = (*CannedPrevOutputFetcher)(nil)
+
+// MultiPrevOutFetcher is an implementation of PrevOutputFetcher backed by a map
+// of outpoints to the outputs they reference.
+type MultiPrevOutFetcher struct {
+	prevOuts map[wire.OutPoint]*wire.TxOut
+}
+
+// NewMultiPrevOutFetcher returns a new instance of a MultiPrevOutFetcher that
+// is backed by the passed map.  A nil map results in an empty fetcher.
+func NewMultiPrevOutFetcher(prevOuts map[wire.OutPoint]*wire.TxOut) *MultiPrevOutFetcher {
+	if prevOuts == nil {
+		prevOuts = make(map[wire.OutPoint]*wire.TxOut)
+	}
+
+	return &MultiPrevOutFetcher{
+		prevOuts: prevOuts,
+	}
+}
+
+// FetchPrevOutput returns the output referenced by the passed outpoint or nil
+// if it is not known.
+//
+// This is part of the PrevOutputFetcher interface.
+func (m *MultiPrevOutFetcher) FetchPrevOutput(op wire.OutPoint) *wire.TxOut {
+	return m.prevOuts[op]
+}
+
+// AddPrevOut adds the output referenced by the passed outpoint to the fetcher.
+func (m *MultiPrevOutFetcher) AddPrevOut(op wire.OutPoint, txOut *wire.TxOut) {
+	m.prevOuts[op] = txOut
+}
+
+// A compile-time assertion to ensure MultiPrevOutFetcher implements the
+// PrevOutputFetcher interface.
+var _ PrevOutputFetcher = (*MultiPrevOutFetcher)(nil)
+
 // TxSigHashes houses the partial set of sighashes introduced within BIP0143.
 // This partial set of sighashes may be re-used within each input across a
 // transaction when validating all inputs. As a result, validation complexity
 // for SigHashAll can be reduced by a polynomial factor.
+//
+// The V1 fields house the single SHA256 midstates introduced within BIP0341
+// which are only computed when the transaction spends a taproot output.
 type TxSigHashes struct {
 	HashPrevOuts chainhash.Hash
 	HashSequence chainhash.Hash
 	HashOutputs  chainhash.Hash
+
+	HashPrevOutsV1     chainhash.Hash
+	HashSequenceV1     chainhash.Hash
+	HashOutputsV1      chainhash.Hash
+	HashInputAmountsV1 chainhash.Hash
+	HashInputScriptsV1 chainhash.Hash
+
+	// hasTaprootHashes indicates the V1 midstates have been computed.
+	hasTaprootHashes bool
+}
+
+// spendsTaprootOutput returns whether or not any of the outputs spent by the
+// passed transaction, as supplied by the fetcher, is a taproot output.
+//
+// NOTE: The scripts are matched directly rather than parsed since parsing
+// depends on the opcode table, which in turn depends on this function.
+func spendsTaprootOutput(tx *wire.MsgTx, prevOutFetcher PrevOutputFetcher) bool {
+	for _, txIn := range tx.TxIn {
+		prevOut := prevOutFetcher.FetchPrevOutput(txIn.PreviousOutPoint)
+		if prevOut == nil {
+			continue
+		}
+
+		// OP_1 OP_DATA_32 <32-byte output key>
+		pkScript := prevOut.PkScript
+		if len(pkScript) == 34 && pkScript[0] == OP_1 &&
+			pkScript[1] == OP_DATA_32 {
+
+			return true
+		}
+	}
+
+	return false
 }
 
 // NewTxSigHashes computes, and returns the cached sighashes of the given
-// transaction.
-func NewTxSigHashes(tx *wire.MsgTx) *TxSigHashes {
-	return &TxSigHashes{
+// transaction.  The BIP0341 midstates are also computed when the passed
+// fetcher is not nil and the transaction spends at least one taproot output.
+func NewTxSigHashes(tx *wire.MsgTx, prevOutFetcher PrevOutputFetcher) *TxSigHashes {
+	sigHashes := &TxSigHashes{
 		HashPrevOuts: calcHashPrevOuts(tx),
 		HashSequence: calcHashSequence(tx),
 		HashOutputs:  calcHashOutputs(tx),
 	}
+	if prevOutFetcher == nil || !spendsTaprootOutput(tx, prevOutFetcher) {
+		return sigHashes
+	}
+
+	sigHashes.HashPrevOutsV1 = calcHashPrevOutsV1(tx)
+	sigHashes.HashSequenceV1 = calcHashSequenceV1(tx)
+	sigHashes.HashOutputsV1 = calcHashOutputsV1(tx)
+	sigHashes.HashInputAmountsV1 = calcHashInputAmounts(tx, prevOutFetcher)
+	sigHashes.HashInputScriptsV1 = calcHashInputScripts(tx, prevOutFetcher)
+	sigHashes.hasTaprootHashes = true
+	return sigHashes
 }
 
 // HashCache houses a set of partial sighashes keyed by txid. The set of partial
@@ -51,10 +172,11 @@ func NewHashCache(maxSize uint) *HashCache {
 }
 
 // AddSigHashes computes, then adds the partial sighashes for the passed
-// transaction.
-func (h *HashCache) AddSigHashes(tx *wire.MsgTx) {
+// transaction.  The fetcher supplies the spent outputs needed for the BIP0341
+// midstates and may be nil when they are not required.
+func (h *HashCache) AddSigHashes(tx *wire.MsgTx, prevOutFetcher PrevOutputFetcher) {
 	h.Lock()
-	h.sigHashes[tx.TxHash()] = NewTxSigHashes(tx)
+	h.sigHashes[tx.TxHash()] = NewTxSigHashes(tx, prevOutFetcher)
 	h.Unlock()
 }
 
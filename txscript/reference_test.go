@@ -444,7 +444,7 @@ func testScripts(t *testing.T, tests [][]interface{}, useSigCache bool) {
 		tx := createSpendingTx(witness, scriptSig, scriptPubKey,
 			int64(inputAmt))
 		vm, err := NewEngine(scriptPubKey, tx, 0, flags, sigCache, nil,
-			int64(inputAmt))
+			int64(inputAmt), nil)
 		if err == nil {
 			err = vm.Execute()
 		}
@@ -651,7 +651,7 @@ testloop:
 			// input fails the transaction has failed. (some of the
 			// test txns have good inputs, too..
 			vm, err := NewEngine(prevOut.pkScript, tx.MsgTx(), k,
-				flags, nil, nil, prevOut.inputVal)
+				flags, nil, nil, prevOut.inputVal, nil)
 			if err != nil {
 				continue testloop
 			}
@@ -803,7 +803,7 @@ testloop:
 				continue testloop
 			}
 			vm, err := NewEngine(prevOut.pkScript, tx.MsgTx(), k,
-				flags, nil, nil, prevOut.inputVal)
+				flags, nil, nil, prevOut.inputVal, nil)
 			if err != nil {
 				t.Errorf("test (%d:%v:%d) failed to create "+
 					"script: %v", i, test, k, err)
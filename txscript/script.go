@@ -24,6 +24,7 @@ type SigHashType uint32
 
 // Hash type bits from the end of a signature.
 const (
+	SigHashDefault      SigHashType = 0x0
 	SigHashOld          SigHashType = 0x0
 	SigHashAll          SigHashType = 0x1
 	SigHashNone         SigHashType = 0x2
@@ -88,6 +89,24 @@ func IsPayToWitnessScriptHash(script []byte) bool {
 	return isWitnessScriptHash(pops)
 }
 
+// isWitnessTaproot returns true if the passed script is a pay-to-taproot
+// output, false otherwise.
+func isWitnessTaproot(pops []parsedOpcode) bool {
+	return len(pops) == 2 &&
+		pops[0].opcode.value == OP_1 &&
+		pops[1].opcode.value == OP_DATA_32
+}
+
+// IsPayToTaproot returns true if the script is in the standard pay-to-taproot
+// (P2TR) format, false otherwise.
+func IsPayToTaproot(script []byte) bool {
+	pops, err := parseScript(script)
+	if err != nil {
+		return false
+	}
+	return isWitnessTaproot(pops)
+}
+
 // IsPayToWitnessPubKeyHash returns true if the is in the standard
 // pay-to-witness-pubkey-hash (P2WKH) format, false otherwise.
 func IsPayToWitnessPubKeyHash(script []byte) bool {
@@ -424,6 +443,79 @@ func calcHashOutputs(tx *wire.MsgTx) chainhash.Hash {
 	return chainhash.DoubleHashH(b.Bytes())
 }
 
+// calcHashPrevOutsV1 is the same as calcHashPrevOuts except it uses a single
+// SHA256 as required by the signature hashes defined in BIP0341.
+func calcHashPrevOutsV1(tx *wire.MsgTx) chainhash.Hash {
+	var b bytes.Buffer
+	for _, in := range tx.TxIn {
+		b.Write(in.PreviousOutPoint.Hash[:])
+		var buf [4]byte
+		binary.LittleEndian.PutUint32(buf[:], in.PreviousOutPoint.Index)
+		b.Write(buf[:])
+	}
+
+	return chainhash.HashH(b.Bytes())
+}
+
+// calcHashSequenceV1 is the same as calcHashSequence except it uses a single
+// SHA256 as required by the signature hashes defined in BIP0341.
+func calcHashSequenceV1(tx *wire.MsgTx) chainhash.Hash {
+	var b bytes.Buffer
+	for _, in := range tx.TxIn {
+		var buf [4]byte
+		binary.LittleEndian.PutUint32(buf[:], in.Sequence)
+		b.Write(buf[:])
+	}
+
+	return chainhash.HashH(b.Bytes())
+}
+
+// calcHashOutputsV1 is the same as calcHashOutputs except it uses a single
+// SHA256 as required by the signature hashes defined in BIP0341.
+func calcHashOutputsV1(tx *wire.MsgTx) chainhash.Hash {
+	var b bytes.Buffer
+	for _, out := range tx.TxOut {
+		wire.WriteTxOut(&b, 0, 0, out)
+	}
+
+	return chainhash.HashH(b.Bytes())
+}
+
+// calcHashInputAmounts computes a single SHA256 of the amounts of all outputs
+// spent by the passed transaction as supplied by the fetcher.  Outputs which
+// are not known to the fetcher are treated as having a zero amount.
+func calcHashInputAmounts(tx *wire.MsgTx, prevOutFetcher PrevOutputFetcher) chainhash.Hash {
+	var b bytes.Buffer
+	for _, in := range tx.TxIn {
+		var amt int64
+		if prevOut := prevOutFetcher.FetchPrevOutput(in.PreviousOutPoint); prevOut != nil {
+			amt = prevOut.Value
+		}
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], uint64(amt))
+		b.Write(buf[:])
+	}
+
+	return chainhash.HashH(b.Bytes())
+}
+
+// calcHashInputScripts computes a single SHA256 of the public key scripts,
+// each serialized with a var int length prefix, of all outputs spent by the
+// passed transaction as supplied by the fetcher.  Outputs which are not known
+// to the fetcher are treated as having an empty script.
+func calcHashInputScripts(tx *wire.MsgTx, prevOutFetcher PrevOutputFetcher) chainhash.Hash {
+	var b bytes.Buffer
+	for _, in := range tx.TxIn {
+		var pkScript []byte
+		if prevOut := prevOutFetcher.FetchPrevOutput(in.PreviousOutPoint); prevOut != nil {
+			pkScript = prevOut.PkScript
+		}
+		wire.WriteVarBytes(&b, 0, pkScript)
+	}
+
+	return chainhash.HashH(b.Bytes())
+}
+
 // calcWitnessSignatureHash computes the sighash digest of a transaction's
 // segwit input using the new, optimized digest calculation algorithm defined
 // in BIP0143: https://github.com/brocoin/bips/blob/master/bip-0143.mediawiki.
@@ -7,6 +7,7 @@ package txscript
 import (
 	"fmt"
 
+	"github.com/brsuite/brond/bronec"
 	"github.com/brsuite/brond/chaincfg"
 	"github.com/brsuite/brond/wire"
 	"github.com/brsuite/bronutil"
@@ -42,7 +43,11 @@ const (
 		ScriptVerifyWitness |
 		ScriptVerifyDiscourageUpgradeableWitnessProgram |
 		ScriptVerifyMinimalIf |
-		ScriptVerifyWitnessPubKeyType
+		ScriptVerifyWitnessPubKeyType |
+		ScriptVerifyTaproot |
+		ScriptVerifyDiscourageUpgradeableTaprootVersion |
+		ScriptVerifyDiscourageOpSuccess |
+		ScriptVerifyDiscourageUpgradeablePubkeyType
 )
 
 // ScriptClass is an enumeration for the list of standard types of script.
@@ -58,6 +63,7 @@ const (
 	WitnessV0ScriptHashTy                    // Pay to witness script hash.
 	MultiSigTy                               // Multi signature.
 	NullDataTy                               // Empty data-only (provably prunable).
+	WitnessV1TaprootTy                       // Pay to taproot output key.
 )
 
 // scriptClassToName houses the human-readable strings which describe each
@@ -71,6 +77,7 @@ var scriptClassToName = []string{
 	WitnessV0ScriptHashTy: "witness_v0_scripthash",
 	MultiSigTy:            "multisig",
 	NullDataTy:            "nulldata",
+	WitnessV1TaprootTy:    "witness_v1_taproot",
 }
 
 // String implements the Stringer interface by returning the name of
@@ -169,6 +176,8 @@ func typeOfScript(pops []parsedOpcode) ScriptClass {
 		return ScriptHashTy
 	} else if isWitnessScriptHash(pops) {
 		return WitnessV0ScriptHashTy
+	} else if isWitnessTaproot(pops) {
+		return WitnessV1TaprootTy
 	} else if isMultiSig(pops) {
 		return MultiSigTy
 	} else if isNullData(pops) {
@@ -212,6 +221,11 @@ func expectedInputs(pops []parsedOpcode, class ScriptClass) int {
 		// Not including script.  That is handled by the caller.
 		return 1
 
+	case WitnessV1TaprootTy:
+		// Key path spends only require a signature.  Script path spends
+		// are handled by the caller.
+		return 1
+
 	case MultiSigTy:
 		// Standard multisig has a push a small number for the number
 		// of sigs and number of keys.  Check the first push instruction
@@ -410,6 +424,14 @@ func payToWitnessScriptHashScript(scriptHash []byte) ([]byte, error) {
 	return NewScriptBuilder().AddOp(OP_0).AddData(scriptHash).Script()
 }
 
+// PayToTaprootScript creates a new script to pay to a version 1 witness program
+// committing to the passed taproot output key.  Only the x coordinate of the
+// key is included as defined by BIP0341.
+func PayToTaprootScript(outputKey *bronec.PublicKey) ([]byte, error) {
+	return NewScriptBuilder().AddOp(OP_1).
+		AddData(outputKey.SerializeSchnorr()).Script()
+}
+
 // payToPubkeyScript creates a new script to pay a transaction output to a
 // public key. It is expected that the input is a valid pubkey.
 func payToPubKeyScript(serializedPubKey []byte) ([]byte, error) {
@@ -612,6 +634,14 @@ func ExtractPkScriptAddrs(pkScript []byte, chainParams *chaincfg.Params) (Script
 			}
 		}
 
+	case WitnessV1TaprootTy:
+		// A pay-to-taproot script is of the form:
+		//  OP_1 <32-byte output key>
+		// There is no address type for taproot outputs yet, so only
+		// the single signature required by a key path spend is
+		// reported.
+		requiredSigs = 1
+
 	case NullDataTy:
 		// Null data transactions have no addresses or required
 		// signatures.
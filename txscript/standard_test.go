@@ -1044,6 +1044,19 @@ var scriptClassTests = []struct {
 		script: "0 DATA_32 0x9f96ade4b41d5433f4eda31e1738ec2b36f6e7d1420d94a6af99801a88f7f7ff",
 		class:  WitnessV0ScriptHashTy,
 	},
+	{
+		// A pay to taproot pk script.
+		name:   "Pay To Taproot",
+		script: "1 DATA_32 0x7ad4375032c38eba4fc60deca75fa30a3a6bdf2fb38f7e617288e2d3776117cb",
+		class:  WitnessV1TaprootTy,
+	},
+	{
+		// A version 1 witness program of the wrong size is not a
+		// taproot output.
+		name:   "Witness v1 with 20-byte program",
+		script: "1 DATA_20 0x1d0f172a0ecb48aee1be1f2687d2963ae33f71a1",
+		class:  NonStandardTy,
+	},
 }
 
 // TestScriptClass ensures all the scripts in scriptClassTests have the expected
@@ -1112,6 +1125,11 @@ func TestStringifyClass(t *testing.T) {
 			class:    NullDataTy,
 			stringed: "nulldata",
 		},
+		{
+			name:     "witnesstaproot",
+			class:    WitnessV1TaprootTy,
+			stringed: "witness_v1_taproot",
+		},
 		{
 			name:     "broken",
 			class:    ScriptClass(255),
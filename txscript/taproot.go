@@ -0,0 +1,633 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/brsuite/brond/bronec"
+	"github.com/brsuite/brond/chaincfg/chainhash"
+	"github.com/brsuite/brond/wire"
+)
+
+const (
+	// BaseLeafVersion is the leaf version of the tapscripts defined by
+	// BIP0342.
+	BaseLeafVersion = 0xc0
+
+	// taprootLeafMask is the mask applied to the first byte of a control
+	// block to extract the leaf version.  The remaining bit holds the
+	// parity of the y coordinate of the output key.
+	taprootLeafMask = 0xfe
+
+	// taprootAnnexTag is the first byte of the last element of a taproot
+	// witness which identifies it as the annex.
+	taprootAnnexTag = 0x50
+
+	// controlBlockBaseSize is the size of a control block without any
+	// merkle path nodes.  It consists of the leaf version and parity byte
+	// followed by the 32-byte internal key.
+	controlBlockBaseSize = 33
+
+	// controlBlockNodeSize is the size of each node of the merkle path
+	// within a control block.
+	controlBlockNodeSize = 32
+
+	// controlBlockMaxNodeCount is the maximum number of nodes of the merkle
+	// path within a control block.
+	controlBlockMaxNodeCount = 128
+
+	// controlBlockMaxSize is the maximum size of a control block.
+	controlBlockMaxSize = controlBlockBaseSize +
+		controlBlockNodeSize*controlBlockMaxNodeCount
+
+	// sigOpsDelta is the amount the signature operation budget of a
+	// tapscript is reduced by for every non-empty signature checked and
+	// also the budget that is granted in addition to the witness size.
+	sigOpsDelta = 50
+
+	// blankCodeSepValue is the code separator position committed to by
+	// tapscript signatures when no OP_CODESEPARATOR was executed.
+	blankCodeSepValue = ^uint32(0)
+)
+
+var (
+	// tapLeafTag, tapBranchTag, tapTweakTag and tapSighashTag are the tags
+	// of the tagged hashes defined by BIP0341.
+	tapLeafTag    = []byte("TapLeaf")
+	tapBranchTag  = []byte("TapBranch")
+	tapTweakTag   = []byte("TapTweak")
+	tapSighashTag = []byte("TapSighash")
+)
+
+// taprootExecutionCtx houses the state needed while validating a spend of a
+// taproot output.
+type taprootExecutionCtx struct {
+	// annex is the annex of the witness, if any.
+	annex []byte
+
+	// codeSepPos is the opcode position of the most recently executed
+	// OP_CODESEPARATOR within the tapscript.
+	codeSepPos uint32
+
+	// tapLeafHash is the leaf hash of the executing tapscript.  It is nil
+	// for key path spends.
+	tapLeafHash *chainhash.Hash
+
+	// sigOpsBudget is the remaining signature operation budget of the
+	// tapscript.
+	sigOpsBudget int32
+
+	// mustSucceed is set when the spend is valid without executing any
+	// further scripts.
+	mustSucceed bool
+}
+
+// newTaprootExecutionCtx returns a new taproot execution context for a spend
+// whose serialized witness has the passed size.
+func newTaprootExecutionCtx(witnessSize int32) *taprootExecutionCtx {
+	return &taprootExecutionCtx{
+		codeSepPos:   blankCodeSepValue,
+		sigOpsBudget: sigOpsDelta + witnessSize,
+	}
+}
+
+// tallySigOp reduces the signature operation budget for a non-empty signature
+// and returns an error when the budget is exhausted.
+func (t *taprootExecutionCtx) tallySigOp() error {
+	t.sigOpsBudget -= sigOpsDelta
+	if t.sigOpsBudget < 0 {
+		return scriptError(ErrTaprootMaxSigOps, "tapscript exceeds the "+
+			"signature operation budget")
+	}
+
+	return nil
+}
+
+// TapLeafHash returns the BIP0341 leaf hash of the passed script with the
+// passed leaf version.
+func TapLeafHash(leafVersion byte, script []byte) chainhash.Hash {
+	var b bytes.Buffer
+	b.WriteByte(leafVersion)
+	wire.WriteVarBytes(&b, 0, script)
+	return *chainhash.TaggedHash(tapLeafTag, b.Bytes())
+}
+
+// TapBranchHash returns the BIP0341 branch hash of the two passed child nodes
+// of a script tree.  The children are sorted before hashing, so the order
+// they are passed in does not matter.
+func TapBranchHash(a, b []byte) chainhash.Hash {
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+	return *chainhash.TaggedHash(tapBranchTag, a, b)
+}
+
+// tapTweak returns the BIP0341 tweak for the passed x-only internal key and
+// script tree root.  An error is returned when the tweak is not less than the
+// order of the curve.
+func tapTweak(internalKeyX, scriptRoot []byte) (*big.Int, error) {
+	h := chainhash.TaggedHash(tapTweakTag, internalKeyX, scriptRoot)
+	t := new(big.Int).SetBytes(h[:])
+	if t.Cmp(bronec.S256().N) >= 0 {
+		return nil, fmt.Errorf("taproot tweak is >= curve.N")
+	}
+
+	return t, nil
+}
+
+// ComputeTaprootOutputKey returns the output key committing to the passed
+// internal key and script tree root as defined by BIP0341.  Only the x
+// coordinate of the internal key is used.  The script root should be nil for
+// outputs which can only be spent via the key path.
+func ComputeTaprootOutputKey(internalKey *bronec.PublicKey,
+	scriptRoot []byte) (*bronec.PublicKey, error) {
+
+	internalKey, err := bronec.ParseSchnorrPubKey(
+		internalKey.SerializeSchnorr())
+	if err != nil {
+		return nil, err
+	}
+	t, err := tapTweak(internalKey.SerializeSchnorr(), scriptRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	// Q = P + t*G
+	curve := bronec.S256()
+	tgx, tgy := curve.ScalarBaseMult(t.Bytes())
+	qx, qy := curve.Add(internalKey.X, internalKey.Y, tgx, tgy)
+	if qx.Sign() == 0 && qy.Sign() == 0 {
+		return nil, fmt.Errorf("taproot output key is the point at " +
+			"infinity")
+	}
+
+	return &bronec.PublicKey{Curve: curve, X: qx, Y: qy}, nil
+}
+
+// TweakTaprootPrivKey returns the private key for the output key committing to
+// the public key of the passed private key and the passed script tree root.
+// The returned key can be used to sign key path spends of the output.
+func TweakTaprootPrivKey(privKey *bronec.PrivateKey,
+	scriptRoot []byte) (*bronec.PrivateKey, error) {
+
+	curve := bronec.S256()
+	d := new(big.Int).Set(privKey.D)
+	pubKey := privKey.PubKey()
+
+	// The internal key is always the point with the even y coordinate, so
+	// negate the private key when needed.
+	if pubKey.Y.Bit(0) == 1 {
+		d.Sub(curve.N, d)
+	}
+	t, err := tapTweak(pubKey.SerializeSchnorr(), scriptRoot)
+	if err != nil {
+		return nil, err
+	}
+	d.Add(d, t)
+	d.Mod(d, curve.N)
+	if d.Sign() == 0 {
+		return nil, fmt.Errorf("tweaked taproot private key is zero")
+	}
+
+	tweaked, _ := bronec.PrivKeyFromBytes(curve, d.Bytes())
+	return tweaked, nil
+}
+
+// verifyTaprootLeafCommitment ensures the passed control block proves the
+// passed script is committed to by the taproot output key in the witness
+// program.  The leaf hash of the script is returned on success.
+func verifyTaprootLeafCommitment(controlBlock, witnessProgram,
+	script []byte) (*chainhash.Hash, error) {
+
+	cbLen := len(controlBlock)
+	if cbLen < controlBlockBaseSize || cbLen > controlBlockMaxSize ||
+		(cbLen-controlBlockBaseSize)%controlBlockNodeSize != 0 {
+
+		str := fmt.Sprintf("control block has invalid length %d", cbLen)
+		return nil, scriptError(ErrControlBlockInvalidLength, str)
+	}
+
+	internalKey, err := bronec.ParseSchnorrPubKey(
+		controlBlock[1:controlBlockBaseSize])
+	if err != nil {
+		str := fmt.Sprintf("control block has invalid internal key: %v",
+			err)
+		return nil, scriptError(ErrTaprootMerkleProofInvalid, str)
+	}
+
+	// Compute the root of the script tree by hashing the leaf along the
+	// merkle path.
+	leafVersion := controlBlock[0] & taprootLeafMask
+	leafHash := TapLeafHash(leafVersion, script)
+	node := leafHash
+	for i := controlBlockBaseSize; i < cbLen; i += controlBlockNodeSize {
+		node = TapBranchHash(node[:], controlBlock[i:i+controlBlockNodeSize])
+	}
+
+	// The output key derived from the internal key and the root must match
+	// the witness program along with the parity of its y coordinate.
+	outputKey, err := ComputeTaprootOutputKey(internalKey, node[:])
+	if err != nil {
+		return nil, scriptError(ErrTaprootMerkleProofInvalid, err.Error())
+	}
+	if !bytes.Equal(outputKey.SerializeSchnorr(), witnessProgram) {
+		return nil, scriptError(ErrTaprootMerkleProofInvalid,
+			"script is not committed to by the output key")
+	}
+	if outputKey.Y.Bit(0) != uint(controlBlock[0]&1) {
+		return nil, scriptError(ErrTaprootMerkleProofInvalid,
+			"control block has wrong output key parity")
+	}
+
+	return &leafHash, nil
+}
+
+// isAnnexedWitness returns whether or not the passed taproot witness includes
+// an annex, which is the case when there are at least two elements and the
+// last one starts with the annex tag.
+func isAnnexedWitness(witness wire.TxWitness) bool {
+	if len(witness) < 2 {
+		return false
+	}
+	lastElement := witness[len(witness)-1]
+	return len(lastElement) > 0 && lastElement[0] == taprootAnnexTag
+}
+
+// isOpSuccess returns whether or not the passed opcode is one of the
+// OP_SUCCESSx opcodes defined by BIP0342.
+func isOpSuccess(opcode byte) bool {
+	switch {
+	case opcode == 80 || opcode == 98:
+		return true
+	case opcode >= 126 && opcode <= 129:
+		return true
+	case opcode >= 131 && opcode <= 134:
+		return true
+	case opcode == 137 || opcode == 138:
+		return true
+	case opcode == 141 || opcode == 142:
+		return true
+	case opcode >= 149 && opcode <= 153:
+		return true
+	case opcode >= 187 && opcode <= 254:
+		return true
+	}
+
+	return false
+}
+
+// verifyTaprootSpend validates the passed witness against the taproot output
+// key stored as the witness program.  Key path spends are fully validated
+// here, while script path spends result in the tapscript being set up as the
+// next script to execute.
+func (vm *Engine) verifyTaprootSpend(witness wire.TxWitness) error {
+	vm.taprootCtx = newTaprootExecutionCtx(int32(witness.SerializeSize()))
+
+	if len(witness) == 0 {
+		return scriptError(ErrWitnessProgramEmpty, "witness program "+
+			"empty passed empty witness")
+	}
+
+	// The annex is not interpreted, but it is committed to by signatures.
+	if isAnnexedWitness(witness) {
+		vm.taprootCtx.annex = witness[len(witness)-1]
+		witness = witness[:len(witness)-1]
+	}
+
+	// A single remaining element is a signature for the output key.
+	if len(witness) == 1 {
+		err := vm.verifyTaprootSig(witness[0], vm.witnessProgram)
+		if err != nil {
+			return err
+		}
+
+		vm.taprootCtx.mustSucceed = true
+		return nil
+	}
+
+	// Otherwise the last two elements are the control block and the
+	// script, which must be committed to by the output key.
+	controlBlock := witness[len(witness)-1]
+	witnessScript := witness[len(witness)-2]
+	leafHash, err := verifyTaprootLeafCommitment(controlBlock,
+		vm.witnessProgram, witnessScript)
+	if err != nil {
+		return err
+	}
+
+	// Scripts with unknown leaf versions are reserved for future soft
+	// forks and are therefore valid.
+	if controlBlock[0]&taprootLeafMask != BaseLeafVersion {
+		if vm.hasFlag(ScriptVerifyDiscourageUpgradeableTaprootVersion) {
+			str := fmt.Sprintf("taproot leaf version 0x%x is "+
+				"unknown", controlBlock[0]&taprootLeafMask)
+			return scriptError(ErrDiscourageUpgradeableTaprootVersion,
+				str)
+		}
+
+		vm.taprootCtx.mustSucceed = true
+		return nil
+	}
+	vm.taprootCtx.tapLeafHash = leafHash
+
+	// Any OP_SUCCESSx opcode encountered before a parse failure makes the
+	// script succeed, even if it is not executed.
+	pops, err := parseScript(witnessScript)
+	for _, pop := range pops {
+		if !isOpSuccess(pop.opcode.value) {
+			continue
+		}
+		if vm.hasFlag(ScriptVerifyDiscourageOpSuccess) {
+			str := fmt.Sprintf("tapscript contains %s",
+				pop.opcode.name)
+			return scriptError(ErrDiscourageOpSuccess, str)
+		}
+
+		vm.taprootCtx.mustSucceed = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// The initial stack is subject to the same limits as the stack during
+	// execution.
+	stack := witness[:len(witness)-2]
+	if len(stack) > MaxStackSize {
+		str := fmt.Sprintf("tapscript stack size %d > max allowed %d",
+			len(stack), MaxStackSize)
+		return scriptError(ErrStackOverflow, str)
+	}
+	for _, element := range stack {
+		if len(element) > MaxScriptElementSize {
+			str := fmt.Sprintf("element size %d exceeds max "+
+				"allowed size %d", len(element),
+				MaxScriptElementSize)
+			return scriptError(ErrElementTooBig, str)
+		}
+	}
+
+	vm.scripts = append(vm.scripts, pops)
+	vm.SetStack(stack)
+	return nil
+}
+
+// taprootSigHashes returns the sighash midstates for the transaction being
+// validated including those defined by BIP0341, computing them when they are
+// not available from the cache passed to the engine.
+func (vm *Engine) taprootSigHashes() (*TxSigHashes, error) {
+	if vm.hashCache != nil && vm.hashCache.hasTaprootHashes {
+		return vm.hashCache, nil
+	}
+	if vm.prevOutFetcher == nil {
+		return nil, scriptError(ErrInternal, "spent outputs are "+
+			"required to validate taproot spends")
+	}
+
+	sigHashes := NewTxSigHashes(&vm.tx, vm.prevOutFetcher)
+	if !sigHashes.hasTaprootHashes {
+		return nil, scriptError(ErrInternal, "spent taproot output "+
+			"is unknown")
+	}
+	vm.hashCache = sigHashes
+	return sigHashes, nil
+}
+
+// verifyTaprootSig verifies the passed BIP0340 signature, which may have a
+// hash type appended, against the passed 32-byte public key using the
+// signature hash of the input being validated.
+func (vm *Engine) verifyTaprootSig(sigBytes, pkBytes []byte) error {
+	hashType := SigHashDefault
+	switch len(sigBytes) {
+	case bronec.SchnorrSigBytesLen:
+	case bronec.SchnorrSigBytesLen + 1:
+		hashType = SigHashType(sigBytes[bronec.SchnorrSigBytesLen])
+		if hashType == SigHashDefault {
+			return scriptError(ErrInvalidTaprootSigLen, "explicit "+
+				"default hash type is not allowed")
+		}
+		sigBytes = sigBytes[:bronec.SchnorrSigBytesLen]
+	default:
+		str := fmt.Sprintf("invalid taproot signature length %d",
+			len(sigBytes))
+		return scriptError(ErrInvalidTaprootSigLen, str)
+	}
+
+	sigHashes, err := vm.taprootSigHashes()
+	if err != nil {
+		return err
+	}
+	hash, err := calcTaprootSignatureHash(sigHashes, hashType, &vm.tx,
+		vm.txIdx, vm.prevOutFetcher, vm.taprootCtx.annex,
+		vm.taprootCtx.tapLeafHash, vm.taprootCtx.codeSepPos)
+	if err != nil {
+		return err
+	}
+
+	pubKey, err := bronec.ParseSchnorrPubKey(pkBytes)
+	if err != nil {
+		str := fmt.Sprintf("invalid taproot public key: %v", err)
+		return scriptError(ErrTaprootSigInvalid, str)
+	}
+	signature, err := bronec.ParseSchnorrSignature(sigBytes)
+	if err != nil {
+		str := fmt.Sprintf("invalid taproot signature: %v", err)
+		return scriptError(ErrTaprootSigInvalid, str)
+	}
+	if !signature.Verify(hash, pubKey) {
+		return scriptError(ErrTaprootSigInvalid, "taproot signature "+
+			"verification failed")
+	}
+
+	return nil
+}
+
+// checkTapscriptSig implements the signature checks of OP_CHECKSIG,
+// OP_CHECKSIGVERIFY and OP_CHECKSIGADD within a tapscript as defined by
+// BIP0342.  It returns whether or not the signature is considered valid, which
+// is the case for all non-empty signatures that don't result in an error.
+func (vm *Engine) checkTapscriptSig(sigBytes, pkBytes []byte) (bool, error) {
+	if len(sigBytes) != 0 {
+		if err := vm.taprootCtx.tallySigOp(); err != nil {
+			return false, err
+		}
+	}
+
+	switch {
+	case len(pkBytes) == 0:
+		return false, scriptError(ErrTaprootPubkeyIsEmpty,
+			"tapscript public key is empty")
+
+	case len(pkBytes) == bronec.SchnorrPubKeyBytesLen:
+		if len(sigBytes) == 0 {
+			return false, nil
+		}
+		if err := vm.verifyTaprootSig(sigBytes, pkBytes); err != nil {
+			return false, err
+		}
+		return true, nil
+
+	// Public keys of unknown types are reserved for future soft forks, so
+	// any non-empty signature is considered valid for them.
+	default:
+		if vm.hasFlag(ScriptVerifyDiscourageUpgradeablePubkeyType) {
+			str := fmt.Sprintf("tapscript public key has unknown "+
+				"size %d", len(pkBytes))
+			return false, scriptError(ErrDiscourageUpgradeablePubKeyType,
+				str)
+		}
+		return len(sigBytes) != 0, nil
+	}
+}
+
+// isValidTaprootSigHash returns whether or not the passed hash type is allowed
+// for taproot signatures.
+func isValidTaprootSigHash(hashType SigHashType) bool {
+	switch hashType {
+	case SigHashDefault, SigHashAll, SigHashNone, SigHashSingle,
+		SigHashAll | SigHashAnyOneCanPay,
+		SigHashNone | SigHashAnyOneCanPay,
+		SigHashSingle | SigHashAnyOneCanPay:
+
+		return true
+	}
+
+	return false
+}
+
+// calcTaprootSignatureHash computes the BIP0341 signature hash of the input at
+// the passed index.  The annex is only committed to when it is not nil, while
+// the tapscript extension defined by BIP0342 is only committed to when the
+// leaf hash is not nil.
+func calcTaprootSignatureHash(sigHashes *TxSigHashes, hashType SigHashType,
+	tx *wire.MsgTx, idx int, prevOutFetcher PrevOutputFetcher, annex []byte,
+	tapLeafHash *chainhash.Hash, codeSepPos uint32) ([]byte, error) {
+
+	if !isValidTaprootSigHash(hashType) {
+		str := fmt.Sprintf("invalid taproot hash type 0x%x", hashType)
+		return nil, scriptError(ErrInvalidSigHashType, str)
+	}
+	if idx < 0 || idx >= len(tx.TxIn) {
+		str := fmt.Sprintf("idx %d but %d txins", idx, len(tx.TxIn))
+		return nil, scriptError(ErrInvalidIndex, str)
+	}
+
+	outputType := hashType & 0x03
+	if hashType == SigHashDefault {
+		outputType = SigHashAll
+	}
+	anyoneCanPay := hashType&SigHashAnyOneCanPay != 0
+	if outputType == SigHashSingle && idx >= len(tx.TxOut) {
+		str := fmt.Sprintf("idx %d but %d txouts", idx, len(tx.TxOut))
+		return nil, scriptError(ErrInvalidIndex, str)
+	}
+
+	// The message starts with the sighash epoch followed by the hash type
+	// and the transaction data.
+	var sigMsg bytes.Buffer
+	var buf [8]byte
+	sigMsg.WriteByte(0x00)
+	sigMsg.WriteByte(byte(hashType))
+	binary.LittleEndian.PutUint32(buf[:4], uint32(tx.Version))
+	sigMsg.Write(buf[:4])
+	binary.LittleEndian.PutUint32(buf[:4], tx.LockTime)
+	sigMsg.Write(buf[:4])
+	if !anyoneCanPay {
+		sigMsg.Write(sigHashes.HashPrevOutsV1[:])
+		sigMsg.Write(sigHashes.HashInputAmountsV1[:])
+		sigMsg.Write(sigHashes.HashInputScriptsV1[:])
+		sigMsg.Write(sigHashes.HashSequenceV1[:])
+	}
+	if outputType != SigHashNone && outputType != SigHashSingle {
+		sigMsg.Write(sigHashes.HashOutputsV1[:])
+	}
+
+	// Next is the data about the input being signed.
+	var spendType byte
+	if tapLeafHash != nil {
+		spendType |= 2
+	}
+	if annex != nil {
+		spendType |= 1
+	}
+	sigMsg.WriteByte(spendType)
+	if anyoneCanPay {
+		txIn := tx.TxIn[idx]
+		prevOut := prevOutFetcher.FetchPrevOutput(txIn.PreviousOutPoint)
+		if prevOut == nil {
+			return nil, scriptError(ErrInternal, "spent output is "+
+				"unknown")
+		}
+
+		sigMsg.Write(txIn.PreviousOutPoint.Hash[:])
+		binary.LittleEndian.PutUint32(buf[:4], txIn.PreviousOutPoint.Index)
+		sigMsg.Write(buf[:4])
+		binary.LittleEndian.PutUint64(buf[:], uint64(prevOut.Value))
+		sigMsg.Write(buf[:])
+		wire.WriteVarBytes(&sigMsg, 0, prevOut.PkScript)
+		binary.LittleEndian.PutUint32(buf[:4], txIn.Sequence)
+		sigMsg.Write(buf[:4])
+	} else {
+		binary.LittleEndian.PutUint32(buf[:4], uint32(idx))
+		sigMsg.Write(buf[:4])
+	}
+	if annex != nil {
+		var b bytes.Buffer
+		wire.WriteVarBytes(&b, 0, annex)
+		sigMsg.Write(chainhash.HashB(b.Bytes()))
+	}
+
+	// Then the data about the output being signed, if any.
+	if outputType == SigHashSingle {
+		var b bytes.Buffer
+		wire.WriteTxOut(&b, 0, 0, tx.TxOut[idx])
+		sigMsg.Write(chainhash.HashB(b.Bytes()))
+	}
+
+	// Finally the tapscript extension.
+	if tapLeafHash != nil {
+		sigMsg.Write(tapLeafHash[:])
+		sigMsg.WriteByte(0x00)
+		binary.LittleEndian.PutUint32(buf[:4], codeSepPos)
+		sigMsg.Write(buf[:4])
+	}
+
+	return chainhash.TaggedHash(tapSighashTag, sigMsg.Bytes())[:], nil
+}
+
+// CalcTaprootSignatureHash computes the BIP0341 signature hash for a key path
+// spend of the specified input of the target transaction observing the desired
+// hash type.  The midstates must have been computed with a fetcher for the
+// outputs spent by the transaction.
+func CalcTaprootSignatureHash(sigHashes *TxSigHashes, hType SigHashType,
+	tx *wire.MsgTx, idx int, prevOutFetcher PrevOutputFetcher) ([]byte, error) {
+
+	if !sigHashes.hasTaprootHashes {
+		return nil, fmt.Errorf("taproot sighash midstates are missing")
+	}
+
+	return calcTaprootSignatureHash(sigHashes, hType, tx, idx,
+		prevOutFetcher, nil, nil, blankCodeSepValue)
+}
+
+// CalcTapscriptSignatureHash computes the BIP0342 signature hash for a script
+// path spend of the specified input of the target transaction using the
+// tapscript with the passed leaf hash.  It assumes the signature is checked
+// without any preceding OP_CODESEPARATOR.
+func CalcTapscriptSignatureHash(sigHashes *TxSigHashes, hType SigHashType,
+	tx *wire.MsgTx, idx int, prevOutFetcher PrevOutputFetcher,
+	tapLeafHash chainhash.Hash) ([]byte, error) {
+
+	if !sigHashes.hasTaprootHashes {
+		return nil, fmt.Errorf("taproot sighash midstates are missing")
+	}
+
+	return calcTaprootSignatureHash(sigHashes, hType, tx, idx,
+		prevOutFetcher, nil, &tapLeafHash, blankCodeSepValue)
+}
@@ -0,0 +1,494 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/brsuite/brond/bronec"
+	"github.com/brsuite/brond/chaincfg/chainhash"
+	"github.com/brsuite/brond/wire"
+)
+
+const (
+	// tstInternalKey, tstKeyA and tstKeyB are the private keys used
+	// throughout the taproot tests.
+	tstInternalKey = "b7e151628aed2a6abf7158809cf4f3c762e7160f38b4da56a784d9045190cfef"
+	tstKeyA        = "c90fdaa22168c234c4c6628b80dc1cd129024e088a67cc74020bbea63b14e5c9"
+	tstKeyB        = "0b432b2677937381aef05bb02a66ecd012773062cf3fa2549e44f58ed2401710"
+
+	// tstTaprootFlags are the consensus flags used to validate taproot
+	// spends.
+	tstTaprootFlags = ScriptBip16 | ScriptVerifyWitness | ScriptVerifyTaproot
+)
+
+// newTaprootTestTx returns a transaction spending a single output with the
+// passed public key script and paying to that same script, along with a fetcher
+// for the spent output.
+func newTaprootTestTx(pkScript []byte) (*wire.MsgTx, PrevOutputFetcher) {
+	prevHash := chainhash.HashH([]byte("taproot"))
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prevHash, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(90000, pkScript))
+	return tx, NewCannedPrevOutputFetcher(pkScript, 100000)
+}
+
+// tstSignSchnorr signs the passed hash with the passed private key using
+// all-zero auxiliary data so the produced signatures are deterministic.
+func tstSignSchnorr(t *testing.T, privKey *bronec.PrivateKey, hash []byte) []byte {
+	t.Helper()
+
+	sig, err := bronec.SignSchnorr(privKey, hash, make([]byte, 32))
+	if err != nil {
+		t.Fatalf("unable to sign: %v", err)
+	}
+	return sig.Serialize()
+}
+
+// TestTaprootKeyPathSpend ensures the output key and signature hash of a key
+// path spend match values computed independently from BIP0341 and that the
+// engine only accepts valid signatures for the output key.
+func TestTaprootKeyPathSpend(t *testing.T) {
+	t.Parallel()
+
+	privKey, pubKey := bronec.PrivKeyFromBytes(bronec.S256(),
+		hexToBytes(tstInternalKey))
+	outputKey, err := ComputeTaprootOutputKey(pubKey, nil)
+	if err != nil {
+		t.Fatalf("ComputeTaprootOutputKey: unexpected error: %v", err)
+	}
+	wantOutputKey := hexToBytes("7ad4375032c38eba4fc60deca75fa30a3a6b" +
+		"df2fb38f7e617288e2d3776117cb")
+	if !bytes.Equal(outputKey.SerializeSchnorr(), wantOutputKey) {
+		t.Fatalf("ComputeTaprootOutputKey: got %x, want %x",
+			outputKey.SerializeSchnorr(), wantOutputKey)
+	}
+
+	// The tweaked private key must correspond to the output key.
+	tweakedKey, err := TweakTaprootPrivKey(privKey, nil)
+	if err != nil {
+		t.Fatalf("TweakTaprootPrivKey: unexpected error: %v", err)
+	}
+	if !bytes.Equal(tweakedKey.PubKey().SerializeSchnorr(), wantOutputKey) {
+		t.Fatalf("TweakTaprootPrivKey: got key %x, want %x",
+			tweakedKey.PubKey().SerializeSchnorr(), wantOutputKey)
+	}
+
+	pkScript, err := PayToTaprootScript(outputKey)
+	if err != nil {
+		t.Fatalf("PayToTaprootScript: unexpected error: %v", err)
+	}
+	if class := GetScriptClass(pkScript); class != WitnessV1TaprootTy {
+		t.Fatalf("GetScriptClass: got %v, want %v", class,
+			WitnessV1TaprootTy)
+	}
+
+	tx, prevOutFetcher := newTaprootTestTx(pkScript)
+	sigHashes := NewTxSigHashes(tx, prevOutFetcher)
+	hash, err := CalcTaprootSignatureHash(sigHashes, SigHashDefault, tx, 0,
+		prevOutFetcher)
+	if err != nil {
+		t.Fatalf("CalcTaprootSignatureHash: unexpected error: %v", err)
+	}
+	wantHash := hexToBytes("f237ae936aed79cf659390ed521b55a1d65c699de5e8" +
+		"42dab5eb2f4acc11e50c")
+	if !bytes.Equal(hash, wantHash) {
+		t.Fatalf("CalcTaprootSignatureHash: got %x, want %x", hash,
+			wantHash)
+	}
+
+	// Create signatures for the default and SigHashAll hash types as well
+	// as one that commits to an annex.
+	sig := tstSignSchnorr(t, tweakedKey, hash)
+	allHash, err := CalcTaprootSignatureHash(sigHashes, SigHashAll, tx, 0,
+		prevOutFetcher)
+	if err != nil {
+		t.Fatalf("CalcTaprootSignatureHash: unexpected error: %v", err)
+	}
+	sigAll := append(tstSignSchnorr(t, tweakedKey, allHash), byte(SigHashAll))
+	annex := []byte{taprootAnnexTag, 0x01, 0x02}
+	annexHash, err := calcTaprootSignatureHash(sigHashes, SigHashDefault, tx,
+		0, prevOutFetcher, annex, nil, blankCodeSepValue)
+	if err != nil {
+		t.Fatalf("calcTaprootSignatureHash: unexpected error: %v", err)
+	}
+	sigAnnex := tstSignSchnorr(t, tweakedKey, annexHash)
+	badSig := append([]byte(nil), sig...)
+	badSig[10] ^= 0x01
+
+	tests := []struct {
+		name    string
+		witness wire.TxWitness
+		flags   ScriptFlags
+		err     error
+	}{{
+		name:    "default hash type",
+		witness: wire.TxWitness{sig},
+		flags:   tstTaprootFlags,
+	}, {
+		name:    "default hash type with standard flags",
+		witness: wire.TxWitness{sig},
+		flags:   StandardVerifyFlags,
+	}, {
+		name:    "explicit SigHashAll",
+		witness: wire.TxWitness{sigAll},
+		flags:   tstTaprootFlags,
+	}, {
+		name:    "explicit default hash type",
+		witness: wire.TxWitness{append(sig[:64:64], 0x00)},
+		flags:   tstTaprootFlags,
+		err:     scriptError(ErrInvalidTaprootSigLen, ""),
+	}, {
+		name:    "undefined hash type",
+		witness: wire.TxWitness{append(sig[:64:64], 0x04)},
+		flags:   tstTaprootFlags,
+		err:     scriptError(ErrInvalidSigHashType, ""),
+	}, {
+		name:    "signature for different hash type",
+		witness: wire.TxWitness{append(sig[:64:64], byte(SigHashAll))},
+		flags:   tstTaprootFlags,
+		err:     scriptError(ErrTaprootSigInvalid, ""),
+	}, {
+		name:    "truncated signature",
+		witness: wire.TxWitness{sig[:63]},
+		flags:   tstTaprootFlags,
+		err:     scriptError(ErrInvalidTaprootSigLen, ""),
+	}, {
+		name:    "corrupted signature",
+		witness: wire.TxWitness{badSig},
+		flags:   tstTaprootFlags,
+		err:     scriptError(ErrTaprootSigInvalid, ""),
+	}, {
+		name:    "signature committing to annex",
+		witness: wire.TxWitness{sigAnnex, annex},
+		flags:   tstTaprootFlags,
+	}, {
+		name:    "annex not committed to",
+		witness: wire.TxWitness{sig, annex},
+		flags:   tstTaprootFlags,
+		err:     scriptError(ErrTaprootSigInvalid, ""),
+	}, {
+		name:  "empty witness",
+		flags: tstTaprootFlags,
+		err:   scriptError(ErrWitnessProgramEmpty, ""),
+	}, {
+		name:    "taproot not active",
+		witness: wire.TxWitness{badSig},
+		flags:   ScriptBip16 | ScriptVerifyWitness,
+	}, {
+		name:    "taproot not active with standard flags",
+		witness: wire.TxWitness{sig},
+		flags: StandardVerifyFlags &^ (ScriptVerifyTaproot |
+			ScriptVerifyDiscourageUpgradeableTaprootVersion |
+			ScriptVerifyDiscourageOpSuccess |
+			ScriptVerifyDiscourageUpgradeablePubkeyType),
+		err: scriptError(ErrDiscourageUpgradableWitnessProgram, ""),
+	}}
+
+	for _, test := range tests {
+		tx.TxIn[0].Witness = test.witness
+		vm, err := NewEngine(pkScript, tx, 0, test.flags, nil, nil, 100000,
+			prevOutFetcher)
+		if err == nil {
+			err = vm.Execute()
+		}
+		if e := tstCheckScriptError(err, test.err); e != nil {
+			t.Errorf("%s: %v", test.name, e)
+		}
+	}
+}
+
+// TestTaprootScriptPathSpend ensures the commitments and signature hashes of
+// script path spends match values computed independently from BIP0341 and that
+// tapscripts are executed according to BIP0342.
+func TestTaprootScriptPathSpend(t *testing.T) {
+	t.Parallel()
+
+	_, internalKey := bronec.PrivKeyFromBytes(bronec.S256(),
+		hexToBytes(tstInternalKey))
+	privKeyA, pubKeyA := bronec.PrivKeyFromBytes(bronec.S256(),
+		hexToBytes(tstKeyA))
+	privKeyB, pubKeyB := bronec.PrivKeyFromBytes(bronec.S256(),
+		hexToBytes(tstKeyB))
+
+	// Create a script tree with a leaf requiring a signature for key A and
+	// a leaf requiring signatures for both keys.
+	scriptA, err := NewScriptBuilder().AddData(pubKeyA.SerializeSchnorr()).
+		AddOp(OP_CHECKSIG).Script()
+	if err != nil {
+		t.Fatalf("unable to build script: %v", err)
+	}
+	scriptB, err := NewScriptBuilder().AddData(pubKeyA.SerializeSchnorr()).
+		AddOp(OP_CHECKSIG).AddData(pubKeyB.SerializeSchnorr()).
+		AddOp(OP_CHECKSIGADD).AddOp(OP_2).AddOp(OP_NUMEQUAL).Script()
+	if err != nil {
+		t.Fatalf("unable to build script: %v", err)
+	}
+	leafA := TapLeafHash(BaseLeafVersion, scriptA)
+	leafB := TapLeafHash(BaseLeafVersion, scriptB)
+	root := TapBranchHash(leafA[:], leafB[:])
+	wantRoot := hexToBytes("a6d43494cd24ed7a2fc7c2fc4d8da4cdce8c4adc05fb" +
+		"25040aed59c8bdb07c41")
+	if !bytes.Equal(root[:], wantRoot) {
+		t.Fatalf("TapBranchHash: got %x, want %x", root, wantRoot)
+	}
+	if swapped := TapBranchHash(leafB[:], leafA[:]); swapped != root {
+		t.Fatalf("TapBranchHash: got %x for swapped children, want %x",
+			swapped, root)
+	}
+
+	outputKey, err := ComputeTaprootOutputKey(internalKey, root[:])
+	if err != nil {
+		t.Fatalf("ComputeTaprootOutputKey: unexpected error: %v", err)
+	}
+	wantOutputKey := hexToBytes("44703fc71588f30d939713ec8ee8b254b12a9e07" +
+		"433223dcbb2c4b59208eebb9")
+	if !bytes.Equal(outputKey.SerializeSchnorr(), wantOutputKey) {
+		t.Fatalf("ComputeTaprootOutputKey: got %x, want %x",
+			outputKey.SerializeSchnorr(), wantOutputKey)
+	}
+	parity := byte(outputKey.Y.Bit(0))
+
+	pkScript, err := PayToTaprootScript(outputKey)
+	if err != nil {
+		t.Fatalf("PayToTaprootScript: unexpected error: %v", err)
+	}
+	tx, prevOutFetcher := newTaprootTestTx(pkScript)
+	sigHashes := NewTxSigHashes(tx, prevOutFetcher)
+	hashA, err := CalcTapscriptSignatureHash(sigHashes, SigHashDefault, tx,
+		0, prevOutFetcher, leafA)
+	if err != nil {
+		t.Fatalf("CalcTapscriptSignatureHash: unexpected error: %v", err)
+	}
+	wantHash := hexToBytes("e8ebb49d2124a5c00a123a8dcd760c0ee483fd3f0cfe" +
+		"92e9b52b1c04d2bd4645")
+	if !bytes.Equal(hashA, wantHash) {
+		t.Fatalf("CalcTapscriptSignatureHash: got %x, want %x", hashA,
+			wantHash)
+	}
+	hashB, err := CalcTapscriptSignatureHash(sigHashes, SigHashDefault, tx,
+		0, prevOutFetcher, leafB)
+	if err != nil {
+		t.Fatalf("CalcTapscriptSignatureHash: unexpected error: %v", err)
+	}
+	sigA := tstSignSchnorr(t, privKeyA, hashA)
+	sigAB := tstSignSchnorr(t, privKeyA, hashB)
+	sigBB := tstSignSchnorr(t, privKeyB, hashB)
+
+	// controlBlock returns a control block for the internal key with the
+	// passed first byte and merkle path.
+	controlBlock := func(first byte, path ...[]byte) []byte {
+		cb := append([]byte{first}, internalKey.SerializeSchnorr()...)
+		for _, node := range path {
+			cb = append(cb, node...)
+		}
+		return cb
+	}
+	cbA := controlBlock(BaseLeafVersion|parity, leafB[:])
+	cbB := controlBlock(BaseLeafVersion|parity, leafA[:])
+
+	tests := []struct {
+		name    string
+		witness wire.TxWitness
+		err     error
+	}{{
+		name:    "checksig leaf",
+		witness: wire.TxWitness{sigA, scriptA, cbA},
+	}, {
+		name:    "checksigadd leaf",
+		witness: wire.TxWitness{sigBB, sigAB, scriptB, cbB},
+	}, {
+		name:    "checksigadd leaf with empty signature",
+		witness: wire.TxWitness{nil, sigAB, scriptB, cbB},
+		err:     scriptError(ErrEvalFalse, ""),
+	}, {
+		name:    "signature for other leaf",
+		witness: wire.TxWitness{sigBB, sigA, scriptB, cbB},
+		err:     scriptError(ErrTaprootSigInvalid, ""),
+	}, {
+		name:    "unclean stack",
+		witness: wire.TxWitness{{0x01}, sigA, scriptA, cbA},
+		err:     scriptError(ErrCleanStack, ""),
+	}, {
+		name: "wrong output key parity",
+		witness: wire.TxWitness{sigA, scriptA,
+			controlBlock(BaseLeafVersion|(parity^1), leafB[:])},
+		err: scriptError(ErrTaprootMerkleProofInvalid, ""),
+	}, {
+		name:    "wrong merkle path",
+		witness: wire.TxWitness{sigA, scriptA, cbB},
+		err:     scriptError(ErrTaprootMerkleProofInvalid, ""),
+	}, {
+		name: "wrong leaf version",
+		witness: wire.TxWitness{sigA, scriptA,
+			controlBlock(0xc2|parity, leafB[:])},
+		err: scriptError(ErrTaprootMerkleProofInvalid, ""),
+	}, {
+		name:    "truncated control block",
+		witness: wire.TxWitness{sigA, scriptA, cbA[:40]},
+		err:     scriptError(ErrControlBlockInvalidLength, ""),
+	}}
+
+	for _, test := range tests {
+		tx.TxIn[0].Witness = test.witness
+		vm, err := NewEngine(pkScript, tx, 0, StandardVerifyFlags, nil,
+			nil, 100000, prevOutFetcher)
+		if err == nil {
+			err = vm.Execute()
+		}
+		if e := tstCheckScriptError(err, test.err); e != nil {
+			t.Errorf("%s: %v", test.name, e)
+		}
+	}
+}
+
+// TestTapscriptRules ensures the rules specific to tapscripts and unknown leaf
+// versions are enforced for script trees with a single leaf.
+func TestTapscriptRules(t *testing.T) {
+	t.Parallel()
+
+	_, internalKey := bronec.PrivKeyFromBytes(bronec.S256(),
+		hexToBytes(tstInternalKey))
+
+	// A script performing more signature checks than the budget granted
+	// by its witness allows.
+	var sigOpsScript []byte
+	for i := 0; i < 10; i++ {
+		sigOpsScript = append(sigOpsScript, OP_DUP, OP_DATA_2, 0x01,
+			0x02, OP_CHECKSIGVERIFY)
+	}
+
+	tests := []struct {
+		name        string
+		leafVersion byte
+		script      []byte
+		stack       [][]byte
+		flags       ScriptFlags
+		err         error
+	}{{
+		name:        "OP_SUCCESS",
+		leafVersion: BaseLeafVersion,
+		script:      []byte{OP_RESERVED},
+		flags:       tstTaprootFlags,
+	}, {
+		name:        "OP_SUCCESS before parse failure",
+		leafVersion: BaseLeafVersion,
+		script:      []byte{OP_RETURN, 0xbb, OP_PUSHDATA1},
+		flags:       tstTaprootFlags,
+	}, {
+		name:        "parse failure before OP_SUCCESS",
+		leafVersion: BaseLeafVersion,
+		script:      []byte{OP_PUSHDATA1, 0x02, 0xbb},
+		flags:       tstTaprootFlags,
+		err:         scriptError(ErrMalformedPush, ""),
+	}, {
+		name:        "OP_SUCCESS discouraged",
+		leafVersion: BaseLeafVersion,
+		script:      []byte{OP_RESERVED},
+		flags:       StandardVerifyFlags,
+		err:         scriptError(ErrDiscourageOpSuccess, ""),
+	}, {
+		name:        "unknown leaf version",
+		leafVersion: 0xc2,
+		script:      []byte{OP_RETURN},
+		flags:       tstTaprootFlags,
+	}, {
+		name:        "unknown leaf version discouraged",
+		leafVersion: 0xc2,
+		script:      []byte{OP_RETURN},
+		flags:       StandardVerifyFlags,
+		err:         scriptError(ErrDiscourageUpgradeableTaprootVersion, ""),
+	}, {
+		name:        "checkmultisig disabled",
+		leafVersion: BaseLeafVersion,
+		script:      []byte{OP_0, OP_0, OP_0, OP_CHECKMULTISIG},
+		flags:       tstTaprootFlags,
+		err:         scriptError(ErrTapscriptCheckMultisig, ""),
+	}, {
+		name:        "empty public key",
+		leafVersion: BaseLeafVersion,
+		script:      []byte{OP_0, OP_CHECKSIG},
+		stack:       [][]byte{{0x01}},
+		flags:       tstTaprootFlags,
+		err:         scriptError(ErrTaprootPubkeyIsEmpty, ""),
+	}, {
+		name:        "unknown public key type",
+		leafVersion: BaseLeafVersion,
+		script:      []byte{OP_DATA_2, 0x01, 0x02, OP_CHECKSIG},
+		stack:       [][]byte{{0x01}},
+		flags:       tstTaprootFlags,
+	}, {
+		name:        "unknown public key type discouraged",
+		leafVersion: BaseLeafVersion,
+		script:      []byte{OP_DATA_2, 0x01, 0x02, OP_CHECKSIG},
+		stack:       [][]byte{{0x01}},
+		flags:       StandardVerifyFlags,
+		err:         scriptError(ErrDiscourageUpgradeablePubKeyType, ""),
+	}, {
+		name:        "signature operation budget exceeded",
+		leafVersion: BaseLeafVersion,
+		script:      sigOpsScript,
+		stack:       [][]byte{{0x01}},
+		flags:       tstTaprootFlags,
+		err:         scriptError(ErrTaprootMaxSigOps, ""),
+	}, {
+		name:        "signature operation budget not exceeded",
+		leafVersion: BaseLeafVersion,
+		script:      sigOpsScript[:5],
+		stack:       [][]byte{{0x01}},
+		flags:       tstTaprootFlags,
+	}, {
+		name:        "minimal if is consensus",
+		leafVersion: BaseLeafVersion,
+		script:      []byte{OP_IF, OP_1, OP_ELSE, OP_1, OP_ENDIF},
+		stack:       [][]byte{{0x02}},
+		flags:       tstTaprootFlags,
+		err:         scriptError(ErrMinimalIf, ""),
+	}, {
+		name:        "checksigadd with unknown public key type",
+		leafVersion: BaseLeafVersion,
+		script:      []byte{OP_0, OP_DATA_2, 0x01, 0x02, OP_CHECKSIGADD},
+		stack:       [][]byte{{0x01}},
+		flags:       tstTaprootFlags,
+	}, {
+		name:        "stack element too big",
+		leafVersion: BaseLeafVersion,
+		script:      []byte{OP_DROP, OP_1},
+		stack:       [][]byte{make([]byte, MaxScriptElementSize+1)},
+		flags:       tstTaprootFlags,
+		err:         scriptError(ErrElementTooBig, ""),
+	}}
+
+	for _, test := range tests {
+		leaf := TapLeafHash(test.leafVersion, test.script)
+		outputKey, err := ComputeTaprootOutputKey(internalKey, leaf[:])
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		pkScript, err := PayToTaprootScript(outputKey)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		cb := append([]byte{test.leafVersion | byte(outputKey.Y.Bit(0))},
+			internalKey.SerializeSchnorr()...)
+		witness := append(wire.TxWitness{}, test.stack...)
+		witness = append(witness, test.script, cb)
+
+		tx, prevOutFetcher := newTaprootTestTx(pkScript)
+		tx.TxIn[0].Witness = witness
+		vm, err := NewEngine(pkScript, tx, 0, test.flags, nil, nil, 100000,
+			prevOutFetcher)
+		if err == nil {
+			err = vm.Execute()
+		}
+		if e := tstCheckScriptError(err, test.err); e != nil {
+			t.Errorf("%s: %v", test.name, e)
+		}
+	}
+}
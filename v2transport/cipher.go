@@ -0,0 +1,130 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package v2transport
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// rekeyInterval is the number of messages encrypted with a key before it is
+// replaced by a new one derived from it, which provides forward secrecy.
+const rekeyInterval = 224
+
+// errAuthFailed is returned when a packet fails to be authenticated, which
+// happens when it was modified or the keys of both sides don't match.
+var errAuthFailed = errors.New("packet authentication failed")
+
+// makeNonce returns a 96-bit nonce made of the passed 32-bit and 64-bit
+// counters in little-endian order.
+func makeNonce(counter uint32, rekeyCounter uint64) []byte {
+	nonce := make([]byte, chacha20.NonceSize)
+	binary.LittleEndian.PutUint32(nonce[:4], counter)
+	binary.LittleEndian.PutUint64(nonce[4:], rekeyCounter)
+	return nonce
+}
+
+// fsChaCha20 is the forward secure stream cipher used to encrypt the length
+// field of packets.  The keystream is shared by consecutive chunks, and after
+// every rekeyInterval chunks the key is replaced by the next 32 bytes of the
+// keystream.
+type fsChaCha20 struct {
+	key          []byte
+	cipher       *chacha20.Cipher
+	chunkCounter uint32
+	rekeyCounter uint64
+}
+
+// newFSChaCha20 returns a new forward secure stream cipher using the passed
+// 32-byte initial key.
+func newFSChaCha20(key []byte) *fsChaCha20 {
+	c := &fsChaCha20{key: key}
+	c.resetCipher()
+	return c
+}
+
+// resetCipher restarts the keystream for the current key and rekey counter.
+func (c *fsChaCha20) resetCipher() {
+	// The key and nonce always have the right size, so this can't fail.
+	c.cipher, _ = chacha20.NewUnauthenticatedCipher(c.key,
+		makeNonce(0, c.rekeyCounter))
+}
+
+// crypt encrypts or decrypts the passed chunk in place.
+func (c *fsChaCha20) crypt(chunk []byte) {
+	c.cipher.XORKeyStream(chunk, chunk)
+
+	c.chunkCounter++
+	if c.chunkCounter == rekeyInterval {
+		newKey := make([]byte, chacha20.KeySize)
+		c.cipher.XORKeyStream(newKey, newKey)
+		c.key = newKey
+		c.chunkCounter = 0
+		c.rekeyCounter++
+		c.resetCipher()
+	}
+}
+
+// fsChaCha20Poly1305 is the forward secure authenticated cipher used to
+// encrypt the contents of packets.  Each packet is encrypted with a nonce made
+// of its number since the last rekey, and after every rekeyInterval packets the
+// key is replaced by 32 bytes of keystream for a nonce no packet uses.
+type fsChaCha20Poly1305 struct {
+	aead          cipher.AEAD
+	packetCounter uint32
+	rekeyCounter  uint64
+}
+
+// newFSChaCha20Poly1305 returns a new forward secure authenticated cipher
+// using the passed 32-byte initial key.
+func newFSChaCha20Poly1305(key []byte) *fsChaCha20Poly1305 {
+	// The key always has the right size, so this can't fail.
+	aead, _ := chacha20poly1305.New(key)
+	return &fsChaCha20Poly1305{aead: aead}
+}
+
+// nextPacket moves on to the nonce of the next packet and rekeys when needed.
+func (c *fsChaCha20Poly1305) nextPacket() {
+	c.packetCounter++
+	if c.packetCounter != rekeyInterval {
+		return
+	}
+
+	// The ciphertext of zero bytes is the raw keystream after the block
+	// used for the authentication key.
+	nonce := makeNonce(0xffffffff, c.rekeyCounter)
+	keystream := c.aead.Seal(nil, nonce, make([]byte, chacha20.KeySize), nil)
+	c.aead, _ = chacha20poly1305.New(keystream[:chacha20.KeySize])
+	c.packetCounter = 0
+	c.rekeyCounter++
+}
+
+// encrypt appends the encryption of the plaintext with its authentication tag
+// to dst and returns the result.  The additional data is authenticated but not
+// encrypted.
+func (c *fsChaCha20Poly1305) encrypt(dst, plaintext, aad []byte) []byte {
+	nonce := makeNonce(c.packetCounter, c.rekeyCounter)
+	ciphertext := c.aead.Seal(dst, nonce, plaintext, aad)
+	c.nextPacket()
+	return ciphertext
+}
+
+// decrypt authenticates and decrypts the ciphertext, which includes its
+// authentication tag, along with the additional data, and appends the
+// plaintext to dst.  The packet counts as received even when it fails to be
+// authenticated, so the connection must not be used after an error.
+func (c *fsChaCha20Poly1305) decrypt(dst, ciphertext, aad []byte) ([]byte, error) {
+	nonce := makeNonce(c.packetCounter, c.rekeyCounter)
+	plaintext, err := c.aead.Open(dst, nonce, ciphertext, aad)
+	c.nextPacket()
+	if err != nil {
+		return nil, errAuthFailed
+	}
+	return plaintext, nil
+}
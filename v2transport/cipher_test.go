@@ -0,0 +1,89 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package v2transport
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestFSChaCha20 ensures the forward secure stream cipher decrypts what it
+// encrypted across rekeys, and that it rekeys after the expected number of
+// chunks.
+func TestFSChaCha20(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	enc, dec := newFSChaCha20(key), newFSChaCha20(key)
+	for i := 0; i < 3*rekeyInterval; i++ {
+		chunk := []byte{byte(i), byte(i >> 8), 0x5a}
+		buf := append([]byte(nil), chunk...)
+		enc.crypt(buf)
+		if bytes.Equal(buf, chunk) {
+			t.Fatalf("chunk %d was not encrypted", i)
+		}
+		dec.crypt(buf)
+		if !bytes.Equal(buf, chunk) {
+			t.Fatalf("chunk %d: got %x after decryption, want %x",
+				i, buf, chunk)
+		}
+	}
+	if enc.rekeyCounter != 3 || enc.chunkCounter != 0 {
+		t.Fatalf("got rekey counter %d and chunk counter %d, want 3 "+
+			"and 0", enc.rekeyCounter, enc.chunkCounter)
+	}
+	if bytes.Equal(enc.key, key) {
+		t.Fatal("key was not replaced when rekeying")
+	}
+}
+
+// TestFSChaCha20Poly1305 ensures the forward secure authenticated cipher
+// decrypts what it encrypted across rekeys, and that modified packets and
+// additional data fail to be authenticated.
+func TestFSChaCha20Poly1305(t *testing.T) {
+	key := bytes.Repeat([]byte{0x24}, 32)
+	enc, dec := newFSChaCha20Poly1305(key), newFSChaCha20Poly1305(key)
+	aad := []byte("aad")
+	for i := 0; i < 2*rekeyInterval+1; i++ {
+		plaintext := bytes.Repeat([]byte{byte(i)}, i%50)
+		ciphertext := enc.encrypt(nil, plaintext, aad)
+		got, err := dec.decrypt(nil, ciphertext, aad)
+		if err != nil {
+			t.Fatalf("packet %d: unexpected error %v", i, err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Fatalf("packet %d: got %x, want %x", i, got, plaintext)
+		}
+	}
+	if enc.rekeyCounter != 2 || enc.packetCounter != 1 {
+		t.Fatalf("got rekey counter %d and packet counter %d, want 2 "+
+			"and 1", enc.rekeyCounter, enc.packetCounter)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(ciphertext, aad []byte) ([]byte, []byte)
+	}{
+		{"modified ciphertext", func(c, a []byte) ([]byte, []byte) {
+			c[0] ^= 1
+			return c, a
+		}},
+		{"modified tag", func(c, a []byte) ([]byte, []byte) {
+			c[len(c)-1] ^= 1
+			return c, a
+		}},
+		{"modified aad", func(c, a []byte) ([]byte, []byte) {
+			return c, []byte("bad")
+		}},
+	}
+	for _, test := range tests {
+		enc, dec := newFSChaCha20Poly1305(key), newFSChaCha20Poly1305(key)
+		ciphertext, modifiedAAD := test.modify(enc.encrypt(nil,
+			[]byte("data"), aad), aad)
+		_, err := dec.decrypt(nil, ciphertext, modifiedAAD)
+		if err != errAuthFailed {
+			t.Errorf("%s: got error %v, want %v", test.name, err,
+				errAuthFailed)
+		}
+	}
+}
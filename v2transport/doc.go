@@ -0,0 +1,37 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+/*
+Package v2transport implements the encrypted v2 transport protocol for
+connections between brocoin peers defined by BIP0324.
+
+Overview
+
+With the original v1 protocol, messages are sent in plaintext behind a header
+with the network magic and the command, so anyone on the path of a connection
+can see and modify what the peers exchange.  The v2 protocol encrypts and
+authenticates all traffic, making a connection look like a stream of random
+bytes from its first byte on.
+
+Each side starts by sending an ephemeral public key encoded with ElligatorSwift
+followed by a random amount of garbage.  The keys of the session are derived
+from the shared secret of the key exchange, and each side then sends a garbage
+terminator and a version packet which authenticates the garbage it sent.
+
+Messages are sent in packets whose length is encrypted with a ChaCha20 stream
+and whose contents are encrypted and authenticated with ChaCha20-Poly1305.
+Both ciphers replace their keys at regular intervals for forward secrecy.  The
+most common messages are identified by a one byte short ID instead of their
+command, see wire.EncodeV2Message.
+
+Falling Back To The v1 Protocol
+
+Peers which don't support the v2 protocol disconnect when they receive a
+public key instead of a version message, so an initiator should only attempt
+the v2 protocol with peers advertising wire.SFNodeP2PV2 and reconnect with the
+v1 protocol when the handshake fails.  A responder detects initiators which use
+the v1 protocol from the first bytes they send, in which case Handshake returns
+ErrV1Peer and V1Prefix returns the bytes which were already read.
+*/
+package v2transport
@@ -0,0 +1,406 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package v2transport
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/brsuite/brond/bronec"
+	"github.com/brsuite/brond/wire"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// MaxGarbageLen is the maximum number of random garbage bytes sent
+	// after the public key during the handshake.
+	MaxGarbageLen = 4095
+
+	// MaxContentsLen is the maximum length of the contents of a packet,
+	// which is the largest value of its 3-byte length field.
+	MaxContentsLen = 1<<24 - 1
+
+	// maxRecvContentsLen is the maximum length of the contents of a
+	// received packet, which is the length of the largest message along
+	// with its command.  Larger packets are rejected before anything is
+	// allocated for them since they can't be authenticated until they
+	// have been read completely.
+	maxRecvContentsLen = 1 + wire.CommandSize + wire.MaxBlockPayload
+
+	// garbageTerminatorLen is the length of the garbage terminators which
+	// mark the end of the garbage.
+	garbageTerminatorLen = 16
+
+	// lengthFieldLen is the length of the encrypted length field at the
+	// start of each packet.
+	lengthFieldLen = 3
+
+	// headerLen is the length of the header byte which is encrypted along
+	// with the contents of each packet.
+	headerLen = 1
+
+	// ignoreBit is the bit of the header byte which marks decoy packets,
+	// whose contents must be ignored.
+	ignoreBit = 0x80
+
+	// v1PrefixLen is the number of bytes at the start of a connection which
+	// identify a peer using the v1 transport protocol.
+	v1PrefixLen = 16
+)
+
+// ErrV1Peer is returned by Handshake when the remote peer of an inbound
+// connection uses the v1 transport protocol.  The bytes already read from the
+// connection are returned by V1Prefix so they can be handed to the v1 message
+// reader.
+var ErrV1Peer = errors.New("remote peer uses the v1 transport protocol")
+
+// sharedSecretSalt is the prefix of the salt used to derive the keys of a
+// session from the shared secret.  It is followed by the network magic.
+const sharedSecretSalt = "brocoin_v2_shared_secret"
+
+// Transport is a connection using the encrypted v2 transport protocol defined
+// by BIP0324.  The handshake must be performed with Handshake before messages
+// can be exchanged.  Once it succeeded, ReadMessage and WriteMessage may be
+// called concurrently with each other, but neither of them concurrently with
+// itself.
+type Transport struct {
+	rw        io.ReadWriter
+	bronnet   wire.BrocoinNet
+	initiator bool
+
+	// v1Prefix holds the first bytes read from the connection by a
+	// responder which found out the remote peer uses the v1 protocol.
+	v1Prefix []byte
+
+	sendL        *fsChaCha20
+	sendP        *fsChaCha20Poly1305
+	recvL        *fsChaCha20
+	recvP        *fsChaCha20Poly1305
+	sessionID    [32]byte
+	sendGarbage  []byte
+	recvGarbage  []byte
+	sendTerm     []byte
+	recvTerm     []byte
+	aadPending   bool
+	recvLenBytes [lengthFieldLen]byte
+}
+
+// NewTransport returns a new v2 transport protocol connection over the passed
+// reader and writer, which is normally a network connection, for the brocoin
+// network.  The initiator flag must be set on the side which opened the
+// connection.
+func NewTransport(rw io.ReadWriter, bronnet wire.BrocoinNet, initiator bool) *Transport {
+	return &Transport{
+		rw:        rw,
+		bronnet:   bronnet,
+		initiator: initiator,
+	}
+}
+
+// v1Prefix returns the first bytes of the version message a v1 peer sends,
+// which are the network magic followed by the zero padded version command.
+func v1Prefix(bronnet wire.BrocoinNet) []byte {
+	prefix := make([]byte, v1PrefixLen)
+	binary.LittleEndian.PutUint32(prefix, uint32(bronnet))
+	copy(prefix[4:], wire.CmdVersion)
+	return prefix
+}
+
+// V1Prefix returns the bytes read from the connection when Handshake returned
+// ErrV1Peer.  They are the start of the first v1 message of the remote peer.
+func (t *Transport) V1Prefix() []byte {
+	return t.v1Prefix
+}
+
+// SessionID returns the identifier of the session, which both sides derive
+// from their shared secret and may compare out of band to detect a man in the
+// middle.  It is only set once the handshake succeeded.
+func (t *Transport) SessionID() [32]byte {
+	return t.sessionID
+}
+
+// randomGarbage returns a random number of up to MaxGarbageLen random bytes.
+func randomGarbage() ([]byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(MaxGarbageLen+1))
+	if err != nil {
+		return nil, err
+	}
+	garbage := make([]byte, n.Int64())
+	if _, err := rand.Read(garbage); err != nil {
+		return nil, err
+	}
+	return garbage, nil
+}
+
+// deriveKeys derives the keys of the ciphers, the garbage terminators and the
+// session ID from the shared secret of the key exchange.
+func (t *Transport) deriveKeys(secret []byte) error {
+	salt := make([]byte, len(sharedSecretSalt)+4)
+	copy(salt, sharedSecretSalt)
+	binary.LittleEndian.PutUint32(salt[len(sharedSecretSalt):],
+		uint32(t.bronnet))
+	prk := hkdf.Extract(sha256.New, secret, salt)
+	expand := func(info string, n int) ([]byte, error) {
+		b := make([]byte, n)
+		_, err := io.ReadFull(hkdf.Expand(sha256.New, prk, []byte(info)), b)
+		return b, err
+	}
+
+	keys := make(map[string][]byte)
+	for _, info := range []string{"initiator_L", "initiator_P",
+		"responder_L", "responder_P", "session_id"} {
+
+		key, err := expand(info, chacha20poly1305.KeySize)
+		if err != nil {
+			return err
+		}
+		keys[info] = key
+	}
+	terms, err := expand("garbage_terminators", 2*garbageTerminatorLen)
+	if err != nil {
+		return err
+	}
+
+	// The initiator sends with the initiator keys and terminator and the
+	// responder with the responder ones.
+	sendPrefix, recvPrefix := "initiator_", "responder_"
+	sendTerm, recvTerm := terms[:garbageTerminatorLen], terms[garbageTerminatorLen:]
+	if !t.initiator {
+		sendPrefix, recvPrefix = recvPrefix, sendPrefix
+		sendTerm, recvTerm = recvTerm, sendTerm
+	}
+	t.sendL = newFSChaCha20(keys[sendPrefix+"L"])
+	t.sendP = newFSChaCha20Poly1305(keys[sendPrefix+"P"])
+	t.recvL = newFSChaCha20(keys[recvPrefix+"L"])
+	t.recvP = newFSChaCha20Poly1305(keys[recvPrefix+"P"])
+	t.sendTerm, t.recvTerm = sendTerm, recvTerm
+	copy(t.sessionID[:], keys["session_id"])
+	return nil
+}
+
+// Handshake performs the key exchange with the remote peer and exchanges the
+// version packets which complete the handshake.  Each side sends an ephemeral
+// public key encoded with ElligatorSwift followed by random garbage, so the
+// whole connection is indistinguishable from random bytes.
+//
+// A responder first checks whether the remote peer sent the start of a v1
+// version message instead, in which case it returns ErrV1Peer without sending
+// anything.
+func (t *Transport) Handshake() error {
+	privKey, err := bronec.NewPrivateKey(bronec.S256())
+	if err != nil {
+		return err
+	}
+	ourKey, err := privKey.PubKey().SerializeEllSwift()
+	if err != nil {
+		return err
+	}
+	t.sendGarbage, err = randomGarbage()
+	if err != nil {
+		return err
+	}
+
+	// A responder detects v1 peers before sending anything.
+	theirKey := make([]byte, bronec.EllSwiftPubKeyBytesLen)
+	var received int
+	if !t.initiator {
+		if _, err := io.ReadFull(t.rw, theirKey[:v1PrefixLen]); err != nil {
+			return err
+		}
+		if bytes.Equal(theirKey[:v1PrefixLen], v1Prefix(t.bronnet)) {
+			t.v1Prefix = theirKey[:v1PrefixLen]
+			return ErrV1Peer
+		}
+		received = v1PrefixLen
+	}
+
+	// Send the public key and garbage, followed by the garbage terminator
+	// and version packet once the keys are known, from another goroutine
+	// since both sides send before reading what the other one sent.
+	keysReady := make(chan bool, 1)
+	sendErr := make(chan error, 1)
+	go func() {
+		_, err := t.rw.Write(append(ourKey, t.sendGarbage...))
+		if err != nil {
+			sendErr <- err
+			return
+		}
+		if !<-keysReady {
+			sendErr <- nil
+			return
+		}
+		_, err = t.rw.Write(t.sendTerm)
+		if err == nil {
+			_, err = t.writePacket(nil, t.sendGarbage, false)
+		}
+		sendErr <- err
+	}()
+
+	// The sending side is waited for even when receiving fails, so it does
+	// not keep using the connection once the handshake has returned.
+	err = t.receiveHandshake(privKey, ourKey, theirKey, received,
+		keysReady)
+	if serr := <-sendErr; err == nil {
+		err = serr
+	}
+	return err
+}
+
+// receiveHandshake reads the public key of the remote peer, of which the
+// passed number of bytes were already read into theirKey, derives the keys and
+// signals the sending side through keysReady, and then reads the garbage and
+// the version packet of the remote peer.
+func (t *Transport) receiveHandshake(privKey *bronec.PrivateKey, ourKey,
+	theirKey []byte, received int, keysReady chan<- bool) error {
+
+	keysDerived := false
+	defer func() {
+		if !keysDerived {
+			keysReady <- false
+		}
+	}()
+
+	if _, err := io.ReadFull(t.rw, theirKey[received:]); err != nil {
+		return err
+	}
+	secret, err := bronec.GenerateEllSwiftSharedSecret(privKey, theirKey,
+		ourKey, t.initiator)
+	if err != nil {
+		return err
+	}
+	if err := t.deriveKeys(secret); err != nil {
+		return err
+	}
+	keysDerived = true
+	keysReady <- true
+
+	// Read the garbage up to the garbage terminator.
+	buf := make([]byte, 0, MaxGarbageLen+garbageTerminatorLen)
+	if _, err := io.ReadFull(t.rw, buf[:garbageTerminatorLen]); err != nil {
+		return err
+	}
+	buf = buf[:garbageTerminatorLen]
+	for !bytes.Equal(buf[len(buf)-garbageTerminatorLen:], t.recvTerm) {
+		if len(buf) == cap(buf) {
+			return errors.New("garbage terminator not found")
+		}
+		if _, err := io.ReadFull(t.rw, buf[len(buf):len(buf)+1]); err != nil {
+			return err
+		}
+		buf = buf[:len(buf)+1]
+	}
+	t.recvGarbage = buf[:len(buf)-garbageTerminatorLen]
+	t.aadPending = true
+
+	// The contents of the version packet are reserved for future
+	// extensions, so they are ignored.
+	_, _, err = t.readPacket()
+	return err
+}
+
+// writePacket encrypts the passed contents into a packet along with the
+// additional data and writes it to the connection.  It returns the number of
+// bytes written.
+func (t *Transport) writePacket(contents, aad []byte, ignore bool) (int, error) {
+	if len(contents) > MaxContentsLen {
+		return 0, fmt.Errorf("packet contents are too large - %d "+
+			"bytes, but maximum is %d bytes", len(contents),
+			MaxContentsLen)
+	}
+
+	packet := make([]byte, lengthFieldLen, lengthFieldLen+headerLen+
+		len(contents)+chacha20poly1305.Overhead)
+	packet[0] = byte(len(contents))
+	packet[1] = byte(len(contents) >> 8)
+	packet[2] = byte(len(contents) >> 16)
+	t.sendL.crypt(packet)
+
+	plaintext := make([]byte, headerLen, headerLen+len(contents))
+	if ignore {
+		plaintext[0] = ignoreBit
+	}
+	plaintext = append(plaintext, contents...)
+	packet = t.sendP.encrypt(packet, plaintext, aad)
+	return t.rw.Write(packet)
+}
+
+// readPacket reads the next packet which isn't a decoy from the connection and
+// returns its decrypted contents along with the number of bytes read,
+// including those of skipped decoy packets.  Packets with contents larger than
+// the largest message are rejected.
+func (t *Transport) readPacket() (int, []byte, error) {
+	totalBytes := 0
+	for {
+		n, err := io.ReadFull(t.rw, t.recvLenBytes[:])
+		totalBytes += n
+		if err != nil {
+			return totalBytes, nil, err
+		}
+		t.recvL.crypt(t.recvLenBytes[:])
+		contentsLen := int(t.recvLenBytes[0]) |
+			int(t.recvLenBytes[1])<<8 | int(t.recvLenBytes[2])<<16
+		if contentsLen > maxRecvContentsLen {
+			return totalBytes, nil, fmt.Errorf("packet contents "+
+				"are too large - %d bytes, but maximum is %d "+
+				"bytes", contentsLen, maxRecvContentsLen)
+		}
+
+		ciphertext := make([]byte, headerLen+contentsLen+
+			chacha20poly1305.Overhead)
+		n, err = io.ReadFull(t.rw, ciphertext)
+		totalBytes += n
+		if err != nil {
+			return totalBytes, nil, err
+		}
+
+		// Only the first packet after the garbage authenticates it.
+		var aad []byte
+		if t.aadPending {
+			aad = t.recvGarbage
+			t.aadPending = false
+		}
+		plaintext, err := t.recvP.decrypt(ciphertext[:0], ciphertext, aad)
+		if err != nil {
+			return totalBytes, nil, err
+		}
+		if plaintext[0]&ignoreBit == 0 {
+			return totalBytes, plaintext[headerLen:], nil
+		}
+	}
+}
+
+// WriteMessage writes the passed message to the remote peer in a packet and
+// returns the number of bytes written.  Messages are encoded as described by
+// wire.EncodeV2Message.
+func (t *Transport) WriteMessage(msg wire.Message, pver uint32,
+	enc wire.MessageEncoding) (int, error) {
+
+	contents, err := wire.EncodeV2Message(msg, pver, enc)
+	if err != nil {
+		return 0, err
+	}
+	return t.writePacket(contents, nil, false)
+}
+
+// ReadMessage reads, authenticates and parses the next message from the remote
+// peer.  It returns the number of bytes read in addition to the parsed message
+// and its raw payload, in the same way as wire.ReadMessageWithEncodingN.
+func (t *Transport) ReadMessage(pver uint32, enc wire.MessageEncoding) (int,
+	wire.Message, []byte, error) {
+
+	n, contents, err := t.readPacket()
+	if err != nil {
+		return n, nil, nil, err
+	}
+	msg, payload, err := wire.DecodeV2Message(contents, pver, enc)
+	return n, msg, payload, err
+}
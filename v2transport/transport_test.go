@@ -0,0 +1,295 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package v2transport
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/brsuite/brond/wire"
+)
+
+// handshakePair performs the handshake between an initiator and a responder
+// over an in-memory connection and returns both sides.
+func handshakePair(t *testing.T) (*Transport, *Transport, func()) {
+	t.Helper()
+
+	inConn, outConn := net.Pipe()
+	initiator := NewTransport(outConn, wire.MainNet, true)
+	responder := NewTransport(inConn, wire.MainNet, false)
+	errs := make(chan error, 2)
+	go func() { errs <- initiator.Handshake() }()
+	go func() { errs <- responder.Handshake() }()
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Handshake: unexpected error %v", err)
+		}
+	}
+	return initiator, responder, func() {
+		inConn.Close()
+		outConn.Close()
+	}
+}
+
+// TestTransport ensures two v2 transport protocol peers agree on the session
+// and can exchange messages in both directions concurrently, including past the
+// point their keys are replaced.
+func TestTransport(t *testing.T) {
+	initiator, responder, cleanup := handshakePair(t)
+	defer cleanup()
+
+	if initiator.SessionID() != responder.SessionID() {
+		t.Fatalf("session IDs differ: %x and %x", initiator.SessionID(),
+			responder.SessionID())
+	}
+
+	// Send pings from both sides concurrently and check each side receives
+	// all of the other side's pings in order.
+	const numMsgs = 2*rekeyInterval + 10
+	pver := wire.ProtocolVersion
+	errs := make(chan error, 4)
+	for _, sender := range []*Transport{initiator, responder} {
+		go func(sender *Transport) {
+			for i := 0; i < numMsgs; i++ {
+				msg := wire.NewMsgPing(uint64(i))
+				_, err := sender.WriteMessage(msg, pver,
+					wire.LatestEncoding)
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+			errs <- nil
+		}(sender)
+	}
+	for _, receiver := range []*Transport{initiator, responder} {
+		go func(receiver *Transport) {
+			for i := 0; i < numMsgs; i++ {
+				_, msg, _, err := receiver.ReadMessage(pver,
+					wire.LatestEncoding)
+				if err != nil {
+					errs <- err
+					return
+				}
+				want := wire.NewMsgPing(uint64(i))
+				if !reflect.DeepEqual(msg, want) {
+					errs <- fmt.Errorf("got message %v, "+
+						"want %v", msg, want)
+					return
+				}
+			}
+			errs <- nil
+		}(receiver)
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+}
+
+// TestTransportDecoys ensures decoy packets are skipped by the receiver while
+// still counting towards the bytes read.
+func TestTransportDecoys(t *testing.T) {
+	initiator, responder, cleanup := handshakePair(t)
+	defer cleanup()
+
+	pver := wire.ProtocolVersion
+	go func() {
+		initiator.writePacket([]byte("decoy"), nil, true)
+		initiator.writePacket(nil, nil, true)
+		initiator.WriteMessage(wire.NewMsgVerAck(), pver,
+			wire.LatestEncoding)
+	}()
+
+	n, msg, _, err := responder.ReadMessage(pver, wire.LatestEncoding)
+	if err != nil {
+		t.Fatalf("ReadMessage: unexpected error %v", err)
+	}
+	if _, ok := msg.(*wire.MsgVerAck); !ok {
+		t.Fatalf("ReadMessage: got %T, want *wire.MsgVerAck", msg)
+	}
+	overhead := lengthFieldLen + headerLen + 16
+	wantN := 3*overhead + len("decoy") + 1 + wire.CommandSize
+	if n != wantN {
+		t.Fatalf("ReadMessage: got %d bytes read, want %d", n, wantN)
+	}
+}
+
+// TestTransportTampered ensures a packet which was modified on the way fails
+// to be authenticated.
+func TestTransportTampered(t *testing.T) {
+	initiator, responder, cleanup := handshakePair(t)
+	defer cleanup()
+
+	var buf bytes.Buffer
+	initiator.rw = &buf
+	_, err := initiator.WriteMessage(wire.NewMsgPing(1),
+		wire.ProtocolVersion, wire.LatestEncoding)
+	if err != nil {
+		t.Fatalf("WriteMessage: unexpected error %v", err)
+	}
+	packet := buf.Bytes()
+	packet[len(packet)-1] ^= 1
+	responder.rw = struct {
+		io.Reader
+		io.Writer
+	}{bytes.NewReader(packet), ioutil.Discard}
+	_, _, _, err = responder.ReadMessage(wire.ProtocolVersion,
+		wire.LatestEncoding)
+	if err != errAuthFailed {
+		t.Fatalf("ReadMessage: got error %v, want %v", err,
+			errAuthFailed)
+	}
+}
+
+// TestTransportOversized ensures a packet with contents larger than the largest
+// message is rejected once its length has been read.
+func TestTransportOversized(t *testing.T) {
+	initiator, responder, cleanup := handshakePair(t)
+	defer cleanup()
+
+	var buf bytes.Buffer
+	initiator.rw = &buf
+	_, err := initiator.writePacket(make([]byte, maxRecvContentsLen+1), nil,
+		true)
+	if err != nil {
+		t.Fatalf("writePacket: unexpected error %v", err)
+	}
+	responder.rw = struct {
+		io.Reader
+		io.Writer
+	}{&buf, ioutil.Discard}
+	n, _, _, err := responder.ReadMessage(wire.ProtocolVersion,
+		wire.LatestEncoding)
+	if err == nil {
+		t.Fatal("ReadMessage: no error for oversized packet")
+	}
+	if n != lengthFieldLen {
+		t.Fatalf("ReadMessage: got %d bytes read, want %d", n,
+			lengthFieldLen)
+	}
+}
+
+// TestHandshakeV1Peer ensures a responder detects a peer using the v1
+// transport protocol and returns the bytes it read, which together with the
+// rest of the connection make up the v1 version message.
+func TestHandshakeV1Peer(t *testing.T) {
+	inConn, outConn := net.Pipe()
+	defer inConn.Close()
+	defer outConn.Close()
+
+	// Send a v1 version message and read it back through the v1 message
+	// reader after the detection.
+	msgVersion := wire.NewMsgVersion(
+		wire.NewNetAddressIPPort(net.ParseIP("127.0.0.1"), 8333, 0),
+		wire.NewNetAddressIPPort(net.ParseIP("127.0.0.1"), 8333, 0),
+		123, 0)
+	go wire.WriteMessage(outConn, msgVersion, wire.ProtocolVersion,
+		wire.MainNet)
+
+	responder := NewTransport(inConn, wire.MainNet, false)
+	if err := responder.Handshake(); err != ErrV1Peer {
+		t.Fatalf("Handshake: got error %v, want %v", err, ErrV1Peer)
+	}
+	r := io.MultiReader(bytes.NewReader(responder.V1Prefix()), inConn)
+	msg, _, err := wire.ReadMessage(r, wire.ProtocolVersion, wire.MainNet)
+	if err != nil {
+		t.Fatalf("ReadMessage: unexpected error %v", err)
+	}
+	if _, ok := msg.(*wire.MsgVersion); !ok {
+		t.Fatalf("ReadMessage: got %T, want *wire.MsgVersion", msg)
+	}
+
+	// A version message for another network isn't taken for a v1 peer,
+	// so the handshake fails once the connection is closed instead.
+	inConn, outConn = net.Pipe()
+	defer inConn.Close()
+	go func() {
+		wire.WriteMessage(outConn, msgVersion, wire.ProtocolVersion,
+			wire.TestNet3)
+		outConn.Close()
+	}()
+	responder = NewTransport(inConn, wire.MainNet, false)
+	if err := responder.Handshake(); err == nil || err == ErrV1Peer {
+		t.Fatalf("Handshake: got error %v, want a connection error", err)
+	}
+}
+
+// TestHandshakeFailure ensures the handshake of an initiator fails when the
+// remote peer closes the connection, as peers using the v1 transport protocol
+// do, and when the remote peer doesn't send the garbage terminator.
+func TestHandshakeFailure(t *testing.T) {
+	inConn, outConn := net.Pipe()
+	go func() {
+		// Read the start of what a v1 peer would interpret as a message
+		// header and then disconnect.
+		var header [wire.MessageHeaderSize]byte
+		io.ReadFull(inConn, header[:])
+		inConn.Close()
+	}()
+	initiator := NewTransport(outConn, wire.MainNet, true)
+	if err := initiator.Handshake(); err == nil {
+		t.Fatal("Handshake: no error when the remote peer disconnected")
+	}
+	outConn.Close()
+
+	// Send a valid public key followed by more garbage than allowed.
+	inConn, outConn = net.Pipe()
+	defer inConn.Close()
+	defer outConn.Close()
+	go func() {
+		key := make([]byte, 64)
+		inConn.Write(key)
+		inConn.Write(make([]byte, MaxGarbageLen+garbageTerminatorLen))
+	}()
+	go io.Copy(ioutil.Discard, inConn)
+	initiator = NewTransport(outConn, wire.MainNet, true)
+	err := initiator.Handshake()
+	if err == nil || err.Error() != "garbage terminator not found" {
+		t.Fatalf("Handshake: got error %v, want garbage terminator "+
+			"not found", err)
+	}
+}
+
+// blockedConn is a connection from which reading fails right away, while
+// writing blocks until release is closed.
+type blockedConn struct {
+	release chan struct{}
+}
+
+func (c *blockedConn) Read(p []byte) (int, error) {
+	return 0, io.EOF
+}
+
+func (c *blockedConn) Write(p []byte) (int, error) {
+	<-c.release
+	return len(p), nil
+}
+
+// TestHandshakeWaitsForSend ensures a failed handshake doesn't return while the
+// public key is still being sent, so nothing uses the connection afterwards.
+func TestHandshakeWaitsForSend(t *testing.T) {
+	conn := &blockedConn{release: make(chan struct{})}
+	initiator := NewTransport(conn, wire.MainNet, true)
+	errs := make(chan error, 1)
+	go func() { errs <- initiator.Handshake() }()
+
+	select {
+	case err := <-errs:
+		t.Fatalf("Handshake: returned %v while still sending", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(conn.release)
+	if err := <-errs; err != io.ErrUnexpectedEOF && err != io.EOF {
+		t.Fatalf("Handshake: got error %v, want %v", err, io.EOF)
+	}
+}
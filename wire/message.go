@@ -57,6 +57,12 @@ const (
 	CmdCFilter      = "cfilter"
 	CmdCFHeaders    = "cfheaders"
 	CmdCFCheckpt    = "cfcheckpt"
+	CmdAddrV2       = "addrv2"
+	CmdSendAddrV2   = "sendaddrv2"
+	CmdSendCmpct    = "sendcmpct"
+	CmdCmpctBlock   = "cmpctblock"
+	CmdGetBlockTxns = "getblocktxn"
+	CmdBlockTxns    = "blocktxn"
 )
 
 // MessageEncoding represents the wire message encoding format to be used.
@@ -180,6 +186,24 @@ func makeEmptyMessage(command string) (Message, error) {
 	case CmdCFCheckpt:
 		msg = &MsgCFCheckpt{}
 
+	case CmdAddrV2:
+		msg = &MsgAddrV2{}
+
+	case CmdSendAddrV2:
+		msg = &MsgSendAddrV2{}
+
+	case CmdSendCmpct:
+		msg = &MsgSendCmpct{}
+
+	case CmdCmpctBlock:
+		msg = &MsgCmpctBlock{}
+
+	case CmdGetBlockTxns:
+		msg = &MsgGetBlockTxns{}
+
+	case CmdBlockTxns:
+		msg = &MsgBlockTxns{}
+
 	default:
 		return nil, fmt.Errorf("unhandled command [%s]", command)
 	}
@@ -438,3 +462,164 @@ func ReadMessage(r io.Reader, pver uint32, bronnet BrocoinNet) (Message, []byte,
 	_, msg, buf, err := ReadMessageN(r, pver, bronnet)
 	return msg, buf, err
 }
+
+// v2MessageCommands holds the commands of the messages which are identified by
+// a one byte short ID instead of their full command in the v2 transport
+// protocol defined by BIP0324, indexed by their short IDs.  Short ID 0 means
+// the full command follows.
+var v2MessageCommands = [...]string{
+	1:  CmdAddr,
+	2:  CmdBlock,
+	3:  CmdBlockTxns,
+	4:  CmdCmpctBlock,
+	5:  CmdFeeFilter,
+	6:  CmdFilterAdd,
+	7:  CmdFilterClear,
+	8:  CmdFilterLoad,
+	9:  CmdGetBlocks,
+	10: CmdGetBlockTxns,
+	11: CmdGetData,
+	12: CmdGetHeaders,
+	13: CmdHeaders,
+	14: CmdInv,
+	15: CmdMemPool,
+	16: CmdMerkleBlock,
+	17: CmdNotFound,
+	18: CmdPing,
+	19: CmdPong,
+	20: CmdSendCmpct,
+	21: CmdTx,
+	22: CmdGetCFilters,
+	23: CmdCFilter,
+	24: CmdGetCFHeaders,
+	25: CmdCFHeaders,
+	26: CmdGetCFCheckpt,
+	27: CmdCFCheckpt,
+	28: CmdAddrV2,
+}
+
+// v2MessageIDs maps the commands in v2MessageCommands back to their short IDs.
+var v2MessageIDs = func() map[string]uint8 {
+	ids := make(map[string]uint8, len(v2MessageCommands))
+	for id, cmd := range v2MessageCommands {
+		if cmd != "" {
+			ids[cmd] = uint8(id)
+		}
+	}
+	return ids
+}()
+
+// EncodeV2Message returns the contents of a v2 transport protocol (BIP0324)
+// packet carrying the passed message, which consist of its one byte short ID,
+// or a zero byte followed by its zero padded command when it has none, and its
+// payload.  The checksum and length of the v1 message header are not needed
+// since the transport authenticates and frames the packets.
+func EncodeV2Message(msg Message, pver uint32, encoding MessageEncoding) ([]byte, error) {
+	// Enforce max command size.
+	cmd := msg.Command()
+	if len(cmd) > CommandSize {
+		str := fmt.Sprintf("command [%s] is too long [max %v]",
+			cmd, CommandSize)
+		return nil, messageError("EncodeV2Message", str)
+	}
+
+	var bw bytes.Buffer
+	if id, ok := v2MessageIDs[cmd]; ok {
+		bw.WriteByte(id)
+	} else {
+		var command [CommandSize]byte
+		copy(command[:], cmd)
+		bw.WriteByte(0)
+		bw.Write(command[:])
+	}
+	headerLen := bw.Len()
+
+	// Encode the message payload.
+	err := msg.BronEncode(&bw, pver, encoding)
+	if err != nil {
+		return nil, err
+	}
+	lenp := bw.Len() - headerLen
+
+	// Enforce maximum overall message payload.
+	if lenp > MaxMessagePayload {
+		str := fmt.Sprintf("message payload is too large - encoded "+
+			"%d bytes, but maximum message payload is %d bytes",
+			lenp, MaxMessagePayload)
+		return nil, messageError("EncodeV2Message", str)
+	}
+
+	// Enforce maximum message payload based on the message type.
+	mpl := msg.MaxPayloadLength(pver)
+	if uint32(lenp) > mpl {
+		str := fmt.Sprintf("message payload is too large - encoded "+
+			"%d bytes, but maximum message payload size for "+
+			"messages of type [%s] is %d.", lenp, cmd, mpl)
+		return nil, messageError("EncodeV2Message", str)
+	}
+
+	return bw.Bytes(), nil
+}
+
+// DecodeV2Message parses the message carried by the contents of a v2 transport
+// protocol (BIP0324) packet as created by EncodeV2Message.  It returns the
+// parsed Message and the raw bytes of its payload.
+func DecodeV2Message(contents []byte, pver uint32, enc MessageEncoding) (Message, []byte, error) {
+	if len(contents) == 0 {
+		return nil, nil, messageError("DecodeV2Message",
+			"packet contents are empty")
+	}
+
+	// Look up the command by its short ID, or read it from the contents
+	// when there is none.
+	var command string
+	payload := contents[1:]
+	switch id := contents[0]; {
+	case id == 0:
+		if len(payload) < CommandSize {
+			str := fmt.Sprintf("packet contents are too short for a "+
+				"command - %d bytes", len(payload))
+			return nil, nil, messageError("DecodeV2Message", str)
+		}
+		command = string(bytes.TrimRight(payload[:CommandSize], "\x00"))
+		payload = payload[CommandSize:]
+
+	case int(id) < len(v2MessageCommands):
+		command = v2MessageCommands[id]
+
+	default:
+		str := fmt.Sprintf("unknown short message ID %d", id)
+		return nil, nil, messageError("DecodeV2Message", str)
+	}
+
+	// Check for malformed commands.
+	if !utf8.ValidString(command) {
+		str := fmt.Sprintf("invalid command %v", []byte(command))
+		return nil, nil, messageError("DecodeV2Message", str)
+	}
+
+	// Create struct of appropriate message type based on the command.
+	msg, err := makeEmptyMessage(command)
+	if err != nil {
+		return nil, nil, messageError("DecodeV2Message", err.Error())
+	}
+
+	// Check for maximum length based on the message type.
+	mpl := msg.MaxPayloadLength(pver)
+	if len(payload) > MaxMessagePayload || uint32(len(payload)) > mpl {
+		str := fmt.Sprintf("payload exceeds max length - packet "+
+			"contains %v bytes, but max payload size for "+
+			"messages of type [%v] is %v.", len(payload), command, mpl)
+		return nil, nil, messageError("DecodeV2Message", str)
+	}
+
+	// Unmarshal message.  NOTE: This must be a *bytes.Buffer since the
+	// MsgVersionBronDecode function requires it.
+	pr := bytes.NewBuffer(payload)
+	err = msg.BronDecode(pr, pver, enc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return msg, payload, nil
+}
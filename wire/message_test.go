@@ -76,6 +76,12 @@ func TestMessage(t *testing.T) {
 		[]byte("payload"))
 	msgCFHeaders := NewMsgCFHeaders()
 	msgCFCheckpt := NewMsgCFCheckpt(GCSFilterRegular, &chainhash.Hash{}, 0)
+	msgAddrV2 := NewMsgAddrV2()
+	msgSendAddrV2 := NewMsgSendAddrV2()
+	msgSendCmpct := NewMsgSendCmpct(true, CmpctBlockVersion2)
+	msgCmpctBlock := NewMsgCmpctBlock(bh, 0)
+	msgGetBlockTxns := NewMsgGetBlockTxns(&chainhash.Hash{})
+	msgBlockTxns := NewMsgBlockTxns(&chainhash.Hash{})
 
 	tests := []struct {
 		in     Message    // Value to encode
@@ -111,6 +117,12 @@ func TestMessage(t *testing.T) {
 		{msgCFilter, msgCFilter, pver, MainNet, 65},
 		{msgCFHeaders, msgCFHeaders, pver, MainNet, 90},
 		{msgCFCheckpt, msgCFCheckpt, pver, MainNet, 58},
+		{msgAddrV2, msgAddrV2, pver, MainNet, 25},
+		{msgSendAddrV2, msgSendAddrV2, pver, MainNet, 24},
+		{msgSendCmpct, msgSendCmpct, pver, MainNet, 33},
+		{msgCmpctBlock, msgCmpctBlock, pver, MainNet, 114},
+		{msgGetBlockTxns, msgGetBlockTxns, pver, MainNet, 57},
+		{msgBlockTxns, msgBlockTxns, pver, MainNet, 57},
 	}
 
 	t.Logf("Running %d tests", len(tests))
@@ -457,3 +469,90 @@ func TestWriteMessageWireErrors(t *testing.T) {
 		}
 	}
 }
+
+// TestV2Message tests the encoding and decoding of messages carried by v2
+// transport protocol packets, using short IDs when the messages have one.
+func TestV2Message(t *testing.T) {
+	pver := ProtocolVersion
+	tests := []struct {
+		msg    Message
+		header []byte // expected short ID or command
+	}{
+		{NewMsgAddr(), []byte{1}},
+		{&blockOne, []byte{2}},
+		{NewMsgPing(123123), []byte{18}},
+		{NewMsgTx(1), []byte{21}},
+		{NewMsgAddrV2(), []byte{28}},
+		{NewMsgVerAck(), append([]byte{0}, "verack\x00\x00\x00\x00\x00\x00"...)},
+		{NewMsgSendAddrV2(), append([]byte{0}, "sendaddrv2\x00\x00"...)},
+	}
+
+	for i, test := range tests {
+		contents, err := EncodeV2Message(test.msg, pver, BaseEncoding)
+		if err != nil {
+			t.Errorf("EncodeV2Message #%d: unexpected error %v", i, err)
+			continue
+		}
+		if !bytes.HasPrefix(contents, test.header) {
+			t.Errorf("EncodeV2Message #%d: got contents %x, want "+
+				"header %x", i, contents, test.header)
+			continue
+		}
+
+		msg, payload, err := DecodeV2Message(contents, pver, BaseEncoding)
+		if err != nil {
+			t.Errorf("DecodeV2Message #%d: unexpected error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(msg, test.msg) {
+			t.Errorf("DecodeV2Message #%d: got %v, want %v", i,
+				spew.Sdump(msg), spew.Sdump(test.msg))
+		}
+		if !bytes.Equal(payload, contents[len(test.header):]) {
+			t.Errorf("DecodeV2Message #%d: got payload %x, want %x",
+				i, payload, contents[len(test.header):])
+		}
+	}
+}
+
+// TestV2MessageErrors performs negative tests against the encoding and decoding
+// of messages carried by v2 transport protocol packets to confirm error paths
+// work correctly.
+func TestV2MessageErrors(t *testing.T) {
+	pver := ProtocolVersion
+
+	encodeTests := []Message{
+		// Command too long.
+		&fakeMessage{command: "somethingtoolong"},
+		// Payload exceeds the maximum of the message type.
+		&fakeMessage{command: CmdPing, payload: make([]byte, 8),
+			forceLenErr: true},
+	}
+	for i, msg := range encodeTests {
+		_, err := EncodeV2Message(msg, pver, BaseEncoding)
+		if _, ok := err.(*MessageError); !ok {
+			t.Errorf("EncodeV2Message #%d: got error %v, want "+
+				"MessageError", i, err)
+		}
+	}
+
+	decodeTests := [][]byte{
+		// Empty contents.
+		{},
+		// Unknown short ID.
+		{29},
+		// Command cut short.
+		append([]byte{0}, "verack"...),
+		// Unknown command.
+		append([]byte{0}, "unknown\x00\x00\x00\x00\x00"...),
+		// Payload exceeds the maximum of the message type.
+		append([]byte{18}, make([]byte, 9)...),
+	}
+	for i, contents := range decodeTests {
+		_, _, err := DecodeV2Message(contents, pver, BaseEncoding)
+		if _, ok := err.(*MessageError); !ok {
+			t.Errorf("DecodeV2Message #%d: got error %v, want "+
+				"MessageError", i, err)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestAddrV2 tests the MsgAddrV2 API.
+func TestAddrV2(t *testing.T) {
+	pver := ProtocolVersion
+
+	// Ensure the command is expected value.
+	wantCmd := "addrv2"
+	msg := NewMsgAddrV2()
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgAddrV2: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value.
+	// Num addresses (varInt) + max allowed addresses.
+	wantPayload := uint32(537009)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+
+	// Ensure NetAddressV2s are added properly.
+	na := NewNetAddressV2IPPort(net.ParseIP("127.0.0.1"), 8688,
+		SFNodeNetwork)
+	err := msg.AddAddress(na)
+	if err != nil {
+		t.Errorf("AddAddress: %v", err)
+	}
+	if msg.AddrList[0] != na {
+		t.Errorf("AddAddress: wrong address added - got %v, want %v",
+			spew.Sprint(msg.AddrList[0]), spew.Sprint(na))
+	}
+
+	// Ensure the address list is cleared properly.
+	msg.ClearAddresses()
+	if len(msg.AddrList) != 0 {
+		t.Errorf("ClearAddresses: address list is not empty - "+
+			"got %v [%v], want %v", len(msg.AddrList),
+			spew.Sprint(msg.AddrList[0]), 0)
+	}
+
+	// Ensure adding more than the max allowed addresses per message returns
+	// error.
+	for i := 0; i < MaxAddrPerMsg+1; i++ {
+		err = msg.AddAddress(na)
+	}
+	if err == nil {
+		t.Errorf("AddAddress: expected error on too many addresses " +
+			"not received")
+	}
+	err = msg.AddAddresses(na)
+	if err == nil {
+		t.Errorf("AddAddresses: expected error on too many addresses " +
+			"not received")
+	}
+}
+
+// TestAddrV2Wire tests the MsgAddrV2 wire encode and decode for addresses on
+// various networks.
+func TestAddrV2Wire(t *testing.T) {
+	timestamp := time.Unix(0x495fab29, 0) // 2009-01-03 12:15:05 -0600 CST
+	ipv4 := &NetAddressV2{
+		Timestamp: timestamp,
+		Services:  SFNodeNetwork,
+		NetworkID: NetIPv4,
+		Addr:      []byte{127, 0, 0, 1},
+		Port:      8688,
+	}
+	torV3 := &NetAddressV2{
+		Timestamp: timestamp,
+		Services:  SFNodeNetwork | SFNodeWitness,
+		NetworkID: NetTorV3,
+		Addr:      bytes.Repeat([]byte{0xab}, 32),
+		Port:      8688,
+	}
+	unknown := &NetAddressV2{
+		Timestamp: timestamp,
+		NetworkID: 0x2a,
+		Addr:      []byte{0x01, 0x02, 0x03},
+		Port:      1,
+	}
+
+	// Empty address message.
+	noAddr := NewMsgAddrV2()
+	noAddrEncoded := []byte{
+		0x00, // Varint for number of addresses
+	}
+
+	// Address message with addresses on a few networks, including one that
+	// is unknown.
+	multiAddr := NewMsgAddrV2()
+	multiAddr.AddAddresses(ipv4, torV3, unknown)
+	multiAddrEncoded := []byte{
+		0x03,                   // Varint for number of addresses
+		0x29, 0xab, 0x5f, 0x49, // Timestamp
+		0x01,                         // Varint for services
+		0x01,                         // Network ID (IPv4)
+		0x04, 0x7f, 0x00, 0x00, 0x01, // Address
+		0x21, 0xf0, // Port 8688 in big-endian
+		0x29, 0xab, 0x5f, 0x49, // Timestamp
+		0x09,       // Varint for services
+		0x04, 0x20, // Network ID (TorV3) and address length
+	}
+	multiAddrEncoded = append(multiAddrEncoded, torV3.Addr...)
+	multiAddrEncoded = append(multiAddrEncoded, []byte{
+		0x21, 0xf0, // Port 8688 in big-endian
+		0x29, 0xab, 0x5f, 0x49, // Timestamp
+		0x00,                   // Varint for services
+		0x2a,                   // Unknown network ID
+		0x03, 0x01, 0x02, 0x03, // Address
+		0x00, 0x01, // Port 1 in big-endian
+	}...)
+
+	tests := []struct {
+		in   *MsgAddrV2      // Message to encode
+		out  *MsgAddrV2      // Expected decoded message
+		buf  []byte          // Wire encoding
+		pver uint32          // Protocol version for wire encoding
+		enc  MessageEncoding // Message encoding format
+	}{
+		{
+			noAddr,
+			noAddr,
+			noAddrEncoded,
+			ProtocolVersion,
+			BaseEncoding,
+		},
+		{
+			multiAddr,
+			multiAddr,
+			multiAddrEncoded,
+			ProtocolVersion,
+			BaseEncoding,
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		err := test.in.BronEncode(&buf, test.pver, test.enc)
+		if err != nil {
+			t.Errorf("BronEncode #%d error %v", i, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("BronEncode #%d\n got: %s want: %s", i,
+				spew.Sdump(buf.Bytes()), spew.Sdump(test.buf))
+			continue
+		}
+
+		// Decode the message from wire format.
+		var msg MsgAddrV2
+		rbuf := bytes.NewReader(test.buf)
+		err = msg.BronDecode(rbuf, test.pver, test.enc)
+		if err != nil {
+			t.Errorf("BronDecode #%d error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(&msg, test.out) {
+			t.Errorf("BronDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(msg), spew.Sdump(test.out))
+			continue
+		}
+	}
+}
+
+// TestAddrV2WireErrors performs negative tests against wire encode and decode
+// of MsgAddrV2 to confirm error paths work correctly.
+func TestAddrV2WireErrors(t *testing.T) {
+	pver := ProtocolVersion
+
+	// An IPv4 address must be 4 bytes.
+	badIPv4Len := []byte{
+		0x01,                   // Varint for number of addresses
+		0x29, 0xab, 0x5f, 0x49, // Timestamp
+		0x01,                               // Varint for services
+		0x01,                               // Network ID (IPv4)
+		0x05, 0x7f, 0x00, 0x00, 0x01, 0x00, // Address
+		0x21, 0xf0, // Port 8688 in big-endian
+	}
+
+	// Addresses may not exceed the maximum size, even when their network
+	// is unknown.
+	tooLongAddr := []byte{
+		0x01,                   // Varint for number of addresses
+		0x29, 0xab, 0x5f, 0x49, // Timestamp
+		0x01,             // Varint for services
+		0x2a,             // Unknown network ID
+		0xfd, 0x01, 0x02, // Varint for address length of 513
+	}
+	tooLongAddr = append(tooLongAddr, make([]byte, 513+2)...)
+
+	// Messages may not claim more than the maximum number of addresses.
+	tooManyAddrs := []byte{
+		0xfd, 0xe9, 0x03, // Varint for number of addresses (1001)
+	}
+
+	for i, buf := range [][]byte{badIPv4Len, tooLongAddr, tooManyAddrs} {
+		var msg MsgAddrV2
+		err := msg.BronDecode(bytes.NewReader(buf), pver, BaseEncoding)
+		if _, ok := err.(*MessageError); !ok {
+			t.Errorf("BronDecode #%d wrong error got: %v <%T>, "+
+				"want: %T", i, err, err, &MessageError{})
+		}
+	}
+
+	// Encoding addresses that exceed the maximum size must fail.
+	msg := NewMsgAddrV2()
+	msg.AddAddress(&NetAddressV2{
+		NetworkID: 0x2a,
+		Addr:      make([]byte, MaxAddrV2Size+1),
+	})
+	var buf bytes.Buffer
+	err := msg.BronEncode(&buf, pver, BaseEncoding)
+	if _, ok := err.(*MessageError); !ok {
+		t.Errorf("BronEncode wrong error got: %v <%T>, want: %T", err,
+			err, &MessageError{})
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/brsuite/brond/chaincfg/chainhash"
+)
+
+// MsgBlockTxns implements the Message interface and represents a brocoin
+// blocktxn message as defined by BIP0152.  It is sent in response to a
+// getblocktxn message (MsgGetBlockTxns) and carries the requested
+// transactions of the block in the order they were requested.
+//
+// This message was not added until protocol versions starting with
+// ShortIDsBlocksVersion.
+type MsgBlockTxns struct {
+	BlockHash    chainhash.Hash
+	Transactions []*MsgTx
+}
+
+// AddTransaction adds a transaction to the message.
+func (msg *MsgBlockTxns) AddTransaction(tx *MsgTx) error {
+	if len(msg.Transactions)+1 > maxTxPerBlock {
+		str := fmt.Sprintf("too many transactions for message "+
+			"[max %v]", maxTxPerBlock)
+		return messageError("MsgBlockTxns.AddTransaction", str)
+	}
+
+	msg.Transactions = append(msg.Transactions, tx)
+	return nil
+}
+
+// BronDecode decodes r using the brocoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgBlockTxns) BronDecode(r io.Reader, pver uint32, enc MessageEncoding) error {
+	if pver < ShortIDsBlocksVersion {
+		str := fmt.Sprintf("blocktxn message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgBlockTxns.BronDecode", str)
+	}
+
+	err := readElement(r, &msg.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	// Read num transactions and limit to max.
+	count, err := ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many transactions for message "+
+			"[count %v, max %v]", count, maxTxPerBlock)
+		return messageError("MsgBlockTxns.BronDecode", str)
+	}
+
+	msg.Transactions = make([]*MsgTx, 0, count)
+	for i := uint64(0); i < count; i++ {
+		tx := MsgTx{}
+		err := tx.BronDecode(r, pver, enc)
+		if err != nil {
+			return err
+		}
+		msg.Transactions = append(msg.Transactions, &tx)
+	}
+
+	return nil
+}
+
+// BronEncode encodes the receiver to w using the brocoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgBlockTxns) BronEncode(w io.Writer, pver uint32, enc MessageEncoding) error {
+	if pver < ShortIDsBlocksVersion {
+		str := fmt.Sprintf("blocktxn message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgBlockTxns.BronEncode", str)
+	}
+
+	count := len(msg.Transactions)
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many transactions for message "+
+			"[count %v, max %v]", count, maxTxPerBlock)
+		return messageError("MsgBlockTxns.BronEncode", str)
+	}
+
+	err := writeElement(w, &msg.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarInt(w, pver, uint64(count))
+	if err != nil {
+		return err
+	}
+	for _, tx := range msg.Transactions {
+		err = tx.BronEncode(w, pver, enc)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgBlockTxns) Command() string {
+	return CmdBlockTxns
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgBlockTxns) MaxPayloadLength(pver uint32) uint32 {
+	return MaxBlockPayload
+}
+
+// NewMsgBlockTxns returns a new brocoin blocktxn message that conforms to the
+// Message interface using the passed parameters.  See MsgBlockTxns for
+// details.
+func NewMsgBlockTxns(blockHash *chainhash.Hash) *MsgBlockTxns {
+	return &MsgBlockTxns{
+		BlockHash:    *blockHash,
+		Transactions: make([]*MsgTx, 0),
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestBlockTxns tests the MsgBlockTxns API.
+func TestBlockTxns(t *testing.T) {
+	pver := ProtocolVersion
+
+	hash := blockOne.BlockHash()
+	msg := NewMsgBlockTxns(&hash)
+	if !msg.BlockHash.IsEqual(&hash) {
+		t.Errorf("NewMsgBlockTxns: wrong hash - got %v, want %v",
+			msg.BlockHash, hash)
+	}
+
+	// Ensure the command is expected value.
+	wantCmd := "blocktxn"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgBlockTxns: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value for latest protocol version.
+	wantPayload := uint32(4000000)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+
+	// Ensure transactions are added properly.
+	tx := blockOne.Transactions[0].Copy()
+	msg.AddTransaction(tx)
+	if !reflect.DeepEqual(msg.Transactions, []*MsgTx{tx}) {
+		t.Errorf("AddTransaction: wrong transactions - got %v, want %v",
+			spew.Sdump(msg.Transactions), spew.Sdump([]*MsgTx{tx}))
+	}
+}
+
+// TestBlockTxnsWire tests the MsgBlockTxns wire encode and decode for both
+// transaction encodings.
+func TestBlockTxnsWire(t *testing.T) {
+	hash := blockOne.BlockHash()
+
+	tests := []struct {
+		tx    *MsgTx          // Transaction to send
+		txBuf []byte          // Wire encoding of transaction
+		enc   MessageEncoding // Message encoding format
+	}{
+		{multiTx, multiTxEncoded, BaseEncoding},
+		{multiWitnessTx, multiWitnessTxEncoded, WitnessEncoding},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		msg := NewMsgBlockTxns(&hash)
+		msg.AddTransaction(test.tx)
+
+		encoded := append(hash[:0:0], hash[:]...)
+		encoded = append(encoded, 0x01) // Varint for number of txns
+		encoded = append(encoded, test.txBuf...)
+
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		err := msg.BronEncode(&buf, ProtocolVersion, test.enc)
+		if err != nil {
+			t.Errorf("BronEncode #%d error %v", i, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), encoded) {
+			t.Errorf("BronEncode #%d\n got: %s want: %s", i,
+				spew.Sdump(buf.Bytes()), spew.Sdump(encoded))
+			continue
+		}
+
+		// Decode the message from wire format.
+		var readmsg MsgBlockTxns
+		err = readmsg.BronDecode(bytes.NewReader(encoded),
+			ProtocolVersion, test.enc)
+		if err != nil {
+			t.Errorf("BronDecode #%d error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(&readmsg, msg) {
+			t.Errorf("BronDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(&readmsg), spew.Sdump(msg))
+			continue
+		}
+	}
+}
+
+// TestBlockTxnsWireErrors performs negative tests against wire encode and
+// decode of MsgBlockTxns to confirm error paths work correctly.
+func TestBlockTxnsWireErrors(t *testing.T) {
+	pver := ProtocolVersion
+	pverNoBlockTxns := ShortIDsBlocksVersion - 1
+	wireErr := &MessageError{}
+
+	hash := blockOne.BlockHash()
+	baseBlockTxns := NewMsgBlockTxns(&hash)
+	baseBlockTxns.AddTransaction(multiTx)
+
+	baseBlockTxnsEncoded := append(hash[:0:0], hash[:]...)
+	baseBlockTxnsEncoded = append(baseBlockTxnsEncoded, 0x01)
+	baseBlockTxnsEncoded = append(baseBlockTxnsEncoded, multiTxEncoded...)
+
+	// Transaction count which exceeds the maximum per block.
+	maxTxEncoded := append(hash[:0:0], hash[:]...)
+	maxTxEncoded = append(maxTxEncoded, 0xfe, 0xff, 0xff, 0xff, 0xff)
+
+	tests := []struct {
+		in       *MsgBlockTxns // Value to encode
+		buf      []byte        // Wire encoding
+		pver     uint32        // Protocol version for wire encoding
+		max      int           // Max size of fixed buffer to induce errors
+		writeErr error         // Expected write error
+		readErr  error         // Expected read error
+	}{
+		// Force error in block hash.
+		{baseBlockTxns, baseBlockTxnsEncoded, pver, 0, io.ErrShortWrite, io.EOF},
+		// Force error in transaction count.
+		{baseBlockTxns, baseBlockTxnsEncoded, pver, 32, io.ErrShortWrite, io.EOF},
+		// Force error in transaction.
+		{baseBlockTxns, baseBlockTxnsEncoded, pver, 33, io.ErrShortWrite, io.EOF},
+		// Force error due to unsupported protocol version.
+		{baseBlockTxns, baseBlockTxnsEncoded, pverNoBlockTxns, 1000, wireErr, wireErr},
+		// Force error due to too many transactions.
+		{baseBlockTxns, maxTxEncoded, pver, 1000, nil, wireErr},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode to wire format.
+		w := newFixedWriter(test.max)
+		err := test.in.BronEncode(w, test.pver, BaseEncoding)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.writeErr) {
+			t.Errorf("BronEncode #%d wrong error got: %v, want: %v",
+				i, err, test.writeErr)
+			continue
+		}
+
+		// For errors which are not of type MessageError, check them for
+		// equality.
+		if _, ok := err.(*MessageError); !ok {
+			if err != test.writeErr {
+				t.Errorf("BronEncode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.writeErr)
+				continue
+			}
+		}
+
+		// Decode from wire format.
+		var msg MsgBlockTxns
+		r := newFixedReader(test.max, test.buf)
+		err = msg.BronDecode(r, test.pver, BaseEncoding)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.readErr) {
+			t.Errorf("BronDecode #%d wrong error got: %v, want: %v",
+				i, err, test.readErr)
+			continue
+		}
+
+		// For errors which are not of type MessageError, check them for
+		// equality.
+		if _, ok := err.(*MessageError); !ok {
+			if err != test.readErr {
+				t.Errorf("BronDecode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.readErr)
+				continue
+			}
+		}
+	}
+}
@@ -0,0 +1,334 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/brsuite/brond/chaincfg/chainhash"
+)
+
+const (
+	// shortTxIDLen is the number of bytes a short transaction id occupies
+	// on the wire.
+	shortTxIDLen = 6
+
+	// shortTxIDMask masks off the bits of a SipHash output which are not
+	// part of a short transaction id.
+	shortTxIDMask = (1 << (8 * shortTxIDLen)) - 1
+)
+
+// PrefilledTx is a transaction sent in full as part of a compact block along
+// with its index in the block.
+type PrefilledTx struct {
+	Index uint32
+	Tx    *MsgTx
+}
+
+// MsgCmpctBlock implements the Message interface and represents a brocoin
+// cmpctblock message as defined by BIP0152.  It is used to relay a block
+// header along with short ids of the block transactions so that peers can
+// reconstruct the block from the transactions they already know about.
+// Transactions the receiver is unlikely to have, such as the coinbase, are
+// sent in full as prefilled transactions.
+//
+// The short ids are calculated from either the transaction hashes or the
+// witness transaction hashes depending on the negotiated compact block
+// version.  See ShortIDKeys and ShortTxID.
+//
+// This message was not added until protocol versions starting with
+// ShortIDsBlocksVersion.
+type MsgCmpctBlock struct {
+	Header        BlockHeader
+	Nonce         uint64
+	ShortIDs      []uint64
+	PrefilledTxns []PrefilledTx
+}
+
+// AddShortID adds a new short transaction id to the message.
+func (msg *MsgCmpctBlock) AddShortID(id uint64) error {
+	if msg.TotalTxns()+1 > maxTxPerBlock {
+		str := fmt.Sprintf("too many transactions for message "+
+			"[max %v]", maxTxPerBlock)
+		return messageError("MsgCmpctBlock.AddShortID", str)
+	}
+
+	msg.ShortIDs = append(msg.ShortIDs, id&shortTxIDMask)
+	return nil
+}
+
+// AddPrefilledTx adds a new prefilled transaction to the message.  The
+// prefilled transactions must be added in increasing index order.
+func (msg *MsgCmpctBlock) AddPrefilledTx(index uint32, tx *MsgTx) error {
+	if msg.TotalTxns()+1 > maxTxPerBlock {
+		str := fmt.Sprintf("too many transactions for message "+
+			"[max %v]", maxTxPerBlock)
+		return messageError("MsgCmpctBlock.AddPrefilledTx", str)
+	}
+	if n := len(msg.PrefilledTxns); n > 0 &&
+		index <= msg.PrefilledTxns[n-1].Index {
+
+		str := fmt.Sprintf("prefilled transaction index %d is not "+
+			"greater than previous index %d", index,
+			msg.PrefilledTxns[n-1].Index)
+		return messageError("MsgCmpctBlock.AddPrefilledTx", str)
+	}
+
+	msg.PrefilledTxns = append(msg.PrefilledTxns, PrefilledTx{
+		Index: index,
+		Tx:    tx,
+	})
+	return nil
+}
+
+// TotalTxns returns the number of transactions in the block the message
+// describes.
+func (msg *MsgCmpctBlock) TotalTxns() int {
+	return len(msg.ShortIDs) + len(msg.PrefilledTxns)
+}
+
+// ShortIDKeys returns the SipHash keys used to calculate the short transaction
+// ids of the message.  As defined by BIP0152, they are the first two
+// little-endian 64-bit integers of the single SHA256 of the serialized block
+// header followed by the little-endian nonce.
+func (msg *MsgCmpctBlock) ShortIDKeys() (uint64, uint64) {
+	var buf bytes.Buffer
+	buf.Grow(MaxBlockHeaderPayload + 8)
+	_ = writeBlockHeader(&buf, 0, &msg.Header)
+	_ = binary.Write(&buf, littleEndian, msg.Nonce)
+
+	hash := sha256.Sum256(buf.Bytes())
+	return littleEndian.Uint64(hash[0:8]), littleEndian.Uint64(hash[8:16])
+}
+
+// ShortTxID returns the short transaction id of the passed transaction hash
+// using the SipHash keys returned by ShortIDKeys.  The hash is expected to be
+// the transaction hash for CmpctBlockVersion1 and the witness transaction hash
+// for CmpctBlockVersion2.
+func ShortTxID(k0, k1 uint64, hash *chainhash.Hash) uint64 {
+	return sipHash24(k0, k1, hash[:]) & shortTxIDMask
+}
+
+// readShortTxID reads a 6-byte little-endian short transaction id from r.
+func readShortTxID(r io.Reader) (uint64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[:shortTxIDLen]); err != nil {
+		return 0, err
+	}
+	return littleEndian.Uint64(buf[:]), nil
+}
+
+// writeShortTxID writes a 6-byte little-endian short transaction id to w.
+func writeShortTxID(w io.Writer, id uint64) error {
+	var buf [8]byte
+	littleEndian.PutUint64(buf[:], id)
+	_, err := w.Write(buf[:shortTxIDLen])
+	return err
+}
+
+// BronDecode decodes r using the brocoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) BronDecode(r io.Reader, pver uint32, enc MessageEncoding) error {
+	if pver < ShortIDsBlocksVersion {
+		str := fmt.Sprintf("cmpctblock message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgCmpctBlock.BronDecode", str)
+	}
+
+	err := readBlockHeader(r, pver, &msg.Header)
+	if err != nil {
+		return err
+	}
+
+	err = readElement(r, &msg.Nonce)
+	if err != nil {
+		return err
+	}
+
+	// Read num short ids and limit to max.
+	count, err := ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many short ids for message "+
+			"[count %v, max %v]", count, maxTxPerBlock)
+		return messageError("MsgCmpctBlock.BronDecode", str)
+	}
+
+	msg.ShortIDs = make([]uint64, 0, count)
+	for i := uint64(0); i < count; i++ {
+		id, err := readShortTxID(r)
+		if err != nil {
+			return err
+		}
+		msg.ShortIDs = append(msg.ShortIDs, id)
+	}
+
+	// Read num prefilled transactions and limit the total number of
+	// transactions to max.
+	count, err = ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	total := uint64(len(msg.ShortIDs)) + count
+	if total > maxTxPerBlock {
+		str := fmt.Sprintf("too many transactions for message "+
+			"[count %v, max %v]", total, maxTxPerBlock)
+		return messageError("MsgCmpctBlock.BronDecode", str)
+	}
+
+	// The indexes of the prefilled transactions are differentially
+	// encoded relative to the previous index plus one.
+	msg.PrefilledTxns = make([]PrefilledTx, 0, count)
+	var nextIndex uint64
+	for i := uint64(0); i < count; i++ {
+		diff, err := ReadVarInt(r, pver)
+		if err != nil {
+			return err
+		}
+		index := nextIndex + diff
+		if diff >= total || index >= total {
+			str := fmt.Sprintf("prefilled transaction index is out "+
+				"of range [max %v]", total-1)
+			return messageError("MsgCmpctBlock.BronDecode", str)
+		}
+
+		tx := MsgTx{}
+		err = tx.BronDecode(r, pver, enc)
+		if err != nil {
+			return err
+		}
+		msg.PrefilledTxns = append(msg.PrefilledTxns, PrefilledTx{
+			Index: uint32(index),
+			Tx:    &tx,
+		})
+		nextIndex = index + 1
+	}
+
+	return nil
+}
+
+// BronEncode encodes the receiver to w using the brocoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) BronEncode(w io.Writer, pver uint32, enc MessageEncoding) error {
+	if pver < ShortIDsBlocksVersion {
+		str := fmt.Sprintf("cmpctblock message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgCmpctBlock.BronEncode", str)
+	}
+
+	total := msg.TotalTxns()
+	if total > maxTxPerBlock {
+		str := fmt.Sprintf("too many transactions for message "+
+			"[count %v, max %v]", total, maxTxPerBlock)
+		return messageError("MsgCmpctBlock.BronEncode", str)
+	}
+
+	err := writeBlockHeader(w, pver, &msg.Header)
+	if err != nil {
+		return err
+	}
+
+	err = writeElement(w, msg.Nonce)
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarInt(w, pver, uint64(len(msg.ShortIDs)))
+	if err != nil {
+		return err
+	}
+	for _, id := range msg.ShortIDs {
+		err = writeShortTxID(w, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = WriteVarInt(w, pver, uint64(len(msg.PrefilledTxns)))
+	if err != nil {
+		return err
+	}
+	var nextIndex uint32
+	for i := range msg.PrefilledTxns {
+		ptx := &msg.PrefilledTxns[i]
+		if ptx.Index < nextIndex || int(ptx.Index) >= total {
+			str := fmt.Sprintf("prefilled transaction index %d is "+
+				"out of order or range", ptx.Index)
+			return messageError("MsgCmpctBlock.BronEncode", str)
+		}
+
+		err = WriteVarInt(w, pver, uint64(ptx.Index-nextIndex))
+		if err != nil {
+			return err
+		}
+		err = ptx.Tx.BronEncode(w, pver, enc)
+		if err != nil {
+			return err
+		}
+		nextIndex = ptx.Index + 1
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgCmpctBlock) Command() string {
+	return CmdCmpctBlock
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) MaxPayloadLength(pver uint32) uint32 {
+	return MaxBlockPayload
+}
+
+// NewMsgCmpctBlock returns a new brocoin cmpctblock message that conforms to
+// the Message interface.  See MsgCmpctBlock for details.
+func NewMsgCmpctBlock(bh *BlockHeader, nonce uint64) *MsgCmpctBlock {
+	return &MsgCmpctBlock{
+		Header:        *bh,
+		Nonce:         nonce,
+		ShortIDs:      make([]uint64, 0),
+		PrefilledTxns: make([]PrefilledTx, 0, 1),
+	}
+}
+
+// NewMsgCmpctBlockFromBlock returns a new brocoin cmpctblock message for the
+// passed block using the given nonce and compact block version.  The coinbase
+// transaction is prefilled and short ids are calculated for all other
+// transactions.
+func NewMsgCmpctBlockFromBlock(block *MsgBlock, nonce uint64,
+	version uint64) *MsgCmpctBlock {
+
+	msg := NewMsgCmpctBlock(&block.Header, nonce)
+	if len(block.Transactions) == 0 {
+		return msg
+	}
+
+	k0, k1 := msg.ShortIDKeys()
+	msg.PrefilledTxns = append(msg.PrefilledTxns, PrefilledTx{
+		Index: 0,
+		Tx:    block.Transactions[0],
+	})
+	msg.ShortIDs = make([]uint64, 0, len(block.Transactions)-1)
+	for _, tx := range block.Transactions[1:] {
+		var hash chainhash.Hash
+		if version >= CmpctBlockVersion2 {
+			hash = tx.WitnessHash()
+		} else {
+			hash = tx.TxHash()
+		}
+		msg.ShortIDs = append(msg.ShortIDs, ShortTxID(k0, k1, &hash))
+	}
+
+	return msg
+}
@@ -0,0 +1,280 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/brsuite/brond/chaincfg/chainhash"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestCmpctBlock tests the MsgCmpctBlock API.
+func TestCmpctBlock(t *testing.T) {
+	pver := ProtocolVersion
+
+	bh := &blockOne.Header
+	msg := NewMsgCmpctBlock(bh, 0x0102030405060708)
+	if !reflect.DeepEqual(&msg.Header, bh) {
+		t.Errorf("NewMsgCmpctBlock: wrong header - got %v, want %v",
+			spew.Sdump(&msg.Header), spew.Sdump(bh))
+	}
+
+	// Ensure the command is expected value.
+	wantCmd := "cmpctblock"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgCmpctBlock: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value for latest protocol version.
+	wantPayload := uint32(4000000)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+
+	// Ensure short ids are truncated to 6 bytes.
+	if err := msg.AddShortID(0xffff010203040506); err != nil {
+		t.Fatalf("AddShortID: unexpected error %v", err)
+	}
+	if msg.ShortIDs[0] != 0x010203040506 {
+		t.Errorf("AddShortID: wrong short id - got %x, want %x",
+			msg.ShortIDs[0], 0x010203040506)
+	}
+
+	// Ensure prefilled transactions must be added in increasing order.
+	tx := blockOne.Transactions[0]
+	if err := msg.AddPrefilledTx(1, tx); err != nil {
+		t.Fatalf("AddPrefilledTx: unexpected error %v", err)
+	}
+	if err := msg.AddPrefilledTx(1, tx); err == nil {
+		t.Errorf("AddPrefilledTx: expected error on duplicate index")
+	}
+	if msg.TotalTxns() != 2 {
+		t.Errorf("TotalTxns: wrong count - got %d, want %d",
+			msg.TotalTxns(), 2)
+	}
+
+	// Ensure the short id keys are the first two little-endian integers
+	// of the SHA256 of the serialized header and nonce.
+	var nonce [8]byte
+	binary.LittleEndian.PutUint64(nonce[:], msg.Nonce)
+	keyHash := sha256.Sum256(append(blockOneBytes[:80:80], nonce[:]...))
+	wantK0 := binary.LittleEndian.Uint64(keyHash[0:8])
+	wantK1 := binary.LittleEndian.Uint64(keyHash[8:16])
+	k0, k1 := msg.ShortIDKeys()
+	if k0 != wantK0 || k1 != wantK1 {
+		t.Errorf("ShortIDKeys: wrong keys - got (%x, %x), want "+
+			"(%x, %x)", k0, k1, wantK0, wantK1)
+	}
+}
+
+// TestCmpctBlockFromBlock ensures compact blocks created from a block prefill
+// the coinbase and use the correct hashes for the short ids of each compact
+// block version.
+func TestCmpctBlockFromBlock(t *testing.T) {
+	block := blockOne
+	block.Transactions = []*MsgTx{
+		blockOne.Transactions[0], multiTx, multiWitnessTx,
+	}
+
+	tests := []struct {
+		version uint64
+		hashes  func(tx *MsgTx) chainhash.Hash
+	}{
+		{CmpctBlockVersion1, func(tx *MsgTx) chainhash.Hash { return tx.TxHash() }},
+		{CmpctBlockVersion2, func(tx *MsgTx) chainhash.Hash { return tx.WitnessHash() }},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		msg := NewMsgCmpctBlockFromBlock(&block, 1234, test.version)
+		if len(msg.PrefilledTxns) != 1 ||
+			msg.PrefilledTxns[0].Index != 0 ||
+			msg.PrefilledTxns[0].Tx != blockOne.Transactions[0] {
+
+			t.Errorf("NewMsgCmpctBlockFromBlock #%d: coinbase not "+
+				"prefilled - got %v", i, spew.Sdump(msg.PrefilledTxns))
+			continue
+		}
+
+		k0, k1 := msg.ShortIDKeys()
+		want := make([]uint64, 0, 2)
+		for _, tx := range block.Transactions[1:] {
+			hash := test.hashes(tx)
+			want = append(want, sipHash24(k0, k1, hash[:])&shortTxIDMask)
+		}
+		if !reflect.DeepEqual(msg.ShortIDs, want) {
+			t.Errorf("NewMsgCmpctBlockFromBlock #%d: wrong short ids "+
+				"- got %x, want %x", i, msg.ShortIDs, want)
+		}
+	}
+}
+
+// TestCmpctBlockWire tests the MsgCmpctBlock wire encode and decode.
+func TestCmpctBlockWire(t *testing.T) {
+	coinbase := blockOne.Transactions[0]
+	coinbaseBytes := blockOneBytes[81:]
+
+	msg := NewMsgCmpctBlock(&blockOne.Header, 0x0102030405060708)
+	msg.AddPrefilledTx(0, coinbase)
+	msg.AddShortID(0x010203040506)
+	msg.AddShortID(0x0a0b0c0d0e0f)
+	msg.AddPrefilledTx(3, coinbase)
+
+	var encoded []byte
+	encoded = append(encoded, blockOneBytes[:80]...)
+	encoded = append(encoded,
+		0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01, // Nonce
+		0x02,                               // Varint for number of short ids
+		0x06, 0x05, 0x04, 0x03, 0x02, 0x01, // Short id 1
+		0x0f, 0x0e, 0x0d, 0x0c, 0x0b, 0x0a, // Short id 2
+		0x02, // Varint for number of prefilled txns
+		0x00, // Differential index of coinbase
+	)
+	encoded = append(encoded, coinbaseBytes...)
+	encoded = append(encoded, 0x02) // Differential index of second tx
+	encoded = append(encoded, coinbaseBytes...)
+
+	// Encode the message to wire format.
+	var buf bytes.Buffer
+	err := msg.BronEncode(&buf, ProtocolVersion, BaseEncoding)
+	if err != nil {
+		t.Fatalf("BronEncode error %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), encoded) {
+		t.Fatalf("BronEncode\n got: %s want: %s",
+			spew.Sdump(buf.Bytes()), spew.Sdump(encoded))
+	}
+
+	// Decode the message from wire format.
+	var readmsg MsgCmpctBlock
+	err = readmsg.BronDecode(bytes.NewReader(encoded), ProtocolVersion,
+		BaseEncoding)
+	if err != nil {
+		t.Fatalf("BronDecode error %v", err)
+	}
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Fatalf("BronDecode\n got: %s want: %s", spew.Sdump(&readmsg),
+			spew.Sdump(msg))
+	}
+}
+
+// TestCmpctBlockWireErrors performs negative tests against wire encode and
+// decode of MsgCmpctBlock to confirm error paths work correctly.
+func TestCmpctBlockWireErrors(t *testing.T) {
+	pver := ProtocolVersion
+	pverNoCmpctBlock := ShortIDsBlocksVersion - 1
+	wireErr := &MessageError{}
+
+	baseCmpctBlock := NewMsgCmpctBlock(&blockOne.Header, 0)
+	baseCmpctBlock.AddShortID(0x010203040506)
+	baseCmpctBlock.AddPrefilledTx(1, blockOne.Transactions[0])
+
+	var baseCmpctBlockEncoded []byte
+	baseCmpctBlockEncoded = append(baseCmpctBlockEncoded,
+		blockOneBytes[:80]...)
+	baseCmpctBlockEncoded = append(baseCmpctBlockEncoded,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Nonce
+		0x01,                               // Varint for number of short ids
+		0x06, 0x05, 0x04, 0x03, 0x02, 0x01, // Short id
+		0x01, // Varint for number of prefilled txns
+		0x01, // Differential index
+	)
+	baseCmpctBlockEncoded = append(baseCmpctBlockEncoded,
+		blockOneBytes[81:]...)
+
+	// Prefilled transaction index beyond the number of transactions.
+	badIndexEncoded := make([]byte, len(baseCmpctBlockEncoded))
+	copy(badIndexEncoded, baseCmpctBlockEncoded)
+	badIndexEncoded[96] = 0x02
+
+	// Out of order prefilled transactions can't be encoded.
+	badOrder := NewMsgCmpctBlock(&blockOne.Header, 0)
+	badOrder.PrefilledTxns = []PrefilledTx{
+		{Index: 1, Tx: blockOne.Transactions[0]},
+		{Index: 0, Tx: blockOne.Transactions[0]},
+	}
+
+	tests := []struct {
+		in       *MsgCmpctBlock // Value to encode
+		buf      []byte         // Wire encoding
+		pver     uint32         // Protocol version for wire encoding
+		max      int            // Max size of fixed buffer to induce errors
+		writeErr error          // Expected write error
+		readErr  error          // Expected read error
+	}{
+		// Force error in header.
+		{baseCmpctBlock, baseCmpctBlockEncoded, pver, 0, io.ErrShortWrite, io.EOF},
+		// Force error in nonce.
+		{baseCmpctBlock, baseCmpctBlockEncoded, pver, 80, io.ErrShortWrite, io.EOF},
+		// Force error in short id count.
+		{baseCmpctBlock, baseCmpctBlockEncoded, pver, 88, io.ErrShortWrite, io.EOF},
+		// Force error in short id.
+		{baseCmpctBlock, baseCmpctBlockEncoded, pver, 89, io.ErrShortWrite, io.EOF},
+		// Force error in prefilled txn count.
+		{baseCmpctBlock, baseCmpctBlockEncoded, pver, 95, io.ErrShortWrite, io.EOF},
+		// Force error in prefilled txn index.
+		{baseCmpctBlock, baseCmpctBlockEncoded, pver, 96, io.ErrShortWrite, io.EOF},
+		// Force error in prefilled txn.
+		{baseCmpctBlock, baseCmpctBlockEncoded, pver, 97, io.ErrShortWrite, io.EOF},
+		// Force error due to unsupported protocol version.
+		{baseCmpctBlock, baseCmpctBlockEncoded, pverNoCmpctBlock, 200, wireErr, wireErr},
+		// Force error due to prefilled txn index out of range.
+		{baseCmpctBlock, badIndexEncoded, pver, len(badIndexEncoded), nil, wireErr},
+		// Force error due to out of order prefilled txns.
+		{badOrder, baseCmpctBlockEncoded, pver, 1000, wireErr, nil},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode to wire format.
+		w := newFixedWriter(test.max)
+		err := test.in.BronEncode(w, test.pver, BaseEncoding)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.writeErr) {
+			t.Errorf("BronEncode #%d wrong error got: %v, want: %v",
+				i, err, test.writeErr)
+			continue
+		}
+
+		// For errors which are not of type MessageError, check them for
+		// equality.
+		if _, ok := err.(*MessageError); !ok {
+			if err != test.writeErr {
+				t.Errorf("BronEncode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.writeErr)
+				continue
+			}
+		}
+
+		// Decode from wire format.
+		var msg MsgCmpctBlock
+		r := newFixedReader(test.max, test.buf)
+		err = msg.BronDecode(r, test.pver, BaseEncoding)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.readErr) {
+			t.Errorf("BronDecode #%d wrong error got: %v, want: %v",
+				i, err, test.readErr)
+			continue
+		}
+
+		// For errors which are not of type MessageError, check them for
+		// equality.
+		if _, ok := err.(*MessageError); !ok {
+			if err != test.readErr {
+				t.Errorf("BronDecode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.readErr)
+				continue
+			}
+		}
+	}
+}
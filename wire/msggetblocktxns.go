@@ -0,0 +1,156 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/brsuite/brond/chaincfg/chainhash"
+)
+
+// MsgGetBlockTxns implements the Message interface and represents a brocoin
+// getblocktxn message as defined by BIP0152.  It is used to request the
+// transactions at the given indexes of a block which could not be
+// reconstructed from a cmpctblock message.  The response is a blocktxn
+// message (MsgBlockTxns).
+//
+// This message was not added until protocol versions starting with
+// ShortIDsBlocksVersion.
+type MsgGetBlockTxns struct {
+	BlockHash chainhash.Hash
+	Indexes   []uint32
+}
+
+// AddIndex adds a new transaction index to the message.  The indexes must be
+// added in increasing order.
+func (msg *MsgGetBlockTxns) AddIndex(index uint32) error {
+	if len(msg.Indexes)+1 > maxTxPerBlock {
+		str := fmt.Sprintf("too many indexes for message [max %v]",
+			maxTxPerBlock)
+		return messageError("MsgGetBlockTxns.AddIndex", str)
+	}
+	if n := len(msg.Indexes); n > 0 && index <= msg.Indexes[n-1] {
+		str := fmt.Sprintf("transaction index %d is not greater than "+
+			"previous index %d", index, msg.Indexes[n-1])
+		return messageError("MsgGetBlockTxns.AddIndex", str)
+	}
+
+	msg.Indexes = append(msg.Indexes, index)
+	return nil
+}
+
+// BronDecode decodes r using the brocoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxns) BronDecode(r io.Reader, pver uint32, enc MessageEncoding) error {
+	if pver < ShortIDsBlocksVersion {
+		str := fmt.Sprintf("getblocktxn message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgGetBlockTxns.BronDecode", str)
+	}
+
+	err := readElement(r, &msg.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	// Read num indexes and limit to max.
+	count, err := ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many indexes for message "+
+			"[count %v, max %v]", count, maxTxPerBlock)
+		return messageError("MsgGetBlockTxns.BronDecode", str)
+	}
+
+	// The indexes are differentially encoded relative to the previous
+	// index plus one.
+	msg.Indexes = make([]uint32, 0, count)
+	var nextIndex uint64
+	for i := uint64(0); i < count; i++ {
+		diff, err := ReadVarInt(r, pver)
+		if err != nil {
+			return err
+		}
+		index := nextIndex + diff
+		if diff >= maxTxPerBlock || index >= maxTxPerBlock {
+			str := fmt.Sprintf("transaction index is out of range "+
+				"[max %v]", maxTxPerBlock-1)
+			return messageError("MsgGetBlockTxns.BronDecode", str)
+		}
+		msg.Indexes = append(msg.Indexes, uint32(index))
+		nextIndex = index + 1
+	}
+
+	return nil
+}
+
+// BronEncode encodes the receiver to w using the brocoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxns) BronEncode(w io.Writer, pver uint32, enc MessageEncoding) error {
+	if pver < ShortIDsBlocksVersion {
+		str := fmt.Sprintf("getblocktxn message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgGetBlockTxns.BronEncode", str)
+	}
+
+	count := len(msg.Indexes)
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many indexes for message "+
+			"[count %v, max %v]", count, maxTxPerBlock)
+		return messageError("MsgGetBlockTxns.BronEncode", str)
+	}
+
+	err := writeElement(w, &msg.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarInt(w, pver, uint64(count))
+	if err != nil {
+		return err
+	}
+	var nextIndex uint32
+	for _, index := range msg.Indexes {
+		if index < nextIndex {
+			str := fmt.Sprintf("transaction index %d is out of "+
+				"order", index)
+			return messageError("MsgGetBlockTxns.BronEncode", str)
+		}
+		err = WriteVarInt(w, pver, uint64(index-nextIndex))
+		if err != nil {
+			return err
+		}
+		nextIndex = index + 1
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgGetBlockTxns) Command() string {
+	return CmdGetBlockTxns
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxns) MaxPayloadLength(pver uint32) uint32 {
+	// Block hash + num indexes (varInt) + max allowed indexes.
+	return chainhash.HashSize + MaxVarIntPayload +
+		(maxTxPerBlock * MaxVarIntPayload)
+}
+
+// NewMsgGetBlockTxns returns a new brocoin getblocktxn message that conforms
+// to the Message interface using the passed parameters.  See MsgGetBlockTxns
+// for details.
+func NewMsgGetBlockTxns(blockHash *chainhash.Hash) *MsgGetBlockTxns {
+	return &MsgGetBlockTxns{
+		BlockHash: *blockHash,
+		Indexes:   make([]uint32, 0),
+	}
+}
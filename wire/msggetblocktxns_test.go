@@ -0,0 +1,181 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestGetBlockTxns tests the MsgGetBlockTxns API.
+func TestGetBlockTxns(t *testing.T) {
+	pver := ProtocolVersion
+
+	hash := mainNetGenesisHash
+	msg := NewMsgGetBlockTxns(&hash)
+	if !msg.BlockHash.IsEqual(&hash) {
+		t.Errorf("NewMsgGetBlockTxns: wrong hash - got %v, want %v",
+			msg.BlockHash, hash)
+	}
+
+	// Ensure the command is expected value.
+	wantCmd := "getblocktxn"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgGetBlockTxns: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value for latest protocol version.
+	// Block hash 32 bytes + num indexes (varInt) 9 bytes + max indexes
+	// (varInt) 9 bytes each.
+	wantPayload := uint32(32 + 9 + maxTxPerBlock*9)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+
+	// Ensure indexes must be added in increasing order.
+	if err := msg.AddIndex(5); err != nil {
+		t.Fatalf("AddIndex: unexpected error %v", err)
+	}
+	if err := msg.AddIndex(5); err == nil {
+		t.Errorf("AddIndex: expected error on duplicate index")
+	}
+}
+
+// TestGetBlockTxnsWire tests the MsgGetBlockTxns wire encode and decode.
+func TestGetBlockTxnsWire(t *testing.T) {
+	hash := mainNetGenesisHash
+	msg := NewMsgGetBlockTxns(&hash)
+	msg.AddIndex(0)
+	msg.AddIndex(1)
+	msg.AddIndex(5)
+	msg.AddIndex(300)
+
+	encoded := append(mainNetGenesisHash[:0:0], mainNetGenesisHash[:]...)
+	encoded = append(encoded,
+		0x04,             // Varint for number of indexes
+		0x00,             // Index 0
+		0x00,             // Index 1
+		0x03,             // Index 5
+		0xfd, 0x26, 0x01, // Index 300
+	)
+
+	// Encode the message to wire format.
+	var buf bytes.Buffer
+	err := msg.BronEncode(&buf, ProtocolVersion, BaseEncoding)
+	if err != nil {
+		t.Fatalf("BronEncode error %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), encoded) {
+		t.Fatalf("BronEncode\n got: %s want: %s",
+			spew.Sdump(buf.Bytes()), spew.Sdump(encoded))
+	}
+
+	// Decode the message from wire format.
+	var readmsg MsgGetBlockTxns
+	err = readmsg.BronDecode(bytes.NewReader(encoded), ProtocolVersion,
+		BaseEncoding)
+	if err != nil {
+		t.Fatalf("BronDecode error %v", err)
+	}
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Fatalf("BronDecode\n got: %s want: %s", spew.Sdump(&readmsg),
+			spew.Sdump(msg))
+	}
+}
+
+// TestGetBlockTxnsWireErrors performs negative tests against wire encode and
+// decode of MsgGetBlockTxns to confirm error paths work correctly.
+func TestGetBlockTxnsWireErrors(t *testing.T) {
+	pver := ProtocolVersion
+	pverNoGetBlockTxns := ShortIDsBlocksVersion - 1
+	wireErr := &MessageError{}
+
+	hash := mainNetGenesisHash
+	baseGetBlockTxns := NewMsgGetBlockTxns(&hash)
+	baseGetBlockTxns.AddIndex(1)
+
+	baseGetBlockTxnsEncoded := append(hash[:0:0], hash[:]...)
+	baseGetBlockTxnsEncoded = append(baseGetBlockTxnsEncoded,
+		0x01, // Varint for number of indexes
+		0x01, // Index 1
+	)
+
+	// Index which overflows the maximum number of transactions.
+	badIndexEncoded := append(hash[:0:0], hash[:]...)
+	badIndexEncoded = append(badIndexEncoded,
+		0x01,                                                 // Varint for number of indexes
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, // Index
+	)
+
+	tests := []struct {
+		in       *MsgGetBlockTxns // Value to encode
+		buf      []byte           // Wire encoding
+		pver     uint32           // Protocol version for wire encoding
+		max      int              // Max size of fixed buffer to induce errors
+		writeErr error            // Expected write error
+		readErr  error            // Expected read error
+	}{
+		// Force error in block hash.
+		{baseGetBlockTxns, baseGetBlockTxnsEncoded, pver, 0, io.ErrShortWrite, io.EOF},
+		// Force error in index count.
+		{baseGetBlockTxns, baseGetBlockTxnsEncoded, pver, 32, io.ErrShortWrite, io.EOF},
+		// Force error in index.
+		{baseGetBlockTxns, baseGetBlockTxnsEncoded, pver, 33, io.ErrShortWrite, io.EOF},
+		// Force error due to unsupported protocol version.
+		{baseGetBlockTxns, baseGetBlockTxnsEncoded, pverNoGetBlockTxns, 34, wireErr, wireErr},
+		// Force error due to index out of range.
+		{baseGetBlockTxns, badIndexEncoded, pver, 42, nil, wireErr},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode to wire format.
+		w := newFixedWriter(test.max)
+		err := test.in.BronEncode(w, test.pver, BaseEncoding)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.writeErr) {
+			t.Errorf("BronEncode #%d wrong error got: %v, want: %v",
+				i, err, test.writeErr)
+			continue
+		}
+
+		// For errors which are not of type MessageError, check them for
+		// equality.
+		if _, ok := err.(*MessageError); !ok {
+			if err != test.writeErr {
+				t.Errorf("BronEncode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.writeErr)
+				continue
+			}
+		}
+
+		// Decode from wire format.
+		var msg MsgGetBlockTxns
+		r := newFixedReader(test.max, test.buf)
+		err = msg.BronDecode(r, test.pver, BaseEncoding)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.readErr) {
+			t.Errorf("BronDecode #%d wrong error got: %v, want: %v",
+				i, err, test.readErr)
+			continue
+		}
+
+		// For errors which are not of type MessageError, check them for
+		// equality.
+		if _, ok := err.(*MessageError); !ok {
+			if err != test.readErr {
+				t.Errorf("BronDecode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.readErr)
+				continue
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"io"
+)
+
+// MsgSendAddrV2 implements the Message interface and represents a brocoin
+// sendaddrv2 message as defined by BIP0155.  It is used to signal that the
+// peer prefers to receive addresses in addrv2 messages (MsgAddrV2) rather
+// than addr messages (MsgAddr).  It must be sent after the version message and
+// before the verack message.
+//
+// This message has no payload.  BIP0155 defines it for all protocol versions,
+// however it is only sent to peers with a protocol version of AddrV2Version or
+// later to avoid disconnects from peers that do not know the message.
+type MsgSendAddrV2 struct{}
+
+// BronDecode decodes r using the brocoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgSendAddrV2) BronDecode(r io.Reader, pver uint32, enc MessageEncoding) error {
+	return nil
+}
+
+// BronEncode encodes the receiver to w using the brocoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgSendAddrV2) BronEncode(w io.Writer, pver uint32, enc MessageEncoding) error {
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgSendAddrV2) Command() string {
+	return CmdSendAddrV2
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgSendAddrV2) MaxPayloadLength(pver uint32) uint32 {
+	return 0
+}
+
+// NewMsgSendAddrV2 returns a new brocoin sendaddrv2 message that conforms to
+// the Message interface.  See MsgSendAddrV2 for details.
+func NewMsgSendAddrV2() *MsgSendAddrV2 {
+	return &MsgSendAddrV2{}
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestSendAddrV2 tests the MsgSendAddrV2 API.
+func TestSendAddrV2(t *testing.T) {
+	pver := ProtocolVersion
+	enc := BaseEncoding
+
+	// Ensure the command is expected value.
+	wantCmd := "sendaddrv2"
+	msg := NewMsgSendAddrV2()
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgSendAddrV2: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value.
+	wantPayload := uint32(0)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+
+	// The message is defined for all protocol versions, so it must encode
+	// and decode with both the latest and old protocol versions.
+	for _, pver := range []uint32{ProtocolVersion, AddrV2Version - 1} {
+		var buf bytes.Buffer
+		err := msg.BronEncode(&buf, pver, enc)
+		if err != nil {
+			t.Errorf("encode of MsgSendAddrV2 failed %v err <%v>",
+				msg, err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("encode of MsgSendAddrV2 produced a payload "+
+				"of %d bytes", buf.Len())
+		}
+
+		readmsg := NewMsgSendAddrV2()
+		err = readmsg.BronDecode(&buf, pver, enc)
+		if err != nil {
+			t.Errorf("decode of MsgSendAddrV2 failed [%v] err <%v>",
+				buf, err)
+		}
+		if !reflect.DeepEqual(readmsg, msg) {
+			t.Errorf("decode of MsgSendAddrV2\n got: %s want: %s",
+				spew.Sdump(readmsg), spew.Sdump(msg))
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	// CmpctBlockVersion1 is the compact block version which calculates
+	// short transaction ids from transaction hashes and serializes
+	// prefilled transactions without witness data.
+	CmpctBlockVersion1 uint64 = 1
+
+	// CmpctBlockVersion2 is the compact block version which calculates
+	// short transaction ids from witness transaction hashes and serializes
+	// prefilled transactions with witness data.
+	CmpctBlockVersion2 uint64 = 2
+)
+
+// MsgSendCmpct implements the Message interface and represents a brocoin
+// sendcmpct message as defined by BIP0152.  It is used to signal that the
+// peer supports compact blocks of the given version and whether it prefers
+// new blocks to be announced directly with a cmpctblock message
+// (high-bandwidth mode) rather than with an inv or headers message
+// (low-bandwidth mode).
+//
+// This message was not added until protocol versions starting with
+// ShortIDsBlocksVersion.
+type MsgSendCmpct struct {
+	AnnounceUsingCmpctBlock bool
+	CmpctBlockVersion       uint64
+}
+
+// BronDecode decodes r using the brocoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgSendCmpct) BronDecode(r io.Reader, pver uint32, enc MessageEncoding) error {
+	if pver < ShortIDsBlocksVersion {
+		str := fmt.Sprintf("sendcmpct message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgSendCmpct.BronDecode", str)
+	}
+
+	return readElements(r, &msg.AnnounceUsingCmpctBlock,
+		&msg.CmpctBlockVersion)
+}
+
+// BronEncode encodes the receiver to w using the brocoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgSendCmpct) BronEncode(w io.Writer, pver uint32, enc MessageEncoding) error {
+	if pver < ShortIDsBlocksVersion {
+		str := fmt.Sprintf("sendcmpct message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgSendCmpct.BronEncode", str)
+	}
+
+	return writeElements(w, msg.AnnounceUsingCmpctBlock,
+		msg.CmpctBlockVersion)
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgSendCmpct) Command() string {
+	return CmdSendCmpct
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgSendCmpct) MaxPayloadLength(pver uint32) uint32 {
+	// Announce flag 1 byte + version 8 bytes.
+	return 9
+}
+
+// NewMsgSendCmpct returns a new brocoin sendcmpct message that conforms to the
+// Message interface using the passed parameters.  See MsgSendCmpct for
+// details.
+func NewMsgSendCmpct(announce bool, version uint64) *MsgSendCmpct {
+	return &MsgSendCmpct{
+		AnnounceUsingCmpctBlock: announce,
+		CmpctBlockVersion:       version,
+	}
+}
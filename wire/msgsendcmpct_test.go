@@ -0,0 +1,179 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestSendCmpct tests the MsgSendCmpct API.
+func TestSendCmpct(t *testing.T) {
+	pver := ProtocolVersion
+
+	msg := NewMsgSendCmpct(true, CmpctBlockVersion2)
+	if !msg.AnnounceUsingCmpctBlock {
+		t.Errorf("NewMsgSendCmpct: wrong announce flag - got %v, "+
+			"want %v", msg.AnnounceUsingCmpctBlock, true)
+	}
+	if msg.CmpctBlockVersion != CmpctBlockVersion2 {
+		t.Errorf("NewMsgSendCmpct: wrong version - got %v, want %v",
+			msg.CmpctBlockVersion, CmpctBlockVersion2)
+	}
+
+	// Ensure the command is expected value.
+	wantCmd := "sendcmpct"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgSendCmpct: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value.
+	wantPayload := uint32(9)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+}
+
+// TestSendCmpctWire tests the MsgSendCmpct wire encode and decode for various
+// protocol versions.
+func TestSendCmpctWire(t *testing.T) {
+	tests := []struct {
+		in   MsgSendCmpct // Message to encode
+		out  MsgSendCmpct // Expected decoded message
+		buf  []byte       // Wire encoding
+		pver uint32       // Protocol version for wire encoding
+	}{
+		// Latest protocol version.
+		{
+			MsgSendCmpct{true, CmpctBlockVersion2},
+			MsgSendCmpct{true, CmpctBlockVersion2},
+			[]byte{
+				0x01,                                           // Announce
+				0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Version
+			},
+			ProtocolVersion,
+		},
+
+		// Protocol version ShortIDsBlocksVersion.
+		{
+			MsgSendCmpct{false, CmpctBlockVersion1},
+			MsgSendCmpct{false, CmpctBlockVersion1},
+			[]byte{
+				0x00,                                           // Announce
+				0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Version
+			},
+			ShortIDsBlocksVersion,
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode the message to wire format.
+		var buf bytes.Buffer
+		err := test.in.BronEncode(&buf, test.pver, BaseEncoding)
+		if err != nil {
+			t.Errorf("BronEncode #%d error %v", i, err)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), test.buf) {
+			t.Errorf("BronEncode #%d\n got: %s want: %s", i,
+				spew.Sdump(buf.Bytes()), spew.Sdump(test.buf))
+			continue
+		}
+
+		// Decode the message from wire format.
+		var msg MsgSendCmpct
+		rbuf := bytes.NewReader(test.buf)
+		err = msg.BronDecode(rbuf, test.pver, BaseEncoding)
+		if err != nil {
+			t.Errorf("BronDecode #%d error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(msg, test.out) {
+			t.Errorf("BronDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(msg), spew.Sdump(test.out))
+			continue
+		}
+	}
+}
+
+// TestSendCmpctWireErrors performs negative tests against wire encode and
+// decode of MsgSendCmpct to confirm error paths work correctly.
+func TestSendCmpctWireErrors(t *testing.T) {
+	pver := ProtocolVersion
+	pverNoSendCmpct := ShortIDsBlocksVersion - 1
+	wireErr := &MessageError{}
+
+	baseSendCmpct := NewMsgSendCmpct(true, CmpctBlockVersion1)
+	baseSendCmpctEncoded := []byte{
+		0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+	tests := []struct {
+		in       *MsgSendCmpct // Value to encode
+		buf      []byte        // Wire encoding
+		pver     uint32        // Protocol version for wire encoding
+		max      int           // Max size of fixed buffer to induce errors
+		writeErr error         // Expected write error
+		readErr  error         // Expected read error
+	}{
+		// Force error in announce flag.
+		{baseSendCmpct, baseSendCmpctEncoded, pver, 0, io.ErrShortWrite, io.EOF},
+		// Force error in version.
+		{baseSendCmpct, baseSendCmpctEncoded, pver, 1, io.ErrShortWrite, io.EOF},
+		// Force error due to unsupported protocol version.
+		{baseSendCmpct, baseSendCmpctEncoded, pverNoSendCmpct, 9, wireErr, wireErr},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode to wire format.
+		w := newFixedWriter(test.max)
+		err := test.in.BronEncode(w, test.pver, BaseEncoding)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.writeErr) {
+			t.Errorf("BronEncode #%d wrong error got: %v, want: %v",
+				i, err, test.writeErr)
+			continue
+		}
+
+		// For errors which are not of type MessageError, check them for
+		// equality.
+		if _, ok := err.(*MessageError); !ok {
+			if err != test.writeErr {
+				t.Errorf("BronEncode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.writeErr)
+				continue
+			}
+		}
+
+		// Decode from wire format.
+		var msg MsgSendCmpct
+		r := newFixedReader(test.max, test.buf)
+		err = msg.BronDecode(r, test.pver, BaseEncoding)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.readErr) {
+			t.Errorf("BronDecode #%d wrong error got: %v, want: %v",
+				i, err, test.readErr)
+			continue
+		}
+
+		// For errors which are not of type MessageError, check them for
+		// equality.
+		if _, ok := err.(*MessageError); !ok {
+			if err != test.readErr {
+				t.Errorf("BronDecode #%d wrong error got: %v, "+
+					"want: %v", i, err, test.readErr)
+				continue
+			}
+		}
+	}
+}
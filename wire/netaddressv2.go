@@ -0,0 +1,273 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// MaxAddrV2Size is the maximum number of bytes an address in an addrv2
+// message may have as defined by BIP0155.
+const MaxAddrV2Size = 512
+
+// NetworkID identifies the network an address in an addrv2 message (MsgAddrV2)
+// belongs to as defined by BIP0155.
+type NetworkID uint8
+
+const (
+	// NetIPv4 identifies an IPv4 address.
+	NetIPv4 NetworkID = 0x01
+
+	// NetIPv6 identifies an IPv6 address.
+	NetIPv6 NetworkID = 0x02
+
+	// NetTorV2 identifies a Tor v2 hidden service address.  Tor no longer
+	// supports these, but they are still parsed so the addresses can be
+	// recognized and ignored.
+	NetTorV2 NetworkID = 0x03
+
+	// NetTorV3 identifies a Tor v3 hidden service address, which is the
+	// ed25519 public key of the service.
+	NetTorV3 NetworkID = 0x04
+
+	// NetI2P identifies an I2P address, which is the SHA256 hash of the
+	// destination.
+	NetI2P NetworkID = 0x05
+
+	// NetCJDNS identifies a CJDNS address, which is an IPv6 address in the
+	// fc00::/8 range.
+	NetCJDNS NetworkID = 0x06
+)
+
+// netIDStrings is a map of network IDs back to their constant names for
+// pretty printing.
+var netIDStrings = map[NetworkID]string{
+	NetIPv4:  "IPv4",
+	NetIPv6:  "IPv6",
+	NetTorV2: "TorV2",
+	NetTorV3: "TorV3",
+	NetI2P:   "I2P",
+	NetCJDNS: "CJDNS",
+}
+
+// String returns the NetworkID in human-readable form.
+func (id NetworkID) String() string {
+	if s, ok := netIDStrings[id]; ok {
+		return s
+	}
+
+	return fmt.Sprintf("Unknown NetworkID (%d)", uint8(id))
+}
+
+// addrLen returns the fixed length of addresses on the network and whether or
+// not the network is known.
+func (id NetworkID) addrLen() (int, bool) {
+	switch id {
+	case NetIPv4:
+		return net.IPv4len, true
+	case NetIPv6, NetCJDNS:
+		return net.IPv6len, true
+	case NetTorV2:
+		return 10, true
+	case NetTorV3, NetI2P:
+		return 32, true
+	}
+
+	return 0, false
+}
+
+// maxNetAddressV2Payload returns the max payload size for an address in an
+// addrv2 message.
+func maxNetAddressV2Payload() uint32 {
+	// Timestamp 4 bytes + services varint + network id 1 byte + address
+	// length varint + max address size + port 2 bytes.
+	return 4 + MaxVarIntPayload + 1 + MaxVarIntPayload + MaxAddrV2Size + 2
+}
+
+// NetAddressV2 defines information about a peer on the network including the
+// time it was last seen, the services it supports, the network it is on, its
+// address on that network and port.  Unlike NetAddress, it is able to describe
+// peers on networks that do not use 16-byte IP addresses such as Tor v3, I2P
+// and CJDNS.
+type NetAddressV2 struct {
+	// Last time the address was seen.  This is encoded as a uint32 on the
+	// wire and therefore is limited to 2106.
+	Timestamp time.Time
+
+	// Bitfield which identifies the services supported by the address.
+	Services ServiceFlag
+
+	// NetworkID identifies the network the address belongs to.
+	NetworkID NetworkID
+
+	// Addr is the address of the peer encoded as defined for its network
+	// by BIP0155.
+	Addr []byte
+
+	// Port the peer is using.  This is encoded in big endian on the wire
+	// which differs from most everything else.
+	Port uint16
+}
+
+// HasService returns whether the specified service is supported by the address.
+func (na *NetAddressV2) HasService(service ServiceFlag) bool {
+	return na.Services&service == service
+}
+
+// AddService adds service as a supported service by the peer generating the
+// message.
+func (na *NetAddressV2) AddService(service ServiceFlag) {
+	na.Services |= service
+}
+
+// IP returns the address as an IP address for the networks which use them,
+// which are IPv4, IPv6 and CJDNS.  Nil is returned for all other networks.
+func (na *NetAddressV2) IP() net.IP {
+	switch na.NetworkID {
+	case NetIPv4, NetIPv6, NetCJDNS:
+		return net.IP(na.Addr)
+	}
+
+	return nil
+}
+
+// ToLegacy converts the address to a NetAddress which can be used in addr and
+// version messages.  Only IPv4 and IPv6 addresses can be represented that way,
+// so false is returned for addresses on all other networks.
+func (na *NetAddressV2) ToLegacy() (*NetAddress, bool) {
+	switch na.NetworkID {
+	case NetIPv4, NetIPv6:
+	default:
+		return nil, false
+	}
+
+	return &NetAddress{
+		Timestamp: na.Timestamp,
+		Services:  na.Services,
+		IP:        net.IP(na.Addr).To16(),
+		Port:      na.Port,
+	}, true
+}
+
+// NewNetAddressV2 returns a new NetAddressV2 using the provided network ID,
+// address, port and supported services with defaults for the remaining fields.
+func NewNetAddressV2(netID NetworkID, addr []byte, port uint16,
+	services ServiceFlag) *NetAddressV2 {
+
+	// Limit the timestamp to one second precision since the protocol
+	// doesn't support better.
+	return &NetAddressV2{
+		Timestamp: time.Unix(time.Now().Unix(), 0),
+		Services:  services,
+		NetworkID: netID,
+		Addr:      addr,
+		Port:      port,
+	}
+}
+
+// NewNetAddressV2IPPort returns a new NetAddressV2 using the provided IP, port
+// and supported services with defaults for the remaining fields.  The address
+// is on the IPv4 network when the IP is an IPv4 or IPv4-mapped IPv6 address
+// and on the IPv6 network otherwise.
+func NewNetAddressV2IPPort(ip net.IP, port uint16,
+	services ServiceFlag) *NetAddressV2 {
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return NewNetAddressV2(NetIPv4, ip4, port, services)
+	}
+	return NewNetAddressV2(NetIPv6, ip.To16(), port, services)
+}
+
+// NetAddressV2FromLegacy returns a new NetAddressV2 which describes the same
+// peer as the passed NetAddress.
+func NetAddressV2FromLegacy(na *NetAddress) *NetAddressV2 {
+	nav2 := NewNetAddressV2IPPort(na.IP, na.Port, na.Services)
+	nav2.Timestamp = na.Timestamp
+	return nav2
+}
+
+// readNetAddressV2 reads an encoded NetAddressV2 from r as defined by BIP0155.
+// Addresses on unknown networks are read as well so they can be ignored by
+// the caller, however an error is returned for addresses on known networks
+// with an invalid length.
+func readNetAddressV2(r io.Reader, pver uint32, na *NetAddressV2) error {
+	err := readElement(r, (*uint32Time)(&na.Timestamp))
+	if err != nil {
+		return err
+	}
+
+	services, err := ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+
+	var netID uint8
+	if err := readElement(r, &netID); err != nil {
+		return err
+	}
+
+	addr, err := ReadVarBytes(r, pver, MaxAddrV2Size, "addrv2 address")
+	if err != nil {
+		return err
+	}
+	if wantLen, ok := NetworkID(netID).addrLen(); ok && len(addr) != wantLen {
+		str := fmt.Sprintf("invalid %v address length - got %d, want %d",
+			NetworkID(netID), len(addr), wantLen)
+		return messageError("readNetAddressV2", str)
+	}
+
+	// Sigh.  Brocoin protocol mixes little and big endian.
+	port, err := binarySerializer.Uint16(r, bigEndian)
+	if err != nil {
+		return err
+	}
+
+	*na = NetAddressV2{
+		Timestamp: na.Timestamp,
+		Services:  ServiceFlag(services),
+		NetworkID: NetworkID(netID),
+		Addr:      addr,
+		Port:      port,
+	}
+	return nil
+}
+
+// writeNetAddressV2 serializes a NetAddressV2 to w as defined by BIP0155.
+func writeNetAddressV2(w io.Writer, pver uint32, na *NetAddressV2) error {
+	if len(na.Addr) > MaxAddrV2Size {
+		str := fmt.Sprintf("address is too long - got %d bytes, max %d",
+			len(na.Addr), MaxAddrV2Size)
+		return messageError("writeNetAddressV2", str)
+	}
+
+	// NOTE: The brocoin protocol uses a uint32 for the timestamp so it will
+	// stop working somewhere around 2106.
+	err := writeElement(w, uint32(na.Timestamp.Unix()))
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarInt(w, pver, uint64(na.Services))
+	if err != nil {
+		return err
+	}
+
+	err = writeElement(w, uint8(na.NetworkID))
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarBytes(w, pver, na.Addr)
+	if err != nil {
+		return err
+	}
+
+	// Sigh.  Brocoin protocol mixes little and big endian.
+	return binary.Write(w, bigEndian, na.Port)
+}
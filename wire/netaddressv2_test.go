@@ -0,0 +1,119 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestNetAddressV2 tests the NetAddressV2 API.
+func TestNetAddressV2(t *testing.T) {
+	tests := []struct {
+		name      string
+		ip        net.IP
+		netID     NetworkID
+		addr      []byte
+		canLegacy bool
+	}{{
+		name:      "ipv4",
+		ip:        net.ParseIP("127.0.0.1"),
+		netID:     NetIPv4,
+		addr:      []byte{127, 0, 0, 1},
+		canLegacy: true,
+	}, {
+		name:      "ipv6",
+		ip:        net.ParseIP("2001:470::1"),
+		netID:     NetIPv6,
+		addr:      net.ParseIP("2001:470::1"),
+		canLegacy: true,
+	}, {
+		name:  "torv3",
+		netID: NetTorV3,
+		addr:  bytes.Repeat([]byte{0x01}, 32),
+	}, {
+		name:  "i2p",
+		netID: NetI2P,
+		addr:  bytes.Repeat([]byte{0x02}, 32),
+	}, {
+		name:  "cjdns",
+		ip:    net.ParseIP("fc00::1"),
+		netID: NetCJDNS,
+		addr:  net.ParseIP("fc00::1"),
+	}}
+
+	for _, test := range tests {
+		na := NewNetAddressV2(test.netID, test.addr, 8688, SFNodeNetwork)
+		if !na.HasService(SFNodeNetwork) {
+			t.Errorf("%s: SFNodeNetwork service not set", test.name)
+		}
+		na.AddService(SFNodeWitness)
+		if na.Services != SFNodeNetwork|SFNodeWitness {
+			t.Errorf("%s: AddService: wrong services - got %v, "+
+				"want %v", test.name, na.Services,
+				SFNodeNetwork|SFNodeWitness)
+		}
+
+		if !na.IP().Equal(test.ip) {
+			t.Errorf("%s: IP: got %v, want %v", test.name, na.IP(),
+				test.ip)
+		}
+
+		legacy, ok := na.ToLegacy()
+		if ok != test.canLegacy {
+			t.Errorf("%s: ToLegacy: got %v, want %v", test.name, ok,
+				test.canLegacy)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if !legacy.IP.Equal(test.ip) || legacy.Port != na.Port ||
+			legacy.Services != na.Services {
+
+			t.Errorf("%s: ToLegacy: unexpected address %v", test.name,
+				legacy)
+		}
+
+		// Converting the legacy address back must result in the same
+		// network and address.
+		legacy.Timestamp = time.Unix(0x495fab29, 0)
+		nav2 := NetAddressV2FromLegacy(legacy)
+		if nav2.NetworkID != test.netID ||
+			!bytes.Equal(nav2.Addr, test.addr) ||
+			!nav2.Timestamp.Equal(legacy.Timestamp) {
+
+			t.Errorf("%s: NetAddressV2FromLegacy: got %v/%x, want "+
+				"%v/%x", test.name, nav2.NetworkID, nav2.Addr,
+				test.netID, test.addr)
+		}
+	}
+}
+
+// TestNetworkIDStringer tests the stringized output for network IDs.
+func TestNetworkIDStringer(t *testing.T) {
+	tests := []struct {
+		in   NetworkID
+		want string
+	}{
+		{NetIPv4, "IPv4"},
+		{NetIPv6, "IPv6"},
+		{NetTorV2, "TorV2"},
+		{NetTorV3, "TorV3"},
+		{NetI2P, "I2P"},
+		{NetCJDNS, "CJDNS"},
+		{0xff, "Unknown NetworkID (255)"},
+	}
+
+	for i, test := range tests {
+		result := test.in.String()
+		if result != test.want {
+			t.Errorf("String #%d\n got: %s want: %s", i, result,
+				test.want)
+		}
+	}
+}
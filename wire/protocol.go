@@ -13,7 +13,7 @@ import (
 // XXX pedro: we will probably need to bump this.
 const (
 	// ProtocolVersion is the latest protocol version this package supports.
-	ProtocolVersion uint32 = 70013
+	ProtocolVersion uint32 = 70016
 
 	// MultipleAddressVersion is the protocol version which added multiple
 	// addresses per message (pver >= MultipleAddressVersion).
@@ -51,6 +51,17 @@ const (
 	// FeeFilterVersion is the protocol version which added a new
 	// feefilter message.
 	FeeFilterVersion uint32 = 70013
+
+	// ShortIDsBlocksVersion is the protocol version which added the
+	// sendcmpct, cmpctblock, getblocktxn and blocktxn messages used for
+	// compact block relay as defined by BIP0152.
+	ShortIDsBlocksVersion uint32 = 70014
+
+	// AddrV2Version is the protocol version which added the sendaddrv2 and
+	// addrv2 messages defined by BIP0155.  Although BIP0155 defines them
+	// for all protocol versions, sendaddrv2 is only sent to peers with
+	// this version or later.
+	AddrV2Version uint32 = 70016
 )
 
 // ServiceFlag identifies services supported by a brocoin peer.
@@ -86,6 +97,14 @@ const (
 	// SFNode2X is a flag used to indicate a peer is running the Segwit2X
 	// software.
 	SFNode2X
+
+	// SFNodeNetworkLimited is a flag used to indicate a peer only serves
+	// the most recent 288 blocks of the chain (BIP0159).
+	SFNodeNetworkLimited ServiceFlag = 1 << 10
+
+	// SFNodeP2PV2 is a flag used to indicate a peer supports the encrypted
+	// v2 transport protocol (BIP0324).
+	SFNodeP2PV2 ServiceFlag = 1 << 11
 )
 
 // Map of service flags back to their constant names for pretty printing.
@@ -98,6 +117,9 @@ var sfStrings = map[ServiceFlag]string{
 	SFNodeBit5:    "SFNodeBit5",
 	SFNodeCF:      "SFNodeCF",
 	SFNode2X:      "SFNode2X",
+
+	SFNodeNetworkLimited: "SFNodeNetworkLimited",
+	SFNodeP2PV2:          "SFNodeP2PV2",
 }
 
 // orderedSFStrings is an ordered list of service flags from highest to
@@ -111,6 +133,8 @@ var orderedSFStrings = []ServiceFlag{
 	SFNodeBit5,
 	SFNodeCF,
 	SFNode2X,
+	SFNodeNetworkLimited,
+	SFNodeP2PV2,
 }
 
 // String returns the ServiceFlag in human-readable form.
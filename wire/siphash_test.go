@@ -0,0 +1,45 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"testing"
+)
+
+// TestSipHash24 ensures the SipHash-2-4 implementation produces the reference
+// test vectors for inputs of various lengths.
+func TestSipHash24(t *testing.T) {
+	const k0, k1 = 0x0706050403020100, 0x0f0e0d0c0b0a0908
+
+	// seq returns a slice with the bytes 0x00 through n-1.
+	seq := func(n int) []byte {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = byte(i)
+		}
+		return b
+	}
+
+	tests := []struct {
+		in   []byte
+		want uint64
+	}{
+		{seq(0), 0x726fdb47dd0e0e31},
+		{seq(1), 0x74f839c593dc67fd},
+		{seq(8), 0x93f5f5799a932462},
+		{seq(15), 0xa129ca6149be45e5},
+		{seq(16), 0x3f2acc7f57c29bdb},
+		{seq(32), 0x7127512f72f27cce},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		got := sipHash24(k0, k1, test.in)
+		if got != test.want {
+			t.Errorf("sipHash24 #%d (len %d): got %#x, want %#x", i,
+				len(test.in), got, test.want)
+		}
+	}
+}
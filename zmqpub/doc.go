@@ -0,0 +1,41 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+/*
+Package zmqpub implements a ZeroMQ compatible publisher of block and
+transaction notifications.
+
+Overview
+
+The publisher speaks the ZeroMQ Message Transport Protocol (ZMTP) 3.0 natively,
+so any ZeroMQ SUB or XSUB socket can connect to it and subscribe to the topics
+it publishes without brond depending on the ZeroMQ library.  Only the NULL
+security mechanism is supported, so endpoints should not be exposed to
+untrusted networks.
+
+Every notification is sent as a message of three frames: the topic, the body
+and the sequence number of the message within its topic as a 4-byte little
+endian integer.  The topics and their bodies are:
+
+	hashblock  the hash of a block connected to the main chain
+	hashtx     the hash of a transaction accepted into the memory pool or
+	           included in a connected block
+	rawblock   the serialized block connected to the main chain
+	rawtx      the serialized transaction accepted into the memory pool or
+	           included in a connected block
+	sequence   the hash of a block or transaction followed by a label: C for
+	           a connected block, D for a disconnected block, A for a
+	           transaction added to the memory pool and R for a transaction
+	           removed from it for any reason other than inclusion in a block.
+	           A and R are followed by the sequence number of the memory pool
+	           event as an 8-byte little endian integer
+
+Hashes are sent in the byte order they are displayed in, which is the reverse
+of the order they are serialized in.
+
+Subscribers which do not keep up with the notifications have new messages
+dropped once the number of messages queued for them reaches the high water
+mark.  The sequence numbers allow them to detect the gaps.
+*/
+package zmqpub
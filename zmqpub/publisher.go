@@ -0,0 +1,502 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package zmqpub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/brsuite/brond/chaincfg/chainhash"
+	"github.com/brsuite/bronutil"
+)
+
+// These constants define the topics which can be published.
+const (
+	TopicHashBlock = "hashblock"
+	TopicHashTx    = "hashtx"
+	TopicRawBlock  = "rawblock"
+	TopicRawTx     = "rawtx"
+	TopicSequence  = "sequence"
+)
+
+// These constants define the labels of the messages of the sequence topic.
+const (
+	labelBlockConnected    = 'C'
+	labelBlockDisconnected = 'D'
+	labelTxAdded           = 'A'
+	labelTxRemoved         = 'R'
+)
+
+const (
+	// DefaultHighWaterMark is the default maximum number of messages
+	// queued for a subscriber before new messages for it are dropped.
+	DefaultHighWaterMark = 1000
+
+	// handshakeTimeout is the time a subscriber has to complete the
+	// handshake after connecting.
+	handshakeTimeout = 10 * time.Second
+)
+
+// socketTypePub is the socket type of the publisher.  Only SUB and XSUB
+// sockets can connect to it.
+const socketTypePub = "PUB"
+
+// Config is the configuration of a Publisher.
+type Config struct {
+	// Endpoints maps each topic to publish to the address of the endpoint
+	// it is published on, in the form tcp://host:port.  Several topics
+	// may be published on the same endpoint.
+	Endpoints map[string]string
+
+	// HighWaterMark is the maximum number of messages queued for each
+	// subscriber before new messages for it are dropped.  It defaults to
+	// DefaultHighWaterMark.
+	HighWaterMark int
+}
+
+// subscriber is a connected SUB or XSUB socket.
+type subscriber struct {
+	conn net.Conn
+
+	// sendQueue holds the encoded messages which are waiting to be sent.
+	sendQueue chan []byte
+
+	// subscriptions holds the topic prefixes the subscriber is subscribed
+	// to along with how many times it subscribed to each.
+	mtx           sync.Mutex
+	subscriptions map[string]int
+
+	quit chan struct{}
+}
+
+// subscribe adds a subscription to the topics starting with the passed prefix.
+func (s *subscriber) subscribe(prefix string) {
+	s.mtx.Lock()
+	s.subscriptions[prefix]++
+	s.mtx.Unlock()
+}
+
+// unsubscribe removes a subscription previously added with subscribe.
+func (s *subscriber) unsubscribe(prefix string) {
+	s.mtx.Lock()
+	if s.subscriptions[prefix] > 1 {
+		s.subscriptions[prefix]--
+	} else {
+		delete(s.subscriptions, prefix)
+	}
+	s.mtx.Unlock()
+}
+
+// subscribed returns whether the subscriber is subscribed to the passed topic.
+func (s *subscriber) subscribed(topic string) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for prefix := range s.subscriptions {
+		if strings.HasPrefix(topic, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// endpoint is an address topics are published on.
+type endpoint struct {
+	listener net.Listener
+
+	mtx         sync.Mutex
+	subscribers map[*subscriber]struct{}
+}
+
+// Publisher publishes block and transaction notifications to ZeroMQ
+// subscribers.  See the package documentation for the topics and the format of
+// their messages.
+type Publisher struct {
+	started  int32
+	shutdown int32
+
+	highWaterMark int
+	endpoints     map[string]*endpoint
+	topics        map[string]*endpoint
+
+	// mtx protects the sequence numbers and ensures the messages of a
+	// notification are published together.
+	mtx        sync.Mutex
+	topicSeqs  map[string]uint32
+	mempoolSeq uint64
+
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+// New returns a new publisher which listens on the configured endpoints.
+func New(cfg *Config) (*Publisher, error) {
+	p := &Publisher{
+		highWaterMark: cfg.HighWaterMark,
+		endpoints:     make(map[string]*endpoint),
+		topics:        make(map[string]*endpoint),
+		topicSeqs:     make(map[string]uint32),
+		quit:          make(chan struct{}),
+	}
+	if p.highWaterMark <= 0 {
+		p.highWaterMark = DefaultHighWaterMark
+	}
+
+	for topic, addr := range cfg.Endpoints {
+		switch topic {
+		case TopicHashBlock, TopicHashTx, TopicRawBlock, TopicRawTx,
+			TopicSequence:
+		default:
+			p.closeListeners()
+			return nil, fmt.Errorf("unknown topic %q", topic)
+		}
+
+		ep, ok := p.endpoints[addr]
+		if !ok {
+			hostPort := strings.TrimPrefix(addr, "tcp://")
+			if hostPort == addr {
+				p.closeListeners()
+				return nil, fmt.Errorf("unsupported endpoint %q -- "+
+					"only tcp:// endpoints are supported", addr)
+			}
+			listener, err := net.Listen("tcp", hostPort)
+			if err != nil {
+				p.closeListeners()
+				return nil, err
+			}
+			ep = &endpoint{
+				listener:    listener,
+				subscribers: make(map[*subscriber]struct{}),
+			}
+			p.endpoints[addr] = ep
+		}
+		p.topics[topic] = ep
+	}
+
+	return p, nil
+}
+
+// closeListeners closes the listeners of all endpoints.
+func (p *Publisher) closeListeners() {
+	for _, ep := range p.endpoints {
+		ep.listener.Close()
+	}
+}
+
+// Addrs returns the addresses the publisher is listening on for each topic.
+func (p *Publisher) Addrs() map[string]net.Addr {
+	addrs := make(map[string]net.Addr, len(p.topics))
+	for topic, ep := range p.topics {
+		addrs[topic] = ep.listener.Addr()
+	}
+	return addrs
+}
+
+// Start begins accepting subscribers.
+func (p *Publisher) Start() {
+	// Already started?
+	if atomic.AddInt32(&p.started, 1) != 1 {
+		return
+	}
+
+	for addr, ep := range p.endpoints {
+		log.Infof("Publishing ZMQ notifications on %s", addr)
+		p.wg.Add(1)
+		go p.acceptHandler(ep)
+	}
+}
+
+// Stop disconnects all subscribers and stops listening on the endpoints.
+func (p *Publisher) Stop() {
+	if atomic.AddInt32(&p.shutdown, 1) != 1 {
+		log.Warnf("Publisher is already in the process of shutting down")
+		return
+	}
+
+	close(p.quit)
+	p.closeListeners()
+	for _, ep := range p.endpoints {
+		ep.mtx.Lock()
+		for s := range ep.subscribers {
+			s.conn.Close()
+		}
+		ep.mtx.Unlock()
+	}
+	p.wg.Wait()
+}
+
+// acceptHandler accepts the subscribers connecting to the passed endpoint.  It
+// must be run as a goroutine.
+func (p *Publisher) acceptHandler(ep *endpoint) {
+	defer p.wg.Done()
+
+	for atomic.LoadInt32(&p.shutdown) == 0 {
+		conn, err := ep.listener.Accept()
+		if err != nil {
+			// Only log the error if not forcibly shutting down.
+			if atomic.LoadInt32(&p.shutdown) == 0 {
+				log.Errorf("Can't accept ZMQ subscriber: %v", err)
+			}
+			continue
+		}
+
+		s := &subscriber{
+			conn:          conn,
+			sendQueue:     make(chan []byte, p.highWaterMark),
+			subscriptions: make(map[string]int),
+			quit:          make(chan struct{}),
+		}
+
+		// Subscribers are registered right away so Stop disconnects
+		// them even while the handshake is in progress.
+		ep.mtx.Lock()
+		select {
+		case <-p.quit:
+			ep.mtx.Unlock()
+			conn.Close()
+			return
+		default:
+		}
+		ep.subscribers[s] = struct{}{}
+		ep.mtx.Unlock()
+
+		p.wg.Add(1)
+		go p.subscriberHandler(ep, s)
+	}
+}
+
+// subscriberHandler performs the handshake with a subscriber and then reads its
+// subscriptions until it disconnects.  It must be run as a goroutine.
+func (p *Publisher) subscriberHandler(ep *endpoint, s *subscriber) {
+	defer p.wg.Done()
+	defer func() {
+		ep.mtx.Lock()
+		delete(ep.subscribers, s)
+		ep.mtx.Unlock()
+		s.conn.Close()
+	}()
+
+	addr := s.conn.RemoteAddr()
+	s.conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	socketType, err := handshake(s.conn, socketTypePub)
+	if err != nil {
+		log.Debugf("ZMQ handshake with %v failed: %v", addr, err)
+		return
+	}
+	if socketType != "SUB" && socketType != "XSUB" {
+		log.Debugf("Rejecting ZMQ %s socket %v", socketType, addr)
+		return
+	}
+	s.conn.SetDeadline(time.Time{})
+	log.Debugf("New ZMQ subscriber %v", addr)
+
+	p.wg.Add(1)
+	go p.sendHandler(s)
+	defer close(s.quit)
+
+	// Subscriptions are sent as messages starting with 1 to subscribe or 0
+	// to unsubscribe followed by the topic prefix.  Later versions of the
+	// protocol send commands instead, which are accepted as well.
+	var more bool
+	for {
+		flags, body, err := readFrame(s.conn)
+		if err != nil {
+			if err != io.EOF {
+				log.Debugf("Disconnecting ZMQ subscriber %v: %v",
+					addr, err)
+			}
+			return
+		}
+
+		if flags&flagCommand != 0 {
+			name, data, err := parseCommand(body)
+			if err != nil {
+				log.Debugf("Disconnecting ZMQ subscriber %v: %v",
+					addr, err)
+				return
+			}
+			switch name {
+			case cmdSubscribe:
+				s.subscribe(string(data))
+			case cmdCancel:
+				s.unsubscribe(string(data))
+			}
+			continue
+		}
+
+		// Only the first frame of a message can be a subscription.
+		first := !more
+		more = flags&flagMore != 0
+		if !first || len(body) == 0 {
+			continue
+		}
+		switch body[0] {
+		case 1:
+			s.subscribe(string(body[1:]))
+		case 0:
+			s.unsubscribe(string(body[1:]))
+		}
+	}
+}
+
+// sendHandler sends the queued messages to a subscriber.  It must be run as a
+// goroutine.
+func (p *Publisher) sendHandler(s *subscriber) {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case msg := <-s.sendQueue:
+			if _, err := s.conn.Write(msg); err != nil {
+				// Closing the connection stops the subscriber
+				// handler as well.
+				s.conn.Close()
+				return
+			}
+
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// publishes returns whether the passed topic is published.
+func (p *Publisher) publishes(topic string) bool {
+	_, ok := p.topics[topic]
+	return ok
+}
+
+// publish sends a message with the passed body to the subscribers of the topic.
+// Subscribers whose queue is full miss the message.
+//
+// This function MUST be called with the publisher lock held.
+func (p *Publisher) publish(topic string, body []byte) {
+	ep, ok := p.topics[topic]
+	if !ok {
+		return
+	}
+
+	var seq [4]byte
+	binary.LittleEndian.PutUint32(seq[:], p.topicSeqs[topic])
+	p.topicSeqs[topic]++
+	msg := encodeMessage([]byte(topic), body, seq[:])
+
+	ep.mtx.Lock()
+	for s := range ep.subscribers {
+		if !s.subscribed(topic) {
+			continue
+		}
+		select {
+		case s.sendQueue <- msg:
+		default:
+			log.Tracef("Dropping %s message for ZMQ subscriber %v",
+				topic, s.conn.RemoteAddr())
+		}
+	}
+	ep.mtx.Unlock()
+}
+
+// reversedHash returns the passed hash in the byte order it is displayed in.
+func reversedHash(hash *chainhash.Hash) []byte {
+	reversed := make([]byte, chainhash.HashSize)
+	for i, b := range hash {
+		reversed[chainhash.HashSize-1-i] = b
+	}
+	return reversed
+}
+
+// sequenceBody returns the body of a message of the sequence topic.  The
+// memory pool sequence number is only included when it is not nil.
+func sequenceBody(hash *chainhash.Hash, label byte, mempoolSeq *uint64) []byte {
+	body := append(reversedHash(hash), label)
+	if mempoolSeq != nil {
+		var seq [8]byte
+		binary.LittleEndian.PutUint64(seq[:], *mempoolSeq)
+		body = append(body, seq[:]...)
+	}
+	return body
+}
+
+// publishTx publishes the hash and the serialized passed transaction.
+//
+// This function MUST be called with the publisher lock held.
+func (p *Publisher) publishTx(tx *bronutil.Tx) {
+	p.publish(TopicHashTx, reversedHash(tx.Hash()))
+	if p.publishes(TopicRawTx) {
+		var buf bytes.Buffer
+		if err := tx.MsgTx().Serialize(&buf); err != nil {
+			log.Errorf("Unable to serialize transaction %v: %v",
+				tx.Hash(), err)
+			return
+		}
+		p.publish(TopicRawTx, buf.Bytes())
+	}
+}
+
+// BlockConnected publishes a block which has been connected to the main chain
+// along with its transactions.
+func (p *Publisher) BlockConnected(block *bronutil.Block) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.publish(TopicHashBlock, reversedHash(block.Hash()))
+	if p.publishes(TopicRawBlock) {
+		blockBytes, err := block.Bytes()
+		if err != nil {
+			log.Errorf("Unable to serialize block %v: %v",
+				block.Hash(), err)
+		} else {
+			p.publish(TopicRawBlock, blockBytes)
+		}
+	}
+	if p.publishes(TopicHashTx) || p.publishes(TopicRawTx) {
+		for _, tx := range block.Transactions() {
+			p.publishTx(tx)
+		}
+	}
+	p.publish(TopicSequence, sequenceBody(block.Hash(),
+		labelBlockConnected, nil))
+}
+
+// BlockDisconnected publishes a block which has been disconnected from the main
+// chain.
+func (p *Publisher) BlockDisconnected(block *bronutil.Block) {
+	p.mtx.Lock()
+	p.publish(TopicSequence, sequenceBody(block.Hash(),
+		labelBlockDisconnected, nil))
+	p.mtx.Unlock()
+}
+
+// TransactionAccepted publishes a transaction which has been accepted into the
+// memory pool.
+func (p *Publisher) TransactionAccepted(tx *bronutil.Tx) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.publishTx(tx)
+	p.mempoolSeq++
+	p.publish(TopicSequence, sequenceBody(tx.Hash(), labelTxAdded,
+		&p.mempoolSeq))
+}
+
+// TransactionRemoved publishes a transaction which has been removed from the
+// memory pool.  It should not be called for transactions which were removed
+// because they were included in a block, since the block notifications cover
+// those.
+func (p *Publisher) TransactionRemoved(tx *bronutil.Tx) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.mempoolSeq++
+	p.publish(TopicSequence, sequenceBody(tx.Hash(), labelTxRemoved,
+		&p.mempoolSeq))
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package zmqpub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/brsuite/brond/chaincfg/chainhash"
+	"github.com/brsuite/brond/wire"
+	"github.com/brsuite/bronutil"
+)
+
+// testSubscriber is an in-process SUB socket connected to a publisher.
+type testSubscriber struct {
+	t    *testing.T
+	conn net.Conn
+}
+
+// newTestSubscriber connects a SUB socket to the passed address and performs
+// the handshake.
+func newTestSubscriber(t *testing.T, addr net.Addr) *testSubscriber {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("Dial: unexpected error %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	socketType, err := handshake(conn, "SUB")
+	if err != nil {
+		t.Fatalf("handshake: unexpected error %v", err)
+	}
+	if socketType != socketTypePub {
+		t.Fatalf("handshake: got socket type %q, want %q", socketType,
+			socketTypePub)
+	}
+	return &testSubscriber{t: t, conn: conn}
+}
+
+// subscribe sends a subscription message for the passed topic prefix.
+func (s *testSubscriber) subscribe(prefix string, subscribe bool) {
+	s.t.Helper()
+
+	body := append([]byte{0}, prefix...)
+	if subscribe {
+		body[0] = 1
+	}
+	if err := writeFrame(s.conn, 0, body); err != nil {
+		s.t.Fatalf("writeFrame: unexpected error %v", err)
+	}
+}
+
+// readMessage reads a message and ensures it has the expected topic, body and
+// sequence number.
+func (s *testSubscriber) readMessage(topic string, body []byte, seq uint32) {
+	s.t.Helper()
+
+	var parts [][]byte
+	for {
+		flags, part, err := readFrame(s.conn)
+		if err != nil {
+			s.t.Fatalf("readFrame: unexpected error %v", err)
+		}
+		parts = append(parts, part)
+		if flags&flagMore == 0 {
+			break
+		}
+	}
+	if len(parts) != 3 {
+		s.t.Fatalf("got message with %d parts, want 3", len(parts))
+	}
+
+	var wantSeq [4]byte
+	binary.LittleEndian.PutUint32(wantSeq[:], seq)
+	if string(parts[0]) != topic || !bytes.Equal(parts[1], body) ||
+		!bytes.Equal(parts[2], wantSeq[:]) {
+
+		s.t.Fatalf("got message %s %x %x, want %s %x %x", parts[0],
+			parts[1], parts[2], topic, body, wantSeq)
+	}
+}
+
+// waitForSubscriptions waits until the publisher has registered the passed
+// number of subscriptions for the endpoint of the topic.
+func waitForSubscriptions(t *testing.T, p *Publisher, topic string, want int) {
+	t.Helper()
+
+	ep := p.topics[topic]
+	for i := 0; i < 500; i++ {
+		var n int
+		ep.mtx.Lock()
+		for s := range ep.subscribers {
+			s.mtx.Lock()
+			n += len(s.subscriptions)
+			s.mtx.Unlock()
+		}
+		ep.mtx.Unlock()
+		if n == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("subscriptions were not registered")
+}
+
+// TestPublisher ensures subscribers receive the messages for the topics they
+// are subscribed to in the expected format and order.
+func TestPublisher(t *testing.T) {
+	t.Parallel()
+
+	const addr = "tcp://127.0.0.1:0"
+	p, err := New(&Config{
+		Endpoints: map[string]string{
+			TopicHashBlock: addr,
+			TopicHashTx:    addr,
+			TopicRawBlock:  addr,
+			TopicSequence:  addr,
+		},
+	})
+	if err != nil {
+		t.Fatalf("New: unexpected error %v", err)
+	}
+	p.Start()
+	defer p.Stop()
+
+	sub := newTestSubscriber(t, p.Addrs()[TopicHashBlock])
+	sub.subscribe("hash", true)
+	sub.subscribe("sequence", true)
+	waitForSubscriptions(t, p, TopicHashBlock, 2)
+
+	tx := wire.NewMsgTx(1)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 0xffffffff},
+		[]byte{0x51}, nil))
+	tx.AddTxOut(wire.NewTxOut(5000000000, []byte{0x51}))
+	block := bronutil.NewBlock(&wire.MsgBlock{
+		Header:       wire.BlockHeader{Version: 1},
+		Transactions: []*wire.MsgTx{tx},
+	})
+	blockHash := reversedHash(block.Hash())
+	txHash := tx.TxHash()
+	txHashRev := reversedHash(&txHash)
+
+	// The raw block is not subscribed to, so only the hashes and the
+	// sequence message are received.
+	p.BlockConnected(block)
+	sub.readMessage(TopicHashBlock, blockHash, 0)
+	sub.readMessage(TopicHashTx, txHashRev, 0)
+	sub.readMessage(TopicSequence, append(blockHash, 'C'), 0)
+
+	// Transactions added to and removed from the mempool carry the
+	// mempool sequence number.
+	p.TransactionAccepted(bronutil.NewTx(tx))
+	sub.readMessage(TopicHashTx, txHashRev, 1)
+	sub.readMessage(TopicSequence, append(txHashRev, 'A', 1, 0, 0, 0, 0,
+		0, 0, 0), 1)
+	p.TransactionRemoved(bronutil.NewTx(tx))
+	sub.readMessage(TopicSequence, append(txHashRev, 'R', 2, 0, 0, 0, 0,
+		0, 0, 0), 2)
+
+	// Messages for topics which are no longer subscribed to are not
+	// received.
+	sub.subscribe("hash", false)
+	waitForSubscriptions(t, p, TopicHashBlock, 1)
+	p.BlockDisconnected(block)
+	p.BlockConnected(block)
+	sub.readMessage(TopicSequence, append(blockHash, 'D'), 3)
+	sub.readMessage(TopicSequence, append(blockHash, 'C'), 4)
+
+	// Stopping the publisher disconnects the subscriber.
+	p.Stop()
+	if _, _, err := readFrame(sub.conn); err == nil {
+		t.Fatal("readFrame: subscriber still connected after Stop")
+	}
+}
+
+// TestPublisherRejects ensures invalid configurations and sockets other than
+// subscribers are rejected.
+func TestPublisherRejects(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(&Config{
+		Endpoints: map[string]string{"rawwallet": "tcp://127.0.0.1:0"},
+	})
+	if err == nil {
+		t.Fatal("New: accepted unknown topic")
+	}
+	_, err = New(&Config{
+		Endpoints: map[string]string{TopicRawTx: "ipc:///tmp/brond"},
+	})
+	if err == nil {
+		t.Fatal("New: accepted ipc endpoint")
+	}
+
+	p, err := New(&Config{
+		Endpoints: map[string]string{TopicRawTx: "tcp://127.0.0.1:0"},
+	})
+	if err != nil {
+		t.Fatalf("New: unexpected error %v", err)
+	}
+	p.Start()
+	defer p.Stop()
+
+	conn, err := net.Dial("tcp", p.Addrs()[TopicRawTx].String())
+	if err != nil {
+		t.Fatalf("Dial: unexpected error %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if _, err := handshake(conn, "PUB"); err != nil {
+		t.Fatalf("handshake: unexpected error %v", err)
+	}
+	if _, _, err := readFrame(conn); err == nil {
+		t.Fatal("readFrame: PUB socket was not disconnected")
+	}
+}
+
+// TestReversedHash ensures hashes are published in the byte order they are
+// displayed in.
+func TestReversedHash(t *testing.T) {
+	hash, err := chainhash.NewHashFromStr("0102")
+	if err != nil {
+		t.Fatalf("NewHashFromStr: unexpected error %v", err)
+	}
+	got := reversedHash(hash)
+	if got[30] != 0x01 || got[31] != 0x02 {
+		t.Fatalf("reversedHash: got %x", got)
+	}
+}
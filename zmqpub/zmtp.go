@@ -0,0 +1,260 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package zmqpub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// greetingSize is the size of the greeting each side of a connection
+	// sends before the handshake.
+	greetingSize = 64
+
+	// zmtpMajorVersion and zmtpMinorVersion are the version of the
+	// protocol announced in the greeting.
+	zmtpMajorVersion = 3
+	zmtpMinorVersion = 0
+
+	// mechanismNull is the name of the NULL security mechanism, which is
+	// the only one supported.
+	mechanismNull = "NULL"
+
+	// maxFrameSize is the maximum size of a frame read from a peer.
+	// Subscribers only send subscriptions and commands, which are far
+	// smaller.
+	maxFrameSize = 1 << 16
+)
+
+// These constants define the flags of a frame.
+const (
+	// flagMore indicates more frames of the same message follow.
+	flagMore = 0x01
+
+	// flagLong indicates the size of the frame is encoded in 8 bytes
+	// instead of 1.
+	flagLong = 0x02
+
+	// flagCommand indicates the frame is a command rather than a message
+	// frame.
+	flagCommand = 0x04
+)
+
+// These constants define the names of the commands which are used.
+const (
+	cmdReady     = "READY"
+	cmdError     = "ERROR"
+	cmdSubscribe = "SUBSCRIBE"
+	cmdCancel    = "CANCEL"
+)
+
+// writeGreeting writes the greeting which starts a connection.  It announces
+// the NULL security mechanism, for which the as-server field is ignored.
+func writeGreeting(w io.Writer) error {
+	var greeting [greetingSize]byte
+	greeting[0] = 0xff
+	greeting[9] = 0x7f
+	greeting[10] = zmtpMajorVersion
+	greeting[11] = zmtpMinorVersion
+	copy(greeting[12:32], mechanismNull)
+	_, err := w.Write(greeting[:])
+	return err
+}
+
+// readGreeting reads the greeting of the peer and ensures it is compatible.
+func readGreeting(r io.Reader) error {
+	var greeting [greetingSize]byte
+	if _, err := io.ReadFull(r, greeting[:]); err != nil {
+		return err
+	}
+	if greeting[0] != 0xff || greeting[9] != 0x7f {
+		return fmt.Errorf("invalid greeting signature")
+	}
+	if greeting[10] < zmtpMajorVersion {
+		return fmt.Errorf("unsupported protocol version %d.%d",
+			greeting[10], greeting[11])
+	}
+	mechanism := string(bytes.TrimRight(greeting[12:32], "\x00"))
+	if mechanism != mechanismNull {
+		return fmt.Errorf("unsupported security mechanism %q", mechanism)
+	}
+	return nil
+}
+
+// writeFrame writes a frame with the passed flags and body.  The long flag is
+// set as needed.
+func writeFrame(w io.Writer, flags byte, body []byte) error {
+	var header [9]byte
+	headerLen := 2
+	if len(body) > 255 {
+		flags |= flagLong
+		binary.BigEndian.PutUint64(header[1:], uint64(len(body)))
+		headerLen = 9
+	} else {
+		header[1] = byte(len(body))
+	}
+	header[0] = flags
+	if _, err := w.Write(header[:headerLen]); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// readFrame reads a frame and returns its flags and body.
+func readFrame(r io.Reader) (byte, []byte, error) {
+	var header [9]byte
+	if _, err := io.ReadFull(r, header[:1]); err != nil {
+		return 0, nil, err
+	}
+	flags := header[0]
+
+	var size uint64
+	if flags&flagLong != 0 {
+		if _, err := io.ReadFull(r, header[1:9]); err != nil {
+			return 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(header[1:9])
+	} else {
+		if _, err := io.ReadFull(r, header[1:2]); err != nil {
+			return 0, nil, err
+		}
+		size = uint64(header[1])
+	}
+	if size > maxFrameSize {
+		return 0, nil, fmt.Errorf("frame size %d exceeds the maximum "+
+			"of %d", size, maxFrameSize)
+	}
+
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return flags, body, nil
+}
+
+// encodeMessage returns the frames of a message consisting of the passed parts
+// encoded for sending to a peer.
+func encodeMessage(parts ...[]byte) []byte {
+	var buf bytes.Buffer
+	for i, part := range parts {
+		var flags byte
+		if i < len(parts)-1 {
+			flags = flagMore
+		}
+
+		// Writing to a bytes.Buffer can't fail.
+		_ = writeFrame(&buf, flags, part)
+	}
+	return buf.Bytes()
+}
+
+// writeCommand writes a command with the passed name and data.
+func writeCommand(w io.Writer, name string, data []byte) error {
+	body := make([]byte, 0, 1+len(name)+len(data))
+	body = append(body, byte(len(name)))
+	body = append(body, name...)
+	body = append(body, data...)
+	return writeFrame(w, flagCommand, body)
+}
+
+// parseCommand returns the name and data of the command in the passed frame
+// body.
+func parseCommand(body []byte) (string, []byte, error) {
+	if len(body) == 0 || len(body) < 1+int(body[0]) {
+		return "", nil, fmt.Errorf("malformed command")
+	}
+	nameLen := int(body[0])
+	return string(body[1 : 1+nameLen]), body[1+nameLen:], nil
+}
+
+// encodeMetadata encodes the passed property name and value pairs as the
+// metadata of a READY command.
+func encodeMetadata(props ...string) []byte {
+	var buf []byte
+	for i := 0; i+1 < len(props); i += 2 {
+		name, value := props[i], props[i+1]
+		buf = append(buf, byte(len(name)))
+		buf = append(buf, name...)
+		var valueLen [4]byte
+		binary.BigEndian.PutUint32(valueLen[:], uint32(len(value)))
+		buf = append(buf, valueLen[:]...)
+		buf = append(buf, value...)
+	}
+	return buf
+}
+
+// parseMetadata returns the properties of the metadata of a READY command.
+// Property names are case-insensitive, so they are returned in lower case.
+func parseMetadata(data []byte) (map[string]string, error) {
+	props := make(map[string]string)
+	for len(data) > 0 {
+		nameLen := int(data[0])
+		if len(data) < 1+nameLen+4 {
+			return nil, fmt.Errorf("malformed metadata")
+		}
+		name := strings.ToLower(string(data[1 : 1+nameLen]))
+		data = data[1+nameLen:]
+
+		valueLen := binary.BigEndian.Uint32(data)
+		data = data[4:]
+		if uint64(len(data)) < uint64(valueLen) {
+			return nil, fmt.Errorf("malformed metadata")
+		}
+		props[name] = string(data[:valueLen])
+		data = data[valueLen:]
+	}
+	return props, nil
+}
+
+// handshake exchanges the greetings and READY commands with the peer on the
+// other end of rw using the NULL security mechanism, announcing the passed
+// socket type.  It returns the socket type announced by the peer.
+func handshake(rw io.ReadWriter, socketType string) (string, error) {
+	if err := writeGreeting(rw); err != nil {
+		return "", err
+	}
+	if err := readGreeting(rw); err != nil {
+		return "", err
+	}
+	err := writeCommand(rw, cmdReady, encodeMetadata("Socket-Type",
+		socketType))
+	if err != nil {
+		return "", err
+	}
+
+	flags, body, err := readFrame(rw)
+	if err != nil {
+		return "", err
+	}
+	if flags&flagCommand == 0 {
+		return "", fmt.Errorf("expected %s command", cmdReady)
+	}
+	name, data, err := parseCommand(body)
+	if err != nil {
+		return "", err
+	}
+	switch name {
+	case cmdReady:
+	case cmdError:
+		if len(data) > 0 && len(data) >= 1+int(data[0]) {
+			data = data[1 : 1+int(data[0])]
+		}
+		return "", fmt.Errorf("peer rejected the handshake: %s", data)
+	default:
+		return "", fmt.Errorf("expected %s command, got %s", cmdReady,
+			name)
+	}
+
+	props, err := parseMetadata(data)
+	if err != nil {
+		return "", err
+	}
+	return props["socket-type"], nil
+}
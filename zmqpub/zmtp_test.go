@@ -0,0 +1,128 @@
+// Copyright (c) 2022 The brsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package zmqpub
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestFrames ensures frames are encoded with the expected header and decode to
+// the same flags and body.
+func TestFrames(t *testing.T) {
+	tests := []struct {
+		name   string
+		flags  byte
+		body   []byte
+		header []byte
+	}{
+		{
+			name:   "empty",
+			flags:  0,
+			body:   nil,
+			header: []byte{0x00, 0x00},
+		},
+		{
+			name:   "short with more",
+			flags:  flagMore,
+			body:   bytes.Repeat([]byte{0xaa}, 255),
+			header: []byte{0x01, 0xff},
+		},
+		{
+			name:   "long",
+			flags:  0,
+			body:   bytes.Repeat([]byte{0xbb}, 256),
+			header: []byte{0x02, 0, 0, 0, 0, 0, 0, 0x01, 0x00},
+		},
+		{
+			name:   "command",
+			flags:  flagCommand,
+			body:   []byte("\x05READY"),
+			header: []byte{0x04, 0x06},
+		},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := writeFrame(&buf, test.flags, test.body); err != nil {
+			t.Fatalf("%s: writeFrame: unexpected error %v", test.name,
+				err)
+		}
+		if !bytes.HasPrefix(buf.Bytes(), test.header) ||
+			buf.Len() != len(test.header)+len(test.body) {
+
+			t.Fatalf("%s: unexpected encoding %x", test.name,
+				buf.Bytes())
+		}
+
+		flags, body, err := readFrame(&buf)
+		if err != nil {
+			t.Fatalf("%s: readFrame: unexpected error %v", test.name,
+				err)
+		}
+		if flags&^flagLong != test.flags || !bytes.Equal(body, test.body) {
+			t.Fatalf("%s: got flags %x and body %x, want %x and %x",
+				test.name, flags, body, test.flags, test.body)
+		}
+	}
+
+	// Frames larger than the maximum must be rejected without reading
+	// their body.
+	header := []byte{flagLong, 0, 0, 0, 0, 0x01, 0, 0, 0}
+	if _, _, err := readFrame(bytes.NewReader(header)); err == nil {
+		t.Fatal("readFrame: accepted oversized frame")
+	}
+}
+
+// TestMetadata ensures the metadata of READY commands round trips and that
+// malformed metadata is rejected.
+func TestMetadata(t *testing.T) {
+	data := encodeMetadata("Socket-Type", "SUB", "Identity", "")
+	props, err := parseMetadata(data)
+	if err != nil {
+		t.Fatalf("parseMetadata: unexpected error %v", err)
+	}
+	if len(props) != 2 || props["socket-type"] != "SUB" ||
+		props["identity"] != "" {
+
+		t.Fatalf("parseMetadata: unexpected properties %v", props)
+	}
+
+	if _, err := parseMetadata(data[:len(data)-1]); err == nil {
+		t.Fatal("parseMetadata: accepted truncated metadata")
+	}
+	if _, _, err := parseCommand([]byte{5, 'R', 'E'}); err == nil {
+		t.Fatal("parseCommand: accepted truncated command")
+	}
+}
+
+// TestGreeting ensures greetings with an unsupported version or security
+// mechanism are rejected.
+func TestGreeting(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeGreeting(&buf); err != nil {
+		t.Fatalf("writeGreeting: unexpected error %v", err)
+	}
+	greeting := buf.Bytes()
+	if err := readGreeting(bytes.NewReader(greeting)); err != nil {
+		t.Fatalf("readGreeting: unexpected error %v", err)
+	}
+
+	oldVersion := append([]byte(nil), greeting...)
+	oldVersion[10] = 2
+	if err := readGreeting(bytes.NewReader(oldVersion)); err == nil {
+		t.Fatal("readGreeting: accepted version 2")
+	}
+
+	plain := append([]byte(nil), greeting...)
+	copy(plain[12:32], "PLAIN")
+	if err := readGreeting(bytes.NewReader(plain)); err == nil {
+		t.Fatal("readGreeting: accepted PLAIN mechanism")
+	}
+
+	if err := readGreeting(bytes.NewReader(greeting[:10])); err == nil {
+		t.Fatal("readGreeting: accepted truncated greeting")
+	}
+}